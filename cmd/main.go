@@ -1,11 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"github.com/BacoFoods/menu/pkg/plemsi"
 	"github.com/BacoFoods/menu/pkg/shared"
 	"github.com/go-resty/resty/v2"
 	"net/http"
+	"time"
+	_ "time/tzdata" // store time zones don't depend on the zoneinfo of the image
 
 	"github.com/BacoFoods/menu/pkg/connector"
 	"github.com/BacoFoods/menu/pkg/scheduler"
@@ -13,11 +16,14 @@ import (
 
 	"github.com/BacoFoods/menu/internal"
 	"github.com/BacoFoods/menu/pkg/account"
+	"github.com/BacoFoods/menu/pkg/aggregator"
+	"github.com/BacoFoods/menu/pkg/analytics"
 	"github.com/BacoFoods/menu/pkg/app"
 	"github.com/BacoFoods/menu/pkg/assets"
 	"github.com/BacoFoods/menu/pkg/availability"
 	"github.com/BacoFoods/menu/pkg/brand"
 	"github.com/BacoFoods/menu/pkg/cashaudit"
+	"github.com/BacoFoods/menu/pkg/catalog"
 	"github.com/BacoFoods/menu/pkg/category"
 	"github.com/BacoFoods/menu/pkg/channel"
 	"github.com/BacoFoods/menu/pkg/client"
@@ -28,12 +34,17 @@ import (
 	"github.com/BacoFoods/menu/pkg/discount"
 	"github.com/BacoFoods/menu/pkg/facturacion"
 	"github.com/BacoFoods/menu/pkg/healthcheck"
+	"github.com/BacoFoods/menu/pkg/inventory"
 	"github.com/BacoFoods/menu/pkg/invoice"
 	"github.com/BacoFoods/menu/pkg/menu"
+	"github.com/BacoFoods/menu/pkg/menucache"
 	"github.com/BacoFoods/menu/pkg/order"
 	"github.com/BacoFoods/menu/pkg/payment"
 	"github.com/BacoFoods/menu/pkg/payment/paymentms"
 	"github.com/BacoFoods/menu/pkg/product"
+	"github.com/BacoFoods/menu/pkg/realtime"
+	"github.com/BacoFoods/menu/pkg/report"
+	"github.com/BacoFoods/menu/pkg/reservation"
 	"github.com/BacoFoods/menu/pkg/router"
 	"github.com/BacoFoods/menu/pkg/shift"
 	"github.com/BacoFoods/menu/pkg/store"
@@ -54,17 +65,24 @@ func main() {
 	gormFramework.MustMakeMigrations(
 		&menu.Menu{},
 		&menu.MenusCategories{},
+		&menu.Daypart{},
+		&menu.DaypartMenu{},
+		&menu.MenuVersion{},
 		&category.Category{},
 		&discount.Discount{},
 		&surcharge.Surcharge{},
 		&product.Product{},
 		&product.Modifier{},
+		&product.ModifierProduct{},
+		&product.ComboComponent{},
 		&product.Overrider{},
 		&taxes.Tax{},
 		&country.Country{},
 		&currency.Currency{},
+		&currency.Denomination{},
 		&brand.Brand{},
 		&store.Store{},
+		&store.ExternalLocation{},
 		&tables.Zone{},
 		&tables.Table{},
 		&channel.Channel{},
@@ -72,6 +90,7 @@ func main() {
 		&order.Order{},
 		&order.OrderItem{},
 		&order.OrderModifier{},
+		&order.OrderItemComponent{},
 		&order.OrderType{},
 		&order.OrderStatus{},
 		&invoice.Invoice{},
@@ -85,18 +104,38 @@ func main() {
 		&payment.Payment{},
 		&order.Attendee{},
 		&shift.Shift{},
+		&shift.DrawerMovement{},
 		&tables.QR{},
+		&tables.TableStateChange{},
+		&reservation.Reservation{},
+		&reservation.WaitlistEntry{},
 		&assets.Asset{},
 		&cashaudit.CashAudit{},
 		&cashaudit.Income{},
+		&cashaudit.CashCount{},
+		&cashaudit.Discrepancy{},
+		&cashaudit.Tolerance{},
+		&cashaudit.Reconciliation{},
+		&cashaudit.Settlement{},
 		&facturacion.FacturacionConfig{},
 		&invoice.Document{},
 		&scheduler.Schedule{},
-		&connector.Equivalence{},
-		&siesa.Reference{},
+		&siesa.ProductReference{},
 		&siesa.SiesaDocument{},
+		&siesa.SiesaRun{},
+		&siesa.SiesaRunAttempt{},
 		&scheduler.Holiday{},
+		&scheduler.ScheduleException{},
 		&invoice.Resolution{},
+		&analytics.DailySale{},
+		&inventory.Ingredient{},
+		&inventory.RecipeLine{},
+		&inventory.Movement{},
+		&inventory.Count{},
+		&aggregator.Listing{},
+		&aggregator.Sync{},
+		&aggregator.SyncError{},
+		&aggregator.Delivery{},
 	)
 
 	rabbitCh := internal.MustNewRabbitMQ(internal.Config.RabbitConfig.ComandasQueue, internal.Config.RabbitConfig.Host, internal.Config.RabbitConfig.Port)
@@ -104,6 +143,15 @@ func main() {
 
 	httpClient := shared.NewRestClient(resty.New())
 
+	// Realtime
+	realtimeBroker := realtime.NewRedisBroker(redisConn)
+	realtimeRepository := realtime.NewDBRepository(gormDB)
+	realtimeHandler := realtime.NewHandler(realtimeBroker, realtimeRepository)
+	realtimeRoutes := realtime.NewRoutes(realtimeHandler)
+
+	// Menu cache
+	menuCache := menucache.NewRedisCache(redisConn, internal.Config.RedisConfig.MenuCacheTTL)
+
 	// Healthcheck
 	healthcheckHandler := healthcheck.NewHandler()
 	healthcheckRoutes := healthcheck.NewRoutes(healthcheckHandler)
@@ -119,26 +167,40 @@ func main() {
 
 	// Store
 	storeRepository := store.NewDBRepository(gormDB)
+	if err := storeRepository.MigrateLegacyLocations(); err != nil {
+		logrus.Errorf("error migrating legacy popapp locations: %v", err)
+	}
 	storeService := store.NewService(storeRepository, channelRepository)
 	storeHandler := store.NewHandler(storeService)
 	storeRoutes := store.NewRoutes(storeHandler)
 
 	// Tables
+	// the table sessions are signed with the secret, without it anyone could forge the tokens
+	if internal.Config.TableSessionSecret == "" && internal.Config.AppEnv != "local" {
+		logrus.Fatal("TABLE_SESSION_SECRET is required to sign the table sessions")
+	}
 	zoneRepository := tables.NewZoneRepository(gormDB)
 	tableRepository := tables.NewTableRepository(gormDB)
-	tablesService := tables.NewService(tableRepository, zoneRepository, internal.Config.OITHost)
-	tablesHandler := tables.NewHandler(tablesService)
+	tablesService := tables.NewService(tableRepository,
+		zoneRepository,
+		internal.Config.OITHost,
+		realtimeBroker,
+		internal.Config.TableSessionSecret,
+		time.Duration(internal.Config.TableSessionExpireHours)*time.Hour,
+	)
+	tablesHandler := tables.NewHandler(tablesService, realtimeBroker)
 	tablesRoutes := tables.NewRoutes(tablesHandler)
 
 	// Availability
 	availabilityRepository := availability.NewDBRepository(gormDB)
-	availabilityService := availability.NewService(availabilityRepository, storeRepository, channelRepository)
+	availabilityService := availability.NewService(availabilityRepository, storeRepository, channelRepository, realtimeBroker, redisConn, menuCache)
 	availabilityHandler := availability.NewHandler(availabilityService)
+	go availabilityService.RunRestorer(context.Background())
 	availabilityRoutes := availability.NewRoutes(availabilityHandler)
 
 	// Product
 	productRepository := product.NewDBRepository(gormDB)
-	productService := product.NewService(productRepository, channelRepository)
+	productService := product.NewService(productRepository, channelRepository, menuCache)
 	productHandler := product.NewHandler(productService)
 	productRoutes := product.NewRoutes(productHandler)
 
@@ -156,14 +218,15 @@ func main() {
 
 	// Category
 	categoryRepository := category.NewDBRepository(gormDB)
-	categoryService := category.NewService(categoryRepository, productRepository)
+	categoryService := category.NewService(categoryRepository, productRepository, menuCache)
 	categoryHandler := category.NewHandler(categoryService)
 	categoryRoutes := category.NewRoutes(categoryHandler)
 
 	// Menu
 	menuRepository := menu.NewDBRepository(gormDB)
-	menuService := menu.NewService(menuRepository, productRepository, availabilityRepository, storeRepository, categoryRepository)
-	menuHandler := menu.NewHandler(menuService)
+	menuService := menu.NewService(menuRepository, productRepository, availabilityRepository, storeRepository, categoryRepository, realtimeBroker, redisConn, menuCache)
+	menuHandler := menu.NewHandler(menuService, realtimeBroker)
+	go menuService.RunPublisher(context.Background())
 	menuRoutes := menu.NewRoutes(menuHandler)
 
 	// Taxes
@@ -232,6 +295,24 @@ func main() {
 
 	// Order
 	orderRepository := order.NewDBRepository(gormDB)
+	// Analytics
+	analyticsRepository := analytics.NewDBRepository(gormDB)
+	analyticsService := analytics.NewService(analyticsRepository, storeRepository)
+	analyticsHandler := analytics.NewHandler(analyticsService)
+	analyticsRoutes := analytics.NewRoutes(analyticsHandler)
+
+	// Inventory
+	inventoryRepository := inventory.NewDBRepository(gormDB)
+	inventoryService := inventory.NewService(inventoryRepository, storeRepository)
+	inventoryHandler := inventory.NewHandler(inventoryService)
+	inventoryRoutes := inventory.NewRoutes(inventoryHandler)
+
+	// Catalog
+	catalogRepository := catalog.NewDBRepository(gormDB)
+	catalogService := catalog.NewService(catalogRepository, menuCache)
+	catalogHandler := catalog.NewHandler(catalogService)
+	catalogRoutes := catalog.NewRoutes(catalogHandler)
+
 	orderService := order.NewService(orderRepository,
 		tableRepository,
 		productRepository,
@@ -247,8 +328,13 @@ func main() {
 		redisConn,
 		plemsiAdapter,
 		clientRepository,
+		realtimeBroker,
+		order.ClosedOrderRecorders{analyticsService, inventoryService},
+		menuService,
+		availabilityService,
+		tablesService,
 	)
-	orderHandler := order.NewHandler(&orderService)
+	orderHandler := order.NewHandler(&orderService, tablesService)
 	orderRoutes := order.NewRoutes(orderHandler)
 
 	// Course
@@ -265,20 +351,56 @@ func main() {
 	}
 
 	siesaRepository := siesa.NewDBRepository(gormDB)
-	siesaService := siesa.NewService(siesaRepository, siesaHttpClient, siesa.SiesaConfig(internal.Config.SiesaConfig))
+	if err := siesaRepository.MigrateLegacyReferences(); err != nil {
+		logrus.Errorf("error migrating legacy siesa references: %v", err)
+	}
+	siesaService := siesa.NewService(siesaRepository, siesaHttpClient, siesa.SiesaConfig(internal.Config.SiesaConfig), storeRepository, redisConn)
 	siesaHandler := siesa.NewHandler(siesaService)
+	go siesaService.RunScheduler(context.Background())
 	siesaRoutes := siesa.NewRoutes(siesaHandler)
 
+	// Aggregator
+	aggregatorConfig := internal.Config.AggregatorConfig
+	aggregatorRepository := aggregator.NewDBRepository(gormDB)
+	aggregatorService := aggregator.NewService(aggregatorRepository,
+		menuService,
+		storeRepository,
+		channelRepository,
+		productRepository,
+		siesaRepository,
+		&orderService,
+		aggregator.Adapters(httpClient, aggregatorConfig),
+		redisConn,
+		aggregatorConfig,
+	)
+	aggregatorHandler := aggregator.NewHandler(aggregatorService)
+	aggregatorRoutes := aggregator.NewRoutes(aggregatorHandler)
+
+	go aggregatorService.RunScheduler(context.Background())
+
 	// Temporal
-	temporalHandler := temporal.NewHandler()
+	temporalHandler := temporal.NewHandler(storeRepository)
 	temporalRoutes := temporal.NewRoutes(temporalHandler)
 
 	// CashAudit
 	cashAuditRepository := cashaudit.NewDBRepository(gormDB)
-	cashAuditService := cashaudit.NewService(cashAuditRepository, storeRepository, orderRepository, invoiceRepository, shiftRepository)
+	cashAuditService := cashaudit.NewService(cashAuditRepository,
+		storeRepository,
+		orderRepository,
+		invoiceRepository,
+		shiftRepository,
+		currencyRepository,
+		internal.Config.CashAuditApproverRoles,
+	)
 	cashAuditHandler := cashaudit.NewHandler(cashAuditService)
 	cashAuditRoutes := cashaudit.NewRoutes(cashAuditHandler)
 
+	// Reports
+	reportRepository := report.NewDBRepository(gormDB)
+	reportService := report.NewService(reportRepository, storeRepository, channelRepository)
+	reportHandler := report.NewHandler(reportService)
+	reportRoutes := report.NewRoutes(reportHandler)
+
 	// Assets
 	assetsRepository := assets.NewAssetRepository(gormDB)
 	assetsService := assets.NewAssetService(assetsRepository)
@@ -287,15 +409,20 @@ func main() {
 
 	// Schedules
 	scheduleRepository := scheduler.NewDBRepository(gormDB)
-	scheduleService := scheduler.NewService(scheduleRepository)
+	scheduleService := scheduler.NewService(scheduleRepository, storeRepository)
 	scheduleHandler := scheduler.NewHandler(scheduleService)
 	scheduleRoutes := scheduler.NewRoutes(scheduleHandler)
 
+	// Reservations
+	reservationRepository := reservation.NewDBRepository(gormDB)
+	reservationService := reservation.NewService(reservationRepository, storeRepository, tablesService, scheduleService, &orderService)
+	reservationHandler := reservation.NewHandler(reservationService)
+	reservationRoutes := reservation.NewRoutes(reservationHandler)
+
 	// Equivalence
-	equivalenceRepository := connector.NewDBRepository(gormDB)
-	equivalenceService := connector.NewService(equivalenceRepository, invoiceRepository, storeRepository)
-	equivalenceHandler := connector.NewHandler(equivalenceService)
-	equivalenceRoutes := connector.NewRoutes(equivalenceHandler)
+	connectorService := connector.NewService(siesaRepository, invoiceRepository, storeRepository)
+	connectorHandler := connector.NewHandler(connectorService)
+	connectorRoutes := connector.NewRoutes(connectorHandler)
 
 	// Application TDP compilation apk and exe
 	appService := app.NewService(internal.Config.GitToken, internal.Config.GitRepository)
@@ -331,9 +458,16 @@ func main() {
 		Assets:       assetsRoutes,
 		Facturacion:  facturacionRoutes,
 		Schedule:     scheduleRoutes,
-		Equivalence:  equivalenceRoutes,
+		Connector:    connectorRoutes,
 		Siesa:        siesaRoutes,
 		App:          appRoutes,
+		Realtime:     realtimeRoutes,
+		Reservation:  reservationRoutes,
+		Report:       reportRoutes,
+		Analytics:    analyticsRoutes,
+		Inventory:    inventoryRoutes,
+		Catalog:      catalogRoutes,
+		Aggregator:   aggregatorRoutes,
 	}
 
 	// Run server
@@ -1,6 +1,8 @@
 package internal
 
 import (
+	"time"
+
 	"github.com/caarlos0/env/v6"
 	"github.com/sirupsen/logrus"
 )
@@ -20,6 +22,7 @@ type config struct {
 	PaylotsConfig
 	RedisConfig
 	SiesaConfig
+	AggregatorConfig
 }
 
 // Config is the global variable that holds the configuration for parse the environment variables
@@ -41,6 +44,13 @@ type AppConfig struct {
 	GoogleConfig     string `env:"GOOGLE_CONFIG"`
 	OITHost          string `env:"OIT_HOST"`
 
+	// Table sessions given to the customers when the table QR is scanned
+	TableSessionSecret      string `env:"TABLE_SESSION_SECRET"`
+	TableSessionExpireHours int    `env:"TABLE_SESSION_EXPIRE_HOURS" envDefault:"3"`
+
+	// Account roles allowed to approve the cash audits out of the brand tolerance
+	CashAuditApproverRoles []string `env:"CASH_AUDIT_APPROVER_ROLES" envSeparator:"," envDefault:"admin,supervisor"`
+
 	// Telemetry
 	InfluxHost  string `env:"INFLUX_HOST"`
 	InfluxPort  string `env:"INFLUX_PORT" envDefault:"8086"`
@@ -64,6 +74,8 @@ type AppConfig struct {
 type RedisConfig struct {
 	Host string `env:"REDIS_HOST"`
 	Port string `env:"REDIS_PORT" envDefault:"6379"`
+	// MenuCacheTTL bounds how long a resolved place menu is cached, zero disables the cache
+	MenuCacheTTL time.Duration `env:"MENU_CACHE_TTL" envDefault:"10m"`
 }
 
 // PopappConfig is the struct that holds the configuration for the firestore
@@ -97,4 +109,27 @@ type SiesaConfig struct {
 	Host       string `env:"SIESA_HOST"`
 	ConniKey   string `env:"SIESA_CONNI_KEY"`
 	ConniToken string `env:"SIESA_CONNI_TOKEN"`
+
+	// Scheduled runs, every store posts its previous day once the store local hour is past ScheduleHour
+	ScheduleEnabled  bool          `env:"SIESA_SCHEDULE_ENABLED" envDefault:"false"`
+	ScheduleHour     int           `env:"SIESA_SCHEDULE_HOUR" envDefault:"4"`
+	ScheduleInterval time.Duration `env:"SIESA_SCHEDULE_INTERVAL" envDefault:"10m"`
+	MaxAttempts      int           `env:"SIESA_MAX_ATTEMPTS" envDefault:"5"`
+	RetryBackoff     time.Duration `env:"SIESA_RETRY_BACKOFF" envDefault:"5m"`
+}
+
+// AggregatorConfig holds the credentials of the delivery platforms the menus of the stores are synced to
+type AggregatorConfig struct {
+	RappiHost  string `env:"RAPPI_HOST"`
+	RappiToken string `env:"RAPPI_TOKEN"`
+	DidiHost   string `env:"DIDI_HOST"`
+	DidiToken  string `env:"DIDI_TOKEN"`
+
+	// The webhooks of the orders placed on the platforms are signed with these secrets
+	RappiWebhookSecret string `env:"RAPPI_WEBHOOK_SECRET"`
+	DidiWebhookSecret  string `env:"DIDI_WEBHOOK_SECRET"`
+
+	// Scheduled syncs push the menu of every enabled listing on every interval
+	SyncEnabled  bool          `env:"AGGREGATOR_SYNC_ENABLED" envDefault:"false"`
+	SyncInterval time.Duration `env:"AGGREGATOR_SYNC_INTERVAL" envDefault:"15m"`
 }
@@ -37,6 +37,14 @@ func (r *redisMutex) Lock() error {
 	return nil
 }
 
+// TryLock acquires the lock without waiting, it returns false when the lock is held by someone else
+func (r *redisMutex) TryLock() (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	return r.client.SetNX(ctx, r.key, true, r.ttl).Result()
+}
+
 func (r *redisMutex) Unlock() error {
 	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
 	defer cancel()
@@ -48,6 +56,7 @@ func (r *redisMutex) Unlock() error {
 	tx := r.client.TxPipeline()
 	tx.Del(ctx, r.key)
 	tx.LPush(ctx, r.doneKey, true)
+	tx.Expire(ctx, r.doneKey, r.ttl)
 	_, err := tx.Exec(ctx)
 
 	r.unlocked = true
@@ -65,3 +74,10 @@ func DistMutex(client *redis.Client, key string) *redisMutex {
 		unlocked: false,
 	}
 }
+
+// DistMutexWithTTL is a DistMutex held up to the ttl, for the critical zones that take longer than a request
+func DistMutexWithTTL(client *redis.Client, key string, ttl time.Duration) *redisMutex {
+	mutex := DistMutex(client, key)
+	mutex.ttl = ttl
+	return mutex
+}
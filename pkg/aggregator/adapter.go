@@ -0,0 +1,572 @@
+package aggregator
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/BacoFoods/menu/internal"
+	"github.com/BacoFoods/menu/pkg/shared"
+)
+
+const (
+	LogAdapter = "pkg/aggregator/adapter"
+
+	RappiMenuPath        = "/api/v2/restaurants-integrations-public-api/menu"
+	RappiOrderPath       = "/api/v2/restaurants-integrations-public-api/orders/"
+	RappiSignatureHeader = "Rappi-Signature"
+	DidiMenuPath         = "/v1/shop/menu/upload"
+	DidiOrderPath        = "/v1/order/order/"
+	DidiSignatureHeader  = "X-Didi-Signature"
+
+	ErrorRappiNestedGroups = "rappi has no nested modifier groups, only the first level of %s was synced"
+)
+
+// Adapter pushes the catalog of a store to a delivery platform and takes the orders placed on it. The items the
+// platform rejects are returned as errors of the push, an error is only returned when the whole catalog was
+// rejected.
+type Adapter interface {
+	Platform() string
+	PushCatalog(externalStoreID string, catalog Catalog) ([]SyncError, error)
+
+	// ParseOrder checks the signature of an order webhook and parses its order
+	ParseOrder(header http.Header, body []byte) (*PlatformOrder, error)
+
+	// UpdateOrderStatus pushes the status of an order to the platform, the reason is given on cancellations
+	UpdateOrderStatus(externalStoreID, externalOrderID, status, reason string) error
+}
+
+// Adapters builds the adapters of the platforms with a host configured
+func Adapters(httpclient shared.RestClient, config internal.AggregatorConfig) map[string]Adapter {
+	adapters := make(map[string]Adapter)
+	if config.RappiHost != "" {
+		adapters[PlatformRappi] = NewRappi(httpclient, config.RappiHost, config.RappiToken, config.RappiWebhookSecret)
+	}
+	if config.DidiHost != "" {
+		adapters[PlatformDidi] = NewDidi(httpclient, config.DidiHost, config.DidiToken, config.DidiWebhookSecret)
+	}
+	return adapters
+}
+
+// Sign returns the signature of a webhook body, the hex HMAC-SHA256 of the body with the webhook secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verify checks the signature of a webhook body, nothing is verified without a secret
+func verify(secret, signature string, body []byte) bool {
+	if secret == "" || signature == "" {
+		return false
+	}
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(strings.ToLower(signature)))
+}
+
+// post sends the payload and decodes the response body, the status is checked by the caller
+func post(httpclient shared.RestClient, endpoint string, headers map[string]string, payload, response any) (int, error) {
+	resp, err := httpclient.Post(shared.Request{Endpoint: endpoint, Headers: headers, Body: payload})
+	if err != nil {
+		shared.LogError("error pushing catalog", LogAdapter, "post", err, endpoint)
+		return 0, err
+	}
+
+	if len(resp.Body()) > 0 {
+		if err := json.Unmarshal(resp.Body(), response); err != nil {
+			shared.LogWarn("warning decoding catalog response", LogAdapter, "post", err, endpoint, resp.StatusCode())
+		}
+	}
+
+	return resp.StatusCode(), nil
+}
+
+// Rappi
+
+type rappi struct {
+	httpclient shared.RestClient
+	host       string
+	token      string
+	secret     string
+}
+
+func NewRappi(httpclient shared.RestClient, host, token, webhookSecret string) *rappi {
+	return &rappi{httpclient, strings.TrimSuffix(host, "/"), token, webhookSecret}
+}
+
+// rappiOrderActions are the order endpoints of each status
+var rappiOrderActions = map[string]string{
+	DeliveryStatusAccepted:  "take",
+	DeliveryStatusReady:     "ready-for-pickup",
+	DeliveryStatusPickedUp:  "picked-up",
+	DeliveryStatusCancelled: "reject",
+}
+
+type RappiMenu struct {
+	StoreID string      `json:"storeId"`
+	Items   []RappiItem `json:"items"`
+}
+
+type RappiItem struct {
+	SKU         string          `json:"sku"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	ImageURL    string          `json:"imageUrl"`
+	Price       float64         `json:"price"`
+	Available   bool            `json:"available"`
+	Category    RappiCategory   `json:"category"`
+	Toppings    []RappiToppings `json:"toppingCategories"`
+}
+
+type RappiCategory struct {
+	ID              string `json:"id"`
+	Name            string `json:"name"`
+	SortingPosition int    `json:"sortingPosition"`
+}
+
+type RappiToppings struct {
+	ID       string         `json:"id"`
+	Name     string         `json:"name"`
+	Min      int            `json:"minToppingsForCategory"`
+	Max      int            `json:"maxToppingsForCategory"`
+	Toppings []RappiTopping `json:"toppings"`
+}
+
+type RappiTopping struct {
+	SKU       string  `json:"sku"`
+	Name      string  `json:"name"`
+	Price     float64 `json:"price"`
+	Available bool    `json:"available"`
+}
+
+// RappiOrder is the order webhook of Rappi, the subitems are the toppings of an item
+type RappiOrder struct {
+	OrderDetail struct {
+		OrderID  string `json:"order_id"`
+		Comments string `json:"instructions"`
+		Totals   struct {
+			Total float64 `json:"total_order"`
+		} `json:"totals"`
+		Items []RappiOrderItem `json:"items"`
+	} `json:"order_detail"`
+	Customer struct {
+		FirstName string `json:"first_name"`
+		LastName  string `json:"last_name"`
+	} `json:"customer"`
+	Store struct {
+		ExternalID string `json:"external_id"`
+	} `json:"store"`
+}
+
+type RappiOrderItem struct {
+	SKU               string           `json:"sku"`
+	Name              string           `json:"name"`
+	ToppingCategoryID string           `json:"topping_category_id,omitempty"`
+	Quantity          int              `json:"quantity"`
+	Price             float64          `json:"unit_price_with_discount"`
+	Comments          string           `json:"comments"`
+	Subitems          []RappiOrderItem `json:"subitems"`
+}
+
+type RappiOrderStatus struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+type RappiResponse struct {
+	Message string `json:"message"`
+	Errors  []struct {
+		SKU     string `json:"sku"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func (a *rappi) Platform() string {
+	return PlatformRappi
+}
+
+// PushCatalog replaces the menu of the store on Rappi, every item carries its category. Rappi has a single level
+// of toppings, the nested groups are left out and reported on their item.
+func (a *rappi) PushCatalog(externalStoreID string, catalog Catalog) ([]SyncError, error) {
+	payload := RappiMenu{StoreID: externalStoreID, Items: make([]RappiItem, 0)}
+	errs := make([]SyncError, 0)
+	for _, category := range catalog.Categories {
+		for _, item := range category.Items {
+			rappiItem := RappiItem{
+				SKU:         item.ExternalID,
+				Name:        item.Name,
+				Description: item.Description,
+				ImageURL:    item.Image,
+				Price:       item.Price,
+				Available:   item.Available,
+				Category:    RappiCategory{ID: fmt.Sprint(category.ID), Name: category.Name, SortingPosition: category.Sort},
+				Toppings:    make([]RappiToppings, 0),
+			}
+
+			nested := false
+			for _, group := range item.Groups {
+				toppings := RappiToppings{ID: fmt.Sprint(group.ID), Name: group.Name, Min: group.MinChoices, Max: group.MaxChoices}
+				for _, option := range group.Options {
+					nested = nested || len(option.Groups) > 0
+					toppings.Toppings = append(toppings.Toppings, RappiTopping{
+						SKU: option.ExternalID, Name: option.Name, Price: option.Price, Available: option.Available,
+					})
+				}
+				rappiItem.Toppings = append(rappiItem.Toppings, toppings)
+			}
+			if nested {
+				productID := item.ProductID
+				errs = append(errs, SyncError{ProductID: &productID, ExternalID: item.ExternalID, Name: item.Name,
+					Error: fmt.Sprintf(ErrorRappiNestedGroups, item.Name)})
+			}
+
+			payload.Items = append(payload.Items, rappiItem)
+		}
+	}
+
+	var response RappiResponse
+	headers := map[string]string{
+		"Content-Type":    "application/json",
+		"x-authorization": fmt.Sprintf("bearer %s", a.token),
+	}
+	status, err := post(a.httpclient, a.host+RappiMenuPath, headers, payload, &response)
+	if err != nil {
+		return nil, err
+	}
+	if status < 200 || status >= 300 {
+		return nil, fmt.Errorf("status %d %s", status, response.Message)
+	}
+
+	names := itemNames(catalog)
+	for _, rejected := range response.Errors {
+		errs = append(errs, names.syncError(rejected.SKU, rejected.Message))
+	}
+
+	return errs, nil
+}
+
+// ParseOrder parses a Rappi order, the webhook is signed on the Rappi-Signature header
+func (a *rappi) ParseOrder(header http.Header, body []byte) (*PlatformOrder, error) {
+	if !verify(a.secret, header.Get(RappiSignatureHeader), body) {
+		return nil, fmt.Errorf(ErrorWebhookSignature)
+	}
+
+	var payload RappiOrder
+	if err := json.Unmarshal(body, &payload); err != nil || payload.OrderDetail.OrderID == "" {
+		shared.LogWarn("warning decoding rappi order", LogAdapter, "ParseOrder", err, string(body))
+		return nil, fmt.Errorf(ErrorWebhookPayload)
+	}
+
+	var items func(rappiItems []RappiOrderItem) []PlatformItem
+	items = func(rappiItems []RappiOrderItem) []PlatformItem {
+		result := make([]PlatformItem, 0)
+		for _, item := range rappiItems {
+			result = append(result, PlatformItem{
+				ExternalID: item.SKU,
+				Name:       item.Name,
+				GroupID:    item.ToppingCategoryID,
+				Quantity:   item.Quantity,
+				Price:      item.Price,
+				Comments:   item.Comments,
+				Modifiers:  items(item.Subitems),
+			})
+		}
+		return result
+	}
+
+	return &PlatformOrder{
+		ExternalID:      payload.OrderDetail.OrderID,
+		ExternalStoreID: payload.Store.ExternalID,
+		ClientName:      strings.TrimSpace(payload.Customer.FirstName + " " + payload.Customer.LastName),
+		Comments:        payload.OrderDetail.Comments,
+		Total:           payload.OrderDetail.Totals.Total,
+		Items:           items(payload.OrderDetail.Items),
+	}, nil
+}
+
+// UpdateOrderStatus takes, rejects or reports the progress of a Rappi order
+func (a *rappi) UpdateOrderStatus(_, externalOrderID, status, reason string) error {
+	action, ok := rappiOrderActions[status]
+	if !ok {
+		return fmt.Errorf(ErrorDeliveryInvalidStatus, "", status)
+	}
+
+	var response RappiResponse
+	headers := map[string]string{
+		"Content-Type":    "application/json",
+		"x-authorization": fmt.Sprintf("bearer %s", a.token),
+	}
+	endpoint := fmt.Sprintf("%s%s%s/%s", a.host, RappiOrderPath, externalOrderID, action)
+	code, err := post(a.httpclient, endpoint, headers, RappiOrderStatus{Reason: reason}, &response)
+	if err != nil {
+		return err
+	}
+	if code < 200 || code >= 300 {
+		return fmt.Errorf("status %d %s", code, response.Message)
+	}
+
+	return nil
+}
+
+// Didi
+
+type didi struct {
+	httpclient shared.RestClient
+	host       string
+	token      string
+	secret     string
+}
+
+func NewDidi(httpclient shared.RestClient, host, token, webhookSecret string) *didi {
+	return &didi{httpclient, strings.TrimSuffix(host, "/"), token, webhookSecret}
+}
+
+// didiOrderActions are the order endpoints of each status
+var didiOrderActions = map[string]string{
+	DeliveryStatusAccepted:  "confirm",
+	DeliveryStatusReady:     "readyForPickup",
+	DeliveryStatusPickedUp:  "pickedUp",
+	DeliveryStatusCancelled: "cancel",
+}
+
+const (
+	DidiStatusOnSale  = 1
+	DidiStatusOffSale = 2
+)
+
+type DidiMenu struct {
+	ShopID     string         `json:"shop_id"`
+	Categories []DidiCategory `json:"categories"`
+}
+
+type DidiCategory struct {
+	CategoryID string     `json:"category_id"`
+	Name       string     `json:"name"`
+	Sort       int        `json:"sort"`
+	Items      []DidiItem `json:"items"`
+}
+
+// DidiItem is an item or a modifier of Didi, the prices are in cents
+type DidiItem struct {
+	ItemID         string      `json:"item_id"`
+	Name           string      `json:"name"`
+	Desc           string      `json:"desc,omitempty"`
+	HeadImg        string      `json:"head_img,omitempty"`
+	Price          int64       `json:"price"`
+	Status         int         `json:"status"`
+	Default        bool        `json:"is_default,omitempty"`
+	ModifierGroups []DidiGroup `json:"modifier_groups"`
+}
+
+type DidiGroup struct {
+	GroupID   string     `json:"group_id"`
+	Name      string     `json:"name"`
+	Min       int        `json:"min"`
+	Max       int        `json:"max"`
+	Modifiers []DidiItem `json:"modifiers"`
+}
+
+// DidiOrder is the order webhook of Didi, the prices are in cents and the sub items are the modifiers of an item
+type DidiOrder struct {
+	OrderID  string `json:"order_id"`
+	ShopID   string `json:"app_shop_id"`
+	Remark   string `json:"remark"`
+	Price    int64  `json:"order_price"`
+	Receiver struct {
+		Name string `json:"name"`
+	} `json:"receive_address"`
+	Items []DidiOrderItem `json:"order_items"`
+}
+
+type DidiOrderItem struct {
+	AppItemID string          `json:"app_item_id"`
+	Name      string          `json:"name"`
+	GroupID   string          `json:"app_content_id,omitempty"`
+	Amount    int             `json:"amount"`
+	SkuPrice  int64           `json:"sku_price"`
+	Remark    string          `json:"remark"`
+	SubItems  []DidiOrderItem `json:"sub_item_list"`
+}
+
+type DidiOrderStatus struct {
+	OrderID string `json:"order_id"`
+	ShopID  string `json:"app_shop_id"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+type DidiResponse struct {
+	Errno  int    `json:"errno"`
+	Errmsg string `json:"errmsg"`
+	Data   struct {
+		FailedItems []struct {
+			ItemID string `json:"item_id"`
+			Reason string `json:"reason"`
+		} `json:"failed_items"`
+	} `json:"data"`
+}
+
+func (a *didi) Platform() string {
+	return PlatformDidi
+}
+
+// PushCatalog replaces the menu of the shop on Didi, the modifier groups are nested as Didi supports them
+func (a *didi) PushCatalog(externalStoreID string, catalog Catalog) ([]SyncError, error) {
+	payload := DidiMenu{ShopID: externalStoreID, Categories: make([]DidiCategory, 0)}
+	for _, category := range catalog.Categories {
+		didiCategory := DidiCategory{CategoryID: fmt.Sprint(category.ID), Name: category.Name, Sort: category.Sort,
+			Items: make([]DidiItem, 0)}
+		for _, item := range category.Items {
+			didiCategory.Items = append(didiCategory.Items, DidiItem{
+				ItemID:         item.ExternalID,
+				Name:           item.Name,
+				Desc:           item.Description,
+				HeadImg:        item.Image,
+				Price:          cents(item.Price),
+				Status:         didiStatus(item.Available),
+				ModifierGroups: didiGroups(item.Groups),
+			})
+		}
+		payload.Categories = append(payload.Categories, didiCategory)
+	}
+
+	var response DidiResponse
+	headers := map[string]string{
+		"Content-Type":  "application/json",
+		"Authorization": fmt.Sprintf("Bearer %s", a.token),
+	}
+	status, err := post(a.httpclient, a.host+DidiMenuPath, headers, payload, &response)
+	if err != nil {
+		return nil, err
+	}
+	if status < 200 || status >= 300 || response.Errno != 0 {
+		return nil, fmt.Errorf("status %d errno %d %s", status, response.Errno, response.Errmsg)
+	}
+
+	names := itemNames(catalog)
+	errs := make([]SyncError, 0)
+	for _, failed := range response.Data.FailedItems {
+		errs = append(errs, names.syncError(failed.ItemID, failed.Reason))
+	}
+
+	return errs, nil
+}
+
+// ParseOrder parses a Didi order, the webhook is signed on the X-Didi-Signature header
+func (a *didi) ParseOrder(header http.Header, body []byte) (*PlatformOrder, error) {
+	if !verify(a.secret, header.Get(DidiSignatureHeader), body) {
+		return nil, fmt.Errorf(ErrorWebhookSignature)
+	}
+
+	var payload DidiOrder
+	if err := json.Unmarshal(body, &payload); err != nil || payload.OrderID == "" {
+		shared.LogWarn("warning decoding didi order", LogAdapter, "ParseOrder", err, string(body))
+		return nil, fmt.Errorf(ErrorWebhookPayload)
+	}
+
+	var items func(didiItems []DidiOrderItem) []PlatformItem
+	items = func(didiItems []DidiOrderItem) []PlatformItem {
+		result := make([]PlatformItem, 0)
+		for _, item := range didiItems {
+			result = append(result, PlatformItem{
+				ExternalID: item.AppItemID,
+				Name:       item.Name,
+				GroupID:    item.GroupID,
+				Quantity:   item.Amount,
+				Price:      float64(item.SkuPrice) / 100,
+				Comments:   item.Remark,
+				Modifiers:  items(item.SubItems),
+			})
+		}
+		return result
+	}
+
+	return &PlatformOrder{
+		ExternalID:      payload.OrderID,
+		ExternalStoreID: payload.ShopID,
+		ClientName:      payload.Receiver.Name,
+		Comments:        payload.Remark,
+		Total:           float64(payload.Price) / 100,
+		Items:           items(payload.Items),
+	}, nil
+}
+
+// UpdateOrderStatus confirms, cancels or reports the progress of a Didi order
+func (a *didi) UpdateOrderStatus(externalStoreID, externalOrderID, status, reason string) error {
+	action, ok := didiOrderActions[status]
+	if !ok {
+		return fmt.Errorf(ErrorDeliveryInvalidStatus, "", status)
+	}
+
+	var response DidiResponse
+	headers := map[string]string{
+		"Content-Type":  "application/json",
+		"Authorization": fmt.Sprintf("Bearer %s", a.token),
+	}
+	payload := DidiOrderStatus{OrderID: externalOrderID, ShopID: externalStoreID, Reason: reason}
+	code, err := post(a.httpclient, a.host+DidiOrderPath+action, headers, payload, &response)
+	if err != nil {
+		return err
+	}
+	if code < 200 || code >= 300 || response.Errno != 0 {
+		return fmt.Errorf("status %d errno %d %s", code, response.Errno, response.Errmsg)
+	}
+
+	return nil
+}
+
+func didiGroups(groups []Group) []DidiGroup {
+	result := make([]DidiGroup, 0)
+	for _, group := range groups {
+		didiGroup := DidiGroup{GroupID: fmt.Sprint(group.ID), Name: group.Name, Min: group.MinChoices,
+			Max: group.MaxChoices, Modifiers: make([]DidiItem, 0)}
+		for _, option := range group.Options {
+			didiGroup.Modifiers = append(didiGroup.Modifiers, DidiItem{
+				ItemID:         option.ExternalID,
+				Name:           option.Name,
+				Price:          cents(option.Price),
+				Status:         didiStatus(option.Available),
+				Default:        option.Default,
+				ModifierGroups: didiGroups(option.Groups),
+			})
+		}
+		result = append(result, didiGroup)
+	}
+	return result
+}
+
+func didiStatus(available bool) int {
+	if available {
+		return DidiStatusOnSale
+	}
+	return DidiStatusOffSale
+}
+
+func cents(price float64) int64 {
+	return int64(math.Round(price * 100))
+}
+
+// names of the items of a catalog by external id, to log the items rejected by the platforms
+type names map[string]Item
+
+func itemNames(catalog Catalog) names {
+	items := make(names)
+	for _, category := range catalog.Categories {
+		for _, item := range category.Items {
+			items[item.ExternalID] = item
+		}
+	}
+	return items
+}
+
+func (n names) syncError(externalID, reason string) SyncError {
+	syncError := SyncError{ExternalID: externalID, Error: reason}
+	if item, ok := n[externalID]; ok {
+		productID := item.ProductID
+		syncError.ProductID = &productID
+		syncError.Name = item.Name
+	}
+	return syncError
+}
@@ -0,0 +1,161 @@
+package aggregator_test
+
+import (
+	"github.com/BacoFoods/menu/internal"
+	"github.com/BacoFoods/menu/pkg/aggregator"
+	"github.com/BacoFoods/menu/pkg/category"
+	"github.com/BacoFoods/menu/pkg/menu"
+	"github.com/BacoFoods/menu/pkg/product"
+	"github.com/BacoFoods/menu/pkg/shared"
+	"github.com/go-resty/resty/v2"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const (
+	token  = "secret"
+	secret = "webhook-secret"
+)
+
+func config(host, token string) internal.AggregatorConfig {
+	return internal.AggregatorConfig{RappiHost: host, RappiToken: token, RappiWebhookSecret: secret,
+		DidiHost: host, DidiToken: token, DidiWebhookSecret: secret}
+}
+
+func menus() aggregator.Menus {
+	cheese := product.Product{ID: 3, Name: "Cheese", SKU: "CHE", Price: 2000, Enabled: true}
+	bacon := product.Product{ID: 4, Name: "Bacon", SKU: "BAC", Price: 3000, Enabled: true}
+	burger := product.Product{ID: 1, Name: "Burger", SKU: "BUR", SKUAggregators: "R-BUR", Price: 20000, Enabled: true,
+		Modifiers: []product.Modifier{{ID: 7, Name: "Extras", Required: true, MaxChoices: 2,
+			Options: []product.ModifierProduct{{Product: &cheese, Default: true}, {Product: &bacon}}}}}
+	fries := product.Product{ID: 2, Name: "Fries", SKU: "FRI", Price: 8000, Enabled: true}
+	water := product.Product{ID: 5, Name: "Water", Price: 3000, Enabled: true}
+
+	channelMenu := menu.Menu{Enable: true, Categories: []category.Category{
+		{ID: 10, Name: "Burgers", SortID: 1, Products: []product.Product{burger}},
+		{ID: 11, Name: "Sides", SortID: 2, Products: []product.Product{fries, water}},
+	}}
+
+	stockedOutBacon := bacon
+	stockedOutBacon.StockedOut = true
+	storeBurger := burger
+	storeBurger.Modifiers = []product.Modifier{{ID: 7, Options: []product.ModifierProduct{{Product: &cheese}, {Product: &stockedOutBacon}}}}
+	storeMenu := menu.Menu{Enable: true, Categories: []category.Category{
+		{ID: 10, Products: []product.Product{storeBurger}},
+	}}
+
+	burgerID, friesID := uint(1), uint(2)
+	return aggregator.Menus{
+		Channel:           []menu.Menu{channelMenu},
+		Store:             []menu.Menu{storeMenu},
+		StoreOverriders:   []product.Overrider{{ProductID: &burgerID, Price: 21000}, {ProductID: &friesID, Price: 9000}},
+		ChannelOverriders: []product.Overrider{{ProductID: &burgerID, Price: 24000, Enable: true}},
+	}
+}
+
+var _ = Describe("Aggregator", func() {
+	var catalog aggregator.Catalog
+	var errs []aggregator.SyncError
+
+	BeforeEach(func() {
+		catalog, errs = aggregator.Resolve(menus())
+	})
+
+	Describe("Resolve", func() {
+		It("should price the products with the channel, then the store overriders", func() {
+			Expect(catalog.Categories).To(HaveLen(2))
+			Expect(catalog.Categories[0].Items[0].Price).To(Equal(24000.0))
+			Expect(catalog.Categories[1].Items[0].Price).To(Equal(9000.0))
+			Expect(catalog.Categories[0].Items[0].Groups[0].Options[0].Price).To(Equal(2000.0))
+		})
+
+		It("should list the products with their aggregators SKU", func() {
+			Expect(catalog.Categories[0].Items[0].ExternalID).To(Equal("R-BUR"))
+			Expect(catalog.Categories[1].Items[0].ExternalID).To(Equal("FRI"))
+		})
+
+		It("should make available the products active at the store and not stocked out", func() {
+			burger := catalog.Categories[0].Items[0]
+			Expect(burger.Available).To(BeTrue())
+			Expect(burger.Groups[0].MinChoices).To(Equal(1))
+			Expect(burger.Groups[0].Options[0].Available).To(BeTrue())
+			Expect(burger.Groups[0].Options[1].Available).To(BeFalse())
+			Expect(catalog.Categories[1].Items[0].Available).To(BeFalse())
+
+			items, available := catalog.Count()
+			Expect(items).To(Equal(2))
+			Expect(available).To(Equal(1))
+		})
+
+		It("should make unavailable the disabled products and the products disabled at the channel", func() {
+			resolved := menus()
+			resolved.Channel[0].Categories[0].Products[0].Modifiers[0].Options[0].Product.Enabled = false
+			resolved.ChannelOverriders[0].Enable = false
+			catalog, _ = aggregator.Resolve(resolved)
+
+			burger := catalog.Categories[0].Items[0]
+			Expect(burger.Available).To(BeFalse())
+			Expect(burger.Price).To(Equal(24000.0))
+			Expect(burger.Groups[0].Options[0].Available).To(BeFalse())
+
+			items, available := catalog.Count()
+			Expect(items).To(Equal(2))
+			Expect(available).To(BeZero())
+		})
+
+		It("should return the products without SKU as errors", func() {
+			Expect(errs).To(HaveLen(1))
+			Expect(*errs[0].ProductID).To(Equal(uint(5)))
+			Expect(errs[0].Error).To(Equal(aggregator.ErrorItemMissingSKU))
+		})
+	})
+
+	Describe("Adapters", func() {
+		var server *aggregator.MockServer
+		var adapters map[string]aggregator.Adapter
+
+		BeforeEach(func() {
+			server = aggregator.NewMockServer(token)
+			server.Reject["FRI"] = "item not approved"
+			adapters = aggregator.Adapters(shared.NewRestClient(resty.New()), config(server.URL, token))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("should push the menu to Rappi with a single level of toppings", func() {
+			rejected, err := adapters[aggregator.PlatformRappi].PushCatalog("900", catalog)
+			Expect(err).To(BeNil())
+			Expect(rejected).To(HaveLen(1))
+			Expect(rejected[0].ExternalID).To(Equal("FRI"))
+			Expect(rejected[0].Name).To(Equal("Fries"))
+
+			pushed, ok := server.RappiMenu("900")
+			Expect(ok).To(BeTrue())
+			Expect(pushed.Items).To(HaveLen(2))
+			Expect(pushed.Items[0].Price).To(Equal(24000.0))
+			Expect(pushed.Items[0].Toppings[0].Toppings).To(HaveLen(2))
+		})
+
+		It("should push the menu to Didi in cents", func() {
+			rejected, err := adapters[aggregator.PlatformDidi].PushCatalog("700", catalog)
+			Expect(err).To(BeNil())
+			Expect(rejected).To(HaveLen(1))
+			Expect(*rejected[0].ProductID).To(Equal(uint(2)))
+
+			pushed, ok := server.DidiMenu("700")
+			Expect(ok).To(BeTrue())
+			Expect(pushed.Categories[0].Items[0].Price).To(Equal(int64(2400000)))
+			Expect(pushed.Categories[1].Items[0].Status).To(Equal(aggregator.DidiStatusOffSale))
+		})
+
+		It("should fail the push with a wrong token", func() {
+			adapters = aggregator.Adapters(shared.NewRestClient(resty.New()), config(server.URL, "wrong"))
+			for _, adapter := range adapters {
+				_, err := adapter.PushCatalog("900", catalog)
+				Expect(err).NotTo(BeNil())
+			}
+		})
+	})
+})
@@ -0,0 +1,171 @@
+package aggregator
+
+import (
+	"fmt"
+
+	"github.com/BacoFoods/menu/pkg/shared"
+	"gorm.io/gorm"
+)
+
+const (
+	LogDBRepository = "pkg/aggregator/db_repository"
+
+	// syncsLimit is the number of syncs returned by the sync log, the most recent first
+	syncsLimit = 100
+
+	// deliveriesLimit is the number of deliveries returned, the most recent first
+	deliveriesLimit = 200
+)
+
+type DBRepository struct {
+	db *gorm.DB
+}
+
+func NewDBRepository(db *gorm.DB) *DBRepository {
+	return &DBRepository{db}
+}
+
+func (r *DBRepository) FindListings(filter map[string]string) ([]Listing, error) {
+	var listings []Listing
+	if err := r.db.Where(filter).Order("id").Find(&listings).Error; err != nil {
+		shared.LogError("error finding listings", LogDBRepository, "FindListings", err, filter)
+		return nil, fmt.Errorf(ErrorListingFinding)
+	}
+
+	return listings, nil
+}
+
+func (r *DBRepository) GetListing(listingID string) (*Listing, error) {
+	var listing Listing
+	if err := r.db.First(&listing, listingID).Error; err != nil {
+		shared.LogError("error getting listing", LogDBRepository, "GetListing", err, listingID)
+		return nil, fmt.Errorf(ErrorListingGetting)
+	}
+
+	return &listing, nil
+}
+
+func (r *DBRepository) CreateListing(listing *Listing) (*Listing, error) {
+	if err := r.db.Create(listing).Error; err != nil {
+		shared.LogError("error creating listing", LogDBRepository, "CreateListing", err, *listing)
+		return nil, fmt.Errorf(ErrorListingCreating)
+	}
+
+	return listing, nil
+}
+
+func (r *DBRepository) UpdateListing(listing *Listing) (*Listing, error) {
+	if err := r.db.Save(listing).Error; err != nil {
+		shared.LogError("error updating listing", LogDBRepository, "UpdateListing", err, *listing)
+		return nil, fmt.Errorf(ErrorListingUpdating)
+	}
+
+	return listing, nil
+}
+
+func (r *DBRepository) DeleteListing(listingID string) (*Listing, error) {
+	listing, err := r.GetListing(listingID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.db.Delete(listing).Error; err != nil {
+		shared.LogError("error deleting listing", LogDBRepository, "DeleteListing", err, listingID)
+		return nil, fmt.Errorf(ErrorListingDeleting)
+	}
+
+	return listing, nil
+}
+
+// CreateSync saves the sync with its item errors and the last sync of its listing
+func (r *DBRepository) CreateSync(sync *Sync) (*Sync, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(sync).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&Listing{}).Where("id = ?", sync.ListingID).
+			Updates(map[string]any{"last_sync_at": sync.FinishedAt, "last_status": sync.Status}).Error
+	})
+	if err != nil {
+		shared.LogError("error creating sync", LogDBRepository, "CreateSync", err, sync.ListingID)
+		return nil, err
+	}
+
+	return sync, nil
+}
+
+// FindSyncs returns the most recent syncs without their item errors
+func (r *DBRepository) FindSyncs(filter map[string]string) ([]Sync, error) {
+	var syncs []Sync
+	if err := r.db.Where(filter).Order("id DESC").Limit(syncsLimit).Find(&syncs).Error; err != nil {
+		shared.LogError("error finding syncs", LogDBRepository, "FindSyncs", err, filter)
+		return nil, fmt.Errorf(ErrorSyncFinding)
+	}
+
+	return syncs, nil
+}
+
+func (r *DBRepository) GetSync(syncID string) (*Sync, error) {
+	var sync Sync
+	if err := r.db.Preload("Errors").First(&sync, syncID).Error; err != nil {
+		shared.LogError("error getting sync", LogDBRepository, "GetSync", err, syncID)
+		return nil, fmt.Errorf(ErrorSyncGetting)
+	}
+
+	return &sync, nil
+}
+
+// FindDeliveries returns the most recent deliveries
+func (r *DBRepository) FindDeliveries(filter map[string]string) ([]Delivery, error) {
+	var deliveries []Delivery
+	if err := r.db.Where(filter).Order("id DESC").Limit(deliveriesLimit).Find(&deliveries).Error; err != nil {
+		shared.LogError("error finding deliveries", LogDBRepository, "FindDeliveries", err, filter)
+		return nil, fmt.Errorf(ErrorDeliveryFinding)
+	}
+
+	return deliveries, nil
+}
+
+func (r *DBRepository) GetDelivery(deliveryID string) (*Delivery, error) {
+	var delivery Delivery
+	if err := r.db.First(&delivery, deliveryID).Error; err != nil {
+		shared.LogError("error getting delivery", LogDBRepository, "GetDelivery", err, deliveryID)
+		return nil, fmt.Errorf(ErrorDeliveryGetting)
+	}
+
+	return &delivery, nil
+}
+
+// GetDeliveryByExternalID returns the delivery of the platform order, or nil when the order wasn't received
+func (r *DBRepository) GetDeliveryByExternalID(platform, externalID string) (*Delivery, error) {
+	var deliveries []Delivery
+	if err := r.db.Where("platform = ? AND external_id = ?", platform, externalID).Limit(1).Find(&deliveries).Error; err != nil {
+		shared.LogError("error getting delivery", LogDBRepository, "GetDeliveryByExternalID", err, platform, externalID)
+		return nil, fmt.Errorf(ErrorDeliveryGetting)
+	}
+
+	if len(deliveries) == 0 {
+		return nil, nil
+	}
+
+	return &deliveries[0], nil
+}
+
+func (r *DBRepository) CreateDelivery(delivery *Delivery) (*Delivery, error) {
+	if err := r.db.Create(delivery).Error; err != nil {
+		shared.LogError("error creating delivery", LogDBRepository, "CreateDelivery", err, delivery.Platform, delivery.ExternalID)
+		return nil, fmt.Errorf(ErrorDeliveryCreating)
+	}
+
+	return delivery, nil
+}
+
+func (r *DBRepository) UpdateDelivery(delivery *Delivery) (*Delivery, error) {
+	if err := r.db.Save(delivery).Error; err != nil {
+		shared.LogError("error updating delivery", LogDBRepository, "UpdateDelivery", err, delivery.ID)
+		return nil, fmt.Errorf(ErrorDeliveryUpdating)
+	}
+
+	return delivery, nil
+}
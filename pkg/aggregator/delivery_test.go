@@ -0,0 +1,142 @@
+package aggregator_test
+
+import (
+	"net/http"
+
+	"github.com/BacoFoods/menu/pkg/aggregator"
+	"github.com/BacoFoods/menu/pkg/shared"
+	"github.com/go-resty/resty/v2"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const rappiOrder = `{
+	"order_detail": {
+		"order_id": "R-1001",
+		"instructions": "no onions",
+		"totals": {"total_order": 52000},
+		"items": [
+			{"sku": "R-BUR", "name": "Burger", "quantity": 2, "unit_price_with_discount": 24000, "subitems": [
+				{"sku": "CHE", "name": "Cheese", "topping_category_id": "7", "quantity": 1, "unit_price_with_discount": 2000}
+			]}
+		]
+	},
+	"customer": {"first_name": "Ana", "last_name": "Gómez"},
+	"store": {"external_id": "900"}
+}`
+
+const didiOrder = `{
+	"order_id": "D-2002",
+	"app_shop_id": "700",
+	"order_price": 900000,
+	"receive_address": {"name": "Luis"},
+	"order_items": [{"app_item_id": "FRI", "name": "Fries", "amount": 1, "sku_price": 900000}]
+}`
+
+func signed(header string, body string) http.Header {
+	headers := http.Header{}
+	headers.Set(header, aggregator.Sign(secret, []byte(body)))
+	return headers
+}
+
+var _ = Describe("Delivery", func() {
+	var server *aggregator.MockServer
+	var adapters map[string]aggregator.Adapter
+	var products aggregator.ProductIndex
+	storeID, channelID, brandID := uint(1), uint(2), uint(3)
+	listing := aggregator.Listing{ID: 4, Platform: aggregator.PlatformRappi, StoreID: &storeID, ChannelID: &channelID, ExternalID: "900"}
+
+	BeforeEach(func() {
+		server = aggregator.NewMockServer(token)
+		adapters = aggregator.Adapters(shared.NewRestClient(resty.New()), config(server.URL, token))
+
+		products = make(aggregator.ProductIndex)
+		products.Add(1, "R-BUR", "")
+		products.Add(3, "CHE", "Cheese")
+		products.Add(2, "FRI", "")
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("ParseOrder", func() {
+		It("should parse a signed Rappi order", func() {
+			order, err := adapters[aggregator.PlatformRappi].ParseOrder(signed(aggregator.RappiSignatureHeader, rappiOrder), []byte(rappiOrder))
+			Expect(err).To(BeNil())
+			Expect(order.ExternalID).To(Equal("R-1001"))
+			Expect(order.ExternalStoreID).To(Equal("900"))
+			Expect(order.ClientName).To(Equal("Ana Gómez"))
+			Expect(order.Items[0].Modifiers[0].GroupID).To(Equal("7"))
+			Expect(order.ExternalIDs()).To(Equal([]string{"R-BUR", "CHE"}))
+		})
+
+		It("should parse a signed Didi order with the prices in cents", func() {
+			order, err := adapters[aggregator.PlatformDidi].ParseOrder(signed(aggregator.DidiSignatureHeader, didiOrder), []byte(didiOrder))
+			Expect(err).To(BeNil())
+			Expect(order.ExternalStoreID).To(Equal("700"))
+			Expect(order.Total).To(Equal(9000.0))
+			Expect(order.Items[0].Price).To(Equal(9000.0))
+		})
+
+		It("should reject the webhooks with a wrong signature", func() {
+			headers := http.Header{}
+			headers.Set(aggregator.RappiSignatureHeader, aggregator.Sign("wrong", []byte(rappiOrder)))
+			_, err := adapters[aggregator.PlatformRappi].ParseOrder(headers, []byte(rappiOrder))
+			Expect(err.Error()).To(Equal(aggregator.ErrorWebhookSignature))
+
+			_, err = adapters[aggregator.PlatformDidi].ParseOrder(http.Header{}, []byte(didiOrder))
+			Expect(err.Error()).To(Equal(aggregator.ErrorWebhookSignature))
+		})
+	})
+
+	Describe("ToOrder", func() {
+		It("should map the order to the store and channel of the listing with an item per unit", func() {
+			platformOrder, _ := adapters[aggregator.PlatformRappi].ParseOrder(signed(aggregator.RappiSignatureHeader, rappiOrder), []byte(rappiOrder))
+			order, err := platformOrder.ToOrder(&brandID, listing, products)
+			Expect(err).To(BeNil())
+			Expect(*order.StoreID).To(Equal(storeID))
+			Expect(*order.ChannelID).To(Equal(channelID))
+			Expect(order.ExternalCode).To(Equal("R-1001"))
+			Expect(order.Comments).To(Equal("no onions"))
+			Expect(order.Items).To(HaveLen(2))
+			Expect(*order.Items[1].ProductID).To(Equal(uint(1)))
+			Expect(*order.Items[1].Modifiers[0].ProductID).To(Equal(uint(3)))
+			Expect(*order.Items[1].Modifiers[0].ModifierID).To(Equal(uint(7)))
+		})
+
+		It("should resolve the items by name when the external id is unknown", func() {
+			platformOrder := aggregator.PlatformOrder{ExternalID: "X", Items: []aggregator.PlatformItem{{ExternalID: "OTHER", Name: "cheese"}}}
+			order, err := platformOrder.ToOrder(&brandID, listing, products)
+			Expect(err).To(BeNil())
+			Expect(*order.Items[0].ProductID).To(Equal(uint(3)))
+		})
+
+		It("should return the items without product", func() {
+			platformOrder := aggregator.PlatformOrder{ExternalID: "X", Items: []aggregator.PlatformItem{{ExternalID: "NEW", Name: "Shake"}}}
+			_, err := platformOrder.ToOrder(&brandID, listing, products)
+			Expect(err.Error()).To(ContainSubstring("Shake (NEW)"))
+		})
+	})
+
+	Describe("UpdateOrderStatus", func() {
+		It("should push the statuses of the orders to the platforms", func() {
+			for _, status := range []string{aggregator.DeliveryStatusAccepted, aggregator.DeliveryStatusReady, aggregator.DeliveryStatusPickedUp} {
+				Expect(adapters[aggregator.PlatformRappi].UpdateOrderStatus("900", "R-1001", status, "")).To(BeNil())
+			}
+			Expect(adapters[aggregator.PlatformDidi].UpdateOrderStatus("700", "D-2002", aggregator.DeliveryStatusCancelled, "closed")).To(BeNil())
+
+			Expect(server.OrderStatuses("R-1001")).To(Equal([]string{"accepted", "ready", "picked_up"}))
+			Expect(server.OrderStatuses("D-2002")).To(Equal([]string{"cancelled"}))
+		})
+
+		It("should only move the deliveries forward", func() {
+			delivery := aggregator.Delivery{Status: aggregator.DeliveryStatusAccepted}
+			Expect(delivery.CanChangeTo(aggregator.DeliveryStatusReady)).To(BeTrue())
+			Expect(delivery.CanChangeTo(aggregator.DeliveryStatusPickedUp)).To(BeFalse())
+
+			delivery.Status = aggregator.DeliveryStatusCancelled
+			Expect(delivery.CanChangeTo(aggregator.DeliveryStatusReady)).To(BeFalse())
+		})
+	})
+})
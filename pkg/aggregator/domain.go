@@ -0,0 +1,532 @@
+package aggregator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BacoFoods/menu/pkg/menu"
+	"github.com/BacoFoods/menu/pkg/order"
+	"github.com/BacoFoods/menu/pkg/product"
+)
+
+const (
+	ErrorAggregatorBadRequest      = "error bad request"
+	ErrorListingFinding            = "error finding aggregator listings"
+	ErrorListingGetting            = "error getting aggregator listing"
+	ErrorListingCreating           = "error creating aggregator listing"
+	ErrorListingUpdating           = "error updating aggregator listing"
+	ErrorListingDeleting           = "error deleting aggregator listing"
+	ErrorListingInvalidPlatform    = "error aggregator platform must be rappi or didi"
+	ErrorListingExternalID         = "error aggregator listing needs the store id of the platform"
+	ErrorListingWrongBrand         = "error aggregator listing store and channel must be of the same brand"
+	ErrorListingDisabled           = "error aggregator listing is disabled"
+	ErrorSyncFinding               = "error finding aggregator syncs"
+	ErrorSyncGetting               = "error getting aggregator sync"
+	ErrorSyncResolvingMenu         = "error resolving the menu of the aggregator listing"
+	ErrorSyncPlatformNotConfigured = "error aggregator platform %s is not configured"
+	ErrorSyncPushing               = "error pushing the menu to %s: %s"
+	ErrorItemMissingSKU            = "product has no sku for the aggregators"
+	ErrorWebhookSignature          = "error aggregator webhook signature is invalid"
+	ErrorWebhookPayload            = "error aggregator webhook payload is invalid"
+	ErrorDeliveryFinding           = "error finding aggregator deliveries"
+	ErrorDeliveryGetting           = "error getting aggregator delivery"
+	ErrorDeliveryCreating          = "error creating aggregator delivery"
+	ErrorDeliveryUpdating          = "error updating aggregator delivery"
+	ErrorDeliveryListingNotFound   = "error no enabled aggregator listing for the %s store %s"
+	ErrorDeliveryEmpty             = "error aggregator order has no items"
+	ErrorDeliveryItemNotFound      = "error aggregator item %s (%s) has no product; "
+	ErrorDeliveryInvalidStatus     = "error aggregator delivery can't go from %s to %s"
+	ErrorDeliveryPushingStatus     = "error pushing the order status to %s: %s"
+	ErrorDeliveryCancelingOrder    = "error canceling the delivery order: %s"
+
+	PlatformRappi = "rappi" // same names as the platforms of the SIESA references
+	PlatformDidi  = "didi"
+
+	SyncStatusSuccess = "success"
+	SyncStatusPartial = "partial" // the menu was pushed, some items were rejected
+	SyncStatusFailed  = "failed"
+
+	SyncTriggerManual    = "manual"
+	SyncTriggerScheduled = "scheduled"
+
+	// Statuses of the orders received from the platforms, every change is pushed back to the platform
+	DeliveryStatusReceived  = "received" // the order is being created
+	DeliveryStatusAccepted  = "accepted"
+	DeliveryStatusReady     = "ready"
+	DeliveryStatusPickedUp  = "picked_up"
+	DeliveryStatusCancelled = "cancelled"
+)
+
+var platforms = map[string]bool{PlatformRappi: true, PlatformDidi: true}
+
+// IsValidPlatform checks the platform has an adapter
+func IsValidPlatform(platform string) bool {
+	return platforms[platform]
+}
+
+type Repository interface {
+	FindListings(filter map[string]string) ([]Listing, error)
+	GetListing(listingID string) (*Listing, error)
+	CreateListing(*Listing) (*Listing, error)
+	UpdateListing(*Listing) (*Listing, error)
+	DeleteListing(listingID string) (*Listing, error)
+
+	CreateSync(*Sync) (*Sync, error)
+	FindSyncs(filter map[string]string) ([]Sync, error)
+	GetSync(syncID string) (*Sync, error)
+
+	FindDeliveries(filter map[string]string) ([]Delivery, error)
+	GetDelivery(deliveryID string) (*Delivery, error)
+	GetDeliveryByExternalID(platform, externalID string) (*Delivery, error)
+	CreateDelivery(*Delivery) (*Delivery, error)
+	UpdateDelivery(*Delivery) (*Delivery, error)
+}
+
+// Listing is a store selling on an aggregator platform, its menu is the menu of the channel of the platform
+// with the stock and dayparts of the store. The external id is the id of the store on the platform.
+type Listing struct {
+	ID         uint       `json:"id"`
+	Platform   string     `json:"platform" binding:"required" gorm:"uniqueIndex:idx_listing_place" enums:"rappi,didi"`
+	StoreID    *uint      `json:"store_id" binding:"required" gorm:"uniqueIndex:idx_listing_place"`
+	ChannelID  *uint      `json:"channel_id" binding:"required" gorm:"uniqueIndex:idx_listing_place"`
+	ExternalID string     `json:"external_id" binding:"required"`
+	Enabled    bool       `json:"enabled"`
+	LastSyncAt *time.Time `json:"last_sync_at" swaggerignore:"true"`
+	LastStatus string     `json:"last_status" swaggerignore:"true"`
+	CreatedAt  *time.Time `json:"created_at,omitempty" swaggerignore:"true"`
+	UpdatedAt  *time.Time `json:"updated_at,omitempty" swaggerignore:"true"`
+}
+
+// Validate checks the platform and the external id of the listing
+func (l Listing) Validate() error {
+	if !IsValidPlatform(l.Platform) {
+		return fmt.Errorf(ErrorListingInvalidPlatform)
+	}
+	if l.ExternalID == "" {
+		return fmt.Errorf(ErrorListingExternalID)
+	}
+	return nil
+}
+
+// Sync is the log of a push of the menu of a listing to its platform, with the items the platform rejected
+type Sync struct {
+	ID         uint        `json:"id"`
+	ListingID  uint        `json:"listing_id" gorm:"index"`
+	Platform   string      `json:"platform"`
+	StoreID    *uint       `json:"store_id"`
+	ChannelID  *uint       `json:"channel_id"`
+	Trigger    string      `json:"trigger" enums:"manual,scheduled"`
+	Status     string      `json:"status" enums:"success,partial,failed"`
+	Items      int         `json:"items"`
+	Available  int         `json:"available"`
+	Failed     int         `json:"failed"`
+	Error      string      `json:"error,omitempty"`
+	Errors     []SyncError `json:"errors,omitempty" gorm:"foreignKey:SyncID"`
+	StartedAt  time.Time   `json:"started_at"`
+	FinishedAt time.Time   `json:"finished_at"`
+	CreatedAt  *time.Time  `json:"created_at,omitempty" swaggerignore:"true"`
+}
+
+// SyncError is an item of the menu that wasn't synced, because it can't be listed or the platform rejected it
+type SyncError struct {
+	ID         uint   `json:"id"`
+	SyncID     uint   `json:"sync_id" gorm:"index"`
+	ProductID  *uint  `json:"product_id"`
+	ExternalID string `json:"external_id"`
+	Name       string `json:"name"`
+	Error      string `json:"error"`
+}
+
+// Catalog is the menu of a listing as it's shown on the platform, the adapters map it to the platform format
+type Catalog struct {
+	Categories []Category `json:"categories"`
+}
+
+type Category struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Sort  int    `json:"sort"`
+	Items []Item `json:"items"`
+}
+
+// Item is a product of the menu, the external id is its SKU for the aggregators or its SKU
+type Item struct {
+	ProductID   uint    `json:"product_id"`
+	ExternalID  string  `json:"external_id"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Image       string  `json:"image"`
+	Price       float64 `json:"price"`
+	Available   bool    `json:"available"`
+	Groups      []Group `json:"groups"`
+}
+
+// Group is a modifier group of an item or of an option, the min choices of a required group is one at least
+type Group struct {
+	ID          uint     `json:"id"`
+	Name        string   `json:"name"`
+	MinChoices  int      `json:"min_choices"`
+	MaxChoices  int      `json:"max_choices"`
+	FreeChoices int      `json:"free_choices"`
+	Options     []Option `json:"options"`
+}
+
+type Option struct {
+	ProductID  uint    `json:"product_id"`
+	ExternalID string  `json:"external_id"`
+	Name       string  `json:"name"`
+	Price      float64 `json:"price"`
+	Available  bool    `json:"available"`
+	Default    bool    `json:"default"`
+	Groups     []Group `json:"groups"`
+}
+
+// Count returns the items of the catalog and the available ones
+func (c Catalog) Count() (int, int) {
+	items, available := 0, 0
+	for _, category := range c.Categories {
+		for _, item := range category.Items {
+			items++
+			if item.Available {
+				available++
+			}
+		}
+	}
+	return items, available
+}
+
+// Menus are the sources of the menu of a listing: the menus of the channel, with the prices and 86-list of the
+// channel, and the menus of the store, active at the store time with the 86-list of the store
+type Menus struct {
+	Channel           []menu.Menu
+	Store             []menu.Menu
+	StoreOverriders   []product.Overrider
+	ChannelOverriders []product.Overrider
+}
+
+// Resolve builds the catalog of a listing from the enabled menus of its channel. The price of a product is the
+// price of its channel overrider, else of its store overrider, else the product price. A product is available
+// when it's enabled and on an active menu of the store, it isn't 86'd at the store nor at the channel and its
+// channel overrider isn't disabled. The products without SKU can't be listed and are returned as errors.
+func Resolve(menus Menus) (Catalog, []SyncError) {
+	prices := make(map[uint]float64)
+	for _, overriders := range [][]product.Overrider{menus.StoreOverriders, menus.ChannelOverriders} {
+		for _, overrider := range overriders {
+			if overrider.ProductID != nil {
+				prices[*overrider.ProductID] = overrider.Price
+			}
+		}
+	}
+
+	disabled := make(map[uint]bool)
+	for _, overrider := range menus.ChannelOverriders {
+		if overrider.ProductID != nil && !overrider.Enable {
+			disabled[*overrider.ProductID] = true
+		}
+	}
+
+	active := make(map[uint]bool)
+	stockedOut := map[bool]map[uint]bool{false: {}, true: {}}
+	for _, m := range menus.Store {
+		if !m.Enable {
+			continue
+		}
+		for _, c := range m.Categories {
+			for _, p := range c.Products {
+				active[p.ID] = true
+			}
+		}
+	}
+	menu.EachProduct(menus.Store, func(p *product.Product, option bool) {
+		if p.StockedOut {
+			stockedOut[option][p.ID] = true
+		}
+	})
+
+	r := resolver{prices: prices, stockedOut: stockedOut, disabled: disabled}
+	catalog := Catalog{Categories: make([]Category, 0)}
+	errs := make([]SyncError, 0)
+	categories := make(map[uint]int)
+	listed := make(map[uint]map[uint]bool)
+	for _, m := range menus.Channel {
+		if !m.Enable {
+			continue
+		}
+
+		for _, c := range m.Categories {
+			index, ok := categories[c.ID]
+			if !ok {
+				index = len(catalog.Categories)
+				categories[c.ID] = index
+				listed[c.ID] = make(map[uint]bool)
+				catalog.Categories = append(catalog.Categories, Category{ID: c.ID, Name: c.Name, Sort: c.SortID, Items: make([]Item, 0)})
+			}
+
+			for _, p := range c.Products {
+				if listed[c.ID][p.ID] {
+					continue
+				}
+				listed[c.ID][p.ID] = true
+
+				productID := p.ID
+				externalID := ExternalID(p)
+				if externalID == "" {
+					errs = append(errs, SyncError{ProductID: &productID, Name: p.Name, Error: ErrorItemMissingSKU})
+					continue
+				}
+
+				catalog.Categories[index].Items = append(catalog.Categories[index].Items, Item{
+					ProductID:   p.ID,
+					ExternalID:  externalID,
+					Name:        p.Name,
+					Description: p.Description,
+					Image:       p.Image,
+					Price:       r.price(p),
+					Available:   active[p.ID] && r.enabled(p) && !p.StockedOut && !stockedOut[false][p.ID],
+					Groups:      r.groups(p),
+				})
+			}
+		}
+	}
+
+	return catalog, errs
+}
+
+// ExternalID returns the SKU of the product on the aggregators, or its SKU when it has no other
+func ExternalID(p product.Product) string {
+	if p.SKUAggregators != "" {
+		return p.SKUAggregators
+	}
+	return p.SKU
+}
+
+type resolver struct {
+	prices     map[uint]float64
+	stockedOut map[bool]map[uint]bool
+	disabled   map[uint]bool
+}
+
+// enabled checks the product is enabled and the channel hasn't disabled it
+func (r resolver) enabled(p product.Product) bool {
+	return p.Enabled && !r.disabled[p.ID]
+}
+
+func (r resolver) price(p product.Product) float64 {
+	if price, ok := r.prices[p.ID]; ok {
+		return price
+	}
+	return p.Price
+}
+
+// groups maps the modifier groups of the product with their options and nested groups, the options without SKU
+// are left out as they can't be chosen on the platform
+func (r resolver) groups(p product.Product) []Group {
+	groups := make([]Group, 0)
+	for _, modifier := range p.Modifiers {
+		minChoices := modifier.MinChoices
+		if modifier.Required && minChoices < 1 {
+			minChoices = 1
+		}
+
+		group := Group{
+			ID:          modifier.ID,
+			Name:        modifier.Name,
+			MinChoices:  minChoices,
+			MaxChoices:  modifier.MaxChoices,
+			FreeChoices: modifier.FreeChoices,
+			Options:     make([]Option, 0),
+		}
+
+		for _, option := range modifier.Options {
+			if option.Product == nil || ExternalID(*option.Product) == "" {
+				continue
+			}
+
+			group.Options = append(group.Options, Option{
+				ProductID:  option.Product.ID,
+				ExternalID: ExternalID(*option.Product),
+				Name:       option.Product.Name,
+				Price:      r.price(*option.Product),
+				Available:  r.enabled(*option.Product) && !option.Product.StockedOut && !r.stockedOut[true][option.Product.ID],
+				Default:    option.Default,
+				Groups:     r.groups(*option.Product),
+			})
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// Delivery is an order received from a platform with the order created for it. The status is the status of the
+// order on the platform, the orders that can't be created are cancelled on the platform with the error.
+type Delivery struct {
+	ID              uint       `json:"id"`
+	Platform        string     `json:"platform" gorm:"uniqueIndex:idx_delivery_external"`
+	ExternalID      string     `json:"external_id" gorm:"uniqueIndex:idx_delivery_external"`
+	ExternalStoreID string     `json:"external_store_id"`
+	ListingID       *uint      `json:"listing_id"`
+	StoreID         *uint      `json:"store_id" gorm:"index"`
+	ChannelID       *uint      `json:"channel_id"`
+	OrderID         *uint      `json:"order_id" gorm:"index"`
+	ClientName      string     `json:"client_name"`
+	Total           float64    `json:"total" gorm:"precision:18;scale:2"`
+	Status          string     `json:"status" gorm:"index" enums:"received,accepted,ready,picked_up,cancelled"`
+	Error           string     `json:"error,omitempty"`
+	Payload         string     `json:"-" gorm:"type:text"`
+	CreatedAt       *time.Time `json:"created_at,omitempty" swaggerignore:"true"`
+	UpdatedAt       *time.Time `json:"updated_at,omitempty" swaggerignore:"true"`
+}
+
+// deliveryTransitions are the statuses a delivery can go to from each status, the cancelled and picked up
+// deliveries are done
+var deliveryTransitions = map[string][]string{
+	DeliveryStatusAccepted: {DeliveryStatusReady, DeliveryStatusCancelled},
+	DeliveryStatusReady:    {DeliveryStatusPickedUp, DeliveryStatusCancelled},
+}
+
+// CanChangeTo checks the delivery can go from its status to the given one
+func (d Delivery) CanChangeTo(status string) bool {
+	for _, next := range deliveryTransitions[d.Status] {
+		if next == status {
+			return true
+		}
+	}
+	return false
+}
+
+// PlatformOrder is an order placed on a platform as the adapters parse it from the webhooks
+type PlatformOrder struct {
+	ExternalID      string         `json:"external_id"`
+	ExternalStoreID string         `json:"external_store_id"`
+	ClientName      string         `json:"client_name"`
+	Comments        string         `json:"comments"`
+	Total           float64        `json:"total"`
+	Items           []PlatformItem `json:"items"`
+}
+
+// PlatformItem is an item or a modifier of a platform order. The external id is the one pushed on the catalog
+// sync and the group is the modifier group the option was chosen on, when the platform reports it.
+type PlatformItem struct {
+	ExternalID string         `json:"external_id"`
+	Name       string         `json:"name"`
+	GroupID    string         `json:"group_id"`
+	Quantity   int            `json:"quantity"`
+	Price      float64        `json:"price"`
+	Comments   string         `json:"comments"`
+	Modifiers  []PlatformItem `json:"modifiers"`
+}
+
+// ProductIndex resolves the products of the items of the platform orders by their external id or their name
+type ProductIndex map[string]uint
+
+func productKey(kind, value string) string {
+	return kind + "|" + strings.ToLower(strings.TrimSpace(value))
+}
+
+// Add indexes the product by the external id and the name given, the first product indexed for a key wins
+func (i ProductIndex) Add(productID uint, externalID, name string) {
+	for _, key := range []string{productKey("id", externalID), productKey("name", name)} {
+		if _, ok := i[key]; !ok && !strings.HasSuffix(key, "|") {
+			i[key] = productID
+		}
+	}
+}
+
+// Resolve returns the product of the item, by its external id before its name
+func (i ProductIndex) Resolve(item PlatformItem) (uint, bool) {
+	if productID, ok := i[productKey("id", item.ExternalID)]; ok && item.ExternalID != "" {
+		return productID, true
+	}
+	productID, ok := i[productKey("name", item.Name)]
+	return productID, ok && item.Name != ""
+}
+
+// ExternalIDs returns the external ids of the items and modifiers of the order
+func (o PlatformOrder) ExternalIDs() []string {
+	ids := make([]string, 0)
+	var add func(items []PlatformItem)
+	add = func(items []PlatformItem) {
+		for _, item := range items {
+			if item.ExternalID != "" {
+				ids = append(ids, item.ExternalID)
+			}
+			add(item.Modifiers)
+		}
+	}
+	add(o.Items)
+	return ids
+}
+
+// ToOrder maps the platform order to an order of the store and channel of the listing. The orders have an item
+// per unit, so the items are repeated by their quantity, and the modifiers of the nested groups get the option
+// they were chosen on. The items without product are returned as a single error.
+func (o PlatformOrder) ToOrder(brandID *uint, listing Listing, products ProductIndex) (order.Order, error) {
+	if len(o.Items) == 0 {
+		return order.Order{}, fmt.Errorf(ErrorDeliveryEmpty)
+	}
+
+	errs := ""
+	var modifiers func(items []PlatformItem, parentProductID *uint) []order.OrderModifier
+	modifiers = func(items []PlatformItem, parentProductID *uint) []order.OrderModifier {
+		result := make([]order.OrderModifier, 0)
+		for _, item := range items {
+			productID, ok := products.Resolve(item)
+			if !ok {
+				errs += fmt.Sprintf(ErrorDeliveryItemNotFound, item.Name, item.ExternalID)
+				continue
+			}
+
+			modifier := order.OrderModifier{ProductID: &productID, ParentProductID: parentProductID, Comments: item.Comments}
+			if groupID, err := strconv.ParseUint(item.GroupID, 10, 64); err == nil {
+				modifierID := uint(groupID)
+				modifier.ModifierID = &modifierID
+			}
+			for n := 0; n < quantity(item); n++ {
+				result = append(result, modifier)
+			}
+			result = append(result, modifiers(item.Modifiers, &productID)...)
+		}
+		return result
+	}
+
+	items := make([]order.OrderItem, 0)
+	for _, item := range o.Items {
+		productID, ok := products.Resolve(item)
+		if !ok {
+			errs += fmt.Sprintf(ErrorDeliveryItemNotFound, item.Name, item.ExternalID)
+			continue
+		}
+
+		itemModifiers := modifiers(item.Modifiers, nil)
+		for n := 0; n < quantity(item); n++ {
+			items = append(items, order.OrderItem{
+				ProductID: &productID,
+				Comments:  item.Comments,
+				Modifiers: append([]order.OrderModifier{}, itemModifiers...),
+			})
+		}
+	}
+
+	if errs != "" {
+		return order.Order{}, fmt.Errorf(errs)
+	}
+
+	return order.Order{
+		BrandID:      brandID,
+		StoreID:      listing.StoreID,
+		ChannelID:    listing.ChannelID,
+		ClientName:   o.ClientName,
+		Comments:     o.Comments,
+		ExternalCode: o.ExternalID,
+		Items:        items,
+	}, nil
+}
+
+func quantity(item PlatformItem) int {
+	if item.Quantity < 1 {
+		return 1
+	}
+	return item.Quantity
+}
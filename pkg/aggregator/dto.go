@@ -0,0 +1,7 @@
+package aggregator
+
+// DeliveryStatusRequest is the status of a delivery to push to its platform, the reason is given on cancellations
+type DeliveryStatusRequest struct {
+	Status string `json:"status" binding:"required" enums:"ready,picked_up,cancelled"`
+	Reason string `json:"reason"`
+}
@@ -0,0 +1,345 @@
+package aggregator
+
+import (
+	"net/http"
+
+	"github.com/BacoFoods/menu/pkg/shared"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	LogHandler = "pkg/aggregator/handler"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service}
+}
+
+// FindListings to handle a request to find the aggregator listings
+// @Tags Aggregator
+// @Summary To find aggregator listings
+// @Description To find the stores and channels listed on the delivery platforms
+// @Param store_id query string false "store id"
+// @Param platform query string false "platform" Enums(rappi, didi)
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=[]Listing}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /aggregator/listing [get]
+func (h *Handler) FindListings(c *gin.Context) {
+	filter := make(map[string]string)
+	for _, key := range []string{"store_id", "platform"} {
+		if value := c.Query(key); value != "" {
+			filter[key] = value
+		}
+	}
+
+	listings, err := h.service.FindListings(filter)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(listings))
+}
+
+// CreateListing to handle a request to create an aggregator listing
+// @Tags Aggregator
+// @Summary To create an aggregator listing
+// @Description To list a store on a delivery platform, its menu is the menu of the channel with the prices, stock and dayparts of the channel and store. The external id is the store id on the platform.
+// @Param listing body Listing true "listing"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=Listing}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /aggregator/listing [post]
+func (h *Handler) CreateListing(c *gin.Context) {
+	var body Listing
+	if err := c.ShouldBindJSON(&body); err != nil {
+		shared.LogWarn("warning binding request body", LogHandler, "CreateListing", err, body)
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorAggregatorBadRequest))
+		return
+	}
+
+	body.ID = 0
+	listing, err := h.service.CreateListing(&body)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(listing))
+}
+
+// UpdateListing to handle a request to update an aggregator listing
+// @Tags Aggregator
+// @Summary To update an aggregator listing
+// @Description To update an aggregator listing, a disabled listing isn't synced
+// @Param id path string true "listing id"
+// @Param listing body Listing true "listing"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=Listing}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /aggregator/listing/{id} [patch]
+func (h *Handler) UpdateListing(c *gin.Context) {
+	listing, err := h.service.GetListing(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	id := listing.ID
+	if err := c.ShouldBindJSON(listing); err != nil {
+		shared.LogWarn("warning binding request body", LogHandler, "UpdateListing", err, c.Param("id"))
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorAggregatorBadRequest))
+		return
+	}
+
+	listing.ID = id
+	listing, err = h.service.UpdateListing(listing)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(listing))
+}
+
+// DeleteListing to handle a request to delete an aggregator listing
+// @Tags Aggregator
+// @Summary To delete an aggregator listing
+// @Description To delete an aggregator listing, the menu isn't removed from the platform
+// @Param id path string true "listing id"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=Listing}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /aggregator/listing/{id} [delete]
+func (h *Handler) DeleteListing(c *gin.Context) {
+	listing, err := h.service.DeleteListing(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(listing))
+}
+
+// Sync to handle a request to sync the menu of an aggregator listing
+// @Tags Aggregator
+// @Summary To sync an aggregator listing
+// @Description To push the menu of the listing to its platform right away: products, prices, modifier groups and availability. The sync is logged with the items that couldn't be synced.
+// @Param id path string true "listing id"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=Sync}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /aggregator/listing/{id}/sync [post]
+func (h *Handler) Sync(c *gin.Context) {
+	sync, err := h.service.Sync(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(sync))
+}
+
+// FindSyncs to handle a request to find the aggregator sync log
+// @Tags Aggregator
+// @Summary To find aggregator syncs
+// @Description To find the most recent syncs of the listings, without their item errors
+// @Param listing_id query string false "listing id"
+// @Param store_id query string false "store id"
+// @Param platform query string false "platform" Enums(rappi, didi)
+// @Param status query string false "status" Enums(success, partial, failed)
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=[]Sync}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /aggregator/sync [get]
+func (h *Handler) FindSyncs(c *gin.Context) {
+	filter := make(map[string]string)
+	for _, key := range []string{"listing_id", "store_id", "platform", "status"} {
+		if value := c.Query(key); value != "" {
+			filter[key] = value
+		}
+	}
+
+	syncs, err := h.service.FindSyncs(filter)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(syncs))
+}
+
+// GetSync to handle a request to get an aggregator sync
+// @Tags Aggregator
+// @Summary To get an aggregator sync
+// @Description To get a sync with the items that couldn't be listed or the platform rejected
+// @Param id path string true "sync id"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=Sync}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /aggregator/sync/{id} [get]
+func (h *Handler) GetSync(c *gin.Context) {
+	sync, err := h.service.GetSync(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(sync))
+}
+
+// ReceiveOrder to handle an order webhook of a platform
+// @Tags Aggregator
+// @Summary To receive an aggregator order
+// @Description To receive an order placed on Rappi or Didi, signed with the webhook secret of the platform. The order is created on the store and channel of the listing of the platform store, sent to the kitchen and accepted on the platform. The orders that can't be created are cancelled on the platform. A webhook received again returns the delivery of its order.
+// @Param platform path string true "platform" Enums(rappi, didi)
+// @Accept json
+// @Produce json
+// @Success 200 {object} object{status=string,data=Delivery}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /public/aggregator/webhook/{platform}/order [post]
+func (h *Handler) ReceiveOrder(c *gin.Context) {
+	body, err := c.GetRawData()
+	if err != nil {
+		shared.LogWarn("warning reading request body", LogHandler, "ReceiveOrder", err, c.Param("platform"))
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorAggregatorBadRequest))
+		return
+	}
+
+	delivery, err := h.service.ReceiveOrder(c.Param("platform"), c.Request.Header, body)
+	if err != nil {
+		switch err.Error() {
+		case ErrorWebhookSignature:
+			c.JSON(http.StatusUnauthorized, shared.ErrorResponse(err.Error()))
+		case ErrorWebhookPayload:
+			c.JSON(http.StatusBadRequest, shared.ErrorResponse(err.Error()))
+		default:
+			c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(delivery))
+}
+
+// FindDeliveries to handle a request to find the aggregator deliveries
+// @Tags Aggregator
+// @Summary To find aggregator deliveries
+// @Description To find the most recent orders received from the platforms with their status on the platform
+// @Param store_id query string false "store id"
+// @Param platform query string false "platform" Enums(rappi, didi)
+// @Param status query string false "status" Enums(received, accepted, ready, picked_up, cancelled)
+// @Param order_id query string false "order id"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=[]Delivery}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /aggregator/delivery [get]
+func (h *Handler) FindDeliveries(c *gin.Context) {
+	filter := make(map[string]string)
+	for _, key := range []string{"store_id", "platform", "status", "order_id"} {
+		if value := c.Query(key); value != "" {
+			filter[key] = value
+		}
+	}
+
+	deliveries, err := h.service.FindDeliveries(filter)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(deliveries))
+}
+
+// GetDelivery to handle a request to get an aggregator delivery
+// @Tags Aggregator
+// @Summary To get an aggregator delivery
+// @Description To get an order received from a platform
+// @Param id path string true "delivery id"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=Delivery}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /aggregator/delivery/{id} [get]
+func (h *Handler) GetDelivery(c *gin.Context) {
+	delivery, err := h.service.GetDelivery(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(delivery))
+}
+
+// UpdateDeliveryStatus to handle a request to update the status of an aggregator delivery
+// @Tags Aggregator
+// @Summary To update the status of an aggregator delivery
+// @Description To push the status of an order received from a platform: an accepted order gets ready, then picked up, and it can be cancelled until it's picked up. The status is saved once the platform took it.
+// @Param id path string true "delivery id"
+// @Param status body DeliveryStatusRequest true "status"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=Delivery}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /aggregator/delivery/{id}/status [patch]
+func (h *Handler) UpdateDeliveryStatus(c *gin.Context) {
+	var body DeliveryStatusRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		shared.LogWarn("warning binding request body", LogHandler, "UpdateDeliveryStatus", err, c.Param("id"))
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorAggregatorBadRequest))
+		return
+	}
+
+	delivery, err := h.service.UpdateDeliveryStatus(c.Param("id"), body.Status, body.Reason)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(delivery))
+}
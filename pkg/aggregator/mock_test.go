@@ -0,0 +1,192 @@
+package aggregator
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+// MockServer is a fake Rappi and Didi menu and order API for the specs. It checks the token, keeps the last menu
+// pushed to every store and rejects the items without price and the items of Reject with their reason. The
+// order statuses pushed are kept by order.
+type MockServer struct {
+	*httptest.Server
+	Token  string
+	Reject map[string]string
+
+	mu       sync.Mutex
+	rappi    map[string]RappiMenu
+	didi     map[string]DidiMenu
+	statuses map[string][]string
+}
+
+func NewMockServer(token string) *MockServer {
+	m := &MockServer{Token: token, Reject: make(map[string]string), rappi: make(map[string]RappiMenu),
+		didi: make(map[string]DidiMenu), statuses: make(map[string][]string)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(RappiMenuPath, m.rappiMenu)
+	mux.HandleFunc(RappiOrderPath, m.rappiOrder)
+	mux.HandleFunc(DidiMenuPath, m.didiMenu)
+	mux.HandleFunc(DidiOrderPath, m.didiOrder)
+	m.Server = httptest.NewServer(mux)
+	return m
+}
+
+// OrderStatuses returns the statuses pushed for the order on both platforms, in order
+func (m *MockServer) OrderStatuses(orderID string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string{}, m.statuses[orderID]...)
+}
+
+func (m *MockServer) pushStatus(orderID string, actions map[string]string, action string) bool {
+	for status, statusAction := range actions {
+		if statusAction == action {
+			m.mu.Lock()
+			m.statuses[orderID] = append(m.statuses[orderID], status)
+			m.mu.Unlock()
+			return true
+		}
+	}
+	return false
+}
+
+// RappiMenu returns the last menu pushed to the Rappi store
+func (m *MockServer) RappiMenu(storeID string) (RappiMenu, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	menu, ok := m.rappi[storeID]
+	return menu, ok
+}
+
+// DidiMenu returns the last menu pushed to the Didi shop
+func (m *MockServer) DidiMenu(shopID string) (DidiMenu, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	menu, ok := m.didi[shopID]
+	return menu, ok
+}
+
+func (m *MockServer) rappiMenu(w http.ResponseWriter, r *http.Request) {
+	response := RappiResponse{}
+	if r.Header.Get("x-authorization") != "bearer "+m.Token {
+		response.Message = "invalid token"
+		writeJSON(w, http.StatusUnauthorized, response)
+		return
+	}
+
+	var menu RappiMenu
+	if err := json.NewDecoder(r.Body).Decode(&menu); err != nil || menu.StoreID == "" {
+		response.Message = "invalid menu"
+		writeJSON(w, http.StatusBadRequest, response)
+		return
+	}
+
+	for _, item := range menu.Items {
+		if reason := m.rejects(item.SKU, item.Price > 0); reason != "" {
+			response.Errors = append(response.Errors, struct {
+				SKU     string `json:"sku"`
+				Message string `json:"message"`
+			}{item.SKU, reason})
+		}
+	}
+
+	m.mu.Lock()
+	m.rappi[menu.StoreID] = menu
+	m.mu.Unlock()
+
+	response.Message = "ok"
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (m *MockServer) didiMenu(w http.ResponseWriter, r *http.Request) {
+	response := DidiResponse{}
+	if r.Header.Get("Authorization") != "Bearer "+m.Token {
+		response.Errno, response.Errmsg = 10001, "invalid token"
+		writeJSON(w, http.StatusOK, response)
+		return
+	}
+
+	var menu DidiMenu
+	if err := json.NewDecoder(r.Body).Decode(&menu); err != nil || menu.ShopID == "" {
+		response.Errno, response.Errmsg = 10002, "invalid menu"
+		writeJSON(w, http.StatusOK, response)
+		return
+	}
+
+	for _, category := range menu.Categories {
+		for _, item := range category.Items {
+			if reason := m.rejects(item.ItemID, item.Price > 0); reason != "" {
+				response.Data.FailedItems = append(response.Data.FailedItems, struct {
+					ItemID string `json:"item_id"`
+					Reason string `json:"reason"`
+				}{item.ItemID, reason})
+			}
+		}
+	}
+
+	m.mu.Lock()
+	m.didi[menu.ShopID] = menu
+	m.mu.Unlock()
+
+	response.Errmsg = "ok"
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (m *MockServer) rappiOrder(w http.ResponseWriter, r *http.Request) {
+	response := RappiResponse{}
+	if r.Header.Get("x-authorization") != "bearer "+m.Token {
+		response.Message = "invalid token"
+		writeJSON(w, http.StatusUnauthorized, response)
+		return
+	}
+
+	path := strings.Split(strings.TrimPrefix(r.URL.Path, RappiOrderPath), "/")
+	if len(path) != 2 || !m.pushStatus(path[0], rappiOrderActions, path[1]) {
+		response.Message = "not found"
+		writeJSON(w, http.StatusNotFound, response)
+		return
+	}
+
+	response.Message = "ok"
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (m *MockServer) didiOrder(w http.ResponseWriter, r *http.Request) {
+	response := DidiResponse{}
+	if r.Header.Get("Authorization") != "Bearer "+m.Token {
+		response.Errno, response.Errmsg = 10001, "invalid token"
+		writeJSON(w, http.StatusOK, response)
+		return
+	}
+
+	var status DidiOrderStatus
+	if err := json.NewDecoder(r.Body).Decode(&status); err != nil || status.OrderID == "" ||
+		!m.pushStatus(status.OrderID, didiOrderActions, strings.TrimPrefix(r.URL.Path, DidiOrderPath)) {
+		response.Errno, response.Errmsg = 10002, "invalid order"
+		writeJSON(w, http.StatusOK, response)
+		return
+	}
+
+	response.Errmsg = "ok"
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (m *MockServer) rejects(itemID string, priced bool) string {
+	if reason, ok := m.Reject[itemID]; ok {
+		return reason
+	}
+	if !priced {
+		return "price must be greater than zero"
+	}
+	return ""
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,28 @@
+package aggregator
+
+import "github.com/BacoFoods/menu/pkg/shared"
+
+type Routes struct {
+	handler *Handler
+}
+
+func NewRoutes(handler *Handler) Routes {
+	return Routes{handler}
+}
+
+func (r Routes) RegisterRoutes(private, public *shared.CustomRoutes) {
+	private.GET("/aggregator/listing", r.handler.FindListings)
+	private.POST("/aggregator/listing", r.handler.CreateListing)
+	private.PATCH("/aggregator/listing/:id", r.handler.UpdateListing)
+	private.DELETE("/aggregator/listing/:id", r.handler.DeleteListing)
+	private.POST("/aggregator/listing/:id/sync", r.handler.Sync)
+
+	private.GET("/aggregator/sync", r.handler.FindSyncs)
+	private.GET("/aggregator/sync/:id", r.handler.GetSync)
+
+	// Orders placed on the platforms, the webhooks are signed by the platforms
+	public.POST("/aggregator/webhook/:platform/order", r.handler.ReceiveOrder)
+	private.GET("/aggregator/delivery", r.handler.FindDeliveries)
+	private.GET("/aggregator/delivery/:id", r.handler.GetDelivery)
+	private.PATCH("/aggregator/delivery/:id/status", r.handler.UpdateDeliveryStatus)
+}
@@ -0,0 +1,512 @@
+package aggregator
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/BacoFoods/menu/internal"
+	"github.com/BacoFoods/menu/pkg/availability"
+	"github.com/BacoFoods/menu/pkg/channel"
+	"github.com/BacoFoods/menu/pkg/menu"
+	"github.com/BacoFoods/menu/pkg/order"
+	"github.com/BacoFoods/menu/pkg/product"
+	"github.com/BacoFoods/menu/pkg/shared"
+	"github.com/BacoFoods/menu/pkg/siesa"
+	"github.com/BacoFoods/menu/pkg/store"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	LogService = "pkg/aggregator/service"
+
+	syncLockKey = "menu:aggregator:sync"
+)
+
+type Service interface {
+	FindListings(filter map[string]string) ([]Listing, error)
+	GetListing(listingID string) (*Listing, error)
+	CreateListing(*Listing) (*Listing, error)
+	UpdateListing(*Listing) (*Listing, error)
+	DeleteListing(listingID string) (*Listing, error)
+
+	Sync(listingID string) (*Sync, error)
+	FindSyncs(filter map[string]string) ([]Sync, error)
+	GetSync(syncID string) (*Sync, error)
+	RunScheduler(ctx context.Context)
+
+	ReceiveOrder(platform string, header http.Header, body []byte) (*Delivery, error)
+	FindDeliveries(filter map[string]string) ([]Delivery, error)
+	GetDelivery(deliveryID string) (*Delivery, error)
+	UpdateDeliveryStatus(deliveryID, status, reason string) (*Delivery, error)
+}
+
+// menuSrv resolves the menus and overriders of a store or channel as they are served
+type menuSrv interface {
+	FindByPlace(place, placeID string) ([]menu.Menu, error)
+	PlaceOverriders(place, placeID string) ([]product.Overrider, error)
+}
+
+// productSrv finds the products of the items of the platform orders
+type productSrv interface {
+	Find(filter map[string]string) ([]product.Product, error)
+	GetByIDs(productIDs []string) ([]product.Product, error)
+}
+
+// referenceSrv finds the product references of the platforms
+type referenceSrv interface {
+	FindReferences(filter map[string]string) ([]siesa.ProductReference, error)
+}
+
+// orderSrv creates the orders of the platforms, sends them to the kitchen and cancels them
+type orderSrv interface {
+	Create(idempotencyKey string, order *order.Order, ctx context.Context) (*order.Order, error)
+	UpdateStatus(orderID, status string) (*order.Order, error)
+}
+
+type service struct {
+	repository Repository
+	menus      menuSrv
+	stores     store.Repository
+	channels   channel.Repository
+	products   productSrv
+	references referenceSrv
+	orders     orderSrv
+	adapters   map[string]Adapter
+	redis      *redis.Client
+	config     internal.AggregatorConfig
+}
+
+func NewService(repository Repository,
+	menus menuSrv,
+	stores store.Repository,
+	channels channel.Repository,
+	products productSrv,
+	references referenceSrv,
+	orders orderSrv,
+	adapters map[string]Adapter,
+	redis *redis.Client,
+	config internal.AggregatorConfig) service {
+	return service{repository, menus, stores, channels, products, references, orders, adapters, redis, config}
+}
+
+func (s service) FindListings(filter map[string]string) ([]Listing, error) {
+	return s.repository.FindListings(filter)
+}
+
+func (s service) GetListing(listingID string) (*Listing, error) {
+	return s.repository.GetListing(listingID)
+}
+
+func (s service) CreateListing(listing *Listing) (*Listing, error) {
+	if err := s.validateListing(*listing); err != nil {
+		return nil, err
+	}
+
+	listing.LastSyncAt, listing.LastStatus = nil, ""
+	return s.repository.CreateListing(listing)
+}
+
+func (s service) UpdateListing(listing *Listing) (*Listing, error) {
+	current, err := s.repository.GetListing(fmt.Sprint(listing.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.validateListing(*listing); err != nil {
+		return nil, err
+	}
+
+	listing.LastSyncAt, listing.LastStatus, listing.CreatedAt = current.LastSyncAt, current.LastStatus, current.CreatedAt
+	return s.repository.UpdateListing(listing)
+}
+
+func (s service) DeleteListing(listingID string) (*Listing, error) {
+	return s.repository.DeleteListing(listingID)
+}
+
+// validateListing checks the platform of the listing and that its store and channel are of the same brand
+func (s service) validateListing(listing Listing) error {
+	if err := listing.Validate(); err != nil {
+		return err
+	}
+
+	st, err := s.stores.Get(fmt.Sprint(*listing.StoreID))
+	if err != nil {
+		return err
+	}
+
+	ch, err := s.channels.Get(fmt.Sprint(*listing.ChannelID))
+	if err != nil {
+		return err
+	}
+
+	if st.BrandID == nil || ch.BrandID == nil || *st.BrandID != *ch.BrandID {
+		return fmt.Errorf(ErrorListingWrongBrand)
+	}
+
+	return nil
+}
+
+// Sync pushes the menu of an enabled listing to its platform right away
+func (s service) Sync(listingID string) (*Sync, error) {
+	listing, err := s.repository.GetListing(listingID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !listing.Enabled {
+		return nil, fmt.Errorf(ErrorListingDisabled)
+	}
+
+	return s.sync(*listing, SyncTriggerManual)
+}
+
+func (s service) FindSyncs(filter map[string]string) ([]Sync, error) {
+	return s.repository.FindSyncs(filter)
+}
+
+func (s service) GetSync(syncID string) (*Sync, error) {
+	return s.repository.GetSync(syncID)
+}
+
+// sync resolves the menu of the listing and pushes it to the platform. The sync is logged whatever its result,
+// with the items that can't be listed and the items the platform rejected.
+func (s service) sync(listing Listing, trigger string) (*Sync, error) {
+	sync := Sync{
+		ListingID: listing.ID,
+		Platform:  listing.Platform,
+		StoreID:   listing.StoreID,
+		ChannelID: listing.ChannelID,
+		Trigger:   trigger,
+		Status:    SyncStatusSuccess,
+		Errors:    make([]SyncError, 0),
+		StartedAt: time.Now(),
+	}
+
+	catalog, errs, err := s.resolve(listing)
+	adapter, configured := s.adapters[listing.Platform]
+	switch {
+	case err != nil:
+		sync.Status, sync.Error = SyncStatusFailed, err.Error()
+	case !configured:
+		sync.Status, sync.Error = SyncStatusFailed, fmt.Sprintf(ErrorSyncPlatformNotConfigured, listing.Platform)
+	default:
+		sync.Items, sync.Available = catalog.Count()
+		sync.Errors = append(sync.Errors, errs...)
+
+		rejected, err := adapter.PushCatalog(listing.ExternalID, catalog)
+		if err != nil {
+			shared.LogError("error pushing catalog", LogService, "sync", err, listing.ID, listing.Platform)
+			sync.Status, sync.Error = SyncStatusFailed, fmt.Sprintf(ErrorSyncPushing, listing.Platform, err.Error())
+			break
+		}
+		sync.Errors = append(sync.Errors, rejected...)
+	}
+
+	sync.Failed = len(sync.Errors)
+	if sync.Status == SyncStatusSuccess && sync.Failed > 0 {
+		sync.Status = SyncStatusPartial
+	}
+	sync.FinishedAt = time.Now()
+
+	return s.repository.CreateSync(&sync)
+}
+
+// resolve builds the catalog of the listing from the menus of its channel and store as they are served
+func (s service) resolve(listing Listing) (Catalog, []SyncError, error) {
+	storeID, channelID := fmt.Sprint(*listing.StoreID), fmt.Sprint(*listing.ChannelID)
+	storePlace, channelPlace := string(availability.PlaceStore), string(availability.PlaceChannel)
+
+	var menus Menus
+	var err error
+	if menus.Channel, err = s.menus.FindByPlace(channelPlace, channelID); err != nil {
+		return Catalog{}, nil, fmt.Errorf(ErrorSyncResolvingMenu)
+	}
+	if menus.Store, err = s.menus.FindByPlace(storePlace, storeID); err != nil {
+		return Catalog{}, nil, fmt.Errorf(ErrorSyncResolvingMenu)
+	}
+	if menus.ChannelOverriders, err = s.menus.PlaceOverriders(channelPlace, channelID); err != nil {
+		return Catalog{}, nil, fmt.Errorf(ErrorSyncResolvingMenu)
+	}
+	if menus.StoreOverriders, err = s.menus.PlaceOverriders(storePlace, storeID); err != nil {
+		return Catalog{}, nil, fmt.Errorf(ErrorSyncResolvingMenu)
+	}
+
+	catalog, errs := Resolve(menus)
+	return catalog, errs, nil
+}
+
+// RunScheduler syncs the enabled listings on every interval until the context is done, the replicas take turns
+// through a redis lock
+func (s service) RunScheduler(ctx context.Context) {
+	if !s.config.SyncEnabled {
+		return
+	}
+
+	ticker := time.NewTicker(s.config.SyncInterval)
+	defer ticker.Stop()
+
+	for {
+		s.syncAll()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s service) syncAll() {
+	mu := internal.DistMutexWithTTL(s.redis, syncLockKey, s.config.SyncInterval)
+	locked, err := mu.TryLock()
+	if err != nil {
+		shared.LogError("error locking aggregator sync", LogService, "syncAll", err)
+		return
+	}
+	if !locked {
+		return // another replica is syncing
+	}
+	defer mu.Unlock()
+
+	listings, err := s.repository.FindListings(map[string]string{"enabled": "true"})
+	if err != nil {
+		return
+	}
+
+	for _, listing := range listings {
+		if _, err := s.sync(listing, SyncTriggerScheduled); err != nil {
+			shared.LogError("error logging aggregator sync", LogService, "syncAll", err, listing.ID)
+		}
+	}
+}
+
+// ReceiveOrder takes an order webhook of a platform. The order is created on the store and channel of the listing
+// of the platform store and sent to the kitchen, then accepted on the platform. The orders that can't be created
+// are cancelled on the platform with the error. A webhook received again returns the delivery of its order.
+func (s service) ReceiveOrder(platform string, header http.Header, body []byte) (*Delivery, error) {
+	adapter, configured := s.adapters[platform]
+	if !configured {
+		return nil, fmt.Errorf(ErrorSyncPlatformNotConfigured, platform)
+	}
+
+	platformOrder, err := adapter.ParseOrder(header, body)
+	if err != nil {
+		return nil, err
+	}
+
+	received, err := s.repository.GetDeliveryByExternalID(platform, platformOrder.ExternalID)
+	if err != nil {
+		return nil, err
+	}
+	if received != nil {
+		return received, nil
+	}
+
+	delivery := &Delivery{
+		Platform:        platform,
+		ExternalID:      platformOrder.ExternalID,
+		ExternalStoreID: platformOrder.ExternalStoreID,
+		ClientName:      platformOrder.ClientName,
+		Total:           platformOrder.Total,
+		Status:          DeliveryStatusReceived,
+		Payload:         string(body),
+	}
+	if _, err := s.repository.CreateDelivery(delivery); err != nil {
+		// the same webhook received at once
+		if received, _ := s.repository.GetDeliveryByExternalID(platform, platformOrder.ExternalID); received != nil {
+			return received, nil
+		}
+		return nil, err
+	}
+
+	reason := ""
+	newOrder, err := s.createOrder(platform, *platformOrder, delivery)
+	if err != nil {
+		shared.LogWarn("warning creating aggregator order", LogService, "ReceiveOrder", err, platform, platformOrder.ExternalID)
+		delivery.Status, delivery.Error, reason = DeliveryStatusCancelled, err.Error(), err.Error()
+	} else {
+		delivery.Status, delivery.OrderID = DeliveryStatusAccepted, &newOrder.ID
+	}
+
+	if err := adapter.UpdateOrderStatus(delivery.ExternalStoreID, delivery.ExternalID, delivery.Status, reason); err != nil {
+		shared.LogError("error pushing order status", LogService, "ReceiveOrder", err, platform, delivery.ExternalID, delivery.Status)
+		delivery.Error = strings.TrimSpace(delivery.Error + " " + fmt.Sprintf(ErrorDeliveryPushingStatus, platform, err.Error()))
+	}
+
+	return s.repository.UpdateDelivery(delivery)
+}
+
+// createOrder creates the order of the platform on the store and channel of the enabled listing of its store
+func (s service) createOrder(platform string, platformOrder PlatformOrder, delivery *Delivery) (*order.Order, error) {
+	listings, err := s.repository.FindListings(map[string]string{
+		"platform": platform, "external_id": platformOrder.ExternalStoreID, "enabled": "true",
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(listings) == 0 {
+		return nil, fmt.Errorf(ErrorDeliveryListingNotFound, platform, platformOrder.ExternalStoreID)
+	}
+
+	listing := listings[0]
+	delivery.ListingID, delivery.StoreID, delivery.ChannelID = &listing.ID, listing.StoreID, listing.ChannelID
+
+	st, err := s.stores.Get(fmt.Sprint(*listing.StoreID))
+	if err != nil {
+		return nil, err
+	}
+
+	products, err := s.productIndex(platform, listing, st.BrandID, platformOrder)
+	if err != nil {
+		return nil, err
+	}
+
+	newOrder, err := platformOrder.ToOrder(st.BrandID, listing, products)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.WithValue(context.Background(), "account_name", platform)
+	ctx = context.WithValue(ctx, "channel_id", fmt.Sprint(*listing.ChannelID))
+	ctx = context.WithValue(ctx, "store_id", fmt.Sprint(*listing.StoreID))
+	if st.BrandID != nil {
+		ctx = context.WithValue(ctx, "brand_id", fmt.Sprint(*st.BrandID))
+	}
+
+	return s.orders.Create(fmt.Sprintf("%s:%s", platform, platformOrder.ExternalID), &newOrder, ctx)
+}
+
+// productIndex indexes the products of the items of the order. The product references of the platform to
+// products of the brand, for any channel or the channel of the listing, come first, then the products of the
+// brand by their aggregators SKU and by their SKU, as the catalog sync lists them.
+func (s service) productIndex(platform string, listing Listing, brandID *uint, platformOrder PlatformOrder) (ProductIndex, error) {
+	index := make(ProductIndex)
+	if brandID == nil {
+		return index, nil
+	}
+
+	references, err := s.brandReferences(platform, *brandID)
+	if err != nil {
+		return nil, err
+	}
+	for _, channelReferences := range []bool{true, false} {
+		for _, reference := range references {
+			if reference.ProductID == nil || (reference.ChannelID != nil) != channelReferences {
+				continue
+			}
+			if reference.ChannelID != nil && *reference.ChannelID != *listing.ChannelID {
+				continue
+			}
+			index.Add(*reference.ProductID, reference.SKU, reference.Name)
+		}
+	}
+
+	for _, externalID := range platformOrder.ExternalIDs() {
+		if _, ok := index.Resolve(PlatformItem{ExternalID: externalID}); ok {
+			continue
+		}
+
+		for _, column := range []string{"sku_aggregators", "sku"} {
+			products, err := s.products.Find(map[string]string{"brand_id": fmt.Sprint(*brandID), column: externalID})
+			if err != nil {
+				shared.LogError("error finding order products", LogService, "productIndex", err, column, externalID)
+				return nil, fmt.Errorf(ErrorDeliveryCreating)
+			}
+			if len(products) > 0 {
+				index.Add(products[0].ID, externalID, "")
+				break
+			}
+		}
+	}
+
+	return index, nil
+}
+
+// brandReferences returns the product references of the platform whose products belong to the brand, the
+// references are shared by all the brands
+func (s service) brandReferences(platform string, brandID uint) ([]siesa.ProductReference, error) {
+	references, err := s.references.FindReferences(map[string]string{"platform": platform})
+	if err != nil {
+		return nil, err
+	}
+
+	productIDs := make([]string, 0)
+	for _, reference := range references {
+		if reference.ProductID != nil {
+			productIDs = append(productIDs, fmt.Sprint(*reference.ProductID))
+		}
+	}
+	if len(productIDs) == 0 {
+		return nil, nil
+	}
+
+	products, err := s.products.GetByIDs(productIDs)
+	if err != nil {
+		shared.LogError("error finding referenced products", LogService, "brandReferences", err, platform, brandID)
+		return nil, fmt.Errorf(ErrorDeliveryCreating)
+	}
+
+	brandProducts := make(map[uint]bool)
+	for _, p := range products {
+		if p.BrandID != nil && *p.BrandID == brandID {
+			brandProducts[p.ID] = true
+		}
+	}
+
+	brandReferences := make([]siesa.ProductReference, 0)
+	for _, reference := range references {
+		if reference.ProductID != nil && brandProducts[*reference.ProductID] {
+			brandReferences = append(brandReferences, reference)
+		}
+	}
+
+	return brandReferences, nil
+}
+
+func (s service) FindDeliveries(filter map[string]string) ([]Delivery, error) {
+	return s.repository.FindDeliveries(filter)
+}
+
+func (s service) GetDelivery(deliveryID string) (*Delivery, error) {
+	return s.repository.GetDelivery(deliveryID)
+}
+
+// UpdateDeliveryStatus pushes the status of a delivery to its platform and saves it once the platform took it. A
+// cancelled delivery cancels its order too, so the kitchen drops it and its units go back to the stock.
+func (s service) UpdateDeliveryStatus(deliveryID, status, reason string) (*Delivery, error) {
+	delivery, err := s.repository.GetDelivery(deliveryID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !delivery.CanChangeTo(status) {
+		return nil, fmt.Errorf(ErrorDeliveryInvalidStatus, delivery.Status, status)
+	}
+
+	adapter, configured := s.adapters[delivery.Platform]
+	if !configured {
+		return nil, fmt.Errorf(ErrorSyncPlatformNotConfigured, delivery.Platform)
+	}
+
+	if err := adapter.UpdateOrderStatus(delivery.ExternalStoreID, delivery.ExternalID, status, reason); err != nil {
+		shared.LogError("error pushing order status", LogService, "UpdateDeliveryStatus", err, delivery.ID, status)
+		return nil, fmt.Errorf(ErrorDeliveryPushingStatus, delivery.Platform, err.Error())
+	}
+
+	delivery.Status = status
+	if status == DeliveryStatusCancelled {
+		delivery.Error = reason
+		if delivery.OrderID != nil {
+			if _, err := s.orders.UpdateStatus(fmt.Sprint(*delivery.OrderID), order.OrderStatusCanceled); err != nil {
+				shared.LogError("error canceling delivery order", LogService, "UpdateDeliveryStatus", err, delivery.ID, *delivery.OrderID)
+				delivery.Error = strings.TrimSpace(delivery.Error + " " + fmt.Sprintf(ErrorDeliveryCancelingOrder, err.Error()))
+			}
+		}
+	}
+
+	return s.repository.UpdateDelivery(delivery)
+}
@@ -0,0 +1,169 @@
+package analytics
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/BacoFoods/menu/pkg/order"
+	"github.com/BacoFoods/menu/pkg/shared"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	LogDBRepository = "pkg/analytics/db_repository"
+)
+
+type DBRepository struct {
+	db *gorm.DB
+}
+
+func NewDBRepository(db *gorm.DB) *DBRepository {
+	return &DBRepository{db}
+}
+
+// Record adds the sales to the daily aggregates
+func (r *DBRepository) Record(sales []DailySale) error {
+	if len(sales) == 0 {
+		return nil
+	}
+
+	if err := r.db.Clauses(upsert()).Create(&sales).Error; err != nil {
+		shared.LogError("error recording daily sales", LogDBRepository, "Record", err, sales)
+		return fmt.Errorf(ErrorAnalyticsRecording)
+	}
+
+	return nil
+}
+
+// Replace deletes the daily aggregates of the store in the range and records the sales
+func (r *DBRepository) Replace(storeID uint, from, to time.Time, sales []DailySale) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("store_id = ? AND date >= ? AND date <= ?", storeID, from.Format(dateLayout), to.Format(dateLayout)).
+			Delete(&DailySale{}).Error; err != nil {
+			return err
+		}
+
+		if len(sales) == 0 {
+			return nil
+		}
+
+		return tx.Clauses(upsert()).CreateInBatches(&sales, 500).Error
+	})
+	if err != nil {
+		shared.LogError("error replacing daily sales", LogDBRepository, "Replace", err, storeID, from, to)
+		return fmt.Errorf(ErrorAnalyticsRebuilding)
+	}
+
+	return nil
+}
+
+func upsert() clause.OnConflict {
+	return clause.OnConflict{
+		Columns: []clause.Column{
+			{Name: "date"}, {Name: "store_id"}, {Name: "channel_id"}, {Name: "hour"},
+			{Name: "dimension"}, {Name: "reference_id"}, {Name: "name"},
+		},
+		DoUpdates: clause.Assignments(map[string]any{
+			"units":      gorm.Expr("analytics_daily_sales.units + excluded.units"),
+			"revenue":    gorm.Expr("analytics_daily_sales.revenue + excluded.revenue"),
+			"updated_at": gorm.Expr("excluded.updated_at"),
+		}),
+	}
+}
+
+// Sum returns the units and revenue of the daily aggregates in the filter grouped by the group by
+func (r *DBRepository) Sum(filter Filter, groupBy string) ([]SalesLine, error) {
+	query := r.db.Model(&DailySale{}).
+		Where("date >= ? AND date <= ?", filter.From.Format(dateLayout), filter.To.Format(dateLayout))
+
+	if filter.BrandID != "" {
+		query = query.Where("brand_id = ?", filter.BrandID)
+	}
+	if filter.StoreID != "" {
+		query = query.Where("store_id = ?", filter.StoreID)
+	}
+	if filter.ChannelID != "" {
+		query = query.Where("channel_id = ?", filter.ChannelID)
+	}
+
+	switch groupBy {
+	case GroupByProduct, GroupByCategory, GroupByModifier, GroupByComponent:
+		query = query.Where("dimension = ?", groupBy).
+			Select("reference_id, name, SUM(units) AS units, SUM(revenue) AS revenue").
+			Group("reference_id, name").
+			Order("revenue DESC")
+	case GroupByStore:
+		query = query.Where("dimension = ?", DimensionProduct).
+			Select("store_id AS reference_id, SUM(units) AS units, SUM(revenue) AS revenue").
+			Group("store_id").
+			Order("revenue DESC")
+	case GroupByChannel:
+		query = query.Where("dimension = ?", DimensionProduct).
+			Select("channel_id AS reference_id, SUM(units) AS units, SUM(revenue) AS revenue").
+			Group("channel_id").
+			Order("revenue DESC")
+	case GroupByHour:
+		query = query.Where("dimension = ?", DimensionProduct).
+			Select("hour AS reference_id, SUM(units) AS units, SUM(revenue) AS revenue").
+			Group("hour").
+			Order("hour")
+	default:
+		return nil, fmt.Errorf(ErrorAnalyticsInvalidGroupBy)
+	}
+
+	lines := make([]SalesLine, 0)
+	if err := query.Scan(&lines).Error; err != nil {
+		shared.LogError("error summing daily sales", LogDBRepository, "Sum", err, filter, groupBy)
+		return nil, fmt.Errorf(ErrorAnalyticsFinding)
+	}
+
+	return lines, nil
+}
+
+// FindClosedOrders returns the orders of the store closed in the range with their items, modifiers and combo components
+func (r *DBRepository) FindClosedOrders(storeID string, from, to time.Time) ([]order.Order, error) {
+	var orders []order.Order
+	if err := r.db.Preload("Items.Modifiers").Preload("Items.Components").
+		Where("store_id = ? AND current_status = ?", storeID, order.OrderStatusClosed).
+		Where("closed_at >= ? AND closed_at < ?", from, to).
+		Find(&orders).Error; err != nil {
+		shared.LogError("error finding closed orders", LogDBRepository, "FindClosedOrders", err, storeID, from, to)
+		return nil, fmt.Errorf(ErrorAnalyticsFindingOrders)
+	}
+
+	return orders, nil
+}
+
+// FindProductCategories returns the first category of each product
+func (r *DBRepository) FindProductCategories(productIDs []uint) (map[uint]Category, error) {
+	categories := make(map[uint]Category)
+	if len(productIDs) == 0 {
+		return categories, nil
+	}
+
+	var rows []struct {
+		ProductID    uint
+		CategoryID   uint
+		CategoryName string
+	}
+	if err := r.db.Table("categories_products").
+		Select("categories_products.product_id, categories.id AS category_id, categories.name AS category_name").
+		Joins("JOIN categories ON categories.id = categories_products.category_id AND categories.deleted_at IS NULL").
+		Where("categories_products.product_id IN ?", productIDs).
+		Order("categories.id").
+		Scan(&rows).Error; err != nil {
+		shared.LogError("error finding product categories", LogDBRepository, "FindProductCategories", err, productIDs)
+		return nil, fmt.Errorf(ErrorAnalyticsFindingCategories)
+	}
+
+	for _, row := range rows {
+		if _, ok := categories[row.ProductID]; !ok {
+			categories[row.ProductID] = Category{ID: row.CategoryID, Name: row.CategoryName}
+		}
+	}
+
+	return categories, nil
+}
+
+var _ Repository = &DBRepository{}
@@ -0,0 +1,257 @@
+package analytics
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/BacoFoods/menu/pkg/order"
+)
+
+const (
+	ErrorAnalyticsRecording         = "error recording sales analytics"
+	ErrorAnalyticsFinding           = "error finding sales analytics"
+	ErrorAnalyticsRebuilding        = "error rebuilding sales analytics"
+	ErrorAnalyticsInvalidGroupBy    = "error invalid analytics group by"
+	ErrorAnalyticsInvalidDates      = "error analytics dates must be formatted as 2006-01-02"
+	ErrorAnalyticsInvalidRange      = "error analytics start date must be before the end date"
+	ErrorAnalyticsRangeTooLong      = "error analytics range is too long"
+	ErrorAnalyticsGettingStore      = "error getting analytics store"
+	ErrorAnalyticsStoreRequired     = "error analytics rebuild needs a store"
+	ErrorAnalyticsFindingOrders     = "error finding analytics orders"
+	ErrorAnalyticsFindingCategories = "error finding analytics product categories"
+
+	DimensionProduct   = "product"
+	DimensionCategory  = "category"
+	DimensionModifier  = "modifier"
+	DimensionComponent = "component"
+
+	GroupByProduct   = DimensionProduct
+	GroupByCategory  = DimensionCategory
+	GroupByModifier  = DimensionModifier
+	GroupByComponent = DimensionComponent
+	GroupByStore     = "store"
+	GroupByChannel   = "channel"
+	GroupByHour      = "hour"
+
+	// MaxRangeDays is the longest date range of an analytics query or rebuild
+	MaxRangeDays = 366
+
+	dateLayout = "2006-01-02"
+)
+
+type Repository interface {
+	Record(sales []DailySale) error
+	Replace(storeID uint, from, to time.Time, sales []DailySale) error
+	Sum(filter Filter, groupBy string) ([]SalesLine, error)
+	FindClosedOrders(storeID string, from, to time.Time) ([]order.Order, error)
+	FindProductCategories(productIDs []uint) (map[uint]Category, error)
+}
+
+// DailySale is the precomputed aggregate of the units and revenue sold of a product, category or modifier
+// on a store local day and hour by channel. The store, channel and hour aggregates are the sum of the
+// product ones, so every item is accounted once.
+type DailySale struct {
+	ID          uint       `json:"id"`
+	Date        time.Time  `json:"date" gorm:"type:date;uniqueIndex:idx_analytics_daily_sales_key"`
+	BrandID     uint       `json:"brand_id" gorm:"index"`
+	StoreID     uint       `json:"store_id" gorm:"uniqueIndex:idx_analytics_daily_sales_key"`
+	ChannelID   uint       `json:"channel_id" gorm:"uniqueIndex:idx_analytics_daily_sales_key"`
+	Hour        int        `json:"hour" gorm:"uniqueIndex:idx_analytics_daily_sales_key"`
+	Dimension   string     `json:"dimension" gorm:"uniqueIndex:idx_analytics_daily_sales_key"`
+	ReferenceID uint       `json:"reference_id" gorm:"uniqueIndex:idx_analytics_daily_sales_key"`
+	Name        string     `json:"name" gorm:"uniqueIndex:idx_analytics_daily_sales_key"`
+	Units       int        `json:"units"`
+	Revenue     float64    `json:"revenue" gorm:"precision:18;scale:4"`
+	CreatedAt   *time.Time `json:"created_at,omitempty" swaggerignore:"true"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty" swaggerignore:"true"`
+}
+
+func (DailySale) TableName() string {
+	return "analytics_daily_sales"
+}
+
+func (d DailySale) key() string {
+	return fmt.Sprintf("%s|%d|%d|%d|%s|%d|%s", d.Date.Format(dateLayout), d.StoreID, d.ChannelID, d.Hour, d.Dimension, d.ReferenceID, d.Name)
+}
+
+type Category struct {
+	ID   uint
+	Name string
+}
+
+// Filter of the analytics queries, the dates are store local dates and both are included
+type Filter struct {
+	BrandID   string
+	StoreID   string
+	ChannelID string
+	From      time.Time
+	To        time.Time
+}
+
+// Previous returns the filter of the period of the same length right before
+func (f Filter) Previous() Filter {
+	days := int(f.To.Sub(f.From).Hours()/24) + 1
+	previous := f
+	previous.From = f.From.AddDate(0, 0, -days)
+	previous.To = f.From.AddDate(0, 0, -1)
+	return previous
+}
+
+// SalesLine is the units and revenue of a group, compared with the previous period
+type SalesLine struct {
+	ReferenceID     uint     `json:"reference_id"`
+	Name            string   `json:"name"`
+	Units           int      `json:"units"`
+	Revenue         float64  `json:"revenue"`
+	PreviousUnits   int      `json:"previous_units"`
+	PreviousRevenue float64  `json:"previous_revenue"`
+	UnitsChange     *float64 `json:"units_change"`   // Percentage, nil when there were no previous units
+	RevenueChange   *float64 `json:"revenue_change"` // Percentage, nil when there was no previous revenue
+}
+
+func (l SalesLine) key() string {
+	return fmt.Sprintf("%d|%s", l.ReferenceID, l.Name)
+}
+
+type Sales struct {
+	GroupBy      string      `json:"group_by"`
+	From         string      `json:"from"`
+	To           string      `json:"to"`
+	PreviousFrom string      `json:"previous_from"`
+	PreviousTo   string      `json:"previous_to"`
+	Lines        []SalesLine `json:"lines"`
+}
+
+func IsValidGroupBy(groupBy string) bool {
+	switch groupBy {
+	case GroupByProduct, GroupByCategory, GroupByModifier, GroupByComponent, GroupByStore, GroupByChannel, GroupByHour:
+		return true
+	}
+	return false
+}
+
+// Compare sets the previous period values on the current lines, the groups sold only on the previous
+// period are added with no current values.
+func Compare(current, previous []SalesLine) []SalesLine {
+	lines := make([]SalesLine, 0)
+	indexes := make(map[string]int)
+	for _, line := range current {
+		indexes[line.key()] = len(lines)
+		lines = append(lines, line)
+	}
+
+	for _, line := range previous {
+		index, ok := indexes[line.key()]
+		if !ok {
+			index = len(lines)
+			lines = append(lines, SalesLine{ReferenceID: line.ReferenceID, Name: line.Name})
+		}
+		lines[index].PreviousUnits = line.Units
+		lines[index].PreviousRevenue = line.Revenue
+	}
+
+	for i := range lines {
+		lines[i].UnitsChange = change(float64(lines[i].Units), float64(lines[i].PreviousUnits))
+		lines[i].RevenueChange = change(lines[i].Revenue, lines[i].PreviousRevenue)
+	}
+
+	return lines
+}
+
+func change(current, previous float64) *float64 {
+	if previous == 0 {
+		return nil
+	}
+	percentage := (current - previous) / previous * 100
+	return &percentage
+}
+
+// Aggregate builds the daily sales of a closed order on the store local day and hour it was closed,
+// the revenue is the price of the items and modifiers before the invoice discounts. The components of
+// a combo are sold with their allocated share of the combo price.
+func Aggregate(closedOrder *order.Order, location *time.Location, categories map[uint]Category) []DailySale {
+	if closedOrder.ClosedAt == nil || closedOrder.StoreID == nil {
+		return nil
+	}
+
+	closedAt := closedOrder.ClosedAt.In(location)
+	base := DailySale{
+		Date:    time.Date(closedAt.Year(), closedAt.Month(), closedAt.Day(), 0, 0, 0, 0, time.UTC),
+		StoreID: *closedOrder.StoreID,
+		Hour:    closedAt.Hour(),
+	}
+	if closedOrder.BrandID != nil {
+		base.BrandID = *closedOrder.BrandID
+	}
+	if closedOrder.ChannelID != nil {
+		base.ChannelID = *closedOrder.ChannelID
+	}
+
+	sales := make([]DailySale, 0)
+	add := func(dimension string, referenceID *uint, name string, units int, revenue float64) {
+		sale := base
+		sale.Dimension = dimension
+		sale.Name = name
+		sale.Units = units
+		sale.Revenue = revenue
+		if referenceID != nil {
+			sale.ReferenceID = *referenceID
+		}
+		sales = append(sales, sale)
+	}
+
+	for _, item := range closedOrder.Items {
+		revenue := item.Price
+		for _, modifier := range item.Modifiers {
+			revenue += modifier.Price
+			add(DimensionModifier, modifier.ProductID, modifier.Name, 1, modifier.Price)
+		}
+
+		for _, component := range item.Components {
+			add(DimensionComponent, component.ProductID, component.Name, component.Quantity, component.Price)
+		}
+
+		add(DimensionProduct, item.ProductID, item.Name, 1, revenue)
+
+		if item.ProductID == nil {
+			continue
+		}
+		if category, ok := categories[*item.ProductID]; ok {
+			add(DimensionCategory, &category.ID, category.Name, 1, revenue)
+		}
+	}
+
+	return Merge(sales)
+}
+
+// ProductIDs returns the products of the items of the orders
+func ProductIDs(orders ...order.Order) []uint {
+	ids := make([]uint, 0)
+	seen := make(map[uint]bool)
+	for _, closedOrder := range orders {
+		for _, item := range closedOrder.Items {
+			if item.ProductID == nil || seen[*item.ProductID] {
+				continue
+			}
+			seen[*item.ProductID] = true
+			ids = append(ids, *item.ProductID)
+		}
+	}
+	return ids
+}
+
+// Merge adds up the sales with the same aggregate key, so they can be recorded in the same statement
+func Merge(sales []DailySale) []DailySale {
+	merged := make([]DailySale, 0)
+	indexes := make(map[string]int)
+	for _, sale := range sales {
+		if index, ok := indexes[sale.key()]; ok {
+			merged[index].Units += sale.Units
+			merged[index].Revenue += sale.Revenue
+			continue
+		}
+		indexes[sale.key()] = len(merged)
+		merged = append(merged, sale)
+	}
+	return merged
+}
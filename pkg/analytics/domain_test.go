@@ -0,0 +1,122 @@
+package analytics_test
+
+import (
+	"time"
+
+	"github.com/BacoFoods/menu/pkg/analytics"
+	"github.com/BacoFoods/menu/pkg/order"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Analytics", func() {
+	id := func(value uint) *uint { return &value }
+
+	Context("Aggregate", func() {
+		bogota := time.FixedZone("America/Bogota", -5*60*60)
+		closedAt := time.Date(2023, 10, 6, 2, 15, 0, 0, time.UTC)
+		categories := map[uint]analytics.Category{10: {ID: 100, Name: "Burgers"}, 11: {ID: 100, Name: "Burgers"}}
+
+		var closedOrder *order.Order
+		BeforeEach(func() {
+			closedOrder = &order.Order{
+				StoreID:   id(1),
+				BrandID:   id(2),
+				ChannelID: id(3),
+				ClosedAt:  &closedAt,
+				Items: []order.OrderItem{
+					{ProductID: id(10), Name: "Baco", Price: 30000, Modifiers: []order.OrderModifier{{ProductID: id(50), Name: "Bacon", Price: 4000}}},
+					{ProductID: id(10), Name: "Baco", Price: 30000},
+					{ProductID: id(11), Name: "Combo", Price: 40000, Components: []order.OrderItemComponent{
+						{ProductID: id(10), Name: "Baco", Quantity: 1, Price: 28000},
+						{ProductID: id(60), Name: "Soda", Quantity: 2, Price: 12000},
+					}},
+				},
+			}
+		})
+
+		find := func(sales []analytics.DailySale, dimension string, referenceID uint) analytics.DailySale {
+			for _, sale := range sales {
+				if sale.Dimension == dimension && sale.ReferenceID == referenceID {
+					return sale
+				}
+			}
+			Fail("sale not found")
+			return analytics.DailySale{}
+		}
+
+		It("Sums the sales on the store local day and hour", func() {
+			sales := analytics.Aggregate(closedOrder, bogota, categories)
+
+			Expect(sales).To(HaveLen(6))
+			for _, sale := range sales {
+				Expect(sale.Date).To(Equal(time.Date(2023, 10, 5, 0, 0, 0, 0, time.UTC)))
+				Expect(sale.Hour).To(Equal(21))
+				Expect(sale.StoreID).To(Equal(uint(1)))
+				Expect(sale.BrandID).To(Equal(uint(2)))
+				Expect(sale.ChannelID).To(Equal(uint(3)))
+			}
+		})
+
+		It("Adds the modifiers to the product revenue and the combo components with their share", func() {
+			sales := analytics.Aggregate(closedOrder, bogota, categories)
+
+			product := find(sales, analytics.DimensionProduct, 10)
+			Expect(product.Units).To(Equal(2))
+			Expect(product.Revenue).To(Equal(64000.0))
+			Expect(find(sales, analytics.DimensionModifier, 50).Revenue).To(Equal(4000.0))
+			Expect(find(sales, analytics.DimensionComponent, 60).Units).To(Equal(2))
+			Expect(find(sales, analytics.DimensionComponent, 10).Revenue).To(Equal(28000.0))
+
+			category := find(sales, analytics.DimensionCategory, 100)
+			Expect(category.Units).To(Equal(3))
+			Expect(category.Revenue).To(Equal(104000.0))
+		})
+
+		It("Skips the orders not closed", func() {
+			closedOrder.ClosedAt = nil
+			Expect(analytics.Aggregate(closedOrder, bogota, categories)).To(BeEmpty())
+		})
+	})
+
+	Context("Compare", func() {
+		It("Sets the previous period and the change of every line", func() {
+			current := []analytics.SalesLine{
+				{ReferenceID: 1, Name: "Baco", Units: 15, Revenue: 450000},
+				{ReferenceID: 2, Name: "Soda", Units: 4, Revenue: 24000},
+			}
+			previous := []analytics.SalesLine{
+				{ReferenceID: 1, Name: "Baco", Units: 10, Revenue: 300000},
+				{ReferenceID: 3, Name: "Fries", Units: 5, Revenue: 40000},
+			}
+
+			lines := analytics.Compare(current, previous)
+
+			Expect(lines).To(HaveLen(3))
+			Expect(lines[0].PreviousUnits).To(Equal(10))
+			Expect(*lines[0].UnitsChange).To(Equal(50.0))
+			Expect(*lines[0].RevenueChange).To(Equal(50.0))
+
+			Expect(lines[1].PreviousUnits).To(BeZero())
+			Expect(lines[1].UnitsChange).To(BeNil())
+			Expect(lines[1].RevenueChange).To(BeNil())
+
+			Expect(lines[2].Name).To(Equal("Fries"))
+			Expect(lines[2].Units).To(BeZero())
+			Expect(lines[2].PreviousRevenue).To(Equal(40000.0))
+			Expect(*lines[2].RevenueChange).To(Equal(-100.0))
+		})
+
+		It("Takes the period of the same length right before", func() {
+			filter := analytics.Filter{
+				From: time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC),
+				To:   time.Date(2023, 10, 7, 0, 0, 0, 0, time.UTC),
+			}
+
+			previous := filter.Previous()
+
+			Expect(previous.From).To(Equal(time.Date(2023, 9, 24, 0, 0, 0, 0, time.UTC)))
+			Expect(previous.To).To(Equal(time.Date(2023, 9, 30, 0, 0, 0, 0, time.UTC)))
+		})
+	})
+})
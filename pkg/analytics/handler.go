@@ -0,0 +1,92 @@
+package analytics
+
+import (
+	"net/http"
+
+	"github.com/BacoFoods/menu/pkg/shared"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	LogHandler = "pkg/analytics/handler"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service}
+}
+
+// Sales to handle the sales analytics request
+// @Tags Analytics
+// @Summary To get the units and revenue sold
+// @Description To get the units and revenue sold by product, category, modifier, combo component, store, channel or hour bucket of the store local days, compared with the previous period of the same length. The revenue is the items price before the invoice discounts.
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param group_by query string true "group by" Enums(product, category, modifier, component, store, channel, hour)
+// @Param from query string true "start date" example(2006-01-02)
+// @Param to query string true "end date, included" example(2006-01-02)
+// @Param brand_id query string false "brand id"
+// @Param store_id query string false "store id"
+// @Param channel_id query string false "channel id"
+// @Success 200 {object} object{status=string,data=Sales}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /analytics/sales [get]
+func (h Handler) Sales(c *gin.Context) {
+	from, to, err := ParseDates(c.Query("from"), c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	filter := Filter{
+		BrandID:   c.Query("brand_id"),
+		StoreID:   c.Query("store_id"),
+		ChannelID: c.Query("channel_id"),
+		From:      from,
+		To:        to,
+	}
+
+	sales, err := h.service.Sales(filter, c.Query("group_by"))
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(sales))
+}
+
+// Rebuild to handle the sales analytics rebuild request
+// @Tags Analytics
+// @Summary To rebuild the sales analytics of a store
+// @Description To recompute the daily sales aggregates of the store local days from the closed orders, used to backfill the days before the analytics or after a failed record
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "store id"
+// @Param from query string true "start date" example(2006-01-02)
+// @Param to query string true "end date, included" example(2006-01-02)
+// @Success 200 {object} object{status=string,data=string}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /analytics/store/{id}/rebuild [post]
+func (h Handler) Rebuild(c *gin.Context) {
+	from, to, err := ParseDates(c.Query("from"), c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	if err := h.service.Rebuild(c.Param("id"), from, to); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse("analytics rebuilt"))
+}
@@ -0,0 +1,16 @@
+package analytics
+
+import "github.com/BacoFoods/menu/pkg/shared"
+
+type Routes struct {
+	handler *Handler
+}
+
+func NewRoutes(handler *Handler) Routes {
+	return Routes{handler}
+}
+
+func (r Routes) RegisterRoutes(router *shared.CustomRoutes) {
+	router.GET("/analytics/sales", r.handler.Sales)
+	router.POST("/analytics/store/:id/rebuild", r.handler.Rebuild)
+}
@@ -0,0 +1,146 @@
+package analytics
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/BacoFoods/menu/pkg/order"
+	"github.com/BacoFoods/menu/pkg/shared"
+	"github.com/BacoFoods/menu/pkg/store"
+)
+
+const (
+	LogService = "pkg/analytics/service"
+)
+
+type Service interface {
+	RecordClosedOrder(closedOrder *order.Order)
+	Sales(filter Filter, groupBy string) (*Sales, error)
+	Rebuild(storeID string, from, to time.Time) error
+}
+
+type service struct {
+	repository Repository
+	stores     store.Repository
+}
+
+func NewService(repository Repository, stores store.Repository) service {
+	return service{repository, stores}
+}
+
+// RecordClosedOrder adds the closed order to the daily aggregates, errors are only logged so closing
+// an order never fails because of the analytics, a rebuild of the day fixes the missing aggregates.
+func (s service) RecordClosedOrder(closedOrder *order.Order) {
+	if closedOrder == nil || closedOrder.StoreID == nil {
+		return
+	}
+
+	orderStore, err := s.stores.Get(fmt.Sprint(*closedOrder.StoreID))
+	if err != nil {
+		shared.LogError("error getting order store", LogService, "RecordClosedOrder", err, closedOrder.ID)
+		return
+	}
+
+	categories, err := s.repository.FindProductCategories(ProductIDs(*closedOrder))
+	if err != nil {
+		return
+	}
+
+	if err := s.repository.Record(Aggregate(closedOrder, orderStore.Location(), categories)); err != nil {
+		shared.LogError("error recording closed order", LogService, "RecordClosedOrder", err, closedOrder.ID)
+	}
+}
+
+// Sales returns the units and revenue grouped by the group by, compared with the previous period
+func (s service) Sales(filter Filter, groupBy string) (*Sales, error) {
+	if !IsValidGroupBy(groupBy) {
+		return nil, fmt.Errorf(ErrorAnalyticsInvalidGroupBy)
+	}
+
+	if err := validateRange(filter.From, filter.To); err != nil {
+		return nil, err
+	}
+
+	current, err := s.repository.Sum(filter, groupBy)
+	if err != nil {
+		return nil, err
+	}
+
+	previousFilter := filter.Previous()
+	previous, err := s.repository.Sum(previousFilter, groupBy)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Sales{
+		GroupBy:      groupBy,
+		From:         filter.From.Format(dateLayout),
+		To:           filter.To.Format(dateLayout),
+		PreviousFrom: previousFilter.From.Format(dateLayout),
+		PreviousTo:   previousFilter.To.Format(dateLayout),
+		Lines:        Compare(current, previous),
+	}, nil
+}
+
+// Rebuild recomputes the daily aggregates of the store local days of the range from the closed orders
+func (s service) Rebuild(storeID string, from, to time.Time) error {
+	if storeID == "" {
+		return fmt.Errorf(ErrorAnalyticsStoreRequired)
+	}
+
+	if err := validateRange(from, to); err != nil {
+		return err
+	}
+
+	rebuildStore, err := s.stores.Get(storeID)
+	if err != nil {
+		return fmt.Errorf(ErrorAnalyticsGettingStore)
+	}
+	location := rebuildStore.Location()
+
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, location)
+	end := time.Date(to.Year(), to.Month(), to.Day()+1, 0, 0, 0, 0, location)
+	closedOrders, err := s.repository.FindClosedOrders(storeID, start, end)
+	if err != nil {
+		return err
+	}
+
+	categories, err := s.repository.FindProductCategories(ProductIDs(closedOrders...))
+	if err != nil {
+		return err
+	}
+
+	sales := make([]DailySale, 0)
+	for i := range closedOrders {
+		sales = append(sales, Aggregate(&closedOrders[i], location, categories)...)
+	}
+
+	return s.repository.Replace(rebuildStore.ID, from, to, Merge(sales))
+}
+
+func validateRange(from, to time.Time) error {
+	if to.Before(from) {
+		return fmt.Errorf(ErrorAnalyticsInvalidRange)
+	}
+
+	if to.Sub(from) > MaxRangeDays*24*time.Hour {
+		return fmt.Errorf(ErrorAnalyticsRangeTooLong)
+	}
+
+	return nil
+}
+
+// ParseDates parses the store local dates of the range
+func ParseDates(from, to string) (time.Time, time.Time, error) {
+	fromDate, err := time.Parse(dateLayout, from)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf(ErrorAnalyticsInvalidDates)
+	}
+
+	toDate, err := time.Parse(dateLayout, to)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf(ErrorAnalyticsInvalidDates)
+	}
+
+	return fromDate, toDate, nil
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/BacoFoods/menu/pkg/currency"
 	"github.com/BacoFoods/menu/pkg/database"
 	"github.com/BacoFoods/menu/pkg/menu"
+	"github.com/BacoFoods/menu/pkg/payment"
 	"github.com/BacoFoods/menu/pkg/product"
 	"github.com/BacoFoods/menu/pkg/store"
 	"github.com/BacoFoods/menu/pkg/taxes"
@@ -61,7 +62,7 @@ var _ = Describe("Availability", func() {
 		channelRepository := channel.NewDBRepository(db)
 
 		brandRepository := brand.NewDBRepository(db)
-		brandService = brand.NewService(brandRepository, channelRepository)
+		brandService = brand.NewService(brandRepository, channelRepository, payment.NewDBRepository(db))
 
 		storeRepository := store.NewDBRepository(db)
 
@@ -69,11 +70,11 @@ var _ = Describe("Availability", func() {
 
 		overridersRepository := product.NewDBRepository(db)
 		availabilityRepository := availability.NewDBRepository(db)
-		availabilityService = availability.NewService(availabilityRepository, storeRepository, channelRepository)
+		availabilityService = availability.NewService(availabilityRepository, storeRepository, channelRepository, nil, nil, nil)
 
 		menuRepository := menu.NewDBRepository(db)
 		categoryRepository := category.NewDBRepository(db)
-		menuService = menu.NewService(menuRepository, overridersRepository, availabilityRepository, storeRepository, categoryRepository)
+		menuService = menu.NewService(menuRepository, overridersRepository, availabilityRepository, storeRepository, categoryRepository, nil, nil, nil)
 	})
 
 	AfterSuite(func() {
@@ -134,7 +135,7 @@ var _ = Describe("Availability", func() {
 
 			BeforeEach(func() {
 				placeID = myStore.ID
-				err := availabilityService.EnableEntity(availability.EntityMenu, place, entityID, placeID, true)
+				err := availabilityService.EnableEntity(availability.EntityMenu, place, entityID, placeID, true, nil)
 				Expect(err).To(BeNil())
 
 				av, err := availabilityService.Get(availability.EntityMenu, place, entityID, placeID)
@@ -174,7 +175,7 @@ var _ = Describe("Availability", func() {
 				Expect(menu).To(Not(BeNil()))
 
 				// Act
-				err = availabilityService.EnableEntity(availability.EntityMenu, place, menu.ID, placeIDs[0], false)
+				err = availabilityService.EnableEntity(availability.EntityMenu, place, menu.ID, placeIDs[0], false, nil)
 				availability, err := availabilityService.Get(availability.EntityMenu, availability.PlaceStore, menu.ID, placeIDs[0])
 
 				// Assert
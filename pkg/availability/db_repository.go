@@ -2,6 +2,7 @@ package availability
 
 import (
 	"log"
+	"time"
 
 	"github.com/BacoFoods/menu/pkg/shared"
 	"gorm.io/gorm"
@@ -92,3 +93,106 @@ func (r *DBRepository) Find(entity Entity, place Place, entityID uint) ([]Availa
 
 	return availabilities, nil
 }
+
+// SetStock creates or updates the stock of a product or modifier at a place
+func (r *DBRepository) SetStock(availability *Availability) (*Availability, error) {
+	var availabilityDB Availability
+
+	if err := r.db.FirstOrInit(&availabilityDB, Availability{
+		Entity:   availability.Entity,
+		EntityID: availability.EntityID,
+		Place:    availability.Place,
+		PlaceID:  availability.PlaceID,
+	}).Error; err != nil {
+		shared.LogError("error getting availability", LogDBRepository, "SetStock", err, *availability)
+		return nil, err
+	}
+
+	availabilityDB.Enable = availability.Enable
+	availabilityDB.Quantity = availability.Quantity
+	availabilityDB.RestoreAt = availability.RestoreAt
+	if err := r.db.Save(&availabilityDB).Error; err != nil {
+		shared.LogError("error saving stock", LogDBRepository, "SetStock", err, availabilityDB)
+		return nil, err
+	}
+
+	return &availabilityDB, nil
+}
+
+// FindStock returns the stock of the products or modifiers at the store and the channel, a zero ID skips the place
+func (r *DBRepository) FindStock(entity Entity, storeID, channelID uint, entityIDs []uint) ([]Availability, error) {
+	var availabilities []Availability
+
+	if len(entityIDs) == 0 {
+		return availabilities, nil
+	}
+
+	if err := r.db.
+		Where("entity = ? AND entity_id IN ?", entity, entityIDs).
+		Where(r.db.Where("place = ? AND place_id = ?", PlaceStore, storeID).Or("place = ? AND place_id = ?", PlaceChannel, channelID)).
+		Find(&availabilities).Error; err != nil {
+		shared.LogError("error finding stock", LogDBRepository, "FindStock", err, entity, storeID, channelID, entityIDs)
+		return nil, err
+	}
+
+	return availabilities, nil
+}
+
+// ConsumeStock takes the units from a counted stock only if there are enough units left, the item is 86'd when
+// its last unit is taken
+func (r *DBRepository) ConsumeStock(availabilityID uint, units int) (bool, error) {
+	result := r.db.Model(&Availability{}).
+		Where("id = ? AND enable = ? AND quantity >= ?", availabilityID, true, units).
+		Updates(map[string]any{
+			"quantity": gorm.Expr("quantity - ?", units),
+			"enable":   gorm.Expr("quantity - ? > 0", units),
+		})
+	if result.Error != nil {
+		shared.LogError("error consuming stock", LogDBRepository, "ConsumeStock", result.Error, availabilityID, units)
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// ReturnStock gives back the units taken from a counted stock
+func (r *DBRepository) ReturnStock(availabilityID uint, units int) error {
+	if err := r.db.Model(&Availability{}).
+		Where("id = ? AND quantity IS NOT NULL", availabilityID).
+		Updates(map[string]any{
+			"quantity": gorm.Expr("quantity + ?", units),
+			"enable":   true,
+		}).Error; err != nil {
+		shared.LogError("error returning stock", LogDBRepository, "ReturnStock", err, availabilityID, units)
+		return err
+	}
+
+	return nil
+}
+
+// RestoreStock removes the stock-outs whose day is over and returns them
+func (r *DBRepository) RestoreStock(now time.Time) ([]Availability, error) {
+	var availabilities []Availability
+
+	if err := r.db.Where("entity IN ? AND restore_at <= ?", []Entity{EntityProduct, EntityModifier}, now).
+		Find(&availabilities).Error; err != nil {
+		shared.LogError("error finding stock to restore", LogDBRepository, "RestoreStock", err, now)
+		return nil, err
+	}
+
+	if len(availabilities) == 0 {
+		return availabilities, nil
+	}
+
+	ids := make([]uint, 0, len(availabilities))
+	for _, availability := range availabilities {
+		ids = append(ids, availability.ID)
+	}
+
+	if err := r.db.Where("id IN ?", ids).Delete(&Availability{}).Error; err != nil {
+		shared.LogError("error restoring stock", LogDBRepository, "RestoreStock", err, ids)
+		return nil, err
+	}
+
+	return availabilities, nil
+}
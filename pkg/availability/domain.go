@@ -18,13 +18,28 @@ const (
 	ErrorPlaceNotFound       = "error place not found"
 	ErrorFindingAvailability = "error finding availability"
 	ErrorGettingAvailability = "error getting availability"
+	ErrorStockNotSupported   = "error stock is only supported for products and modifiers"
+	ErrorStockInvalid        = "error stock quantity must be greater than zero"
+	ErrorStockFinding        = "error finding stock"
+	ErrorStockSoldOut        = "error %s with id %v is out of stock; "
+	ErrorStockReserving      = "error reserving stock"
 
 	EntityMenu     Entity = "menu"
 	EntityCategory Entity = "category"
+	EntityProduct  Entity = "product"
+	EntityModifier Entity = "modifier"
 	PlaceStore     Place  = "store"
 	PlaceChannel   Place  = "channel"
+
+	// RestoreInterval is how often the stock-outs past the end of the day are restored
+	RestoreInterval = 5 * time.Minute
 )
 
+// IsStock reports whether the entity is an item that can be 86'd at a store or channel
+func (e Entity) IsStock() bool {
+	return e == EntityProduct || e == EntityModifier
+}
+
 type Availability struct {
 	ID        uint            `json:"id"`
 	Entity    string          `json:"entity"`
@@ -32,6 +47,8 @@ type Availability struct {
 	Enable    bool            `json:"enable"`
 	Place     string          `json:"place"`
 	PlaceID   *uint           `json:"place_id"`
+	Quantity  *int            `json:"quantity"`   // units left of a product or modifier, empty when not counted
+	RestoreAt *time.Time      `json:"restore_at"` // end of the local day, when a stock-out is lifted
 	CreatedAt *time.Time      `json:"created_at" swaggerignore:"true"`
 	UpdatedAt *time.Time      `json:"updated_at" swaggerignore:"true"`
 	DeletedAt *gorm.DeletedAt `json:"deleted_at" swaggerignore:"true"`
@@ -44,6 +61,40 @@ type Repository interface {
 	FindPlacesByEntity(entity Entity, entityID uint, place string) ([]Availability, error)
 	Get(entity Entity, place Place, entityID, placeID uint) (Availability, error)
 	Find(entity Entity, place Place, entityID uint) ([]Availability, error)
+	SetStock(availability *Availability) (*Availability, error)
+	FindStock(entity Entity, storeID, channelID uint, entityIDs []uint) ([]Availability, error)
+	ConsumeStock(availabilityID uint, units int) (bool, error)
+	ReturnStock(availabilityID uint, units int) error
+	RestoreStock(now time.Time) ([]Availability, error)
+}
+
+// Available reports whether the units can be sold, the 86'd items and the counted items with fewer units
+// left are not available
+func (a Availability) Available(units int) bool {
+	if !a.Enable {
+		return false
+	}
+
+	return a.Quantity == nil || *a.Quantity >= units
+}
+
+// Units is the number of units ordered by product or modifier ID
+type Units map[uint]int
+
+// IDs returns the product or modifier IDs of the units
+func (u Units) IDs() []uint {
+	ids := make([]uint, 0, len(u))
+	for id := range u {
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+// EndOfDay returns the start of the next day at the location, when the stock-outs of the day are restored
+func EndOfDay(now time.Time, location *time.Location) time.Time {
+	local := now.In(location)
+	return time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, location)
 }
 
 func GetEntity(entity string) (Entity, error) {
@@ -52,6 +103,10 @@ func GetEntity(entity string) (Entity, error) {
 		return EntityMenu, nil
 	case "category":
 		return EntityCategory, nil
+	case "product":
+		return EntityProduct, nil
+	case "modifier":
+		return EntityModifier, nil
 	default:
 		return "", fmt.Errorf(ErrorEntityNotFound)
 	}
@@ -19,7 +19,8 @@ type ResponsePlaces struct {
 }
 
 type EnableRequest struct {
-	Enable bool `json:"enable"`
+	Enable   bool `json:"enable"`
+	Quantity *int `json:"quantity,omitempty"` // units left of a product or modifier, counted down on each order
 }
 
 type Handler struct {
@@ -83,9 +84,10 @@ func (h *Handler) RemoveEntity(c *gin.Context) {
 	c.JSON(http.StatusOK, shared.SuccessResponse(fmt.Sprintf("%v: %v in %v: %v", entity, entityID, place, placeID)))
 }
 
-// EnableEntity to handle a request to enable a menu or category
-// @Summary Enable menu or category
-// @Description Enable menu or category
+// EnableEntity to handle a request to enable a menu or category, or to 86 a product or modifier
+// @Summary Enable menu or category, 86 product or modifier
+// @Description Enable menu or category. Products and modifiers are 86'd until the end of the day when disabled,
+// @Description or counted down from the quantity on each order when enabled with one.
 // @Tags Availability
 // @Produce json
 // @Security ApiKeyAuth
@@ -135,8 +137,12 @@ func (h *Handler) EnableEntity(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.EnableEntity(entity, place, uint(entityID), uint(placeID), body.Enable); err != nil {
+	if err := h.service.EnableEntity(entity, place, uint(entityID), uint(placeID), body.Enable, body.Quantity); err != nil {
 		shared.LogError("error enabling entity", LogHandler, "EnableEntity", err, entity, entityID, place, placeID)
+		if err.Error() == ErrorStockNotSupported || err.Error() == ErrorStockInvalid {
+			c.JSON(http.StatusBadRequest, shared.ErrorResponse(err.Error()))
+			return
+		}
 		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(ErrorEnablingEntity))
 		return
 	}
@@ -1,41 +1,119 @@
 package availability
 
 import (
+	"context"
 	"fmt"
+	"time"
 
+	"github.com/BacoFoods/menu/internal"
 	channelPkg "github.com/BacoFoods/menu/pkg/channel"
+	"github.com/BacoFoods/menu/pkg/menucache"
+	"github.com/BacoFoods/menu/pkg/realtime"
+	"github.com/BacoFoods/menu/pkg/shared"
 	storePkg "github.com/BacoFoods/menu/pkg/store"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	LogService string = "pkg/availability/service"
+
+	restorerLockKey = "menu:availability:restorer"
 )
 
 type Service interface {
-	EnableEntity(entity Entity, place Place, entityID, placeID uint, enable bool) error
+	EnableEntity(entity Entity, place Place, entityID, placeID uint, enable bool, quantity *int) error
 	RemoveEntity(entity Entity, place Place, entityID, placeID uint) error
 	FindEntities() []Entity
 	FindPlaces() []Place
 	Get(entity Entity, place Place, entityID, placeID uint) (any, error)
 	Find(entity Entity, place Place, entityID uint) ([]any, error)
+	Unavailable(entity Entity, storeID, channelID uint, units Units) ([]uint, error)
+	Reserve(entity Entity, storeID, channelID uint, units Units) error
+	Release(entity Entity, storeID, channelID uint, units Units)
+	RunRestorer(ctx context.Context)
 }
 
 type service struct {
 	repository Repository
 	store      storePkg.Repository
 	channel    channelPkg.Repository
+	events     realtime.Publisher
+	redis      *redis.Client
+	cache      menucache.Invalidator
 }
 
-func NewService(repository Repository, store storePkg.Repository, channel channelPkg.Repository) service {
-	return service{repository, store, channel}
+func NewService(repository Repository, store storePkg.Repository, channel channelPkg.Repository, events realtime.Publisher, redis *redis.Client, cache menucache.Invalidator) service {
+	return service{repository, store, channel, events, redis, cache}
 }
 
-func (s service) EnableEntity(entity Entity, place Place, entityID, placeID uint, enable bool) error {
-	return s.repository.EnableEntity(entity, place, entityID, placeID, enable)
+// EnableEntity enables or disables a menu or category at a place. Products and modifiers are 86'd until the
+// end of the local day when disabled, or counted down from the quantity when enabled with one.
+func (s service) EnableEntity(entity Entity, place Place, entityID, placeID uint, enable bool, quantity *int) error {
+	if !entity.IsStock() {
+		if quantity != nil {
+			return fmt.Errorf(ErrorStockNotSupported)
+		}
+		if err := s.repository.EnableEntity(entity, place, entityID, placeID, enable); err != nil {
+			return err
+		}
+		s.invalidate(string(place), &placeID)
+		return nil
+	}
+
+	if enable && quantity == nil {
+		return s.RemoveEntity(entity, place, entityID, placeID)
+	}
+
+	if quantity != nil && *quantity <= 0 {
+		return fmt.Errorf(ErrorStockInvalid)
+	}
+
+	if !enable {
+		quantity = nil
+	}
+
+	restoreAt := EndOfDay(time.Now(), s.location(place, placeID))
+	availability, err := s.repository.SetStock(&Availability{
+		Entity:    string(entity),
+		EntityID:  &entityID,
+		Enable:    enable,
+		Place:     string(place),
+		PlaceID:   &placeID,
+		Quantity:  quantity,
+		RestoreAt: &restoreAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.publish(realtime.EventMenuStockChanged, *availability)
+
+	return nil
 }
 
 func (s service) RemoveEntity(entity Entity, place Place, entityID, placeID uint) error {
-	return s.repository.RemoveEntity(entity, place, entityID, placeID)
+	if err := s.repository.RemoveEntity(entity, place, entityID, placeID); err != nil {
+		return err
+	}
+
+	if entity.IsStock() {
+		s.publish(realtime.EventMenuStockRestored, Availability{
+			Entity:   string(entity),
+			EntityID: &entityID,
+			Enable:   true,
+			Place:    string(place),
+			PlaceID:  &placeID,
+		})
+		return nil
+	}
+
+	s.invalidate(string(place), &placeID)
+
+	return nil
 }
 
 func (s service) FindEntities() []Entity {
-	return []Entity{EntityMenu, EntityCategory}
+	return []Entity{EntityMenu, EntityCategory, EntityProduct, EntityModifier}
 }
 
 func (s service) FindPlaces() []Place {
@@ -104,3 +182,177 @@ func (s service) Find(entity Entity, place Place, entityID uint) ([]any, error)
 		return nil, fmt.Errorf("place %s not supported", place)
 	}
 }
+
+// Unavailable returns the products or modifiers that can't be sold on the units ordered, because they are 86'd or
+// don't have enough units left at the store or the channel
+func (s service) Unavailable(entity Entity, storeID, channelID uint, units Units) ([]uint, error) {
+	availabilities, err := s.repository.FindStock(entity, storeID, channelID, units.IDs())
+	if err != nil {
+		return nil, fmt.Errorf(ErrorStockFinding)
+	}
+
+	unavailable := make([]uint, 0)
+	seen := make(map[uint]bool)
+	for _, availability := range availabilities {
+		entityID := *availability.EntityID
+		if seen[entityID] || availability.Available(units[entityID]) {
+			continue
+		}
+		seen[entityID] = true
+		unavailable = append(unavailable, entityID)
+	}
+
+	return unavailable, nil
+}
+
+// Reserve takes the units ordered from the counted stock of the store and the channel, no unit is taken when
+// any of them is out of stock
+func (s service) Reserve(entity Entity, storeID, channelID uint, units Units) error {
+	availabilities, err := s.repository.FindStock(entity, storeID, channelID, units.IDs())
+	if err != nil {
+		return fmt.Errorf(ErrorStockReserving)
+	}
+
+	taken := make([]Availability, 0)
+	for _, availability := range availabilities {
+		if availability.Quantity == nil {
+			continue
+		}
+
+		entityID := *availability.EntityID
+		ok, err := s.repository.ConsumeStock(availability.ID, units[entityID])
+		if err != nil || !ok {
+			s.giveBack(taken, units)
+			if err != nil {
+				return fmt.Errorf(ErrorStockReserving)
+			}
+			return fmt.Errorf(ErrorStockSoldOut, entity, entityID)
+		}
+		taken = append(taken, availability)
+	}
+
+	for _, availability := range taken {
+		left := *availability.Quantity - units[*availability.EntityID]
+		availability.Quantity = &left
+		availability.Enable = left > 0
+		s.publish(realtime.EventMenuStockChanged, availability)
+	}
+
+	return nil
+}
+
+// Release gives back the units reserved for an order that couldn't be placed
+func (s service) Release(entity Entity, storeID, channelID uint, units Units) {
+	availabilities, err := s.repository.FindStock(entity, storeID, channelID, units.IDs())
+	if err != nil {
+		shared.LogError("error finding stock to release", LogService, "Release", err, entity, storeID, channelID, units)
+		return
+	}
+
+	counted := make([]Availability, 0)
+	for _, availability := range availabilities {
+		if availability.Quantity != nil {
+			counted = append(counted, availability)
+		}
+	}
+
+	s.giveBack(counted, units)
+}
+
+func (s service) giveBack(availabilities []Availability, units Units) {
+	for _, availability := range availabilities {
+		if err := s.repository.ReturnStock(availability.ID, units[*availability.EntityID]); err != nil {
+			shared.LogError("error giving back stock", LogService, "giveBack", err, availability.ID, units)
+		}
+		s.invalidate(availability.Place, availability.PlaceID)
+	}
+}
+
+// RunRestorer lifts the stock-outs at the end of the local day of their places on every interval, until the
+// context is done. The replicas take turns through a redis lock.
+func (s service) RunRestorer(ctx context.Context) {
+	ticker := time.NewTicker(RestoreInterval)
+	defer ticker.Stop()
+
+	for {
+		s.restore(time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s service) restore(now time.Time) {
+	mu := internal.DistMutexWithTTL(s.redis, restorerLockKey, RestoreInterval)
+	locked, err := mu.TryLock()
+	if err != nil {
+		shared.LogError("error locking restorer", LogService, "restore", err)
+		return
+	}
+	if !locked {
+		return // another replica is restoring the stock
+	}
+	defer mu.Unlock()
+
+	availabilities, err := s.repository.RestoreStock(now)
+	if err != nil {
+		return
+	}
+
+	for _, availability := range availabilities {
+		availability.Enable = true
+		availability.Quantity = nil
+		availability.RestoreAt = nil
+		s.publish(realtime.EventMenuStockRestored, availability)
+	}
+}
+
+// location returns the time zone of the store, the channels are not bound to a store so they use the default one
+func (s service) location(place Place, placeID uint) *time.Location {
+	if place == PlaceStore {
+		store, err := s.store.Get(fmt.Sprint(placeID))
+		if err == nil && store != nil {
+			return store.Location()
+		}
+		shared.LogWarn("error getting store location", LogService, "location", err, placeID)
+	}
+
+	return (&storePkg.Store{}).Location()
+}
+
+// publish pushes the stock change to the staff and the public menus of the place
+func (s service) publish(eventType string, availability Availability) {
+	s.invalidate(availability.Place, availability.PlaceID)
+
+	if s.events == nil {
+		return
+	}
+
+	event := realtime.Event{
+		Type:      eventType,
+		Data:      availability,
+		Timestamp: time.Now(),
+	}
+
+	switch Place(availability.Place) {
+	case PlaceStore:
+		event.StoreID = availability.PlaceID
+	case PlaceChannel:
+		event.ChannelID = availability.PlaceID
+	}
+
+	if err := s.events.Publish(event); err != nil {
+		shared.LogWarn("error publishing stock event", LogService, "publish", err, eventType, availability.ID)
+	}
+}
+
+// invalidate drops the cached menus of the place, they show the menus and categories enabled at it and the stock
+func (s service) invalidate(place string, placeID *uint) {
+	if s.cache == nil || placeID == nil {
+		return
+	}
+	s.cache.Invalidate(menucache.PlaceTag(place, *placeID))
+}
@@ -0,0 +1,137 @@
+package unit_test
+
+import (
+	"time"
+
+	"github.com/BacoFoods/menu/pkg/availability"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// stockRepository keeps the stock in memory, consuming and returning units as the database repository does
+type stockRepository struct {
+	availability.Repository
+	stock []availability.Availability
+}
+
+func (r *stockRepository) FindStock(entity availability.Entity, storeID, channelID uint, entityIDs []uint) ([]availability.Availability, error) {
+	ids := make(map[uint]bool)
+	for _, id := range entityIDs {
+		ids[id] = true
+	}
+
+	found := make([]availability.Availability, 0)
+	for _, stock := range r.stock {
+		inPlace := (stock.Place == string(availability.PlaceStore) && *stock.PlaceID == storeID) ||
+			(stock.Place == string(availability.PlaceChannel) && *stock.PlaceID == channelID)
+		if stock.Entity == string(entity) && ids[*stock.EntityID] && inPlace {
+			found = append(found, stock)
+		}
+	}
+	return found, nil
+}
+
+func (r *stockRepository) ConsumeStock(availabilityID uint, units int) (bool, error) {
+	for i, stock := range r.stock {
+		if stock.ID != availabilityID || !stock.Enable || stock.Quantity == nil || *stock.Quantity < units {
+			continue
+		}
+		left := *stock.Quantity - units
+		r.stock[i].Quantity = &left
+		r.stock[i].Enable = left > 0
+		return true, nil
+	}
+	return false, nil
+}
+
+func (r *stockRepository) ReturnStock(availabilityID uint, units int) error {
+	for i, stock := range r.stock {
+		if stock.ID == availabilityID && stock.Quantity != nil {
+			left := *stock.Quantity + units
+			r.stock[i].Quantity = &left
+			r.stock[i].Enable = true
+		}
+	}
+	return nil
+}
+
+func (r *stockRepository) quantity(availabilityID uint) int {
+	for _, stock := range r.stock {
+		if stock.ID == availabilityID {
+			return *stock.Quantity
+		}
+	}
+	return -1
+}
+
+var _ = Describe("Stock", func() {
+	id := func(value uint) *uint { return &value }
+	quantity := func(value int) *int { return &value }
+	storeID, channelID := uint(1), uint(2)
+	burger, fries, soda := uint(10), uint(11), uint(12)
+
+	var repository *stockRepository
+	BeforeEach(func() {
+		repository = &stockRepository{stock: []availability.Availability{
+			{ID: 1, Entity: string(availability.EntityProduct), EntityID: id(burger), Place: string(availability.PlaceStore), PlaceID: id(storeID), Enable: true, Quantity: quantity(5)},
+			{ID: 2, Entity: string(availability.EntityProduct), EntityID: id(fries), Place: string(availability.PlaceChannel), PlaceID: id(channelID), Enable: true, Quantity: quantity(1)},
+			{ID: 3, Entity: string(availability.EntityProduct), EntityID: id(soda), Place: string(availability.PlaceStore), PlaceID: id(storeID), Enable: false},
+		}}
+	})
+
+	It("Reports the 86'd items and the ones without enough units left", func() {
+		service := availability.NewService(repository, nil, nil, nil, nil, nil)
+
+		unavailable, err := service.Unavailable(availability.EntityProduct, storeID, channelID, availability.Units{burger: 5, fries: 2, soda: 1})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(unavailable).To(ConsistOf(fries, soda))
+	})
+
+	It("Takes the units ordered and 86's the item sold out", func() {
+		service := availability.NewService(repository, nil, nil, nil, nil, nil)
+
+		Expect(service.Reserve(availability.EntityProduct, storeID, channelID, availability.Units{burger: 2, fries: 1})).To(Succeed())
+
+		Expect(repository.quantity(1)).To(Equal(3))
+		Expect(repository.quantity(2)).To(BeZero())
+		Expect(repository.stock[1].Enable).To(BeFalse())
+	})
+
+	It("Takes no unit when an item runs out", func() {
+		service := availability.NewService(repository, nil, nil, nil, nil, nil)
+
+		err := service.Reserve(availability.EntityProduct, storeID, channelID, availability.Units{burger: 2, fries: 3})
+
+		Expect(err).To(HaveOccurred())
+		Expect(repository.quantity(1)).To(Equal(5))
+		Expect(repository.quantity(2)).To(Equal(1))
+	})
+
+	It("Gives back the units released", func() {
+		service := availability.NewService(repository, nil, nil, nil, nil, nil)
+		Expect(service.Reserve(availability.EntityProduct, storeID, channelID, availability.Units{burger: 2, fries: 1})).To(Succeed())
+
+		service.Release(availability.EntityProduct, storeID, channelID, availability.Units{burger: 2, fries: 1, soda: 1})
+
+		Expect(repository.quantity(1)).To(Equal(5))
+		Expect(repository.quantity(2)).To(Equal(1))
+		Expect(repository.stock[1].Enable).To(BeTrue())
+		Expect(repository.stock[2].Quantity).To(BeNil())
+	})
+
+	It("Restores the stock-outs at the start of the next local day", func() {
+		bogota := time.FixedZone("America/Bogota", -5*60*60)
+		now := time.Date(2023, 10, 6, 3, 0, 0, 0, time.UTC) // 22:00 of the 5th in Bogota
+
+		Expect(availability.EndOfDay(now, bogota)).To(Equal(time.Date(2023, 10, 6, 0, 0, 0, 0, bogota)))
+		Expect(availability.EndOfDay(now, time.UTC)).To(Equal(time.Date(2023, 10, 7, 0, 0, 0, 0, time.UTC)))
+	})
+
+	It("Sells the counted items while they have enough units", func() {
+		Expect(availability.Availability{Enable: true}.Available(100)).To(BeTrue())
+		Expect(availability.Availability{Enable: true, Quantity: quantity(2)}.Available(2)).To(BeTrue())
+		Expect(availability.Availability{Enable: true, Quantity: quantity(2)}.Available(3)).To(BeFalse())
+		Expect(availability.Availability{Enable: false}.Available(1)).To(BeFalse())
+	})
+})
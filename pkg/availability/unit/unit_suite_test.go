@@ -0,0 +1,15 @@
+// Package unit_test has the availability specs that don't need the database, the availability suite migrates
+// a database before running its specs.
+package unit_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestAvailabilityUnit(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Availability Unit Suite")
+}
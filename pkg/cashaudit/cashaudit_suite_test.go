@@ -0,0 +1,13 @@
+package cashaudit_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestCashAudit(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "CashAudit Suite")
+}
@@ -20,10 +20,10 @@ func NewDBRepository(db *gorm.DB) *DBRepository {
 	return &DBRepository{db}
 }
 
-func (r *DBRepository) Get(storeID string) (*CashAudit, error) {
+func (r *DBRepository) Get(cashAuditID string) (*CashAudit, error) {
 	var cashAudit CashAudit
-	if err := r.db.Where("store_id = ?", storeID).First(&cashAudit).Error; err != nil {
-		shared.LogError("error getting cash audit", LogDBRepository, "Get", err, storeID)
+	if err := r.db.Preload(clause.Associations).First(&cashAudit, cashAuditID).Error; err != nil {
+		shared.LogError("error getting cash audit", LogDBRepository, "Get", err, cashAuditID)
 		return nil, fmt.Errorf(ErrorCashAuditGetting)
 	}
 
@@ -48,18 +48,83 @@ func (r *DBRepository) Create(cashAudit *CashAudit) (*CashAudit, error) {
 	return cashAudit, nil
 }
 
-func (r *DBRepository) GetTodayCashAudit(storeID string) (*CashAudit, error) {
+// GetByShift returns the cash audit submitted for the shift, nil when it was not submitted yet
+func (r *DBRepository) GetByShift(shiftID uint) (*CashAudit, error) {
 	var cashAudit CashAudit
 
 	if err := r.db.Preload(clause.Associations).
-		Where("store_id = ? AND created_at >= NOW() - INTERVAL '1' DAY", storeID).
+		Where("shift_id = ?", shiftID).
 		First(&cashAudit).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
 		}
-		shared.LogError("error getting orders", LogDBRepository, "GetLastDayOrders", err, storeID)
+		shared.LogError("error getting cash audit", LogDBRepository, "GetByShift", err, shiftID)
 		return nil, err
 	}
 
 	return &cashAudit, nil
 }
+
+// GetTolerance returns the cash audit tolerance of the brand, nil when the brand has none
+func (r *DBRepository) GetTolerance(brandID string) (*Tolerance, error) {
+	var tolerance Tolerance
+	if err := r.db.Where("brand_id = ?", brandID).First(&tolerance).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		shared.LogError("error getting tolerance", LogDBRepository, "GetTolerance", err, brandID)
+		return nil, fmt.Errorf(ErrorCashAuditGettingTolerance)
+	}
+
+	return &tolerance, nil
+}
+
+// SaveTolerance creates or replaces the cash audit tolerance of the brand
+func (r *DBRepository) SaveTolerance(tolerance *Tolerance) (*Tolerance, error) {
+	var toleranceDB Tolerance
+	if err := r.db.Where("brand_id = ?", tolerance.BrandID).First(&toleranceDB).Error; err == nil {
+		tolerance.ID = toleranceDB.ID
+	}
+
+	if err := r.db.Save(tolerance).Error; err != nil {
+		shared.LogError("error saving tolerance", LogDBRepository, "SaveTolerance", err, tolerance)
+		return nil, fmt.Errorf(ErrorCashAuditSavingTolerance)
+	}
+
+	return tolerance, nil
+}
+
+// SaveSettlement replaces the imported settlement of the cash audit and updates its reconciliations
+func (r *DBRepository) SaveSettlement(cashAudit *CashAudit) (*CashAudit, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("cash_audit_id = ?", cashAudit.ID).Delete(&Settlement{}).Error; err != nil {
+			return err
+		}
+
+		for i := range cashAudit.Settlements {
+			cashAudit.Settlements[i].CashAuditID = &cashAudit.ID
+		}
+
+		if len(cashAudit.Settlements) > 0 {
+			if err := tx.Create(&cashAudit.Settlements).Error; err != nil {
+				return err
+			}
+		}
+
+		for i := range cashAudit.Reconciliations {
+			if err := tx.Save(&cashAudit.Reconciliations[i]).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		shared.LogError("error saving settlement", LogDBRepository, "SaveSettlement", err, cashAudit.ID)
+		return nil, fmt.Errorf(ErrorCashAuditSettlementSaving)
+	}
+
+	return cashAudit, nil
+}
+
+var _ Repository = &DBRepository{}
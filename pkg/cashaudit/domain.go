@@ -1,25 +1,50 @@
 package cashaudit
 
 import (
+	"encoding/csv"
+	"fmt"
 	"github.com/BacoFoods/menu/pkg/invoice"
 	orderPKG "github.com/BacoFoods/menu/pkg/order"
 	"github.com/BacoFoods/menu/pkg/payment"
 	"github.com/BacoFoods/menu/pkg/shared"
+	"github.com/BacoFoods/menu/pkg/shift"
 	"gorm.io/gorm"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
 const (
-	ErrorCashAuditCreating           = "error creating cash audit"
-	ErrorCashAuditUpdating           = "error updating cash audit"
-	ErrorCashAuditGetting            = "error getting cash audit"
-	ErrorCashAuditGettingStore       = "error getting store"
-	ErrorCashAuditGettingStoreID     = "error getting store id"
-	ErrorCashAuditGettingLastShift   = "error getting last shift"
-	ErrorCashAuditGettingOrders      = "error getting orders"
-	ErrorCashAuditNotAllOrdersClosed = "error not all orders closed"
-	ErrorCashAuditNotOrdersFound     = "error not orders found"
-	ErrorCashAuditInvalidOrderStatus = "error invalid order status"
+	ErrorCashAuditCreating            = "error creating cash audit"
+	ErrorCashAuditUpdating            = "error updating cash audit"
+	ErrorCashAuditGetting             = "error getting cash audit"
+	ErrorCashAuditGettingStore        = "error getting store"
+	ErrorCashAuditGettingAccountID    = "error getting account id"
+	ErrorCashAuditGettingShift        = "error getting shift"
+	ErrorCashAuditNoOpenShift         = "error there is no open shift for the cashier"
+	ErrorCashAuditShiftClosed         = "error the shift is already closed"
+	ErrorCashAuditGettingOrders       = "error getting orders"
+	ErrorCashAuditGettingMovements    = "error getting drawer movements"
+	ErrorCashAuditNotAllOrdersClosed  = "error not all orders closed"
+	ErrorCashAuditInvalidOrderStatus  = "error invalid order status"
+	ErrorCashAuditStoreCurrency       = "error getting store currency denominations"
+	ErrorCashAuditInvalidDenomination = "error denomination does not belong to the store currency"
+	ErrorCashAuditInvalidQuantity     = "error denomination quantity can't be negative"
+	ErrorCashAuditGettingTolerance    = "error getting brand cash audit tolerance"
+	ErrorCashAuditSavingTolerance     = "error saving brand cash audit tolerance"
+	ErrorCashAuditApprovalNotNeeded   = "error cash audit doesn't need approval"
+	ErrorCashAuditApprovalForbidden   = "error account role can't approve cash audits"
+	ErrorCashAuditToleranceForbidden  = "error account role can't change the cash audit tolerance"
+	ErrorCashAuditAlreadyApproved     = "error cash audit already approved"
+	ErrorCashAuditSettlementFile      = "error reading settlement file"
+	ErrorCashAuditSettlementColumns   = "error settlement file must have amount and date columns"
+	ErrorCashAuditSettlementRow       = "error invalid settlement file row"
+	ErrorCashAuditSettlementSeparator = "error settlement decimal separator must be a comma or a dot"
+	ErrorCashAuditSettlementSaving    = "error saving settlement"
+	ErrorCashAuditWithoutShift        = "error cash audit without shift"
 
 	IncomeTypeTip    = "tip"
 	IncomeTypeCash   = "cash"
@@ -27,15 +52,217 @@ const (
 	IncomeTypeCard   = "card"
 	IncomeTypeOther  = "other"
 
-	IncomeDiscrepancyCash = "difference between cash incomes calculated and cash incomes reported"
-	IncomeDiscrepancyCard = "difference between card incomes calculated and card incomes reported"
+	DiscrepancyScopeDrawer        = "drawer"
+	DiscrepancyScopeIncomeType    = "income_type"
+	DiscrepancyScopePaymentMethod = "payment_method"
+
+	SettlementStatusMatched  = "matched"    // Terminal transaction matched with a POS payment
+	SettlementStatusMissing  = "missing"    // POS payment not found in the terminal settlement
+	SettlementStatusNotInPOS = "not_in_pos" // Terminal transaction without a POS payment
+
+	// SettlementMatchWindow is the time difference accepted between a POS payment and its terminal transaction
+	SettlementMatchWindow = 15 * time.Minute
+	// settlementAmountDelta is the amount difference accepted between a POS payment and its terminal transaction
+	settlementAmountDelta = 0.01
 )
 
+// TerminalPaymentMethods are the payment methods charged in the card terminal, the ones in the settlement file
+var TerminalPaymentMethods = []string{
+	payment.PaymentMethodCardVisa,
+	payment.PaymentMethodCardMaster,
+	payment.PaymentMethodCardAmex,
+	payment.PaymentMethodCardDinners,
+	payment.PaymentMethodBold,
+}
+
+// SettlementFormat is the number format of the acquirer settlement file, the colombian acquirers write
+// the amounts with dot thousands and comma decimals.
+type SettlementFormat struct {
+	Thousands string
+	Decimal   string
+}
+
+// NewSettlementFormat returns the settlement format of the decimal separator, the thousands separator is the
+// other one. An empty decimal separator is the colombian format.
+func NewSettlementFormat(decimal string) (SettlementFormat, error) {
+	switch strings.TrimSpace(decimal) {
+	case "", ",":
+		return SettlementFormat{Thousands: ".", Decimal: ","}, nil
+	case ".":
+		return SettlementFormat{Thousands: ",", Decimal: "."}, nil
+	default:
+		return SettlementFormat{}, fmt.Errorf(ErrorCashAuditSettlementSeparator)
+	}
+}
+
+// ParseAmount reads an amount of the settlement file. Misplaced thousands separators and a three digits
+// fraction without thousands separators (45,000 may be forty five or forty five thousand) are ambiguous and
+// rejected.
+func (f SettlementFormat) ParseAmount(value string) (float64, error) {
+	value = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(value), "$"))
+	if value == "" || strings.Count(value, f.Decimal) > 1 {
+		return 0, fmt.Errorf("ambiguous amount %q", value)
+	}
+
+	integer, fraction, hasFraction := strings.Cut(value, f.Decimal)
+	if strings.Contains(fraction, f.Thousands) || (hasFraction && fraction == "") {
+		return 0, fmt.Errorf("ambiguous amount %q", value)
+	}
+
+	sign := ""
+	if strings.HasPrefix(integer, "-") {
+		sign, integer = "-", strings.TrimPrefix(integer, "-")
+	}
+
+	groups := strings.Split(integer, f.Thousands)
+	for i, group := range groups {
+		if (i == 0 && (group == "" || len(group) > 3 && len(groups) > 1)) || (i > 0 && len(group) != 3) {
+			return 0, fmt.Errorf("ambiguous amount %q", value)
+		}
+	}
+
+	if hasFraction && len(groups) == 1 && len(fraction) == 3 {
+		return 0, fmt.Errorf("ambiguous amount %q", value)
+	}
+
+	number := sign + strings.Join(groups, "")
+	if hasFraction {
+		number += "." + fraction
+	}
+
+	return strconv.ParseFloat(number, 64)
+}
+
+// settlementLayouts are the date layouts accepted in the settlement file
+var settlementLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"02/01/2006 15:04:05",
+	"02/01/2006 15:04",
+}
+
 type Repository interface {
-	Get(storeID string) (*CashAudit, error)
+	Get(cashAuditID string) (*CashAudit, error)
 	Update(cashAudit *CashAudit) (*CashAudit, error)
-	GetTodayCashAudit(storeID string) (*CashAudit, error)
+	GetByShift(shiftID uint) (*CashAudit, error)
 	Create(cashAudit *CashAudit) (*CashAudit, error)
+	GetTolerance(brandID string) (*Tolerance, error)
+	SaveTolerance(tolerance *Tolerance) (*Tolerance, error)
+	SaveSettlement(cashAudit *CashAudit) (*CashAudit, error)
+}
+
+// Reconciliation compares the payments of a payment method with the amount reported by the cashier
+// and, once the terminal settlement is imported, with the amount settled by the card terminal.
+type Reconciliation struct {
+	ID                  uint           `json:"id"`
+	CashAuditID         *uint          `json:"cash_audit_id"`
+	PaymentMethodID     *uint          `json:"payment_method_id"`
+	Method              string         `json:"method"`
+	Name                string         `json:"name"`
+	Transactions        int            `json:"transactions"`
+	Tips                float64        `json:"tips" gorm:"precision:18;scale:4"`
+	Expected            float64        `json:"expected" gorm:"precision:18;scale:4"`
+	Reported            float64        `json:"reported" gorm:"precision:18;scale:4"`
+	Difference          float64        `json:"difference" gorm:"precision:18;scale:4"`
+	Settled             float64        `json:"settled" gorm:"precision:18;scale:4"`
+	SettledTransactions int            `json:"settled_transactions"`
+	MissingTransactions int            `json:"missing_transactions"`
+	CreatedAt           *time.Time     `json:"created_at,omitempty" swaggerignore:"true"`
+	UpdatedAt           *time.Time     `json:"updated_at,omitempty" swaggerignore:"true"`
+	DeletedAt           gorm.DeletedAt `json:"deleted_at,omitempty" swaggerignore:"true"`
+}
+
+func (Reconciliation) TableName() string {
+	return "cash_audit_reconciliations"
+}
+
+// Settlement is a transaction of the card terminal settlement file, or a POS payment missing in it
+type Settlement struct {
+	ID          uint           `json:"id"`
+	CashAuditID *uint          `json:"cash_audit_id"`
+	PaymentID   *uint          `json:"payment_id"`
+	Method      string         `json:"method"`
+	Reference   string         `json:"reference"`
+	Card        string         `json:"card"`
+	Amount      float64        `json:"amount" gorm:"precision:18;scale:4"`
+	SettledAt   time.Time      `json:"settled_at"`
+	Status      string         `json:"status" enums:"matched,missing,not_in_pos"`
+	CreatedAt   *time.Time     `json:"created_at,omitempty" swaggerignore:"true"`
+	UpdatedAt   *time.Time     `json:"updated_at,omitempty" swaggerignore:"true"`
+	DeletedAt   gorm.DeletedAt `json:"deleted_at,omitempty" swaggerignore:"true"`
+}
+
+func (Settlement) TableName() string {
+	return "cash_audit_settlements"
+}
+
+// CashCount is the quantity of a bill or coin denomination counted in the drawer
+type CashCount struct {
+	ID             uint           `json:"id"`
+	CashAuditID    *uint          `json:"cash_audit_id"`
+	DenominationID *uint          `json:"denomination_id"`
+	Type           string         `json:"type" enums:"bill,coin"`
+	Value          float64        `json:"value" gorm:"precision:18;scale:4"`
+	Quantity       int            `json:"quantity"`
+	Total          float64        `json:"total" gorm:"precision:18;scale:4"`
+	CreatedAt      *time.Time     `json:"created_at,omitempty" swaggerignore:"true"`
+	UpdatedAt      *time.Time     `json:"updated_at,omitempty" swaggerignore:"true"`
+	DeletedAt      gorm.DeletedAt `json:"deleted_at,omitempty" swaggerignore:"true"`
+}
+
+// Discrepancy compares an expected amount of the cash audit with the amount reported by the cashier
+type Discrepancy struct {
+	ID              uint           `json:"id"`
+	CashAuditID     *uint          `json:"cash_audit_id"`
+	Scope           string         `json:"scope" enums:"drawer,income_type,payment_method"`
+	Name            string         `json:"name"`
+	Expected        float64        `json:"expected" gorm:"precision:18;scale:4"`
+	Reported        float64        `json:"reported" gorm:"precision:18;scale:4"`
+	Difference      float64        `json:"difference" gorm:"precision:18;scale:4"`
+	Tolerance       float64        `json:"tolerance" gorm:"precision:18;scale:4"`
+	WithinTolerance bool           `json:"within_tolerance"`
+	CreatedAt       *time.Time     `json:"created_at,omitempty" swaggerignore:"true"`
+	UpdatedAt       *time.Time     `json:"updated_at,omitempty" swaggerignore:"true"`
+	DeletedAt       gorm.DeletedAt `json:"deleted_at,omitempty" swaggerignore:"true"`
+}
+
+// Tolerance is the difference a brand accepts on the cash audits before a supervisor must approve them,
+// the allowed difference is the greater of the fixed amount and the percentage of the expected amount.
+type Tolerance struct {
+	ID         uint           `json:"id"`
+	BrandID    *uint          `json:"brand_id" gorm:"uniqueIndex"`
+	Amount     float64        `json:"amount" gorm:"precision:18;scale:4"`
+	Percentage float64        `json:"percentage" gorm:"precision:18;scale:4"`
+	CreatedAt  *time.Time     `json:"created_at,omitempty" swaggerignore:"true"`
+	UpdatedAt  *time.Time     `json:"updated_at,omitempty" swaggerignore:"true"`
+	DeletedAt  gorm.DeletedAt `json:"deleted_at,omitempty" swaggerignore:"true"`
+}
+
+func (Tolerance) TableName() string {
+	return "cash_audit_tolerances"
+}
+
+// Allowed returns the difference accepted for the expected amount
+func (t *Tolerance) Allowed(expected float64) float64 {
+	if t == nil {
+		return 0
+	}
+	return math.Max(t.Amount, math.Abs(expected)*t.Percentage/100)
+}
+
+func NewDiscrepancy(scope, name string, expected, reported float64, tolerance *Tolerance) Discrepancy {
+	difference := reported - expected
+	allowed := tolerance.Allowed(expected)
+	return Discrepancy{
+		Scope:           scope,
+		Name:            name,
+		Expected:        expected,
+		Reported:        reported,
+		Difference:      difference,
+		Tolerance:       allowed,
+		WithinTolerance: math.Abs(difference) <= allowed,
+	}
 }
 
 type Income struct {
@@ -53,6 +280,7 @@ type CashAudit struct {
 	ID                uint       `json:"id"`
 	StoreID           *uint      `json:"store_id"`
 	StoreName         string     `json:"store_name"`
+	ShiftID           *uint      `json:"shift_id" gorm:"index"`
 	ShiftOpen         *time.Time `json:"shift_open"`
 	CashierAccountID  *uint      `json:"cashier_account_id"`
 	ShiftStartBalance float64    `json:"shift_start_balance"`
@@ -68,18 +296,35 @@ type CashAudit struct {
 	TotalSell         float64    `json:"total_sell"`
 	BruteSell         float64    `json:"brute_sell"`
 	Incomes           []Income   `json:"total_incomes" gorm:"foreignKey:CashAuditID"`
+	// Drawer section compares the cash expected in the drawer with the cash counted by the cashier
+	CashSales      float64                `json:"cash_sales"`
+	PayIns         float64                `json:"pay_ins"`
+	PayOuts        float64                `json:"pay_outs"`
+	SafeDrops      float64                `json:"safe_drops"`
+	ExpectedCash   float64                `json:"expected_cash"`
+	CountedCash    float64                `json:"counted_cash"`
+	CashDifference float64                `json:"cash_difference"`
+	Movements      []shift.DrawerMovement `json:"movements" gorm:"-"`
+	CashCounts     []CashCount            `json:"cash_counts" gorm:"foreignKey:CashAuditID"`
 	// Reported section is for the reported values from cashier
-	TipsReported         float64        `json:"tips_reported"`
-	TotalSellReported    float64        `json:"total_sell_reported"`
-	CashIncomesReported  float64        `json:"cash_incomes_reported"`
-	OtherIncomesReported float64        `json:"other_incomes_reported"`
-	CardIncomesReported  float64        `json:"card_incomes_reported"`
-	Differences          string         `json:"differences"`                       // To save the differences between calculated and reported founded by system
-	Observations         string         `json:"observations"`                      // To save the observations or issues reported from cashier
-	Confirmation         bool           `json:"confirmation" gorm:"default:false"` // To save the confirmation from cashier
-	CreatedAt            *time.Time     `json:"created_at,omitempty" swaggerignore:"true"`
-	UpdatedAt            *time.Time     `json:"updated_at,omitempty" swaggerignore:"true"`
-	DeletedAt            gorm.DeletedAt `json:"deleted_at,omitempty" swaggerignore:"true"`
+	TipsReported           float64            `json:"tips_reported"`
+	TotalSellReported      float64            `json:"total_sell_reported"`
+	CashIncomesReported    float64            `json:"cash_incomes_reported"`
+	OtherIncomesReported   float64            `json:"other_incomes_reported"`
+	CardIncomesReported    float64            `json:"card_incomes_reported"`
+	PaymentMethodsReported map[string]float64 `json:"payment_methods_reported,omitempty" gorm:"-"` // Reported by payment method, only to build the discrepancies
+	Differences            string             `json:"differences"`                                 // To save the differences between calculated and reported founded by system
+	Discrepancies          []Discrepancy      `json:"discrepancies" gorm:"foreignKey:CashAuditID"`
+	Reconciliations        []Reconciliation   `json:"reconciliations" gorm:"foreignKey:CashAuditID"`
+	Settlements            []Settlement       `json:"settlements" gorm:"foreignKey:CashAuditID"`
+	RequiresApproval       bool               `json:"requires_approval"` // When a discrepancy is out of the brand tolerance
+	ApprovedByAccountID    *uint              `json:"approved_by_account_id"`
+	ApprovedAt             *time.Time         `json:"approved_at"`
+	Observations           string             `json:"observations"`                      // To save the observations or issues reported from cashier
+	Confirmation           bool               `json:"confirmation" gorm:"default:false"` // To save the confirmation from cashier
+	CreatedAt              *time.Time         `json:"created_at,omitempty" swaggerignore:"true"`
+	UpdatedAt              *time.Time         `json:"updated_at,omitempty" swaggerignore:"true"`
+	DeletedAt              gorm.DeletedAt     `json:"deleted_at,omitempty" swaggerignore:"true"`
 }
 
 func GetInvoices(orders []orderPKG.Order) []invoice.Invoice {
@@ -240,12 +485,290 @@ func GetTotalDiscounts(invoices []invoice.Invoice) float64 {
 	return total
 }
 
+func GetCashSales(incomes []Income) float64 {
+	total := 0.0
+	for _, income := range incomes {
+		if income.Type == IncomeTypeCash {
+			total += income.Income
+		}
+	}
+
+	return total
+}
+
+func FilterOrdersByStatus(orders []orderPKG.Order, status string) []orderPKG.Order {
+	filtered := make([]orderPKG.Order, 0)
+	for _, order := range orders {
+		if order.CurrentStatus == status {
+			filtered = append(filtered, order)
+		}
+	}
+	return filtered
+}
+
+// GetOrdersClosed counts the orders in a final status, the closed and the canceled ones.
 func GetOrdersClosed(orders []orderPKG.Order) uint {
 	closed := 0
 	for _, order := range orders {
-		if order.CurrentStatus == orderPKG.OrderStatusClosed {
+		if order.CurrentStatus == orderPKG.OrderStatusClosed || order.CurrentStatus == orderPKG.OrderStatusCanceled {
 			closed++
 		}
 	}
 	return uint(closed)
 }
+
+// GetDiscrepancies compares every expected amount of the cash audit with the reported one: the drawer cash,
+// each income type and, when the cashier reported them, each payment method reconciliation.
+func GetDiscrepancies(cashAudit *CashAudit, tolerance *Tolerance) []Discrepancy {
+	expectedByType := make(map[string]float64)
+	for _, income := range cashAudit.Incomes {
+		if income.Type == IncomeTypeTip {
+			continue
+		}
+		expectedByType[income.Type] += income.Income
+	}
+
+	discrepancies := []Discrepancy{
+		NewDiscrepancy(DiscrepancyScopeDrawer, "cash", cashAudit.ExpectedCash, cashAudit.CountedCash, tolerance),
+		NewDiscrepancy(DiscrepancyScopeIncomeType, IncomeTypeCash, expectedByType[IncomeTypeCash], cashAudit.CashIncomesReported, tolerance),
+		NewDiscrepancy(DiscrepancyScopeIncomeType, IncomeTypeCard, expectedByType[IncomeTypeCard], cashAudit.CardIncomesReported, tolerance),
+		NewDiscrepancy(DiscrepancyScopeIncomeType, IncomeTypeOther, expectedByType[IncomeTypeOther]+expectedByType[IncomeTypeOnline], cashAudit.OtherIncomesReported, tolerance),
+		NewDiscrepancy(DiscrepancyScopeIncomeType, IncomeTypeTip, cashAudit.TotalTipsPayments, cashAudit.TipsReported, tolerance),
+	}
+
+	if len(cashAudit.PaymentMethodsReported) == 0 {
+		return discrepancies
+	}
+
+	for _, reconciliation := range cashAudit.Reconciliations {
+		discrepancies = append(discrepancies,
+			NewDiscrepancy(DiscrepancyScopePaymentMethod, reconciliation.Method, reconciliation.Expected, reconciliation.Reported, tolerance))
+	}
+
+	return discrepancies
+}
+
+// GetDifferences returns a summary of the discrepancies out of tolerance
+func GetDifferences(discrepancies []Discrepancy) string {
+	differences := make([]string, 0)
+	for _, discrepancy := range discrepancies {
+		if !discrepancy.WithinTolerance {
+			differences = append(differences, fmt.Sprintf("%s %s: %.2f", discrepancy.Scope, discrepancy.Name, discrepancy.Difference))
+		}
+	}
+	return strings.Join(differences, "; ")
+}
+
+// paymentMethodKey returns the code and name of the payment method of the payment, falling back to the
+// method string for payments without a payment method.
+func paymentMethodKey(paymnt payment.Payment) (string, string) {
+	if paymnt.PaymentMethod != nil && paymnt.PaymentMethod.Code != "" {
+		return paymnt.PaymentMethod.Code, paymnt.PaymentMethod.Name
+	}
+	return paymnt.Method, paymnt.Method
+}
+
+// GetReconciliations groups the payments by payment method with their transactions and tips
+func GetReconciliations(payments []payment.Payment) []Reconciliation {
+	reconciliations := make(map[string]*Reconciliation)
+	for _, paymnt := range payments {
+		method, name := paymentMethodKey(paymnt)
+		if _, ok := reconciliations[method]; !ok {
+			reconciliations[method] = &Reconciliation{PaymentMethodID: paymnt.PaymentMethodID, Method: method, Name: name}
+		}
+		reconciliations[method].Transactions++
+		reconciliations[method].Tips += paymnt.Tip
+		reconciliations[method].Expected += paymnt.TotalValue
+	}
+
+	reconciliationsSlice := make([]Reconciliation, 0)
+	for _, reconciliation := range reconciliations {
+		reconciliation.Difference = -reconciliation.Expected
+		reconciliationsSlice = append(reconciliationsSlice, *reconciliation)
+	}
+
+	return sortReconciliations(reconciliationsSlice)
+}
+
+// ReportReconciliations sets the amounts reported by the cashier keyed by the payment method code,
+// the reported methods without payments are added too.
+func ReportReconciliations(reconciliations []Reconciliation, reported map[string]float64) []Reconciliation {
+	found := make(map[string]bool)
+	for i := range reconciliations {
+		found[reconciliations[i].Method] = true
+		reconciliations[i].Reported = reported[reconciliations[i].Method]
+		reconciliations[i].Difference = reconciliations[i].Reported - reconciliations[i].Expected
+	}
+
+	for method, amount := range reported {
+		if !found[method] {
+			reconciliations = append(reconciliations, Reconciliation{Method: method, Name: method, Reported: amount, Difference: amount})
+		}
+	}
+
+	return sortReconciliations(reconciliations)
+}
+
+func sortReconciliations(reconciliations []Reconciliation) []Reconciliation {
+	sort.Slice(reconciliations, func(i, j int) bool {
+		return reconciliations[i].Method < reconciliations[j].Method
+	})
+	return reconciliations
+}
+
+func IsTerminalPayment(paymnt payment.Payment) bool {
+	method, _ := paymentMethodKey(paymnt)
+	for _, terminalMethod := range TerminalPaymentMethods {
+		if method == terminalMethod {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseSettlement reads the card terminal settlement CSV file, the header must have the amount and date
+// columns, the reference and card columns are optional. Dates without time zone are read in the location and
+// amounts with the acquirer format.
+func ParseSettlement(file io.Reader, format SettlementFormat, location *time.Location) ([]Settlement, error) {
+	reader := csv.NewReader(file)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		shared.LogWarn("error reading settlement file", LogService, "ParseSettlement", err)
+		return nil, fmt.Errorf(ErrorCashAuditSettlementFile)
+	}
+
+	if len(rows) == 0 {
+		return nil, fmt.Errorf(ErrorCashAuditSettlementColumns)
+	}
+
+	columns := map[string]int{"amount": -1, "date": -1, "reference": -1, "card": -1}
+	aliases := map[string]string{
+		"amount": "amount", "monto": "amount", "valor": "amount",
+		"date": "date", "fecha": "date", "datetime": "date",
+		"reference": "reference", "referencia": "reference", "authorization": "reference", "autorizacion": "reference",
+		"card": "card", "franquicia": "card", "brand": "card",
+	}
+	for i, header := range rows[0] {
+		if column, ok := aliases[strings.ToLower(strings.TrimSpace(header))]; ok {
+			columns[column] = i
+		}
+	}
+
+	if columns["amount"] < 0 || columns["date"] < 0 {
+		return nil, fmt.Errorf(ErrorCashAuditSettlementColumns)
+	}
+
+	settlements := make([]Settlement, 0)
+	for line, row := range rows[1:] {
+		value := func(column string) string {
+			if columns[column] < 0 || columns[column] >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[columns[column]])
+		}
+
+		amount, err := format.ParseAmount(value("amount"))
+		if err != nil {
+			shared.LogWarn("error parsing settlement amount", LogService, "ParseSettlement", err, line+2)
+			return nil, fmt.Errorf("%s %d", ErrorCashAuditSettlementRow, line+2)
+		}
+
+		settledAt, ok := parseSettlementDate(value("date"), location)
+		if !ok {
+			shared.LogWarn("error parsing settlement date", LogService, "ParseSettlement", nil, line+2, value("date"))
+			return nil, fmt.Errorf("%s %d", ErrorCashAuditSettlementRow, line+2)
+		}
+
+		settlements = append(settlements, Settlement{
+			Reference: value("reference"),
+			Card:      value("card"),
+			Amount:    amount,
+			SettledAt: settledAt,
+			Status:    SettlementStatusNotInPOS,
+		})
+	}
+
+	return settlements, nil
+}
+
+func parseSettlementDate(value string, location *time.Location) (time.Time, bool) {
+	for _, layout := range settlementLayouts {
+		if settledAt, err := time.ParseInLocation(layout, value, location); err == nil {
+			return settledAt, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// MatchSettlement matches every terminal payment with the closest settlement transaction of the same amount
+// within SettlementMatchWindow. The terminal payments not matched are added as missing settlements and the
+// reconciliations are updated with the settled amounts.
+func MatchSettlement(payments []payment.Payment, settlements []Settlement, reconciliations []Reconciliation) ([]Settlement, []Reconciliation) {
+	matched := make([]Settlement, len(settlements))
+	copy(matched, settlements)
+
+	missing := make([]Settlement, 0)
+	for _, paymnt := range payments {
+		if !IsTerminalPayment(paymnt) || paymnt.CreatedAt == nil {
+			continue
+		}
+
+		method, _ := paymentMethodKey(paymnt)
+		closest := -1
+		for i, settlement := range matched {
+			if settlement.Status != SettlementStatusNotInPOS || math.Abs(settlement.Amount-paymnt.TotalValue) > settlementAmountDelta {
+				continue
+			}
+
+			gap := settlement.SettledAt.Sub(*paymnt.CreatedAt).Abs()
+			if gap > SettlementMatchWindow {
+				continue
+			}
+
+			if closest < 0 || gap < matched[closest].SettledAt.Sub(*paymnt.CreatedAt).Abs() {
+				closest = i
+			}
+		}
+
+		paymentID := paymnt.ID
+		if closest < 0 {
+			missing = append(missing, Settlement{
+				PaymentID: &paymentID,
+				Method:    method,
+				Reference: paymnt.Code,
+				Amount:    paymnt.TotalValue,
+				SettledAt: *paymnt.CreatedAt,
+				Status:    SettlementStatusMissing,
+			})
+			continue
+		}
+
+		matched[closest].PaymentID = &paymentID
+		matched[closest].Method = method
+		matched[closest].Status = SettlementStatusMatched
+	}
+
+	settled := append(matched, missing...)
+	for i := range reconciliations {
+		reconciliations[i].Settled = 0
+		reconciliations[i].SettledTransactions = 0
+		reconciliations[i].MissingTransactions = 0
+		for _, settlement := range settled {
+			if settlement.Method != reconciliations[i].Method {
+				continue
+			}
+			switch settlement.Status {
+			case SettlementStatusMatched:
+				reconciliations[i].Settled += settlement.Amount
+				reconciliations[i].SettledTransactions++
+			case SettlementStatusMissing:
+				reconciliations[i].MissingTransactions++
+			}
+		}
+	}
+
+	return settled, reconciliations
+}
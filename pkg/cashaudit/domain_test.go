@@ -0,0 +1,101 @@
+package cashaudit_test
+
+import (
+	"github.com/BacoFoods/menu/pkg/cashaudit"
+	"github.com/BacoFoods/menu/pkg/order"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Discrepancies", func() {
+	var cashAudit *cashaudit.CashAudit
+	tolerance := &cashaudit.Tolerance{Amount: 1000, Percentage: 1}
+
+	BeforeEach(func() {
+		cashAudit = &cashaudit.CashAudit{
+			ExpectedCash: 150000,
+			CountedCash:  149500,
+			Incomes: []cashaudit.Income{
+				{Type: cashaudit.IncomeTypeCash, Income: 100000},
+				{Type: cashaudit.IncomeTypeCard, Income: 200000},
+				{Type: cashaudit.IncomeTypeOnline, Income: 30000},
+				{Type: cashaudit.IncomeTypeOther, Income: 20000},
+				{Type: cashaudit.IncomeTypeTip, Income: 8000},
+			},
+			TotalTipsPayments:    8000,
+			CashIncomesReported:  100000,
+			CardIncomesReported:  195000,
+			OtherIncomesReported: 50000,
+			TipsReported:         8000,
+			Reconciliations: []cashaudit.Reconciliation{
+				{Method: "datafono", Expected: 200000, Reported: 195000},
+			},
+		}
+	})
+
+	It("Compares the drawer cash and every income type", func() {
+		discrepancies := cashaudit.GetDiscrepancies(cashAudit, tolerance)
+
+		Expect(discrepancies).To(HaveLen(5))
+		Expect(discrepancies[0].Scope).To(Equal(cashaudit.DiscrepancyScopeDrawer))
+		Expect(discrepancies[0].Difference).To(Equal(-500.0))
+		Expect(discrepancies[0].Tolerance).To(Equal(1500.0))
+		Expect(discrepancies[0].WithinTolerance).To(BeTrue())
+
+		Expect(discrepancies[2].Name).To(Equal(cashaudit.IncomeTypeCard))
+		Expect(discrepancies[2].Difference).To(Equal(-5000.0))
+		Expect(discrepancies[2].Tolerance).To(Equal(2000.0))
+		Expect(discrepancies[2].WithinTolerance).To(BeFalse())
+	})
+
+	It("Adds the online incomes to the other incomes and leaves the tips out of the income types", func() {
+		discrepancies := cashaudit.GetDiscrepancies(cashAudit, tolerance)
+
+		Expect(discrepancies[1].Expected).To(Equal(100000.0))
+		Expect(discrepancies[3].Name).To(Equal(cashaudit.IncomeTypeOther))
+		Expect(discrepancies[3].Expected).To(Equal(50000.0))
+		Expect(discrepancies[3].WithinTolerance).To(BeTrue())
+		Expect(discrepancies[4].Name).To(Equal(cashaudit.IncomeTypeTip))
+		Expect(discrepancies[4].Expected).To(Equal(8000.0))
+	})
+
+	It("Compares the payment methods only when the cashier reported them", func() {
+		cashAudit.PaymentMethodsReported = map[string]float64{"datafono": 195000}
+
+		discrepancies := cashaudit.GetDiscrepancies(cashAudit, tolerance)
+
+		Expect(discrepancies).To(HaveLen(6))
+		Expect(discrepancies[5].Scope).To(Equal(cashaudit.DiscrepancyScopePaymentMethod))
+		Expect(discrepancies[5].Name).To(Equal("datafono"))
+		Expect(discrepancies[5].WithinTolerance).To(BeFalse())
+	})
+
+	It("Accepts no difference without a tolerance", func() {
+		discrepancies := cashaudit.GetDiscrepancies(cashAudit, nil)
+
+		Expect(discrepancies[0].Tolerance).To(BeZero())
+		Expect(discrepancies[0].WithinTolerance).To(BeFalse())
+		Expect(discrepancies[1].WithinTolerance).To(BeTrue())
+	})
+
+	It("Summarizes the discrepancies out of tolerance", func() {
+		differences := cashaudit.GetDifferences(cashaudit.GetDiscrepancies(cashAudit, tolerance))
+
+		Expect(differences).To(Equal("income_type card: -5000.00"))
+	})
+})
+
+var _ = Describe("Orders closed", func() {
+	It("Counts the closed and the canceled orders as final", func() {
+		orders := []order.Order{
+			{CurrentStatus: order.OrderStatusClosed},
+			{CurrentStatus: order.OrderStatusCanceled},
+			{CurrentStatus: order.OrderStatusPaying},
+			{CurrentStatus: order.OrderStatusCreated},
+		}
+
+		Expect(cashaudit.GetOrdersClosed(orders)).To(Equal(uint(2)))
+		Expect(cashaudit.FilterOrdersByStatus(orders, order.OrderStatusCanceled)).To(HaveLen(1))
+		Expect(order.OrderStatusValid(order.OrderStatusCanceled)).To(BeTrue())
+	})
+})
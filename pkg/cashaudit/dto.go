@@ -1,16 +1,59 @@
 package cashaudit
 
+import (
+	"time"
+
+	"github.com/BacoFoods/menu/pkg/shift"
+)
+
 type DTOCashAudit struct {
-	CashIncomesReported  float64 `json:"cash_incomes"`
-	OtherIncomesReported float64 `json:"other_incomes"`
-	CardIncomesReported  float64 `json:"card_incomes"`
+	CashIncomesReported  float64            `json:"cash_incomes"`
+	OtherIncomesReported float64            `json:"other_incomes"`
+	CardIncomesReported  float64            `json:"card_incomes"`
+	TipsReported         float64            `json:"tips"`
+	CountedCash          float64            `json:"counted_cash"` // Used when the cash is not counted by denomination
+	CashCounts           []DTOCashCount     `json:"cash_counts"`
+	PaymentMethods       map[string]float64 `json:"payment_methods" example:"visa:1000,mastercard:500"`
+}
+
+type DTOCashCount struct {
+	DenominationID uint `json:"denomination_id" binding:"required"`
+	Quantity       int  `json:"quantity"`
 }
 
 func (dto DTOCashAudit) ToCashAudit() CashAudit {
+	cashCounts := make([]CashCount, 0)
+	for _, count := range dto.CashCounts {
+		denominationID := count.DenominationID
+		cashCounts = append(cashCounts, CashCount{
+			DenominationID: &denominationID,
+			Quantity:       count.Quantity,
+		})
+	}
+
 	return CashAudit{
-		CashIncomesReported:  dto.CashIncomesReported,
-		CardIncomesReported:  dto.CardIncomesReported,
-		OtherIncomesReported: dto.OtherIncomesReported,
+		CashIncomesReported:    dto.CashIncomesReported,
+		CardIncomesReported:    dto.CardIncomesReported,
+		OtherIncomesReported:   dto.OtherIncomesReported,
+		TipsReported:           dto.TipsReported,
+		CountedCash:            dto.CountedCash,
+		CashCounts:             cashCounts,
+		PaymentMethodsReported: dto.PaymentMethods,
+	}
+}
+
+type DTOTolerance struct {
+	BrandID    uint    `json:"brand_id" binding:"required"`
+	Amount     float64 `json:"amount"`
+	Percentage float64 `json:"percentage"`
+}
+
+func (dto DTOTolerance) ToTolerance() Tolerance {
+	brandID := dto.BrandID
+	return Tolerance{
+		BrandID:    &brandID,
+		Amount:     dto.Amount,
+		Percentage: dto.Percentage,
 	}
 }
 
@@ -20,12 +63,35 @@ type DTOCashAuditConfirmationRequest struct {
 }
 
 type DTOCashAuditCategories struct {
+	ID        uint          `json:"id,omitempty"`
+	ShiftID   *uint         `json:"shift_id"`
 	TotalSell float64       `json:"total_sell"`
 	BruteSell float64       `json:"brute_sell"`
 	Orders    int           `json:"orders_length"`
 	Seats     int           `json:"seats"`
 	Variables []DTOVariable `json:"variables"`
 	Incomes   DTOIncome     `json:"incomes"`
+	Drawer    DTODrawer     `json:"drawer"`
+	// Discrepancies between the expected and reported amounts, out of tolerance ones need a supervisor approval
+	Discrepancies    []Discrepancy `json:"discrepancies"`
+	RequiresApproval bool          `json:"requires_approval"`
+	ApprovedAt       *time.Time    `json:"approved_at"`
+	// Reconciliations by payment method and the card terminal settlement transactions when imported
+	Reconciliations []Reconciliation `json:"reconciliations"`
+	Settlements     []Settlement     `json:"settlements"`
+}
+
+type DTODrawer struct {
+	StartBalance float64                `json:"start_balance"`
+	CashSales    float64                `json:"cash_sales"`
+	PayIns       float64                `json:"pay_ins"`
+	PayOuts      float64                `json:"pay_outs"`
+	SafeDrops    float64                `json:"safe_drops"`
+	Expected     float64                `json:"expected"`
+	Counted      float64                `json:"counted"`
+	Difference   float64                `json:"difference"`
+	Movements    []shift.DrawerMovement `json:"movements"`
+	Counts       []CashCount            `json:"counts"`
 }
 
 type DTOVariable struct {
@@ -84,6 +150,8 @@ func ToDTOCashAuditCategories(cashAudit CashAudit) DTOCashAuditCategories {
 	}
 
 	return DTOCashAuditCategories{
+		ID:        cashAudit.ID,
+		ShiftID:   cashAudit.ShiftID,
 		TotalSell: cashAudit.TotalSell,
 		BruteSell: cashAudit.BruteSell,
 		Orders:    int(cashAudit.Orders),
@@ -105,5 +173,22 @@ func ToDTOCashAuditCategories(cashAudit CashAudit) DTOCashAuditCategories {
 			Cards:  cardIncomes,
 			Others: otherIncomes,
 		},
+		Drawer: DTODrawer{
+			StartBalance: cashAudit.ShiftStartBalance,
+			CashSales:    cashAudit.CashSales,
+			PayIns:       cashAudit.PayIns,
+			PayOuts:      cashAudit.PayOuts,
+			SafeDrops:    cashAudit.SafeDrops,
+			Expected:     cashAudit.ExpectedCash,
+			Counted:      cashAudit.CountedCash,
+			Difference:   cashAudit.CashDifference,
+			Movements:    cashAudit.Movements,
+			Counts:       cashAudit.CashCounts,
+		},
+		Discrepancies:    cashAudit.Discrepancies,
+		RequiresApproval: cashAudit.RequiresApproval,
+		ApprovedAt:       cashAudit.ApprovedAt,
+		Reconciliations:  cashAudit.Reconciliations,
+		Settlements:      cashAudit.Settlements,
 	}
 }
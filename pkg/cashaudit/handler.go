@@ -5,7 +5,6 @@ import (
 	"github.com/BacoFoods/menu/pkg/shared"
 	"github.com/gin-gonic/gin"
 	"net/http"
-	"strconv"
 )
 
 const (
@@ -22,24 +21,26 @@ func NewHandler(service Service) *Handler {
 
 // OrdersClosedValidation to handle orders closed validation request
 // @Tags Cash Audit
-// @Summary To validate if all orders are closed
-// @Description To validate if all orders are closed
+// @Summary To validate if all orders of the shift are closed
+// @Description To validate if all orders of the shift are closed, by default the open shift of the cashier
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
+// @Param shift_id query string false "shift id"
 // @Success 200 {object} object{status=string,data=string}
 // @Failure 400 {object} shared.Response
 // @Failure 422 {object} shared.Response
 // @Failure 401 {object} shared.Response
 // @Router /cash-audit/orders-closed [get]
 func (h Handler) OrdersClosedValidation(c *gin.Context) {
-	storeID, ok := c.Get("store_id")
+	accountID, ok := c.Get("account_id")
 	if !ok {
-		shared.LogWarn("error getting store id", LogHandler, "OrdersClosedValidation", fmt.Errorf(ErrorCashAuditGettingStoreID))
-		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorCashAuditGettingStoreID))
+		shared.LogWarn("error getting account id", LogHandler, "OrdersClosedValidation", fmt.Errorf(ErrorCashAuditGettingAccountID))
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorCashAuditGettingAccountID))
+		return
 	}
 
-	if err := h.service.AllOrdersClosed(storeID.(string)); err != nil {
+	if err := h.service.AllOrdersClosed(c.Query("shift_id"), accountID.(string)); err != nil {
 		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
 		return
 	}
@@ -49,24 +50,26 @@ func (h Handler) OrdersClosedValidation(c *gin.Context) {
 
 // Get to handle get cash audit request
 // @Tags Cash Audit
-// @Summary Just calculate the cash audit only for closed orders
-// @Description Just calculate the cash audit only for closed orders
+// @Summary To get the cash audit of a shift
+// @Description To get the cash audit submitted for the shift or calculate it only for closed orders, by default the open shift of the cashier
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
+// @Param shift_id query string false "shift id"
 // @Success 200 {object} object{status=string,data=DTOCashAuditCategories}
 // @Failure 400 {object} shared.Response
 // @Failure 422 {object} shared.Response
 // @Failure 401 {object} shared.Response
 // @Router /cash-audit [get]
 func (h Handler) Get(c *gin.Context) {
-	storeID, ok := c.Get("store_id")
+	accountID, ok := c.Get("account_id")
 	if !ok {
-		shared.LogWarn("error getting store id", LogHandler, "Get", fmt.Errorf(ErrorCashAuditGettingStoreID))
-		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorCashAuditGettingStoreID))
+		shared.LogWarn("error getting account id", LogHandler, "Get", fmt.Errorf(ErrorCashAuditGettingAccountID))
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorCashAuditGettingAccountID))
+		return
 	}
 
-	cashAudit, err := h.service.Get(storeID.(string))
+	cashAudit, err := h.service.Get(c.Query("shift_id"), accountID.(string))
 	if err != nil {
 		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
 		return
@@ -77,11 +80,12 @@ func (h Handler) Get(c *gin.Context) {
 
 // Create to handle create cash audit request
 // @Tags Cash Audit
-// @Summary To create cash audit only for closed orders
-// @Description To create cash audit only for closed orders, if cash audit already exists, it will return the existing cash audit
+// @Summary To submit the cash audit of a shift
+// @Description To submit the cash audit of the shift only for closed orders, by default the open shift of the cashier, if the shift cash audit already exists, it will return the existing cash audit
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
+// @Param shift_id query string false "shift id"
 // @Param cashAudit body DTOCashAudit true "Cash Audit"
 // @Success 200 {object} object{status=string,data=DTOCashAuditCategories}
 // @Failure 400 {object} shared.Response
@@ -89,21 +93,12 @@ func (h Handler) Get(c *gin.Context) {
 // @Failure 401 {object} shared.Response
 // @Router /cash-audit [post]
 func (h Handler) Create(c *gin.Context) {
-	storeID, ok := c.Get("store_id")
-	if !ok {
-		shared.LogWarn("error getting store id", LogHandler, "Create", fmt.Errorf(ErrorCashAuditGettingStoreID))
-		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorCashAuditGettingStoreID))
-	}
-
 	accountID, ok := c.Get("account_id")
 	if !ok {
-		shared.LogWarn("error getting account id", LogHandler, "Create", fmt.Errorf(ErrorCashAuditGettingStoreID))
-	}
-	u64, err := strconv.ParseUint(accountID.(string), 10, 32)
-	if err != nil {
-		shared.LogWarn("error parsing account id", LogHandler, "Create", err)
+		shared.LogWarn("error getting account id", LogHandler, "Create", fmt.Errorf(ErrorCashAuditGettingAccountID))
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorCashAuditGettingAccountID))
+		return
 	}
-	accountUintID := uint(u64)
 
 	var dtoCashAudit DTOCashAudit
 	if err := c.ShouldBindJSON(&dtoCashAudit); err != nil {
@@ -112,8 +107,7 @@ func (h Handler) Create(c *gin.Context) {
 	}
 
 	cashAudit := dtoCashAudit.ToCashAudit()
-	cashAudit.CashierAccountID = &accountUintID
-	createdCashAudit, err := h.service.Create(storeID.(string), &cashAudit)
+	createdCashAudit, err := h.service.Create(c.Query("shift_id"), accountID.(string), &cashAudit)
 	if err != nil {
 		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
 		return
@@ -149,3 +143,140 @@ func (h Handler) Confirm(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, shared.SuccessResponse(&cashAudit))
 }
+
+// Approve to handle approve cash audit request
+// @Tags Cash Audit
+// @Summary To approve a cash audit out of tolerance
+// @Description To approve a cash audit with discrepancies out of the brand tolerance, only for supervisor roles
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "cash audit id"
+// @Success 200 {object} object{status=string,data=DTOCashAuditCategories}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /cash-audit/{id}/approve [post]
+func (h Handler) Approve(c *gin.Context) {
+	accountID, ok := c.Get("account_id")
+	if !ok {
+		shared.LogWarn("error getting account id", LogHandler, "Approve", fmt.Errorf(ErrorCashAuditGettingAccountID))
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorCashAuditGettingAccountID))
+		return
+	}
+
+	role, _ := c.Get("account_role")
+	cashAudit, err := h.service.Approve(c.Param("id"), accountID.(string), fmt.Sprint(role))
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(ToDTOCashAuditCategories(*cashAudit)))
+}
+
+// GetTolerance to handle get brand tolerance request
+// @Tags Cash Audit
+// @Summary To get the cash audit tolerance of a brand
+// @Description To get the cash audit tolerance of a brand
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "brand id"
+// @Success 200 {object} object{status=string,data=Tolerance}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /cash-audit/tolerance/{id} [get]
+func (h Handler) GetTolerance(c *gin.Context) {
+	tolerance, err := h.service.GetTolerance(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(tolerance))
+}
+
+// SaveTolerance to handle save brand tolerance request
+// @Tags Cash Audit
+// @Summary To save the cash audit tolerance of a brand
+// @Description To save the fixed amount and percentage of difference accepted on the brand cash audits, only for supervisor roles
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param tolerance body DTOTolerance true "Tolerance"
+// @Success 200 {object} object{status=string,data=Tolerance}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Failure 403 {object} shared.Response
+// @Router /cash-audit/tolerance [put]
+func (h Handler) SaveTolerance(c *gin.Context) {
+	var dto DTOTolerance
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	role, _ := c.Get("account_role")
+	tolerance := dto.ToTolerance()
+	toleranceDB, err := h.service.SaveTolerance(&tolerance, fmt.Sprint(role))
+	if err != nil {
+		if err.Error() == ErrorCashAuditToleranceForbidden {
+			c.JSON(http.StatusForbidden, shared.ErrorResponse(err.Error()))
+			return
+		}
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(toleranceDB))
+}
+
+// ImportSettlement to handle import card terminal settlement request
+// @Tags Cash Audit
+// @Summary To import the card terminal settlement of a cash audit
+// @Description To match the card terminal settlement CSV file with the shift card payments by amount and time, the file needs amount and date columns, reference and card are optional
+// @Accept multipart/form-data
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "cash audit id"
+// @Param file formData file true "settlement csv file"
+// @Param decimal_separator formData string false "decimal separator of the amounts, comma by default" Enums(",", ".")
+// @Success 200 {object} object{status=string,data=DTOCashAuditCategories}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /cash-audit/{id}/settlement [post]
+func (h Handler) ImportSettlement(c *gin.Context) {
+	format, err := NewSettlementFormat(c.PostForm("decimal_separator"))
+	if err != nil {
+		shared.LogWarn("error getting settlement format", LogHandler, "ImportSettlement", err, c.PostForm("decimal_separator"))
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		shared.LogWarn("error getting settlement file", LogHandler, "ImportSettlement", err)
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorCashAuditSettlementFile))
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		shared.LogWarn("error opening settlement file", LogHandler, "ImportSettlement", err)
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorCashAuditSettlementFile))
+		return
+	}
+	defer file.Close()
+
+	cashAudit, err := h.service.ImportSettlement(c.Param("id"), file, format)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(ToDTOCashAuditCategories(*cashAudit)))
+}
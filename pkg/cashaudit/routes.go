@@ -15,4 +15,8 @@ func (r Routes) RegisterRoutes(router *shared.CustomRoutes) {
 	router.POST("/cash-audit", r.handler.Create)
 	router.GET("/cash-audit", r.handler.Get)
 	router.POST("/cash-audit/confirm", r.handler.Confirm)
+	router.POST("/cash-audit/:id/approve", r.handler.Approve)
+	router.POST("/cash-audit/:id/settlement", r.handler.ImportSettlement)
+	router.GET("/cash-audit/tolerance/:id", r.handler.GetTolerance)
+	router.PUT("/cash-audit/tolerance", r.handler.SaveTolerance)
 }
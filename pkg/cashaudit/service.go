@@ -2,6 +2,12 @@ package cashaudit
 
 import (
 	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BacoFoods/menu/pkg/currency"
 	"github.com/BacoFoods/menu/pkg/invoice"
 	"github.com/BacoFoods/menu/pkg/order"
 	"github.com/BacoFoods/menu/pkg/shared"
@@ -14,58 +20,102 @@ const (
 )
 
 type Service interface {
-	AllOrdersClosed(storeID string) error
-	Get(storeID string) (*CashAudit, error)
-	Create(storeID string, cashAudit *CashAudit) (*CashAudit, error)
+	AllOrdersClosed(shiftID, accountID string) error
+	Get(shiftID, accountID string) (*CashAudit, error)
+	Create(shiftID, accountID string, cashAudit *CashAudit) (*CashAudit, error)
 	Confirm(cashAuditID, observations string) (*CashAudit, error)
+	Approve(cashAuditID, accountID, role string) (*CashAudit, error)
+	GetTolerance(brandID string) (*Tolerance, error)
+	SaveTolerance(tolerance *Tolerance, role string) (*Tolerance, error)
+	ImportSettlement(cashAuditID string, file io.Reader, format SettlementFormat) (*CashAudit, error)
 }
 
 type service struct {
-	repository Repository
-	stores     store.Repository
-	orders     order.Repository
-	invoices   invoice.Repository
-	shifts     shift.Repository
+	repository    Repository
+	stores        store.Repository
+	orders        order.Repository
+	invoices      invoice.Repository
+	shifts        shift.Repository
+	currencies    currency.Repository
+	approverRoles []string
 }
 
 func NewService(repository Repository,
 	stores store.Repository,
 	orders order.Repository,
 	invoices invoice.Repository,
-	shifts shift.Repository) service {
+	shifts shift.Repository,
+	currencies currency.Repository,
+	approverRoles []string) service {
 	return service{
 		repository,
 		stores,
 		orders,
 		invoices,
 		shifts,
+		currencies,
+		approverRoles,
 	}
 }
 
-func (s service) AllOrdersClosed(storeID string) error {
-	return s.validateAllOrdersClosed(storeID)
-}
+func (s service) AllOrdersClosed(shiftID, accountID string) error {
+	auditShift, err := s.getShift(shiftID, accountID)
+	if err != nil {
+		return err
+	}
 
-func (s service) Get(storeID string) (*CashAudit, error) {
-	return s.calculateCashAudit(storeID, order.OrderStatusClosed)
+	return s.validateAllOrdersClosed(auditShift)
 }
 
-func (s service) Create(storeID string, cashReported *CashAudit) (*CashAudit, error) {
-	if err := s.validateAllOrdersClosed(storeID); err != nil {
+// Get returns the cash audit submitted for the shift, or calculates it when it was not submitted yet.
+func (s service) Get(shiftID, accountID string) (*CashAudit, error) {
+	auditShift, err := s.getShift(shiftID, accountID)
+	if err != nil {
 		return nil, err
 	}
 
-	todayCashAudit, err := s.repository.GetTodayCashAudit(storeID)
+	cashAudit, err := s.repository.GetByShift(auditShift.ID)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorCashAuditGetting)
+	}
+
+	if cashAudit != nil {
+		cashAudit.Movements, err = s.shifts.FindMovements(auditShift.ID)
+		if err != nil {
+			return nil, fmt.Errorf(ErrorCashAuditGettingMovements)
+		}
+		return cashAudit, nil
+	}
+
+	return s.calculateCashAudit(auditShift, order.OrderStatusClosed)
+}
+
+// Create submits the cash audit of the shift with the values reported by the cashier, a shift has
+// only one cash audit so when it already exists it is returned.
+func (s service) Create(shiftID, accountID string, cashReported *CashAudit) (*CashAudit, error) {
+	auditShift, err := s.getShift(shiftID, accountID)
 	if err != nil {
 		return nil, err
 	}
 
-	if todayCashAudit != nil {
-		return todayCashAudit, nil
+	if auditShift.EndTime != nil {
+		return nil, fmt.Errorf(ErrorCashAuditShiftClosed)
 	}
 
-	// Set Store details
-	cashAudit, err := s.calculateCashAudit(storeID, order.OrderStatusClosed)
+	if err := s.validateAllOrdersClosed(auditShift); err != nil {
+		return nil, err
+	}
+
+	shiftCashAudit, err := s.repository.GetByShift(auditShift.ID)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorCashAuditGetting)
+	}
+
+	if shiftCashAudit != nil {
+		return shiftCashAudit, nil
+	}
+
+	cashAudit, err := s.calculateCashAudit(auditShift, order.OrderStatusClosed)
 	if err != nil {
 		return nil, err
 	}
@@ -75,9 +125,35 @@ func (s service) Create(storeID string, cashReported *CashAudit) (*CashAudit, er
 	cashAudit.CashIncomesReported = cashReported.CashIncomesReported
 	cashAudit.OtherIncomesReported = cashReported.OtherIncomesReported
 	cashAudit.CardIncomesReported = cashReported.CardIncomesReported
+	cashAudit.CountedCash = cashReported.CountedCash
+	cashAudit.PaymentMethodsReported = cashReported.PaymentMethodsReported
+	cashAudit.Reconciliations = ReportReconciliations(cashAudit.Reconciliations, cashReported.PaymentMethodsReported)
+
+	auditStore, err := s.stores.Get(fmt.Sprint(*cashAudit.StoreID))
+	if err != nil {
+		shared.LogError("error getting store", LogService, "Create", err, *cashAudit.StoreID)
+		return nil, fmt.Errorf(ErrorCashAuditGettingStore)
+	}
+
+	// Counting the drawer cash by denomination when the cashier reported the counts
+	if err := s.countCash(auditStore, cashAudit, cashReported.CashCounts); err != nil {
+		return nil, err
+	}
+	cashAudit.CashDifference = cashAudit.CountedCash - cashAudit.ExpectedCash
 
-	// Validate discrepancies between reported and calculated
-	cashAudit.Differences = s.validateDiscrepancy(cashAudit)
+	// Validate discrepancies between reported and calculated against the brand tolerance
+	tolerance, err := s.getBrandTolerance(auditStore.BrandID)
+	if err != nil {
+		return nil, err
+	}
+
+	cashAudit.Discrepancies = GetDiscrepancies(cashAudit, tolerance)
+	cashAudit.Differences = GetDifferences(cashAudit.Discrepancies)
+	for _, discrepancy := range cashAudit.Discrepancies {
+		if !discrepancy.WithinTolerance {
+			cashAudit.RequiresApproval = true
+		}
+	}
 
 	// Create cash audit
 	cashAudit, err = s.repository.Create(cashAudit)
@@ -85,6 +161,13 @@ func (s service) Create(storeID string, cashReported *CashAudit) (*CashAudit, er
 		return nil, err
 	}
 
+	// Marking the shift audit as submitted, so the shift can be closed
+	auditShift.CashAuditID = &cashAudit.ID
+	if _, err := s.shifts.Update(auditShift); err != nil {
+		shared.LogError("error setting shift cash audit", LogService, "Create", err, auditShift.ID, cashAudit.ID)
+		return nil, fmt.Errorf(ErrorCashAuditUpdating)
+	}
+
 	return cashAudit, nil
 }
 
@@ -105,8 +188,185 @@ func (s service) Confirm(cashAuditID, observations string) (*CashAudit, error) {
 	return cashAudit, nil
 }
 
-func (s service) calculateCashAudit(storeID string, status string) (*CashAudit, error) {
-	cashAudit := CashAudit{}
+// Approve records the supervisor approval of a cash audit with discrepancies out of the brand tolerance
+func (s service) Approve(cashAuditID, accountID, role string) (*CashAudit, error) {
+	if !s.isApprover(role) {
+		return nil, fmt.Errorf(ErrorCashAuditApprovalForbidden)
+	}
+
+	cashAudit, err := s.repository.Get(cashAuditID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !cashAudit.RequiresApproval {
+		return nil, fmt.Errorf(ErrorCashAuditApprovalNotNeeded)
+	}
+
+	if cashAudit.ApprovedAt != nil {
+		return nil, fmt.Errorf(ErrorCashAuditAlreadyApproved)
+	}
+
+	approverID, err := strconv.ParseUint(accountID, 10, 64)
+	if err != nil {
+		shared.LogWarn("error parsing account id", LogService, "Approve", err, accountID)
+		return nil, fmt.Errorf(ErrorCashAuditGettingAccountID)
+	}
+
+	now := time.Now()
+	approver := uint(approverID)
+	cashAudit.ApprovedByAccountID = &approver
+	cashAudit.ApprovedAt = &now
+
+	return s.repository.Update(cashAudit)
+}
+
+func (s service) GetTolerance(brandID string) (*Tolerance, error) {
+	return s.repository.GetTolerance(brandID)
+}
+
+// SaveTolerance changes the brand tolerance, only the roles that approve the cash audits can change it
+func (s service) SaveTolerance(tolerance *Tolerance, role string) (*Tolerance, error) {
+	if !s.isApprover(role) {
+		return nil, fmt.Errorf(ErrorCashAuditToleranceForbidden)
+	}
+
+	return s.repository.SaveTolerance(tolerance)
+}
+
+// isApprover checks if the account role is one of the supervisor roles
+func (s service) isApprover(role string) bool {
+	for _, approverRole := range s.approverRoles {
+		if strings.EqualFold(strings.TrimSpace(approverRole), role) {
+			return true
+		}
+	}
+	return false
+}
+
+// ImportSettlement matches the card terminal settlement file with the terminal payments of the cash audit shift,
+// a new import replaces the previous one.
+func (s service) ImportSettlement(cashAuditID string, file io.Reader, format SettlementFormat) (*CashAudit, error) {
+	cashAudit, err := s.repository.Get(cashAuditID)
+	if err != nil {
+		return nil, err
+	}
+
+	if cashAudit.ShiftID == nil {
+		return nil, fmt.Errorf(ErrorCashAuditWithoutShift)
+	}
+
+	auditStore, err := s.stores.Get(fmt.Sprint(*cashAudit.StoreID))
+	if err != nil {
+		shared.LogError("error getting store", LogService, "ImportSettlement", err, *cashAudit.StoreID)
+		return nil, fmt.Errorf(ErrorCashAuditGettingStore)
+	}
+
+	settlements, err := ParseSettlement(file, format, auditStore.Location())
+	if err != nil {
+		return nil, err
+	}
+
+	shiftOrders, err := s.orders.FindByShift(*cashAudit.ShiftID)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorCashAuditGettingOrders)
+	}
+	payments := GetPayments(GetInvoices(FilterOrdersByStatus(shiftOrders, order.OrderStatusClosed)))
+
+	cashAudit.Settlements, cashAudit.Reconciliations = MatchSettlement(payments, settlements, cashAudit.Reconciliations)
+
+	return s.repository.SaveSettlement(cashAudit)
+}
+
+// countCash sets the counted cash from the denominations counts, the denominations must be of the store currency.
+func (s service) countCash(auditStore *store.Store, cashAudit *CashAudit, counts []CashCount) error {
+	if len(counts) == 0 {
+		return nil
+	}
+
+	if auditStore.Country == nil || auditStore.Country.CurrencyID == nil {
+		shared.LogWarn("store without currency", LogService, "countCash", nil, auditStore.ID)
+		return fmt.Errorf(ErrorCashAuditStoreCurrency)
+	}
+
+	denominations, err := s.currencies.FindDenominations(fmt.Sprint(*auditStore.Country.CurrencyID))
+	if err != nil {
+		return fmt.Errorf(ErrorCashAuditStoreCurrency)
+	}
+
+	denominationsByID := make(map[uint]currency.Denomination)
+	for _, denomination := range denominations {
+		denominationsByID[denomination.ID] = denomination
+	}
+
+	total := 0.0
+	cashCounts := make([]CashCount, 0)
+	for _, count := range counts {
+		if count.DenominationID == nil {
+			return fmt.Errorf(ErrorCashAuditInvalidDenomination)
+		}
+
+		denomination, ok := denominationsByID[*count.DenominationID]
+		if !ok {
+			return fmt.Errorf(ErrorCashAuditInvalidDenomination)
+		}
+
+		if count.Quantity < 0 {
+			return fmt.Errorf(ErrorCashAuditInvalidQuantity)
+		}
+
+		count.Type = denomination.Type
+		count.Value = denomination.Value
+		count.Total = denomination.Value * float64(count.Quantity)
+		total += count.Total
+		cashCounts = append(cashCounts, count)
+	}
+
+	cashAudit.CashCounts = cashCounts
+	cashAudit.CountedCash = total
+
+	return nil
+}
+
+func (s service) getBrandTolerance(brandID *uint) (*Tolerance, error) {
+	if brandID == nil {
+		return nil, nil
+	}
+
+	return s.repository.GetTolerance(fmt.Sprint(*brandID))
+}
+
+// getShift returns the requested shift, or the open shift of the cashier account when no shift is requested.
+func (s service) getShift(shiftID, accountID string) (*shift.Shift, error) {
+	if shiftID != "" {
+		auditShift, err := s.shifts.Get(shiftID)
+		if err != nil {
+			return nil, fmt.Errorf(ErrorCashAuditGettingShift)
+		}
+		return auditShift, nil
+	}
+
+	auditShift, err := s.shifts.GetOpenShiftByAccount(accountID)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorCashAuditGettingShift)
+	}
+
+	if auditShift == nil {
+		return nil, fmt.Errorf(ErrorCashAuditNoOpenShift)
+	}
+
+	return auditShift, nil
+}
+
+func (s service) calculateCashAudit(auditShift *shift.Shift, status string) (*CashAudit, error) {
+	cashAudit := CashAudit{
+		ShiftID:           &auditShift.ID,
+		ShiftOpen:         auditShift.StartTime,
+		ShiftClose:        auditShift.EndTime,
+		CashierAccountID:  auditShift.AccountID,
+		ShiftStartBalance: auditShift.StartBalance,
+		ShiftEndBalance:   auditShift.EndBalance,
+	}
 
 	// Validate status
 	if !order.OrderStatusValid(status) {
@@ -114,35 +374,51 @@ func (s service) calculateCashAudit(storeID string, status string) (*CashAudit,
 	}
 
 	// Set Store details
+	storeID := ""
+	if auditShift.StoreID != nil {
+		storeID = fmt.Sprint(*auditShift.StoreID)
+	}
 	auditStore, err := s.stores.Get(storeID)
 	if err != nil {
-		shared.LogError("error getting store", LogService, "Get", err, storeID)
+		shared.LogError("error getting store", LogService, "calculateCashAudit", err, storeID)
 		return nil, fmt.Errorf(ErrorCashAuditGettingStore)
 	}
 	cashAudit.StoreID = &auditStore.ID
 	cashAudit.StoreName = auditStore.Name
 
-	// Getting day's orders by status
-	orderList, err := s.orders.GetLastDayOrdersByStatus(storeID, status)
+	// Getting shift's orders, only the ones with the status are accounted
+	shiftOrders, err := s.orders.FindByShift(auditShift.ID)
 	if err != nil {
 		return nil, fmt.Errorf(ErrorCashAuditGettingOrders)
 	}
-
-	if len(orderList) == 0 {
-		return nil, fmt.Errorf(ErrorCashAuditNotOrdersFound)
-	}
+	orderList := FilterOrdersByStatus(shiftOrders, status)
 
 	// Getting invoices from orders
 	invoiceList := GetInvoices(orderList)
 	paymentsList := GetPayments(invoiceList)
 
-	cashAudit.Orders = uint(len(orderList))
-	cashAudit.OrdersClosed = GetOrdersClosed(orderList)
+	cashAudit.Orders = uint(len(shiftOrders))
+	cashAudit.OrdersClosed = GetOrdersClosed(shiftOrders)
 	cashAudit.Eaters = GetTotalEaters(orderList)
 
 	// Setting incomes
 	cashAudit.Incomes = GetIncomes(paymentsList)
 
+	// Setting the reconciliation by payment method
+	cashAudit.Reconciliations = GetReconciliations(paymentsList)
+
+	// Setting drawer cash, the opening balance plus the cash sales and the drawer movements of the shift
+	movements, err := s.shifts.FindMovements(auditShift.ID)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorCashAuditGettingMovements)
+	}
+	cashAudit.Movements = movements
+	cashAudit.PayIns = shift.GetMovementsTotal(movements, shift.DrawerMovementPayIn)
+	cashAudit.PayOuts = shift.GetMovementsTotal(movements, shift.DrawerMovementPayOut)
+	cashAudit.SafeDrops = shift.GetMovementsTotal(movements, shift.DrawerMovementSafeDrop)
+	cashAudit.CashSales = GetCashSales(cashAudit.Incomes)
+	cashAudit.ExpectedCash = cashAudit.ShiftStartBalance + cashAudit.CashSales + shift.GetMovementsCash(movements)
+
 	// Setting tips
 	cashAudit.Incomes = append(cashAudit.Incomes, GetTipIncomes(paymentsList)...)
 
@@ -155,44 +431,13 @@ func (s service) calculateCashAudit(storeID string, status string) (*CashAudit,
 	return &cashAudit, nil
 }
 
-func (s service) validateDiscrepancy(cashAudit *CashAudit) string {
-	cashIncomesTotal := 0.0
-	for _, income := range cashAudit.Incomes {
-		if income.Type == IncomeTypeCash {
-			cashIncomesTotal += income.Income
-		}
-	}
-
-	if cashAudit.CashIncomesReported != cashIncomesTotal {
-		return IncomeDiscrepancyCash
-	}
-
-	cardIncomesTotal := 0.0
-	for _, income := range cashAudit.Incomes {
-		if income.Type == IncomeTypeCard {
-			cardIncomesTotal += income.Income
-		}
-	}
-
-	if cashAudit.CardIncomesReported != cardIncomesTotal {
-		return IncomeDiscrepancyCard
-	}
-
-	return ""
-}
-
-func (s service) validateAllOrdersClosed(storeID string) error {
-	orderClosedList, err := s.orders.GetLastDayOrdersByStatus(storeID, order.OrderStatusClosed)
-	if err != nil {
-		return fmt.Errorf(ErrorCashAuditGettingOrders)
-	}
-
-	orderList, err := s.orders.GetLastDayOrders(storeID)
+func (s service) validateAllOrdersClosed(auditShift *shift.Shift) error {
+	orderList, err := s.orders.FindByShift(auditShift.ID)
 	if err != nil {
 		return fmt.Errorf(ErrorCashAuditGettingOrders)
 	}
 
-	if len(orderClosedList) != len(orderList) {
+	if int(GetOrdersClosed(orderList)) != len(orderList) {
 		return fmt.Errorf(ErrorCashAuditNotAllOrdersClosed)
 	}
 
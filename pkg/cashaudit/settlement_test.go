@@ -0,0 +1,124 @@
+package cashaudit_test
+
+import (
+	"strings"
+	"time"
+
+	"github.com/BacoFoods/menu/pkg/cashaudit"
+	"github.com/BacoFoods/menu/pkg/payment"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Settlement", func() {
+	bogota := time.FixedZone("America/Bogota", -5*60*60)
+	colombian, _ := cashaudit.NewSettlementFormat(",")
+	american, _ := cashaudit.NewSettlementFormat(".")
+
+	Context("Amounts", func() {
+		It("Reads the amounts with the acquirer separators", func() {
+			Expect(colombian.ParseAmount("45.000")).To(Equal(45000.0))
+			Expect(colombian.ParseAmount("$ 1.234.567,89")).To(Equal(1234567.89))
+			Expect(colombian.ParseAmount("45000,5")).To(Equal(45000.5))
+			Expect(american.ParseAmount("45,000")).To(Equal(45000.0))
+			Expect(american.ParseAmount("1,234,567.89")).To(Equal(1234567.89))
+			Expect(american.ParseAmount("-12.50")).To(Equal(-12.5))
+		})
+
+		It("Rejects the ambiguous amounts", func() {
+			for _, value := range []string{"45,000", "45.00", "1.23.456", "1,234,56", "1234.567,00", ".500", "12,", ""} {
+				_, err := colombian.ParseAmount(value)
+				Expect(err).To(HaveOccurred(), value)
+			}
+			_, err := american.ParseAmount("45.000")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("Defaults to the colombian format and rejects other separators", func() {
+			format, err := cashaudit.NewSettlementFormat("")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(format).To(Equal(colombian))
+
+			_, err = cashaudit.NewSettlementFormat(";")
+			Expect(err).To(MatchError(cashaudit.ErrorCashAuditSettlementSeparator))
+		})
+	})
+
+	Context("Parse", func() {
+		It("Reads the file columns by their aliases", func() {
+			file := strings.NewReader("Fecha,Valor,Franquicia,Autorizacion\n" +
+				"2023-10-05 12:30:00,45.000,VISA,123456\n" +
+				"05/10/2023 13:10,\"1.250,50\",MASTER,654321\n")
+
+			settlements, err := cashaudit.ParseSettlement(file, colombian, bogota)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(settlements).To(HaveLen(2))
+			Expect(settlements[0].Amount).To(Equal(45000.0))
+			Expect(settlements[0].Card).To(Equal("VISA"))
+			Expect(settlements[0].Reference).To(Equal("123456"))
+			Expect(settlements[0].SettledAt).To(Equal(time.Date(2023, 10, 5, 12, 30, 0, 0, bogota)))
+			Expect(settlements[0].Status).To(Equal(cashaudit.SettlementStatusNotInPOS))
+			Expect(settlements[1].Amount).To(Equal(1250.5))
+			Expect(settlements[1].SettledAt).To(Equal(time.Date(2023, 10, 5, 13, 10, 0, 0, bogota)))
+		})
+
+		It("Rejects the files without amount or date columns", func() {
+			_, err := cashaudit.ParseSettlement(strings.NewReader("valor,tarjeta\n45.000,VISA\n"), colombian, bogota)
+			Expect(err).To(MatchError(cashaudit.ErrorCashAuditSettlementColumns))
+		})
+
+		It("Reports the line of an ambiguous amount", func() {
+			file := strings.NewReader("fecha,valor\n2023-10-05 12:30:00,45.000\n2023-10-05 12:40:00,\"45,000\"\n")
+
+			_, err := cashaudit.ParseSettlement(file, colombian, bogota)
+			Expect(err).To(MatchError(cashaudit.ErrorCashAuditSettlementRow + " 3"))
+		})
+	})
+
+	Context("Match", func() {
+		at := func(hour, minute int) *time.Time {
+			date := time.Date(2023, 10, 5, hour, minute, 0, 0, bogota)
+			return &date
+		}
+
+		payments := []payment.Payment{
+			{ID: 1, Method: payment.PaymentMethodCardVisa, TotalValue: 45000, CreatedAt: at(12, 30)},
+			{ID: 2, Method: payment.PaymentMethodCardVisa, TotalValue: 45000, CreatedAt: at(12, 50)},
+			{ID: 3, Method: payment.PaymentMethodCardVisa, TotalValue: 20000, CreatedAt: at(13, 0)},
+			{ID: 4, Method: payment.PaymentMethodCash, TotalValue: 20000, CreatedAt: at(13, 0)},
+		}
+
+		It("Matches every terminal payment with the closest settlement of the same amount", func() {
+			settlements := []cashaudit.Settlement{
+				{Amount: 45000, SettledAt: *at(12, 48), Status: cashaudit.SettlementStatusNotInPOS},
+				{Amount: 45000, SettledAt: *at(12, 31), Status: cashaudit.SettlementStatusNotInPOS},
+				{Amount: 20000, SettledAt: *at(13, 40), Status: cashaudit.SettlementStatusNotInPOS},
+			}
+			reconciliations := []cashaudit.Reconciliation{{Method: payment.PaymentMethodCardVisa, Expected: 110000}}
+
+			settled, reconciliations := cashaudit.MatchSettlement(payments, settlements, reconciliations)
+
+			Expect(settled).To(HaveLen(4))
+			Expect(*settled[0].PaymentID).To(Equal(uint(2)))
+			Expect(*settled[1].PaymentID).To(Equal(uint(1)))
+			Expect(settled[1].Status).To(Equal(cashaudit.SettlementStatusMatched))
+			Expect(settled[2].Status).To(Equal(cashaudit.SettlementStatusNotInPOS))
+			Expect(settled[3].Status).To(Equal(cashaudit.SettlementStatusMissing))
+			Expect(*settled[3].PaymentID).To(Equal(uint(3)))
+
+			Expect(reconciliations[0].Settled).To(Equal(90000.0))
+			Expect(reconciliations[0].SettledTransactions).To(Equal(2))
+			Expect(reconciliations[0].MissingTransactions).To(Equal(1))
+		})
+
+		It("Keeps the imported settlements untouched", func() {
+			settlements := []cashaudit.Settlement{{Amount: 45000, SettledAt: *at(12, 30), Status: cashaudit.SettlementStatusNotInPOS}}
+
+			cashaudit.MatchSettlement(payments, settlements, nil)
+
+			Expect(settlements[0].Status).To(Equal(cashaudit.SettlementStatusNotInPOS))
+			Expect(settlements[0].PaymentID).To(BeNil())
+		})
+	})
+})
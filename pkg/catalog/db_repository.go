@@ -0,0 +1,377 @@
+package catalog
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/BacoFoods/menu/pkg/category"
+	"github.com/BacoFoods/menu/pkg/channel"
+	"github.com/BacoFoods/menu/pkg/menu"
+	"github.com/BacoFoods/menu/pkg/product"
+	"github.com/BacoFoods/menu/pkg/shared"
+	"github.com/BacoFoods/menu/pkg/store"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const LogDBRepository = "pkg/catalog/db_repository"
+
+type DBRepository struct {
+	db *gorm.DB
+}
+
+func NewDBRepository(db *gorm.DB) *DBRepository {
+	return &DBRepository{db}
+}
+
+// Snapshot method for load the catalog of a brand in database
+func (r *DBRepository) Snapshot(brandID uint) (*Snapshot, error) {
+	var snapshot Snapshot
+	if err := r.db.Where("brand_id = ?", brandID).Preload("Modifiers").Order("id").
+		Find(&snapshot.Products).Error; err != nil {
+		shared.LogError("error finding products", LogDBRepository, "Snapshot", err, brandID)
+		return nil, err
+	}
+
+	if err := r.db.Where("brand_id = ?", brandID).Preload("Options.Product").Order("id").
+		Find(&snapshot.Modifiers).Error; err != nil {
+		shared.LogError("error finding modifiers", LogDBRepository, "Snapshot", err, brandID)
+		return nil, err
+	}
+
+	if err := r.db.Where("brand_id = ?", brandID).Preload("Products").Order("id").
+		Find(&snapshot.Categories).Error; err != nil {
+		shared.LogError("error finding categories", LogDBRepository, "Snapshot", err, brandID)
+		return nil, err
+	}
+
+	if err := r.db.Where("brand_id = ?", brandID).Preload("Categories").Order("id").
+		Find(&snapshot.Menus).Error; err != nil {
+		shared.LogError("error finding menus", LogDBRepository, "Snapshot", err, brandID)
+		return nil, err
+	}
+
+	productIDs := make([]uint, 0, len(snapshot.Products))
+	for _, p := range snapshot.Products {
+		productIDs = append(productIDs, p.ID)
+	}
+	snapshot.Overriders = make([]product.Overrider, 0)
+	if len(productIDs) > 0 {
+		if err := r.db.Where("product_id IN ?", productIDs).Order("id").Find(&snapshot.Overriders).Error; err != nil {
+			shared.LogError("error finding overriders", LogDBRepository, "Snapshot", err, brandID)
+			return nil, err
+		}
+	}
+
+	if err := r.db.Order("id").Find(&snapshot.Taxes).Error; err != nil {
+		shared.LogError("error finding taxes", LogDBRepository, "Snapshot", err, brandID)
+		return nil, err
+	}
+
+	if err := r.db.Model(&store.Store{}).Where("brand_id = ?", brandID).Pluck("id", &snapshot.StoreIDs).Error; err != nil {
+		shared.LogError("error finding stores", LogDBRepository, "Snapshot", err, brandID)
+		return nil, err
+	}
+
+	if err := r.db.Model(&channel.Channel{}).Where("brand_id = ?", brandID).Pluck("id", &snapshot.ChannelIDs).Error; err != nil {
+		shared.LogError("error finding channels", LogDBRepository, "Snapshot", err, brandID)
+		return nil, err
+	}
+
+	return &snapshot, nil
+}
+
+// Apply method for apply the changes of a catalog import in a transaction in database, the sheets are applied
+// in order so the rows find the products, modifiers and categories created by the former sheets
+func (r *DBRepository) Apply(brandID uint, snapshot *Snapshot, changes []Change) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		applier := newApplier(tx, brandID, snapshot)
+		for _, step := range []func([]Change) error{
+			applier.applyProducts, applier.applyModifiers, applier.applyProductModifiers, applier.applyCategories,
+			applier.applyMenus, applier.applyOverriders,
+		} {
+			if err := step(changes); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		shared.LogError("error applying catalog", LogDBRepository, "Apply", err, brandID)
+		return err
+	}
+
+	return nil
+}
+
+// applier keeps the ids of the catalog by key while the changes are applied
+type applier struct {
+	tx          *gorm.DB
+	brandID     uint
+	snapshot    *Snapshot
+	taxIDs      map[string]uint
+	products    map[string]product.Product
+	modifiers   map[string]product.Modifier
+	categoryIDs map[string]uint
+	menus       map[string]menu.Menu
+	overriders  map[string]product.Overrider
+	created     []product.Product
+}
+
+func newApplier(tx *gorm.DB, brandID uint, snapshot *Snapshot) *applier {
+	a := applier{
+		tx:          tx,
+		brandID:     brandID,
+		snapshot:    snapshot,
+		taxIDs:      make(map[string]uint),
+		products:    make(map[string]product.Product),
+		modifiers:   make(map[string]product.Modifier),
+		categoryIDs: make(map[string]uint),
+		menus:       make(map[string]menu.Menu),
+		overriders:  make(map[string]product.Overrider),
+	}
+
+	for _, tax := range snapshot.Taxes {
+		if _, ok := a.taxIDs[strings.ToLower(tax.Name)]; !ok {
+			a.taxIDs[strings.ToLower(tax.Name)] = tax.ID
+		}
+	}
+
+	skus := make(map[uint]string)
+	for _, p := range snapshot.Products {
+		skus[p.ID] = strings.ToLower(p.SKU)
+		if _, ok := a.products[skus[p.ID]]; !ok {
+			a.products[skus[p.ID]] = p
+		}
+	}
+
+	for _, m := range snapshot.Modifiers {
+		if _, ok := a.modifiers[strings.ToLower(m.Name)]; !ok {
+			a.modifiers[strings.ToLower(m.Name)] = m
+		}
+	}
+
+	for _, c := range snapshot.Categories {
+		if _, ok := a.categoryIDs[strings.ToLower(c.Name)]; !ok {
+			a.categoryIDs[strings.ToLower(c.Name)] = c.ID
+		}
+	}
+
+	for _, m := range snapshot.Menus {
+		if _, ok := a.menus[strings.ToLower(m.Name)]; !ok {
+			a.menus[strings.ToLower(m.Name)] = m
+		}
+	}
+
+	for _, o := range snapshot.Overriders {
+		if o.ProductID == nil || o.PlaceID == nil {
+			continue
+		}
+		key := fmt.Sprintf("%s/%s/%d", skus[*o.ProductID], o.Place, *o.PlaceID)
+		if _, ok := a.overriders[key]; !ok {
+			a.overriders[key] = o
+		}
+	}
+
+	return &a
+}
+
+// each calls the apply function with the created and updated rows of the sheet
+func each(changes []Change, sheet string, apply func(Change) error) error {
+	for _, change := range changes {
+		if change.Sheet != sheet || change.Action == ActionUnchanged {
+			continue
+		}
+		if err := apply(change); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *applier) applyProducts(changes []Change) error {
+	return each(changes, SheetProducts, func(change Change) error {
+		p := product.Product{BrandID: &a.brandID}
+		if change.Action == ActionUpdate {
+			p = a.products[change.Key]
+		}
+		p.Tax, p.Discount, p.Modifiers, p.Components = nil, nil, nil, nil
+
+		SetProduct(&p, change.Values, a.taxIDs)
+		if err := a.tx.Omit(clause.Associations).Save(&p).Error; err != nil {
+			return err
+		}
+
+		a.products[change.Key] = p
+		if change.Action == ActionCreate {
+			a.created = append(a.created, p)
+		}
+		return nil
+	})
+}
+
+func (a *applier) applyModifiers(changes []Change) error {
+	return each(changes, SheetModifiers, func(change Change) error {
+		m := product.Modifier{BrandID: &a.brandID}
+		if change.Action == ActionUpdate {
+			m = a.modifiers[change.Key]
+		}
+		m.Products, m.Options = nil, nil
+
+		SetModifier(&m, change.Values)
+		if err := a.tx.Omit(clause.Associations).Save(&m).Error; err != nil {
+			return err
+		}
+		a.modifiers[change.Key] = m
+
+		if !change.Changed("options") && !change.Changed("defaults") {
+			return nil
+		}
+
+		options := make([]product.Product, 0)
+		for _, sku := range change.List("options") {
+			options = append(options, product.Product{ID: a.products[strings.ToLower(sku)].ID})
+		}
+		if err := a.tx.Model(&m).Omit("Products.*").Association("Products").Replace(options); err != nil {
+			return err
+		}
+
+		defaultIDs := make([]uint, 0)
+		for _, sku := range change.List("defaults") {
+			defaultIDs = append(defaultIDs, a.products[strings.ToLower(sku)].ID)
+		}
+		if err := a.tx.Model(&product.ModifierProduct{}).Where("modifier_id = ?", m.ID).
+			Update("default", false).Error; err != nil {
+			return err
+		}
+		if len(defaultIDs) == 0 {
+			return nil
+		}
+		return a.tx.Model(&product.ModifierProduct{}).Where("modifier_id = ? AND product_id IN ?", m.ID, defaultIDs).
+			Update("default", true).Error
+	})
+}
+
+// applyProductModifiers links the modifier groups to the products once the modifiers of the file are created
+func (a *applier) applyProductModifiers(changes []Change) error {
+	return each(changes, SheetProducts, func(change Change) error {
+		if !change.Changed("modifiers") {
+			return nil
+		}
+
+		modifiers := make([]product.Modifier, 0)
+		for _, name := range change.List("modifiers") {
+			modifiers = append(modifiers, product.Modifier{ID: a.modifiers[strings.ToLower(name)].ID})
+		}
+
+		p := product.Product{ID: a.products[change.Key].ID}
+		return a.tx.Model(&p).Omit("Modifiers.*").Association("Modifiers").Replace(modifiers)
+	})
+}
+
+func (a *applier) applyCategories(changes []Change) error {
+	existing := make(map[uint]category.Category)
+	for _, c := range a.snapshot.Categories {
+		existing[c.ID] = c
+	}
+
+	return each(changes, SheetCategories, func(change Change) error {
+		c := category.Category{BrandID: &a.brandID}
+		if change.Action == ActionUpdate {
+			c = existing[a.categoryIDs[change.Key]]
+		}
+		c.Products = nil
+
+		SetCategory(&c, change.Values)
+		if err := a.tx.Omit(clause.Associations).Save(&c).Error; err != nil {
+			return err
+		}
+		a.categoryIDs[change.Key] = c.ID
+
+		if !change.Changed("products") {
+			return nil
+		}
+
+		products := make([]product.Product, 0)
+		for _, sku := range change.List("products") {
+			products = append(products, product.Product{ID: a.products[strings.ToLower(sku)].ID})
+		}
+		return a.tx.Model(&c).Omit("Products.*").Association("Products").Replace(products)
+	})
+}
+
+func (a *applier) applyMenus(changes []Change) error {
+	return each(changes, SheetMenus, func(change Change) error {
+		m := menu.Menu{BrandID: &a.brandID}
+		if change.Action == ActionUpdate {
+			m = a.menus[change.Key]
+		}
+		m.Categories = nil
+
+		SetMenu(&m, change.Values)
+		if err := a.tx.Omit(clause.Associations).Save(&m).Error; err != nil {
+			return err
+		}
+		a.menus[change.Key] = m
+
+		if !change.Changed("categories") {
+			return nil
+		}
+
+		categories := make([]category.Category, 0)
+		for _, name := range change.List("categories") {
+			categories = append(categories, category.Category{ID: a.categoryIDs[strings.ToLower(name)]})
+		}
+		return a.tx.Model(&m).Omit("Categories.*").Association("Categories").Replace(categories)
+	})
+}
+
+// applyOverriders saves the overriders of the file, the products created without channel overriders get a disabled
+// one for every channel of the brand like the products created one by one
+func (a *applier) applyOverriders(changes []Change) error {
+	err := each(changes, SheetOverriders, func(change Change) error {
+		o := a.overriders[change.Key]
+		if change.Action == ActionCreate {
+			productID := a.products[strings.ToLower(change.Values["sku"])].ID
+			o = product.Overrider{ProductID: &productID}
+		}
+		o.Product, o.Discount = nil, nil
+
+		SetOverrider(&o, change.Values)
+		if err := a.tx.Omit(clause.Associations).Save(&o).Error; err != nil {
+			return err
+		}
+		a.overriders[change.Key] = o
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	defaults := make([]product.Overrider, 0)
+	for _, p := range a.created {
+		for _, channelID := range a.snapshot.ChannelIDs {
+			key := fmt.Sprintf("%s/%s/%d", strings.ToLower(p.SKU), PlaceChannel, channelID)
+			if _, ok := a.overriders[key]; ok {
+				continue
+			}
+
+			productID, placeID := p.ID, channelID
+			defaults = append(defaults, product.Overrider{
+				ProductID:   &productID,
+				Place:       PlaceChannel,
+				PlaceID:     &placeID,
+				Name:        p.Name,
+				Description: p.Description,
+				Image:       p.Image,
+				Price:       p.Price,
+				Enable:      false,
+			})
+		}
+	}
+
+	if len(defaults) == 0 {
+		return nil
+	}
+	return a.tx.Create(&defaults).Error
+}
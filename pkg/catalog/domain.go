@@ -0,0 +1,833 @@
+package catalog
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BacoFoods/menu/pkg/category"
+	"github.com/BacoFoods/menu/pkg/menu"
+	"github.com/BacoFoods/menu/pkg/product"
+	"github.com/BacoFoods/menu/pkg/shared"
+	"github.com/BacoFoods/menu/pkg/taxes"
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	ErrorCatalogBadRequest    = "error bad request"
+	ErrorCatalogBrandRequired = "error catalog needs a brand"
+	ErrorCatalogLoading       = "error loading catalog"
+	ErrorCatalogExporting     = "error exporting catalog"
+	ErrorCatalogApplying      = "error applying catalog import"
+	ErrorCatalogInvalidFormat = "error catalog format must be xlsx or csv"
+	ErrorCatalogInvalidSheet  = "error catalog sheet must be one of products, modifiers, categories, menus or overriders"
+	ErrorCatalogFile          = "error reading catalog file, it must be a CSV or XLSX file"
+	ErrorCatalogColumns       = "error catalog sheet %s needs the %s columns"
+	ErrorCatalogEmpty         = "error catalog file has no known sheets"
+	ErrorCatalogInvalidRows   = "error catalog file has invalid rows, nothing was imported"
+
+	SheetProducts   = "products"
+	SheetModifiers  = "modifiers"
+	SheetCategories = "categories"
+	SheetMenus      = "menus"
+	SheetOverriders = "overriders"
+	SheetTaxes      = "taxes"
+
+	FormatXLSX = "xlsx"
+	FormatCSV  = "csv"
+
+	ActionCreate    = "create"
+	ActionUpdate    = "update"
+	ActionUnchanged = "unchanged"
+
+	PlaceStore   = "store"
+	PlaceChannel = "channel"
+
+	kindText    = "text"
+	kindRef     = "ref" // name of a row of another sheet
+	kindNumber  = "number"
+	kindInteger = "integer"
+	kindBool    = "bool"
+	kindList    = "list"
+
+	// listSeparator splits the SKUs or names of the list columns, like the options of a modifier
+	listSeparator = "|"
+)
+
+type Repository interface {
+	Snapshot(brandID uint) (*Snapshot, error)
+	Apply(brandID uint, snapshot *Snapshot, changes []Change) error
+}
+
+// Column of a catalog sheet, the list columns hold the SKUs or names of other sheets separated by |
+type Column struct {
+	Name string
+	Kind string
+}
+
+// Sheet of the catalog file. The key columns match the rows with the catalog of the brand, a row without a
+// match is created. Read only sheets are exported as a reference and ignored on import.
+type Sheet struct {
+	Name     string
+	Key      []string
+	Columns  []Column
+	ReadOnly bool
+}
+
+// Sheets of the catalog, in the order they are applied so the rows can reference the rows of the former sheets
+var Sheets = []Sheet{
+	{Name: SheetProducts, Key: []string{"sku"}, Columns: []Column{
+		{"sku", kindText}, {"name", kindText}, {"description", kindText}, {"price", kindNumber},
+		{"tax", kindRef}, {"unit", kindText}, {"color", kindText}, {"enabled", kindBool}, {"image", kindText},
+		{"sku_aggregators", kindText}, {"modifiers", kindList},
+	}},
+	{Name: SheetModifiers, Key: []string{"name"}, Columns: []Column{
+		{"name", kindText}, {"description", kindText}, {"apply_price", kindNumber}, {"required", kindBool},
+		{"min_choices", kindInteger}, {"max_choices", kindInteger}, {"free_choices", kindInteger},
+		{"options", kindList}, {"defaults", kindList},
+	}},
+	{Name: SheetCategories, Key: []string{"name"}, Columns: []Column{
+		{"name", kindText}, {"description", kindText}, {"color", kindText}, {"sort_id", kindInteger},
+		{"enable", kindBool}, {"products", kindList},
+	}},
+	{Name: SheetMenus, Key: []string{"name"}, Columns: []Column{
+		{"name", kindText}, {"description", kindText}, {"enable", kindBool}, {"categories", kindList},
+	}},
+	{Name: SheetOverriders, Key: []string{"sku", "place", "place_id"}, Columns: []Column{
+		{"sku", kindText}, {"place", kindText}, {"place_id", kindInteger}, {"name", kindText},
+		{"description", kindText}, {"price", kindNumber}, {"enable", kindBool},
+	}},
+	{Name: SheetTaxes, Key: []string{"name"}, ReadOnly: true, Columns: []Column{
+		{"name", kindText}, {"percentage", kindNumber}, {"description", kindText},
+	}},
+}
+
+// GetSheet returns the catalog sheet with the given name
+func GetSheet(name string) (Sheet, bool) {
+	for _, sheet := range Sheets {
+		if sheet.Name == name {
+			return sheet, true
+		}
+	}
+	return Sheet{}, false
+}
+
+// IsImportable checks the sheet can be read from a CSV file
+func IsImportable(name string) bool {
+	sheet, ok := GetSheet(name)
+	return ok && !sheet.ReadOnly
+}
+
+// Snapshot is the current catalog of a brand with the stores and channels its overriders can point to
+type Snapshot struct {
+	Products   []product.Product
+	Modifiers  []product.Modifier
+	Categories []category.Category
+	Menus      []menu.Menu
+	Overriders []product.Overrider
+	Taxes      []taxes.Tax
+	StoreIDs   []uint
+	ChannelIDs []uint
+}
+
+// Row of a catalog sheet, only the columns of the file are set on the imported rows
+type Row struct {
+	Line   int               `json:"row"`
+	Values map[string]string `json:"values"`
+}
+
+// Rows of the catalog by sheet
+type Rows map[string][]Row
+
+// RowError is a validation error of a row of a sheet, the rows are numbered from the header
+type RowError struct {
+	Sheet string `json:"sheet"`
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// Change is the action of an imported row on the catalog, the fields are the columns that change
+type Change struct {
+	Sheet  string            `json:"sheet"`
+	Row    int               `json:"row"`
+	Key    string            `json:"key"`
+	Action string            `json:"action" enums:"create,update"`
+	Fields []string          `json:"fields"`
+	Values map[string]string `json:"-"`
+}
+
+// SheetSummary counts the actions of the rows of a sheet
+type SheetSummary struct {
+	Sheet     string `json:"sheet"`
+	Created   int    `json:"created"`
+	Updated   int    `json:"updated"`
+	Unchanged int    `json:"unchanged"`
+}
+
+// Import is the result of a catalog import, a dry run returns the changes without applying them. The warnings
+// are the rows skipped without failing the import.
+type Import struct {
+	DryRun   bool           `json:"dry_run"`
+	Summary  []SheetSummary `json:"summary"`
+	Changes  []Change       `json:"changes"`
+	Errors   []RowError     `json:"errors"`
+	Warnings []RowError     `json:"warnings"`
+}
+
+// NewImport summarizes the changes by sheet, the unchanged rows are only counted
+func NewImport(dryRun bool, changes []Change, rowErrors, warnings []RowError) *Import {
+	result := Import{DryRun: dryRun, Summary: make([]SheetSummary, 0), Changes: make([]Change, 0), Errors: rowErrors, Warnings: warnings}
+	for _, sheet := range Sheets {
+		if sheet.ReadOnly {
+			continue
+		}
+
+		summary := SheetSummary{Sheet: sheet.Name}
+		for _, change := range changes {
+			if change.Sheet != sheet.Name {
+				continue
+			}
+
+			switch change.Action {
+			case ActionCreate:
+				summary.Created++
+			case ActionUpdate:
+				summary.Updated++
+			default:
+				summary.Unchanged++
+				continue
+			}
+			result.Changes = append(result.Changes, change)
+		}
+		result.Summary = append(result.Summary, summary)
+	}
+
+	return &result
+}
+
+// Rows returns the current catalog as the rows of its sheets, the same rows are exported and compared on import.
+// The products without SKU and their overriders are exported with an empty SKU, they can't be matched on import.
+func (s Snapshot) Rows() Rows {
+	taxNames := make(map[uint]string)
+	for _, tax := range s.Taxes {
+		taxNames[tax.ID] = tax.Name
+	}
+
+	skus := make(map[uint]string)
+	for _, p := range s.Products {
+		skus[p.ID] = p.SKU
+	}
+
+	rows := make(Rows)
+	add := func(sheetName string, values map[string]string) {
+		sheet, _ := GetSheet(sheetName)
+		normalized, _ := sheet.normalize(values)
+		rows[sheetName] = append(rows[sheetName], Row{Values: normalized})
+	}
+
+	for _, p := range s.Products {
+		tax := ""
+		if p.TaxID != nil {
+			tax = taxNames[*p.TaxID]
+		}
+
+		modifiers := make([]string, 0)
+		for _, modifier := range p.Modifiers {
+			modifiers = append(modifiers, modifier.Name)
+		}
+
+		add(SheetProducts, map[string]string{
+			"sku": p.SKU, "name": p.Name, "description": p.Description, "price": formatNumber(p.Price),
+			"tax": tax, "unit": p.Unit, "color": p.Color, "enabled": strconv.FormatBool(p.Enabled), "image": p.Image,
+			"sku_aggregators": p.SKUAggregators, "modifiers": strings.Join(modifiers, listSeparator),
+		})
+	}
+
+	for _, modifier := range s.Modifiers {
+		options, defaults := make([]string, 0), make([]string, 0)
+		for _, option := range modifier.Options {
+			if option.Product == nil {
+				continue
+			}
+			options = append(options, option.Product.SKU)
+			if option.Default {
+				defaults = append(defaults, option.Product.SKU)
+			}
+		}
+
+		add(SheetModifiers, map[string]string{
+			"name": modifier.Name, "description": modifier.Description, "apply_price": formatNumber(modifier.ApplyPrice),
+			"required": strconv.FormatBool(modifier.Required), "min_choices": strconv.Itoa(modifier.MinChoices),
+			"max_choices": strconv.Itoa(modifier.MaxChoices), "free_choices": strconv.Itoa(modifier.FreeChoices),
+			"options": strings.Join(options, listSeparator), "defaults": strings.Join(defaults, listSeparator),
+		})
+	}
+
+	for _, c := range s.Categories {
+		products := make([]string, 0)
+		for _, p := range c.Products {
+			products = append(products, p.SKU)
+		}
+
+		add(SheetCategories, map[string]string{
+			"name": c.Name, "description": c.Description, "color": c.Color, "sort_id": strconv.Itoa(c.SortID),
+			"enable": strconv.FormatBool(c.Enable), "products": strings.Join(products, listSeparator),
+		})
+	}
+
+	for _, m := range s.Menus {
+		categories := make([]string, 0)
+		for _, c := range m.Categories {
+			categories = append(categories, c.Name)
+		}
+
+		add(SheetMenus, map[string]string{
+			"name": m.Name, "description": m.Description, "enable": strconv.FormatBool(m.Enable),
+			"categories": strings.Join(categories, listSeparator),
+		})
+	}
+
+	for _, overrider := range s.Overriders {
+		if overrider.ProductID == nil || overrider.PlaceID == nil {
+			continue
+		}
+		if _, ok := skus[*overrider.ProductID]; !ok {
+			continue
+		}
+
+		add(SheetOverriders, map[string]string{
+			"sku": skus[*overrider.ProductID], "place": overrider.Place, "place_id": fmt.Sprintf("%d", *overrider.PlaceID),
+			"name": overrider.Name, "description": overrider.Description, "price": formatNumber(overrider.Price),
+			"enable": strconv.FormatBool(overrider.Enable),
+		})
+	}
+
+	for _, tax := range s.Taxes {
+		add(SheetTaxes, map[string]string{
+			"name": tax.Name, "percentage": formatNumber(tax.Percentage), "description": tax.Description,
+		})
+	}
+
+	return rows
+}
+
+// Parse reads the sheets of a catalog file. The XLSX files are read by sheet name and a CSV file holds the
+// given sheet. The header must have the key columns of the sheet, the missing columns are not imported.
+func Parse(file io.Reader, filename, sheetName string) (Rows, error) {
+	tables := make(map[string][][]string)
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".xlsx":
+		workbook, err := excelize.OpenReader(file)
+		if err != nil {
+			shared.LogWarn("error opening catalog file", LogService, "Parse", err, filename)
+			return nil, fmt.Errorf(ErrorCatalogFile)
+		}
+		defer workbook.Close()
+
+		for _, name := range workbook.GetSheetList() {
+			if !IsImportable(strings.ToLower(strings.TrimSpace(name))) {
+				continue
+			}
+
+			rows, err := workbook.GetRows(name)
+			if err != nil {
+				shared.LogWarn("error reading catalog sheet", LogService, "Parse", err, filename, name)
+				return nil, fmt.Errorf(ErrorCatalogFile)
+			}
+			tables[strings.ToLower(strings.TrimSpace(name))] = rows
+		}
+	case ".csv":
+		if !IsImportable(sheetName) {
+			return nil, fmt.Errorf(ErrorCatalogInvalidSheet)
+		}
+
+		reader := csv.NewReader(file)
+		reader.TrimLeadingSpace = true
+		reader.FieldsPerRecord = -1
+
+		rows, err := reader.ReadAll()
+		if err != nil {
+			shared.LogWarn("error reading catalog file", LogService, "Parse", err, filename)
+			return nil, fmt.Errorf(ErrorCatalogFile)
+		}
+		tables[sheetName] = rows
+	default:
+		return nil, fmt.Errorf(ErrorCatalogFile)
+	}
+
+	if len(tables) == 0 {
+		return nil, fmt.Errorf(ErrorCatalogEmpty)
+	}
+
+	result := make(Rows)
+	for name, table := range tables {
+		sheet, _ := GetSheet(name)
+		rows, err := sheet.read(table)
+		if err != nil {
+			return nil, err
+		}
+		result[name] = rows
+	}
+
+	return result, nil
+}
+
+// read maps the columns of the header to the sheet columns and skips the empty rows
+func (s Sheet) read(table [][]string) ([]Row, error) {
+	columns := make(map[string]int)
+	if len(table) > 0 {
+		for i, header := range table[0] {
+			name := strings.ToLower(strings.TrimSpace(header))
+			if _, ok := s.column(name); ok {
+				columns[name] = i
+			}
+		}
+	}
+
+	for _, key := range s.Key {
+		if _, ok := columns[key]; !ok {
+			return nil, fmt.Errorf(ErrorCatalogColumns, s.Name, strings.Join(s.Key, ", "))
+		}
+	}
+
+	rows := make([]Row, 0)
+	for i := 1; i < len(table); i++ {
+		if strings.TrimSpace(strings.Join(table[i], "")) == "" {
+			continue
+		}
+
+		values := make(map[string]string)
+		for name, index := range columns {
+			if index < len(table[i]) {
+				values[name] = strings.TrimSpace(table[i][index])
+			} else {
+				values[name] = ""
+			}
+		}
+		rows = append(rows, Row{Line: i + 1, Values: values})
+	}
+
+	return rows, nil
+}
+
+func (s Sheet) column(name string) (Column, bool) {
+	for _, column := range s.Columns {
+		if column.Name == name {
+			return column, true
+		}
+	}
+	return Column{}, false
+}
+
+// key of a row, the names and SKUs are matched regardless of their case
+func (s Sheet) key(values map[string]string) string {
+	parts := make([]string, 0, len(s.Key))
+	for _, column := range s.Key {
+		if values[column] == "" {
+			return ""
+		}
+		parts = append(parts, strings.ToLower(values[column]))
+	}
+	return strings.Join(parts, "/")
+}
+
+// equal compares the values of a column, the references to other sheets are compared regardless of their case
+func (s Sheet) equal(name, a, b string) bool {
+	if column, _ := s.column(name); column.Kind == kindRef || column.Kind == kindList {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// normalize writes the values of a row in the same way they are exported, so the rows can be compared
+func (s Sheet) normalize(values map[string]string) (map[string]string, error) {
+	normalized := make(map[string]string)
+	for name, value := range values {
+		column, ok := s.column(name)
+		if !ok {
+			continue
+		}
+
+		value = strings.TrimSpace(value)
+		switch column.Kind {
+		case kindNumber:
+			if value == "" {
+				value = "0"
+			}
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s", name)
+			}
+			value = formatNumber(number)
+		case kindInteger:
+			if value == "" {
+				value = "0"
+			}
+			integer, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s", name)
+			}
+			value = strconv.Itoa(integer)
+		case kindBool:
+			switch strings.ToLower(value) {
+			case "true", "1", "yes", "si", "sí", "x":
+				value = "true"
+			case "false", "0", "no", "":
+				value = "false"
+			default:
+				return nil, fmt.Errorf("invalid %s", name)
+			}
+		case kindList:
+			value = strings.Join(parseList(value), listSeparator)
+		case kindText:
+			if name == "place" {
+				value = strings.ToLower(value)
+			}
+		}
+		normalized[name] = value
+	}
+
+	return normalized, nil
+}
+
+// Plan compares the imported rows with the current catalog of the brand. Every row is validated with the
+// current values of the missing columns, the references to other sheets can point to the catalog or the file.
+// The rows without key are invalid, unless they are left as exported, like the products without SKU, those
+// rows are skipped with a warning.
+func Plan(rows Rows, snapshot Snapshot) ([]Change, []RowError, []RowError) {
+	current := make(map[string]map[string]map[string]string)
+	unkeyed := make(map[string][]map[string]string)
+	for name, sheetRows := range snapshot.Rows() {
+		sheet, _ := GetSheet(name)
+		current[name] = make(map[string]map[string]string)
+		for _, row := range sheetRows {
+			key := sheet.key(row.Values)
+			if key == "" {
+				unkeyed[name] = append(unkeyed[name], row.Values)
+				continue
+			}
+			if _, ok := current[name][key]; !ok {
+				current[name][key] = row.Values
+			}
+		}
+	}
+
+	refs := newReferences(snapshot, current, rows)
+	changes := make([]Change, 0)
+	rowErrors := make([]RowError, 0)
+	warnings := make([]RowError, 0)
+	for _, sheet := range Sheets {
+		if sheet.ReadOnly {
+			continue
+		}
+
+		seen := make(map[string]int)
+		for _, row := range rows[sheet.Name] {
+			rowError := func(err string) {
+				rowErrors = append(rowErrors, RowError{Sheet: sheet.Name, Row: row.Line, Error: err})
+			}
+
+			values, err := sheet.normalize(row.Values)
+			if err != nil {
+				rowError(err.Error())
+				continue
+			}
+
+			key := sheet.key(values)
+			if key == "" && sheet.exported(values, unkeyed[sheet.Name]) {
+				missing := make([]string, 0)
+				for _, column := range sheet.Key {
+					if values[column] == "" {
+						missing = append(missing, column)
+					}
+				}
+				warnings = append(warnings, RowError{Sheet: sheet.Name, Row: row.Line,
+					Error: fmt.Sprintf("%s missing, the row is skipped", strings.Join(missing, ", "))})
+				continue
+			}
+			if key == "" {
+				rowError(fmt.Sprintf("%s required", strings.Join(sheet.Key, ", ")))
+				continue
+			}
+			if line, ok := seen[key]; ok {
+				rowError(fmt.Sprintf("duplicated %s of row %d", strings.Join(sheet.Key, ", "), line))
+				continue
+			}
+			seen[key] = row.Line
+
+			existing, exists := current[sheet.Name][key]
+			merged := make(map[string]string)
+			for name, value := range existing {
+				merged[name] = value
+			}
+			for name, value := range values {
+				merged[name] = value
+			}
+
+			if err := refs.validate(sheet.Name, merged); err != nil {
+				rowError(err.Error())
+				continue
+			}
+
+			change := Change{Sheet: sheet.Name, Row: row.Line, Key: key, Action: ActionUnchanged, Values: merged}
+			for name, value := range values {
+				if !exists || !sheet.equal(name, existing[name], value) {
+					change.Fields = append(change.Fields, name)
+				}
+			}
+			sort.Strings(change.Fields)
+
+			if !exists {
+				change.Action = ActionCreate
+			} else if len(change.Fields) > 0 {
+				change.Action = ActionUpdate
+			}
+			changes = append(changes, change)
+		}
+	}
+
+	return changes, rowErrors, warnings
+}
+
+// exported checks the values of the row are the ones of an exported row
+func (s Sheet) exported(values map[string]string, rows []map[string]string) bool {
+	for _, row := range rows {
+		equal := true
+		for name, value := range values {
+			if !s.equal(name, row[name], value) {
+				equal = false
+				break
+			}
+		}
+		if equal {
+			return true
+		}
+	}
+	return false
+}
+
+// references are the keys the rows can point to, by sheet, with the taxes, stores and channels of the brand
+type references map[string]map[string]bool
+
+func newReferences(snapshot Snapshot, current map[string]map[string]map[string]string, rows Rows) references {
+	refs := make(references)
+	for _, sheet := range Sheets {
+		refs[sheet.Name] = make(map[string]bool)
+		for key := range current[sheet.Name] {
+			refs[sheet.Name][key] = true
+		}
+
+		if sheet.ReadOnly {
+			continue
+		}
+		for _, row := range rows[sheet.Name] {
+			if values, err := sheet.normalize(row.Values); err == nil {
+				if key := sheet.key(values); key != "" {
+					refs[sheet.Name][key] = true
+				}
+			}
+		}
+	}
+
+	refs[PlaceStore] = make(map[string]bool)
+	for _, storeID := range snapshot.StoreIDs {
+		refs[PlaceStore][fmt.Sprintf("%d", storeID)] = true
+	}
+	refs[PlaceChannel] = make(map[string]bool)
+	for _, channelID := range snapshot.ChannelIDs {
+		refs[PlaceChannel][fmt.Sprintf("%d", channelID)] = true
+	}
+
+	return refs
+}
+
+// unknown returns the first value of the list without a row in the sheet
+func (r references) unknown(sheet, list string) string {
+	for _, value := range parseList(list) {
+		if !r[sheet][strings.ToLower(value)] {
+			return value
+		}
+	}
+	return ""
+}
+
+func (r references) validate(sheet string, values map[string]string) error {
+	switch sheet {
+	case SheetProducts:
+		if values["name"] == "" {
+			return fmt.Errorf("name required")
+		}
+		if parseNumber(values["price"]) < 0 {
+			return fmt.Errorf("price can't be negative")
+		}
+		if values["tax"] != "" && !r[SheetTaxes][strings.ToLower(values["tax"])] {
+			return fmt.Errorf("unknown tax %s", values["tax"])
+		}
+		if name := r.unknown(SheetModifiers, values["modifiers"]); name != "" {
+			return fmt.Errorf("unknown modifier %s", name)
+		}
+
+	case SheetModifiers:
+		modifier := product.Modifier{
+			Required:    parseBool(values["required"]),
+			MinChoices:  parseInteger(values["min_choices"]),
+			MaxChoices:  parseInteger(values["max_choices"]),
+			FreeChoices: parseInteger(values["free_choices"]),
+		}
+		if err := modifier.ValidateChoices(); err != nil {
+			return err
+		}
+		if sku := r.unknown(SheetProducts, values["options"]); sku != "" {
+			return fmt.Errorf("unknown product %s", sku)
+		}
+
+		options := make(map[string]bool)
+		for _, sku := range parseList(values["options"]) {
+			options[strings.ToLower(sku)] = true
+		}
+		defaults := parseList(values["defaults"])
+		for _, sku := range defaults {
+			if !options[strings.ToLower(sku)] {
+				return fmt.Errorf("default %s is not an option", sku)
+			}
+		}
+		if modifier.MaxChoices > 0 && len(defaults) > modifier.MaxChoices {
+			return fmt.Errorf(product.ErrorModifierDefaults)
+		}
+
+	case SheetCategories:
+		if sku := r.unknown(SheetProducts, values["products"]); sku != "" {
+			return fmt.Errorf("unknown product %s", sku)
+		}
+
+	case SheetMenus:
+		if name := r.unknown(SheetCategories, values["categories"]); name != "" {
+			return fmt.Errorf("unknown category %s", name)
+		}
+
+	case SheetOverriders:
+		if !r[SheetProducts][strings.ToLower(values["sku"])] {
+			return fmt.Errorf("unknown product %s", values["sku"])
+		}
+		if values["place"] != PlaceStore && values["place"] != PlaceChannel {
+			return fmt.Errorf("place must be store or channel")
+		}
+		if !r[values["place"]][values["place_id"]] {
+			return fmt.Errorf("unknown %s %s of the brand", values["place"], values["place_id"])
+		}
+		if parseNumber(values["price"]) < 0 {
+			return fmt.Errorf("price can't be negative")
+		}
+	}
+
+	return nil
+}
+
+// parseList splits a list column, sorted so the lists compare regardless of the order of their values
+func parseList(value string) []string {
+	list := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(value, listSeparator) {
+		item = strings.TrimSpace(item)
+		if item == "" || seen[strings.ToLower(item)] {
+			continue
+		}
+		seen[strings.ToLower(item)] = true
+		list = append(list, item)
+	}
+	sort.Slice(list, func(i, j int) bool { return strings.ToLower(list[i]) < strings.ToLower(list[j]) })
+	return list
+}
+
+func formatNumber(number float64) string {
+	return strconv.FormatFloat(number, 'f', -1, 64)
+}
+
+// the values are normalized before they are parsed, so they are always valid
+func parseNumber(value string) float64 {
+	number, _ := strconv.ParseFloat(value, 64)
+	return number
+}
+
+func parseInteger(value string) int {
+	integer, _ := strconv.Atoi(value)
+	return integer
+}
+
+func parseBool(value string) bool {
+	return value == "true"
+}
+
+// SetProduct sets the values of a products row on the product, the tax is looked up by name
+func SetProduct(p *product.Product, values map[string]string, taxIDs map[string]uint) {
+	p.SKU = values["sku"]
+	p.Name = values["name"]
+	p.Description = values["description"]
+	p.Price = parseNumber(values["price"])
+	p.Unit = values["unit"]
+	p.Color = values["color"]
+	p.Enabled = parseBool(values["enabled"])
+	p.Image = values["image"]
+	p.SKUAggregators = values["sku_aggregators"]
+	p.TaxID = nil
+	if taxID, ok := taxIDs[strings.ToLower(values["tax"])]; ok {
+		p.TaxID = &taxID
+	}
+}
+
+// SetModifier sets the values of a modifiers row on the modifier, its options are set apart
+func SetModifier(m *product.Modifier, values map[string]string) {
+	m.Name = values["name"]
+	m.Description = values["description"]
+	m.ApplyPrice = parseNumber(values["apply_price"])
+	m.Required = parseBool(values["required"])
+	m.MinChoices = parseInteger(values["min_choices"])
+	m.MaxChoices = parseInteger(values["max_choices"])
+	m.FreeChoices = parseInteger(values["free_choices"])
+}
+
+// SetCategory sets the values of a categories row on the category, its products are set apart
+func SetCategory(c *category.Category, values map[string]string) {
+	c.Name = values["name"]
+	c.Description = values["description"]
+	c.Color = values["color"]
+	c.SortID = parseInteger(values["sort_id"])
+	c.Enable = parseBool(values["enable"])
+}
+
+// SetMenu sets the values of a menus row on the menu, its categories are set apart
+func SetMenu(m *menu.Menu, values map[string]string) {
+	m.Name = values["name"]
+	m.Description = values["description"]
+	m.Enable = parseBool(values["enable"])
+}
+
+// SetOverrider sets the values of an overriders row on the overrider, the product is set apart
+func SetOverrider(o *product.Overrider, values map[string]string) {
+	placeID := uint(parseInteger(values["place_id"]))
+	o.Place = values["place"]
+	o.PlaceID = &placeID
+	o.Name = values["name"]
+	o.Description = values["description"]
+	o.Price = parseNumber(values["price"])
+	o.Enable = parseBool(values["enable"])
+}
+
+// List returns the values of a list column of a change
+func (c Change) List(column string) []string {
+	return parseList(c.Values[column])
+}
+
+// Changed checks if the column changes, the created rows change all the columns of the file
+func (c Change) Changed(column string) bool {
+	for _, field := range c.Fields {
+		if field == column {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,174 @@
+package catalog_test
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/BacoFoods/menu/pkg/catalog"
+	"github.com/BacoFoods/menu/pkg/category"
+	"github.com/BacoFoods/menu/pkg/menu"
+	"github.com/BacoFoods/menu/pkg/product"
+	"github.com/BacoFoods/menu/pkg/taxes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Catalog", func() {
+	id := func(value uint) *uint { return &value }
+
+	salsas := product.Modifier{ID: 5, Name: "Salsas"}
+	baco := product.Product{ID: 10, SKU: "1000", Name: "Baco", Price: 20000, TaxID: id(1), Enabled: true, Modifiers: []product.Modifier{salsas}}
+	soda := product.Product{ID: 11, SKU: "1001", Name: "Soda", Price: 5000, Enabled: true}
+	noSKU := product.Product{ID: 12, Name: "Sin SKU", Price: 1000}
+	salsas.Options = []product.ModifierProduct{{Product: &soda, Default: true}, {Product: &noSKU}}
+	hamburguesas := category.Category{ID: 7, Name: "Hamburguesas", Enable: true, Products: []product.Product{baco, noSKU}}
+
+	snapshot := catalog.Snapshot{
+		Products:   []product.Product{baco, soda, noSKU},
+		Modifiers:  []product.Modifier{salsas},
+		Categories: []category.Category{hamburguesas},
+		Menus:      []menu.Menu{{ID: 3, Name: "Principal", Enable: true, Categories: []category.Category{hamburguesas}}},
+		Overriders: []product.Overrider{
+			{ProductID: id(10), Place: catalog.PlaceStore, PlaceID: id(1), Name: "Baco", Price: 21000, Enable: true},
+			{ProductID: id(12), Place: catalog.PlaceStore, PlaceID: id(1), Price: 1500},
+		},
+		Taxes:      []taxes.Tax{{ID: 1, Name: "ICO", Percentage: 0.08}},
+		StoreIDs:   []uint{1},
+		ChannelIDs: []uint{2},
+	}
+
+	parse := func(sheet, file string) catalog.Rows {
+		rows, err := catalog.Parse(strings.NewReader(file), sheet+".csv", sheet)
+		Expect(err).NotTo(HaveOccurred())
+		return rows
+	}
+
+	Context("Parse", func() {
+		It("Reads the known columns of the header and skips the empty rows", func() {
+			rows := parse(catalog.SheetProducts, "SKU, Name,unknown\n1000,Baco,x\n,,\n1001\n")
+
+			Expect(rows[catalog.SheetProducts]).To(Equal([]catalog.Row{
+				{Line: 2, Values: map[string]string{"sku": "1000", "name": "Baco"}},
+				{Line: 4, Values: map[string]string{"sku": "1001", "name": ""}},
+			}))
+		})
+
+		It("Rejects the sheets without their key columns", func() {
+			_, err := catalog.Parse(strings.NewReader("sku,place\n1000,store\n"), "overriders.csv", catalog.SheetOverriders)
+			Expect(err).To(MatchError("error catalog sheet overriders needs the sku, place, place_id columns"))
+		})
+
+		It("Rejects the read only sheets and the unknown files", func() {
+			_, err := catalog.Parse(strings.NewReader("name\nICO\n"), "taxes.csv", catalog.SheetTaxes)
+			Expect(err).To(MatchError(catalog.ErrorCatalogInvalidSheet))
+
+			_, err = catalog.Parse(strings.NewReader("sku\n1000\n"), "products.txt", catalog.SheetProducts)
+			Expect(err).To(MatchError(catalog.ErrorCatalogFile))
+		})
+	})
+
+	Context("Plan", func() {
+		It("Creates the new rows, updates the changed ones and compares the values as they are exported", func() {
+			rows := parse(catalog.SheetProducts, "sku,name,price,enabled,tax\n1000,Baco,20000.0,si,ico\n1001,Soda,5500,true,\n2000,Papas,7000,x,\n")
+
+			changes, rowErrors, _ := catalog.Plan(rows, snapshot)
+
+			Expect(rowErrors).To(BeEmpty())
+			Expect(changes).To(HaveLen(3))
+			Expect(changes[0].Action).To(Equal(catalog.ActionUnchanged))
+			Expect(changes[1].Action).To(Equal(catalog.ActionUpdate))
+			Expect(changes[1].Fields).To(Equal([]string{"price"}))
+			Expect(changes[1].Values["name"]).To(Equal("Soda"))
+			Expect(changes[2].Action).To(Equal(catalog.ActionCreate))
+			Expect(changes[2].Key).To(Equal("2000"))
+			Expect(changes[2].Values["enabled"]).To(Equal("true"))
+
+			result := catalog.NewImport(true, changes, rowErrors, nil)
+			Expect(result.Summary[0]).To(Equal(catalog.SheetSummary{Sheet: catalog.SheetProducts, Created: 1, Updated: 1, Unchanged: 1}))
+			Expect(result.Changes).To(HaveLen(2))
+		})
+
+		It("Reports the values that can't be normalized", func() {
+			_, rowErrors, _ := catalog.Plan(parse(catalog.SheetProducts, "sku,price,enabled\n1000,abc,true\n1001,5000,maybe\n"), snapshot)
+
+			Expect(rowErrors).To(Equal([]catalog.RowError{
+				{Sheet: catalog.SheetProducts, Row: 2, Error: "invalid price"},
+				{Sheet: catalog.SheetProducts, Row: 3, Error: "invalid enabled"},
+			}))
+		})
+
+		It("Reports the missing and duplicated keys", func() {
+			_, rowErrors, _ := catalog.Plan(parse(catalog.SheetProducts, "sku,name\n1000,Baco\n1000,Otro\n,Nada\n"), snapshot)
+
+			Expect(rowErrors).To(Equal([]catalog.RowError{
+				{Sheet: catalog.SheetProducts, Row: 3, Error: "duplicated sku of row 2"},
+				{Sheet: catalog.SheetProducts, Row: 4, Error: "sku required"},
+			}))
+		})
+
+		It("Reports the references to rows that are neither in the catalog nor in the file", func() {
+			rows := parse(catalog.SheetProducts, "sku,name,tax,modifiers\n3000,X,IVA,\n3001,Y,,Aderezos\n3002,Z,,salsas\n")
+			rows[catalog.SheetModifiers] = parse(catalog.SheetModifiers, "name,options,defaults\nSalsas,1001,1000\nPapas,3002|4000,\n")[catalog.SheetModifiers]
+			rows[catalog.SheetOverriders] = parse(catalog.SheetOverriders, "sku,place,place_id,price\n1000,Store,1,22000\n1000,channel,9,1\n")[catalog.SheetOverriders]
+
+			changes, rowErrors, _ := catalog.Plan(rows, snapshot)
+
+			Expect(rowErrors).To(Equal([]catalog.RowError{
+				{Sheet: catalog.SheetProducts, Row: 2, Error: "unknown tax IVA"},
+				{Sheet: catalog.SheetProducts, Row: 3, Error: "unknown modifier Aderezos"},
+				{Sheet: catalog.SheetModifiers, Row: 2, Error: "default 1000 is not an option"},
+				{Sheet: catalog.SheetModifiers, Row: 3, Error: "unknown product 4000"},
+				{Sheet: catalog.SheetOverriders, Row: 3, Error: "unknown channel 9 of the brand"},
+			}))
+			Expect(changes).To(HaveLen(2))
+			Expect(changes[0].Key).To(Equal("3002"))
+			Expect(changes[1].Key).To(Equal("1000/store/1"))
+			Expect(changes[1].Fields).To(Equal([]string{"price"}))
+		})
+	})
+
+	Context("Round trip", func() {
+		It("Imports the exported catalog without changes or errors", func() {
+			file, err := snapshot.Rows().ToXLSX()
+			Expect(err).NotTo(HaveOccurred())
+
+			rows, err := catalog.Parse(bytes.NewReader(file), "catalog.xlsx", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			changes, rowErrors, warnings := catalog.Plan(rows, snapshot)
+
+			Expect(rowErrors).To(BeEmpty())
+			Expect(warnings).To(HaveLen(2))
+			Expect(changes).To(HaveLen(6))
+			for _, change := range changes {
+				Expect(change.Action).To(Equal(catalog.ActionUnchanged), change.Key)
+			}
+		})
+
+		It("Exports the products without SKU and their overriders with an empty SKU", func() {
+			rows := snapshot.Rows()
+
+			Expect(rows[catalog.SheetProducts]).To(HaveLen(3))
+			Expect(rows[catalog.SheetProducts][2].Values["sku"]).To(BeEmpty())
+			Expect(rows[catalog.SheetProducts][2].Values["name"]).To(Equal("Sin SKU"))
+			Expect(rows[catalog.SheetOverriders]).To(HaveLen(2))
+			Expect(rows[catalog.SheetOverriders][1].Values["sku"]).To(BeEmpty())
+			Expect(rows[catalog.SheetOverriders][1].Values["price"]).To(Equal("1500"))
+			Expect(rows[catalog.SheetCategories][0].Values["products"]).To(Equal("1000"))
+		})
+
+		It("Skips the rows without SKU left as exported and rejects the edited ones", func() {
+			rows := parse(catalog.SheetProducts, "sku,name,price\n,Sin SKU,1000\n,Sin SKU,1200\n")
+			rows[catalog.SheetOverriders] = parse(catalog.SheetOverriders, "sku,place,place_id,price\n,store,1,1500\n")[catalog.SheetOverriders]
+
+			changes, rowErrors, warnings := catalog.Plan(rows, snapshot)
+
+			Expect(changes).To(BeEmpty())
+			Expect(rowErrors).To(Equal([]catalog.RowError{{Sheet: catalog.SheetProducts, Row: 3, Error: "sku required"}}))
+			Expect(warnings).To(Equal([]catalog.RowError{
+				{Sheet: catalog.SheetProducts, Row: 2, Error: "sku missing, the row is skipped"},
+				{Sheet: catalog.SheetOverriders, Row: 2, Error: "sku missing, the row is skipped"},
+			}))
+		})
+	})
+})
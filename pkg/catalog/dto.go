@@ -0,0 +1,87 @@
+package catalog
+
+import (
+	"bytes"
+	"encoding/csv"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// ToXLSX writes a sheet for every catalog sheet, the numbers are written as numbers so they can be edited
+func (r Rows) ToXLSX() ([]byte, error) {
+	file := excelize.NewFile()
+	defer file.Close()
+
+	for i, sheet := range Sheets {
+		if i == 0 {
+			if err := file.SetSheetName("Sheet1", sheet.Name); err != nil {
+				return nil, err
+			}
+		} else if _, err := file.NewSheet(sheet.Name); err != nil {
+			return nil, err
+		}
+
+		header := make([]any, 0, len(sheet.Columns))
+		for _, column := range sheet.Columns {
+			header = append(header, column.Name)
+		}
+
+		lines := [][]any{header}
+		for _, row := range r[sheet.Name] {
+			line := make([]any, 0, len(sheet.Columns))
+			for _, column := range sheet.Columns {
+				if column.Kind == kindNumber || column.Kind == kindInteger {
+					line = append(line, parseNumber(row.Values[column.Name]))
+					continue
+				}
+				line = append(line, row.Values[column.Name])
+			}
+			lines = append(lines, line)
+		}
+
+		for rowIdx, line := range lines {
+			cell, err := excelize.CoordinatesToCellName(1, rowIdx+1)
+			if err != nil {
+				return nil, err
+			}
+			if err := file.SetSheetRow(sheet.Name, cell, &line); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	buffer, err := file.WriteToBuffer()
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// ToCSV writes the rows of one catalog sheet
+func (r Rows) ToCSV(sheetName string) ([]byte, error) {
+	sheet, _ := GetSheet(sheetName)
+
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+
+	header := make([]string, 0, len(sheet.Columns))
+	for _, column := range sheet.Columns {
+		header = append(header, column.Name)
+	}
+
+	lines := [][]string{header}
+	for _, row := range r[sheet.Name] {
+		line := make([]string, 0, len(sheet.Columns))
+		for _, column := range sheet.Columns {
+			line = append(line, row.Values[column.Name])
+		}
+		lines = append(lines, line)
+	}
+
+	if err := writer.WriteAll(lines); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,117 @@
+package catalog
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/BacoFoods/menu/pkg/shared"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	LogHandler = "pkg/catalog/handler"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service}
+}
+
+// Export to handle a request to export the catalog of a brand
+// @Tags Catalog
+// @Summary To export the catalog
+// @Description To export the products, modifiers, categories, menus and store and channel overriders of a brand. The xlsx format has a sheet by entity and a taxes sheet as a reference, the csv format exports the given sheet. The list columns hold SKUs or names separated by |.
+// @Param brand_id query string true "brand id"
+// @Param format query string false "export format" Enums(xlsx, csv) default(xlsx)
+// @Param sheet query string false "sheet of the csv export" Enums(products, modifiers, categories, menus, overriders, taxes) default(products)
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Produce text/csv
+// @Security ApiKeyAuth
+// @Success 200 {file} file
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /catalog/export [get]
+func (h *Handler) Export(c *gin.Context) {
+	format := c.DefaultQuery("format", FormatXLSX)
+	sheet := c.DefaultQuery("sheet", SheetProducts)
+	if format != FormatXLSX && format != FormatCSV {
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorCatalogInvalidFormat))
+		return
+	}
+
+	file, err := h.service.Export(c.Query("brand_id"), format, sheet)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	filename := fmt.Sprintf("catalog-%s.xlsx", c.Query("brand_id"))
+	contentType := "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	if format == FormatCSV {
+		filename = fmt.Sprintf("catalog-%s-%s.csv", c.Query("brand_id"), sheet)
+		contentType = "text/csv"
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	c.Header("Access-Control-Expose-Headers", "Content-Disposition")
+	c.Data(http.StatusOK, contentType, file)
+}
+
+// Import to handle a request to import the catalog of a brand
+// @Tags Catalog
+// @Summary To import the catalog
+// @Description To create and update the products, modifiers, categories, menus and overriders of a brand from a file with the export layout. The rows are matched by sku, name or sku, place and place_id, the missing columns are kept. Nothing is imported when a row is invalid, the errors are returned by sheet and row. A dry run returns the changes without applying them.
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "CSV or XLSX catalog file"
+// @Param brand_id query string true "brand id"
+// @Param sheet query string false "sheet of a csv file" Enums(products, modifiers, categories, menus, overriders)
+// @Param dry_run query bool false "validate and diff without applying"
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=Import}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} object{message=string,data=Import}
+// @Failure 401 {object} shared.Response
+// @Router /catalog/import [post]
+func (h *Handler) Import(c *gin.Context) {
+	dryRun := false
+	if value := c.Query("dry_run"); value != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorCatalogBadRequest))
+			return
+		}
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		shared.LogWarn("warning getting catalog file", LogHandler, "Import", err)
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorCatalogBadRequest))
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		shared.LogWarn("warning opening catalog file", LogHandler, "Import", err)
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorCatalogFile))
+		return
+	}
+	defer file.Close()
+
+	result, err := h.service.Import(c.Query("brand_id"), file, fileHeader.Filename, c.Query("sheet"), dryRun)
+	if err != nil {
+		if result != nil {
+			c.JSON(http.StatusUnprocessableEntity, shared.Response{Message: err.Error(), Data: result})
+			return
+		}
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(result))
+}
@@ -0,0 +1,16 @@
+package catalog
+
+import "github.com/BacoFoods/menu/pkg/shared"
+
+type Routes struct {
+	handler *Handler
+}
+
+func NewRoutes(handler *Handler) Routes {
+	return Routes{handler}
+}
+
+func (r Routes) RegisterRoutes(router *shared.CustomRoutes) {
+	router.GET("/catalog/export", r.handler.Export)
+	router.POST("/catalog/import", r.handler.Import)
+}
@@ -0,0 +1,108 @@
+package catalog
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/BacoFoods/menu/pkg/menucache"
+	"github.com/BacoFoods/menu/pkg/shared"
+)
+
+const (
+	LogService = "pkg/catalog/service"
+)
+
+type Service interface {
+	Export(brandID, format, sheet string) ([]byte, error)
+	Import(brandID string, file io.Reader, filename, sheet string, dryRun bool) (*Import, error)
+}
+
+type service struct {
+	repository Repository
+	cache      menucache.Invalidator
+}
+
+func NewService(repository Repository, cache menucache.Invalidator) service {
+	return service{repository, cache}
+}
+
+// Export writes the catalog of the brand as a XLSX workbook with a sheet by entity, or one sheet as CSV
+func (s service) Export(brandID, format, sheet string) ([]byte, error) {
+	if format != FormatXLSX && format != FormatCSV {
+		return nil, fmt.Errorf(ErrorCatalogInvalidFormat)
+	}
+	if _, ok := GetSheet(sheet); format == FormatCSV && !ok {
+		return nil, fmt.Errorf(ErrorCatalogInvalidSheet)
+	}
+
+	snapshot, err := s.snapshot(brandID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := snapshot.Rows()
+	var file []byte
+	if format == FormatXLSX {
+		file, err = rows.ToXLSX()
+	} else {
+		file, err = rows.ToCSV(sheet)
+	}
+	if err != nil {
+		shared.LogError("error writing catalog", LogService, "Export", err, brandID, format)
+		return nil, fmt.Errorf(ErrorCatalogExporting)
+	}
+
+	return file, nil
+}
+
+// Import validates the rows of the file against the catalog of the brand and applies them in a transaction.
+// Nothing is applied when a row is invalid or on a dry run, the changes, the row errors and the skipped rows
+// are returned.
+func (s service) Import(brandID string, file io.Reader, filename, sheet string, dryRun bool) (*Import, error) {
+	snapshot, err := s.snapshot(brandID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := Parse(file, filename, sheet)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, rowErrors, warnings := Plan(rows, *snapshot)
+	result := NewImport(dryRun, changes, rowErrors, warnings)
+	if len(rowErrors) > 0 {
+		return result, fmt.Errorf(ErrorCatalogInvalidRows)
+	}
+
+	if dryRun || len(result.Changes) == 0 {
+		return result, nil
+	}
+
+	id, _ := strconv.ParseUint(brandID, 10, 64)
+	if err := s.repository.Apply(uint(id), snapshot, changes); err != nil {
+		return nil, fmt.Errorf(ErrorCatalogApplying)
+	}
+
+	// An import may touch any record of the brand, all its cached menus are dropped
+	if s.cache != nil {
+		s.cache.Invalidate(menucache.BrandTag(uint(id)))
+	}
+
+	return result, nil
+}
+
+func (s service) snapshot(brandID string) (*Snapshot, error) {
+	id, err := strconv.ParseUint(brandID, 10, 64)
+	if err != nil || id == 0 {
+		return nil, fmt.Errorf(ErrorCatalogBrandRequired)
+	}
+
+	snapshot, err := s.repository.Snapshot(uint(id))
+	if err != nil {
+		return nil, fmt.Errorf(ErrorCatalogLoading)
+	}
+
+	return snapshot, nil
+}
@@ -1,6 +1,7 @@
 package category
 
 import (
+	"github.com/BacoFoods/menu/pkg/menucache"
 	"github.com/BacoFoods/menu/pkg/product"
 )
 
@@ -18,10 +19,24 @@ type Service interface {
 type service struct {
 	repository Repository
 	product    product.Repository
+	cache      menucache.Invalidator
 }
 
-func NewService(repository Repository, product product.Repository) service {
-	return service{repository, product}
+func NewService(repository Repository, product product.Repository, cache menucache.Invalidator) service {
+	return service{repository, product, cache}
+}
+
+// invalidate drops the cached menus with the category, and those of its brand the category may appear in
+func (s service) invalidate(category *Category) {
+	if s.cache == nil || category == nil {
+		return
+	}
+
+	tags := []menucache.Tag{menucache.CategoryTag(category.ID)}
+	if category.BrandID != nil {
+		tags = append(tags, menucache.BrandTag(*category.BrandID))
+	}
+	s.cache.Invalidate(tags...)
 }
 
 func (s service) Find(filter map[string]string) ([]Category, error) {
@@ -37,11 +52,23 @@ func (s service) Create(category *Category) (*Category, error) {
 }
 
 func (s service) Update(category *Category) (*Category, error) {
-	return s.repository.Update(category)
+	categoryDB, err := s.repository.Update(category)
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidate(categoryDB)
+	return categoryDB, nil
 }
 
 func (s service) Delete(categoryID string) (*Category, error) {
-	return s.repository.Delete(categoryID)
+	categoryDB, err := s.repository.Delete(categoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidate(categoryDB)
+	return categoryDB, nil
 }
 
 func (s service) GetMenus(categoryID string) ([]MenusCategory, error) {
@@ -49,9 +76,25 @@ func (s service) GetMenus(categoryID string) ([]MenusCategory, error) {
 }
 
 func (s service) AddProduct(categoryID, productID uint) (*Category, error) {
-	return s.repository.AddProduct(categoryID, productID)
+	categoryDB, err := s.repository.AddProduct(categoryID, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.cache != nil {
+		s.cache.Invalidate(menucache.CategoryTag(categoryID))
+	}
+	return categoryDB, nil
 }
 
 func (s service) RemoveProduct(categoryID, productID uint) (*Category, error) {
-	return s.repository.RemoveProduct(categoryID, productID)
+	categoryDB, err := s.repository.RemoveProduct(categoryID, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.cache != nil {
+		s.cache.Invalidate(menucache.CategoryTag(categoryID))
+	}
+	return categoryDB, nil
 }
@@ -47,8 +47,8 @@ var _ = Describe("Category", func() {
 		menuRepository := menu.NewDBRepository(db)
 		overriderRepository := product.NewDBRepository(db)
 		availabilityRepository := availability.NewDBRepository(db)
-		srv = category.NewService(categoryRepository, productRepository)
-		menuSrv = menu.NewService(menuRepository, overriderRepository, availabilityRepository, store.NewDBRepository(db), categoryRepository)
+		srv = category.NewService(categoryRepository, productRepository, nil)
+		menuSrv = menu.NewService(menuRepository, overriderRepository, availabilityRepository, store.NewDBRepository(db), categoryRepository, nil, nil, nil)
 	})
 
 	BeforeEach(func() {
@@ -32,6 +32,7 @@ type Country struct {
 	CurrencyID *uint              `json:"currency_id"`
 	Currency   *currency.Currency `json:"currency,omitempty" gorm:"foreignKey:CurrencyID"`
 	PhoneCode  string             `json:"phone_code,omitempty"`
+	Timezone   string             `json:"timezone,omitempty" example:"America/Bogota"`
 	CreatedAt  *time.Time         `json:"created_at,omitempty" swaggerignore:"true"`
 	UpdatedAt  *time.Time         `json:"updated_at,omitempty" swaggerignore:"true"`
 	DeletedAt  gorm.DeletedAt     `json:"deleted_at,omitempty" swaggerignore:"true"`
@@ -78,3 +78,28 @@ func (r *DBRepository) Delete(currencyID string) (*Currency, error) {
 	}
 	return &currencyDB, nil
 }
+
+func (r *DBRepository) CreateDenomination(denomination *Denomination) (*Denomination, error) {
+	if err := r.db.Create(denomination).Error; err != nil {
+		shared.LogError("error creating denomination", LogDBRepository, "CreateDenomination", err, denomination)
+		return nil, err
+	}
+	return denomination, nil
+}
+
+func (r *DBRepository) FindDenominations(currencyID string) ([]Denomination, error) {
+	var denominations []Denomination
+	if err := r.db.Where("currency_id = ?", currencyID).Order("value DESC").Find(&denominations).Error; err != nil {
+		shared.LogError("error finding denominations", LogDBRepository, "FindDenominations", err, currencyID)
+		return nil, err
+	}
+	return denominations, nil
+}
+
+func (r *DBRepository) DeleteDenomination(denominationID string) error {
+	if err := r.db.Delete(&Denomination{}, denominationID).Error; err != nil {
+		shared.LogError("error deleting denomination", LogDBRepository, "DeleteDenomination", err, denominationID)
+		return err
+	}
+	return nil
+}
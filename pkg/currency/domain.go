@@ -13,16 +13,26 @@ const (
 	ErrorCurrencyDeleting   string = "error deleting currency"
 
 	ErrorCurrencyIDEmpty string = "error currency id empty"
+
+	ErrorDenominationCreation    string = "error creating denomination"
+	ErrorDenominationFinding     string = "error finding denominations"
+	ErrorDenominationDeleting    string = "error deleting denomination"
+	ErrorDenominationInvalidType string = "error invalid denomination type"
+	ErrorDenominationValue       string = "error denomination value must be greater than zero"
+
+	DenominationTypeBill = "bill"
+	DenominationTypeCoin = "coin"
 )
 
 type Currency struct {
-	ID        uint           `json:"id,omitempty"`
-	Name      string         `json:"name,omitempty"`
-	Code      string         `json:"code,omitempty"`
-	Symbol    string         `json:"symbol,omitempty"`
-	CreatedAt *time.Time     `json:"created_at,omitempty" swaggerignore:"true"`
-	UpdatedAt *time.Time     `json:"updated_at,omitempty" swaggerignore:"true"`
-	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" swaggerignore:"true"`
+	ID            uint           `json:"id,omitempty"`
+	Name          string         `json:"name,omitempty"`
+	Code          string         `json:"code,omitempty"`
+	Symbol        string         `json:"symbol,omitempty"`
+	Denominations []Denomination `json:"denominations,omitempty" gorm:"foreignKey:CurrencyID"`
+	CreatedAt     *time.Time     `json:"created_at,omitempty" swaggerignore:"true"`
+	UpdatedAt     *time.Time     `json:"updated_at,omitempty" swaggerignore:"true"`
+	DeletedAt     gorm.DeletedAt `json:"deleted_at,omitempty" swaggerignore:"true"`
 }
 
 type Repository interface {
@@ -31,4 +41,18 @@ type Repository interface {
 	Get(string) (*Currency, error)
 	Update(Currency) (*Currency, error)
 	Delete(string) (*Currency, error)
+	CreateDenomination(*Denomination) (*Denomination, error)
+	FindDenominations(currencyID string) ([]Denomination, error)
+	DeleteDenomination(denominationID string) error
+}
+
+// Denomination is a bill or coin of a currency counted by the cashiers on the cash audit
+type Denomination struct {
+	ID         uint           `json:"id,omitempty"`
+	CurrencyID *uint          `json:"currency_id"`
+	Type       string         `json:"type" enums:"bill,coin"`
+	Value      float64        `json:"value" gorm:"precision:18;scale:4"`
+	CreatedAt  *time.Time     `json:"created_at,omitempty" swaggerignore:"true"`
+	UpdatedAt  *time.Time     `json:"updated_at,omitempty" swaggerignore:"true"`
+	DeletedAt  gorm.DeletedAt `json:"deleted_at,omitempty" swaggerignore:"true"`
 }
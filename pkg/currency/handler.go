@@ -4,6 +4,7 @@ import (
 	"github.com/BacoFoods/menu/pkg/shared"
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"strconv"
 )
 
 const LogHandler string = "pkg/currency/handler"
@@ -149,3 +150,86 @@ func (h *Handler) Delete(ctx *gin.Context) {
 	}
 	ctx.JSON(http.StatusOK, shared.SuccessResponse(currency))
 }
+
+// CreateDenomination to handle a request for create a denomination of a currency
+// @Tags Currency
+// @Summary To create a currency denomination
+// @Description To create a bill or coin denomination of a currency
+// @Param id path string true "currency id"
+// @Param denomination body Denomination true "denomination request"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=Denomination}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Router /currency/{id}/denomination [post]
+func (h *Handler) CreateDenomination(ctx *gin.Context) {
+	currencyID, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		shared.LogWarn("warning parsing currency id", LogHandler, "CreateDenomination", err)
+		ctx.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorCurrencyBadRequest))
+		return
+	}
+
+	var requestBody Denomination
+	if err := ctx.BindJSON(&requestBody); err != nil {
+		shared.LogWarn("warning binding request fail", LogHandler, "CreateDenomination", err)
+		ctx.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorCurrencyBadRequest))
+		return
+	}
+
+	id := uint(currencyID)
+	requestBody.CurrencyID = &id
+	denomination, err := h.service.CreateDenomination(&requestBody)
+	if err != nil {
+		shared.LogError("error creating denomination", LogHandler, "CreateDenomination", err, requestBody)
+		ctx.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+	ctx.JSON(http.StatusOK, shared.SuccessResponse(denomination))
+}
+
+// FindDenominations to handle a request for find the denominations of a currency
+// @Tags Currency
+// @Summary To find currency denominations
+// @Description To find the bill and coin denominations of a currency
+// @Param id path string true "currency id"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=[]Denomination}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Router /currency/{id}/denominations [get]
+func (h *Handler) FindDenominations(ctx *gin.Context) {
+	denominations, err := h.service.FindDenominations(ctx.Param("id"))
+	if err != nil {
+		shared.LogError("error finding denominations", LogHandler, "FindDenominations", err, ctx.Param("id"))
+		ctx.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(ErrorDenominationFinding))
+		return
+	}
+	ctx.JSON(http.StatusOK, shared.SuccessResponse(denominations))
+}
+
+// DeleteDenomination to handle a request for delete a denomination
+// @Tags Currency
+// @Summary To delete a currency denomination
+// @Description To delete a currency denomination
+// @Param id path string true "denomination id"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=string}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Router /currency/denomination/{id} [delete]
+func (h *Handler) DeleteDenomination(ctx *gin.Context) {
+	denominationID := ctx.Param("id")
+	if err := h.service.DeleteDenomination(denominationID); err != nil {
+		shared.LogError("error deleting denomination", LogHandler, "DeleteDenomination", err, denominationID)
+		ctx.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(ErrorDenominationDeleting))
+		return
+	}
+	ctx.JSON(http.StatusOK, shared.SuccessResponse(denominationID))
+}
@@ -16,4 +16,7 @@ func (r Routes) RegisterRoutes(private *shared.CustomRoutes) {
 	private.GET("/currency/:id", r.handler.Get)
 	private.PATCH("/currency", r.handler.Update)
 	private.DELETE("/currency/:id", r.handler.Delete)
+	private.POST("/currency/:id/denomination", r.handler.CreateDenomination)
+	private.GET("/currency/:id/denominations", r.handler.FindDenominations)
+	private.DELETE("/currency/denomination/:id", r.handler.DeleteDenomination)
 }
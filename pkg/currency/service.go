@@ -1,5 +1,7 @@
 package currency
 
+import "fmt"
+
 type service struct {
 	repository Repository
 }
@@ -28,10 +30,33 @@ func (s service) Delete(currencyID string) (*Currency, error) {
 	return s.repository.Delete(currencyID)
 }
 
+func (s service) CreateDenomination(denomination *Denomination) (*Denomination, error) {
+	if denomination.Type != DenominationTypeBill && denomination.Type != DenominationTypeCoin {
+		return nil, fmt.Errorf(ErrorDenominationInvalidType)
+	}
+
+	if denomination.Value <= 0 {
+		return nil, fmt.Errorf(ErrorDenominationValue)
+	}
+
+	return s.repository.CreateDenomination(denomination)
+}
+
+func (s service) FindDenominations(currencyID string) ([]Denomination, error) {
+	return s.repository.FindDenominations(currencyID)
+}
+
+func (s service) DeleteDenomination(denominationID string) error {
+	return s.repository.DeleteDenomination(denominationID)
+}
+
 type Service interface {
 	Create(*Currency) (*Currency, error)
 	Find(map[string]string) ([]Currency, error)
 	Get(string) (*Currency, error)
 	Update(Currency) (*Currency, error)
 	Delete(string) (*Currency, error)
+	CreateDenomination(*Denomination) (*Denomination, error)
+	FindDenominations(currencyID string) ([]Denomination, error)
+	DeleteDenomination(denominationID string) error
 }
@@ -0,0 +1,179 @@
+package inventory
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/BacoFoods/menu/pkg/shared"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	LogDBRepository = "pkg/inventory/db_repository"
+)
+
+type DBRepository struct {
+	db *gorm.DB
+}
+
+func NewDBRepository(db *gorm.DB) *DBRepository {
+	return &DBRepository{db}
+}
+
+func (r *DBRepository) FindIngredients(filter map[string]string) ([]Ingredient, error) {
+	var ingredients []Ingredient
+	if err := r.db.Where(filter).Order("name").Find(&ingredients).Error; err != nil {
+		shared.LogError("error finding ingredients", LogDBRepository, "FindIngredients", err, filter)
+		return nil, fmt.Errorf(ErrorIngredientFinding)
+	}
+
+	return ingredients, nil
+}
+
+func (r *DBRepository) GetIngredient(ingredientID string) (*Ingredient, error) {
+	var ingredient Ingredient
+	if err := r.db.First(&ingredient, ingredientID).Error; err != nil {
+		shared.LogError("error getting ingredient", LogDBRepository, "GetIngredient", err, ingredientID)
+		return nil, fmt.Errorf(ErrorIngredientGetting)
+	}
+
+	return &ingredient, nil
+}
+
+func (r *DBRepository) CreateIngredient(ingredient *Ingredient) (*Ingredient, error) {
+	if err := r.db.Create(ingredient).Error; err != nil {
+		shared.LogError("error creating ingredient", LogDBRepository, "CreateIngredient", err, *ingredient)
+		return nil, fmt.Errorf(ErrorIngredientCreating)
+	}
+
+	return ingredient, nil
+}
+
+func (r *DBRepository) UpdateIngredient(ingredient *Ingredient) (*Ingredient, error) {
+	if err := r.db.Save(ingredient).Error; err != nil {
+		shared.LogError("error updating ingredient", LogDBRepository, "UpdateIngredient", err, *ingredient)
+		return nil, fmt.Errorf(ErrorIngredientUpdating)
+	}
+
+	return ingredient, nil
+}
+
+func (r *DBRepository) DeleteIngredient(ingredientID string) (*Ingredient, error) {
+	ingredient, err := r.GetIngredient(ingredientID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.db.Delete(ingredient).Error; err != nil {
+		shared.LogError("error deleting ingredient", LogDBRepository, "DeleteIngredient", err, ingredientID)
+		return nil, fmt.Errorf(ErrorIngredientDeleting)
+	}
+
+	return ingredient, nil
+}
+
+// FindRecipes returns the recipe lines of the products with their ingredients
+func (r *DBRepository) FindRecipes(productIDs []uint) ([]RecipeLine, error) {
+	lines := make([]RecipeLine, 0)
+	if len(productIDs) == 0 {
+		return lines, nil
+	}
+
+	if err := r.db.Preload("Ingredient").
+		Where("product_id IN ?", productIDs).
+		Order("product_id, usage, id").
+		Find(&lines).Error; err != nil {
+		shared.LogError("error finding recipes", LogDBRepository, "FindRecipes", err, productIDs)
+		return nil, fmt.Errorf(ErrorRecipeFinding)
+	}
+
+	return lines, nil
+}
+
+// ReplaceRecipe replaces the recipe lines of the product for the usage
+func (r *DBRepository) ReplaceRecipe(productID uint, usage string, lines []RecipeLine) ([]RecipeLine, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("product_id = ? AND usage = ?", productID, usage).Delete(&RecipeLine{}).Error; err != nil {
+			return err
+		}
+
+		if len(lines) == 0 {
+			return nil
+		}
+
+		for i := range lines {
+			lines[i].ID = 0
+			lines[i].ProductID = productID
+			lines[i].Usage = usage
+			lines[i].Ingredient = nil
+		}
+
+		return tx.Create(&lines).Error
+	})
+	if err != nil {
+		shared.LogError("error replacing recipe", LogDBRepository, "ReplaceRecipe", err, productID, usage, lines)
+		return nil, fmt.Errorf(ErrorRecipeSaving)
+	}
+
+	return lines, nil
+}
+
+// CreateMovements records the movements, the sales already recorded for an order are skipped
+func (r *DBRepository) CreateMovements(movements []Movement) error {
+	if len(movements) == 0 {
+		return nil
+	}
+
+	if err := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&movements).Error; err != nil {
+		shared.LogError("error creating movements", LogDBRepository, "CreateMovements", err, movements)
+		return fmt.Errorf(ErrorMovementCreating)
+	}
+
+	return nil
+}
+
+// SumMovements returns the quantity moved of every ingredient by type at the store in the range
+func (r *DBRepository) SumMovements(storeID uint, from, to time.Time) ([]MovementSum, error) {
+	sums := make([]MovementSum, 0)
+	if err := r.db.Model(&Movement{}).
+		Select("ingredient_id, type, SUM(quantity) AS quantity").
+		Where("store_id = ? AND occurred_at >= ? AND occurred_at < ?", storeID, from, to).
+		Group("ingredient_id, type").
+		Scan(&sums).Error; err != nil {
+		shared.LogError("error summing movements", LogDBRepository, "SumMovements", err, storeID, from, to)
+		return nil, fmt.Errorf(ErrorMovementFinding)
+	}
+
+	return sums, nil
+}
+
+func (r *DBRepository) CreateCounts(counts []Count) error {
+	if err := r.db.Create(&counts).Error; err != nil {
+		shared.LogError("error creating counts", LogDBRepository, "CreateCounts", err, counts)
+		return fmt.Errorf(ErrorCountCreating)
+	}
+
+	return nil
+}
+
+// LastCounts returns the last count of every ingredient of the store before the time
+func (r *DBRepository) LastCounts(storeID uint, before time.Time) (map[uint]Count, error) {
+	var counts []Count
+	if err := r.db.Select("DISTINCT ON (ingredient_id) *").
+		Where("store_id = ? AND counted_at < ?", storeID, before).
+		Order("ingredient_id, counted_at DESC, id DESC").
+		Find(&counts).Error; err != nil {
+		shared.LogError("error finding last counts", LogDBRepository, "LastCounts", err, storeID, before)
+		return nil, fmt.Errorf(ErrorCountFinding)
+	}
+
+	lastCounts := make(map[uint]Count)
+	for _, count := range counts {
+		lastCounts[count.IngredientID] = count
+	}
+
+	return lastCounts, nil
+}
+
+var _ Repository = &DBRepository{}
@@ -0,0 +1,377 @@
+package inventory
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/BacoFoods/menu/pkg/order"
+	"gorm.io/gorm"
+)
+
+const (
+	ErrorInventoryBadRequest        = "error bad request"
+	ErrorIngredientFinding          = "error finding ingredients"
+	ErrorIngredientGetting          = "error getting ingredient"
+	ErrorIngredientCreating         = "error creating ingredient"
+	ErrorIngredientUpdating         = "error updating ingredient"
+	ErrorIngredientDeleting         = "error deleting ingredient"
+	ErrorIngredientInvalidUnit      = "error ingredient unit must be one of g, ml or unit"
+	ErrorRecipeFinding              = "error finding recipe"
+	ErrorRecipeSaving               = "error saving recipe"
+	ErrorRecipeInvalidUsage         = "error recipe usage must be item or modifier"
+	ErrorRecipeInvalidQuantity      = "error recipe quantities must be greater than zero"
+	ErrorRecipeDuplicatedIngredient = "error recipe has the same ingredient twice"
+	ErrorMovementCreating           = "error creating inventory movement"
+	ErrorMovementFinding            = "error finding inventory movements"
+	ErrorMovementInvalidType        = "error inventory movement type must be receipt or waste"
+	ErrorMovementInvalidQuantity    = "error inventory movement quantity must be greater than zero"
+	ErrorCountCreating              = "error creating inventory count"
+	ErrorCountFinding               = "error finding inventory counts"
+	ErrorCountInvalidQuantity       = "error inventory counted quantities can't be negative"
+	ErrorCountEmpty                 = "error inventory count has no ingredients"
+	ErrorInventoryGettingStore      = "error getting inventory store"
+	ErrorInventoryFindingStores     = "error finding inventory stores"
+	ErrorInventoryInvalidDates      = "error inventory dates must be formatted as 2006-01-02"
+	ErrorInventoryInvalidRange      = "error inventory start date must be before the end date"
+	ErrorInventoryInvalidFormat     = "error inventory export format must be json or csv"
+	ErrorInventoryExporting         = "error exporting inventory depletion"
+	ErrorInventoryBrandRequired     = "error inventory depletion needs a brand"
+
+	UnitGram       = "g"
+	UnitMilliliter = "ml"
+	UnitPiece      = "unit"
+
+	UsageItem     = "item"
+	UsageModifier = "modifier"
+
+	MovementSale    = "sale"
+	MovementReceipt = "receipt"
+	MovementWaste   = "waste"
+
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+
+	dateLayout = "2006-01-02"
+)
+
+type Repository interface {
+	FindIngredients(filter map[string]string) ([]Ingredient, error)
+	GetIngredient(ingredientID string) (*Ingredient, error)
+	CreateIngredient(*Ingredient) (*Ingredient, error)
+	UpdateIngredient(*Ingredient) (*Ingredient, error)
+	DeleteIngredient(ingredientID string) (*Ingredient, error)
+
+	FindRecipes(productIDs []uint) ([]RecipeLine, error)
+	ReplaceRecipe(productID uint, usage string, lines []RecipeLine) ([]RecipeLine, error)
+
+	CreateMovements(movements []Movement) error
+	SumMovements(storeID uint, from, to time.Time) ([]MovementSum, error)
+	CreateCounts(counts []Count) error
+	LastCounts(storeID uint, before time.Time) (map[uint]Count, error)
+}
+
+// Ingredient is a raw material of the brand recipes, counted in its unit. The reference is the ERP item of the
+// ingredient, used to export the depletion to the warehouses.
+type Ingredient struct {
+	ID        uint            `json:"id"`
+	BrandID   *uint           `json:"brand_id" binding:"required"`
+	Name      string          `json:"name" binding:"required"`
+	Unit      string          `json:"unit" binding:"required" enums:"g,ml,unit"`
+	Reference string          `json:"reference"`
+	Cost      float64         `json:"cost" gorm:"precision:18;scale:4"` // cost of one unit
+	CreatedAt *time.Time      `json:"created_at,omitempty" swaggerignore:"true"`
+	UpdatedAt *time.Time      `json:"updated_at,omitempty" swaggerignore:"true"`
+	DeletedAt *gorm.DeletedAt `json:"deleted_at,omitempty" swaggerignore:"true"`
+}
+
+func IsValidUnit(unit string) bool {
+	switch unit {
+	case UnitGram, UnitMilliliter, UnitPiece:
+		return true
+	}
+	return false
+}
+
+// RecipeLine is the quantity of an ingredient used by a unit of a product, the bill of materials of a product is
+// the set of its lines. A product chosen as a modifier option uses its modifier lines, or its item lines when it
+// has none, so a side sold alone and as an option can have different portions.
+type RecipeLine struct {
+	ID           uint        `json:"id"`
+	ProductID    uint        `json:"product_id" gorm:"uniqueIndex:idx_recipe_line"`
+	Usage        string      `json:"usage" gorm:"uniqueIndex:idx_recipe_line" enums:"item,modifier"`
+	IngredientID uint        `json:"ingredient_id" gorm:"uniqueIndex:idx_recipe_line" binding:"required"`
+	Ingredient   *Ingredient `json:"ingredient,omitempty" gorm:"foreignKey:IngredientID"`
+	Quantity     float64     `json:"quantity" gorm:"precision:18;scale:4" binding:"required"`
+	CreatedAt    *time.Time  `json:"created_at,omitempty" swaggerignore:"true"`
+	UpdatedAt    *time.Time  `json:"updated_at,omitempty" swaggerignore:"true"`
+}
+
+func IsValidUsage(usage string) bool {
+	return usage == UsageItem || usage == UsageModifier
+}
+
+// ValidateRecipe checks the lines of a recipe, every ingredient is used once with a positive quantity
+func ValidateRecipe(lines []RecipeLine) error {
+	seen := make(map[uint]bool)
+	for _, line := range lines {
+		if line.Quantity <= 0 {
+			return fmt.Errorf(ErrorRecipeInvalidQuantity)
+		}
+		if seen[line.IngredientID] {
+			return fmt.Errorf(ErrorRecipeDuplicatedIngredient)
+		}
+		seen[line.IngredientID] = true
+	}
+	return nil
+}
+
+// Recipes are the recipe lines of the products by usage
+type Recipes map[uint]map[string][]RecipeLine
+
+func NewRecipes(lines []RecipeLine) Recipes {
+	recipes := make(Recipes)
+	for _, line := range lines {
+		if recipes[line.ProductID] == nil {
+			recipes[line.ProductID] = make(map[string][]RecipeLine)
+		}
+		recipes[line.ProductID][line.Usage] = append(recipes[line.ProductID][line.Usage], line)
+	}
+	return recipes
+}
+
+// Lines returns the recipe of the product for the usage, the modifier usage falls back to the item recipe
+func (r Recipes) Lines(productID uint, usage string) []RecipeLine {
+	lines := r[productID][usage]
+	if len(lines) == 0 && usage == UsageModifier {
+		return r[productID][UsageItem]
+	}
+	return lines
+}
+
+// Movement is a change of the theoretical stock of an ingredient at a store: the receipts add stock, the sales
+// and the waste take it out with a negative quantity. The sales of an order are recorded once.
+type Movement struct {
+	ID           uint        `json:"id"`
+	StoreID      uint        `json:"store_id" gorm:"index"`
+	IngredientID uint        `json:"ingredient_id" gorm:"uniqueIndex:idx_inventory_movement_order"`
+	Ingredient   *Ingredient `json:"ingredient,omitempty" gorm:"foreignKey:IngredientID" swaggerignore:"true"`
+	Type         string      `json:"type" gorm:"uniqueIndex:idx_inventory_movement_order" enums:"sale,receipt,waste"`
+	Quantity     float64     `json:"quantity" gorm:"precision:18;scale:4"`
+	OrderID      *uint       `json:"order_id,omitempty" gorm:"uniqueIndex:idx_inventory_movement_order"`
+	Notes        string      `json:"notes"`
+	OccurredAt   time.Time   `json:"occurred_at" gorm:"index"`
+	CreatedAt    *time.Time  `json:"created_at,omitempty" swaggerignore:"true"`
+}
+
+func (Movement) TableName() string {
+	return "inventory_movements"
+}
+
+// MovementSum is the quantity of an ingredient moved by type
+type MovementSum struct {
+	IngredientID uint
+	Type         string
+	Quantity     float64
+}
+
+// Count is the physical stock of an ingredient at a store at the counted time
+type Count struct {
+	ID           uint       `json:"id"`
+	StoreID      uint       `json:"store_id" gorm:"index"`
+	IngredientID uint       `json:"ingredient_id" gorm:"index"`
+	Quantity     float64    `json:"quantity" gorm:"precision:18;scale:4"`
+	Notes        string     `json:"notes"`
+	CountedAt    time.Time  `json:"counted_at" gorm:"index"`
+	CreatedAt    *time.Time `json:"created_at,omitempty" swaggerignore:"true"`
+}
+
+func (Count) TableName() string {
+	return "inventory_counts"
+}
+
+// Deplete returns the sale movements of the ingredients used by the items and modifiers of a closed order,
+// the fixed components of a combo use their item recipe and the chosen ones are depleted as modifiers
+func Deplete(closedOrder *order.Order, recipes Recipes) []Movement {
+	if closedOrder.StoreID == nil {
+		return nil
+	}
+
+	occurredAt := time.Now()
+	if closedOrder.ClosedAt != nil {
+		occurredAt = *closedOrder.ClosedAt
+	}
+
+	used := make(map[uint]float64)
+	ingredientIDs := make([]uint, 0)
+	add := func(productID *uint, usage string, units int) {
+		if productID == nil {
+			return
+		}
+		for _, line := range recipes.Lines(*productID, usage) {
+			if _, ok := used[line.IngredientID]; !ok {
+				ingredientIDs = append(ingredientIDs, line.IngredientID)
+			}
+			used[line.IngredientID] += line.Quantity * float64(units)
+		}
+	}
+
+	for _, item := range closedOrder.Items {
+		add(item.ProductID, UsageItem, 1)
+		for _, modifier := range item.Modifiers {
+			add(modifier.ProductID, UsageModifier, 1)
+		}
+		for _, component := range item.Components {
+			if component.ModifierID == nil {
+				add(component.ProductID, UsageItem, component.Quantity)
+			}
+		}
+	}
+
+	orderID := closedOrder.ID
+	movements := make([]Movement, 0, len(ingredientIDs))
+	for _, ingredientID := range ingredientIDs {
+		movements = append(movements, Movement{
+			StoreID:      *closedOrder.StoreID,
+			IngredientID: ingredientID,
+			Type:         MovementSale,
+			Quantity:     -used[ingredientID],
+			OrderID:      &orderID,
+			OccurredAt:   occurredAt,
+		})
+	}
+
+	return movements
+}
+
+// ProductIDs returns the products of the items, modifiers and combo components of the order
+func ProductIDs(closedOrder order.Order) []uint {
+	ids := make([]uint, 0)
+	seen := make(map[uint]bool)
+	add := func(productID *uint) {
+		if productID == nil || seen[*productID] {
+			return
+		}
+		seen[*productID] = true
+		ids = append(ids, *productID)
+	}
+
+	for _, item := range closedOrder.Items {
+		add(item.ProductID)
+		for _, modifier := range item.Modifiers {
+			add(modifier.ProductID)
+		}
+		for _, component := range item.Components {
+			add(component.ProductID)
+		}
+	}
+
+	return ids
+}
+
+// UsageLine compares the theoretical usage of an ingredient, the one of the recipes of the sales, with the actual
+// usage between the physical counts. The actual usage and the variance are only known when both counts exist.
+type UsageLine struct {
+	IngredientID uint     `json:"ingredient_id"`
+	Name         string   `json:"name"`
+	Unit         string   `json:"unit"`
+	Opening      *float64 `json:"opening"` // last count before the range
+	Receipts     float64  `json:"receipts"`
+	Waste        float64  `json:"waste"`
+	Theoretical  float64  `json:"theoretical"`
+	Expected     *float64 `json:"expected"` // stock expected at the end of the range from the opening count
+	Closing      *float64 `json:"closing"`  // last count of the range
+	Actual       *float64 `json:"actual"`
+	Variance     *float64 `json:"variance"` // actual minus theoretical usage, positive when more was used
+	VarianceCost *float64 `json:"variance_cost"`
+}
+
+type Usage struct {
+	StoreID uint        `json:"store_id"`
+	From    string      `json:"from"`
+	To      string      `json:"to"`
+	Lines   []UsageLine `json:"lines"`
+}
+
+// BuildUsage builds the usage lines of the ingredients moved or counted, the quantities of the sales and the
+// waste are reported as positive usages
+func BuildUsage(ingredients map[uint]Ingredient, sums []MovementSum, opening, closing map[uint]Count) []UsageLine {
+	lines := make(map[uint]*UsageLine)
+	line := func(ingredientID uint) *UsageLine {
+		if l, ok := lines[ingredientID]; ok {
+			return l
+		}
+		l := &UsageLine{IngredientID: ingredientID}
+		if ingredient, ok := ingredients[ingredientID]; ok {
+			l.Name = ingredient.Name
+			l.Unit = ingredient.Unit
+		}
+		lines[ingredientID] = l
+		return l
+	}
+
+	for _, sum := range sums {
+		l := line(sum.IngredientID)
+		switch sum.Type {
+		case MovementReceipt:
+			l.Receipts += sum.Quantity
+		case MovementWaste:
+			l.Waste -= sum.Quantity
+		case MovementSale:
+			l.Theoretical -= sum.Quantity
+		}
+	}
+	for ingredientID, count := range opening {
+		quantity := count.Quantity
+		line(ingredientID).Opening = &quantity
+	}
+	for ingredientID, count := range closing {
+		quantity := count.Quantity
+		line(ingredientID).Closing = &quantity
+	}
+
+	usage := make([]UsageLine, 0, len(lines))
+	for _, l := range lines {
+		if l.Opening != nil {
+			expected := *l.Opening + l.Receipts - l.Waste - l.Theoretical
+			l.Expected = &expected
+		}
+		if l.Opening != nil && l.Closing != nil {
+			actual := *l.Opening + l.Receipts - l.Waste - *l.Closing
+			variance := actual - l.Theoretical
+			cost := variance * ingredients[l.IngredientID].Cost
+			l.Actual = &actual
+			l.Variance = &variance
+			l.VarianceCost = &cost
+		}
+		usage = append(usage, *l)
+	}
+
+	sort.Slice(usage, func(i, j int) bool { return usage[i].IngredientID < usage[j].IngredientID })
+	return usage
+}
+
+// DepletionLine is the quantity of an ingredient sold and wasted at the stores of a warehouse
+type DepletionLine struct {
+	IngredientID uint    `json:"ingredient_id"`
+	Reference    string  `json:"reference"`
+	Name         string  `json:"name"`
+	Unit         string  `json:"unit"`
+	Sales        float64 `json:"sales"`
+	Waste        float64 `json:"waste"`
+}
+
+// WarehouseDepletion is the depletion of the stores that share an ERP warehouse, as sent to SIESA in the
+// F461_ID_BODEGA_COMPON_PROCESO of the sales documents
+type WarehouseDepletion struct {
+	Warehouse string          `json:"warehouse"`
+	OpsCenter string          `json:"ops_center"`
+	StoreIDs  []uint          `json:"store_ids"`
+	Lines     []DepletionLine `json:"lines"`
+}
+
+type Depletion struct {
+	From       string               `json:"from"`
+	To         string               `json:"to"`
+	Warehouses []WarehouseDepletion `json:"warehouses"`
+}
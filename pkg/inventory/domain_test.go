@@ -0,0 +1,108 @@
+package inventory_test
+
+import (
+	"time"
+
+	"github.com/BacoFoods/menu/pkg/inventory"
+	"github.com/BacoFoods/menu/pkg/order"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Inventory", func() {
+	id := func(value uint) *uint { return &value }
+	burger, combo, bacon, soda, cola := uint(10), uint(11), uint(20), uint(30), uint(31)
+	bun, patty, baconStrip, can := uint(100), uint(101), uint(102), uint(103)
+
+	recipes := inventory.NewRecipes([]inventory.RecipeLine{
+		{ProductID: burger, Usage: inventory.UsageItem, IngredientID: bun, Quantity: 1},
+		{ProductID: burger, Usage: inventory.UsageItem, IngredientID: patty, Quantity: 150},
+		{ProductID: bacon, Usage: inventory.UsageModifier, IngredientID: baconStrip, Quantity: 2},
+		{ProductID: bacon, Usage: inventory.UsageItem, IngredientID: baconStrip, Quantity: 6},
+		{ProductID: cola, Usage: inventory.UsageItem, IngredientID: can, Quantity: 1},
+	})
+
+	Context("Recipes", func() {
+		It("Falls back to the item recipe for the modifiers without one", func() {
+			Expect(recipes.Lines(bacon, inventory.UsageModifier)[0].Quantity).To(Equal(2.0))
+			Expect(recipes.Lines(cola, inventory.UsageModifier)[0].IngredientID).To(Equal(can))
+			Expect(recipes.Lines(soda, inventory.UsageItem)).To(BeEmpty())
+		})
+
+		It("Validates the quantities and the ingredients used once", func() {
+			Expect(inventory.ValidateRecipe([]inventory.RecipeLine{{IngredientID: bun, Quantity: 1}, {IngredientID: patty, Quantity: 0.5}})).To(Succeed())
+			Expect(inventory.ValidateRecipe([]inventory.RecipeLine{{IngredientID: bun, Quantity: 0}})).To(MatchError(inventory.ErrorRecipeInvalidQuantity))
+			Expect(inventory.ValidateRecipe([]inventory.RecipeLine{{IngredientID: bun, Quantity: 1}, {IngredientID: bun, Quantity: 2}})).
+				To(MatchError(inventory.ErrorRecipeDuplicatedIngredient))
+		})
+	})
+
+	Context("Deplete", func() {
+		closedAt := time.Date(2023, 10, 5, 21, 0, 0, 0, time.UTC)
+		closedOrder := &order.Order{
+			ID:       7,
+			StoreID:  id(1),
+			ClosedAt: &closedAt,
+			Items: []order.OrderItem{
+				{ProductID: id(burger), Modifiers: []order.OrderModifier{{ProductID: id(bacon)}}},
+				{ProductID: id(combo),
+					Modifiers: []order.OrderModifier{{ProductID: id(cola), ModifierID: id(50)}},
+					Components: []order.OrderItemComponent{
+						{ProductID: id(burger), Quantity: 2},
+						{ProductID: id(cola), ModifierID: id(50), Quantity: 1},
+					}},
+			},
+		}
+
+		It("Takes out the ingredients of the items, modifiers and combo components", func() {
+			movements := inventory.Deplete(closedOrder, recipes)
+
+			used := make(map[uint]float64)
+			for _, movement := range movements {
+				Expect(movement.StoreID).To(Equal(uint(1)))
+				Expect(*movement.OrderID).To(Equal(uint(7)))
+				Expect(movement.Type).To(Equal(inventory.MovementSale))
+				Expect(movement.OccurredAt).To(Equal(closedAt))
+				used[movement.IngredientID] = movement.Quantity
+			}
+
+			Expect(used).To(Equal(map[uint]float64{bun: -3, patty: -450, baconStrip: -2, can: -1}))
+		})
+
+		It("Skips the orders without store", func() {
+			Expect(inventory.Deplete(&order.Order{Items: closedOrder.Items}, recipes)).To(BeEmpty())
+		})
+
+		It("Lists the products of the items, modifiers and components once", func() {
+			Expect(inventory.ProductIDs(*closedOrder)).To(Equal([]uint{burger, bacon, combo, cola}))
+		})
+	})
+
+	Context("Usage", func() {
+		It("Compares the theoretical usage with the counts", func() {
+			ingredients := map[uint]inventory.Ingredient{patty: {Name: "Patty", Unit: "g", Cost: 0.02}}
+			sums := []inventory.MovementSum{
+				{IngredientID: patty, Type: inventory.MovementReceipt, Quantity: 5000},
+				{IngredientID: patty, Type: inventory.MovementSale, Quantity: -4500},
+				{IngredientID: patty, Type: inventory.MovementWaste, Quantity: -300},
+				{IngredientID: bun, Type: inventory.MovementSale, Quantity: -30},
+			}
+			opening := map[uint]inventory.Count{patty: {Quantity: 1000}}
+			closing := map[uint]inventory.Count{patty: {Quantity: 1000}}
+
+			usage := inventory.BuildUsage(ingredients, sums, opening, closing)
+
+			Expect(usage).To(HaveLen(2))
+			Expect(usage[0].IngredientID).To(Equal(bun))
+			Expect(usage[0].Theoretical).To(Equal(30.0))
+			Expect(usage[0].Expected).To(BeNil())
+
+			Expect(usage[1].Name).To(Equal("Patty"))
+			Expect(usage[1].Waste).To(Equal(300.0))
+			Expect(*usage[1].Expected).To(Equal(1200.0))
+			Expect(*usage[1].Actual).To(Equal(4700.0))
+			Expect(*usage[1].Variance).To(Equal(200.0))
+			Expect(*usage[1].VarianceCost).To(Equal(4.0))
+		})
+	})
+})
@@ -0,0 +1,57 @@
+package inventory
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strconv"
+)
+
+type RequestRecipe struct {
+	Usage string       `json:"usage" binding:"required" enums:"item,modifier"`
+	Lines []RecipeLine `json:"lines"`
+}
+
+type RequestMovement struct {
+	IngredientID uint    `json:"ingredient_id" binding:"required"`
+	Type         string  `json:"type" binding:"required" enums:"receipt,waste"`
+	Quantity     float64 `json:"quantity" binding:"required"`
+	Notes        string  `json:"notes"`
+}
+
+type RequestCount struct {
+	Notes  string `json:"notes"`
+	Counts []struct {
+		IngredientID uint    `json:"ingredient_id" binding:"required"`
+		Quantity     float64 `json:"quantity"`
+	} `json:"counts" binding:"required"`
+}
+
+func IsValidFormat(format string) bool {
+	return format == FormatJSON || format == FormatCSV
+}
+
+// ToCSV writes a line for every ingredient of every warehouse, so each warehouse can be posted on its own
+func (d Depletion) ToCSV() ([]byte, error) {
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+
+	lines := [][]string{{"warehouse", "ops_center", "from", "to", "reference", "ingredient", "unit", "sales", "waste"}}
+	for _, warehouse := range d.Warehouses {
+		for _, line := range warehouse.Lines {
+			lines = append(lines, []string{
+				warehouse.Warehouse, warehouse.OpsCenter, d.From, d.To, line.Reference, line.Name, line.Unit,
+				formatQuantity(line.Sales), formatQuantity(line.Waste),
+			})
+		}
+	}
+
+	if err := writer.WriteAll(lines); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func formatQuantity(quantity float64) string {
+	return strconv.FormatFloat(quantity, 'f', 4, 64)
+}
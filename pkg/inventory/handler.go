@@ -0,0 +1,366 @@
+package inventory
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/BacoFoods/menu/pkg/shared"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	LogHandler = "pkg/inventory/handler"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service}
+}
+
+// FindIngredients to handle a request to find the ingredients
+// @Tags Inventory
+// @Summary To find ingredients
+// @Description To find the ingredients of the recipes
+// @Param brand_id query string false "brand id"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=[]Ingredient}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /inventory/ingredient [get]
+func (h *Handler) FindIngredients(c *gin.Context) {
+	filter := make(map[string]string)
+	if brandID := c.Query("brand_id"); brandID != "" {
+		filter["brand_id"] = brandID
+	}
+
+	ingredients, err := h.service.FindIngredients(filter)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(ingredients))
+}
+
+// CreateIngredient to handle a request to create an ingredient
+// @Tags Inventory
+// @Summary To create an ingredient
+// @Description To create an ingredient counted in g, ml or unit, the reference is its ERP item
+// @Param ingredient body Ingredient true "ingredient"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=Ingredient}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /inventory/ingredient [post]
+func (h *Handler) CreateIngredient(c *gin.Context) {
+	var body Ingredient
+	if err := c.ShouldBindJSON(&body); err != nil {
+		shared.LogWarn("warning binding request body", LogHandler, "CreateIngredient", err, body)
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorInventoryBadRequest))
+		return
+	}
+
+	body.ID = 0
+	ingredient, err := h.service.CreateIngredient(&body)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(ingredient))
+}
+
+// UpdateIngredient to handle a request to update an ingredient
+// @Tags Inventory
+// @Summary To update an ingredient
+// @Description To update an ingredient
+// @Param id path string true "ingredient id"
+// @Param ingredient body Ingredient true "ingredient"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=Ingredient}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /inventory/ingredient/{id} [patch]
+func (h *Handler) UpdateIngredient(c *gin.Context) {
+	ingredient, err := h.service.GetIngredient(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	id := ingredient.ID
+	if err := c.ShouldBindJSON(ingredient); err != nil {
+		shared.LogWarn("warning binding request body", LogHandler, "UpdateIngredient", err, c.Param("id"))
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorInventoryBadRequest))
+		return
+	}
+
+	ingredient.ID = id
+	ingredient, err = h.service.UpdateIngredient(ingredient)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(ingredient))
+}
+
+// DeleteIngredient to handle a request to delete an ingredient
+// @Tags Inventory
+// @Summary To delete an ingredient
+// @Description To delete an ingredient
+// @Param id path string true "ingredient id"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=Ingredient}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /inventory/ingredient/{id} [delete]
+func (h *Handler) DeleteIngredient(c *gin.Context) {
+	ingredient, err := h.service.DeleteIngredient(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(ingredient))
+}
+
+// GetRecipe to handle a request to get the recipe of a product
+// @Tags Inventory
+// @Summary To get the recipe of a product
+// @Description To get the item and modifier recipe lines of a product
+// @Param id path string true "product id"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=[]RecipeLine}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /inventory/recipe/product/{id} [get]
+func (h *Handler) GetRecipe(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		shared.LogWarn("warning parsing product id", LogHandler, "GetRecipe", err, c.Param("id"))
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorInventoryBadRequest))
+		return
+	}
+
+	lines, err := h.service.GetRecipe(uint(productID))
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(lines))
+}
+
+// SetRecipe to handle a request to set the recipe of a product
+// @Tags Inventory
+// @Summary To set the recipe of a product
+// @Description To replace the recipe lines of a product for a usage, the quantities are in the unit of the ingredient by unit sold. The modifier usage is the recipe of the product chosen as a modifier option, the item recipe is used when it has none.
+// @Param id path string true "product id"
+// @Param recipe body RequestRecipe true "recipe"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=[]RecipeLine}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /inventory/recipe/product/{id} [put]
+func (h *Handler) SetRecipe(c *gin.Context) {
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		shared.LogWarn("warning parsing product id", LogHandler, "SetRecipe", err, c.Param("id"))
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorInventoryBadRequest))
+		return
+	}
+
+	var body RequestRecipe
+	if err := c.ShouldBindJSON(&body); err != nil {
+		shared.LogWarn("warning binding request body", LogHandler, "SetRecipe", err, productID)
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorInventoryBadRequest))
+		return
+	}
+
+	lines, err := h.service.SetRecipe(uint(productID), body.Usage, body.Lines)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(lines))
+}
+
+// RecordMovement to handle a request to record a receipt or waste
+// @Tags Inventory
+// @Summary To record a receipt or waste of an ingredient
+// @Description To record the quantity of an ingredient received or wasted at a store, in the unit of the ingredient
+// @Param id path string true "store id"
+// @Param movement body RequestMovement true "movement"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=Movement}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /inventory/store/{id}/movement [post]
+func (h *Handler) RecordMovement(c *gin.Context) {
+	var body RequestMovement
+	if err := c.ShouldBindJSON(&body); err != nil {
+		shared.LogWarn("warning binding request body", LogHandler, "RecordMovement", err, body)
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorInventoryBadRequest))
+		return
+	}
+
+	movement, err := h.service.RecordMovement(c.Param("id"), &Movement{
+		IngredientID: body.IngredientID,
+		Type:         body.Type,
+		Quantity:     body.Quantity,
+		Notes:        body.Notes,
+	})
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(movement))
+}
+
+// RecordCount to handle a request to record a physical count
+// @Tags Inventory
+// @Summary To record a physical count
+// @Description To record the counted stock of the ingredients at a store, in the unit of each ingredient
+// @Param id path string true "store id"
+// @Param count body RequestCount true "count"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=[]Count}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /inventory/store/{id}/count [post]
+func (h *Handler) RecordCount(c *gin.Context) {
+	var body RequestCount
+	if err := c.ShouldBindJSON(&body); err != nil {
+		shared.LogWarn("warning binding request body", LogHandler, "RecordCount", err, body)
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorInventoryBadRequest))
+		return
+	}
+
+	counts := make([]Count, 0, len(body.Counts))
+	for _, count := range body.Counts {
+		counts = append(counts, Count{IngredientID: count.IngredientID, Quantity: count.Quantity, Notes: body.Notes})
+	}
+
+	counts, err := h.service.RecordCount(c.Param("id"), counts)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(counts))
+}
+
+// Usage to handle a request to compare the theoretical and actual usage
+// @Tags Inventory
+// @Summary To get the theoretical versus actual usage of a store
+// @Description To compare the ingredients used by the recipes of the closed orders with the usage between the physical counts, on the store local days of the range. The opening stock is the last count before the range and the closing stock the last count of the range.
+// @Param id path string true "store id"
+// @Param from query string true "start date" example(2006-01-02)
+// @Param to query string true "end date, included" example(2006-01-02)
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=Usage}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /inventory/store/{id}/usage [get]
+func (h *Handler) Usage(c *gin.Context) {
+	from, to, err := ParseDates(c.Query("from"), c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	usage, err := h.service.Usage(c.Param("id"), from, to)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(usage))
+}
+
+// Depletion to handle a request to export the depletion by warehouse
+// @Tags Inventory
+// @Summary To export the ingredients depletion by warehouse
+// @Description To get the ingredients sold and wasted at the stores of a brand on the store local days of the range, grouped by the SIESA warehouse of the stores
+// @Param brand_id query string true "brand id"
+// @Param from query string true "start date" example(2006-01-02)
+// @Param to query string true "end date, included" example(2006-01-02)
+// @Param format query string false "output format" Enums(json, csv)
+// @Accept json
+// @Produce json,text/csv
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=Depletion}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /inventory/depletion [get]
+func (h *Handler) Depletion(c *gin.Context) {
+	format := c.DefaultQuery("format", FormatJSON)
+	if !IsValidFormat(format) {
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorInventoryInvalidFormat))
+		return
+	}
+
+	from, to, err := ParseDates(c.Query("from"), c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	depletion, err := h.service.Depletion(c.Query("brand_id"), from, to)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	if format == FormatJSON {
+		c.JSON(http.StatusOK, shared.SuccessResponse(depletion))
+		return
+	}
+
+	file, err := depletion.ToCSV()
+	if err != nil {
+		shared.LogError("error exporting depletion", LogHandler, "Depletion", err, c.Query("brand_id"))
+		c.JSON(http.StatusInternalServerError, shared.ErrorResponse(ErrorInventoryExporting))
+		return
+	}
+
+	filename := fmt.Sprintf("depletion-%s-%s-%s.csv", c.Query("brand_id"), depletion.From, depletion.To)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	c.Header("Access-Control-Expose-Headers", "Content-Disposition")
+	c.Data(http.StatusOK, "text/csv", file)
+}
@@ -0,0 +1,27 @@
+package inventory
+
+import "github.com/BacoFoods/menu/pkg/shared"
+
+type Routes struct {
+	handler *Handler
+}
+
+func NewRoutes(handler *Handler) Routes {
+	return Routes{handler}
+}
+
+func (r Routes) RegisterRoutes(router *shared.CustomRoutes) {
+	router.GET("/inventory/ingredient", r.handler.FindIngredients)
+	router.POST("/inventory/ingredient", r.handler.CreateIngredient)
+	router.PATCH("/inventory/ingredient/:id", r.handler.UpdateIngredient)
+	router.DELETE("/inventory/ingredient/:id", r.handler.DeleteIngredient)
+
+	router.GET("/inventory/recipe/product/:id", r.handler.GetRecipe)
+	router.PUT("/inventory/recipe/product/:id", r.handler.SetRecipe)
+
+	router.POST("/inventory/store/:id/movement", r.handler.RecordMovement)
+	router.POST("/inventory/store/:id/count", r.handler.RecordCount)
+	router.GET("/inventory/store/:id/usage", r.handler.Usage)
+
+	router.GET("/inventory/depletion", r.handler.Depletion)
+}
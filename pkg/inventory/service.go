@@ -0,0 +1,332 @@
+package inventory
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/BacoFoods/menu/pkg/order"
+	"github.com/BacoFoods/menu/pkg/shared"
+	"github.com/BacoFoods/menu/pkg/store"
+)
+
+const (
+	LogService = "pkg/inventory/service"
+)
+
+type Service interface {
+	RecordClosedOrder(closedOrder *order.Order)
+
+	FindIngredients(filter map[string]string) ([]Ingredient, error)
+	GetIngredient(ingredientID string) (*Ingredient, error)
+	CreateIngredient(*Ingredient) (*Ingredient, error)
+	UpdateIngredient(*Ingredient) (*Ingredient, error)
+	DeleteIngredient(ingredientID string) (*Ingredient, error)
+
+	GetRecipe(productID uint) ([]RecipeLine, error)
+	SetRecipe(productID uint, usage string, lines []RecipeLine) ([]RecipeLine, error)
+
+	RecordMovement(storeID string, movement *Movement) (*Movement, error)
+	RecordCount(storeID string, counts []Count) ([]Count, error)
+	Usage(storeID string, from, to time.Time) (*Usage, error)
+	Depletion(brandID string, from, to time.Time) (*Depletion, error)
+}
+
+type service struct {
+	repository Repository
+	stores     store.Repository
+}
+
+func NewService(repository Repository, stores store.Repository) service {
+	return service{repository, stores}
+}
+
+// RecordClosedOrder takes the ingredients of the recipes of the closed order from the theoretical stock of its
+// store, errors are only logged so closing an order never fails because of the inventory
+func (s service) RecordClosedOrder(closedOrder *order.Order) {
+	if closedOrder == nil || closedOrder.StoreID == nil {
+		return
+	}
+
+	lines, err := s.repository.FindRecipes(ProductIDs(*closedOrder))
+	if err != nil {
+		return
+	}
+
+	if err := s.repository.CreateMovements(Deplete(closedOrder, NewRecipes(lines))); err != nil {
+		shared.LogError("error depleting closed order", LogService, "RecordClosedOrder", err, closedOrder.ID)
+	}
+}
+
+func (s service) FindIngredients(filter map[string]string) ([]Ingredient, error) {
+	return s.repository.FindIngredients(filter)
+}
+
+func (s service) GetIngredient(ingredientID string) (*Ingredient, error) {
+	return s.repository.GetIngredient(ingredientID)
+}
+
+func (s service) CreateIngredient(ingredient *Ingredient) (*Ingredient, error) {
+	if !IsValidUnit(ingredient.Unit) {
+		return nil, fmt.Errorf(ErrorIngredientInvalidUnit)
+	}
+
+	return s.repository.CreateIngredient(ingredient)
+}
+
+func (s service) UpdateIngredient(ingredient *Ingredient) (*Ingredient, error) {
+	if !IsValidUnit(ingredient.Unit) {
+		return nil, fmt.Errorf(ErrorIngredientInvalidUnit)
+	}
+
+	return s.repository.UpdateIngredient(ingredient)
+}
+
+func (s service) DeleteIngredient(ingredientID string) (*Ingredient, error) {
+	return s.repository.DeleteIngredient(ingredientID)
+}
+
+// GetRecipe returns the item and modifier recipe lines of the product
+func (s service) GetRecipe(productID uint) ([]RecipeLine, error) {
+	return s.repository.FindRecipes([]uint{productID})
+}
+
+// SetRecipe replaces the recipe of the product for the usage, an empty recipe removes it
+func (s service) SetRecipe(productID uint, usage string, lines []RecipeLine) ([]RecipeLine, error) {
+	if !IsValidUsage(usage) {
+		return nil, fmt.Errorf(ErrorRecipeInvalidUsage)
+	}
+
+	if err := ValidateRecipe(lines); err != nil {
+		return nil, err
+	}
+
+	return s.repository.ReplaceRecipe(productID, usage, lines)
+}
+
+// RecordMovement records a receipt or the waste of an ingredient at the store, the waste is taken out of the stock
+func (s service) RecordMovement(storeID string, movement *Movement) (*Movement, error) {
+	if movement.Type != MovementReceipt && movement.Type != MovementWaste {
+		return nil, fmt.Errorf(ErrorMovementInvalidType)
+	}
+
+	if movement.Quantity <= 0 {
+		return nil, fmt.Errorf(ErrorMovementInvalidQuantity)
+	}
+
+	movementStore, err := s.stores.Get(storeID)
+	if err != nil {
+		shared.LogError("error getting store", LogService, "RecordMovement", err, storeID)
+		return nil, fmt.Errorf(ErrorInventoryGettingStore)
+	}
+
+	movement.StoreID = movementStore.ID
+	movement.OrderID = nil
+	movement.OccurredAt = time.Now()
+	if movement.Type == MovementWaste {
+		movement.Quantity = -movement.Quantity
+	}
+
+	if err := s.repository.CreateMovements([]Movement{*movement}); err != nil {
+		return nil, err
+	}
+
+	return movement, nil
+}
+
+// RecordCount records the physical count of the ingredients at the store
+func (s service) RecordCount(storeID string, counts []Count) ([]Count, error) {
+	if len(counts) == 0 {
+		return nil, fmt.Errorf(ErrorCountEmpty)
+	}
+
+	countStore, err := s.stores.Get(storeID)
+	if err != nil {
+		shared.LogError("error getting store", LogService, "RecordCount", err, storeID)
+		return nil, fmt.Errorf(ErrorInventoryGettingStore)
+	}
+
+	countedAt := time.Now()
+	for i := range counts {
+		if counts[i].Quantity < 0 {
+			return nil, fmt.Errorf(ErrorCountInvalidQuantity)
+		}
+		counts[i].ID = 0
+		counts[i].StoreID = countStore.ID
+		counts[i].CountedAt = countedAt
+	}
+
+	if err := s.repository.CreateCounts(counts); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// Usage compares the theoretical and actual usage of the ingredients at the store on the local days of the range,
+// the opening stock is the last count before the range and the closing stock the last count of the range
+func (s service) Usage(storeID string, from, to time.Time) (*Usage, error) {
+	if to.Before(from) {
+		return nil, fmt.Errorf(ErrorInventoryInvalidRange)
+	}
+
+	usageStore, err := s.stores.Get(storeID)
+	if err != nil {
+		shared.LogError("error getting store", LogService, "Usage", err, storeID)
+		return nil, fmt.Errorf(ErrorInventoryGettingStore)
+	}
+
+	start, end := localRange(from, to, usageStore.Location())
+	sums, err := s.repository.SumMovements(usageStore.ID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	opening, err := s.repository.LastCounts(usageStore.ID, start)
+	if err != nil {
+		return nil, err
+	}
+
+	closing, err := s.repository.LastCounts(usageStore.ID, end)
+	if err != nil {
+		return nil, err
+	}
+
+	// a count before the range is not a closing count
+	for ingredientID, count := range closing {
+		if count.CountedAt.Before(start) {
+			delete(closing, ingredientID)
+		}
+	}
+
+	filter := map[string]string{}
+	if usageStore.BrandID != nil {
+		filter["brand_id"] = fmt.Sprint(*usageStore.BrandID)
+	}
+
+	ingredients, err := s.ingredients(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Usage{
+		StoreID: usageStore.ID,
+		From:    from.Format(dateLayout),
+		To:      to.Format(dateLayout),
+		Lines:   BuildUsage(ingredients, sums, opening, closing),
+	}, nil
+}
+
+// Depletion returns the ingredients sold and wasted at the stores of the brand on the local days of the range,
+// grouped by the ERP warehouse of the stores
+func (s service) Depletion(brandID string, from, to time.Time) (*Depletion, error) {
+	if brandID == "" {
+		return nil, fmt.Errorf(ErrorInventoryBrandRequired)
+	}
+
+	if to.Before(from) {
+		return nil, fmt.Errorf(ErrorInventoryInvalidRange)
+	}
+
+	stores, err := s.stores.Find(map[string]string{"brand_id": brandID})
+	if err != nil {
+		shared.LogError("error finding stores", LogService, "Depletion", err, brandID)
+		return nil, fmt.Errorf(ErrorInventoryFindingStores)
+	}
+
+	ingredients, err := s.ingredients(map[string]string{"brand_id": brandID})
+	if err != nil {
+		return nil, err
+	}
+
+	warehouses := make(map[string]*WarehouseDepletion)
+	lines := make(map[string]map[uint]*DepletionLine)
+	for _, brandStore := range stores {
+		start, end := localRange(from, to, brandStore.Location())
+		sums, err := s.repository.SumMovements(brandStore.ID, start, end)
+		if err != nil {
+			return nil, err
+		}
+
+		warehouse, ok := warehouses[brandStore.Wharehouse]
+		if !ok {
+			warehouse = &WarehouseDepletion{Warehouse: brandStore.Wharehouse, OpsCenter: brandStore.OpsCenter}
+			warehouses[brandStore.Wharehouse] = warehouse
+			lines[brandStore.Wharehouse] = make(map[uint]*DepletionLine)
+		}
+		warehouse.StoreIDs = append(warehouse.StoreIDs, brandStore.ID)
+
+		for _, sum := range sums {
+			if sum.Type != MovementSale && sum.Type != MovementWaste {
+				continue
+			}
+
+			line, ok := lines[brandStore.Wharehouse][sum.IngredientID]
+			if !ok {
+				ingredient := ingredients[sum.IngredientID]
+				line = &DepletionLine{
+					IngredientID: sum.IngredientID,
+					Reference:    ingredient.Reference,
+					Name:         ingredient.Name,
+					Unit:         ingredient.Unit,
+				}
+				lines[brandStore.Wharehouse][sum.IngredientID] = line
+			}
+
+			if sum.Type == MovementSale {
+				line.Sales -= sum.Quantity
+			} else {
+				line.Waste -= sum.Quantity
+			}
+		}
+	}
+
+	depletion := &Depletion{From: from.Format(dateLayout), To: to.Format(dateLayout), Warehouses: make([]WarehouseDepletion, 0)}
+	for name, warehouse := range warehouses {
+		for _, line := range lines[name] {
+			warehouse.Lines = append(warehouse.Lines, *line)
+		}
+		sort.Slice(warehouse.Lines, func(i, j int) bool { return warehouse.Lines[i].IngredientID < warehouse.Lines[j].IngredientID })
+		depletion.Warehouses = append(depletion.Warehouses, *warehouse)
+	}
+	sort.Slice(depletion.Warehouses, func(i, j int) bool { return depletion.Warehouses[i].Warehouse < depletion.Warehouses[j].Warehouse })
+
+	return depletion, nil
+}
+
+// ingredients returns the ingredients of the filter by ID
+func (s service) ingredients(filter map[string]string) (map[uint]Ingredient, error) {
+	ingredients, err := s.repository.FindIngredients(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	ingredientsByID := make(map[uint]Ingredient)
+	for _, ingredient := range ingredients {
+		ingredientsByID[ingredient.ID] = ingredient
+	}
+
+	return ingredientsByID, nil
+}
+
+// localRange returns the start of the first day and the end of the last day of the range at the location
+func localRange(from, to time.Time, location *time.Location) (time.Time, time.Time) {
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, location)
+	end := time.Date(to.Year(), to.Month(), to.Day()+1, 0, 0, 0, 0, location)
+	return start, end
+}
+
+// ParseDates parses the store local dates of the range
+func ParseDates(from, to string) (time.Time, time.Time, error) {
+	fromDate, err := time.Parse(dateLayout, from)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf(ErrorInventoryInvalidDates)
+	}
+
+	toDate, err := time.Parse(dateLayout, to)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf(ErrorInventoryInvalidDates)
+	}
+
+	return fromDate, toDate, nil
+}
@@ -230,10 +230,11 @@ func (r *DBRepository) Print(invoiceID string) (*DTOPrintable, error) {
 	}
 
 	var items []Item
-	if err := r.db.Find(&items, "invoice_id = ?", invoiceID).Error; err != nil {
+	if err := r.db.Order("id").Find(&items, "invoice_id = ?", invoiceID).Error; err != nil {
 		shared.LogError("error printing invoice", LogRepository, "Print", err, invoiceID)
 		return nil, err
 	}
+	invoice.Items = PrintableItems(items)
 
 	return &invoice, nil
 }
@@ -188,6 +188,9 @@ type Item struct {
 	DiscountAmount     float64         `json:"discount_amount"`
 	Comments           string          `json:"comments"`
 	Hash               string          `json:"hash"`
+	OrderItemID        *uint           `json:"order_item_id"` // order item of the combo of a component line
+	ComboID            *uint           `json:"combo_id"`      // combo whose price is allocated to this component line
+	ComboName          string          `json:"combo_name"`    // name of the combo, printed as one line on the receipt
 	Tax                string          `json:"tax"`
 	TaxPercentage      float64         `json:"tax_percentage"`
 	TaxAmount          float64         `json:"tax_amount" gorm:"precision:18;scale:2"`
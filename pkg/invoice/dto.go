@@ -1,6 +1,8 @@
 package invoice
 
 import (
+	"fmt"
+
 	"github.com/BacoFoods/menu/pkg/shared"
 	"time"
 )
@@ -52,6 +54,34 @@ type DTOPrintableItem struct {
 	Total    float64 `json:"total"`
 }
 
+// PrintableItems returns the lines of the receipt, the component lines of a combo are printed as one line with the
+// name and the price of the combo
+func PrintableItems(items []Item) []DTOPrintableItem {
+	printable := make([]DTOPrintableItem, 0)
+	combos := make(map[string]int)
+	for _, item := range items {
+		if item.ComboID != nil {
+			key := item.Hash
+			if item.OrderItemID != nil {
+				key = fmt.Sprint(*item.OrderItemID)
+			}
+
+			if i, ok := combos[key]; ok {
+				printable[i].Price += item.Price
+				printable[i].Total += item.Price
+				continue
+			}
+			combos[key] = len(printable)
+			printable = append(printable, DTOPrintableItem{Name: item.ComboName, Quantity: 1, Price: item.Price, Total: item.Price})
+			continue
+		}
+
+		printable = append(printable, DTOPrintableItem{Name: item.Name, Quantity: 1, Price: item.Price, Total: item.Price})
+	}
+
+	return printable
+}
+
 type DTOResolution struct {
 	BrandID        *uint  `json:"brand_id"`
 	StoreID        *uint  `json:"store_id" validate:"required"`
@@ -3,8 +3,10 @@ package menu
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/BacoFoods/menu/pkg/category"
+	"github.com/BacoFoods/menu/pkg/product"
 	"github.com/BacoFoods/menu/pkg/shared"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -89,9 +91,26 @@ func (r *DBRepository) Delete(menuID string) (*Menu, error) {
 
 // FindByPlace method for find menus by place
 func (r *DBRepository) FindByPlace(place, placeID string) ([]Menu, error) {
+	brandID, err := r.BrandIDByPlace(place, placeID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Getting Menu by brandID
 	var menus []Menu
+	if err := r.db.Preload(clause.Associations).
+		Preload("Categories.Products.Modifiers.Products").
+		Preload(product.ModifierTree("Categories.Products.")).
+		Find(&menus, "brand_id = ?", brandID).Error; err != nil {
+		shared.LogError("error getting menus", LogDBRepository, "FindByPlace", err, brandID, place, placeID)
+		return nil, err
+	}
 
-	// Getting brandID from placeID
+	return menus, nil
+}
+
+// BrandIDByPlace method for get the brand of a brand, store or channel place in database
+func (r *DBRepository) BrandIDByPlace(place, placeID string) (string, error) {
 	brandID := "0"
 	switch place {
 	case "brand":
@@ -99,29 +118,21 @@ func (r *DBRepository) FindByPlace(place, placeID string) ([]Menu, error) {
 
 	case "store":
 		if err := r.db.Select("brand_id").Table("stores").Where("id = ?", placeID).Scan(&brandID).Error; err != nil {
-			shared.LogError("error getting brand_id", LogDBRepository, "FindByPlace", err, place, placeID)
-			return nil, err
+			shared.LogError("error getting brand_id", LogDBRepository, "BrandIDByPlace", err, place, placeID)
+			return "", err
 		}
 
 	case "channel":
 		if err := r.db.Select("brand_id").Table("channels").Where("id = ?", placeID).Scan(&brandID).Error; err != nil {
-			shared.LogError("error getting brand_id", LogDBRepository, "FindByPlace", err, place, placeID)
-			return nil, err
+			shared.LogError("error getting brand_id", LogDBRepository, "BrandIDByPlace", err, place, placeID)
+			return "", err
 		}
 
 	default:
-		return nil, fmt.Errorf(ErrorMenuFindingByPlace)
+		return "", fmt.Errorf(ErrorMenuFindingByPlace)
 	}
 
-	// Getting Menu by brandID
-	if err := r.db.Preload(clause.Associations).
-		Preload("Categories.Products.Modifiers.Products").
-		Find(&menus, "brand_id = ?", brandID).Error; err != nil {
-		shared.LogError("error getting menus", LogDBRepository, "FindByPlace", err, brandID, place, placeID)
-		return nil, err
-	}
-
-	return menus, nil
+	return brandID, nil
 }
 
 // GetMenuItems method for get menu items in database
@@ -182,3 +193,221 @@ func (r *DBRepository) RemoveCategory(menuID string, category *category.Category
 
 	return &menu, nil
 }
+
+// FindMenusCategories method for find the categories of the menus with their validity in database
+func (r *DBRepository) FindMenusCategories(menuIDs []uint) ([]MenusCategories, error) {
+	var menusCategories []MenusCategories
+	if len(menuIDs) == 0 {
+		return menusCategories, nil
+	}
+
+	if err := r.db.Where("menu_id IN ?", menuIDs).Find(&menusCategories).Error; err != nil {
+		shared.LogError("error getting menus categories", LogDBRepository, "FindMenusCategories", err, menuIDs)
+		return nil, err
+	}
+	return menusCategories, nil
+}
+
+// FindDayparts method for find dayparts with their menus in database
+func (r *DBRepository) FindDayparts(filter map[string]string) ([]Daypart, error) {
+	var dayparts []Daypart
+	if err := r.db.Preload("Menus").Order("start_time").Find(&dayparts, filter).Error; err != nil {
+		shared.LogError("error getting dayparts", LogDBRepository, "FindDayparts", err, filter)
+		return nil, err
+	}
+	return dayparts, nil
+}
+
+// CreateDaypart method for create a daypart with its menus in database
+func (r *DBRepository) CreateDaypart(daypart *Daypart) (*Daypart, error) {
+	if err := r.db.Create(daypart).Error; err != nil {
+		shared.LogError("error creating daypart", LogDBRepository, "CreateDaypart", err, daypart)
+		return nil, err
+	}
+	return daypart, nil
+}
+
+// UpdateDaypart method for update a daypart in database, its menus are replaced by the given ones
+func (r *DBRepository) UpdateDaypart(daypart *Daypart) (*Daypart, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Daypart{ID: daypart.ID}).
+			Select("store_id", "name", "weekdays", "start_time", "end_time").
+			Updates(daypart).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("daypart_id = ?", daypart.ID).Delete(&DaypartMenu{}).Error; err != nil {
+			return err
+		}
+
+		for i := range daypart.Menus {
+			daypart.Menus[i].ID = 0
+			daypart.Menus[i].DaypartID = daypart.ID
+		}
+		if len(daypart.Menus) > 0 {
+			return tx.Create(&daypart.Menus).Error
+		}
+		return nil
+	})
+	if err != nil {
+		shared.LogError("error updating daypart", LogDBRepository, "UpdateDaypart", err, daypart)
+		return nil, err
+	}
+
+	var daypartDB Daypart
+	if err := r.db.Preload("Menus").First(&daypartDB, daypart.ID).Error; err != nil {
+		shared.LogError("error getting daypart", LogDBRepository, "UpdateDaypart", err, daypart.ID)
+		return nil, err
+	}
+	return &daypartDB, nil
+}
+
+// DeleteDaypart method for delete a daypart and its menus in database
+func (r *DBRepository) DeleteDaypart(daypartID string) (*Daypart, error) {
+	var daypart Daypart
+	if err := r.db.Preload("Menus").First(&daypart, daypartID).Error; err != nil {
+		shared.LogError("error getting daypart", LogDBRepository, "DeleteDaypart", err, daypartID)
+		return nil, err
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("daypart_id = ?", daypart.ID).Delete(&DaypartMenu{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&daypart).Error
+	})
+	if err != nil {
+		shared.LogError("error deleting daypart", LogDBRepository, "DeleteDaypart", err, daypartID)
+		return nil, err
+	}
+
+	return &daypart, nil
+}
+
+// FindOverriders method for find the overriders of the products in database
+func (r *DBRepository) FindOverriders(productIDs []uint) ([]product.Overrider, error) {
+	overriders := make([]product.Overrider, 0)
+	if len(productIDs) == 0 {
+		return overriders, nil
+	}
+
+	if err := r.db.Where("product_id IN ?", productIDs).Find(&overriders).Error; err != nil {
+		shared.LogError("error finding overriders", LogDBRepository, "FindOverriders", err, productIDs)
+		return nil, err
+	}
+	return overriders, nil
+}
+
+// FindVersions method for find menu versions without their snapshots in database
+func (r *DBRepository) FindVersions(filter map[string]string) ([]MenuVersion, error) {
+	var versions []MenuVersion
+	if err := r.db.Omit("menus", "overriders").Order("number DESC").Find(&versions, filter).Error; err != nil {
+		shared.LogError("error finding menu versions", LogDBRepository, "FindVersions", err, filter)
+		return nil, err
+	}
+	return versions, nil
+}
+
+// GetVersion method for get a menu version with its snapshot in database
+func (r *DBRepository) GetVersion(versionID string) (*MenuVersion, error) {
+	var version MenuVersion
+	if err := r.db.First(&version, versionID).Error; err != nil {
+		shared.LogError("error getting menu version", LogDBRepository, "GetVersion", err, versionID)
+		return nil, err
+	}
+	return &version, nil
+}
+
+// GetPublishedVersion method for get the published menu version of a brand in database, nil when it has none
+func (r *DBRepository) GetPublishedVersion(brandID string) (*MenuVersion, error) {
+	var versions []MenuVersion
+	if err := r.db.Where("brand_id = ? AND status = ?", brandID, VersionPublished).
+		Order("published_at DESC").
+		Limit(1).
+		Find(&versions).Error; err != nil {
+		shared.LogError("error getting published menu version", LogDBRepository, "GetPublishedVersion", err, brandID)
+		return nil, err
+	}
+
+	if len(versions) == 0 {
+		return nil, nil
+	}
+	return &versions[0], nil
+}
+
+// CreateVersion method for create a menu version numbered after the last one of the brand in database
+func (r *DBRepository) CreateVersion(version *MenuVersion) (*MenuVersion, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var last int
+		if err := tx.Model(&MenuVersion{}).
+			Select("COALESCE(MAX(number), 0)").
+			Where("brand_id = ?", version.BrandID).
+			Scan(&last).Error; err != nil {
+			return err
+		}
+
+		version.Number = last + 1
+		return tx.Create(version).Error
+	})
+	if err != nil {
+		shared.LogError("error creating menu version", LogDBRepository, "CreateVersion", err, version.BrandID)
+		return nil, err
+	}
+	return version, nil
+}
+
+// ScheduleVersion method for update the status and go-live time of a menu version in database
+func (r *DBRepository) ScheduleVersion(version *MenuVersion) (*MenuVersion, error) {
+	if err := r.db.Model(version).Select("status", "publish_at").Updates(version).Error; err != nil {
+		shared.LogError("error scheduling menu version", LogDBRepository, "ScheduleVersion", err, version.ID)
+		return nil, err
+	}
+	return version, nil
+}
+
+// PublishVersion method for publish a menu version in database, the version published before is superseded
+func (r *DBRepository) PublishVersion(version *MenuVersion, now time.Time) (*MenuVersion, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&MenuVersion{}).
+			Where("brand_id = ? AND status = ? AND id <> ?", version.BrandID, VersionPublished, version.ID).
+			Update("status", VersionSuperseded).Error; err != nil {
+			return err
+		}
+
+		version.Status = VersionPublished
+		version.PublishedAt = &now
+		return tx.Model(version).Select("status", "published_at").Updates(version).Error
+	})
+	if err != nil {
+		shared.LogError("error publishing menu version", LogDBRepository, "PublishVersion", err, version.ID)
+		return nil, err
+	}
+	return version, nil
+}
+
+// FindDueVersions method for find the scheduled menu versions due to go live in database
+func (r *DBRepository) FindDueVersions(now time.Time) ([]MenuVersion, error) {
+	var versions []MenuVersion
+	if err := r.db.Omit("menus", "overriders").
+		Where("status = ? AND publish_at <= ?", VersionScheduled, now).
+		Order("publish_at").
+		Find(&versions).Error; err != nil {
+		shared.LogError("error finding due menu versions", LogDBRepository, "FindDueVersions", err, now)
+		return nil, err
+	}
+	return versions, nil
+}
+
+// DeleteVersion method for delete a menu version in database
+func (r *DBRepository) DeleteVersion(versionID string) (*MenuVersion, error) {
+	version, err := r.GetVersion(versionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.db.Delete(version).Error; err != nil {
+		shared.LogError("error deleting menu version", LogDBRepository, "DeleteVersion", err, versionID)
+		return nil, err
+	}
+	return version, nil
+}
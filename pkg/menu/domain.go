@@ -1,10 +1,14 @@
 package menu
 
 import (
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/BacoFoods/menu/pkg/category"
+	"github.com/BacoFoods/menu/pkg/menucache"
 	"github.com/BacoFoods/menu/pkg/product"
 	"gorm.io/gorm"
-	"time"
 )
 
 const (
@@ -21,6 +25,35 @@ const (
 	ErrorMenuAddingCategory       string = "error adding category"
 	ErrorMenuRemovingCategory     string = "error removing category"
 	ErrorMenuWrongBrand           string = "error adding category to menu wrong brand"
+	ErrorMenuNotActive            string = "error menu is not available at this time"
+	ErrorMenuResolvingActive      string = "error resolving active menus"
+	ErrorMenuResolvingStock       string = "error resolving stock-outs of the menus"
+	ErrorDaypartFinding           string = "error finding dayparts"
+	ErrorDaypartCreating          string = "error creating daypart"
+	ErrorDaypartUpdating          string = "error updating daypart"
+	ErrorDaypartDeleting          string = "error deleting daypart"
+	ErrorDaypartWeekdays          string = "error daypart weekdays must be a comma separated list of mon, tue, wed, thu, fri, sat, sun"
+	ErrorDaypartTime              string = "error daypart start and end times must be formatted as 15:04 and be different"
+	ErrorDaypartWithoutMenu       string = "error daypart menus need a menu id"
+	ErrorVersionFinding           string = "error finding menu versions"
+	ErrorVersionGetting           string = "error getting menu version"
+	ErrorVersionCreating          string = "error creating menu version"
+	ErrorVersionPublishing        string = "error publishing menu version"
+	ErrorVersionDeleting          string = "error deleting menu version"
+	ErrorVersionNotEditable       string = "error only draft or scheduled menu versions can be published or deleted"
+	ErrorVersionNotSuperseded     string = "error only superseded menu versions can be rolled back to"
+	ErrorVersionBrandRequired     string = "error menu version needs a brand"
+	ErrorVersionPrices            string = "error getting menu version prices"
+
+	VersionDraft      = "draft"
+	VersionScheduled  = "scheduled"
+	VersionPublished  = "published"
+	VersionSuperseded = "superseded"
+
+	// PublishInterval is how often the scheduled menu versions are checked
+	PublishInterval = time.Minute
+
+	daypartTimeLayout = "15:04"
 )
 
 type Repository interface {
@@ -33,6 +66,21 @@ type Repository interface {
 	GetMenuItems(string) ([]Item, error)
 	AddCategory(menuID string, category *category.Category) (*Menu, error)
 	RemoveCategory(menuID string, category *category.Category) (*Menu, error)
+	FindMenusCategories(menuIDs []uint) ([]MenusCategories, error)
+	FindDayparts(filter map[string]string) ([]Daypart, error)
+	CreateDaypart(*Daypart) (*Daypart, error)
+	UpdateDaypart(*Daypart) (*Daypart, error)
+	DeleteDaypart(daypartID string) (*Daypart, error)
+	BrandIDByPlace(place, placeID string) (string, error)
+	FindOverriders(productIDs []uint) ([]product.Overrider, error)
+	FindVersions(filter map[string]string) ([]MenuVersion, error)
+	GetVersion(versionID string) (*MenuVersion, error)
+	GetPublishedVersion(brandID string) (*MenuVersion, error)
+	CreateVersion(*MenuVersion) (*MenuVersion, error)
+	ScheduleVersion(*MenuVersion) (*MenuVersion, error)
+	PublishVersion(version *MenuVersion, now time.Time) (*MenuVersion, error)
+	FindDueVersions(now time.Time) ([]MenuVersion, error)
+	DeleteVersion(versionID string) (*MenuVersion, error)
 }
 
 type Menu struct {
@@ -60,6 +108,26 @@ type MenusCategories struct {
 	// DeletedAt  gorm.DeletedAt `json:"deleted_at,omitempty" swaggerignore:"true"`
 }
 
+// InWindow checks if the menu is valid at the given time, menus without start or end time are always valid
+func (m Menu) InWindow(now time.Time) bool {
+	return inWindow(m.StartTime, m.EndTime, now)
+}
+
+// InWindow checks if the category of the menu is valid at the given time
+func (mc MenusCategories) InWindow(now time.Time) bool {
+	return inWindow(mc.StartTime, mc.EndTime, now)
+}
+
+func inWindow(start, end *time.Time, now time.Time) bool {
+	if start != nil && now.Before(*start) {
+		return false
+	}
+	if end != nil && !now.Before(*end) {
+		return false
+	}
+	return true
+}
+
 // JoinTable this function allows to generate a many2many relation between entities
 // this function is called by migration to associate the tables to this relation
 // please take a look to MenusCategories struct, this struct is used
@@ -128,3 +196,372 @@ func OverrideProducts(items []Item, overriders []product.Overrider) map[uint][]p
 
 	return itemsByCategories
 }
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Daypart is a time range of a store on some days of the week, like breakfast, lunch or late night. The times
+// are in the store time zone and a daypart ending before it starts runs past midnight.
+type Daypart struct {
+	ID        uint          `json:"id"`
+	StoreID   *uint         `json:"store_id" binding:"required"`
+	Name      string        `json:"name" binding:"required" example:"breakfast"`
+	Weekdays  string        `json:"weekdays" binding:"required" example:"mon,tue,wed,thu,fri"`
+	StartTime string        `json:"start_time" binding:"required" example:"06:00"`
+	EndTime   string        `json:"end_time" binding:"required" example:"11:00"`
+	Menus     []DaypartMenu `json:"menus" gorm:"foreignKey:DaypartID"`
+	CreatedAt *time.Time    `json:"created_at,omitempty" swaggerignore:"true"`
+	UpdatedAt *time.Time    `json:"updated_at,omitempty" swaggerignore:"true"`
+}
+
+// DaypartMenu limits a menu, or only a category of the menu when the category is set, to the daypart
+type DaypartMenu struct {
+	ID         uint  `json:"id"`
+	DaypartID  uint  `json:"daypart_id" gorm:"index"`
+	MenuID     *uint `json:"menu_id"`
+	CategoryID *uint `json:"category_id"`
+}
+
+// Validate checks the weekdays and times of the daypart and that its menus are set
+func (d Daypart) Validate() error {
+	if _, err := d.days(); err != nil {
+		return err
+	}
+
+	start, err := time.Parse(daypartTimeLayout, d.StartTime)
+	if err != nil {
+		return fmt.Errorf(ErrorDaypartTime)
+	}
+	end, err := time.Parse(daypartTimeLayout, d.EndTime)
+	if err != nil || start.Equal(end) {
+		return fmt.Errorf(ErrorDaypartTime)
+	}
+
+	for _, daypartMenu := range d.Menus {
+		if daypartMenu.MenuID == nil {
+			return fmt.Errorf(ErrorDaypartWithoutMenu)
+		}
+	}
+
+	return nil
+}
+
+func (d Daypart) days() (map[time.Weekday]bool, error) {
+	days := make(map[time.Weekday]bool)
+	for _, day := range strings.Split(d.Weekdays, ",") {
+		weekday, ok := weekdays[strings.ToLower(strings.TrimSpace(day))]
+		if !ok {
+			return nil, fmt.Errorf(ErrorDaypartWeekdays)
+		}
+		days[weekday] = true
+	}
+	return days, nil
+}
+
+// IsActive checks if the daypart is running at the given local time of the store. The hours past midnight of
+// a daypart ending before it starts belong to the day it started.
+func (d Daypart) IsActive(local time.Time) bool {
+	days, err := d.days()
+	if err != nil {
+		return false
+	}
+
+	start, err := time.Parse(daypartTimeLayout, d.StartTime)
+	if err != nil {
+		return false
+	}
+	end, err := time.Parse(daypartTimeLayout, d.EndTime)
+	if err != nil {
+		return false
+	}
+
+	clock := local.Hour()*60 + local.Minute()
+	startClock := start.Hour()*60 + start.Minute()
+	endClock := end.Hour()*60 + end.Minute()
+
+	if startClock < endClock {
+		return days[local.Weekday()] && clock >= startClock && clock < endClock
+	}
+
+	return (days[local.Weekday()] && clock >= startClock) ||
+		(days[local.AddDate(0, 0, -1).Weekday()] && clock < endClock)
+}
+
+type menuCategory struct {
+	menuID     uint
+	categoryID uint
+}
+
+// Schedule resolves the menus and categories of a store active at a given local time, by the store dayparts
+// and the validity of the menus and their categories. A menu or category without dayparts is always active.
+type Schedule struct {
+	local      time.Time
+	menus      map[uint][]Daypart
+	categories map[menuCategory][]Daypart
+	windows    map[menuCategory]MenusCategories
+}
+
+// NewSchedule indexes the dayparts and the menu categories of a store at a given local time
+func NewSchedule(local time.Time, dayparts []Daypart, menusCategories []MenusCategories) Schedule {
+	schedule := Schedule{
+		local:      local,
+		menus:      make(map[uint][]Daypart),
+		categories: make(map[menuCategory][]Daypart),
+		windows:    make(map[menuCategory]MenusCategories),
+	}
+
+	for _, daypart := range dayparts {
+		for _, daypartMenu := range daypart.Menus {
+			if daypartMenu.MenuID == nil {
+				continue
+			}
+			if daypartMenu.CategoryID == nil {
+				schedule.menus[*daypartMenu.MenuID] = append(schedule.menus[*daypartMenu.MenuID], daypart)
+				continue
+			}
+			key := menuCategory{*daypartMenu.MenuID, *daypartMenu.CategoryID}
+			schedule.categories[key] = append(schedule.categories[key], daypart)
+		}
+	}
+
+	for _, menuCat := range menusCategories {
+		if menuCat.MenuID == nil || menuCat.CategoryID == nil {
+			continue
+		}
+		schedule.windows[menuCategory{*menuCat.MenuID, *menuCat.CategoryID}] = menuCat
+	}
+
+	return schedule
+}
+
+// MenuActive checks if the menu is valid and in one of its dayparts
+func (s Schedule) MenuActive(menu Menu) bool {
+	return menu.InWindow(s.local) && anyActive(s.menus[menu.ID], s.local)
+}
+
+// CategoryActive checks if the category of the menu is valid and in one of its dayparts
+func (s Schedule) CategoryActive(menuID, categoryID uint) bool {
+	key := menuCategory{menuID, categoryID}
+	if window, ok := s.windows[key]; ok && !window.InWindow(s.local) {
+		return false
+	}
+	return anyActive(s.categories[key], s.local)
+}
+
+// Resolve returns the menu with only its active categories, and false when the menu itself isn't active
+func (s Schedule) Resolve(menu Menu) (Menu, bool) {
+	if !s.MenuActive(menu) {
+		return menu, false
+	}
+
+	categories := make([]category.Category, 0)
+	for _, cat := range menu.Categories {
+		if s.CategoryActive(menu.ID, cat.ID) {
+			categories = append(categories, cat)
+		}
+	}
+	menu.Categories = categories
+
+	return menu, true
+}
+
+// NextChange returns when the active menus or categories may change next, the closest daypart start or end and
+// menu or category validity bound after the schedule time. It is zero when nothing changes the resolved menus.
+func (s Schedule) NextChange(menus []Menu) time.Time {
+	var next time.Time
+	consider := func(t time.Time) {
+		if t.After(s.local) && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+	considerPtr := func(t *time.Time) {
+		if t != nil {
+			consider(*t)
+		}
+	}
+
+	dayparts := make([]Daypart, 0)
+	for _, menuDayparts := range s.menus {
+		dayparts = append(dayparts, menuDayparts...)
+	}
+	for _, categoryDayparts := range s.categories {
+		dayparts = append(dayparts, categoryDayparts...)
+	}
+
+	// The weekdays are ignored, a bound on a day the daypart doesn't run only refreshes the menus earlier
+	for _, daypart := range dayparts {
+		for _, clock := range []string{daypart.StartTime, daypart.EndTime} {
+			if at, ok := nextClock(s.local, clock); ok {
+				consider(at)
+			}
+		}
+	}
+
+	for _, menu := range menus {
+		considerPtr(menu.StartTime)
+		considerPtr(menu.EndTime)
+	}
+	for _, window := range s.windows {
+		considerPtr(window.StartTime)
+		considerPtr(window.EndTime)
+	}
+
+	return next
+}
+
+// nextClock returns the next time after local the store clock shows the given daypart time
+func nextClock(local time.Time, clock string) (time.Time, bool) {
+	parsed, err := time.Parse(daypartTimeLayout, clock)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	at := time.Date(local.Year(), local.Month(), local.Day(), parsed.Hour(), parsed.Minute(), 0, 0, local.Location())
+	if !at.After(local) {
+		at = at.AddDate(0, 0, 1)
+	}
+	return at, true
+}
+
+func anyActive(dayparts []Daypart, local time.Time) bool {
+	if len(dayparts) == 0 {
+		return true
+	}
+
+	for _, daypart := range dayparts {
+		if daypart.IsActive(local) {
+			return true
+		}
+	}
+	return false
+}
+
+// EachProduct calls fn with every product of the menus and the options of their modifier groups, the nested
+// groups included. The products are changed in place.
+func EachProduct(menus []Menu, fn func(p *product.Product, option bool)) {
+	for i := range menus {
+		for j := range menus[i].Categories {
+			for k := range menus[i].Categories[j].Products {
+				p := &menus[i].Categories[j].Products[k]
+				fn(p, false)
+				eachOption(p, fn)
+			}
+		}
+	}
+}
+
+func eachOption(p *product.Product, fn func(p *product.Product, option bool)) {
+	for i := range p.Modifiers {
+		for j := range p.Modifiers[i].Options {
+			if option := p.Modifiers[i].Options[j].Product; option != nil {
+				fn(option, true)
+				eachOption(option, fn)
+			}
+		}
+	}
+}
+
+// CacheTags returns the cache tags of the records the menus are built from: the menus, their categories, the
+// products, options and combo components, and the modifier groups.
+func CacheTags(menus []Menu) []menucache.Tag {
+	seen := make(map[menucache.Tag]bool)
+	tags := make([]menucache.Tag, 0)
+	add := func(tag menucache.Tag) {
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+
+	for _, menu := range menus {
+		add(menucache.MenuTag(menu.ID))
+		for _, cat := range menu.Categories {
+			add(menucache.CategoryTag(cat.ID))
+		}
+	}
+
+	EachProduct(menus, func(p *product.Product, _ bool) {
+		add(menucache.ProductTag(p.ID))
+		for _, modifier := range p.Modifiers {
+			add(menucache.ModifierTag(modifier.ID))
+		}
+		for _, component := range p.Components {
+			if component.ProductID != nil {
+				add(menucache.ProductTag(*component.ProductID))
+			}
+		}
+	})
+
+	return tags
+}
+
+// MenuVersion is a snapshot of the menus of a brand with their categories, products and overriders. The catalog
+// tables are the draft of the next version: once a brand has a published version its stores and channels are
+// served and priced from it, so the catalog changes only go live when a version with them is published.
+type MenuVersion struct {
+	ID          uint                `json:"id"`
+	BrandID     uint                `json:"brand_id" gorm:"index"`
+	Number      int                 `json:"number"`
+	Status      string              `json:"status" gorm:"index" enums:"draft,scheduled,published,superseded"`
+	Notes       string              `json:"notes"`
+	PublishAt   *time.Time          `json:"publish_at"`
+	PublishedAt *time.Time          `json:"published_at"`
+	Menus       []Menu              `json:"menus,omitempty" gorm:"serializer:json"`
+	Overriders  []product.Overrider `json:"overriders,omitempty" gorm:"serializer:json"`
+	CreatedAt   *time.Time          `json:"created_at,omitempty" swaggerignore:"true"`
+	UpdatedAt   *time.Time          `json:"updated_at,omitempty" swaggerignore:"true"`
+}
+
+// Editable checks if the version hasn't been published yet
+func (v MenuVersion) Editable() bool {
+	return v.Status == VersionDraft || v.Status == VersionScheduled
+}
+
+// Menu returns the menu of the version
+func (v MenuVersion) Menu(menuID string) (*Menu, bool) {
+	for _, menu := range v.Menus {
+		if fmt.Sprint(menu.ID) == menuID {
+			return &menu, true
+		}
+	}
+	return nil, false
+}
+
+// PlaceOverriders returns the overriders of the version for the place
+func (v MenuVersion) PlaceOverriders(place, placeID string) []product.Overrider {
+	overriders := make([]product.Overrider, 0)
+	for _, overrider := range v.Overriders {
+		if overrider.Place == place && overrider.PlaceID != nil && fmt.Sprint(*overrider.PlaceID) == placeID {
+			overriders = append(overriders, overrider)
+		}
+	}
+	return overriders
+}
+
+// Prices returns the price of the products and modifier options of the version
+func (v MenuVersion) Prices() map[uint]float64 {
+	prices := make(map[uint]float64)
+	EachProduct(v.Menus, func(p *product.Product, _ bool) {
+		prices[p.ID] = p.Price
+	})
+	return prices
+}
+
+// MenuItems returns the products of the categories of a menu as menu items
+func MenuItems(menu Menu) []Item {
+	items := make([]Item, 0)
+	for _, cat := range menu.Categories {
+		categoryID := cat.ID
+		for _, p := range cat.Products {
+			items = append(items, Item{Product: p, CategoryID: &categoryID})
+		}
+	}
+	return items
+}
@@ -2,8 +2,11 @@ package menu
 
 import (
 	"net/http"
+	"strconv"
+	"time"
 
 	availabilityPkg "github.com/BacoFoods/menu/pkg/availability"
+	"github.com/BacoFoods/menu/pkg/realtime"
 	"github.com/BacoFoods/menu/pkg/shared"
 	"github.com/gin-gonic/gin"
 )
@@ -24,12 +27,22 @@ type RequestMenuAvailability struct {
 	} `json:"places" binding:"required"`
 }
 
+type RequestVersionCreate struct {
+	BrandID uint   `json:"brand_id" binding:"required"`
+	Notes   string `json:"notes"`
+}
+
+type RequestVersionPublish struct {
+	PublishAt *time.Time `json:"publish_at" example:"2024-01-01T06:00:00-05:00"` // empty to publish right away
+}
+
 type Handler struct {
-	service Service
+	service    Service
+	subscriber realtime.Subscriber
 }
 
-func NewHandler(service Service) *Handler {
-	return &Handler{service: service}
+func NewHandler(service Service, subscriber realtime.Subscriber) *Handler {
+	return &Handler{service: service, subscriber: subscriber}
 }
 
 // Find to handle a request to find all menus
@@ -209,12 +222,14 @@ func (h *Handler) ListByPlace(c *gin.Context) {
 // PublicStoreMenu to handle a request to list menus for a store
 // @Tags Menu
 // @Summary To list menus by place
-// @Description To list menus by place
+// @Description To list menus by place, the response has an ETag and a request with it in If-None-Match gets a 304 while the menus are unchanged
 // @Param place path string true "place"
 // @Param place-id path string true "place id"
+// @Param If-None-Match header string false "ETag of a previous response"
 // @Accept json
 // @Produce json
 // @Success 200 {object} object{status=string,data=[]Menu}
+// @Success 304 "Not Modified"
 // @Failure 400 {object} shared.Response
 // @Failure 422 {object} shared.Response
 // @Failure 401 {object} shared.Response
@@ -222,13 +237,49 @@ func (h *Handler) ListByPlace(c *gin.Context) {
 func (h *Handler) PublicStoreMenu(c *gin.Context) {
 	place := c.Param("place")
 	placeID := c.Param("place-id")
-	menus, err := h.service.FindByPlace(place, placeID)
+	entry, err := h.service.PublicMenu(place, placeID)
 	if err != nil {
-		shared.LogError("error finding menus", LogHandler, "PublicStoreMenu", err, menus)
+		shared.LogError("error finding menus", LogHandler, "PublicStoreMenu", err, place, placeID)
 		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(ErrorMenuFindingByPlace))
 		return
 	}
-	c.JSON(http.StatusOK, shared.SuccessResponse(menus))
+
+	c.Header("ETag", entry.ETag)
+	c.Header("Cache-Control", "no-cache")
+	if entry.Matches(c.GetHeader("If-None-Match")) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", entry.Body)
+}
+
+// PublicMenuEvents to handle a subscription to the menu changes of a place
+// @Tags Menu
+// @Summary To subscribe to menu changes by place
+// @Description Server sent events stream with the stock-outs and restocks of the products and modifiers of a store or channel, and the menu versions published for it
+// @Param place path string true "place"
+// @Param place-id path string true "place id"
+// @Produce text/event-stream
+// @Success 200 {object} realtime.Event
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Router /public/menu/place/{place}/{place-id}/events [get]
+func (h *Handler) PublicMenuEvents(c *gin.Context) {
+	place, err := availabilityPkg.GetPlace(c.Param("place"))
+	if err != nil {
+		shared.LogWarn("warning getting place", LogHandler, "PublicMenuEvents", err, c.Param("place"))
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorMenuBadRequest))
+		return
+	}
+
+	placeID, err := strconv.ParseUint(c.Param("place-id"), 10, 64)
+	if err != nil {
+		shared.LogWarn("warning parsing place id", LogHandler, "PublicMenuEvents", err, c.Param("place-id"))
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorMenuBadRequest))
+		return
+	}
+
+	realtime.Stream(c, h.subscriber, realtime.MenuTopic(string(place), uint(placeID)))
 }
 
 // GetByPlace to handle a request to get a menu by place and load overriders and availabilities
@@ -252,6 +303,11 @@ func (h *Handler) GetByPlace(c *gin.Context) {
 	menuID := c.Param("menu-id")
 
 	menu, err := h.service.GetByPlace(place, placeID, menuID)
+	if err != nil && err.Error() == ErrorMenuNotActive {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(ErrorMenuNotActive))
+		return
+	}
+
 	if err != nil {
 		shared.LogError("error getting menu by place", LogHandler, "GetByPlace", err, menu)
 		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(ErrorMenuGetting))
@@ -390,3 +446,305 @@ func (h *Handler) RemoveCategory(c *gin.Context) {
 
 	c.JSON(http.StatusOK, shared.SuccessResponse(menu))
 }
+
+// FindDayparts to handle a request to find the dayparts of a store
+// @Tags Menu
+// @Summary To find dayparts
+// @Description To find the dayparts of a store with the menus and categories limited to them
+// @Param store_id query string false "store id"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=[]Daypart}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /menu/daypart [get]
+func (h *Handler) FindDayparts(c *gin.Context) {
+	filter := make(map[string]string)
+	if storeID := c.Query("store_id"); storeID != "" {
+		filter["store_id"] = storeID
+	}
+
+	dayparts, err := h.service.FindDayparts(filter)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(dayparts))
+}
+
+// CreateDaypart to handle a request to create a daypart
+// @Tags Menu
+// @Summary To create a daypart
+// @Description To create a daypart of a store, times are in the store time zone as 15:04 and weekdays as mon,tue,wed,thu,fri,sat,sun
+// @Param daypart body Daypart true "daypart"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=Daypart}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /menu/daypart [post]
+func (h *Handler) CreateDaypart(c *gin.Context) {
+	var body Daypart
+	if err := c.ShouldBindJSON(&body); err != nil {
+		shared.LogWarn("warning binding request body", LogHandler, "CreateDaypart", err, body)
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorMenuBadRequest))
+		return
+	}
+
+	body.ID = 0
+	daypart, err := h.service.CreateDaypart(&body)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(daypart))
+}
+
+// UpdateDaypart to handle a request to update a daypart
+// @Tags Menu
+// @Summary To update a daypart
+// @Description To update a daypart, its menus and categories are replaced by the given ones
+// @Param id path string true "daypart id"
+// @Param daypart body Daypart true "daypart"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=Daypart}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /menu/daypart/{id} [patch]
+func (h *Handler) UpdateDaypart(c *gin.Context) {
+	daypartID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		shared.LogWarn("warning parsing daypart id", LogHandler, "UpdateDaypart", err, c.Param("id"))
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorMenuBadRequest))
+		return
+	}
+
+	var body Daypart
+	if err := c.ShouldBindJSON(&body); err != nil {
+		shared.LogWarn("warning binding request body", LogHandler, "UpdateDaypart", err, body)
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorMenuBadRequest))
+		return
+	}
+
+	body.ID = uint(daypartID)
+	daypart, err := h.service.UpdateDaypart(&body)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(daypart))
+}
+
+// DeleteDaypart to handle a request to delete a daypart
+// @Tags Menu
+// @Summary To delete a daypart
+// @Description To delete a daypart, its menus and categories are available all day again
+// @Param id path string true "daypart id"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=Daypart}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /menu/daypart/{id} [delete]
+func (h *Handler) DeleteDaypart(c *gin.Context) {
+	daypart, err := h.service.DeleteDaypart(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(daypart))
+}
+
+// FindVersions to handle a request to find the menu versions of a brand
+// @Tags Menu
+// @Summary To find menu versions
+// @Description To find the menu versions of a brand without their snapshots, newest first
+// @Param brand-id query string false "brand id"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=[]MenuVersion}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /menu/version [get]
+func (h *Handler) FindVersions(c *gin.Context) {
+	versions, err := h.service.FindVersions(c.Query("brand-id"))
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(versions))
+}
+
+// GetVersion to handle a request to get a menu version
+// @Tags Menu
+// @Summary To get a menu version
+// @Description To get a menu version with the snapshot of its menus and overriders
+// @Param id path string true "version id"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=MenuVersion}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /menu/version/{id} [get]
+func (h *Handler) GetVersion(c *gin.Context) {
+	version, err := h.service.GetVersion(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(version))
+}
+
+// CreateVersion to handle a request to create a draft menu version
+// @Tags Menu
+// @Summary To create a draft menu version
+// @Description To take a draft snapshot of the current menus, categories, products and overriders of a brand. The catalog changes only go live when a version with them is published.
+// @Param version body RequestVersionCreate true "version"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=MenuVersion}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /menu/version [post]
+func (h *Handler) CreateVersion(c *gin.Context) {
+	var body RequestVersionCreate
+	if err := c.ShouldBindJSON(&body); err != nil {
+		shared.LogWarn("warning binding request body", LogHandler, "CreateVersion", err, body)
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorMenuBadRequest))
+		return
+	}
+
+	version, err := h.service.CreateVersion(body.BrandID, body.Notes)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(version))
+}
+
+// PublishVersion to handle a request to publish a menu version
+// @Tags Menu
+// @Summary To publish a menu version
+// @Description To publish a draft or scheduled menu version right away, or to schedule it to go live at the publish at time
+// @Param id path string true "version id"
+// @Param request body RequestVersionPublish false "request"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=MenuVersion}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /menu/version/{id}/publish [post]
+func (h *Handler) PublishVersion(c *gin.Context) {
+	var body RequestVersionPublish
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			shared.LogWarn("warning binding request body", LogHandler, "PublishVersion", err, body)
+			c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorMenuBadRequest))
+			return
+		}
+	}
+
+	version, err := h.service.PublishVersion(c.Param("id"), body.PublishAt)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(version))
+}
+
+// RollbackVersion to handle a request to roll back to a previous menu version
+// @Tags Menu
+// @Summary To roll back to a menu version
+// @Description To publish again a menu version superseded by a later one
+// @Param id path string true "version id"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=MenuVersion}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /menu/version/{id}/rollback [post]
+func (h *Handler) RollbackVersion(c *gin.Context) {
+	version, err := h.service.RollbackVersion(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(version))
+}
+
+// DeleteVersion to handle a request to delete a menu version
+// @Tags Menu
+// @Summary To delete a menu version
+// @Description To delete a draft or scheduled menu version
+// @Param id path string true "version id"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=MenuVersion}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /menu/version/{id} [delete]
+func (h *Handler) DeleteVersion(c *gin.Context) {
+	version, err := h.service.DeleteVersion(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(version))
+}
+
+// PreviewVersion to handle a request to preview a menu version at a place
+// @Tags Menu
+// @Summary To preview a menu version by place
+// @Description To resolve the menus of a version at a store or channel as they would be served once published
+// @Param id path string true "version id"
+// @Param place path string true "place"
+// @Param place-id path string true "place id"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=[]Menu}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /menu/version/{id}/preview/{place}/{place-id} [get]
+func (h *Handler) PreviewVersion(c *gin.Context) {
+	menus, err := h.service.PreviewVersion(c.Param("id"), c.Param("place"), c.Param("place-id"))
+	if err != nil {
+		shared.LogError("error previewing menu version", LogHandler, "PreviewVersion", err, c.Param("id"), c.Param("place"), c.Param("place-id"))
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(menus))
+}
@@ -24,5 +24,19 @@ func (r Routes) RegisterRoutes(private, public *shared.CustomRoutes) {
 	private.PATCH("/menu/:id/category/:categoryID/remove", r.handler.RemoveCategory)
 	private.DELETE("/menu/:id", r.handler.Delete)
 
+	private.GET("/menu/daypart", r.handler.FindDayparts)
+	private.POST("/menu/daypart", r.handler.CreateDaypart)
+	private.PATCH("/menu/daypart/:id", r.handler.UpdateDaypart)
+	private.DELETE("/menu/daypart/:id", r.handler.DeleteDaypart)
+
+	private.GET("/menu/version", r.handler.FindVersions)
+	private.POST("/menu/version", r.handler.CreateVersion)
+	private.GET("/menu/version/:id", r.handler.GetVersion)
+	private.GET("/menu/version/:id/preview/:place/:place-id", r.handler.PreviewVersion)
+	private.POST("/menu/version/:id/publish", r.handler.PublishVersion)
+	private.POST("/menu/version/:id/rollback", r.handler.RollbackVersion)
+	private.DELETE("/menu/version/:id", r.handler.DeleteVersion)
+
 	public.GET("/menu/place/:place/:place-id/list", r.handler.PublicStoreMenu)
+	public.GET("/menu/place/:place/:place-id/events", r.handler.PublicMenuEvents)
 }
@@ -1,9 +1,18 @@
 package menu
 
 import (
-	productPkg "github.com/BacoFoods/menu/pkg/product"
+	"context"
+	"encoding/json"
+	"fmt"
 	"strconv"
+	"time"
+
+	"github.com/BacoFoods/menu/pkg/menucache"
+	productPkg "github.com/BacoFoods/menu/pkg/product"
+	"github.com/BacoFoods/menu/pkg/realtime"
+	"github.com/redis/go-redis/v9"
 
+	"github.com/BacoFoods/menu/internal"
 	availabilityPkg "github.com/BacoFoods/menu/pkg/availability"
 	categoryPkg "github.com/BacoFoods/menu/pkg/category"
 	"github.com/BacoFoods/menu/pkg/shared"
@@ -12,6 +21,8 @@ import (
 
 const (
 	LogService string = "pkg/menu/service"
+
+	publisherLockKey = "menu:versions:publisher"
 )
 
 // Service is the interface that provides menu methods, used for dependency injection.
@@ -22,11 +33,27 @@ type Service interface {
 	Update(*Menu) (*Menu, error)
 	Delete(string) (*Menu, error)
 	FindByPlace(string, string) ([]Menu, error)
+	PublicMenu(place, placeID string) (*menucache.Entry, error)
 	GetByPlace(string, string, string) (*Menu, error)
 	UpdateAvailability(menuID, place string, placeIDs map[uint]bool) (*Menu, error)
 	FindChannels(menuID, storeID string) ([]any, error)
 	AddCategory(menuID, categoryID string) (*Menu, error)
 	RemoveCategory(menuID, categoryID string) (*Menu, error)
+	UnavailableProducts(storeID, channelID string, productIDs []uint) ([]uint, error)
+	PlaceOverriders(place, placeID string) ([]productPkg.Overrider, error)
+	FindDayparts(filter map[string]string) ([]Daypart, error)
+	CreateDaypart(*Daypart) (*Daypart, error)
+	UpdateDaypart(*Daypart) (*Daypart, error)
+	DeleteDaypart(daypartID string) (*Daypart, error)
+	FindVersions(brandID string) ([]MenuVersion, error)
+	GetVersion(versionID string) (*MenuVersion, error)
+	CreateVersion(brandID uint, notes string) (*MenuVersion, error)
+	PublishVersion(versionID string, publishAt *time.Time) (*MenuVersion, error)
+	RollbackVersion(versionID string) (*MenuVersion, error)
+	DeleteVersion(versionID string) (*MenuVersion, error)
+	PreviewVersion(versionID, place, placeID string) ([]Menu, error)
+	VersionPrices(brandID uint, versionID *uint) (*uint, map[uint]float64, error)
+	RunPublisher(ctx context.Context)
 }
 
 // service is the default implementation of the Service interface for menu.
@@ -36,6 +63,9 @@ type service struct {
 	availability availabilityPkg.Repository
 	store        storePkg.Repository
 	category     categoryPkg.Repository
+	events       realtime.Publisher
+	redis        *redis.Client
+	cache        menucache.Cache
 }
 
 // NewService creates a new instance of the service for menu, using the provided repository implementation.
@@ -43,8 +73,11 @@ func NewService(repository Repository,
 	product productPkg.Repository,
 	availability availabilityPkg.Repository,
 	store storePkg.Repository,
-	category categoryPkg.Repository) service {
-	return service{repository, product, availability, store, category}
+	category categoryPkg.Repository,
+	events realtime.Publisher,
+	redis *redis.Client,
+	cache menucache.Cache) service {
+	return service{repository, product, availability, store, category, events, redis, cache}
 }
 
 // Find returns a list of menu objects filtering by query map.
@@ -88,27 +121,267 @@ func (s service) Create(name string, brandID uint, placeName string, placeIds []
 		}
 	}
 
+	s.invalidate(menucache.BrandTag(brandID))
+
 	return menu, nil
 }
 
 // Update updates an existing menu object only the fields that are present in the provided object.
 // this method doesn't create new register if the provided id doesn't exist.
 func (s service) Update(menu *Menu) (*Menu, error) {
-	return s.repository.Update(menu)
+	menuDB, err := s.repository.Update(menu)
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidate(menuTags(menuDB)...)
+	return menuDB, nil
 }
 
 // Delete deletes an existing menu object.
 func (s service) Delete(menuID string) (*Menu, error) {
-	return s.repository.Delete(menuID)
+	menu, err := s.repository.Delete(menuID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidate(menuTags(menu)...)
+	return menu, nil
 }
 
-// FindByPlace returns a list of menu objects filtering by place and placeID.
+// FindByPlace returns a list of menu objects filtering by place and placeID. The menus of a store are resolved
+// at request time, only the active menus and categories are returned.
 func (s service) FindByPlace(place, placeID string) ([]Menu, error) {
+	menus, err := s.findByPlace(place, placeID)
+	if err != nil {
+		return []Menu{}, err
+	}
+
+	menus, _, err = s.resolve(place, placeID, menus)
+	return menus, err
+}
+
+// PublicMenu returns the menus of the place as a response body with its ETag. The bodies are cached until a
+// record they are built from changes or the schedule of the store changes the active menus or categories.
+func (s service) PublicMenu(place, placeID string) (*menucache.Entry, error) {
+	if s.cache != nil {
+		if entry, ok := s.cache.Get(place, placeID); ok {
+			return entry, nil
+		}
+	}
+
+	// The epoch is read before the menus so an invalidation while they are resolved discards this entry
+	epoch := ""
+	if s.cache != nil {
+		epoch = s.cache.Epoch()
+	}
+
+	menus, err := s.findByPlace(place, placeID)
+	if err != nil {
+		return nil, err
+	}
+	tags := CacheTags(menus)
+
+	menus, nextChange, err := s.resolve(place, placeID, menus)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(shared.SuccessResponse(menus))
+	if err != nil {
+		shared.LogError("error marshalling menus", LogService, "PublicMenu", err, place, placeID)
+		return nil, fmt.Errorf(ErrorMenuFindingByPlace)
+	}
+	entry := menucache.NewEntry(body)
+
+	if s.cache == nil {
+		return &entry, nil
+	}
+
+	placeTags, err := s.placeTags(place, placeID)
+	if err != nil {
+		return &entry, nil
+	}
+
+	ttl := s.cache.TTL()
+	if !nextChange.IsZero() && time.Until(nextChange) < ttl {
+		ttl = time.Until(nextChange)
+	}
+	s.cache.Set(place, placeID, epoch, entry, append(tags, placeTags...), ttl)
+
+	return &entry, nil
+}
+
+// placeTags returns the cache tags of the place and of its brand
+func (s service) placeTags(place, placeID string) ([]menucache.Tag, error) {
+	id, err := strconv.ParseUint(placeID, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	brand, err := s.repository.BrandIDByPlace(place, placeID)
+	if err != nil {
+		return nil, err
+	}
+
+	brandID, err := strconv.ParseUint(brand, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return []menucache.Tag{menucache.PlaceTag(place, uint(id)), menucache.BrandTag(uint(brandID))}, nil
+}
+
+// invalidate drops the cached menus built from the tags
+func (s service) invalidate(tags ...menucache.Tag) {
+	if s.cache == nil {
+		return
+	}
+	s.cache.Invalidate(tags...)
+}
+
+// menuTags returns the cache tags of a changed menu, the brand one covers the places the menu wasn't served at
+func menuTags(menu *Menu) []menucache.Tag {
+	if menu == nil {
+		return nil
+	}
+
+	tags := []menucache.Tag{menucache.MenuTag(menu.ID)}
+	if menu.BrandID != nil {
+		tags = append(tags, menucache.BrandTag(*menu.BrandID))
+	}
+	return tags
+}
+
+// resolve keeps the active menus and categories of a store and flags the products 86'd at the place, it also
+// returns when the active ones may change next, zero when they don't depend on the time
+func (s service) resolve(place, placeID string, menus []Menu) ([]Menu, time.Time, error) {
+	var nextChange time.Time
+	if len(menus) == 0 {
+		return menus, nextChange, nil
+	}
+
+	if place == string(availabilityPkg.PlaceStore) {
+		schedule, err := s.schedule(placeID, menus)
+		if err != nil {
+			return []Menu{}, nextChange, err
+		}
+		nextChange = schedule.NextChange(menus)
+
+		activeMenus := make([]Menu, 0)
+		for _, menu := range menus {
+			if activeMenu, ok := schedule.Resolve(menu); ok {
+				activeMenus = append(activeMenus, activeMenu)
+			}
+		}
+		menus = activeMenus
+	}
+
+	if err := s.markStock(place, placeID, menus); err != nil {
+		return []Menu{}, nextChange, err
+	}
+
+	return menus, nextChange, nil
+}
+
+// markStock flags the products and modifier options 86'd at the place, with the units left of the counted ones
+func (s service) markStock(place, placeID string, menus []Menu) error {
+	id, err := strconv.ParseUint(placeID, 10, 64)
+	if err != nil {
+		return nil
+	}
+
+	storeID, channelID := uint(0), uint(0)
+	switch place {
+	case string(availabilityPkg.PlaceStore):
+		storeID = uint(id)
+	case string(availabilityPkg.PlaceChannel):
+		channelID = uint(id)
+	default:
+		return nil
+	}
+
+	productIDs := make([]uint, 0)
+	optionIDs := make([]uint, 0)
+	EachProduct(menus, func(p *productPkg.Product, option bool) {
+		if option {
+			optionIDs = append(optionIDs, p.ID)
+			return
+		}
+		productIDs = append(productIDs, p.ID)
+	})
+
+	products, err := s.availability.FindStock(availabilityPkg.EntityProduct, storeID, channelID, productIDs)
+	if err != nil {
+		return fmt.Errorf(ErrorMenuResolvingStock)
+	}
+
+	options, err := s.availability.FindStock(availabilityPkg.EntityModifier, storeID, channelID, optionIDs)
+	if err != nil {
+		return fmt.Errorf(ErrorMenuResolvingStock)
+	}
+
+	if len(products) == 0 && len(options) == 0 {
+		return nil
+	}
+
+	stock := map[bool]map[uint]availabilityPkg.Availability{false: {}, true: {}}
+	for _, availability := range products {
+		stock[false][*availability.EntityID] = availability
+	}
+	for _, availability := range options {
+		stock[true][*availability.EntityID] = availability
+	}
+
+	EachProduct(menus, func(p *productPkg.Product, option bool) {
+		availability, ok := stock[option][p.ID]
+		if !ok {
+			return
+		}
+		p.StockedOut = !availability.Available(1)
+		p.Stock = availability.Quantity
+	})
+
+	return nil
+}
+
+// findByPlace returns the menus of the brand of the place enabled at the place, from the published version of the
+// brand or from the catalog when the brand has never published one
+func (s service) findByPlace(place, placeID string) ([]Menu, error) {
+	version, err := s.publishedVersion(place, placeID)
+	if err != nil {
+		return []Menu{}, err
+	}
+
+	if version != nil {
+		return s.availableAt(place, placeID, version.Menus)
+	}
+
 	menus, err := s.repository.FindByPlace(place, placeID)
 	if err != nil {
 		return []Menu{}, err
 	}
 
+	return s.availableAt(place, placeID, menus)
+}
+
+// publishedVersion returns the published menu version of the brand of the place, nil when it has none
+func (s service) publishedVersion(place, placeID string) (*MenuVersion, error) {
+	brandID, err := s.repository.BrandIDByPlace(place, placeID)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := s.repository.GetPublishedVersion(brandID)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorVersionGetting)
+	}
+
+	return version, nil
+}
+
+// availableAt returns the menus enabled at the place
+func (s service) availableAt(place, placeID string, menus []Menu) ([]Menu, error) {
 	if len(menus) == 0 {
 		return []Menu{}, nil
 	}
@@ -140,27 +413,31 @@ func (s service) FindByPlace(place, placeID string) ([]Menu, error) {
 	return menuList, nil
 }
 
-// GetByPlace returns a single menu object loading overriders by ID.
+// GetByPlace returns a single menu object loading overriders by ID. The menu of a store is resolved at request
+// time, only its active categories are returned.
 func (s service) GetByPlace(place, placeID, menuID string) (*Menu, error) {
-	menu, err := s.repository.Get(menuID)
+	menu, menuItems, overriders, err := s.placeMenu(place, placeID, menuID)
 	if err != nil {
 		return nil, err
 	}
 
-	menuItems, err := s.repository.GetMenuItems(menuID)
-	if err != nil {
-		return nil, err
+	if place == string(availabilityPkg.PlaceStore) {
+		schedule, err := s.schedule(placeID, []Menu{*menu})
+		if err != nil {
+			return nil, err
+		}
+
+		activeMenu, ok := schedule.Resolve(*menu)
+		if !ok {
+			return nil, fmt.Errorf(ErrorMenuNotActive)
+		}
+		menu = &activeMenu
 	}
 
 	if len(menuItems) == 0 {
 		return menu, nil
 	}
 
-	overriders, err := s.product.OverriderFindByPlace(place, placeID)
-	if err != nil {
-		return nil, err
-	}
-
 	productsByCategory := OverrideProducts(menuItems, overriders)
 
 	var categories []categoryPkg.Category
@@ -170,9 +447,148 @@ func (s service) GetByPlace(place, placeID, menuID string) (*Menu, error) {
 	}
 	menu.Categories = categories
 
+	if err := s.markStock(place, placeID, []Menu{*menu}); err != nil {
+		return nil, err
+	}
+
 	return menu, nil
 }
 
+// placeMenu returns the menu with its items and the overriders of the place, from the published version of the
+// brand or from the catalog when the brand has never published one
+func (s service) placeMenu(place, placeID, menuID string) (*Menu, []Item, []productPkg.Overrider, error) {
+	version, err := s.publishedVersion(place, placeID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if version != nil {
+		menu, ok := version.Menu(menuID)
+		if !ok {
+			return nil, nil, nil, fmt.Errorf(ErrorMenuGetting)
+		}
+		return menu, MenuItems(*menu), version.PlaceOverriders(place, placeID), nil
+	}
+
+	menu, err := s.repository.Get(menuID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	menuItems, err := s.repository.GetMenuItems(menuID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	overriders, err := s.product.OverriderFindByPlace(place, placeID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return menu, menuItems, overriders, nil
+}
+
+// PlaceOverriders returns the overriders of the store or channel, from the published version of the brand or from
+// the catalog when the brand has never published one
+func (s service) PlaceOverriders(place, placeID string) ([]productPkg.Overrider, error) {
+	version, err := s.publishedVersion(place, placeID)
+	if err != nil {
+		return nil, err
+	}
+
+	if version != nil {
+		return version.PlaceOverriders(place, placeID), nil
+	}
+
+	return s.product.OverriderFindByPlace(place, placeID)
+}
+
+// schedule loads the dayparts of the store and the categories of the menus at the store local time
+func (s service) schedule(storeID string, menus []Menu) (*Schedule, error) {
+	store, err := s.store.Get(storeID)
+	if err != nil {
+		shared.LogError("error getting store", LogService, "schedule", err, storeID)
+		return nil, fmt.Errorf(ErrorMenuResolvingActive)
+	}
+
+	dayparts, err := s.repository.FindDayparts(map[string]string{"store_id": storeID})
+	if err != nil {
+		return nil, fmt.Errorf(ErrorMenuResolvingActive)
+	}
+
+	menuIDs := make([]uint, 0)
+	for _, menu := range menus {
+		menuIDs = append(menuIDs, menu.ID)
+	}
+
+	menusCategories, err := s.repository.FindMenusCategories(menuIDs)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorMenuResolvingActive)
+	}
+
+	schedule := NewSchedule(time.Now().In(store.Location()), dayparts, menusCategories)
+	return &schedule, nil
+}
+
+// UnavailableProducts returns the products that can't be sold right now on the channel of the store, that is
+// the products on the channel menus whose menus or categories are all out of their dayparts. The products
+// out of the menus are not checked.
+func (s service) UnavailableProducts(storeID, channelID string, productIDs []uint) ([]uint, error) {
+	menus, err := s.findByPlace(string(availabilityPkg.PlaceChannel), channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(menus) == 0 {
+		if menus, err = s.findByPlace(string(availabilityPkg.PlaceStore), storeID); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(menus) == 0 {
+		return []uint{}, nil
+	}
+
+	schedule, err := s.schedule(storeID, menus)
+	if err != nil {
+		return nil, err
+	}
+
+	onMenu := make(map[uint]bool)
+	sellable := make(map[uint]bool)
+	for _, menu := range menus {
+		if !menu.Enable {
+			continue
+		}
+
+		menuActive := schedule.MenuActive(menu)
+		for _, category := range menu.Categories {
+			active := menuActive && schedule.CategoryActive(menu.ID, category.ID)
+			for _, product := range category.Products {
+				onMenu[product.ID] = true
+				if active {
+					sellable[product.ID] = true
+				}
+			}
+		}
+	}
+
+	unavailable := make([]uint, 0)
+	checked := make(map[uint]bool)
+	for _, productID := range productIDs {
+		if checked[productID] {
+			continue
+		}
+		checked[productID] = true
+
+		if onMenu[productID] && !sellable[productID] {
+			unavailable = append(unavailable, productID)
+		}
+	}
+
+	return unavailable, nil
+}
+
 // UpdateAvailability updates the availability of a menu.
 func (s service) UpdateAvailability(menuID string, placeName string, placeIDs map[uint]bool) (*Menu, error) {
 	place, err := availabilityPkg.GetPlace(placeName)
@@ -197,6 +613,7 @@ func (s service) UpdateAvailability(menuID string, placeName string, placeIDs ma
 			if err := s.availability.EnableEntity(availabilityPkg.EntityMenu, place, menu.ID, *availability.PlaceID, enable); err != nil {
 				return nil, err
 			}
+			s.invalidate(menucache.PlaceTag(string(place), *availability.PlaceID))
 		}
 	}
 
@@ -258,7 +675,13 @@ func (s service) AddCategory(menuID, categoryID string) (*Menu, error) {
 		return nil, err
 	}
 
-	return s.repository.AddCategory(menuID, cat)
+	menu, err := s.repository.AddCategory(menuID, cat)
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidate(menuTags(menu)...)
+	return menu, nil
 }
 
 // RemoveCategory removes a category from a menu.
@@ -268,5 +691,306 @@ func (s service) RemoveCategory(menuID, categoryID string) (*Menu, error) {
 		return nil, err
 	}
 
-	return s.repository.RemoveCategory(menuID, cat)
+	menu, err := s.repository.RemoveCategory(menuID, cat)
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidate(menuTags(menu)...)
+	return menu, nil
+}
+
+// FindDayparts returns the dayparts filtered by store
+func (s service) FindDayparts(filter map[string]string) ([]Daypart, error) {
+	dayparts, err := s.repository.FindDayparts(filter)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorDaypartFinding)
+	}
+	return dayparts, nil
+}
+
+// CreateDaypart creates a daypart with the menus and categories limited to it
+func (s service) CreateDaypart(daypart *Daypart) (*Daypart, error) {
+	if err := daypart.Validate(); err != nil {
+		return nil, err
+	}
+
+	daypartDB, err := s.repository.CreateDaypart(daypart)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorDaypartCreating)
+	}
+
+	s.invalidate(daypartTags(daypartDB)...)
+	return daypartDB, nil
+}
+
+// UpdateDaypart updates a daypart, its menus and categories are replaced by the given ones
+func (s service) UpdateDaypart(daypart *Daypart) (*Daypart, error) {
+	if err := daypart.Validate(); err != nil {
+		return nil, err
+	}
+
+	daypartDB, err := s.repository.UpdateDaypart(daypart)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorDaypartUpdating)
+	}
+
+	s.invalidate(daypartTags(daypartDB)...)
+	return daypartDB, nil
+}
+
+// DeleteDaypart deletes a daypart, its menus and categories are available all day again
+func (s service) DeleteDaypart(daypartID string) (*Daypart, error) {
+	daypart, err := s.repository.DeleteDaypart(daypartID)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorDaypartDeleting)
+	}
+
+	s.invalidate(daypartTags(daypart)...)
+	return daypart, nil
+}
+
+// daypartTags returns the cache tag of the store of a changed daypart
+func daypartTags(daypart *Daypart) []menucache.Tag {
+	if daypart == nil || daypart.StoreID == nil {
+		return nil
+	}
+	return []menucache.Tag{menucache.PlaceTag(string(availabilityPkg.PlaceStore), *daypart.StoreID)}
+}
+
+// FindVersions returns the menu versions of the brand, newest first
+func (s service) FindVersions(brandID string) ([]MenuVersion, error) {
+	filter := map[string]string{}
+	if brandID != "" {
+		filter["brand_id"] = brandID
+	}
+
+	versions, err := s.repository.FindVersions(filter)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorVersionFinding)
+	}
+	return versions, nil
+}
+
+// GetVersion returns a menu version with its snapshot
+func (s service) GetVersion(versionID string) (*MenuVersion, error) {
+	version, err := s.repository.GetVersion(versionID)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorVersionGetting)
+	}
+	return version, nil
+}
+
+// CreateVersion takes a draft snapshot of the current menus of the brand with their categories, products and
+// overriders, the draft can be previewed and then published
+func (s service) CreateVersion(brandID uint, notes string) (*MenuVersion, error) {
+	if brandID == 0 {
+		return nil, fmt.Errorf(ErrorVersionBrandRequired)
+	}
+
+	menus, err := s.repository.FindByPlace("brand", fmt.Sprint(brandID))
+	if err != nil {
+		return nil, fmt.Errorf(ErrorVersionCreating)
+	}
+
+	productIDs := make([]uint, 0)
+	seen := make(map[uint]bool)
+	EachProduct(menus, func(p *productPkg.Product, _ bool) {
+		if !seen[p.ID] {
+			seen[p.ID] = true
+			productIDs = append(productIDs, p.ID)
+		}
+	})
+
+	overriders, err := s.repository.FindOverriders(productIDs)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorVersionCreating)
+	}
+
+	version, err := s.repository.CreateVersion(&MenuVersion{
+		BrandID:    brandID,
+		Status:     VersionDraft,
+		Notes:      notes,
+		Menus:      menus,
+		Overriders: overriders,
+	})
+	if err != nil {
+		return nil, fmt.Errorf(ErrorVersionCreating)
+	}
+	return version, nil
+}
+
+// PublishVersion publishes a draft or scheduled version right away, or schedules it to go live at the given time
+func (s service) PublishVersion(versionID string, publishAt *time.Time) (*MenuVersion, error) {
+	version, err := s.repository.GetVersion(versionID)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorVersionGetting)
+	}
+
+	if !version.Editable() {
+		return nil, fmt.Errorf(ErrorVersionNotEditable)
+	}
+
+	now := time.Now()
+	if publishAt != nil && publishAt.After(now) {
+		version.Status = VersionScheduled
+		version.PublishAt = publishAt
+		if _, err := s.repository.ScheduleVersion(version); err != nil {
+			return nil, fmt.Errorf(ErrorVersionPublishing)
+		}
+		return version, nil
+	}
+
+	return s.publish(version, now)
+}
+
+// RollbackVersion publishes again a version superseded by a later one
+func (s service) RollbackVersion(versionID string) (*MenuVersion, error) {
+	version, err := s.repository.GetVersion(versionID)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorVersionGetting)
+	}
+
+	if version.Status != VersionSuperseded {
+		return nil, fmt.Errorf(ErrorVersionNotSuperseded)
+	}
+
+	return s.publish(version, time.Now())
+}
+
+// DeleteVersion deletes a version that hasn't been published
+func (s service) DeleteVersion(versionID string) (*MenuVersion, error) {
+	version, err := s.repository.GetVersion(versionID)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorVersionGetting)
+	}
+
+	if !version.Editable() {
+		return nil, fmt.Errorf(ErrorVersionNotEditable)
+	}
+
+	if _, err := s.repository.DeleteVersion(versionID); err != nil {
+		return nil, fmt.Errorf(ErrorVersionDeleting)
+	}
+	return version, nil
+}
+
+// PreviewVersion resolves the menus of a version at a store or channel as they would be served once published
+func (s service) PreviewVersion(versionID, place, placeID string) ([]Menu, error) {
+	version, err := s.repository.GetVersion(versionID)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorVersionGetting)
+	}
+
+	menus, err := s.availableAt(place, placeID, version.Menus)
+	if err != nil {
+		return nil, err
+	}
+
+	menus, _, err = s.resolve(place, placeID, menus)
+	return menus, err
+}
+
+// VersionPrices returns the prices of the products and modifier options of a version, or of the published version
+// of the brand when no version is given. The version is nil when the brand has never published one.
+func (s service) VersionPrices(brandID uint, versionID *uint) (*uint, map[uint]float64, error) {
+	var version *MenuVersion
+	var err error
+	if versionID != nil {
+		version, err = s.repository.GetVersion(fmt.Sprint(*versionID))
+	} else {
+		version, err = s.repository.GetPublishedVersion(fmt.Sprint(brandID))
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf(ErrorVersionPrices)
+	}
+
+	if version == nil {
+		return nil, nil, nil
+	}
+
+	return &version.ID, version.Prices(), nil
+}
+
+// RunPublisher publishes the scheduled versions when they are due, only one replica publishes at a time
+func (s service) RunPublisher(ctx context.Context) {
+	ticker := time.NewTicker(PublishInterval)
+	defer ticker.Stop()
+
+	for {
+		s.publishDue(time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s service) publishDue(now time.Time) {
+	mu := internal.DistMutexWithTTL(s.redis, publisherLockKey, PublishInterval)
+	locked, err := mu.TryLock()
+	if err != nil {
+		shared.LogError("error locking publisher", LogService, "publishDue", err)
+		return
+	}
+	if !locked {
+		return // another replica is publishing the versions
+	}
+	defer mu.Unlock()
+
+	versions, err := s.repository.FindDueVersions(now)
+	if err != nil {
+		return
+	}
+
+	for i := range versions {
+		if _, err := s.publish(&versions[i], now); err != nil {
+			shared.LogError("error publishing scheduled menu version", LogService, "publishDue", err, versions[i].ID)
+		}
+	}
+}
+
+// publish makes the version the live menu of its brand and notifies the stores of the brand
+func (s service) publish(version *MenuVersion, now time.Time) (*MenuVersion, error) {
+	if _, err := s.repository.PublishVersion(version, now); err != nil {
+		return nil, fmt.Errorf(ErrorVersionPublishing)
+	}
+
+	s.invalidate(menucache.BrandTag(version.BrandID))
+
+	if s.events == nil {
+		return version, nil
+	}
+
+	stores, err := s.store.Find(map[string]string{"brand_id": fmt.Sprint(version.BrandID)})
+	if err != nil {
+		shared.LogWarn("error finding brand stores", LogService, "publish", err, version.BrandID)
+		return version, nil
+	}
+
+	data := MenuVersion{ID: version.ID, BrandID: version.BrandID, Number: version.Number, Status: version.Status, PublishedAt: version.PublishedAt}
+	events := make([]realtime.Event, 0)
+	channels := make(map[uint]bool)
+	for _, brandStore := range stores {
+		storeID := brandStore.ID
+		events = append(events, realtime.Event{Type: realtime.EventMenuPublished, StoreID: &storeID, Data: data, Timestamp: now})
+		for _, storeChannel := range brandStore.Channels {
+			if channels[storeChannel.ID] {
+				continue
+			}
+			channels[storeChannel.ID] = true
+			channelID := storeChannel.ID
+			events = append(events, realtime.Event{Type: realtime.EventMenuPublished, ChannelID: &channelID, Data: data, Timestamp: now})
+		}
+	}
+
+	for _, event := range events {
+		if err := s.events.Publish(event); err != nil {
+			shared.LogWarn("error publishing menu version event", LogService, "publish", err, version.ID, event.Topics())
+		}
+	}
+
+	return version, nil
 }
@@ -60,7 +60,7 @@ var _ = Describe("Service Menu", func() {
 		availabilityRepository = availability.NewDBRepository(db)
 		storeRepository := store.NewDBRepository(db)
 		categoryRepository := category.NewDBRepository(db)
-		menuService = menu.NewService(menuRepository, overriderRepository, availabilityRepository, storeRepository, categoryRepository)
+		menuService = menu.NewService(menuRepository, overriderRepository, availabilityRepository, storeRepository, categoryRepository, nil, nil, nil)
 	})
 
 	AfterSuite(func() {
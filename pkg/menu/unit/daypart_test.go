@@ -0,0 +1,104 @@
+package unit_test
+
+import (
+	"time"
+
+	"github.com/BacoFoods/menu/pkg/category"
+	"github.com/BacoFoods/menu/pkg/menu"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Dayparts", func() {
+	monday := func(hour, minute int) time.Time {
+		return time.Date(2023, time.October, 2, hour, minute, 0, 0, time.UTC)
+	}
+
+	It("Validates the weekdays, times and menus", func() {
+		menuID := uint(1)
+		valid := menu.Daypart{Weekdays: "mon, Tue", StartTime: "06:00", EndTime: "11:00", Menus: []menu.DaypartMenu{{MenuID: &menuID}}}
+		Expect(valid.Validate()).To(Succeed())
+
+		weekdays := valid
+		weekdays.Weekdays = "mon,lun"
+		Expect(weekdays.Validate()).To(MatchError(menu.ErrorDaypartWeekdays))
+
+		times := valid
+		times.EndTime = "06:00"
+		Expect(times.Validate()).To(MatchError(menu.ErrorDaypartTime))
+
+		withoutMenu := valid
+		withoutMenu.Menus = []menu.DaypartMenu{{}}
+		Expect(withoutMenu.Validate()).To(MatchError(menu.ErrorDaypartWithoutMenu))
+	})
+
+	It("Is active from its start to its end on its weekdays", func() {
+		breakfast := menu.Daypart{Weekdays: "mon", StartTime: "06:00", EndTime: "11:00"}
+
+		Expect(breakfast.IsActive(monday(6, 0))).To(BeTrue())
+		Expect(breakfast.IsActive(monday(10, 59))).To(BeTrue())
+		Expect(breakfast.IsActive(monday(11, 0))).To(BeFalse())
+		Expect(breakfast.IsActive(monday(5, 59))).To(BeFalse())
+		Expect(breakfast.IsActive(monday(8, 0).AddDate(0, 0, 1))).To(BeFalse())
+	})
+
+	It("Keeps the hours past midnight on the day it started", func() {
+		lateNight := menu.Daypart{Weekdays: "sun", StartTime: "22:00", EndTime: "02:00"}
+
+		Expect(lateNight.IsActive(monday(1, 30))).To(BeTrue())
+		Expect(lateNight.IsActive(monday(2, 0))).To(BeFalse())
+		Expect(lateNight.IsActive(monday(23, 0))).To(BeFalse())
+		Expect(lateNight.IsActive(monday(23, 0).AddDate(0, 0, -1))).To(BeTrue())
+	})
+})
+
+var _ = Describe("Schedule", func() {
+	breakfastID, lunchID := uint(1), uint(2)
+	drinksID, dessertsID := uint(10), uint(11)
+	local := time.Date(2023, time.October, 2, 10, 30, 0, 0, time.UTC) // monday
+
+	menus := []menu.Menu{
+		{ID: breakfastID, Categories: []category.Category{{ID: drinksID}, {ID: dessertsID}}},
+		{ID: lunchID, Categories: []category.Category{{ID: drinksID}}},
+	}
+	dayparts := []menu.Daypart{
+		{Weekdays: "mon,tue,wed,thu,fri", StartTime: "06:00", EndTime: "11:00", Menus: []menu.DaypartMenu{{MenuID: &breakfastID}}},
+		{Weekdays: "mon,tue,wed,thu,fri", StartTime: "12:00", EndTime: "16:00", Menus: []menu.DaypartMenu{{MenuID: &lunchID}}},
+		{Weekdays: "sat,sun", StartTime: "14:00", EndTime: "18:00", Menus: []menu.DaypartMenu{{MenuID: &breakfastID, CategoryID: &dessertsID}}},
+	}
+
+	It("Resolves the menus in their dayparts", func() {
+		schedule := menu.NewSchedule(local, dayparts, nil)
+
+		Expect(schedule.MenuActive(menus[0])).To(BeTrue())
+		Expect(schedule.MenuActive(menus[1])).To(BeFalse())
+		Expect(schedule.MenuActive(menu.Menu{ID: 99})).To(BeTrue())
+	})
+
+	It("Leaves out the categories out of their dayparts", func() {
+		schedule := menu.NewSchedule(local, dayparts, nil)
+
+		breakfast, ok := schedule.Resolve(menus[0])
+
+		Expect(ok).To(BeTrue())
+		Expect(breakfast.Categories).To(HaveLen(1))
+		Expect(breakfast.Categories[0].ID).To(Equal(drinksID))
+
+		_, ok = schedule.Resolve(menus[1])
+		Expect(ok).To(BeFalse())
+	})
+
+	It("Checks the validity of the menus and their categories", func() {
+		ended := local.Add(-time.Minute)
+		starting := local.Add(time.Hour)
+		expiredMenu := menu.Menu{ID: 99, EndTime: &ended}
+		menusCategories := []menu.MenusCategories{{MenuID: &breakfastID, CategoryID: &drinksID, StartTime: &starting}}
+
+		schedule := menu.NewSchedule(local, dayparts, menusCategories)
+
+		Expect(schedule.MenuActive(expiredMenu)).To(BeFalse())
+		Expect(schedule.CategoryActive(breakfastID, drinksID)).To(BeFalse())
+		Expect(schedule.CategoryActive(lunchID, drinksID)).To(BeTrue())
+		Expect(schedule.NextChange(menus)).To(Equal(time.Date(2023, time.October, 2, 11, 0, 0, 0, time.UTC)))
+	})
+})
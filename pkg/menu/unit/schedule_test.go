@@ -0,0 +1,86 @@
+package unit_test
+
+import (
+	"time"
+
+	"github.com/BacoFoods/menu/pkg/category"
+	"github.com/BacoFoods/menu/pkg/menu"
+	"github.com/BacoFoods/menu/pkg/menucache"
+	"github.com/BacoFoods/menu/pkg/product"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Schedule next change", func() {
+	menuID, categoryID := uint(1), uint(2)
+	local := time.Date(2023, time.October, 2, 10, 30, 0, 0, time.UTC) // monday
+	menus := []menu.Menu{{ID: menuID, Categories: []category.Category{{ID: categoryID}}}}
+
+	It("Is zero without dayparts or windows", func() {
+		schedule := menu.NewSchedule(local, nil, nil)
+		Expect(schedule.NextChange(menus).IsZero()).To(BeTrue())
+	})
+
+	It("Is the closest daypart bound", func() {
+		dayparts := []menu.Daypart{{
+			Weekdays:  "mon",
+			StartTime: "06:00",
+			EndTime:   "11:00",
+			Menus:     []menu.DaypartMenu{{MenuID: &menuID}},
+		}}
+
+		schedule := menu.NewSchedule(local, dayparts, nil)
+		Expect(schedule.NextChange(menus)).To(Equal(time.Date(2023, time.October, 2, 11, 0, 0, 0, time.UTC)))
+	})
+
+	It("Rolls a passed daypart bound to the next day", func() {
+		dayparts := []menu.Daypart{{
+			Weekdays:  "mon,tue",
+			StartTime: "06:00",
+			EndTime:   "09:00",
+			Menus:     []menu.DaypartMenu{{MenuID: &menuID, CategoryID: &categoryID}},
+		}}
+
+		schedule := menu.NewSchedule(local, dayparts, nil)
+		Expect(schedule.NextChange(menus)).To(Equal(time.Date(2023, time.October, 3, 6, 0, 0, 0, time.UTC)))
+	})
+
+	It("Takes the menu and category windows ahead", func() {
+		menuEnd := local.Add(3 * time.Hour)
+		categoryStart := local.Add(time.Hour)
+		windowMenus := []menu.Menu{{ID: menuID, EndTime: &menuEnd, Categories: menus[0].Categories}}
+		menusCategories := []menu.MenusCategories{{MenuID: &menuID, CategoryID: &categoryID, StartTime: &categoryStart}}
+
+		schedule := menu.NewSchedule(local, nil, menusCategories)
+		Expect(schedule.NextChange(windowMenus)).To(Equal(categoryStart))
+	})
+})
+
+var _ = Describe("Cache tags", func() {
+	It("Tags the menus, categories, products, options, components and modifier groups", func() {
+		optionID, componentID := uint(12), uint(13)
+		menus := []menu.Menu{{
+			ID: 1,
+			Categories: []category.Category{{
+				ID: 2,
+				Products: []product.Product{{
+					ID:         10,
+					Components: []product.ComboComponent{{ProductID: &componentID}},
+					Modifiers: []product.Modifier{{
+						ID:      20,
+						Options: []product.ModifierProduct{{ProductID: optionID, Product: &product.Product{ID: optionID}}},
+					}},
+				}},
+			}},
+		}}
+
+		Expect(menu.CacheTags(menus)).To(ConsistOf(
+			menucache.MenuTag(1),
+			menucache.CategoryTag(2),
+			menucache.ProductTag(10),
+			menucache.ModifierTag(20),
+			menucache.ProductTag(12),
+			menucache.ProductTag(13),
+		))
+	})
+})
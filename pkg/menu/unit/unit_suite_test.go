@@ -0,0 +1,15 @@
+// Package unit_test has the menu specs that don't need the database, the menu suite migrates a database
+// before running its specs.
+package unit_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestMenuUnit(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Menu Unit Suite")
+}
@@ -0,0 +1,31 @@
+package unit_test
+
+import (
+	"github.com/BacoFoods/menu/pkg/category"
+	"github.com/BacoFoods/menu/pkg/menu"
+	"github.com/BacoFoods/menu/pkg/product"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Menu versions", func() {
+	It("Prices the products and the nested modifier options of every menu", func() {
+		sauce := product.Product{ID: 40, Price: 500}
+		fries := product.Product{ID: 30, Price: 7000, Modifiers: []product.Modifier{
+			{Options: []product.ModifierProduct{{Product: &sauce}}},
+		}}
+		burger := product.Product{ID: 10, Price: 20000, Modifiers: []product.Modifier{
+			{Options: []product.ModifierProduct{{Product: &fries}, {ProductID: 99}}},
+		}}
+		version := menu.MenuVersion{Menus: []menu.Menu{
+			{Categories: []category.Category{{Products: []product.Product{burger}}}},
+			{Categories: []category.Category{{Products: []product.Product{{ID: 11, Price: 8000}}}}},
+		}}
+
+		Expect(version.Prices()).To(Equal(map[uint]float64{10: 20000, 30: 7000, 40: 500, 11: 8000}))
+	})
+
+	It("Prices nothing without menus", func() {
+		Expect(menu.MenuVersion{}.Prices()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,78 @@
+package menucache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Tag names a record a cached menu was built from, invalidating a tag drops every cached menu that used it.
+type Tag string
+
+func ProductTag(productID uint) Tag {
+	return Tag(fmt.Sprintf("product:%d", productID))
+}
+
+func ModifierTag(modifierID uint) Tag {
+	return Tag(fmt.Sprintf("modifier:%d", modifierID))
+}
+
+func CategoryTag(categoryID uint) Tag {
+	return Tag(fmt.Sprintf("category:%d", categoryID))
+}
+
+func MenuTag(menuID uint) Tag {
+	return Tag(fmt.Sprintf("menu:%d", menuID))
+}
+
+func BrandTag(brandID uint) Tag {
+	return Tag(fmt.Sprintf("brand:%d", brandID))
+}
+
+// PlaceTag covers the menus of a store or channel, place is the availability place name.
+func PlaceTag(place string, placeID uint) Tag {
+	return Tag(fmt.Sprintf("place:%s:%d", place, placeID))
+}
+
+// Entry is a cached menu response body with its entity tag.
+type Entry struct {
+	ETag string
+	Body []byte
+}
+
+func NewEntry(body []byte) Entry {
+	sum := sha256.Sum256(body)
+	return Entry{ETag: `"` + hex.EncodeToString(sum[:16]) + `"`, Body: body}
+}
+
+// Matches reports whether an If-None-Match header value matches the entry, weak and listed tags included.
+func (e Entry) Matches(ifNoneMatch string) bool {
+	if e.ETag == "" || ifNoneMatch == "" {
+		return false
+	}
+
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == e.ETag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Invalidator drops the cached menus built from any of the tags.
+type Invalidator interface {
+	Invalidate(tags ...Tag)
+}
+
+type Cache interface {
+	Invalidator
+	Get(place, placeID string) (*Entry, bool)
+	// Epoch returns the invalidation counter, Set discards entries computed before a later invalidation.
+	Epoch() string
+	Set(place, placeID, epoch string, entry Entry, tags []Tag, ttl time.Duration)
+	TTL() time.Duration
+}
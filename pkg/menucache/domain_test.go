@@ -0,0 +1,41 @@
+package menucache_test
+
+import (
+	"github.com/BacoFoods/menu/pkg/menucache"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Entry", func() {
+	entry := menucache.NewEntry([]byte(`{"status":"success","data":[]}`))
+
+	It("Tags the body with a quoted hash", func() {
+		Expect(entry.ETag).To(HavePrefix(`"`))
+		Expect(entry.ETag).To(HaveSuffix(`"`))
+		Expect(menucache.NewEntry([]byte(`{"status":"success","data":[]}`)).ETag).To(Equal(entry.ETag))
+		Expect(menucache.NewEntry([]byte(`{"status":"success","data":[{}]}`)).ETag).NotTo(Equal(entry.ETag))
+	})
+
+	It("Matches the same, weak, listed and any tag", func() {
+		Expect(entry.Matches(entry.ETag)).To(BeTrue())
+		Expect(entry.Matches("W/" + entry.ETag)).To(BeTrue())
+		Expect(entry.Matches(`"other", ` + entry.ETag)).To(BeTrue())
+		Expect(entry.Matches("*")).To(BeTrue())
+	})
+
+	It("Doesn't match a missing or different tag", func() {
+		Expect(entry.Matches("")).To(BeFalse())
+		Expect(entry.Matches(`"other"`)).To(BeFalse())
+	})
+})
+
+var _ = Describe("RedisCache", func() {
+	It("Is a miss and a no-op without a client", func() {
+		cache := menucache.NewRedisCache(nil, 0)
+		_, ok := cache.Get("store", "1")
+		Expect(ok).To(BeFalse())
+		Expect(cache.TTL()).To(BeZero())
+		cache.Set("store", "1", "0", menucache.NewEntry([]byte("{}")), []menucache.Tag{menucache.ProductTag(1)}, 0)
+		cache.Invalidate(menucache.PlaceTag("store", 1))
+	})
+})
@@ -0,0 +1,13 @@
+package menucache_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestMenucache(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Menucache Suite")
+}
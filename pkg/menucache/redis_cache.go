@@ -0,0 +1,169 @@
+package menucache
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/BacoFoods/menu/pkg/shared"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	LogRedisCache = "pkg/menucache/redis_cache"
+
+	keyPrefix    = "menu:cache:"
+	epochKey     = keyPrefix + "epoch"
+	cacheTimeout = 1 * time.Second
+)
+
+// setScript stores the entry only when no invalidation happened since the epoch was read, and indexes its key
+// under every tag. A tag only outlives its entries when its TTL is extended, never shortened, so a short lived
+// entry can't expire the index of the longer ones. KEYS: epoch, entry, tags... ARGV: epoch, value, ttl in ms.
+var setScript = redis.NewScript(`
+local epoch = redis.call('GET', KEYS[1]) or '0'
+if epoch ~= ARGV[1] then
+	return 0
+end
+local ttl = tonumber(ARGV[3])
+redis.call('SET', KEYS[2], ARGV[2], 'PX', ttl)
+for i = 3, #KEYS do
+	redis.call('SADD', KEYS[i], KEYS[2])
+	if redis.call('PTTL', KEYS[i]) < ttl then
+		redis.call('PEXPIRE', KEYS[i], ttl)
+	end
+end
+return 1
+`)
+
+// invalidateScript bumps the epoch and deletes the entries indexed under the tags. KEYS: epoch, tags...
+var invalidateScript = redis.NewScript(`
+redis.call('INCR', KEYS[1])
+for i = 2, #KEYS do
+	local entries = redis.call('SMEMBERS', KEYS[i])
+	for _, entry in ipairs(entries) do
+		redis.call('DEL', entry)
+	end
+	redis.call('DEL', KEYS[i])
+end
+return 1
+`)
+
+// RedisCache keeps the resolved place menus in redis so every replica serves and drops the same entries.
+type RedisCache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func NewRedisCache(client *redis.Client, ttl time.Duration) *RedisCache {
+	return &RedisCache{client: client, ttl: ttl}
+}
+
+func placeKey(place, placeID string) string {
+	return keyPrefix + "place:" + place + ":" + placeID
+}
+
+func tagKey(tag Tag) string {
+	return keyPrefix + "tag:" + string(tag)
+}
+
+func (c *RedisCache) enabled() bool {
+	return c != nil && c.client != nil && c.ttl > 0
+}
+
+func (c *RedisCache) TTL() time.Duration {
+	if !c.enabled() {
+		return 0
+	}
+	return c.ttl
+}
+
+// Get returns the cached entry of the place, errors are logged and reported as a miss.
+func (c *RedisCache) Get(place, placeID string) (*Entry, bool) {
+	if !c.enabled() {
+		return nil, false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cacheTimeout)
+	defer cancel()
+
+	value, err := c.client.Get(ctx, placeKey(place, placeID)).Bytes()
+	if err == redis.Nil {
+		return nil, false
+	}
+	if err != nil {
+		shared.LogWarn("error getting cached menu", LogRedisCache, "Get", err, place, placeID)
+		return nil, false
+	}
+
+	// The value is the etag and the body separated by a line break, etags never contain one
+	etag, body, found := bytes.Cut(value, []byte("\n"))
+	if !found {
+		return nil, false
+	}
+
+	return &Entry{ETag: string(etag), Body: body}, true
+}
+
+func (c *RedisCache) Epoch() string {
+	if !c.enabled() {
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cacheTimeout)
+	defer cancel()
+
+	epoch, err := c.client.Get(ctx, epochKey).Result()
+	if err == redis.Nil {
+		return "0"
+	}
+	if err != nil {
+		shared.LogWarn("error getting cache epoch", LogRedisCache, "Epoch", err)
+		return ""
+	}
+
+	return epoch
+}
+
+// Set stores the entry for ttl, it is skipped when the epoch could not be read or moved since.
+func (c *RedisCache) Set(place, placeID, epoch string, entry Entry, tags []Tag, ttl time.Duration) {
+	if !c.enabled() || epoch == "" || ttl <= 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(tags)+2)
+	keys = append(keys, epochKey, placeKey(place, placeID))
+	for _, tag := range tags {
+		keys = append(keys, tagKey(tag))
+	}
+
+	value := append([]byte(entry.ETag+"\n"), entry.Body...)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cacheTimeout)
+	defer cancel()
+
+	if err := setScript.Run(ctx, c.client, keys, epoch, value, ttl.Milliseconds()).Err(); err != nil {
+		shared.LogWarn("error caching menu", LogRedisCache, "Set", err, place, placeID)
+	}
+}
+
+// Invalidate drops the entries built from the tags, it runs even with the cache disabled so no stale entry
+// outlives a configuration change.
+func (c *RedisCache) Invalidate(tags ...Tag) {
+	if c == nil || c.client == nil || len(tags) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(tags)+1)
+	keys = append(keys, epochKey)
+	for _, tag := range tags {
+		keys = append(keys, tagKey(tag))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cacheTimeout)
+	defer cancel()
+
+	if err := invalidateScript.Run(ctx, c.client, keys).Err(); err != nil {
+		shared.LogError("error invalidating cached menus", LogRedisCache, "Invalidate", err, tags)
+	}
+}
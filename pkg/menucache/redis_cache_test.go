@@ -0,0 +1,81 @@
+package menucache_test
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/BacoFoods/menu/internal"
+	"github.com/BacoFoods/menu/pkg/menucache"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Redis cache", func() {
+	const place = "store"
+	var cache *menucache.RedisCache
+	var placeID string
+	var tag menucache.Tag
+
+	get := func(placeID string) *menucache.Entry {
+		entry, _ := cache.Get(place, placeID)
+		return entry
+	}
+
+	BeforeEach(func() {
+		cache = nil
+		host, port := os.Getenv("REDIS_HOST"), os.Getenv("REDIS_PORT")
+		if host == "" {
+			host = "localhost"
+		}
+		if port == "" {
+			port = "6379"
+		}
+
+		client, err := internal.NewRedis(host, port)
+		if err != nil {
+			Skip(fmt.Sprintf("redis is not available on %s:%s", host, port))
+		}
+
+		cache = menucache.NewRedisCache(client, time.Minute)
+		id := uint(time.Now().UnixNano())
+		placeID, tag = fmt.Sprint(id), menucache.ProductTag(id)
+	})
+
+	AfterEach(func() {
+		if cache != nil {
+			cache.Invalidate(tag)
+		}
+	})
+
+	It("Serves the entry until one of its tags is invalidated", func() {
+		entry := menucache.NewEntry([]byte(`{"status":"success","data":[]}`))
+		cache.Set(place, placeID, cache.Epoch(), entry, []menucache.Tag{tag}, time.Minute)
+
+		Expect(get(placeID)).To(Equal(&entry))
+
+		cache.Invalidate(tag)
+
+		Expect(get(placeID)).To(BeNil())
+	})
+
+	It("Skips the entries built before an invalidation", func() {
+		epoch := cache.Epoch()
+		cache.Invalidate(tag)
+
+		cache.Set(place, placeID, epoch, menucache.NewEntry([]byte(`{}`)), []menucache.Tag{tag}, time.Minute)
+
+		Expect(get(placeID)).To(BeNil())
+	})
+
+	It("Keeps the tag of a long lived entry when a short lived one shares it", func() {
+		entry := menucache.NewEntry([]byte(`{"status":"success","data":[]}`))
+		cache.Set(place, placeID, cache.Epoch(), entry, []menucache.Tag{tag}, time.Minute)
+		cache.Set(place, placeID+"-short", cache.Epoch(), entry, []menucache.Tag{tag}, 50*time.Millisecond)
+		time.Sleep(200 * time.Millisecond)
+
+		cache.Invalidate(tag)
+
+		Expect(get(placeID)).To(BeNil())
+	})
+})
@@ -0,0 +1,89 @@
+package order_test
+
+import (
+	"github.com/BacoFoods/menu/pkg/invoice"
+	"github.com/BacoFoods/menu/pkg/order"
+	"github.com/BacoFoods/menu/pkg/product"
+	"github.com/BacoFoods/menu/pkg/taxes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Combo item", func() {
+	id := func(value uint) *uint { return &value }
+
+	burger := product.Product{ID: 10, Name: "Baco", SKU: "1000", Price: 20000, Tax: &taxes.Tax{Name: "ico", Percentage: 0.08}}
+	combo := product.Product{ID: 1, Name: "Combo Baco", Combo: true, Components: []product.ComboComponent{
+		{ID: 1, ProductID: id(burger.ID), Product: &burger, Quantity: 1},
+		{ID: 2, ModifierID: id(5), Quantity: 1},
+	}}
+	options := map[string]product.Product{
+		"30": {ID: 30, Name: "Cola", SKU: "1001", Price: 6000},
+		"31": {ID: 31, Name: "Hielo", Price: 0},
+	}
+
+	var item *order.OrderItem
+
+	BeforeEach(func() {
+		item = &order.OrderItem{
+			ProductID: id(combo.ID),
+			Name:      combo.Name,
+			Price:     25000,
+			Modifiers: []order.OrderModifier{
+				{ProductID: id(30), ModifierID: id(5), Price: 2000},
+				{ProductID: id(31), ModifierID: id(5), ParentProductID: id(30)},
+			},
+		}
+	})
+
+	Context("ExpandCombo", func() {
+		It("Allocates the item price across the fixed components and the chosen options", func() {
+			item.ExpandCombo(combo, options)
+
+			Expect(item.Components).To(HaveLen(2))
+			Expect(*item.Components[0].ProductID).To(Equal(burger.ID))
+			Expect(item.Components[0].ModifierID).To(BeNil())
+			Expect(item.Components[0].ListPrice).To(Equal(20000.0))
+			Expect(item.Components[0].Price).To(Equal(19231.0))
+			Expect(item.Components[0].Tax).To(Equal("ico"))
+
+			Expect(*item.Components[1].ProductID).To(Equal(uint(30)))
+			Expect(*item.Components[1].ModifierID).To(Equal(uint(5)))
+			Expect(item.Components[1].Upcharge).To(Equal(2000.0))
+			Expect(item.Components[1].Price).To(Equal(7769.0))
+		})
+
+		It("Leaves the products that are not combos without components", func() {
+			item.Components = []order.OrderItemComponent{{Name: "stale"}}
+
+			item.ExpandCombo(burger, options)
+
+			Expect(item.Components).To(BeNil())
+		})
+	})
+
+	Context("ComboInvoiceItems", func() {
+		It("Invoices every component with its tax and discount and sums them on the item", func() {
+			item.ExpandCombo(combo, options)
+
+			items := item.ComboInvoiceItems([]invoice.DiscountApplied{{Name: "Empleado", Percentage: 10}})
+
+			Expect(items).To(HaveLen(2))
+			Expect(*items[0].ComboID).To(Equal(combo.ID))
+			Expect(items[0].ComboName).To(Equal("Combo Baco"))
+			Expect(items[0].SKU).To(Equal("1000"))
+			Expect(items[0].TaxBase).To(Equal(17807.0))
+			Expect(items[0].TaxAmount).To(Equal(1424.0))
+			Expect(items[0].DiscountAmount).To(Equal(1923.0))
+			Expect(items[0].DiscountedPrice).To(Equal(17308.0))
+
+			Expect(items[1].Tax).To(Equal("ico"))
+			Expect(items[1].TaxPercentage).To(Equal(order.TaxPercentage))
+			Expect(items[1].Price).To(Equal(7769.0))
+
+			Expect(item.Discount).To(Equal(items[0].DiscountAmount + items[1].DiscountAmount))
+			Expect(item.DiscountedPrice).To(Equal(items[0].DiscountedPrice + items[1].DiscountedPrice))
+			Expect(item.TaxBase + item.TaxAmount).To(Equal(27000.0))
+		})
+	})
+})
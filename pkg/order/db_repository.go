@@ -58,6 +58,7 @@ func (r *DBRepository) Get(orderID string) (*Order, error) {
 		Preload(clause.Associations).
 		Preload("Invoices.Documents").
 		Preload("Items.Modifiers").
+		Preload("Items.Components").
 		Preload("Table.Zone").
 		First(&order, orderID).Error; err != nil {
 		shared.LogError("error getting order", LogDBRepository, "Get", err, orderID)
@@ -102,12 +103,14 @@ func (r *DBRepository) Find(filter map[string]any) ([]Order, error) {
 	tx := r.db.
 		Preload(clause.Associations).
 		Preload("Items.Modifiers").
+		Preload("Items.Components").
 		Preload("Table.Zone").
 		Preload("Invoices.Payments")
 
 	if days, ok := filter["days"]; ok {
 		tx.Preload(clause.Associations).
 			Preload("Items.Modifiers").
+			Preload("Items.Components").
 			Where(fmt.Sprintf("created_at >= NOW() - INTERVAL '%s' DAY", days))
 		delete(filter, "days")
 	}
@@ -121,6 +124,7 @@ func (r *DBRepository) Find(filter map[string]any) ([]Order, error) {
 	err := tx.
 		Preload(clause.Associations).
 		Preload("Items.Modifiers").
+		Preload("Items.Components").
 		Where(filter).
 		Order("created_at DESC").
 		Find(&orders).Error
@@ -162,7 +166,9 @@ func (r *DBRepository) GetOrderItem(orderItemID string) (*OrderItem, error) {
 // FindByShift method for find orders by shift in database
 func (r *DBRepository) FindByShift(shiftID uint) ([]Order, error) {
 	var orders []Order
-	if err := r.db.Preload(clause.Associations).Find(&orders, "shift_id = ?", shiftID).Error; err != nil {
+	if err := r.db.Preload(clause.Associations).
+		Preload("Invoices.Payments.PaymentMethod").
+		Find(&orders, "shift_id = ?", shiftID).Error; err != nil {
 		shared.LogError("error finding orders", LogDBRepository, "FindByShift", err, shiftID)
 		return nil, err
 	}
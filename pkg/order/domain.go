@@ -41,6 +41,12 @@ const (
 	ErrorOrderProductNotFound              = "error order product with id %v not found; "
 	ErrorOrderProductsNotFound             = "error order products not found"
 	ErrorOrderModifierNotFound             = "error order modifier with id %v not found; "
+	ErrorOrderProductNotSellable           = "error order product with id %v is not sellable at this time; "
+	ErrorOrderProductSellableChecking      = "error checking order products sellable at this time"
+	ErrorOrderProductStockedOut            = "error order product with id %v is out of stock; "
+	ErrorOrderModifierStockedOut           = "error order modifier with id %v is out of stock; "
+	ErrorOrderStockChecking                = "error checking order products stock"
+	ErrorOrderMenuVersionPricing           = "error pricing order with the menu version"
 	ErrorOrderUpdatingComments             = "error updating order comments"
 	ErrorOrderUpdatingClientName           = "error updating order client name"
 	ErrorOrderUpdatingStatus               = "error updating order status"
@@ -51,6 +57,8 @@ const (
 	ErrorOrderInvoiceCalculation           = "error calculating invoice"
 	ErrorOrderClosed                       = "error order is closed"
 	ErrorOrderIDEmpty                      = "order id is empty"
+	ErrorOrderTableSessionRequired         = "error table session is required"
+	ErrorOrderTableSessionForbidden        = "error order does not belong to the table session"
 
 	ErrorOrderItemUpdate       = "error updating order item"
 	ErrorOrderItemGetting      = "error getting order item"
@@ -78,9 +86,10 @@ const (
 
 	LogDomain = "pkg/order/domain"
 
-	OrderStatusCreated = "created"
-	OrderStatusPaying  = "paying"
-	OrderStatusClosed  = "closed"
+	OrderStatusCreated  = "created"
+	OrderStatusPaying   = "paying"
+	OrderStatusClosed   = "closed"
+	OrderStatusCanceled = "canceled"
 )
 
 func applyDiscount(value float64, discounts []invoice.DiscountApplied) (newValue float64, appliedDiscount float64) {
@@ -98,7 +107,7 @@ func applyDiscount(value float64, discounts []invoice.DiscountApplied) (newValue
 
 func OrderStatusValid(status string) bool {
 	switch status {
-	case OrderStatusCreated, OrderStatusPaying, OrderStatusClosed:
+	case OrderStatusCreated, OrderStatusPaying, OrderStatusClosed, OrderStatusCanceled:
 		return true
 	default:
 		return false
@@ -163,6 +172,7 @@ type Order struct {
 	Attendees      []Attendee        `json:"attendees" gorm:"foreignKey:OrderID"`
 	ShiftID        *uint             `json:"shift_id"`
 	IdempotencyKey *string           `json:"idempotency_key"`
+	MenuVersionID  *uint             `json:"menu_version_id" swaggerignore:"true"` // published menu version the order was priced against
 	ClosedAt       *time.Time        `json:"closed_at" swaggerignore:"true"`
 	CreatedAt      *time.Time        `json:"created_at,omitempty" swaggerignore:"true"`
 	UpdatedAt      *time.Time        `json:"updated_at,omitempty" swaggerignore:"true"`
@@ -225,6 +235,9 @@ func (o *Order) SetItems(products []product.Product, modifiers []product.Product
 					modifier.Image = m.Image
 					modifier.SKU = m.SKU
 					modifier.Price = m.Price
+					if modifier.Free {
+						modifier.Price = 0
+					}
 					modifier.Unit = m.Unit
 					modifier.ProductID = &m.ID
 					modifier.OrderID = o.ID
@@ -232,23 +245,76 @@ func (o *Order) SetItems(products []product.Product, modifiers []product.Product
 				}
 			}
 			item.Modifiers = modifierList
+			item.ExpandCombo(p, modifiersMap)
 			items = append(items, item)
 		}
 	}
 	o.Items = items
 }
 
+// ResolveModifiers validates the modifiers of the items against the modifier groups of their products and adds
+// the default options of the groups without choices
+func (o *Order) ResolveModifiers(products []product.Product) error {
+	productsMap := make(map[uint]product.Product)
+	for _, p := range products {
+		productsMap[p.ID] = p
+	}
+
+	errs := ""
+	for i := range o.Items {
+		if o.Items[i].ProductID == nil {
+			continue
+		}
+
+		p, ok := productsMap[*o.Items[i].ProductID]
+		if !ok {
+			continue
+		}
+
+		if err := o.Items[i].ResolveModifiers(p); err != nil {
+			errs += err.Error()
+		}
+	}
+
+	if errs != "" {
+		return fmt.Errorf(errs)
+	}
+
+	return nil
+}
+
+// ApplyPrices sets the prices of the products on the price list, the combo components included, the products
+// out of the list keep their catalog price
+func ApplyPrices(prices map[uint]float64, productList []product.Product) {
+	for i := range productList {
+		if price, ok := prices[productList[i].ID]; ok {
+			productList[i].Price = price
+		}
+		for j := range productList[i].Components {
+			component := productList[i].Components[j].Product
+			if component == nil {
+				continue
+			}
+			if price, ok := prices[component.ID]; ok {
+				component.Price = price
+			}
+		}
+	}
+}
+
 func (o *Order) AddProduct(orderItem OrderItem) {
 	o.Items = append(o.Items, orderItem)
 }
 
-func (o *Order) RemoveProduct(product *product.Product) {
+// RemoveProduct removes the first item of the product and returns it, nil when the order doesn't have it
+func (o *Order) RemoveProduct(product *product.Product) *OrderItem {
 	for i, item := range o.Items {
-		if *item.ProductID == product.ID {
+		if item.ProductID != nil && *item.ProductID == product.ID {
 			o.Items = append(o.Items[:i], o.Items[i+1:]...)
-			return
+			return &item
 		}
 	}
+	return nil
 }
 
 // ToInvoice uses next definitions:
@@ -299,60 +365,75 @@ func (o *Order) ToInvoice(tip *TipData, discounts ...discountPKG.Discount) {
 	orderItems := make([]OrderItem, 0)
 	for _, orderItem := range o.Items {
 
-		// Default tax values
-		if orderItem.Tax == "" {
-			orderItem.Tax = "ico" // Default tax type
-		}
+		// Combos are invoiced by their components
+		if len(orderItem.Components) > 0 {
+			for _, item := range orderItem.ComboInvoiceItems(newInvoice.Discounts) {
+				newInvoice.BaseTax += item.TaxBase
+				newInvoice.Taxes += item.TaxAmount
+				newInvoice.TotalDiscounts += item.DiscountAmount
+				subtotal += item.Price
+				newInvoice.Items = append(newInvoice.Items, item)
+			}
+		} else {
+			// Default tax values
+			if orderItem.Tax == "" {
+				orderItem.Tax = "ico" // Default tax type
+			}
 
-		if orderItem.TaxPercentage == 0 {
-			orderItem.TaxPercentage = 0.08 // Default tax percentage
-		}
+			if orderItem.TaxPercentage == 0 {
+				orderItem.TaxPercentage = 0.08 // Default tax percentage
+			}
 
-		if orderItem.TaxBase == 0 {
-			orderItem.TaxBase = math.Ceil(orderItem.Price / (1 + orderItem.TaxPercentage)) // Default tax base
-		}
+			if orderItem.TaxBase == 0 {
+				orderItem.TaxBase = math.Ceil(orderItem.Price / (1 + orderItem.TaxPercentage)) // Default tax base
+			}
 
-		if orderItem.TaxAmount == 0 {
-			orderItem.TaxAmount = orderItem.Price - orderItem.TaxBase // Default tax amount
-		}
+			if orderItem.TaxAmount == 0 {
+				orderItem.TaxAmount = orderItem.Price - orderItem.TaxBase // Default tax amount
+			}
 
-		// Discounts
-		orderItem.DiscountedPrice = orderItem.Price
-		orderItem.Discount = 0
+			// Discounts
+			orderItem.DiscountedPrice = orderItem.Price
+			orderItem.Discount = 0
 
-		for _, discount := range newInvoice.Discounts {
-			orderItem.DiscountedPrice -= discount.CalculateAmountRounded(orderItem.Price)
-			orderItem.DiscountPercent += discount.Percentage
-			orderItem.Discount += discount.CalculateAmountRounded(orderItem.Price)
-			orderItem.DiscountReason += fmt.Sprintf("%s - %s - %.2f - %.2f - applied to: %.2f;", discount.Name, discount.Description, discount.Percentage, discount.CalculateAmountRounded(orderItem.Price), orderItem.Price)
-		}
+			for _, discount := range newInvoice.Discounts {
+				orderItem.DiscountedPrice -= discount.CalculateAmountRounded(orderItem.Price)
+				orderItem.DiscountPercent += discount.Percentage
+				orderItem.Discount += discount.CalculateAmountRounded(orderItem.Price)
+				orderItem.DiscountReason += fmt.Sprintf("%s - %s - %.2f - %.2f - applied to: %.2f;", discount.Name, discount.Description, discount.Percentage, discount.CalculateAmountRounded(orderItem.Price), orderItem.Price)
+			}
 
-		newInvoice.BaseTax += orderItem.TaxBase
-		newInvoice.Taxes += orderItem.TaxAmount
-		newInvoice.TotalDiscounts += orderItem.Discount
+			newInvoice.BaseTax += orderItem.TaxBase
+			newInvoice.Taxes += orderItem.TaxAmount
+			newInvoice.TotalDiscounts += orderItem.Discount
 
-		newInvoice.Items = append(newInvoice.Items, invoice.Item{
-			ProductID:          orderItem.ProductID,
-			Name:               orderItem.Name,
-			Description:        orderItem.Description,
-			SKU:                orderItem.SKU,
-			Price:              orderItem.Price,
-			Comments:           orderItem.Comments,
-			Hash:               orderItem.Hash,
-			DiscountedPrice:    orderItem.DiscountedPrice,
-			DiscountPercentage: orderItem.DiscountPercent,
-			DiscountReason:     orderItem.DiscountReason,
-			DiscountAmount:     orderItem.Discount,
-			Tax:                orderItem.Tax,
-			TaxPercentage:      orderItem.TaxPercentage,
-			TaxAmount:          orderItem.TaxAmount,
-			TaxBase:            orderItem.TaxBase,
-		})
+			newInvoice.Items = append(newInvoice.Items, invoice.Item{
+				ProductID:          orderItem.ProductID,
+				Name:               orderItem.Name,
+				Description:        orderItem.Description,
+				SKU:                orderItem.SKU,
+				Price:              orderItem.Price,
+				Comments:           orderItem.Comments,
+				Hash:               orderItem.Hash,
+				DiscountedPrice:    orderItem.DiscountedPrice,
+				DiscountPercentage: orderItem.DiscountPercent,
+				DiscountReason:     orderItem.DiscountReason,
+				DiscountAmount:     orderItem.Discount,
+				Tax:                orderItem.Tax,
+				TaxPercentage:      orderItem.TaxPercentage,
+				TaxAmount:          orderItem.TaxAmount,
+				TaxBase:            orderItem.TaxBase,
+			})
 
-		// Adding orderItem price to subtotal
-		subtotal += orderItem.Price
+			// Adding orderItem price to subtotal
+			subtotal += orderItem.Price
+		}
 
 		for _, modifier := range orderItem.Modifiers {
+			// the option chosen for a combo component is invoiced on the component line
+			if orderItem.isComponent(modifier) {
+				continue
+			}
 
 			// Discounts
 			modifier.DiscountedPrice = orderItem.Price
@@ -478,32 +559,33 @@ func (o *Order) UpdatePrevStatus() {
 }
 
 type OrderItem struct {
-	ID              uint            `json:"id" gorm:"primaryKey"`
-	OrderID         *uint           `json:"order_id"`
-	ProductID       *uint           `json:"product_id"`
-	Name            string          `json:"name"`
-	Description     string          `json:"description"`
-	Image           string          `json:"image"`
-	SKU             string          `json:"sku"`
-	Price           float64         `json:"price" gorm:"precision:18;scale:2"`
-	Unit            string          `json:"unit"`
-	Discount        float64         `json:"discount" gorm:"precision:18;scale:2"`
-	DiscountedPrice float64         `json:"discounted_price" gorm:"precision:18;scale:2"` // DiscountPrice is the tax base after applying discount
-	DiscountPercent float64         `json:"discount_percent" gorm:"precision:18;scale:2"`
-	DiscountReason  string          `json:"discount_reason,omitempty"`
-	Surcharge       float64         `json:"surcharge" gorm:"precision:18;scale:2"`
-	SurchargeReason string          `json:"surcharge_reason,omitempty"`
-	Comments        string          `json:"comments"`
-	Course          string          `json:"course"`
-	Hash            string          `json:"hash"`
-	Modifiers       []OrderModifier `json:"modifiers"  gorm:"foreignKey:OrderItemID"`
-	Tax             string          `json:"tax"`
-	TaxPercentage   float64         `json:"tax_percentage"`
-	TaxBase         float64         `json:"tax_base" gorm:"precision:18;scale:2"`
-	TaxAmount       float64         `json:"tax_amount" gorm:"precision:18;scale:2"`
-	CreatedAt       *time.Time      `json:"created_at,omitempty" swaggerignore:"true"`
-	UpdatedAt       *time.Time      `json:"updated_at,omitempty" swaggerignore:"true"`
-	DeletedAt       *gorm.DeletedAt `json:"deleted_at,omitempty" swaggerignore:"true"`
+	ID              uint                 `json:"id" gorm:"primaryKey"`
+	OrderID         *uint                `json:"order_id"`
+	ProductID       *uint                `json:"product_id"`
+	Name            string               `json:"name"`
+	Description     string               `json:"description"`
+	Image           string               `json:"image"`
+	SKU             string               `json:"sku"`
+	Price           float64              `json:"price" gorm:"precision:18;scale:2"`
+	Unit            string               `json:"unit"`
+	Discount        float64              `json:"discount" gorm:"precision:18;scale:2"`
+	DiscountedPrice float64              `json:"discounted_price" gorm:"precision:18;scale:2"` // DiscountPrice is the tax base after applying discount
+	DiscountPercent float64              `json:"discount_percent" gorm:"precision:18;scale:2"`
+	DiscountReason  string               `json:"discount_reason,omitempty"`
+	Surcharge       float64              `json:"surcharge" gorm:"precision:18;scale:2"`
+	SurchargeReason string               `json:"surcharge_reason,omitempty"`
+	Comments        string               `json:"comments"`
+	Course          string               `json:"course"`
+	Hash            string               `json:"hash"`
+	Modifiers       []OrderModifier      `json:"modifiers"  gorm:"foreignKey:OrderItemID"`
+	Components      []OrderItemComponent `json:"components,omitempty" gorm:"foreignKey:OrderItemID"` // products of a combo item
+	Tax             string               `json:"tax"`
+	TaxPercentage   float64              `json:"tax_percentage"`
+	TaxBase         float64              `json:"tax_base" gorm:"precision:18;scale:2"`
+	TaxAmount       float64              `json:"tax_amount" gorm:"precision:18;scale:2"`
+	CreatedAt       *time.Time           `json:"created_at,omitempty" swaggerignore:"true"`
+	UpdatedAt       *time.Time           `json:"updated_at,omitempty" swaggerignore:"true"`
+	DeletedAt       *gorm.DeletedAt      `json:"deleted_at,omitempty" swaggerignore:"true"`
 }
 
 func (oi *OrderItem) SetHash() {
@@ -514,6 +596,183 @@ func (oi *OrderItem) SetHash() {
 	oi.Hash = fmt.Sprintf("%x", orderItemString)
 }
 
+// ResolveModifiers validates the modifiers of the item against the modifier groups of the product, the
+// modifiers get their group and parent option and the default options of the groups without choices are added
+func (oi *OrderItem) ResolveModifiers(p product.Product) error {
+	selections := make([]product.ModifierSelection, 0)
+	for i, modifier := range oi.Modifiers {
+		if modifier.ProductID == nil {
+			continue
+		}
+		selections = append(selections, product.ModifierSelection{
+			ModifierID:      modifier.ModifierID,
+			ParentProductID: modifier.ParentProductID,
+			ProductID:       *modifier.ProductID,
+			Ref:             i,
+		})
+	}
+
+	resolved, err := p.ResolveSelections(selections)
+	if err != nil {
+		return err
+	}
+
+	modifiers := make([]OrderModifier, 0)
+	for _, selection := range resolved {
+		modifier := OrderModifier{}
+		if !selection.Default {
+			modifier = oi.Modifiers[selection.Ref]
+		}
+
+		productID := selection.ProductID
+		modifier.ProductID = &productID
+		modifier.ModifierID = selection.ModifierID
+		modifier.ParentProductID = selection.ParentProductID
+		modifier.Free = selection.Free
+		modifiers = append(modifiers, modifier)
+	}
+	oi.Modifiers = modifiers
+
+	return nil
+}
+
+// ExpandCombo sets the components of a combo item, the fixed components of the combo and the options chosen on the
+// groups of the choosable ones. The item price is allocated across the components by their list prices and the
+// price of a chosen option is added to its component.
+func (oi *OrderItem) ExpandCombo(combo product.Product, options map[string]product.Product) {
+	oi.Components = nil
+	if !combo.Combo {
+		return
+	}
+
+	components := make([]OrderItemComponent, 0)
+	listPrices := make([]float64, 0)
+	add := func(comboComponent product.ComboComponent, p product.Product, modifierID *uint, upcharge float64) {
+		comboComponentID, productID := comboComponent.ID, p.ID
+		component := OrderItemComponent{
+			ComboComponentID: &comboComponentID,
+			ProductID:        &productID,
+			ModifierID:       modifierID,
+			Name:             p.Name,
+			SKU:              p.SKU,
+			Quantity:         comboComponent.Quantity,
+			ListPrice:        p.Price * float64(comboComponent.Quantity),
+			Upcharge:         upcharge,
+		}
+		if p.Tax != nil {
+			component.Tax = p.Tax.Name
+			component.TaxPercentage = p.Tax.Percentage
+		}
+		components = append(components, component)
+		listPrices = append(listPrices, component.ListPrice)
+	}
+
+	for _, comboComponent := range combo.Components {
+		if comboComponent.Product != nil {
+			add(comboComponent, *comboComponent.Product, nil, 0)
+			continue
+		}
+
+		if comboComponent.ModifierID == nil {
+			continue
+		}
+
+		for _, modifier := range oi.Modifiers {
+			if !modifier.chooses(*comboComponent.ModifierID) {
+				continue
+			}
+			if p, ok := options[fmt.Sprintf("%d", *modifier.ProductID)]; ok {
+				add(comboComponent, p, comboComponent.ModifierID, modifier.Price)
+			}
+		}
+	}
+
+	for i, price := range product.AllocatePrice(oi.Price, listPrices) {
+		components[i].Price = price + components[i].Upcharge
+	}
+	oi.Components = components
+}
+
+// GetPrice returns the price of the item with its modifiers before discounts, the options chosen for the combo
+// components are charged as their upcharge
+func (oi *OrderItem) GetPrice() float64 {
+	price := oi.Price
+	for _, modifier := range oi.Modifiers {
+		price += modifier.Price
+	}
+	return price
+}
+
+// isComponent reports if the modifier is the option chosen for a component of the combo item
+func (oi *OrderItem) isComponent(modifier OrderModifier) bool {
+	for _, component := range oi.Components {
+		if component.ModifierID != nil && component.ProductID != nil && modifier.ProductID != nil &&
+			modifier.chooses(*component.ModifierID) && *modifier.ProductID == *component.ProductID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ComboInvoiceItems returns an invoice line for every component of the combo item with its allocated price, tax and
+// discount, the totals of the item are the sums of its components
+func (oi *OrderItem) ComboInvoiceItems(discounts []invoice.DiscountApplied) []invoice.Item {
+	oi.TaxBase, oi.TaxAmount, oi.Discount, oi.DiscountedPrice = 0, 0, 0, 0
+
+	items := make([]invoice.Item, 0)
+	for i := range oi.Components {
+		component := &oi.Components[i]
+		if component.Tax == "" {
+			component.Tax = "ico" // Default tax type
+		}
+
+		if component.TaxPercentage == 0 {
+			component.TaxPercentage = TaxPercentage // Default tax percentage
+		}
+
+		component.TaxBase = math.Ceil(component.Price / (1 + component.TaxPercentage))
+		component.TaxAmount = component.Price - component.TaxBase
+
+		discountedPrice, discount, discountPercent, discountReason := component.Price, 0.0, 0.0, ""
+		for _, d := range discounts {
+			discountedPrice -= d.CalculateAmountRounded(component.Price)
+			discountPercent += d.Percentage
+			discount += d.CalculateAmountRounded(component.Price)
+			discountReason += fmt.Sprintf("%s - %s - %.2f - %.2f - applied to: %.2f;", d.Name, d.Description, d.Percentage, d.CalculateAmountRounded(component.Price), component.Price)
+		}
+
+		oi.TaxBase += component.TaxBase
+		oi.TaxAmount += component.TaxAmount
+		oi.Discount += discount
+		oi.DiscountedPrice += discountedPrice
+		oi.DiscountPercent = discountPercent
+		oi.DiscountReason = discountReason
+
+		items = append(items, invoice.Item{
+			ProductID:          component.ProductID,
+			Name:               component.Name,
+			SKU:                component.SKU,
+			Price:              component.Price,
+			Comments:           oi.Comments,
+			Hash:               oi.Hash,
+			OrderItemID:        &oi.ID,
+			ComboID:            oi.ProductID,
+			ComboName:          oi.Name,
+			DiscountedPrice:    discountedPrice,
+			DiscountPercentage: discountPercent,
+			DiscountReason:     discountReason,
+			DiscountAmount:     discount,
+			Tax:                component.Tax,
+			TaxPercentage:      component.TaxPercentage,
+			TaxAmount:          component.TaxAmount,
+			TaxBase:            component.TaxBase,
+		})
+	}
+
+	return items
+}
+
 func (oi *OrderItem) AddModifiers(modifier []OrderModifier) {
 	oi.Modifiers = append(oi.Modifiers, modifier...)
 }
@@ -538,6 +797,9 @@ type OrderModifier struct {
 	Image           string          `json:"image"`
 	Category        string          `json:"category"`
 	ProductID       *uint           `json:"product_id"`
+	ModifierID      *uint           `json:"modifier_id"`       // modifier group of the choice
+	ParentProductID *uint           `json:"parent_product_id"` // option whose nested group has the choice, empty for the item groups
+	Free            bool            `json:"free"`              // choice included in the item price
 	SKU             string          `json:"sku"`
 	Price           float64         `json:"price"  gorm:"precision:18;scale:2"`
 	Discount        float64         `json:"discount" gorm:"precision:18;scale:2"`
@@ -557,6 +819,34 @@ type OrderModifier struct {
 	DeletedAt       *gorm.DeletedAt `json:"deleted_at,omitempty" swaggerignore:"true"`
 }
 
+// chooses reports if the modifier is a choice of the modifier group of the item
+func (om OrderModifier) chooses(modifierID uint) bool {
+	return om.ProductID != nil && om.ModifierID != nil && *om.ModifierID == modifierID && om.ParentProductID == nil
+}
+
+// OrderItemComponent is a product of a combo item with its share of the combo price, the components are sent to
+// the kitchen and invoiced on their own lines
+type OrderItemComponent struct {
+	ID               uint            `json:"id" gorm:"primaryKey"`
+	OrderItemID      *uint           `json:"order_item_id" gorm:"index"`
+	ComboComponentID *uint           `json:"combo_component_id"`
+	ProductID        *uint           `json:"product_id"`
+	ModifierID       *uint           `json:"modifier_id"` // modifier group of a choosable component
+	Name             string          `json:"name"`
+	SKU              string          `json:"sku"`
+	Quantity         int             `json:"quantity"`
+	ListPrice        float64         `json:"list_price" gorm:"precision:18;scale:2"` // price of the product sold alone
+	Upcharge         float64         `json:"upcharge" gorm:"precision:18;scale:2"`   // price of the chosen option
+	Price            float64         `json:"price" gorm:"precision:18;scale:2"`      // allocated share of the combo price
+	Tax              string          `json:"tax"`
+	TaxPercentage    float64         `json:"tax_percentage"`
+	TaxBase          float64         `json:"tax_base" gorm:"precision:18;scale:2"`
+	TaxAmount        float64         `json:"tax_amount" gorm:"precision:18;scale:2"`
+	CreatedAt        *time.Time      `json:"created_at,omitempty" swaggerignore:"true"`
+	UpdatedAt        *time.Time      `json:"updated_at,omitempty" swaggerignore:"true"`
+	DeletedAt        *gorm.DeletedAt `json:"deleted_at,omitempty" swaggerignore:"true"`
+}
+
 type OrderType struct {
 	ID          uint            `json:"id"`
 	Name        string          `json:"name"`
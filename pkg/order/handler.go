@@ -7,20 +7,80 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/BacoFoods/menu/internal"
 	"github.com/BacoFoods/menu/pkg/client"
 	"github.com/BacoFoods/menu/pkg/invoice"
 	"github.com/BacoFoods/menu/pkg/shared"
+	"github.com/BacoFoods/menu/pkg/tables"
 	"github.com/gin-gonic/gin"
 )
 
-const LogHandler string = "pkg/order/handler"
+const (
+	LogHandler string = "pkg/order/handler"
+
+	HeaderTableSession = "X-Table-Session"
+)
+
+// TableSessionValidator validates the session tokens given when the table QR is scanned
+type TableSessionValidator interface {
+	ValidateSession(token string) (*tables.Table, error)
+}
 
 type Handler struct {
-	service Service
+	service  Service
+	sessions TableSessionValidator
+}
+
+func NewHandler(service Service, sessions TableSessionValidator) *Handler {
+	return &Handler{service, sessions}
+}
+
+// TableSession middleware limits the public order routes to the table session given when the QR was scanned,
+// when the route has an order id the order must be on the session table.
+func (h *Handler) TableSession(c *gin.Context) {
+	if internal.Config.AppEnv == "local" {
+		c.Next()
+		return
+	}
+
+	table, ok := h.sessionTable(c)
+	if !ok {
+		return
+	}
+
+	if orderID := c.Param("id"); orderID != "" {
+		order, err := h.service.Get(orderID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, shared.ErrorResponse(ErrorOrderGetting))
+			return
+		}
+
+		if order.TableID == nil || *order.TableID != table.ID {
+			c.AbortWithStatusJSON(http.StatusForbidden, shared.ErrorResponse(ErrorOrderTableSessionForbidden))
+			return
+		}
+	}
+
+	c.Next()
 }
 
-func NewHandler(service Service) *Handler {
-	return &Handler{service}
+// sessionTable returns the table of the session given when the QR was scanned, the request is aborted when the
+// session is missing or no longer valid
+func (h *Handler) sessionTable(c *gin.Context) (*tables.Table, bool) {
+	token := strings.TrimSpace(c.GetHeader(HeaderTableSession))
+	if token == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, shared.ErrorResponse(ErrorOrderTableSessionRequired))
+		return nil, false
+	}
+
+	table, err := h.sessions.ValidateSession(token)
+	if err != nil {
+		shared.LogWarn("invalid table session", LogHandler, "sessionTable", err)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, shared.ErrorResponse(err.Error()))
+		return nil, false
+	}
+
+	return table, true
 }
 
 // Order
@@ -124,7 +184,7 @@ func (h *Handler) Update(c *gin.Context) {
 // CreatePublic to handle a request to create an order
 // @Tags Order
 // @Summary To create an order
-// @Description To create an order
+// @Description To create an order, the table orders need the table session given when the QR was scanned
 // @Accept json
 // @Produce json
 // @Param order body OrderDTO true "Order"
@@ -141,7 +201,16 @@ func (h *Handler) CreatePublic(c *gin.Context) {
 		return
 	}
 
+	// The orders for a table come from its session, the takeaway orders have no table and need no session
 	order := body.ToOrder()
+	if (order.TableID != nil || c.GetHeader(HeaderTableSession) != "") && internal.Config.AppEnv != "local" {
+		table, ok := h.sessionTable(c)
+		if !ok {
+			return
+		}
+		order.TableID = &table.ID
+	}
+
 	orderDB, err := h.service.Create("", &order, c)
 	if err != nil {
 		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
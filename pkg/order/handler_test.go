@@ -0,0 +1,78 @@
+package order_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/BacoFoods/menu/pkg/order"
+	"github.com/BacoFoods/menu/pkg/tables"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// orderService records the created orders, the other methods aren't used by the public create
+type orderService struct {
+	order.Service
+	created []order.Order
+}
+
+func (s *orderService) Create(_ string, newOrder *order.Order, _ context.Context) (*order.Order, error) {
+	s.created = append(s.created, *newOrder)
+	return newOrder, nil
+}
+
+// sessions holds the table of each valid session token
+type sessions map[string]uint
+
+func (s sessions) ValidateSession(token string) (*tables.Table, error) {
+	tableID, ok := s[token]
+	if !ok {
+		return nil, fmt.Errorf(tables.ErrorTableSessionInvalid)
+	}
+	return &tables.Table{ID: tableID}, nil
+}
+
+var _ = Describe("Public orders", func() {
+	var service *orderService
+	var router *gin.Engine
+
+	BeforeEach(func() {
+		gin.SetMode(gin.TestMode)
+		service = &orderService{}
+		handler := order.NewHandler(service, sessions{"qr-5": 5})
+		router = gin.New()
+		router.POST("/public/order", handler.CreatePublic)
+	})
+
+	create := func(body, session string) int {
+		req := httptest.NewRequest(http.MethodPost, "/public/order", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if session != "" {
+			req.Header.Set(order.HeaderTableSession, session)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	It("Creates the takeaway orders without a table session", func() {
+		Expect(create(`{"brand_id": 1, "channel_id": 1, "store_id": 1}`, "")).To(Equal(http.StatusOK))
+		Expect(service.created).To(HaveLen(1))
+		Expect(service.created[0].TableID).To(BeNil())
+	})
+
+	It("Requires the session of the table for the table orders", func() {
+		Expect(create(`{"brand_id": 1, "channel_id": 1, "store_id": 1, "table_id": 5}`, "")).To(Equal(http.StatusUnauthorized))
+		Expect(create(`{"brand_id": 1, "channel_id": 1, "store_id": 1, "table_id": 5}`, "expired")).To(Equal(http.StatusUnauthorized))
+		Expect(service.created).To(BeEmpty())
+	})
+
+	It("Puts the order on the table of the session", func() {
+		Expect(create(`{"brand_id": 1, "channel_id": 1, "store_id": 1, "table_id": 9}`, "qr-5")).To(Equal(http.StatusOK))
+		Expect(*service.created[0].TableID).To(Equal(uint(5)))
+	})
+})
@@ -0,0 +1,13 @@
+package order_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestOrder(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Order Suite")
+}
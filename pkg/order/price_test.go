@@ -0,0 +1,26 @@
+package order_test
+
+import (
+	"github.com/BacoFoods/menu/pkg/order"
+	"github.com/BacoFoods/menu/pkg/product"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Price list", func() {
+	It("Prices the products and the combo components on the list and keeps the catalog price of the others", func() {
+		soda := product.Product{ID: 30, Price: 5000}
+		products := []product.Product{
+			{ID: 10, Price: 20000},
+			{ID: 11, Price: 8000},
+			{ID: 1, Price: 25000, Combo: true, Components: []product.ComboComponent{{Product: &soda}, {}}},
+		}
+
+		order.ApplyPrices(map[uint]float64{10: 22000, 30: 6000, 99: 1000}, products)
+
+		Expect(products[0].Price).To(Equal(22000.0))
+		Expect(products[1].Price).To(Equal(8000.0))
+		Expect(products[2].Price).To(Equal(25000.0))
+		Expect(products[2].Components[0].Product.Price).To(Equal(6000.0))
+	})
+})
@@ -36,13 +36,13 @@ func (r Routes) RegisterRoutes(private, public *shared.CustomRoutes) {
 	private.POST("/order", r.handler.Create, telemetryMiddleware)
 	public.POST("/order", r.handler.CreatePublic, telemetryMiddleware)
 	private.GET("/order/:id", r.handler.Get, telemetryMiddleware)
-	public.GET("/order/:id", r.handler.GetPublic, telemetryMiddleware)
+	public.GET("/order/:id", r.handler.TableSession, r.handler.GetPublic, telemetryMiddleware)
 
 	private.PATCH("/order", r.handler.Update)
 	private.PATCH("/order/:id/table/:table", r.handler.UpdateTable)
 	private.PATCH("/order/:id/seats", r.handler.UpdateSeats)
 	private.PATCH("/order/:id/add/products", r.handler.AddProducts)
-	public.PATCH("/order/:id/add/products", r.handler.AddProducts)
+	public.PATCH("/order/:id/add/products", r.handler.TableSession, r.handler.AddProducts)
 	private.PATCH("/order/:id/remove/product", r.handler.RemoveProduct)
 	private.PATCH("/order/:id/update/product", r.handler.UpdateProduct)
 	private.PATCH("/order/:id/update/comments", r.handler.UpdateComments)
@@ -64,7 +64,7 @@ func (r Routes) RegisterRoutes(private, public *shared.CustomRoutes) {
 	// Invoice
 	private.POST("/order/:id/invoice", r.handler.CreateInvoice, telemetryMiddleware)
 	private.POST("/order/:id/invoice/calculate", r.handler.CalculateInvoice)
-	public.GET("/order/:id/invoice/calculate", r.handler.PublicCalculateInvoice)
-	public.POST("/order/:id/checkout", r.handler.PublicCheckout)
+	public.GET("/order/:id/invoice/calculate", r.handler.TableSession, r.handler.PublicCalculateInvoice)
+	public.POST("/order/:id/checkout", r.handler.TableSession, r.handler.PublicCheckout)
 	private.POST("/invoice/:id/close", r.handler.CloseInvoice)
 }
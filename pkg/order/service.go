@@ -6,11 +6,13 @@ import (
 	"fmt"
 	"github.com/BacoFoods/menu/pkg/facturacion"
 	"github.com/BacoFoods/menu/pkg/plemsi"
+	"github.com/BacoFoods/menu/pkg/realtime"
 	"strconv"
 	"time"
 
 	"github.com/BacoFoods/menu/internal"
 	accounts "github.com/BacoFoods/menu/pkg/account"
+	"github.com/BacoFoods/menu/pkg/availability"
 	channels "github.com/BacoFoods/menu/pkg/channel"
 	"github.com/BacoFoods/menu/pkg/client"
 	"github.com/BacoFoods/menu/pkg/discount"
@@ -72,6 +74,38 @@ type channelSrv interface {
 	Get(string) (*channels.Channel, error)
 }
 
+// closedOrderRecorder records the closed orders, like the sales analytics aggregates
+type closedOrderRecorder interface {
+	RecordClosedOrder(order *Order)
+}
+
+// ClosedOrderRecorders records the closed orders on each recorder in turn
+type ClosedOrderRecorders []closedOrderRecorder
+
+func (r ClosedOrderRecorders) RecordClosedOrder(order *Order) {
+	for _, recorder := range r {
+		recorder.RecordClosedOrder(order)
+	}
+}
+
+// menuSrv resolves the products out of the dayparts of a store on a channel and the prices of the menu versions
+type menuSrv interface {
+	UnavailableProducts(storeID, channelID string, productIDs []uint) ([]uint, error)
+	VersionPrices(brandID uint, versionID *uint) (*uint, map[uint]float64, error)
+}
+
+// stockSrv checks and counts down the products and modifiers 86'd at a store or channel
+type stockSrv interface {
+	Unavailable(entity availability.Entity, storeID, channelID uint, units availability.Units) ([]uint, error)
+	Reserve(entity availability.Entity, storeID, channelID uint, units availability.Units) error
+	Release(entity availability.Entity, storeID, channelID uint, units availability.Units)
+}
+
+// tableSrv moves the tables through their lifecycle and notifies the staff of it
+type tableSrv interface {
+	SetState(tableID uint, state string) (*tables.Table, error)
+}
+
 type facturacionSrv interface {
 	Generate(invoice *invoices.Invoice, docType string, data any) (*invoices.Document, error)
 	IsFinalCustomer(documentType string) bool
@@ -94,6 +128,11 @@ type ServiceImpl struct {
 	redis           *redis.Client
 	plemsi          plemsi.Adapter
 	client          client.Repository
+	events          realtime.Publisher
+	closedOrders    closedOrderRecorder
+	menus           menuSrv
+	stock           stockSrv
+	tableStates     tableSrv
 }
 
 func NewService(repository Repository,
@@ -111,6 +150,11 @@ func NewService(repository Repository,
 	redis *redis.Client,
 	plemsi plemsi.Adapter,
 	client client.Repository,
+	events realtime.Publisher,
+	closedOrders closedOrderRecorder,
+	menus menuSrv,
+	stock stockSrv,
+	tableStates tableSrv,
 ) ServiceImpl {
 	return ServiceImpl{repository,
 		table,
@@ -127,6 +171,11 @@ func NewService(repository Repository,
 		redis,
 		plemsi,
 		client,
+		events,
+		closedOrders,
+		menus,
+		stock,
+		tableStates,
 	}
 }
 
@@ -135,12 +184,17 @@ func NewService(repository Repository,
 func (s *ServiceImpl) Create(idempoKey string, order *Order, ctx context.Context) (*Order, error) {
 	// Setting product items
 	productIDs := order.GetProductIDs()
-	prods, err := s.product.GetByIDs(productIDs)
+	prods, err := s.product.GetWithModifierTree(productIDs)
 	if err != nil {
 		shared.LogError("error getting products", LogService, "Create", err, productIDs)
 		return nil, fmt.Errorf(ErrorOrderCreation)
 	}
 
+	if err := order.ResolveModifiers(prods); err != nil {
+		shared.LogWarn("invalid modifiers", LogService, "Create", err, productIDs)
+		return nil, err
+	}
+
 	modifierIDs := order.GetModifierIDs()
 	modifiers, err := s.product.GetByIDs(modifierIDs)
 	if err != nil {
@@ -152,6 +206,12 @@ func (s *ServiceImpl) Create(idempoKey string, order *Order, ctx context.Context
 		return nil, fmt.Errorf(ErrorOrderProductsNotFound)
 	}
 
+	versionID, err := s.priceWithMenuVersion(order.BrandID, nil, prods, modifiers)
+	if err != nil {
+		return nil, err
+	}
+	order.MenuVersionID = versionID
+
 	order.SetItems(prods, modifiers)
 	// order.ToInvoice(nil) // TODO: check if this is needed for oit, commented because it was causing an error duplicating invoice
 
@@ -192,6 +252,10 @@ func (s *ServiceImpl) Create(idempoKey string, order *Order, ctx context.Context
 		return nil, err
 	}
 
+	if err := s.checkSellable(order.StoreID, channel.ID, order.Items); err != nil {
+		return nil, err
+	}
+
 	brandID := uint(0)
 	if value := ctx.Value("brand_id"); value != nil {
 		brandIDInt, _ := strconv.Atoi(value.(string))
@@ -204,12 +268,12 @@ func (s *ServiceImpl) Create(idempoKey string, order *Order, ctx context.Context
 	}
 	accountID := uint(0)
 
-	// Setting order shift
-	shift, err := s.shift.GetOpenShift(&storeID)
-	if err != nil {
-		shared.LogWarn("error getting shift", LogService, "Create", err, storeID)
+	// Setting order shift, the open shift of the cashier or the last open shift of the store, skipping the audited ones
+	accountIDValue := ""
+	if value := ctx.Value("account_id"); value != nil {
+		accountIDValue = value.(string)
 	}
-	if shift != nil {
+	if shift := shifts.OrderShift(s.shift, accountIDValue, &storeID); shift != nil {
 		order.ShiftID = &shift.ID
 	}
 
@@ -223,9 +287,15 @@ func (s *ServiceImpl) Create(idempoKey string, order *Order, ctx context.Context
 		order.IdempotencyKey = &idempoKey
 	}
 
+	release, err := s.reserveStock(order.StoreID, channel.ID, order.Items)
+	if err != nil {
+		return nil, err
+	}
+
 	newOrder, err := s.repository.Create(order, channel)
 	if err != nil {
 		shared.LogError("error creating order", LogService, "Create", err, *order)
+		release()
 		return nil, fmt.Errorf(ErrorOrderCreation)
 	}
 
@@ -274,6 +344,8 @@ func (s *ServiceImpl) Create(idempoKey string, order *Order, ctx context.Context
 	// TODO: Send create order and set table to repository to make a trx and rollback if error to avoid has order without table
 	if newOrder.TableID != nil && *newOrder.TableID != 0 {
 		if _, err := s.tables.SetOrder(newOrder.TableID, &newOrder.ID); err != nil {
+			shared.LogError("error setting table order", LogService, "Create", err, *newOrder.TableID, newOrder.ID)
+			release()
 			return nil, err
 		}
 	}
@@ -282,12 +354,163 @@ func (s *ServiceImpl) Create(idempoKey string, order *Order, ctx context.Context
 	orderDB, err := s.repository.Get(fmt.Sprintf("%d", newOrder.ID))
 	if err != nil {
 		shared.LogError("error getting order", LogService, "Create", err, newOrder.ID)
+		release()
 		return nil, fmt.Errorf(ErrorOrderCreation)
 	}
 
+	s.publish(realtime.EventOrderCreated, orderDB, orderDB)
+
 	return orderDB, nil
 }
 
+// priceWithMenuVersion prices the products and modifiers with the given menu version, or with the published version
+// of the brand when none is given, and returns the version. Brands that have never published a version keep the
+// catalog prices.
+func (s *ServiceImpl) priceWithMenuVersion(brandID, versionID *uint, productLists ...[]products.Product) (*uint, error) {
+	if s.menus == nil || brandID == nil {
+		return versionID, nil
+	}
+
+	pricedVersionID, prices, err := s.menus.VersionPrices(*brandID, versionID)
+	if err != nil {
+		shared.LogError("error getting menu version prices", LogService, "priceWithMenuVersion", err, *brandID, versionID)
+		return nil, fmt.Errorf(ErrorOrderMenuVersionPricing)
+	}
+
+	for _, productList := range productLists {
+		ApplyPrices(prices, productList)
+	}
+
+	return pricedVersionID, nil
+}
+
+// checkSellable rejects the items whose products are out of the dayparts of the store on the channel
+func (s *ServiceImpl) checkSellable(storeID *uint, channelID uint, items []OrderItem) error {
+	if s.menus == nil || storeID == nil {
+		return nil
+	}
+
+	productIDs := make([]uint, 0)
+	for _, item := range items {
+		if item.ProductID != nil {
+			productIDs = append(productIDs, *item.ProductID)
+		}
+	}
+
+	unavailable, err := s.menus.UnavailableProducts(fmt.Sprint(*storeID), fmt.Sprint(channelID), productIDs)
+	if err != nil {
+		shared.LogError("error checking sellable products", LogService, "checkSellable", err, *storeID, channelID, productIDs)
+		return fmt.Errorf(ErrorOrderProductSellableChecking)
+	}
+
+	errs := ""
+	for _, productID := range unavailable {
+		errs += fmt.Sprintf(ErrorOrderProductNotSellable, productID)
+	}
+	if errs != "" {
+		return fmt.Errorf(errs)
+	}
+
+	return nil
+}
+
+// reserveStock rejects the items whose products or modifiers are 86'd at the store or the channel and takes the
+// units ordered from the counted ones, the returned release gives them back when the order can't be placed
+func (s *ServiceImpl) reserveStock(storeID *uint, channelID uint, items []OrderItem) (func(), error) {
+	release := func() {}
+	if s.stock == nil || storeID == nil {
+		return release, nil
+	}
+
+	productUnits, modifierUnits := stockUnits(items)
+
+	errs := ""
+	for _, entity := range []availability.Entity{availability.EntityProduct, availability.EntityModifier} {
+		units := productUnits
+		if entity == availability.EntityModifier {
+			units = modifierUnits
+		}
+
+		unavailable, err := s.stock.Unavailable(entity, *storeID, channelID, units)
+		if err != nil {
+			shared.LogError("error checking stock", LogService, "reserveStock", err, *storeID, channelID, units)
+			return release, fmt.Errorf(ErrorOrderStockChecking)
+		}
+
+		for _, id := range unavailable {
+			if entity == availability.EntityProduct {
+				errs += fmt.Sprintf(ErrorOrderProductStockedOut, id)
+			} else {
+				errs += fmt.Sprintf(ErrorOrderModifierStockedOut, id)
+			}
+		}
+	}
+	if errs != "" {
+		return release, fmt.Errorf(errs)
+	}
+
+	if err := s.stock.Reserve(availability.EntityProduct, *storeID, channelID, productUnits); err != nil {
+		shared.LogWarn("error reserving products stock", LogService, "reserveStock", err, *storeID, channelID, productUnits)
+		return release, err
+	}
+
+	if err := s.stock.Reserve(availability.EntityModifier, *storeID, channelID, modifierUnits); err != nil {
+		shared.LogWarn("error reserving modifiers stock", LogService, "reserveStock", err, *storeID, channelID, modifierUnits)
+		s.stock.Release(availability.EntityProduct, *storeID, channelID, productUnits)
+		return release, err
+	}
+
+	release = func() {
+		s.releaseStock(storeID, channelID, items)
+	}
+
+	return release, nil
+}
+
+// releaseStock gives back the units of the items to the counted stock, as when the items are removed or the
+// order is canceled
+func (s *ServiceImpl) releaseStock(storeID *uint, channelID uint, items []OrderItem) {
+	if s.stock == nil || storeID == nil || len(items) == 0 {
+		return
+	}
+
+	productUnits, modifierUnits := stockUnits(items)
+	s.stock.Release(availability.EntityProduct, *storeID, channelID, productUnits)
+	s.stock.Release(availability.EntityModifier, *storeID, channelID, modifierUnits)
+}
+
+// stockUnits counts the units of the products and modifiers of the items
+func stockUnits(items []OrderItem) (availability.Units, availability.Units) {
+	productUnits := availability.Units{}
+	modifierUnits := availability.Units{}
+	for _, item := range items {
+		if item.ProductID != nil {
+			productUnits[*item.ProductID]++
+		}
+		for _, modifier := range item.Modifiers {
+			if modifier.ProductID != nil {
+				modifierUnits[*modifier.ProductID]++
+			}
+		}
+		// the fixed components of a combo take the stock of their products
+		for _, component := range item.Components {
+			if component.ProductID != nil && component.ModifierID == nil {
+				productUnits[*component.ProductID] += component.Quantity
+			}
+		}
+	}
+
+	return productUnits, modifierUnits
+}
+
+// orderChannelID returns the channel of the order, zero when it has none
+func orderChannelID(order *Order) uint {
+	if order.ChannelID == nil {
+		return 0
+	}
+	return *order.ChannelID
+}
+
 func (s *ServiceImpl) Update(order *Order) (*Order, error) {
 	return s.repository.Update(order)
 }
@@ -321,6 +544,11 @@ func (s *ServiceImpl) UpdateTable(orderID, tableID uint64) (*Order, error) {
 
 	// TODO: loggear en los eventos de la orden, que se cambio de mesa y quien lo hizo
 
+	s.publish(realtime.EventOrderTableChanged, orderDB, orderDB)
+	if oldTableID != nil && *oldTableID != 0 {
+		s.publish(realtime.EventTableReleased, &Order{ID: order.ID, StoreID: order.StoreID, TableID: oldTableID}, nil)
+	}
+
 	return orderDB, nil
 }
 
@@ -355,6 +583,8 @@ func (s *ServiceImpl) UpdateSeats(orderID string, seats int) (*Order, error) {
 		return nil, fmt.Errorf(ErrorOrderUpdate)
 	}
 
+	s.publish(realtime.EventOrderUpdated, orderDB, orderDB)
+
 	return orderDB, nil
 }
 
@@ -372,30 +602,75 @@ func (s *ServiceImpl) AddProducts(idempoKey, orderID string, orderItems []OrderI
 	}
 
 	productIDs := make([]string, len(orderItems))
-	modifierIDs := make([]string, 0)
 	for i, item := range orderItems {
 		if item.ProductID != nil {
 			productIDs[i] = fmt.Sprintf("%d", *item.ProductID)
-			for _, mod := range item.Modifiers {
-				modifierIDs = append(modifierIDs, fmt.Sprintf("%d", *mod.ProductID))
-			}
 		}
 	}
 
-	productsMap, err := s.product.GetAsMapByIDs(productIDs)
+	if order.ChannelID != nil {
+		if err := s.checkSellable(order.StoreID, *order.ChannelID, orderItems); err != nil {
+			return nil, err
+		}
+	}
+
+	prods, err := s.product.GetWithModifierTree(productIDs)
 	if err != nil {
 		shared.LogError("error getting products", LogService, "AddProduct", err, productIDs)
 		return nil, fmt.Errorf(ErrorOrderProductGetting)
 	}
 
+	productsMap := make(map[string]products.Product)
+	for _, p := range prods {
+		productsMap[fmt.Sprintf("%d", p.ID)] = p
+	}
+
+	// the modifiers are validated against the modifier groups of the products, adding the default options
+	errs := ""
+	modifierIDs := make([]string, 0)
+	for i, item := range orderItems {
+		if item.ProductID == nil {
+			continue
+		}
+
+		if p, ok := productsMap[fmt.Sprintf("%d", *item.ProductID)]; ok {
+			if err := orderItems[i].ResolveModifiers(p); err != nil {
+				errs += err.Error()
+				continue
+			}
+		}
+
+		for _, mod := range orderItems[i].Modifiers {
+			modifierIDs = append(modifierIDs, fmt.Sprintf("%d", *mod.ProductID))
+		}
+	}
+
+	if errs != "" {
+		return nil, fmt.Errorf(errs)
+	}
+
 	productModifiersMap, err := s.product.GetAsMapByIDs(modifierIDs)
 	if err != nil {
 		shared.LogError("error getting modifiers products", LogService, "AddProduct", err, modifierIDs)
 		return nil, fmt.Errorf(ErrorOrderProductGetting)
 	}
 
+	// the products added are priced against the menu version of the order
+	productModifiers := make([]products.Product, 0, len(productModifiersMap))
+	for _, modifier := range productModifiersMap {
+		productModifiers = append(productModifiers, modifier)
+	}
+	if _, err := s.priceWithMenuVersion(order.BrandID, order.MenuVersionID, prods, productModifiers); err != nil {
+		return nil, err
+	}
+	for _, p := range prods {
+		productsMap[fmt.Sprintf("%d", p.ID)] = p
+	}
+	for _, modifier := range productModifiers {
+		productModifiersMap[fmt.Sprintf("%d", modifier.ID)] = modifier
+	}
+
 	newOrderItems := make([]OrderItem, 0)
-	errs := ""
 	for _, item := range orderItems {
 		productID := fmt.Sprintf("%d", *item.ProductID)
 		if _, ok := productsMap[productID]; !ok {
@@ -414,16 +689,24 @@ func (s *ServiceImpl) AddProducts(idempoKey, orderID string, orderItems []OrderI
 			}
 
 			modifier := productModifiersMap[productID]
+			price := modifier.Price
+			if mod.Free {
+				price = 0
+			}
+
 			modifiers[i] = OrderModifier{
-				OrderID:     order.ID,
-				ProductID:   mod.ProductID,
-				Name:        modifier.Name,
-				Description: modifier.Description,
-				Image:       modifier.Image,
-				SKU:         modifier.SKU,
-				Price:       modifier.Price,
-				Unit:        modifier.Unit,
-				Comments:    mod.Comments,
+				OrderID:         order.ID,
+				ProductID:       mod.ProductID,
+				ModifierID:      mod.ModifierID,
+				ParentProductID: mod.ParentProductID,
+				Free:            mod.Free,
+				Name:            modifier.Name,
+				Description:     modifier.Description,
+				Image:           modifier.Image,
+				SKU:             modifier.SKU,
+				Price:           price,
+				Unit:            modifier.Unit,
+				Comments:        mod.Comments,
 			}
 		}
 		newItem := OrderItem{
@@ -439,6 +722,7 @@ func (s *ServiceImpl) AddProducts(idempoKey, orderID string, orderItems []OrderI
 			Course:      item.Course,
 			Modifiers:   modifiers,
 		}
+		newItem.ExpandCombo(product, productModifiersMap)
 
 		newOrderItems = append(newOrderItems, newItem)
 	}
@@ -447,10 +731,21 @@ func (s *ServiceImpl) AddProducts(idempoKey, orderID string, orderItems []OrderI
 		return nil, fmt.Errorf(errs)
 	}
 
+	channelID := uint(0)
+	if order.ChannelID != nil {
+		channelID = *order.ChannelID
+	}
+
+	release, err := s.reserveStock(order.StoreID, channelID, newOrderItems)
+	if err != nil {
+		return nil, err
+	}
+
 	//	this sets the OrderItem.ID and appends the list to the orignal list of items in the order
 	orderDB, err := s.repository.AddProducts(order, newOrderItems)
 	if err != nil {
 		shared.LogError("error updating order", LogService, "AddProduct", err, *order)
+		release()
 		return nil, fmt.Errorf(ErrorOrderUpdate)
 	}
 
@@ -467,6 +762,8 @@ func (s *ServiceImpl) AddProducts(idempoKey, orderID string, orderItems []OrderI
 		}
 	}()
 
+	s.publish(realtime.EventOrderProductsAdded, orderDB, newOrderItems)
+
 	return orderDB, nil
 }
 
@@ -483,7 +780,7 @@ func (s *ServiceImpl) RemoveProduct(orderID, productID string) (*Order, error) {
 		return nil, fmt.Errorf(ErrorOrderGetting)
 	}
 
-	order.RemoveProduct(product)
+	removed := order.RemoveProduct(product)
 
 	orderDB, err := s.repository.Update(order)
 	if err != nil {
@@ -491,6 +788,12 @@ func (s *ServiceImpl) RemoveProduct(orderID, productID string) (*Order, error) {
 		return nil, fmt.Errorf(ErrorOrderUpdate)
 	}
 
+	if removed != nil {
+		s.releaseStock(order.StoreID, orderChannelID(order), []OrderItem{*removed})
+	}
+
+	s.publish(realtime.EventOrderUpdated, orderDB, orderDB)
+
 	return orderDB, nil
 }
 
@@ -522,6 +825,8 @@ func (s *ServiceImpl) UpdateStatusNext(orderID string) (*Order, error) {
 		return nil, fmt.Errorf(ErrorOrderUpdateStatus)
 	}
 
+	s.publish(realtime.EventOrderStatusChanged, order, order)
+
 	return order, nil
 }
 
@@ -539,6 +844,8 @@ func (s *ServiceImpl) UpdateStatusPrev(orderID string) (*Order, error) {
 		return nil, fmt.Errorf(ErrorOrderUpdateStatus)
 	}
 
+	s.publish(realtime.EventOrderStatusChanged, order, order)
+
 	return order, nil
 }
 
@@ -616,6 +923,9 @@ func (s *ServiceImpl) UpdateStatus(orderID, status string) (*Order, error) {
 		return nil, fmt.Errorf(ErrorOrderGetting)
 	}
 
+	// the units of a canceled order go back to the stock once, a closed order was already sold
+	releasing := status == OrderStatusCanceled && order.CurrentStatus != OrderStatusCanceled && order.CurrentStatus != OrderStatusClosed
+
 	order.CurrentStatus = status
 	order.Statuses = append(order.Statuses, OrderStatus{
 		Code:    status,
@@ -627,6 +937,12 @@ func (s *ServiceImpl) UpdateStatus(orderID, status string) (*Order, error) {
 		return nil, fmt.Errorf(ErrorOrderUpdateStatus)
 	}
 
+	if releasing {
+		s.releaseStock(order.StoreID, orderChannelID(order), order.Items)
+	}
+
+	s.publish(realtime.EventOrderStatusChanged, order, order)
+
 	return order, nil
 }
 
@@ -781,11 +1097,14 @@ func (s *ServiceImpl) CreateInvoice(req CreateInvoiceRequest) (*invoices.Invoice
 		return nil, fmt.Errorf(ErrorOrderInvoiceUpdate)
 	}
 
+	s.publish(realtime.EventOrderInvoiced, order, invoiceDB)
+
 	// release table
 	if order.TableID != nil && *order.TableID != 0 {
 		if _, err := s.tables.RemoveOrder(order.TableID); err != nil {
 			return nil, err
 		}
+		s.publish(realtime.EventTableReleased, order, nil)
 	}
 
 	if req.attendee != nil {
@@ -894,6 +1213,13 @@ func (s *ServiceImpl) Checkout(orderID string, data CheckoutRequest) (*InvoiceCh
 		return nil, err
 	}
 
+	// the customer is paying from the table
+	if invDB.TableID != nil && *invDB.TableID != 0 {
+		if _, err := s.tableStates.SetState(*invDB.TableID, tables.TableStatePaying); err != nil {
+			shared.LogWarn("error setting table state", LogService, "Checkout", err, *invDB.TableID)
+		}
+	}
+
 	return &InvoiceCheckout{
 		Payment: payment,
 		Invoice: invDB,
@@ -981,6 +1307,12 @@ func (s *ServiceImpl) CloseInvoice(req CloseInvoiceRequest) (*invoices.Invoice,
 		return nil, err
 	}
 
+	s.publish(realtime.EventOrderPaymentLanded, order, invDB)
+
+	if s.closedOrders != nil {
+		go s.closedOrders.RecordClosedOrder(order)
+	}
+
 	// Setting attendee
 	att := req.attendee
 	if att != nil {
@@ -1034,4 +1366,24 @@ func (s *ServiceImpl) EmitElectronicInvoice(storeID *uint, documentType string,
 	return *cude, *qr, nil
 }
 
+// publish notifies the store, table and order subscribers, errors are only logged
+// because the change is already saved.
+func (s *ServiceImpl) publish(eventType string, order *Order, data any) {
+	if s.events == nil || order == nil {
+		return
+	}
+
+	event := realtime.Event{
+		Type:    eventType,
+		StoreID: order.StoreID,
+		TableID: order.TableID,
+		OrderID: &order.ID,
+		Data:    data,
+	}
+
+	if err := s.events.Publish(event); err != nil {
+		shared.LogWarn("error publishing order event", LogService, "publish", err, eventType, order.ID)
+	}
+}
+
 var _ Service = &ServiceImpl{}
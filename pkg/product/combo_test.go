@@ -0,0 +1,25 @@
+package product_test
+
+import (
+	"github.com/BacoFoods/menu/pkg/product"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Combo", func() {
+	Context("AllocatePrice", func() {
+		It("Splits the price by the share of every list price and gives the remainder to the largest", func() {
+			allocated := product.AllocatePrice(25000, []float64{20000, 8000, 5000})
+
+			Expect(allocated).To(Equal([]float64{15151, 6061, 3788}))
+		})
+
+		It("Splits the price evenly without list prices", func() {
+			Expect(product.AllocatePrice(10000, []float64{0, 0, 0})).To(Equal([]float64{3334, 3333, 3333}))
+		})
+
+		It("Allocates nothing without components", func() {
+			Expect(product.AllocatePrice(10000, nil)).To(BeEmpty())
+		})
+	})
+})
@@ -52,6 +52,7 @@ func (r *DBRepository) Get(productID string) (*Product, error) {
 	var product Product
 	if err := r.db.Preload(clause.Associations).
 		Preload("Modifiers.Products").
+		Preload("Components.Product").
 		First(&product, productID).Error; err != nil {
 		shared.LogError("error getting product", LogDBRepository, "Get", err, productID)
 		return nil, err
@@ -69,6 +70,20 @@ func (r *DBRepository) GetByIDs(productIDs []string) ([]Product, error) {
 	return products, nil
 }
 
+// GetWithModifierTree method for get products by ids with their modifier groups, options and nested groups in database
+func (r *DBRepository) GetWithModifierTree(productIDs []string) ([]Product, error) {
+	var products []Product
+	if err := r.db.Where("id in ?", productIDs).
+		Preload(clause.Associations).
+		Preload("Components.Product.Tax").
+		Preload(ModifierTree("")).
+		Find(&products).Error; err != nil {
+		shared.LogError("error getting products", LogDBRepository, "GetWithModifierTree", err, productIDs)
+		return nil, err
+	}
+	return products, nil
+}
+
 // GetAsMapByIDs method for get products as map by ids in database
 func (r *DBRepository) GetAsMapByIDs(productIDs []string) (map[string]Product, error) {
 	var products []Product
@@ -266,6 +281,10 @@ func (r *DBRepository) ModifierUpdate(modifier *Modifier) (*Modifier, error) {
 	modifierMap["image"] = modifier.Image
 	modifierMap["apply_price"] = modifier.ApplyPrice
 	modifierMap["category"] = modifier.Category
+	modifierMap["required"] = modifier.Required
+	modifierMap["min_choices"] = modifier.MinChoices
+	modifierMap["max_choices"] = modifier.MaxChoices
+	modifierMap["free_choices"] = modifier.FreeChoices
 	modifierMap["brand_id"] = modifier.BrandID
 
 	if err := r.db.Model(&modifierDB).Updates(modifierMap).Error; err != nil {
@@ -276,6 +295,54 @@ func (r *DBRepository) ModifierUpdate(modifier *Modifier) (*Modifier, error) {
 	return &modifierDB, nil
 }
 
+// ModifierSetDefault method for set if an option of a modifier is selected by default in database
+func (r *DBRepository) ModifierSetDefault(modifierID, productID string, isDefault bool) (*Modifier, error) {
+	result := r.db.Model(&ModifierProduct{}).
+		Where("modifier_id = ? AND product_id = ?", modifierID, productID).
+		Update("default", isDefault)
+	if result.Error != nil {
+		shared.LogError("error setting modifier default", LogDBRepository, "ModifierSetDefault", result.Error, modifierID, productID)
+		return nil, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		err := fmt.Errorf(ErrorModifierOptionNotFound, productID, modifierID)
+		shared.LogWarn("error setting modifier default", LogDBRepository, "ModifierSetDefault", err, modifierID, productID)
+		return nil, err
+	}
+
+	return r.ModifierGet(modifierID)
+}
+
+// SetComponents replaces the components of the combo, a product without components is no longer a combo
+func (r *DBRepository) SetComponents(comboID uint, components []ComboComponent) (*Product, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("combo_id = ?", comboID).Delete(&ComboComponent{}).Error; err != nil {
+			return err
+		}
+
+		for i := range components {
+			components[i].ID = 0
+			components[i].ComboID = comboID
+			components[i].Product = nil
+		}
+
+		if len(components) > 0 {
+			if err := tx.Create(&components).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(&Product{}).Where("id = ?", comboID).Update("combo", len(components) > 0).Error
+	})
+	if err != nil {
+		shared.LogError("error setting combo components", LogDBRepository, "SetComponents", err, comboID, components)
+		return nil, fmt.Errorf(ErrorComboSettingComponents)
+	}
+
+	return r.Get(fmt.Sprint(comboID))
+}
+
 // Overrider
 
 // OverriderCreate method for create a new overrider in database
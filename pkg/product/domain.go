@@ -1,7 +1,10 @@
 package product
 
 import (
+	"fmt"
+	"math"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/BacoFoods/menu/pkg/discount"
@@ -28,6 +31,21 @@ const (
 	ErrorModifierGetting         string = "error getting modifiers"
 	ErrorModifierUpdate          string = "error updating modifier"
 	ErrorModifierBadRequest      string = "error bad request"
+	ErrorModifierChoices         string = "error modifier min choices must be lower than max choices and free choices can't be negative"
+	ErrorModifierDefaults        string = "error modifier has more default options than max choices"
+	ErrorModifierSettingDefault  string = "error setting modifier default option"
+	ErrorModifierOptionNotFound  string = "error product %v is not an option of modifier %v"
+
+	ErrorSelectionNotAllowed string = "error modifier product %v is not an option of product %v; "
+	ErrorSelectionMinChoices string = "error modifier %s needs at least %d choices; "
+	ErrorSelectionMaxChoices string = "error modifier %s allows at most %d choices; "
+
+	// MaxModifierDepth is the number of nested modifier levels of a product, like side then sauce
+	MaxModifierDepth = 3
+
+	ErrorComboSettingComponents string = "error setting combo components"
+	ErrorComboComponent         string = "error combo components need either a product or a modifier of the combo and a positive quantity"
+	ErrorComboNested            string = "error combo components can't be combos"
 
 	ErrorOverriderCreating   string = "error creating overriders"
 	ErrorOverriderFinding    string = "error finding overriders"
@@ -59,23 +77,251 @@ type Product struct {
 	Enabled        bool               `json:"enabled"`
 	ImageURL       *string            `json:"image_url"`
 	Modifiers      []Modifier         `json:"modifiers" gorm:"many2many:product_modifiers;"`
+	Combo          bool               `json:"combo"` // bundle of components, its price is allocated across them
+	Components     []ComboComponent   `json:"components,omitempty" gorm:"foreignKey:ComboID"`
+	StockedOut     bool               `json:"stocked_out" gorm:"-"`     // 86'd at the place of the menu
+	Stock          *int               `json:"stock,omitempty" gorm:"-"` // units left at the place of the menu when counted
 	CreatedAt      *time.Time         `json:"created_at,omitempty" swaggerignore:"true"`
 	UpdatedAt      *time.Time         `json:"updated_at,omitempty" swaggerignore:"true"`
 	DeletedAt      *gorm.DeletedAt    `json:"deleted_at,omitempty" swaggerignore:"true"`
 }
 
 type Modifier struct {
-	ID          uint            `json:"id"`
-	Name        string          `json:"name"`
-	Description string          `json:"description"`
-	Image       string          `json:"image"`
-	ApplyPrice  float64         `json:"apply_price" gorm:"precision:18;scale:2"`
-	Category    Category        `json:"category"`
-	Products    []Product       `json:"products" swaggerignore:"true" gorm:"many2many:modifier_products;"`
-	BrandID     *uint           `json:"brand_id" binding:"required"`
-	CreatedAt   *time.Time      `json:"created_at,omitempty" swaggerignore:"true"`
-	UpdatedAt   *time.Time      `json:"updated_at,omitempty" swaggerignore:"true"`
-	DeletedAt   *gorm.DeletedAt `json:"deleted_at,omitempty" swaggerignore:"true"`
+	ID          uint              `json:"id"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Image       string            `json:"image"`
+	ApplyPrice  float64           `json:"apply_price" gorm:"precision:18;scale:2"`
+	Category    Category          `json:"category"`
+	Required    bool              `json:"required"`
+	MinChoices  int               `json:"min_choices"`
+	MaxChoices  int               `json:"max_choices"`  // zero allows any number of choices
+	FreeChoices int               `json:"free_choices"` // choices included in the product price, the next ones are charged
+	Products    []Product         `json:"products" swaggerignore:"true" gorm:"many2many:modifier_products;"`
+	Options     []ModifierProduct `json:"options,omitempty" gorm:"foreignKey:ModifierID"`
+	BrandID     *uint             `json:"brand_id" binding:"required"`
+	CreatedAt   *time.Time        `json:"created_at,omitempty" swaggerignore:"true"`
+	UpdatedAt   *time.Time        `json:"updated_at,omitempty" swaggerignore:"true"`
+	DeletedAt   *gorm.DeletedAt   `json:"deleted_at,omitempty" swaggerignore:"true"`
+}
+
+// ComboComponent is a product included in a combo. A fixed component is always the same product, a choosable
+// component is the option chosen on a modifier group of the combo, like the drink of a meal deal.
+type ComboComponent struct {
+	ID         uint       `json:"id"`
+	ComboID    uint       `json:"combo_id" gorm:"index"`
+	ProductID  *uint      `json:"product_id"`
+	Product    *Product   `json:"product,omitempty" gorm:"foreignKey:ProductID" swaggerignore:"true"`
+	ModifierID *uint      `json:"modifier_id"`
+	Quantity   int        `json:"quantity" gorm:"default:1"`
+	CreatedAt  *time.Time `json:"created_at,omitempty" swaggerignore:"true"`
+	UpdatedAt  *time.Time `json:"updated_at,omitempty" swaggerignore:"true"`
+}
+
+// ValidateComponents checks the components of a combo, the choosable ones must be modifier groups of the combo
+func (p Product) ValidateComponents(components []ComboComponent) error {
+	groups := make(map[uint]bool)
+	for _, modifier := range p.Modifiers {
+		groups[modifier.ID] = true
+	}
+
+	for _, component := range components {
+		fixed := component.ProductID != nil
+		choosable := component.ModifierID != nil
+		if fixed == choosable || component.Quantity <= 0 {
+			return fmt.Errorf(ErrorComboComponent)
+		}
+		if choosable && !groups[*component.ModifierID] {
+			return fmt.Errorf(ErrorComboComponent)
+		}
+		if fixed && *component.ProductID == p.ID {
+			return fmt.Errorf(ErrorComboNested)
+		}
+	}
+
+	return nil
+}
+
+// AllocatePrice splits the price across the list prices by their share of the total, the rounding remainder goes
+// to the largest share so the allocation adds up to the price. The price is split evenly when there are no list prices.
+func AllocatePrice(price float64, listPrices []float64) []float64 {
+	allocated := make([]float64, len(listPrices))
+	if len(listPrices) == 0 {
+		return allocated
+	}
+
+	total := 0.0
+	for _, listPrice := range listPrices {
+		total += listPrice
+	}
+
+	sum, largest := 0.0, 0
+	for i, listPrice := range listPrices {
+		share := 1 / float64(len(listPrices))
+		if total > 0 {
+			share = listPrice / total
+		}
+		allocated[i] = math.Round(price * share)
+		sum += allocated[i]
+		if listPrices[i] > listPrices[largest] {
+			largest = i
+		}
+	}
+	allocated[largest] += price - sum
+
+	return allocated
+}
+
+// ModifierProduct is an option of a modifier group, the default options are selected when none is chosen. The
+// modifiers of the option product are its nested groups, like the sauce of a side.
+type ModifierProduct struct {
+	ModifierID uint       `json:"modifier_id" gorm:"primaryKey"`
+	ProductID  uint       `json:"product_id" gorm:"primaryKey"`
+	Product    *Product   `json:"product,omitempty" gorm:"foreignKey:ProductID"`
+	Default    bool       `json:"default"`
+	CreatedAt  *time.Time `json:"created_at,omitempty" swaggerignore:"true"`
+}
+
+// JoinTable sets ModifierProduct as the join table of the modifier products, to keep the default options
+func (m Modifier) JoinTable(db gorm.DB) error {
+	return db.SetupJoinTable(&Modifier{}, "Products", &ModifierProduct{})
+}
+
+// ValidateChoices checks the min, max and free choices of the modifier
+func (m Modifier) ValidateChoices() error {
+	if m.MinChoices < 0 || m.MaxChoices < 0 || m.FreeChoices < 0 {
+		return fmt.Errorf(ErrorModifierChoices)
+	}
+	if m.MaxChoices > 0 && m.MinChoices > m.MaxChoices {
+		return fmt.Errorf(ErrorModifierChoices)
+	}
+	return nil
+}
+
+// minChoices is the min choices of the modifier, a required modifier needs one choice at least
+func (m Modifier) minChoices() int {
+	if m.Required && m.MinChoices < 1 {
+		return 1
+	}
+	return m.MinChoices
+}
+
+// ModifierTree returns the preload path of the modifier groups of a product, with their options and nested
+// groups, under the given prefix
+func ModifierTree(prefix string) string {
+	levels := make([]string, MaxModifierDepth)
+	for i := range levels {
+		levels[i] = "Modifiers.Options.Product"
+	}
+	return prefix + strings.Join(levels, ".")
+}
+
+// ModifierSelection is an option chosen in a modifier group. The parent is the option product whose nested group
+// the selection belongs to, it's empty for the groups of the item product.
+type ModifierSelection struct {
+	ModifierID      *uint
+	ParentProductID *uint
+	ProductID       uint
+	Free            bool
+	Default         bool // added as the default option of a group without choices
+	Ref             int  // position of the chosen selection in the request
+}
+
+// ResolveSelections validates the modifiers chosen for the product against its modifier groups and nested groups.
+// The selections without group are matched to the group of their parent offering them, the default options
+// are added to the groups without choices and the choices within the free choices of a group are marked free.
+func (p Product) ResolveSelections(selections []ModifierSelection) ([]ModifierSelection, error) {
+	pending := make([]*ModifierSelection, len(selections))
+	for i := range selections {
+		selection := selections[i]
+		pending[i] = &selection
+	}
+
+	resolved := make([]ModifierSelection, 0)
+	errs := p.resolveGroups(nil, 1, pending, &resolved)
+
+	for _, selection := range pending {
+		if selection != nil {
+			errs += fmt.Sprintf(ErrorSelectionNotAllowed, selection.ProductID, p.ID)
+		}
+	}
+
+	if errs != "" {
+		return nil, fmt.Errorf(errs)
+	}
+
+	return resolved, nil
+}
+
+func (p Product) resolveGroups(parentID *uint, depth int, pending []*ModifierSelection, resolved *[]ModifierSelection) string {
+	errs := ""
+	for _, group := range p.Modifiers {
+		group := group
+		options := make(map[uint]ModifierProduct)
+		for _, option := range group.Options {
+			options[option.ProductID] = option
+		}
+
+		chosen := make([]ModifierSelection, 0)
+		for i, selection := range pending {
+			if selection == nil || !sameParent(selection.ParentProductID, parentID) {
+				continue
+			}
+			if selection.ModifierID != nil && *selection.ModifierID != group.ID {
+				continue
+			}
+			if _, ok := options[selection.ProductID]; !ok {
+				continue
+			}
+
+			chosen = append(chosen, *selection)
+			pending[i] = nil
+		}
+
+		if len(chosen) == 0 {
+			for _, option := range group.Options {
+				if option.Default {
+					chosen = append(chosen, ModifierSelection{ProductID: option.ProductID, Default: true})
+				}
+			}
+		}
+
+		if len(chosen) < group.minChoices() {
+			errs += fmt.Sprintf(ErrorSelectionMinChoices, group.Name, group.minChoices())
+		}
+		if group.MaxChoices > 0 && len(chosen) > group.MaxChoices {
+			errs += fmt.Sprintf(ErrorSelectionMaxChoices, group.Name, group.MaxChoices)
+		}
+
+		for i, selection := range chosen {
+			selection.ModifierID = &group.ID
+			selection.ParentProductID = parentID
+			selection.Free = i < group.FreeChoices
+			*resolved = append(*resolved, selection)
+
+			option := options[selection.ProductID]
+			if option.Product == nil || len(option.Product.Modifiers) == 0 {
+				continue
+			}
+
+			// the options below the max depth aren't loaded, their selections are not allowed
+			if depth >= MaxModifierDepth {
+				continue
+			}
+
+			optionID := selection.ProductID
+			errs += option.Product.resolveGroups(&optionID, depth+1, pending, resolved)
+		}
+	}
+
+	return errs
+}
+
+func sameParent(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
 }
 
 type Overrider struct {
@@ -119,6 +365,9 @@ type Repository interface {
 	ModifierAddProduct(product *Product, modifier *Modifier) (*Modifier, error)
 	ModifierRemoveProduct(product *Product, modifier *Modifier) (*Modifier, error)
 	ModifierUpdate(*Modifier) (*Modifier, error)
+	ModifierSetDefault(modifierID, productID string, isDefault bool) (*Modifier, error)
+	GetWithModifierTree(productIDs []string) ([]Product, error)
+	SetComponents(comboID uint, components []ComboComponent) (*Product, error)
 
 	OverriderCreate(*Overrider) (*Overrider, error)
 	OverriderCreateAll([]Overrider) error
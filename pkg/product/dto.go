@@ -19,6 +19,10 @@ type ModifierDTO struct {
 	Image       string   `json:"image"`
 	ApplyPrice  float64  `json:"apply_price" gorm:"precision:18;scale:2"`
 	Category    Category `json:"category"`
+	Required    bool     `json:"required"`
+	MinChoices  int      `json:"min_choices"`
+	MaxChoices  int      `json:"max_choices"`
+	FreeChoices int      `json:"free_choices"`
 }
 
 func (dto ModifierDTO) ToModifier() Modifier {
@@ -28,5 +32,13 @@ func (dto ModifierDTO) ToModifier() Modifier {
 		Image:       dto.Image,
 		ApplyPrice:  dto.ApplyPrice,
 		Category:    dto.Category,
+		Required:    dto.Required,
+		MinChoices:  dto.MinChoices,
+		MaxChoices:  dto.MaxChoices,
+		FreeChoices: dto.FreeChoices,
 	}
 }
+
+type RequestComponents struct {
+	Components []ComboComponent `json:"components"`
+}
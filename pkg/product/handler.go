@@ -307,6 +307,45 @@ func (h *Handler) UpdateAllOverriders(c *gin.Context) {
 	c.JSON(http.StatusOK, shared.SuccessResponse(err))
 }
 
+// SetComponents to handle a request to set the components of a combo
+// @Tags Product
+// @Summary To set the components of a combo
+// @Description To replace the fixed and choosable components of a combo, no components means it's not a combo
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "product id"
+// @Param request body RequestComponents true "request"
+// @Success 200 {object} object{status=string,data=Product}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /product/{id}/components [put]
+func (h *Handler) SetComponents(c *gin.Context) {
+	productID := c.Param("id")
+
+	var request RequestComponents
+	if err := c.ShouldBindJSON(&request); err != nil {
+		shared.LogWarn("warning binding request", LogHandler, "SetComponents", err, productID)
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorProductBadRequest))
+		return
+	}
+
+	product, err := h.service.SetComponents(productID, request.Components)
+	if err != nil && (err.Error() == ErrorComboComponent || err.Error() == ErrorComboNested) {
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	if err != nil {
+		shared.LogError("error setting combo components", LogHandler, "SetComponents", err, productID, request)
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(ErrorComboSettingComponents))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(product))
+}
+
 // GetCategories to handle a request to get categories for a product
 // @Tags Product
 // @Summary To get categories for a product
@@ -394,6 +433,11 @@ func (h *Handler) ModifierCreate(c *gin.Context) {
 	}
 
 	modifier, err := h.service.ModifierCreate(&body)
+	if err != nil && err.Error() == ErrorModifierChoices {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(ErrorModifierChoices))
+		return
+	}
+
 	if err != nil {
 		shared.LogError("error creating modifier", LogHandler, "CreateModifier", err, modifier)
 		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(ErrorModifierCreation))
@@ -490,6 +534,11 @@ func (h *Handler) ModifierUpdate(c *gin.Context) {
 	modifier := body.ToModifier()
 	modifier.ID = uint(modifierID)
 	modifierUpdated, err := h.service.ModifierUpdate(&modifier)
+	if err != nil && err.Error() == ErrorModifierChoices {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(ErrorModifierChoices))
+		return
+	}
+
 	if err != nil {
 		shared.LogError("error updating modifier", LogHandler, "ModifierUpdate", err, modifier)
 		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(ErrorModifierUpdate))
@@ -499,6 +548,47 @@ func (h *Handler) ModifierUpdate(c *gin.Context) {
 	c.JSON(http.StatusOK, shared.SuccessResponse(modifierUpdated))
 }
 
+// ModifierSetDefault to handle a request to set if an option of a modifier is selected by default
+// @Tags Modifiers
+// @Summary To set a default option of a modifier
+// @Description To set if an option of a modifier is selected when the customer chooses none
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "modifier id"
+// @Param productID path string true "product id"
+// @Param default query bool true "is default"
+// @Success 200 {object} object{status=string,data=Modifier}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /modifier/{id}/product/{productID}/default [patch]
+func (h *Handler) ModifierSetDefault(c *gin.Context) {
+	modifierID := c.Param("id")
+	productID := c.Param("productID")
+
+	isDefault, err := strconv.ParseBool(c.Query("default"))
+	if err != nil {
+		shared.LogWarn("warning parsing default", LogHandler, "ModifierSetDefault", err, c.Query("default"))
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorModifierBadRequest))
+		return
+	}
+
+	modifier, err := h.service.ModifierSetDefault(modifierID, productID, isDefault)
+	if err != nil && err.Error() == ErrorModifierDefaults {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(ErrorModifierDefaults))
+		return
+	}
+
+	if err != nil {
+		shared.LogError("error setting modifier default", LogHandler, "ModifierSetDefault", err, modifierID, productID)
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(ErrorModifierSettingDefault))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(modifier))
+}
+
 // Overriders
 
 // OverriderFind to handle a request to find all overriders
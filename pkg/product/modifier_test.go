@@ -0,0 +1,107 @@
+package product_test
+
+import (
+	"fmt"
+
+	"github.com/BacoFoods/menu/pkg/product"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Modifier selections", func() {
+	id := func(value uint) *uint { return &value }
+
+	// burger has a required side group with fries by default and an extras group with one free choice, the
+	// fries have a sauce group of their own
+	sauces := product.Modifier{ID: 30, Name: "Sauce", MaxChoices: 1, Options: []product.ModifierProduct{
+		{ProductID: 300},
+		{ProductID: 301},
+	}}
+	fries := &product.Product{ID: 100, Modifiers: []product.Modifier{sauces}}
+	burger := product.Product{ID: 1, Modifiers: []product.Modifier{
+		{ID: 10, Name: "Side", Required: true, MaxChoices: 1, Options: []product.ModifierProduct{
+			{ProductID: 100, Product: fries, Default: true},
+			{ProductID: 101},
+		}},
+		{ID: 20, Name: "Extras", MaxChoices: 3, FreeChoices: 1, Options: []product.ModifierProduct{
+			{ProductID: 200},
+			{ProductID: 201},
+			{ProductID: 202},
+			{ProductID: 203},
+		}},
+	}}
+
+	It("Adds the default options of the groups without choices", func() {
+		selections, err := burger.ResolveSelections(nil)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(selections).To(HaveLen(1))
+		Expect(selections[0].ProductID).To(Equal(uint(100)))
+		Expect(*selections[0].ModifierID).To(Equal(uint(10)))
+		Expect(selections[0].Default).To(BeTrue())
+	})
+
+	It("Matches the selections without group and marks the free choices", func() {
+		selections, err := burger.ResolveSelections([]product.ModifierSelection{
+			{ProductID: 101, Ref: 1},
+			{ProductID: 200, Ref: 2},
+			{ModifierID: id(20), ProductID: 201, Ref: 3},
+		})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(selections).To(HaveLen(3))
+		Expect(*selections[0].ModifierID).To(Equal(uint(10)))
+		Expect(selections[0].Default).To(BeFalse())
+		Expect(selections[1].Ref).To(Equal(2))
+		Expect(selections[1].Free).To(BeTrue())
+		Expect(selections[2].Free).To(BeFalse())
+	})
+
+	It("Resolves the nested groups of the chosen options", func() {
+		selections, err := burger.ResolveSelections([]product.ModifierSelection{
+			{ProductID: 100},
+			{ParentProductID: id(100), ProductID: 301},
+		})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(selections).To(HaveLen(2))
+		Expect(*selections[1].ModifierID).To(Equal(uint(30)))
+		Expect(*selections[1].ParentProductID).To(Equal(uint(100)))
+	})
+
+	It("Rejects the choices out of the group limits", func() {
+		_, err := burger.ResolveSelections([]product.ModifierSelection{
+			{ProductID: 100},
+			{ProductID: 101},
+			{ProductID: 200}, {ProductID: 201}, {ProductID: 202}, {ProductID: 203},
+		})
+
+		Expect(err).To(MatchError(fmt.Sprintf(product.ErrorSelectionMaxChoices, "Side", 1) +
+			fmt.Sprintf(product.ErrorSelectionMaxChoices, "Extras", 3)))
+	})
+
+	It("Rejects a required group without choices nor default", func() {
+		salad := product.Product{ID: 2, Modifiers: []product.Modifier{
+			{ID: 40, Name: "Dressing", Required: true, Options: []product.ModifierProduct{{ProductID: 400}}},
+		}}
+
+		_, err := salad.ResolveSelections(nil)
+		Expect(err).To(MatchError(fmt.Sprintf(product.ErrorSelectionMinChoices, "Dressing", 1)))
+	})
+
+	It("Rejects the options not offered by the product", func() {
+		_, err := burger.ResolveSelections([]product.ModifierSelection{
+			{ProductID: 999},
+			{ParentProductID: id(101), ProductID: 300},
+		})
+
+		Expect(err).To(MatchError(fmt.Sprintf(product.ErrorSelectionNotAllowed, 999, 1) +
+			fmt.Sprintf(product.ErrorSelectionNotAllowed, 300, 1)))
+	})
+
+	It("Validates the group choices", func() {
+		Expect(product.Modifier{MinChoices: 1, MaxChoices: 2, FreeChoices: 1}.ValidateChoices()).To(Succeed())
+		Expect(product.Modifier{MinChoices: 3, MaxChoices: 2}.ValidateChoices()).To(MatchError(product.ErrorModifierChoices))
+		Expect(product.Modifier{FreeChoices: -1}.ValidateChoices()).To(MatchError(product.ErrorModifierChoices))
+	})
+})
@@ -22,12 +22,14 @@ func (r Routes) RegisterRoutes(private *shared.CustomRoutes) {
 	private.GET("/product/:id/overrider", r.handler.GetOverridersByField)
 	private.PATCH("/product/:id/overrider/update-all", r.handler.UpdateAllOverriders)
 	private.GET("/product/:id/category", r.handler.GetCategories)
+	private.PUT("/product/:id/components", r.handler.SetComponents)
 
 	// Modifiers
 	private.GET("/modifier", r.handler.ModifierFind)
 	private.POST("/modifier", r.handler.ModifierCreate)
 	private.POST("/modifier/:id/product/:productID", r.handler.ModifierAddProduct)
 	private.PATCH("/modifier/:id", r.handler.ModifierUpdate)
+	private.PATCH("/modifier/:id/product/:productID/default", r.handler.ModifierSetDefault)
 	private.DELETE("/modifier/:id/product/:productID", r.handler.ModifierRemoveProduct)
 
 	// Overriders
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/BacoFoods/menu/pkg/availability"
 	channels "github.com/BacoFoods/menu/pkg/channel"
+	"github.com/BacoFoods/menu/pkg/menucache"
 	"github.com/BacoFoods/menu/pkg/shared"
 )
 
@@ -18,12 +19,14 @@ type Service interface {
 	GetOverriders(productID, field string) ([]OverriderDTO, error)
 	UpdateAllOverriders(productID, field string, value any) error
 	GetCategory(productID string) ([]CategoryDTO, error)
+	SetComponents(productID string, components []ComboComponent) (*Product, error)
 
 	ModifierFind(map[string]string) ([]Modifier, error)
 	ModifierCreate(*Modifier) (*Modifier, error)
 	ModifierAddProduct(productID, modifierID string) (*Modifier, error)
 	ModifierRemoveProduct(productID, modifierID string) (*Modifier, error)
 	ModifierUpdate(*Modifier) (*Modifier, error)
+	ModifierSetDefault(modifierID, productID string, isDefault bool) (*Modifier, error)
 
 	OverriderFind(map[string]string) ([]Overrider, error)
 	OverriderGet(string) (*Overrider, error)
@@ -35,10 +38,26 @@ type Service interface {
 type service struct {
 	repository Repository
 	channel    channels.Repository
+	cache      menucache.Invalidator
 }
 
-func NewService(repository Repository, channel channels.Repository) service {
-	return service{repository, channel}
+func NewService(repository Repository, channel channels.Repository, cache menucache.Invalidator) service {
+	return service{repository, channel, cache}
+}
+
+// invalidate drops the cached menus with the changed products and modifiers
+func (s service) invalidate(tags ...menucache.Tag) {
+	if s.cache == nil || len(tags) == 0 {
+		return
+	}
+	s.cache.Invalidate(tags...)
+}
+
+func overriderTags(overrider *Overrider) []menucache.Tag {
+	if overrider == nil || overrider.ProductID == nil {
+		return nil
+	}
+	return []menucache.Tag{menucache.ProductTag(*overrider.ProductID)}
 }
 
 // Product
@@ -91,11 +110,23 @@ func (s service) Create(product *Product) (*Product, error) {
 }
 
 func (s service) Update(product *Product) (*Product, error) {
-	return s.repository.Update(product)
+	productDB, err := s.repository.Update(product)
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidate(menucache.ProductTag(productDB.ID))
+	return productDB, nil
 }
 
 func (s service) Delete(productID string) (*Product, error) {
-	return s.repository.Delete(productID)
+	product, err := s.repository.Delete(productID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidate(menucache.ProductTag(product.ID))
+	return product, nil
 }
 
 func (s service) AddModifier(productID, modifierID string) (*Product, error) {
@@ -109,7 +140,13 @@ func (s service) AddModifier(productID, modifierID string) (*Product, error) {
 		return nil, err
 	}
 
-	return s.repository.AddModifier(product, modifier)
+	productDB, err := s.repository.AddModifier(product, modifier)
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidate(menucache.ProductTag(product.ID))
+	return productDB, nil
 }
 
 func (s service) RemoveModifier(productID, modifierID string) (*Product, error) {
@@ -123,7 +160,13 @@ func (s service) RemoveModifier(productID, modifierID string) (*Product, error)
 		return nil, err
 	}
 
-	return s.repository.RemoveModifier(product, modifier)
+	productDB, err := s.repository.RemoveModifier(product, modifier)
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidate(menucache.ProductTag(product.ID), menucache.ModifierTag(modifier.ID))
+	return productDB, nil
 }
 
 func (s service) GetOverriders(productID, field string) ([]OverriderDTO, error) {
@@ -134,6 +177,51 @@ func (s service) GetCategory(productID string) ([]CategoryDTO, error) {
 	return s.repository.GetCategory(productID)
 }
 
+// SetComponents replaces the components of a combo, the fixed components can't be combos themselves and the
+// choosable ones must be modifier groups of the combo
+func (s service) SetComponents(productID string, components []ComboComponent) (*Product, error) {
+	combo, err := s.repository.Get(productID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := combo.ValidateComponents(components); err != nil {
+		return nil, err
+	}
+
+	fixedIDs := make([]string, 0)
+	for _, component := range components {
+		if component.ProductID != nil {
+			fixedIDs = append(fixedIDs, fmt.Sprint(*component.ProductID))
+		}
+	}
+
+	if len(fixedIDs) > 0 {
+		fixed, err := s.repository.GetAsMapByIDs(fixedIDs)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, productID := range fixedIDs {
+			component, ok := fixed[productID]
+			if !ok {
+				return nil, fmt.Errorf(ErrorComboComponent)
+			}
+			if component.Combo {
+				return nil, fmt.Errorf(ErrorComboNested)
+			}
+		}
+	}
+
+	comboDB, err := s.repository.SetComponents(combo.ID, components)
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidate(menucache.ProductTag(combo.ID))
+	return comboDB, nil
+}
+
 // Modifier
 
 func (s service) ModifierFind(filter map[string]string) ([]Modifier, error) {
@@ -141,6 +229,9 @@ func (s service) ModifierFind(filter map[string]string) ([]Modifier, error) {
 }
 
 func (s service) ModifierCreate(modifier *Modifier) (*Modifier, error) {
+	if err := modifier.ValidateChoices(); err != nil {
+		return nil, err
+	}
 	return s.repository.ModifierCreate(modifier)
 }
 
@@ -155,7 +246,13 @@ func (s service) ModifierAddProduct(productID, modifierID string) (*Modifier, er
 		return nil, err
 	}
 
-	return s.repository.ModifierAddProduct(product, modifier)
+	modifierDB, err := s.repository.ModifierAddProduct(product, modifier)
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidate(menucache.ModifierTag(modifier.ID))
+	return modifierDB, nil
 }
 
 func (s service) ModifierRemoveProduct(productID, modifierID string) (*Modifier, error) {
@@ -169,7 +266,13 @@ func (s service) ModifierRemoveProduct(productID, modifierID string) (*Modifier,
 		return nil, err
 	}
 
-	return s.repository.ModifierRemoveProduct(product, modifier)
+	modifierDB, err := s.repository.ModifierRemoveProduct(product, modifier)
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidate(menucache.ModifierTag(modifier.ID), menucache.ProductTag(product.ID))
+	return modifierDB, nil
 }
 
 func (s service) UpdateAllOverriders(productID, field string, value any) error {
@@ -178,11 +281,58 @@ func (s service) UpdateAllOverriders(productID, field string, value any) error {
 		return err
 	}
 
-	return s.repository.UpdateOverriders(overridersIDs, field, value)
+	if err := s.repository.UpdateOverriders(overridersIDs, field, value); err != nil {
+		return err
+	}
+
+	if product, err := s.repository.Get(productID); err == nil {
+		s.invalidate(menucache.ProductTag(product.ID))
+	}
+	return nil
 }
 
 func (s service) ModifierUpdate(modifier *Modifier) (*Modifier, error) {
-	return s.repository.ModifierUpdate(modifier)
+	if err := modifier.ValidateChoices(); err != nil {
+		return nil, err
+	}
+	modifierDB, err := s.repository.ModifierUpdate(modifier)
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidate(menucache.ModifierTag(modifierDB.ID))
+	return modifierDB, nil
+}
+
+// ModifierSetDefault sets if an option of a modifier is selected when the customer chooses none, the default
+// options can't be more than the max choices of the modifier
+func (s service) ModifierSetDefault(modifierID, productID string, isDefault bool) (*Modifier, error) {
+	modifier, err := s.repository.ModifierGet(modifierID)
+	if err != nil {
+		return nil, err
+	}
+
+	defaults := 0
+	for _, option := range modifier.Options {
+		if option.Default && fmt.Sprint(option.ProductID) != productID {
+			defaults++
+		}
+	}
+	if isDefault {
+		defaults++
+	}
+
+	if modifier.MaxChoices > 0 && defaults > modifier.MaxChoices {
+		return nil, fmt.Errorf(ErrorModifierDefaults)
+	}
+
+	modifierDB, err := s.repository.ModifierSetDefault(modifierID, productID, isDefault)
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidate(menucache.ModifierTag(modifier.ID))
+	return modifierDB, nil
 }
 
 // Overrider
@@ -196,13 +346,31 @@ func (s service) OverriderGet(overriderID string) (*Overrider, error) {
 }
 
 func (s service) OverriderCreate(overrider *Overrider) (*Overrider, error) {
-	return s.repository.OverriderCreate(overrider)
+	overriderDB, err := s.repository.OverriderCreate(overrider)
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidate(overriderTags(overriderDB)...)
+	return overriderDB, nil
 }
 
 func (s service) OverriderUpdate(overrider *Overrider) (*Overrider, error) {
-	return s.repository.OverriderUpdate(overrider)
+	overriderDB, err := s.repository.OverriderUpdate(overrider)
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidate(overriderTags(overriderDB)...)
+	return overriderDB, nil
 }
 
 func (s service) OverriderDelete(overriderID string) (*Overrider, error) {
-	return s.repository.OverriderDelete(overriderID)
+	overrider, err := s.repository.OverriderDelete(overriderID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidate(overriderTags(overrider)...)
+	return overrider, nil
 }
@@ -0,0 +1,56 @@
+package realtime
+
+import (
+	"fmt"
+
+	"github.com/BacoFoods/menu/pkg/shared"
+	"gorm.io/gorm"
+)
+
+const LogDBRepository = "pkg/realtime/db_repository"
+
+type DBRepository struct {
+	db *gorm.DB
+}
+
+func NewDBRepository(db *gorm.DB) *DBRepository {
+	return &DBRepository{db: db}
+}
+
+// StoreOwner method for get the brand of a store in database
+func (r *DBRepository) StoreOwner(storeID uint) (*Owner, error) {
+	return r.owner("StoreOwner", storeID, r.db.Table("stores AS s").
+		Select("s.id AS store_id, s.brand_id AS brand_id").
+		Where("s.id = ? AND s.deleted_at IS NULL", storeID))
+}
+
+// TableOwner method for get the store and brand of a table in database
+func (r *DBRepository) TableOwner(tableID uint) (*Owner, error) {
+	return r.owner("TableOwner", tableID, r.db.Table("tables AS t").
+		Select("s.id AS store_id, s.brand_id AS brand_id").
+		Joins("JOIN stores s ON s.id = t.store_id").
+		Where("t.id = ? AND t.deleted_at IS NULL", tableID))
+}
+
+// OrderOwner method for get the store and brand of an order in database
+func (r *DBRepository) OrderOwner(orderID uint) (*Owner, error) {
+	return r.owner("OrderOwner", orderID, r.db.Table("orders AS o").
+		Select("o.store_id AS store_id, o.brand_id AS brand_id").
+		Where("o.id = ? AND o.deleted_at IS NULL", orderID))
+}
+
+func (r *DBRepository) owner(function string, id uint, query *gorm.DB) (*Owner, error) {
+	var owners []Owner
+	if err := query.Limit(1).Scan(&owners).Error; err != nil {
+		shared.LogError("error getting topic owner", LogDBRepository, function, err, id)
+		return nil, err
+	}
+
+	if len(owners) == 0 {
+		err := fmt.Errorf(ErrorRealtimeOwner)
+		shared.LogWarn("topic owner not found", LogDBRepository, function, err, id)
+		return nil, err
+	}
+
+	return &owners[0], nil
+}
@@ -0,0 +1,108 @@
+package realtime
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	ErrorRealtimeBadRequest  = "error bad request"
+	ErrorRealtimeSubscribing = "error subscribing to events"
+	ErrorRealtimePublishing  = "error publishing event"
+	ErrorRealtimeOwner       = "error getting the owner of the topic"
+	ErrorRealtimeForbidden   = "the topic doesn't belong to the account"
+	ErrorRealtimeNoBrand     = "the account token has no brand to subscribe with"
+
+	EventOrderCreated       = "order.created"
+	EventOrderProductsAdded = "order.products_added"
+	EventOrderUpdated       = "order.updated"
+	EventOrderStatusChanged = "order.status_changed"
+	EventOrderTableChanged  = "order.table_changed"
+	EventOrderInvoiced      = "order.invoiced"
+	EventOrderPaymentLanded = "order.payment_landed"
+	EventTableReleased      = "table.released"
+	EventTableStateChanged  = "table.state_changed"
+	EventMenuStockChanged   = "menu.stock_changed"
+	EventMenuStockRestored  = "menu.stock_restored"
+	EventMenuPublished      = "menu.published"
+
+	topicPrefix     = "menu:realtime"
+	menuEventPrefix = "menu."
+)
+
+// Event is the message pushed to subscribers of a store, table or order.
+type Event struct {
+	Type      string    `json:"type"`
+	StoreID   *uint     `json:"store_id,omitempty"`
+	ChannelID *uint     `json:"channel_id,omitempty"`
+	TableID   *uint     `json:"table_id,omitempty"`
+	OrderID   *uint     `json:"order_id,omitempty"`
+	Data      any       `json:"data,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Topics returns the channels where the event must be delivered.
+func (e Event) Topics() []string {
+	topics := make([]string, 0, 3)
+	if e.StoreID != nil && *e.StoreID != 0 {
+		topics = append(topics, StoreTopic(*e.StoreID))
+	}
+	// menu events are also delivered to the public menus of the store or channel
+	if strings.HasPrefix(e.Type, menuEventPrefix) {
+		if e.StoreID != nil && *e.StoreID != 0 {
+			topics = append(topics, MenuTopic("store", *e.StoreID))
+		}
+		if e.ChannelID != nil && *e.ChannelID != 0 {
+			topics = append(topics, MenuTopic("channel", *e.ChannelID))
+		}
+	}
+	if e.TableID != nil && *e.TableID != 0 {
+		topics = append(topics, TableTopic(*e.TableID))
+	}
+	if e.OrderID != nil && *e.OrderID != 0 {
+		topics = append(topics, OrderTopic(*e.OrderID))
+	}
+	return topics
+}
+
+func StoreTopic(storeID uint) string {
+	return fmt.Sprintf("%s:store:%d", topicPrefix, storeID)
+}
+
+func TableTopic(tableID uint) string {
+	return fmt.Sprintf("%s:table:%d", topicPrefix, tableID)
+}
+
+func OrderTopic(orderID uint) string {
+	return fmt.Sprintf("%s:order:%d", topicPrefix, orderID)
+}
+
+// MenuTopic is the public channel of the menu changes of a store or channel place
+func MenuTopic(place string, placeID uint) string {
+	return fmt.Sprintf("%s:menu:%s:%d", topicPrefix, place, placeID)
+}
+
+// Publisher sends events to every api replica.
+type Publisher interface {
+	Publish(event Event) error
+}
+
+// Subscriber listens events on the given topics until the context is done.
+type Subscriber interface {
+	Subscribe(ctx context.Context, topics ...string) (<-chan Event, error)
+}
+
+// Owner is the store and brand a store, table or order topic belongs to
+type Owner struct {
+	StoreID uint
+	BrandID uint
+}
+
+// Repository finds the owners of the topics, the subscriptions are only accepted from accounts of the owner.
+type Repository interface {
+	StoreOwner(storeID uint) (*Owner, error)
+	TableOwner(tableID uint) (*Owner, error)
+	OrderOwner(orderID uint) (*Owner, error)
+}
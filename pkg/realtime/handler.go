@@ -0,0 +1,185 @@
+package realtime
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/BacoFoods/menu/pkg/shared"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	LogHandler = "pkg/realtime/handler"
+
+	heartbeatInterval = 25 * time.Second
+)
+
+type Handler struct {
+	subscriber Subscriber
+	repository Repository
+}
+
+func NewHandler(subscriber Subscriber, repository Repository) *Handler {
+	return &Handler{subscriber, repository}
+}
+
+// Stream writes the events of the given topics as server sent events until the client disconnects.
+func Stream(c *gin.Context, subscriber Subscriber, topics ...string) {
+	events, err := subscriber.Subscribe(c.Request.Context(), topics...)
+	if err != nil {
+		shared.LogError("error subscribing", LogHandler, "Stream", err, topics)
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(ErrorRealtimeSubscribing))
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			c.SSEvent(event.Type, event)
+			return true
+		case <-heartbeat.C:
+			c.SSEvent("ping", time.Now().Unix())
+			return true
+		}
+	})
+}
+
+func paramID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorRealtimeBadRequest))
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// authorize checks the topic owner is the brand of the account, and its store when the account is bound to one.
+// Only the account tokens carry a brand: the Google logins have no account claims, so they can't subscribe.
+func authorize(c *gin.Context, owner *Owner, err error) bool {
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(ErrorRealtimeOwner))
+		return false
+	}
+
+	brandID, hasBrand := claimID(c, "brand_id")
+	if !hasBrand {
+		shared.LogWarn("subscription without brand", LogHandler, "authorize", fmt.Errorf(ErrorRealtimeNoBrand),
+			c.FullPath(), c.Param("id"))
+		c.JSON(http.StatusForbidden, shared.ErrorResponse(ErrorRealtimeNoBrand))
+		return false
+	}
+
+	storeID, hasStore := claimID(c, "store_id")
+	if brandID != owner.BrandID || (hasStore && storeID != owner.StoreID) {
+		shared.LogWarn("forbidden subscription", LogHandler, "authorize", fmt.Errorf(ErrorRealtimeForbidden),
+			c.FullPath(), c.Param("id"), c.GetString("account_id"))
+		c.JSON(http.StatusForbidden, shared.ErrorResponse(ErrorRealtimeForbidden))
+		return false
+	}
+
+	return true
+}
+
+// claimID returns an id claim of the account token, false when the token has none
+func claimID(c *gin.Context, key string) (uint, bool) {
+	id, err := strconv.ParseFloat(c.GetString(key), 64)
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// Store to handle a subscription to store events
+// @Tags Realtime
+// @Summary To subscribe to store events
+// @Description Server sent events stream with the order and table updates of a store, for the accounts of its brand. The Google logins have no brand and can't subscribe.
+// @Param id path string true "store id"
+// @Produce text/event-stream
+// @Security ApiKeyAuth
+// @Success 200 {object} Event
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Failure 403 {object} shared.Response
+// @Router /realtime/store/{id} [get]
+func (h *Handler) Store(c *gin.Context) {
+	storeID, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	owner, err := h.repository.StoreOwner(storeID)
+	if !authorize(c, owner, err) {
+		return
+	}
+
+	Stream(c, h.subscriber, StoreTopic(storeID))
+}
+
+// Table to handle a subscription to table events
+// @Tags Realtime
+// @Summary To subscribe to table events
+// @Description Server sent events stream with the updates of a table and its orders, for the accounts of its brand. The Google logins have no brand and can't subscribe.
+// @Param id path string true "table id"
+// @Produce text/event-stream
+// @Security ApiKeyAuth
+// @Success 200 {object} Event
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Failure 403 {object} shared.Response
+// @Router /realtime/table/{id} [get]
+func (h *Handler) Table(c *gin.Context) {
+	tableID, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	owner, err := h.repository.TableOwner(tableID)
+	if !authorize(c, owner, err) {
+		return
+	}
+
+	Stream(c, h.subscriber, TableTopic(tableID))
+}
+
+// Order to handle a subscription to order events
+// @Tags Realtime
+// @Summary To subscribe to order events
+// @Description Server sent events stream with the updates of an order, for the accounts of its brand. The Google logins have no brand and can't subscribe.
+// @Param id path string true "order id"
+// @Produce text/event-stream
+// @Security ApiKeyAuth
+// @Success 200 {object} Event
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Failure 403 {object} shared.Response
+// @Router /realtime/order/{id} [get]
+func (h *Handler) Order(c *gin.Context) {
+	orderID, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	owner, err := h.repository.OrderOwner(orderID)
+	if !authorize(c, owner, err) {
+		return
+	}
+
+	Stream(c, h.subscriber, OrderTopic(orderID))
+}
@@ -0,0 +1,104 @@
+package realtime_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/BacoFoods/menu/pkg/realtime"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type owners map[string]realtime.Owner
+
+func (o owners) owner(kind string, id uint) (*realtime.Owner, error) {
+	owner, ok := o[fmt.Sprintf("%s:%d", kind, id)]
+	if !ok {
+		return nil, fmt.Errorf(realtime.ErrorRealtimeOwner)
+	}
+	return &owner, nil
+}
+
+func (o owners) StoreOwner(id uint) (*realtime.Owner, error) { return o.owner("store", id) }
+func (o owners) TableOwner(id uint) (*realtime.Owner, error) { return o.owner("table", id) }
+func (o owners) OrderOwner(id uint) (*realtime.Owner, error) { return o.owner("order", id) }
+
+// closedSubscriber ends the stream right away so the authorized requests return
+type closedSubscriber struct{}
+
+func (closedSubscriber) Subscribe(_ context.Context, _ ...string) (<-chan realtime.Event, error) {
+	events := make(chan realtime.Event)
+	close(events)
+	return events, nil
+}
+
+// recorder adds the close notification the gin streams wait on
+type recorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (recorder) CloseNotify() <-chan bool {
+	return make(chan bool)
+}
+
+var _ = Describe("Handler", func() {
+	var router *gin.Engine
+
+	BeforeEach(func() {
+		gin.SetMode(gin.TestMode)
+		handler := realtime.NewHandler(closedSubscriber{}, owners{
+			"store:1": {StoreID: 1, BrandID: 10},
+			"table:5": {StoreID: 1, BrandID: 10},
+			"order:7": {StoreID: 2, BrandID: 20},
+		})
+
+		router = gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Set("brand_id", c.GetHeader("X-Brand"))
+			c.Set("store_id", c.GetHeader("X-Store"))
+		})
+		router.GET("/realtime/store/:id", handler.Store)
+		router.GET("/realtime/table/:id", handler.Table)
+		router.GET("/realtime/order/:id", handler.Order)
+	})
+
+	subscribe := func(path, brand, store string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-Brand", brand)
+		req.Header.Set("X-Store", store)
+		w := recorder{httptest.NewRecorder()}
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	It("Subscribes the accounts of the brand", func() {
+		Expect(subscribe("/realtime/store/1", "10", "<nil>")).To(Equal(http.StatusOK))
+		Expect(subscribe("/realtime/table/5", "10", "1")).To(Equal(http.StatusOK))
+	})
+
+	It("Forbids the accounts of other brands", func() {
+		Expect(subscribe("/realtime/store/1", "20", "<nil>")).To(Equal(http.StatusForbidden))
+		Expect(subscribe("/realtime/order/7", "10", "<nil>")).To(Equal(http.StatusForbidden))
+	})
+
+	It("Forbids the tokens without brand, like the Google logins", func() {
+		req := httptest.NewRequest(http.MethodGet, "/realtime/order/7", nil)
+		req.Header.Set("X-Brand", "<nil>")
+		w := recorder{httptest.NewRecorder()}
+		router.ServeHTTP(w, req)
+
+		Expect(w.Code).To(Equal(http.StatusForbidden))
+		Expect(w.Body.String()).To(ContainSubstring(realtime.ErrorRealtimeNoBrand))
+	})
+
+	It("Forbids the accounts bound to another store of the brand", func() {
+		Expect(subscribe("/realtime/table/5", "10", "3")).To(Equal(http.StatusForbidden))
+	})
+
+	It("Rejects unknown topics", func() {
+		Expect(subscribe("/realtime/store/9", "10", "<nil>")).To(Equal(http.StatusUnprocessableEntity))
+	})
+})
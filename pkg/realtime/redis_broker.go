@@ -0,0 +1,101 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/BacoFoods/menu/pkg/shared"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	LogRedisBroker = "pkg/realtime/redis_broker"
+
+	publishTimeout = 1 * time.Second
+)
+
+// RedisBroker fans out events through redis pub/sub, so a client connected
+// to any replica receives the events published by the others.
+type RedisBroker struct {
+	client *redis.Client
+}
+
+func NewRedisBroker(client *redis.Client) *RedisBroker {
+	return &RedisBroker{client: client}
+}
+
+// Publish sends the event to the store, table and order topics it belongs to.
+func (b *RedisBroker) Publish(event Event) error {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		shared.LogError("error marshalling event", LogRedisBroker, "Publish", err, event.Type)
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+
+	pipe := b.client.Pipeline()
+	for _, topic := range event.Topics() {
+		pipe.Publish(ctx, topic, payload)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		shared.LogError("error publishing event", LogRedisBroker, "Publish", err, event.Type)
+		return err
+	}
+
+	return nil
+}
+
+// Subscribe returns a channel with the events of the given topics, it is closed when ctx is done.
+func (b *RedisBroker) Subscribe(ctx context.Context, topics ...string) (<-chan Event, error) {
+	sub := b.client.Subscribe(ctx, topics...)
+
+	// Wait for the subscription confirmation so errors are reported to the caller
+	if _, err := sub.Receive(ctx); err != nil {
+		shared.LogError("error subscribing to topics", LogRedisBroker, "Subscribe", err, topics)
+		_ = sub.Close()
+		return nil, err
+	}
+
+	events := make(chan Event)
+	go func() {
+		defer close(events)
+		defer sub.Close()
+
+		messages := sub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+
+				var event Event
+				if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+					shared.LogWarn("error unmarshalling event", LogRedisBroker, "Subscribe", err, msg.Channel)
+					continue
+				}
+
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+var _ Publisher = &RedisBroker{}
+var _ Subscriber = &RedisBroker{}
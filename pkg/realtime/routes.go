@@ -0,0 +1,17 @@
+package realtime
+
+import "github.com/BacoFoods/menu/pkg/shared"
+
+type Routes struct {
+	handler *Handler
+}
+
+func NewRoutes(handler *Handler) Routes {
+	return Routes{handler}
+}
+
+func (r Routes) RegisterRoutes(private *shared.CustomRoutes) {
+	private.GET("/realtime/store/:id", r.handler.Store)
+	private.GET("/realtime/table/:id", r.handler.Table)
+	private.GET("/realtime/order/:id", r.handler.Order)
+}
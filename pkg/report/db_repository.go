@@ -0,0 +1,58 @@
+package report
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/BacoFoods/menu/pkg/order"
+	"github.com/BacoFoods/menu/pkg/shared"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	LogDBRepository = "pkg/report/db_repository"
+)
+
+type DBRepository struct {
+	db *gorm.DB
+}
+
+func NewDBRepository(db *gorm.DB) *DBRepository {
+	return &DBRepository{db}
+}
+
+// FindClosedOrders returns the orders of the store closed in the range with their invoices and payments
+func (r *DBRepository) FindClosedOrders(storeID string, from, to time.Time) ([]order.Order, error) {
+	var orders []order.Order
+	if err := r.db.Preload(clause.Associations).
+		Preload("Invoices.Items").
+		Preload("Invoices.Payments.PaymentMethod").
+		Where("store_id = ? AND current_status = ?", storeID, order.OrderStatusClosed).
+		Where("closed_at >= ? AND closed_at < ?", from, to).
+		Find(&orders).Error; err != nil {
+		shared.LogError("error finding closed orders", LogDBRepository, "FindClosedOrders", err, storeID, from, to)
+		return nil, fmt.Errorf(ErrorReportFindingOrders)
+	}
+
+	return orders, nil
+}
+
+// FindVoidedOrders returns the orders of the store deleted or canceled in the range, a canceled order is
+// dated by its last update
+func (r *DBRepository) FindVoidedOrders(storeID string, from, to time.Time) ([]order.Order, error) {
+	var orders []order.Order
+	if err := r.db.Unscoped().
+		Preload("Items.Modifiers").
+		Where("store_id = ?", storeID).
+		Where("(deleted_at >= ? AND deleted_at < ?) OR (deleted_at IS NULL AND current_status = ? AND updated_at >= ? AND updated_at < ?)",
+			from, to, order.OrderStatusCanceled, from, to).
+		Find(&orders).Error; err != nil {
+		shared.LogError("error finding voided orders", LogDBRepository, "FindVoidedOrders", err, storeID, from, to)
+		return nil, fmt.Errorf(ErrorReportFindingVoids)
+	}
+
+	return orders, nil
+}
+
+var _ Repository = &DBRepository{}
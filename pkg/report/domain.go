@@ -0,0 +1,298 @@
+package report
+
+import (
+	"sort"
+	"time"
+
+	"github.com/BacoFoods/menu/pkg/order"
+	"github.com/BacoFoods/menu/pkg/payment"
+)
+
+const (
+	ErrorReportInvalidFormat = "error invalid report format"
+	ErrorReportInvalidDates  = "error report dates must be formatted as 2006-01-02"
+	ErrorReportInvalidRange  = "error report start date must be before the end date"
+	ErrorReportRangeTooLong  = "error report range is too long"
+	ErrorReportGettingStore  = "error getting report store"
+	ErrorReportFindingOrders = "error finding report orders"
+	ErrorReportFindingVoids  = "error finding report voided orders"
+	ErrorReportGenerating    = "error generating report file"
+
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+
+	// MaxReportDays is the longest date range of a report
+	MaxReportDays = 93
+
+	dateLayout = "2006-01-02"
+)
+
+type Repository interface {
+	FindClosedOrders(storeID string, from, to time.Time) ([]order.Order, error)
+	FindVoidedOrders(storeID string, from, to time.Time) ([]order.Order, error)
+}
+
+// ZReport is the end of day sales report of a store, the amounts are taken from the invoices of the orders
+// closed on the day. Gross sales include taxes, net sales are the gross sales after discounts and surcharges.
+type ZReport struct {
+	StoreID        uint        `json:"store_id"`
+	StoreName      string      `json:"store_name"`
+	Date           string      `json:"date" example:"2006-01-02"`
+	Orders         int         `json:"orders"`
+	Covers         int         `json:"covers"`
+	GrossSales     float64     `json:"gross_sales"`
+	Discounts      float64     `json:"discounts"`
+	Surcharges     float64     `json:"surcharges"`
+	NetSales       float64     `json:"net_sales"`
+	Taxes          float64     `json:"taxes"`
+	Tips           float64     `json:"tips"`
+	Total          float64     `json:"total"`
+	AverageTicket  float64     `json:"average_ticket"`
+	TaxesByType    []TaxLine   `json:"taxes_by_type"`
+	Channels       []Breakdown `json:"channels"`
+	OrderTypes     []Breakdown `json:"order_types"`
+	PaymentMethods []Breakdown `json:"payment_methods"`
+	Voids          Voids       `json:"voids"`
+}
+
+type TaxLine struct {
+	Name       string  `json:"name"`
+	Percentage float64 `json:"percentage"`
+	Base       float64 `json:"base"`
+	Amount     float64 `json:"amount"`
+}
+
+type Breakdown struct {
+	Name   string  `json:"name"`
+	Count  int     `json:"count"`
+	Amount float64 `json:"amount"`
+	Tips   float64 `json:"tips"`
+}
+
+// Voids are the orders deleted and the payments canceled on the day
+type Voids struct {
+	Orders         int     `json:"orders"`
+	OrdersAmount   float64 `json:"orders_amount"`
+	Payments       int     `json:"payments"`
+	PaymentsAmount float64 `json:"payments_amount"`
+}
+
+// Report has the Z report of every day of the range and the totals of the range
+type Report struct {
+	StoreID   uint      `json:"store_id"`
+	StoreName string    `json:"store_name"`
+	Timezone  string    `json:"timezone"`
+	From      string    `json:"from" example:"2006-01-02"`
+	To        string    `json:"to" example:"2006-01-02"`
+	Days      []ZReport `json:"days"`
+	Total     ZReport   `json:"total"`
+}
+
+// VoidedAt returns when the order was voided, the deletion time of a deleted order or the last update of
+// a canceled one, nil when the order was not voided.
+func VoidedAt(voidedOrder order.Order) *time.Time {
+	if voidedOrder.DeletedAt != nil && voidedOrder.DeletedAt.Valid {
+		return &voidedOrder.DeletedAt.Time
+	}
+	if voidedOrder.CurrentStatus == order.OrderStatusCanceled {
+		return voidedOrder.UpdatedAt
+	}
+	return nil
+}
+
+// NewZReport builds the Z report of the orders closed and voided on the same day, the voided orders are
+// the deleted and the canceled ones. Channel names are looked up by channel id.
+func NewZReport(date string, closed, voided []order.Order, channels map[uint]string) ZReport {
+	report := ZReport{Date: date}
+
+	taxes := make(map[string]*TaxLine)
+	byChannel := make(map[string]*Breakdown)
+	byOrderType := make(map[string]*Breakdown)
+	byPaymentMethod := make(map[string]*Breakdown)
+
+	for _, closedOrder := range closed {
+		report.Orders++
+		report.Covers += closedOrder.Seats
+
+		orderTotal := 0.0
+		for _, orderInvoice := range closedOrder.Invoices {
+			report.GrossSales += orderInvoice.SubTotal
+			report.Discounts += orderInvoice.TotalDiscounts
+			report.Surcharges += orderInvoice.TotalSurcharges
+			report.Taxes += orderInvoice.Taxes
+			report.Tips += orderInvoice.TipAmount
+			report.Total += orderInvoice.Total
+			orderTotal += orderInvoice.Total
+
+			orderInvoice.CalculateTaxDetails()
+			for _, taxDetail := range orderInvoice.TaxDetails {
+				if _, ok := taxes[taxDetail.Name]; !ok {
+					taxes[taxDetail.Name] = &TaxLine{Name: taxDetail.Name, Percentage: taxDetail.Percentage}
+				}
+				taxes[taxDetail.Name].Base += taxDetail.Base
+				taxes[taxDetail.Name].Amount += taxDetail.Amount
+			}
+
+			for _, orderPayment := range orderInvoice.Payments {
+				if orderPayment.Status == payment.PaymentStatusCanceled {
+					report.Voids.Payments++
+					report.Voids.PaymentsAmount += orderPayment.TotalValue
+					continue
+				}
+				addBreakdown(byPaymentMethod, paymentMethodName(orderPayment), orderPayment.TotalValue, orderPayment.Tip)
+			}
+		}
+
+		addBreakdown(byChannel, channelName(closedOrder, channels), orderTotal, 0)
+		addBreakdown(byOrderType, orderTypeName(closedOrder), orderTotal, 0)
+	}
+
+	for _, voidedOrder := range voided {
+		report.Voids.Orders++
+		for _, item := range voidedOrder.Items {
+			report.Voids.OrdersAmount += item.GetPrice()
+		}
+	}
+
+	report.NetSales = report.GrossSales - report.Discounts + report.Surcharges
+	if report.Orders > 0 {
+		report.AverageTicket = report.NetSales / float64(report.Orders)
+	}
+
+	report.TaxesByType = make([]TaxLine, 0)
+	for _, tax := range taxes {
+		report.TaxesByType = append(report.TaxesByType, *tax)
+	}
+	sort.Slice(report.TaxesByType, func(i, j int) bool {
+		return report.TaxesByType[i].Name < report.TaxesByType[j].Name
+	})
+
+	report.Channels = sortBreakdowns(byChannel)
+	report.OrderTypes = sortBreakdowns(byOrderType)
+	report.PaymentMethods = sortBreakdowns(byPaymentMethod)
+
+	return report
+}
+
+// Sum returns the totals of the Z reports of a range
+func Sum(reports []ZReport) ZReport {
+	total := ZReport{}
+	taxes := make(map[string]*TaxLine)
+	byChannel := make(map[string]*Breakdown)
+	byOrderType := make(map[string]*Breakdown)
+	byPaymentMethod := make(map[string]*Breakdown)
+
+	for _, report := range reports {
+		total.Orders += report.Orders
+		total.Covers += report.Covers
+		total.GrossSales += report.GrossSales
+		total.Discounts += report.Discounts
+		total.Surcharges += report.Surcharges
+		total.NetSales += report.NetSales
+		total.Taxes += report.Taxes
+		total.Tips += report.Tips
+		total.Total += report.Total
+		total.Voids.Orders += report.Voids.Orders
+		total.Voids.OrdersAmount += report.Voids.OrdersAmount
+		total.Voids.Payments += report.Voids.Payments
+		total.Voids.PaymentsAmount += report.Voids.PaymentsAmount
+
+		for _, tax := range report.TaxesByType {
+			if _, ok := taxes[tax.Name]; !ok {
+				taxes[tax.Name] = &TaxLine{Name: tax.Name, Percentage: tax.Percentage}
+			}
+			taxes[tax.Name].Base += tax.Base
+			taxes[tax.Name].Amount += tax.Amount
+		}
+
+		mergeBreakdowns(byChannel, report.Channels)
+		mergeBreakdowns(byOrderType, report.OrderTypes)
+		mergeBreakdowns(byPaymentMethod, report.PaymentMethods)
+	}
+
+	if total.Orders > 0 {
+		total.AverageTicket = total.NetSales / float64(total.Orders)
+	}
+
+	total.TaxesByType = make([]TaxLine, 0)
+	for _, tax := range taxes {
+		total.TaxesByType = append(total.TaxesByType, *tax)
+	}
+	sort.Slice(total.TaxesByType, func(i, j int) bool {
+		return total.TaxesByType[i].Name < total.TaxesByType[j].Name
+	})
+
+	total.Channels = sortBreakdowns(byChannel)
+	total.OrderTypes = sortBreakdowns(byOrderType)
+	total.PaymentMethods = sortBreakdowns(byPaymentMethod)
+
+	return total
+}
+
+func addBreakdown(breakdowns map[string]*Breakdown, name string, amount, tips float64) {
+	if _, ok := breakdowns[name]; !ok {
+		breakdowns[name] = &Breakdown{Name: name}
+	}
+	breakdowns[name].Count++
+	breakdowns[name].Amount += amount
+	breakdowns[name].Tips += tips
+}
+
+func mergeBreakdowns(breakdowns map[string]*Breakdown, lines []Breakdown) {
+	for _, line := range lines {
+		if _, ok := breakdowns[line.Name]; !ok {
+			breakdowns[line.Name] = &Breakdown{Name: line.Name}
+		}
+		breakdowns[line.Name].Count += line.Count
+		breakdowns[line.Name].Amount += line.Amount
+		breakdowns[line.Name].Tips += line.Tips
+	}
+}
+
+func sortBreakdowns(breakdowns map[string]*Breakdown) []Breakdown {
+	lines := make([]Breakdown, 0)
+	for _, line := range breakdowns {
+		lines = append(lines, *line)
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		return lines[i].Name < lines[j].Name
+	})
+	return lines
+}
+
+func paymentMethodName(orderPayment payment.Payment) string {
+	if orderPayment.PaymentMethod != nil && orderPayment.PaymentMethod.Name != "" {
+		return orderPayment.PaymentMethod.Name
+	}
+	return orderPayment.Method
+}
+
+func channelName(closedOrder order.Order, channels map[uint]string) string {
+	if closedOrder.ChannelID == nil {
+		return "unknown"
+	}
+	if name, ok := channels[*closedOrder.ChannelID]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+func orderTypeName(closedOrder order.Order) string {
+	if closedOrder.Type != nil && closedOrder.Type.Name != "" {
+		return closedOrder.Type.Name
+	}
+	if closedOrder.OrderType != "" {
+		return closedOrder.OrderType
+	}
+	return "unknown"
+}
+
+// IsValidFormat checks the report output format
+func IsValidFormat(format string) bool {
+	switch format {
+	case FormatJSON, FormatCSV, FormatXLSX:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+package report_test
+
+import (
+	"time"
+
+	"github.com/BacoFoods/menu/pkg/invoice"
+	"github.com/BacoFoods/menu/pkg/order"
+	"github.com/BacoFoods/menu/pkg/payment"
+	"github.com/BacoFoods/menu/pkg/report"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gorm.io/gorm"
+)
+
+var _ = Describe("Z report", func() {
+	salon, delivery := uint(1), uint(2)
+	channels := map[uint]string{salon: "Salon", delivery: "Rappi"}
+
+	closed := []order.Order{
+		{
+			ChannelID: &salon,
+			OrderType: "dine-in",
+			Seats:     2,
+			Invoices: []invoice.Invoice{{
+				SubTotal:       54000,
+				TotalDiscounts: 4000,
+				Taxes:          4000,
+				TipAmount:      5000,
+				Total:          55000,
+				Items: []invoice.Item{
+					{Tax: "ico", TaxPercentage: 0.08, TaxBase: 25000, TaxAmount: 2000},
+					{Tax: "ico", TaxPercentage: 0.08, TaxBase: 25000, TaxAmount: 2000},
+				},
+				Payments: []payment.Payment{
+					{Method: payment.PaymentMethodCash, TotalValue: 20000},
+					{Method: payment.PaymentMethodCardVisa, PaymentMethod: &payment.PaymentMethod{Name: "Visa"}, TotalValue: 35000, Tip: 5000},
+					{Method: payment.PaymentMethodCash, TotalValue: 10000, Status: payment.PaymentStatusCanceled},
+				},
+			}},
+		},
+		{
+			ChannelID: &delivery,
+			OrderType: "delivery",
+			Seats:     1,
+			Invoices: []invoice.Invoice{{
+				SubTotal:        30000,
+				TotalSurcharges: 2000,
+				Taxes:           2222,
+				Total:           32000,
+				Items:           []invoice.Item{{Tax: "iva", TaxPercentage: 0.19, TaxBase: 11000, TaxAmount: 2222}},
+				Payments:        []payment.Payment{{Method: payment.PaymentMethodCash, TotalValue: 32000}},
+			}},
+		},
+	}
+
+	voided := []order.Order{{
+		Items: []order.OrderItem{
+			{Price: 20000, Modifiers: []order.OrderModifier{{Price: 3000}, {Price: 2000}}},
+			{Price: 8000},
+		},
+	}}
+
+	It("Totals the sales of the closed orders", func() {
+		zReport := report.NewZReport("2023-10-05", closed, nil, channels)
+
+		Expect(zReport.Date).To(Equal("2023-10-05"))
+		Expect(zReport.Orders).To(Equal(2))
+		Expect(zReport.Covers).To(Equal(3))
+		Expect(zReport.GrossSales).To(Equal(84000.0))
+		Expect(zReport.Discounts).To(Equal(4000.0))
+		Expect(zReport.Surcharges).To(Equal(2000.0))
+		Expect(zReport.NetSales).To(Equal(82000.0))
+		Expect(zReport.Tips).To(Equal(5000.0))
+		Expect(zReport.Total).To(Equal(87000.0))
+		Expect(zReport.AverageTicket).To(Equal(41000.0))
+	})
+
+	It("Breaks down the taxes, channels, order types and payment methods", func() {
+		zReport := report.NewZReport("2023-10-05", closed, nil, channels)
+
+		Expect(zReport.TaxesByType).To(Equal([]report.TaxLine{
+			{Name: "ico", Percentage: 0.08, Base: 50000, Amount: 4000},
+			{Name: "iva", Percentage: 0.19, Base: 11000, Amount: 2222},
+		}))
+		Expect(zReport.Channels).To(Equal([]report.Breakdown{
+			{Name: "Rappi", Count: 1, Amount: 32000},
+			{Name: "Salon", Count: 1, Amount: 55000},
+		}))
+		Expect(zReport.OrderTypes[0].Name).To(Equal("delivery"))
+		Expect(zReport.PaymentMethods).To(Equal([]report.Breakdown{
+			{Name: "Visa", Count: 1, Amount: 35000, Tips: 5000},
+			{Name: payment.PaymentMethodCash, Count: 2, Amount: 52000},
+		}))
+	})
+
+	It("Counts the voided orders with their modifiers and the canceled payments", func() {
+		zReport := report.NewZReport("2023-10-05", closed, voided, channels)
+
+		Expect(zReport.Voids.Orders).To(Equal(1))
+		Expect(zReport.Voids.OrdersAmount).To(Equal(33000.0))
+		Expect(zReport.Voids.Payments).To(Equal(1))
+		Expect(zReport.Voids.PaymentsAmount).To(Equal(10000.0))
+	})
+
+	It("Counts the canceled orders as voided", func() {
+		canceled := order.Order{CurrentStatus: order.OrderStatusCanceled, Items: []order.OrderItem{{Price: 12000}}}
+
+		zReport := report.NewZReport("2023-10-05", closed, append(voided, canceled), channels)
+
+		Expect(zReport.Orders).To(Equal(2))
+		Expect(zReport.Voids.Orders).To(Equal(2))
+		Expect(zReport.Voids.OrdersAmount).To(Equal(45000.0))
+	})
+
+	It("Dates the deleted orders by deletion and the canceled ones by their last update", func() {
+		deletedAt := time.Date(2023, 10, 5, 20, 0, 0, 0, time.UTC)
+		updatedAt := time.Date(2023, 10, 6, 13, 0, 0, 0, time.UTC)
+
+		deleted := order.Order{UpdatedAt: &updatedAt, DeletedAt: &gorm.DeletedAt{Time: deletedAt, Valid: true}}
+		canceled := order.Order{CurrentStatus: order.OrderStatusCanceled, UpdatedAt: &updatedAt}
+
+		Expect(*report.VoidedAt(deleted)).To(Equal(deletedAt))
+		Expect(*report.VoidedAt(canceled)).To(Equal(updatedAt))
+		Expect(report.VoidedAt(order.Order{CurrentStatus: order.OrderStatusClosed, UpdatedAt: &updatedAt})).To(BeNil())
+	})
+
+	It("Sums the reports of a range", func() {
+		day := report.NewZReport("2023-10-05", closed, voided, channels)
+
+		total := report.Sum([]report.ZReport{day, day})
+
+		Expect(total.Orders).To(Equal(4))
+		Expect(total.NetSales).To(Equal(164000.0))
+		Expect(total.AverageTicket).To(Equal(41000.0))
+		Expect(total.Voids.OrdersAmount).To(Equal(66000.0))
+		Expect(total.Channels[1]).To(Equal(report.Breakdown{Name: "Salon", Count: 2, Amount: 110000}))
+	})
+
+	It("Names the orders without a known channel", func() {
+		zReport := report.NewZReport("2023-10-05", []order.Order{{}}, nil, channels)
+
+		Expect(zReport.Channels[0].Name).To(Equal("unknown"))
+		Expect(zReport.OrderTypes[0].Name).To(Equal("unknown"))
+		Expect(zReport.AverageTicket).To(BeZero())
+	})
+})
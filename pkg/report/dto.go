@@ -0,0 +1,149 @@
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+
+	"github.com/xuri/excelize/v2"
+)
+
+var summaryHeaders = []string{
+	"date", "orders", "covers", "gross_sales", "discounts", "surcharges", "net_sales", "taxes", "tips", "total",
+	"average_ticket", "voided_orders", "voided_orders_amount", "voided_payments", "voided_payments_amount",
+}
+
+func summaryRow(report ZReport) []any {
+	return []any{
+		report.Date, report.Orders, report.Covers, report.GrossSales, report.Discounts, report.Surcharges, report.NetSales,
+		report.Taxes, report.Tips, report.Total, report.AverageTicket, report.Voids.Orders, report.Voids.OrdersAmount,
+		report.Voids.Payments, report.Voids.PaymentsAmount,
+	}
+}
+
+// rows returns the days and the total of the report, the total goes last
+func (r Report) rows() []ZReport {
+	return append(append(make([]ZReport, 0), r.Days...), r.Total)
+}
+
+// ToCSV writes the report in long format, one line by date, section and name, so it can be pivoted.
+// The summary section has a line for each summary metric.
+func (r Report) ToCSV() ([]byte, error) {
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+
+	lines := [][]string{{"store", "date", "section", "name", "count", "base", "amount", "tips"}}
+	for _, day := range r.rows() {
+		line := func(section, name string, count int, base, amount, tips float64) {
+			lines = append(lines, []string{
+				r.StoreName, day.Date, section, name, fmt.Sprint(count), formatAmount(base), formatAmount(amount), formatAmount(tips),
+			})
+		}
+
+		summary := summaryRow(day)
+		for i, header := range summaryHeaders[1:] {
+			switch value := summary[i+1].(type) {
+			case int:
+				line("summary", header, value, 0, 0, 0)
+			case float64:
+				line("summary", header, 0, 0, value, 0)
+			}
+		}
+		for _, tax := range day.TaxesByType {
+			line("tax", fmt.Sprintf("%s %.2f%%", tax.Name, tax.Percentage), 0, tax.Base, tax.Amount, 0)
+		}
+		for _, breakdown := range day.Channels {
+			line("channel", breakdown.Name, breakdown.Count, 0, breakdown.Amount, breakdown.Tips)
+		}
+		for _, breakdown := range day.OrderTypes {
+			line("order_type", breakdown.Name, breakdown.Count, 0, breakdown.Amount, breakdown.Tips)
+		}
+		for _, breakdown := range day.PaymentMethods {
+			line("payment_method", breakdown.Name, breakdown.Count, 0, breakdown.Amount, breakdown.Tips)
+		}
+	}
+
+	if err := writer.WriteAll(lines); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// ToXLSX writes the report with a sheet for the summary and a sheet for each breakdown
+func (r Report) ToXLSX() ([]byte, error) {
+	file := excelize.NewFile()
+	defer file.Close()
+
+	sheets := []struct {
+		name    string
+		headers []string
+		rows    func(day ZReport) [][]any
+	}{
+		{"Summary", summaryHeaders, func(day ZReport) [][]any { return [][]any{summaryRow(day)} }},
+		{"Taxes", []string{"date", "name", "percentage", "base", "amount"}, func(day ZReport) [][]any {
+			rows := make([][]any, 0)
+			for _, tax := range day.TaxesByType {
+				rows = append(rows, []any{day.Date, tax.Name, tax.Percentage, tax.Base, tax.Amount})
+			}
+			return rows
+		}},
+		{"Channels", breakdownHeaders, func(day ZReport) [][]any { return breakdownRows(day.Date, day.Channels) }},
+		{"Order types", breakdownHeaders, func(day ZReport) [][]any { return breakdownRows(day.Date, day.OrderTypes) }},
+		{"Payment methods", breakdownHeaders, func(day ZReport) [][]any { return breakdownRows(day.Date, day.PaymentMethods) }},
+	}
+
+	for i, sheet := range sheets {
+		if i == 0 {
+			if err := file.SetSheetName("Sheet1", sheet.name); err != nil {
+				return nil, err
+			}
+		} else if _, err := file.NewSheet(sheet.name); err != nil {
+			return nil, err
+		}
+
+		rows := [][]any{toAny(sheet.headers)}
+		for _, day := range r.rows() {
+			rows = append(rows, sheet.rows(day)...)
+		}
+
+		for rowIdx, row := range rows {
+			cell, err := excelize.CoordinatesToCellName(1, rowIdx+1)
+			if err != nil {
+				return nil, err
+			}
+			if err := file.SetSheetRow(sheet.name, cell, &row); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	buffer, err := file.WriteToBuffer()
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+var breakdownHeaders = []string{"date", "name", "count", "amount", "tips"}
+
+func breakdownRows(date string, breakdowns []Breakdown) [][]any {
+	rows := make([][]any, 0)
+	for _, breakdown := range breakdowns {
+		rows = append(rows, []any{date, breakdown.Name, breakdown.Count, breakdown.Amount, breakdown.Tips})
+	}
+	return rows
+}
+
+func toAny(values []string) []any {
+	anys := make([]any, len(values))
+	for i, value := range values {
+		anys[i] = value
+	}
+	return anys
+}
+
+func formatAmount(amount float64) string {
+	return fmt.Sprintf("%.2f", amount)
+}
@@ -0,0 +1,77 @@
+package report
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/BacoFoods/menu/pkg/shared"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	LogHandler = "pkg/report/handler"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service}
+}
+
+// ZReport to handle the end of day report request
+// @Tags Report
+// @Summary To get the end of day sales report of a store
+// @Description To get the Z report of every day of the range and its totals, with gross and net sales, taxes by type, discounts, surcharges, tips, covers, average ticket, breakdown by channel, order type and payment method, and voids. The days are in the store time zone.
+// @Accept json
+// @Produce json,text/csv,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Security ApiKeyAuth
+// @Param id path string true "store id"
+// @Param from query string true "start date" example(2006-01-02)
+// @Param to query string true "end date, included" example(2006-01-02)
+// @Param format query string false "output format" Enums(json, csv, xlsx)
+// @Success 200 {object} object{status=string,data=Report}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /report/z/store/{id} [get]
+func (h Handler) ZReport(c *gin.Context) {
+	format := c.DefaultQuery("format", FormatJSON)
+	if !IsValidFormat(format) {
+		shared.LogWarn("invalid report format", LogHandler, "ZReport", fmt.Errorf(ErrorReportInvalidFormat), format)
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorReportInvalidFormat))
+		return
+	}
+
+	report, err := h.service.ZReport(c.Param("id"), c.Query("from"), c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	if format == FormatJSON {
+		c.JSON(http.StatusOK, shared.SuccessResponse(report))
+		return
+	}
+
+	var file []byte
+	contentType := "text/csv"
+	if format == FormatCSV {
+		file, err = report.ToCSV()
+	} else {
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+		file, err = report.ToXLSX()
+	}
+
+	if err != nil {
+		shared.LogError("error generating report file", LogHandler, "ZReport", err, format)
+		c.JSON(http.StatusInternalServerError, shared.ErrorResponse(ErrorReportGenerating))
+		return
+	}
+
+	filename := fmt.Sprintf("z-report-%d-%s-%s.%s", report.StoreID, report.From, report.To, format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	c.Header("Access-Control-Expose-Headers", "Content-Disposition")
+	c.Data(http.StatusOK, contentType, file)
+}
@@ -0,0 +1,15 @@
+package report
+
+import "github.com/BacoFoods/menu/pkg/shared"
+
+type Routes struct {
+	handler *Handler
+}
+
+func NewRoutes(handler *Handler) Routes {
+	return Routes{handler}
+}
+
+func (r Routes) RegisterRoutes(router *shared.CustomRoutes) {
+	router.GET("/report/z/store/:id", r.handler.ZReport)
+}
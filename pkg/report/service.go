@@ -0,0 +1,142 @@
+package report
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/BacoFoods/menu/pkg/channel"
+	"github.com/BacoFoods/menu/pkg/order"
+	"github.com/BacoFoods/menu/pkg/shared"
+	"github.com/BacoFoods/menu/pkg/store"
+)
+
+const (
+	LogService = "pkg/report/service"
+)
+
+type Service interface {
+	ZReport(storeID, from, to string) (*Report, error)
+}
+
+type service struct {
+	repository Repository
+	stores     store.Repository
+	channels   channel.Repository
+}
+
+func NewService(repository Repository, stores store.Repository, channels channel.Repository) service {
+	return service{repository, stores, channels}
+}
+
+// ZReport builds the end of day report of every day of the range, the days are in the store time zone
+// and both dates are included.
+func (s service) ZReport(storeID, from, to string) (*Report, error) {
+	reportStore, err := s.stores.Get(storeID)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorReportGettingStore)
+	}
+	location := reportStore.Location()
+
+	fromDate, err := time.ParseInLocation(dateLayout, from, location)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorReportInvalidDates)
+	}
+
+	toDate, err := time.ParseInLocation(dateLayout, to, location)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorReportInvalidDates)
+	}
+
+	if toDate.Before(fromDate) {
+		return nil, fmt.Errorf(ErrorReportInvalidRange)
+	}
+
+	if toDate.Sub(fromDate) > MaxReportDays*24*time.Hour {
+		return nil, fmt.Errorf(ErrorReportRangeTooLong)
+	}
+
+	end := toDate.AddDate(0, 0, 1)
+	closed, err := s.repository.FindClosedOrders(storeID, fromDate, end)
+	if err != nil {
+		return nil, err
+	}
+
+	voided, err := s.repository.FindVoidedOrders(storeID, fromDate, end)
+	if err != nil {
+		return nil, err
+	}
+
+	channels, err := s.channelNames(closed)
+	if err != nil {
+		return nil, err
+	}
+
+	closedByDay := make(map[string][]order.Order)
+	for _, closedOrder := range closed {
+		day := closedOrder.ClosedAt.In(location).Format(dateLayout)
+		closedByDay[day] = append(closedByDay[day], closedOrder)
+	}
+
+	voidedByDay := make(map[string][]order.Order)
+	for _, voidedOrder := range voided {
+		voidedAt := VoidedAt(voidedOrder)
+		if voidedAt == nil {
+			continue
+		}
+		day := voidedAt.In(location).Format(dateLayout)
+		voidedByDay[day] = append(voidedByDay[day], voidedOrder)
+	}
+
+	report := Report{
+		StoreID:   reportStore.ID,
+		StoreName: reportStore.Name,
+		Timezone:  location.String(),
+		From:      from,
+		To:        to,
+		Days:      make([]ZReport, 0),
+	}
+
+	for day := fromDate; day.Before(end); day = day.AddDate(0, 0, 1) {
+		date := day.Format(dateLayout)
+		zReport := NewZReport(date, closedByDay[date], voidedByDay[date], channels)
+		zReport.StoreID = reportStore.ID
+		zReport.StoreName = reportStore.Name
+		report.Days = append(report.Days, zReport)
+	}
+
+	report.Total = Sum(report.Days)
+	report.Total.StoreID = reportStore.ID
+	report.Total.StoreName = reportStore.Name
+	report.Total.Date = fmt.Sprintf("%s/%s", from, to)
+
+	return &report, nil
+}
+
+func (s service) channelNames(orders []order.Order) (map[uint]string, error) {
+	ids := make([]string, 0)
+	seen := make(map[uint]bool)
+	for _, closedOrder := range orders {
+		if closedOrder.ChannelID == nil || seen[*closedOrder.ChannelID] {
+			continue
+		}
+		seen[*closedOrder.ChannelID] = true
+		ids = append(ids, fmt.Sprint(*closedOrder.ChannelID))
+	}
+
+	names := make(map[uint]string)
+	if len(ids) == 0 {
+		return names, nil
+	}
+
+	channels, err := s.channels.FindByIDs(ids)
+	if err != nil {
+		shared.LogError("error finding channels", LogService, "channelNames", err, ids)
+		return nil, fmt.Errorf(ErrorReportFindingOrders)
+	}
+
+	for _, ch := range channels {
+		names[ch.ID] = ch.Name
+	}
+
+	return names, nil
+}
@@ -0,0 +1,159 @@
+package reservation
+
+import (
+	"time"
+
+	"github.com/BacoFoods/menu/pkg/shared"
+	"github.com/BacoFoods/menu/pkg/tables"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const LogDBRepository = "pkg/reservation/db_repository"
+
+type DBRepository struct {
+	db *gorm.DB
+}
+
+func NewDBRepository(db *gorm.DB) *DBRepository {
+	return &DBRepository{db}
+}
+
+func (r *DBRepository) Create(reservation *Reservation) (*Reservation, error) {
+	if err := r.db.Create(reservation).Error; err != nil {
+		shared.LogError("error creating reservation", LogDBRepository, "Create", err, *reservation)
+		return nil, err
+	}
+
+	return reservation, nil
+}
+
+// CreateOnFreeTable creates the reservation locking its table, so two reservations can't keep the same table for
+// overlapping slots. ErrTableKept is returned when an active reservation keeps the table between start and end.
+func (r *DBRepository) CreateOnFreeTable(reservation *Reservation, now time.Time) (*Reservation, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var table tables.Table
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&table, *reservation.TableID).Error; err != nil {
+			return err
+		}
+
+		var kept int64
+		err := tx.Model(&Reservation{}).
+			Where("table_id = ?", *reservation.TableID).
+			Where("start_time < ? AND end_time > ?", reservation.EndTime, reservation.StartTime).
+			Where("status IN ? OR (status = ? AND held_until > ?)",
+				[]string{ReservationStatusConfirmed, ReservationStatusSeated}, ReservationStatusHeld, now).
+			Count(&kept).Error
+		if err != nil {
+			return err
+		}
+
+		if kept > 0 {
+			return ErrTableKept
+		}
+
+		return tx.Omit("Table").Create(reservation).Error
+	})
+	if err == ErrTableKept {
+		return nil, err
+	}
+	if err != nil {
+		shared.LogError("error creating reservation", LogDBRepository, "CreateOnFreeTable", err, *reservation)
+		return nil, err
+	}
+
+	return reservation, nil
+}
+
+func (r *DBRepository) Get(reservationID string) (*Reservation, error) {
+	var reservation Reservation
+	if err := r.db.Preload("Table").First(&reservation, reservationID).Error; err != nil {
+		shared.LogError("error getting reservation", LogDBRepository, "Get", err, reservationID)
+		return nil, err
+	}
+
+	return &reservation, nil
+}
+
+// Find returns the reservations filtered by columns, the date filter matches the start time day.
+func (r *DBRepository) Find(filter map[string]any) ([]Reservation, error) {
+	tx := r.db.Model(&Reservation{})
+
+	if date, ok := filter["date"]; ok {
+		tx = tx.Where("start_time::date = ?", date)
+		delete(filter, "date")
+	}
+
+	var reservations []Reservation
+	if err := tx.Where(filter).Preload("Table").Order("start_time ASC").Find(&reservations).Error; err != nil {
+		shared.LogError("error finding reservations", LogDBRepository, "Find", err, filter)
+		return nil, err
+	}
+
+	return reservations, nil
+}
+
+func (r *DBRepository) Update(reservation *Reservation) (*Reservation, error) {
+	if err := r.db.Omit("Table").Save(reservation).Error; err != nil {
+		shared.LogError("error updating reservation", LogDBRepository, "Update", err, *reservation)
+		return nil, err
+	}
+
+	return reservation, nil
+}
+
+// FindOverlapping returns the reservations of the store that may keep a table between start and end.
+func (r *DBRepository) FindOverlapping(storeID uint, start, end time.Time) ([]Reservation, error) {
+	var reservations []Reservation
+	err := r.db.
+		Where("store_id = ?", storeID).
+		Where("status IN ?", []string{ReservationStatusHeld, ReservationStatusConfirmed, ReservationStatusSeated}).
+		Where("start_time < ? AND end_time > ?", end, start).
+		Find(&reservations).Error
+	if err != nil {
+		shared.LogError("error finding overlapping reservations", LogDBRepository, "FindOverlapping", err, storeID, start, end)
+		return nil, err
+	}
+
+	return reservations, nil
+}
+
+func (r *DBRepository) CreateWaitlistEntry(entry *WaitlistEntry) (*WaitlistEntry, error) {
+	if err := r.db.Create(entry).Error; err != nil {
+		shared.LogError("error creating waitlist entry", LogDBRepository, "CreateWaitlistEntry", err, *entry)
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func (r *DBRepository) GetWaitlistEntry(entryID string) (*WaitlistEntry, error) {
+	var entry WaitlistEntry
+	if err := r.db.First(&entry, entryID).Error; err != nil {
+		shared.LogError("error getting waitlist entry", LogDBRepository, "GetWaitlistEntry", err, entryID)
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+func (r *DBRepository) FindWaitlist(filter map[string]any) ([]WaitlistEntry, error) {
+	var entries []WaitlistEntry
+	if err := r.db.Where(filter).Order("created_at ASC").Find(&entries).Error; err != nil {
+		shared.LogError("error finding waitlist", LogDBRepository, "FindWaitlist", err, filter)
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (r *DBRepository) UpdateWaitlistEntry(entry *WaitlistEntry) (*WaitlistEntry, error) {
+	if err := r.db.Save(entry).Error; err != nil {
+		shared.LogError("error updating waitlist entry", LogDBRepository, "UpdateWaitlistEntry", err, *entry)
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+var _ Repository = &DBRepository{}
@@ -0,0 +1,122 @@
+package reservation
+
+import (
+	"errors"
+	"time"
+
+	"github.com/BacoFoods/menu/pkg/tables"
+	"gorm.io/gorm"
+)
+
+const (
+	ErrorReservationBadRequest       = "error bad request"
+	ErrorReservationCreating         = "error creating reservation"
+	ErrorReservationGetting          = "error getting reservation"
+	ErrorReservationFinding          = "error finding reservations"
+	ErrorReservationUpdating         = "error updating reservation"
+	ErrorReservationPartySize        = "error party size must be greater than 0"
+	ErrorReservationTimeSlot         = "error reservation end time must be after start time"
+	ErrorReservationStoreClosed      = "error store is closed for the requested time"
+	ErrorReservationNoCapacity       = "error no table available for the party size and time slot"
+	ErrorReservationTableUnavailable = "error table is not available for the time slot"
+	ErrorReservationHoldExpired      = "error reservation hold expired"
+	ErrorReservationWrongStatus      = "error reservation status does not allow this action"
+	ErrorReservationSeating          = "error seating reservation"
+	ErrorReservationAvailability     = "error getting availability"
+
+	ErrorWaitlistCreating    = "error creating waitlist entry"
+	ErrorWaitlistGetting     = "error getting waitlist entry"
+	ErrorWaitlistFinding     = "error finding waitlist"
+	ErrorWaitlistUpdating    = "error updating waitlist entry"
+	ErrorWaitlistWrongStatus = "error waitlist entry status does not allow this action"
+	ErrorWaitlistSeating     = "error seating waitlist entry"
+
+	ReservationStatusHeld      = "held"
+	ReservationStatusConfirmed = "confirmed"
+	ReservationStatusSeated    = "seated"
+	ReservationStatusCancelled = "cancelled"
+	ReservationStatusNoShow    = "no_show"
+
+	WaitlistStatusWaiting   = "waiting"
+	WaitlistStatusSeated    = "seated"
+	WaitlistStatusCancelled = "cancelled"
+
+	// ReservationDefaultDuration is the time slot used when the reservation has no end time
+	ReservationDefaultDuration = 90 * time.Minute
+	// ReservationHoldDuration is the time a table is held until the reservation is confirmed
+	ReservationHoldDuration = 15 * time.Minute
+	// WaitlistTurnDuration is the average time a party uses a table, used to quote wait times
+	WaitlistTurnDuration = 45 * time.Minute
+)
+
+// ErrTableKept is returned when another reservation took the table while the reservation was being created
+var ErrTableKept = errors.New(ErrorReservationTableUnavailable)
+
+type Reservation struct {
+	ID          uint            `json:"id"`
+	BrandID     *uint           `json:"brand_id"`
+	StoreID     *uint           `json:"store_id" binding:"required"`
+	ChannelID   *uint           `json:"channel_id"`
+	ZoneID      *uint           `json:"zone_id"`
+	TableID     *uint           `json:"table_id"`
+	Table       *tables.Table   `json:"table,omitempty" gorm:"foreignKey:TableID" swaggerignore:"true"`
+	ClientID    *uint           `json:"client_id"`
+	ClientName  string          `json:"client_name"`
+	ClientPhone string          `json:"client_phone"`
+	PartySize   int             `json:"party_size" binding:"required"`
+	StartTime   time.Time       `json:"start_time" binding:"required" gorm:"index"`
+	EndTime     time.Time       `json:"end_time" gorm:"index"`
+	Status      string          `json:"status" gorm:"index"`
+	Notes       string          `json:"notes"`
+	HeldUntil   *time.Time      `json:"held_until"`
+	OrderID     *uint           `json:"order_id"`
+	SeatedAt    *time.Time      `json:"seated_at"`
+	CreatedAt   *time.Time      `json:"created_at,omitempty" swaggerignore:"true"`
+	UpdatedAt   *time.Time      `json:"updated_at,omitempty" swaggerignore:"true"`
+	DeletedAt   *gorm.DeletedAt `json:"deleted_at,omitempty" swaggerignore:"true"`
+}
+
+// IsActive checks if the reservation keeps its table, held reservations release it when the hold expires.
+func (r *Reservation) IsActive(now time.Time) bool {
+	switch r.Status {
+	case ReservationStatusConfirmed, ReservationStatusSeated:
+		return true
+	case ReservationStatusHeld:
+		return r.HeldUntil != nil && r.HeldUntil.After(now)
+	}
+	return false
+}
+
+type WaitlistEntry struct {
+	ID                uint            `json:"id"`
+	BrandID           *uint           `json:"brand_id"`
+	StoreID           *uint           `json:"store_id" binding:"required"`
+	ChannelID         *uint           `json:"channel_id"`
+	ClientID          *uint           `json:"client_id"`
+	ClientName        string          `json:"client_name" binding:"required"`
+	ClientPhone       string          `json:"client_phone"`
+	PartySize         int             `json:"party_size" binding:"required"`
+	Notes             string          `json:"notes"`
+	Status            string          `json:"status" gorm:"index"`
+	QuotedWaitMinutes int             `json:"quoted_wait_minutes"`
+	TableID           *uint           `json:"table_id"`
+	OrderID           *uint           `json:"order_id"`
+	SeatedAt          *time.Time      `json:"seated_at"`
+	CreatedAt         *time.Time      `json:"created_at,omitempty" swaggerignore:"true"`
+	UpdatedAt         *time.Time      `json:"updated_at,omitempty" swaggerignore:"true"`
+	DeletedAt         *gorm.DeletedAt `json:"deleted_at,omitempty" swaggerignore:"true"`
+}
+
+type Repository interface {
+	Create(reservation *Reservation) (*Reservation, error)
+	CreateOnFreeTable(reservation *Reservation, now time.Time) (*Reservation, error)
+	Get(reservationID string) (*Reservation, error)
+	Find(filter map[string]any) ([]Reservation, error)
+	Update(reservation *Reservation) (*Reservation, error)
+	FindOverlapping(storeID uint, start, end time.Time) ([]Reservation, error)
+
+	CreateWaitlistEntry(entry *WaitlistEntry) (*WaitlistEntry, error)
+	GetWaitlistEntry(entryID string) (*WaitlistEntry, error)
+	FindWaitlist(filter map[string]any) ([]WaitlistEntry, error)
+	UpdateWaitlistEntry(entry *WaitlistEntry) (*WaitlistEntry, error)
+}
@@ -0,0 +1,427 @@
+package reservation
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/BacoFoods/menu/pkg/shared"
+	"github.com/gin-gonic/gin"
+)
+
+const LogHandler = "pkg/reservation/handler"
+
+type RequestReservation struct {
+	BrandID     *uint     `json:"brand_id"`
+	StoreID     *uint     `json:"store_id" binding:"required"`
+	ChannelID   *uint     `json:"channel_id"`
+	ZoneID      *uint     `json:"zone_id"`
+	TableID     *uint     `json:"table_id"`
+	ClientID    *uint     `json:"client_id"`
+	ClientName  string    `json:"client_name" binding:"required"`
+	ClientPhone string    `json:"client_phone"`
+	PartySize   int       `json:"party_size" binding:"required"`
+	StartTime   time.Time `json:"start_time" binding:"required"`
+	EndTime     time.Time `json:"end_time"`
+	Notes       string    `json:"notes"`
+}
+
+func (r RequestReservation) ToReservation() *Reservation {
+	return &Reservation{
+		BrandID:     r.BrandID,
+		StoreID:     r.StoreID,
+		ChannelID:   r.ChannelID,
+		ZoneID:      r.ZoneID,
+		TableID:     r.TableID,
+		ClientID:    r.ClientID,
+		ClientName:  r.ClientName,
+		ClientPhone: r.ClientPhone,
+		PartySize:   r.PartySize,
+		StartTime:   r.StartTime,
+		EndTime:     r.EndTime,
+		Notes:       r.Notes,
+	}
+}
+
+type RequestWaitlist struct {
+	BrandID     *uint  `json:"brand_id"`
+	StoreID     *uint  `json:"store_id" binding:"required"`
+	ChannelID   *uint  `json:"channel_id"`
+	ClientID    *uint  `json:"client_id"`
+	ClientName  string `json:"client_name" binding:"required"`
+	ClientPhone string `json:"client_phone"`
+	PartySize   int    `json:"party_size" binding:"required"`
+	Notes       string `json:"notes"`
+}
+
+func (r RequestWaitlist) ToWaitlistEntry() *WaitlistEntry {
+	return &WaitlistEntry{
+		BrandID:     r.BrandID,
+		StoreID:     r.StoreID,
+		ChannelID:   r.ChannelID,
+		ClientID:    r.ClientID,
+		ClientName:  r.ClientName,
+		ClientPhone: r.ClientPhone,
+		PartySize:   r.PartySize,
+		Notes:       r.Notes,
+	}
+}
+
+type RequestSeatWaitlist struct {
+	TableID *uint `json:"table_id"`
+}
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service}
+}
+
+// Availability to handle a request to get the free tables for a party
+// @Tags Reservation
+// @Summary To get the free tables for a party
+// @Description To get the tables that fit the party and are free for the time slot
+// @Param store_id query int true "store id"
+// @Param party_size query int true "party size"
+// @Param start query string true "start time RFC3339"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=[]tables.Table}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /reservation/availability [get]
+func (h *Handler) Availability(c *gin.Context) {
+	storeID, err := strconv.ParseUint(c.Query("store_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorReservationBadRequest))
+		return
+	}
+
+	partySize, err := strconv.Atoi(c.Query("party_size"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorReservationBadRequest))
+		return
+	}
+
+	start, err := time.Parse(time.RFC3339, c.Query("start"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorReservationBadRequest))
+		return
+	}
+
+	tables, err := h.service.Availability(uint(storeID), partySize, start)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(tables))
+}
+
+// Create to handle a request to create a reservation
+// @Tags Reservation
+// @Summary To create a reservation
+// @Description To create a reservation, the table is held until the reservation is confirmed
+// @Param reservation body RequestReservation true "reservation"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=Reservation}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /reservation [post]
+func (h *Handler) Create(c *gin.Context) {
+	var body RequestReservation
+	if err := c.ShouldBindJSON(&body); err != nil {
+		shared.LogError("error binding request body", LogHandler, "Create", err, body)
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorReservationBadRequest))
+		return
+	}
+
+	reservation, err := h.service.Create(body.ToReservation())
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(reservation))
+}
+
+// Find to handle a request to find reservations
+// @Tags Reservation
+// @Summary To find reservations
+// @Description To find reservations
+// @Param store_id query int false "store id"
+// @Param status query string false "status" Enums(held, confirmed, seated, cancelled, no_show)
+// @Param date query string false "date YYYY-MM-DD"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=[]Reservation}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /reservation [get]
+func (h *Handler) Find(c *gin.Context) {
+	filter := make(map[string]any)
+
+	if storeID := c.Query("store_id"); storeID != "" {
+		filter["store_id"] = storeID
+	}
+
+	if status := c.Query("status"); status != "" {
+		filter["status"] = status
+	}
+
+	if date := c.Query("date"); date != "" {
+		filter["date"] = date
+	}
+
+	reservations, err := h.service.Find(filter)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(ErrorReservationFinding))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(reservations))
+}
+
+// Get to handle a request to get a reservation
+// @Tags Reservation
+// @Summary To get a reservation
+// @Description To get a reservation
+// @Param id path string true "reservation id"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=Reservation}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /reservation/{id} [get]
+func (h *Handler) Get(c *gin.Context) {
+	reservation, err := h.service.Get(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(ErrorReservationGetting))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(reservation))
+}
+
+// Confirm to handle a request to confirm a reservation
+// @Tags Reservation
+// @Summary To confirm a reservation
+// @Description To confirm a held reservation before the hold expires
+// @Param id path string true "reservation id"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=Reservation}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /reservation/{id}/confirm [post]
+func (h *Handler) Confirm(c *gin.Context) {
+	reservation, err := h.service.Confirm(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(reservation))
+}
+
+// Cancel to handle a request to cancel a reservation
+// @Tags Reservation
+// @Summary To cancel a reservation
+// @Description To cancel a reservation and release its table
+// @Param id path string true "reservation id"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=Reservation}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /reservation/{id}/cancel [post]
+func (h *Handler) Cancel(c *gin.Context) {
+	reservation, err := h.service.Cancel(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(reservation))
+}
+
+// NoShow to handle a request to mark a reservation as no show
+// @Tags Reservation
+// @Summary To mark a reservation as no show
+// @Description To mark a reservation as no show and release its table
+// @Param id path string true "reservation id"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=Reservation}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /reservation/{id}/no-show [post]
+func (h *Handler) NoShow(c *gin.Context) {
+	reservation, err := h.service.NoShow(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(reservation))
+}
+
+// Seat to handle a request to seat a reservation
+// @Tags Reservation
+// @Summary To seat a reservation
+// @Description To seat a reservation creating the order on the assigned table
+// @Param id path string true "reservation id"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=Reservation}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /reservation/{id}/seat [post]
+func (h *Handler) Seat(c *gin.Context) {
+	reservation, err := h.service.Seat(c.Param("id"), c)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(reservation))
+}
+
+// AddToWaitlist to handle a request to add a walk-in party to the waitlist
+// @Tags Waitlist
+// @Summary To add a party to the waitlist
+// @Description To add a walk-in party to the waitlist with a quoted wait time
+// @Param entry body RequestWaitlist true "waitlist entry"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=WaitlistEntry}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /waitlist [post]
+func (h *Handler) AddToWaitlist(c *gin.Context) {
+	var body RequestWaitlist
+	if err := c.ShouldBindJSON(&body); err != nil {
+		shared.LogError("error binding request body", LogHandler, "AddToWaitlist", err, body)
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorReservationBadRequest))
+		return
+	}
+
+	entry, err := h.service.AddToWaitlist(body.ToWaitlistEntry())
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(entry))
+}
+
+// FindWaitlist to handle a request to find the waitlist
+// @Tags Waitlist
+// @Summary To find the waitlist
+// @Description To find the waitlist in arrival order
+// @Param store_id query int false "store id"
+// @Param status query string false "status" Enums(waiting, seated, cancelled)
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=[]WaitlistEntry}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /waitlist [get]
+func (h *Handler) FindWaitlist(c *gin.Context) {
+	filter := make(map[string]any)
+
+	if storeID := c.Query("store_id"); storeID != "" {
+		filter["store_id"] = storeID
+	}
+
+	if status := c.Query("status"); status != "" {
+		filter["status"] = status
+	}
+
+	entries, err := h.service.FindWaitlist(filter)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(ErrorWaitlistFinding))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(entries))
+}
+
+// SeatWaitlist to handle a request to seat a waitlist party
+// @Tags Waitlist
+// @Summary To seat a waitlist party
+// @Description To seat a waitlist party on the given table or on the smallest free table that fits
+// @Param id path string true "waitlist entry id"
+// @Param table body RequestSeatWaitlist false "table"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=WaitlistEntry}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /waitlist/{id}/seat [post]
+func (h *Handler) SeatWaitlist(c *gin.Context) {
+	var body RequestSeatWaitlist
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			shared.LogError("error binding request body", LogHandler, "SeatWaitlist", err, body)
+			c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorReservationBadRequest))
+			return
+		}
+	}
+
+	entry, err := h.service.SeatWaitlist(c.Param("id"), body.TableID, c)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(entry))
+}
+
+// CancelWaitlist to handle a request to remove a party from the waitlist
+// @Tags Waitlist
+// @Summary To remove a party from the waitlist
+// @Description To remove a party from the waitlist
+// @Param id path string true "waitlist entry id"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=WaitlistEntry}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /waitlist/{id}/cancel [post]
+func (h *Handler) CancelWaitlist(c *gin.Context) {
+	entry, err := h.service.CancelWaitlist(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(entry))
+}
@@ -0,0 +1,29 @@
+package reservation
+
+import "github.com/BacoFoods/menu/pkg/shared"
+
+type Routes struct {
+	handler *Handler
+}
+
+func NewRoutes(handler *Handler) Routes {
+	return Routes{handler}
+}
+
+func (r Routes) RegisterRoutes(private *shared.CustomRoutes) {
+	// Reservations
+	private.GET("/reservation/availability", r.handler.Availability)
+	private.GET("/reservation", r.handler.Find)
+	private.POST("/reservation", r.handler.Create)
+	private.GET("/reservation/:id", r.handler.Get)
+	private.POST("/reservation/:id/confirm", r.handler.Confirm)
+	private.POST("/reservation/:id/cancel", r.handler.Cancel)
+	private.POST("/reservation/:id/no-show", r.handler.NoShow)
+	private.POST("/reservation/:id/seat", r.handler.Seat)
+
+	// Waitlist
+	private.GET("/waitlist", r.handler.FindWaitlist)
+	private.POST("/waitlist", r.handler.AddToWaitlist)
+	private.POST("/waitlist/:id/seat", r.handler.SeatWaitlist)
+	private.POST("/waitlist/:id/cancel", r.handler.CancelWaitlist)
+}
@@ -0,0 +1,479 @@
+package reservation
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/BacoFoods/menu/pkg/order"
+	"github.com/BacoFoods/menu/pkg/shared"
+	"github.com/BacoFoods/menu/pkg/store"
+	"github.com/BacoFoods/menu/pkg/tables"
+)
+
+const LogService = "pkg/reservation/service"
+
+type Service interface {
+	Availability(storeID uint, partySize int, start time.Time) ([]tables.Table, error)
+	Create(reservation *Reservation) (*Reservation, error)
+	Get(reservationID string) (*Reservation, error)
+	Find(filter map[string]any) ([]Reservation, error)
+	Confirm(reservationID string) (*Reservation, error)
+	Cancel(reservationID string) (*Reservation, error)
+	NoShow(reservationID string) (*Reservation, error)
+	Seat(reservationID string, ctx context.Context) (*Reservation, error)
+
+	AddToWaitlist(entry *WaitlistEntry) (*WaitlistEntry, error)
+	FindWaitlist(filter map[string]any) ([]WaitlistEntry, error)
+	SeatWaitlist(entryID string, tableID *uint, ctx context.Context) (*WaitlistEntry, error)
+	CancelWaitlist(entryID string) (*WaitlistEntry, error)
+}
+
+type scheduleSrv interface {
+	IsOpenAt(storeID string, at time.Time) (bool, error)
+}
+
+// tableSrv moves the tables through their lifecycle and notifies the staff of it
+type tableSrv interface {
+	SetState(tableID uint, state string) (*tables.Table, error)
+}
+
+type orderSrv interface {
+	Create(idempotencyKey string, order *order.Order, ctx context.Context) (*order.Order, error)
+}
+
+type service struct {
+	repository Repository
+	stores     store.Repository
+	tables     tableSrv
+	schedules  scheduleSrv
+	orders     orderSrv
+}
+
+func NewService(repository Repository, stores store.Repository, tables tableSrv, schedules scheduleSrv, orders orderSrv) service {
+	return service{repository, stores, tables, schedules, orders}
+}
+
+// Availability returns the tables of the store that fit the party and are free for the time slot,
+// smaller tables first.
+func (s service) Availability(storeID uint, partySize int, start time.Time) ([]tables.Table, error) {
+	if partySize <= 0 {
+		return nil, fmt.Errorf(ErrorReservationPartySize)
+	}
+
+	return s.freeTables(storeID, partySize, start, start.Add(ReservationDefaultDuration))
+}
+
+// Create checks the store is open for the whole slot and holds the smallest free table that fits the party,
+// the hold expires if the reservation is not confirmed. A table taken by a concurrent reservation is skipped.
+func (s service) Create(reservation *Reservation) (*Reservation, error) {
+	if reservation.StoreID == nil {
+		return nil, fmt.Errorf(ErrorReservationBadRequest)
+	}
+
+	if reservation.PartySize <= 0 {
+		return nil, fmt.Errorf(ErrorReservationPartySize)
+	}
+
+	if reservation.EndTime.IsZero() {
+		reservation.EndTime = reservation.StartTime.Add(ReservationDefaultDuration)
+	}
+
+	if !reservation.EndTime.After(reservation.StartTime) {
+		return nil, fmt.Errorf(ErrorReservationTimeSlot)
+	}
+
+	// The slot ends when the party leaves, so the store must be open until the minute before
+	for _, at := range []time.Time{reservation.StartTime, reservation.EndTime.Add(-time.Minute)} {
+		open, err := s.schedules.IsOpenAt(fmt.Sprint(*reservation.StoreID), at)
+		if err != nil {
+			shared.LogError("error checking store schedule", LogService, "Create", err, *reservation.StoreID, at)
+			return nil, fmt.Errorf(ErrorReservationCreating)
+		}
+
+		if !open {
+			return nil, fmt.Errorf(ErrorReservationStoreClosed)
+		}
+	}
+
+	candidates, err := s.candidateTables(reservation.StoreID, reservation.TableID, reservation.ZoneID, reservation.PartySize, reservation.StartTime, reservation.EndTime)
+	if err != nil {
+		return nil, err
+	}
+
+	requested := reservation.TableID != nil && *reservation.TableID != 0
+	for _, table := range candidates {
+		now := time.Now()
+		heldUntil := now.Add(ReservationHoldDuration)
+		tableID := table.ID
+		reservation.ID = 0
+		reservation.TableID = &tableID
+		reservation.ZoneID = table.ZoneID
+		reservation.Status = ReservationStatusHeld
+		reservation.HeldUntil = &heldUntil
+
+		reservationDB, err := s.repository.CreateOnFreeTable(reservation, now)
+		if err == ErrTableKept {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf(ErrorReservationCreating)
+		}
+
+		return reservationDB, nil
+	}
+
+	if requested {
+		return nil, fmt.Errorf(ErrorReservationTableUnavailable)
+	}
+
+	return nil, fmt.Errorf(ErrorReservationNoCapacity)
+}
+
+func (s service) Get(reservationID string) (*Reservation, error) {
+	return s.repository.Get(reservationID)
+}
+
+func (s service) Find(filter map[string]any) ([]Reservation, error) {
+	return s.repository.Find(filter)
+}
+
+// Confirm keeps the held table for the reservation.
+func (s service) Confirm(reservationID string) (*Reservation, error) {
+	reservation, err := s.repository.Get(reservationID)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorReservationGetting)
+	}
+
+	if reservation.Status == ReservationStatusConfirmed {
+		return reservation, nil
+	}
+
+	if reservation.Status != ReservationStatusHeld {
+		return nil, fmt.Errorf(ErrorReservationWrongStatus)
+	}
+
+	if !reservation.IsActive(time.Now()) {
+		return nil, fmt.Errorf(ErrorReservationHoldExpired)
+	}
+
+	reservation.Status = ReservationStatusConfirmed
+	reservation.HeldUntil = nil
+
+	return s.repository.Update(reservation)
+}
+
+func (s service) Cancel(reservationID string) (*Reservation, error) {
+	return s.closeReservation(reservationID, ReservationStatusCancelled)
+}
+
+func (s service) NoShow(reservationID string) (*Reservation, error) {
+	return s.closeReservation(reservationID, ReservationStatusNoShow)
+}
+
+func (s service) closeReservation(reservationID, status string) (*Reservation, error) {
+	reservation, err := s.repository.Get(reservationID)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorReservationGetting)
+	}
+
+	if reservation.Status != ReservationStatusHeld && reservation.Status != ReservationStatusConfirmed {
+		return nil, fmt.Errorf(ErrorReservationWrongStatus)
+	}
+
+	reservation.Status = status
+	reservation.HeldUntil = nil
+
+	return s.repository.Update(reservation)
+}
+
+// Seat creates the order on the assigned table and marks the table as seated.
+func (s service) Seat(reservationID string, ctx context.Context) (*Reservation, error) {
+	reservation, err := s.repository.Get(reservationID)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorReservationGetting)
+	}
+
+	if reservation.Status == ReservationStatusSeated {
+		return reservation, nil
+	}
+
+	if !reservation.IsActive(time.Now()) {
+		return nil, fmt.Errorf(ErrorReservationWrongStatus)
+	}
+
+	if reservation.TableID == nil {
+		return nil, fmt.Errorf(ErrorReservationTableUnavailable)
+	}
+
+	orderDB, err := s.seat(ctx, reservation.TableID, reservation.BrandID, reservation.StoreID, reservation.ChannelID, reservation.PartySize, reservation.ClientName, reservation.Notes)
+	if err != nil {
+		shared.LogError("error seating reservation", LogService, "Seat", err, reservationID)
+		return nil, err
+	}
+
+	now := time.Now()
+	reservation.Status = ReservationStatusSeated
+	reservation.OrderID = &orderDB.ID
+	reservation.SeatedAt = &now
+	reservation.HeldUntil = nil
+
+	return s.repository.Update(reservation)
+}
+
+// AddToWaitlist registers a walk-in party and quotes the wait time.
+func (s service) AddToWaitlist(entry *WaitlistEntry) (*WaitlistEntry, error) {
+	if entry.StoreID == nil {
+		return nil, fmt.Errorf(ErrorReservationBadRequest)
+	}
+
+	if entry.PartySize <= 0 {
+		return nil, fmt.Errorf(ErrorReservationPartySize)
+	}
+
+	quote, err := s.quoteWait(*entry.StoreID, entry.PartySize)
+	if err != nil {
+		return nil, err
+	}
+
+	entry.Status = WaitlistStatusWaiting
+	entry.QuotedWaitMinutes = quote
+
+	entryDB, err := s.repository.CreateWaitlistEntry(entry)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorWaitlistCreating)
+	}
+
+	return entryDB, nil
+}
+
+func (s service) FindWaitlist(filter map[string]any) ([]WaitlistEntry, error) {
+	return s.repository.FindWaitlist(filter)
+}
+
+// SeatWaitlist seats the party on the given table or on the smallest free table that fits.
+func (s service) SeatWaitlist(entryID string, tableID *uint, ctx context.Context) (*WaitlistEntry, error) {
+	entry, err := s.repository.GetWaitlistEntry(entryID)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorWaitlistGetting)
+	}
+
+	if entry.Status != WaitlistStatusWaiting {
+		return nil, fmt.Errorf(ErrorWaitlistWrongStatus)
+	}
+
+	now := time.Now()
+	table, err := s.pickTable(entry.StoreID, tableID, nil, entry.PartySize, now, now.Add(WaitlistTurnDuration))
+	if err != nil {
+		return nil, err
+	}
+
+	orderDB, err := s.seat(ctx, &table.ID, entry.BrandID, entry.StoreID, entry.ChannelID, entry.PartySize, entry.ClientName, entry.Notes)
+	if err != nil {
+		shared.LogError("error seating waitlist entry", LogService, "SeatWaitlist", err, entryID)
+		return nil, err
+	}
+
+	entry.Status = WaitlistStatusSeated
+	entry.TableID = &table.ID
+	entry.OrderID = &orderDB.ID
+	entry.SeatedAt = &now
+
+	return s.repository.UpdateWaitlistEntry(entry)
+}
+
+func (s service) CancelWaitlist(entryID string) (*WaitlistEntry, error) {
+	entry, err := s.repository.GetWaitlistEntry(entryID)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorWaitlistGetting)
+	}
+
+	if entry.Status != WaitlistStatusWaiting {
+		return nil, fmt.Errorf(ErrorWaitlistWrongStatus)
+	}
+
+	entry.Status = WaitlistStatusCancelled
+
+	return s.repository.UpdateWaitlistEntry(entry)
+}
+
+// seat creates the order on the table, the order sets the table as ordered so it is moved back to seated.
+func (s service) seat(ctx context.Context, tableID, brandID, storeID, channelID *uint, partySize int, clientName, notes string) (*order.Order, error) {
+	newOrder := &order.Order{
+		BrandID:    brandID,
+		StoreID:    storeID,
+		ChannelID:  channelID,
+		TableID:    tableID,
+		Seats:      partySize,
+		ClientName: clientName,
+		Comments:   notes,
+	}
+
+	orderDB, err := s.orders.Create("", newOrder, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.tables.SetState(*tableID, tables.TableStateSeated); err != nil {
+		shared.LogWarn("error setting table state", LogService, "seat", err, *tableID)
+	}
+
+	return orderDB, nil
+}
+
+// pickTable returns the requested table when it is free, or the smallest free table that fits the party.
+func (s service) pickTable(storeID, tableID, zoneID *uint, partySize int, start, end time.Time) (*tables.Table, error) {
+	candidates, err := s.candidateTables(storeID, tableID, zoneID, partySize, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	return &candidates[0], nil
+}
+
+// candidateTables returns the requested table when it is free, or the free tables of the zone that fit the
+// party, smaller tables first.
+func (s service) candidateTables(storeID, tableID, zoneID *uint, partySize int, start, end time.Time) ([]tables.Table, error) {
+	if storeID == nil {
+		return nil, fmt.Errorf(ErrorReservationBadRequest)
+	}
+
+	free, err := s.freeTables(*storeID, partySize, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]tables.Table, 0)
+	for _, table := range free {
+		if tableID != nil && *tableID != 0 {
+			if table.ID == *tableID {
+				return []tables.Table{table}, nil
+			}
+			continue
+		}
+
+		if zoneID != nil && *zoneID != 0 && (table.ZoneID == nil || *table.ZoneID != *zoneID) {
+			continue
+		}
+
+		candidates = append(candidates, table)
+	}
+
+	if len(candidates) > 0 {
+		return candidates, nil
+	}
+
+	if tableID != nil && *tableID != 0 {
+		return nil, fmt.Errorf(ErrorReservationTableUnavailable)
+	}
+
+	return nil, fmt.Errorf(ErrorReservationNoCapacity)
+}
+
+// freeTables returns the active tables of the store that fit the party and are not kept by another
+// reservation between start and end, tables with an open order are busy when the slot starts now.
+func (s service) freeTables(storeID uint, partySize int, start, end time.Time) ([]tables.Table, error) {
+	fitting, err := s.fittingTables(storeID, partySize)
+	if err != nil {
+		return nil, err
+	}
+
+	reservations, err := s.repository.FindOverlapping(storeID, start, end)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorReservationAvailability)
+	}
+
+	now := time.Now()
+	kept := make(map[uint]bool)
+	for _, reservation := range reservations {
+		if reservation.TableID == nil || !reservation.IsActive(now) {
+			continue
+		}
+		kept[*reservation.TableID] = true
+	}
+
+	startsNow := !start.After(now.Add(WaitlistTurnDuration))
+
+	free := make([]tables.Table, 0)
+	for _, table := range fitting {
+		if kept[table.ID] {
+			continue
+		}
+		if startsNow && table.OrderID != nil {
+			continue
+		}
+		free = append(free, table)
+	}
+
+	return free, nil
+}
+
+// fittingTables returns the active tables in the active zones of the store with capacity for the party.
+func (s service) fittingTables(storeID uint, partySize int) ([]tables.Table, error) {
+	zones, err := s.stores.FindZonesByStore(fmt.Sprint(storeID))
+	if err != nil {
+		shared.LogError("error getting store zones", LogService, "fittingTables", err, storeID)
+		return nil, fmt.Errorf(ErrorReservationAvailability)
+	}
+
+	fitting := make([]tables.Table, 0)
+	for _, zone := range zones {
+		if !zone.Active {
+			continue
+		}
+		for _, table := range zone.Tables {
+			if table.IsActive && table.Capacity >= partySize {
+				fitting = append(fitting, table)
+			}
+		}
+	}
+
+	sort.SliceStable(fitting, func(i, j int) bool {
+		return fitting[i].Capacity < fitting[j].Capacity
+	})
+
+	return fitting, nil
+}
+
+// quoteWait estimates the wait in minutes from the parties ahead and the tables that fit the party,
+// every round of fitting tables takes an average turn.
+func (s service) quoteWait(storeID uint, partySize int) (int, error) {
+	fitting, err := s.fittingTables(storeID, partySize)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(fitting) == 0 {
+		return 0, fmt.Errorf(ErrorReservationNoCapacity)
+	}
+
+	now := time.Now()
+	free, err := s.freeTables(storeID, partySize, now, now.Add(WaitlistTurnDuration))
+	if err != nil {
+		return 0, err
+	}
+
+	waiting, err := s.repository.FindWaitlist(map[string]any{"store_id": storeID, "status": WaitlistStatusWaiting})
+	if err != nil {
+		return 0, fmt.Errorf(ErrorWaitlistFinding)
+	}
+
+	ahead := 0
+	for _, entry := range waiting {
+		if entry.PartySize <= fitting[len(fitting)-1].Capacity {
+			ahead++
+		}
+	}
+
+	if ahead < len(free) {
+		return 0, nil
+	}
+
+	rounds := math.Ceil(float64(ahead-len(free)+1) / float64(len(fitting)))
+
+	return int(rounds * WaitlistTurnDuration.Minutes()), nil
+}
+
+var _ Service = service{}
@@ -11,11 +11,14 @@ import (
 	"github.com/BacoFoods/menu/internal"
 	"github.com/BacoFoods/menu/internal/telemetry"
 	"github.com/BacoFoods/menu/pkg/account"
+	"github.com/BacoFoods/menu/pkg/aggregator"
+	"github.com/BacoFoods/menu/pkg/analytics"
 	"github.com/BacoFoods/menu/pkg/app"
 	"github.com/BacoFoods/menu/pkg/assets"
 	"github.com/BacoFoods/menu/pkg/availability"
 	"github.com/BacoFoods/menu/pkg/brand"
 	"github.com/BacoFoods/menu/pkg/cashaudit"
+	"github.com/BacoFoods/menu/pkg/catalog"
 	"github.com/BacoFoods/menu/pkg/category"
 	"github.com/BacoFoods/menu/pkg/channel"
 	"github.com/BacoFoods/menu/pkg/client"
@@ -26,11 +29,15 @@ import (
 	"github.com/BacoFoods/menu/pkg/discount"
 	"github.com/BacoFoods/menu/pkg/facturacion"
 	"github.com/BacoFoods/menu/pkg/healthcheck"
+	"github.com/BacoFoods/menu/pkg/inventory"
 	"github.com/BacoFoods/menu/pkg/invoice"
 	"github.com/BacoFoods/menu/pkg/menu"
 	"github.com/BacoFoods/menu/pkg/order"
 	"github.com/BacoFoods/menu/pkg/payment"
 	"github.com/BacoFoods/menu/pkg/product"
+	"github.com/BacoFoods/menu/pkg/realtime"
+	"github.com/BacoFoods/menu/pkg/report"
+	"github.com/BacoFoods/menu/pkg/reservation"
 	"github.com/BacoFoods/menu/pkg/scheduler"
 	"github.com/BacoFoods/menu/pkg/shared"
 	"github.com/BacoFoods/menu/pkg/shift"
@@ -101,9 +108,14 @@ func NewRouter(routes *RoutesGroup) Router {
 	routes.CashAudit.RegisterRoutes(private)
 	routes.Assets.RegisterRoutes(private)
 	routes.Facturacion.RegisterRoutes(private)
-	routes.Schedule.RegisterRoutes(private)
-	routes.Equivalence.RegisterRoutes(private)
+	routes.Connector.RegisterRoutes(private)
 	routes.Siesa.RegisterRoutes(private)
+	routes.Realtime.RegisterRoutes(private)
+	routes.Reservation.RegisterRoutes(private)
+	routes.Report.RegisterRoutes(private)
+	routes.Analytics.RegisterRoutes(private)
+	routes.Inventory.RegisterRoutes(private)
+	routes.Catalog.RegisterRoutes(private)
 	routes.App.RegisterRoutes(privateGroup)
 
 	// Register public routes
@@ -116,6 +128,8 @@ func NewRouter(routes *RoutesGroup) Router {
 	routes.Menu.RegisterRoutes(private, public)
 	routes.Account.RegisterRoutes(private, public)
 	routes.Order.RegisterRoutes(private, public)
+	routes.Schedule.RegisterRoutes(private, public)
+	routes.Aggregator.RegisterRoutes(private, public)
 	routes.Telemetry.RegisterRoutes(publicGroup)
 
 	routes.Swagger.Register(publicGroup)
@@ -152,8 +166,15 @@ type RoutesGroup struct {
 	Assets       assets.Routes
 	Facturacion  facturacion.Routes
 	Schedule     scheduler.Routes
-	Equivalence  connector.Routes
+	Connector    connector.Routes
 	Siesa        siesa.Routes
 	App          app.Routes
+	Realtime     realtime.Routes
+	Reservation  reservation.Routes
+	Report       report.Routes
+	Analytics    analytics.Routes
+	Inventory    inventory.Routes
+	Catalog      catalog.Routes
+	Aggregator   aggregator.Routes
 	Telemetry    telemetry.Routes
 }
@@ -2,11 +2,11 @@ package scheduler
 
 import (
 	"fmt"
+	"time"
+
 	"github.com/BacoFoods/menu/pkg/shared"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
-	"strings"
-	"time"
 )
 
 const (
@@ -23,7 +23,7 @@ func NewDBRepository(db *gorm.DB) DBRepository {
 
 func (r DBRepository) Find(filter map[string]any) ([]Schedule, error) {
 	var schedule []Schedule
-	if err := r.db.Preload(clause.Associations).Find(&schedule, filter).Error; err != nil {
+	if err := r.db.Preload(clause.Associations).Preload("Store.Country").Find(&schedule, filter).Error; err != nil {
 		shared.LogError("error finding schedule", LogRepository, "Get", err, filter)
 		return nil, fmt.Errorf(ErrorScheduleFinding)
 	}
@@ -31,17 +31,8 @@ func (r DBRepository) Find(filter map[string]any) ([]Schedule, error) {
 }
 
 func (r DBRepository) Create(schedule *Schedule) error {
-	var storeDaySchedule Schedule
-	if err := r.db.Where("store_id = ? AND day = ?", schedule.StoreID, schedule.Day).First(&storeDaySchedule).Error; err != nil {
-		if err != gorm.ErrRecordNotFound {
-			shared.LogError("error finding schedule", LogRepository, "Create", err, *schedule)
-			return fmt.Errorf(ErrorScheduleCreating)
-		}
-	}
-
-	if storeDaySchedule.ID != 0 {
-		shared.LogError("schedule already exists", LogRepository, "Create", nil, *schedule)
-		return fmt.Errorf(ErrorScheduleCreatingDayAlreadyExists)
+	if err := r.checkOverlapping(schedule, "Create"); err != nil {
+		return err
 	}
 
 	if err := r.db.Create(schedule).Error; err != nil {
@@ -52,6 +43,10 @@ func (r DBRepository) Create(schedule *Schedule) error {
 }
 
 func (r DBRepository) Update(schedule *Schedule) error {
+	if err := r.checkOverlapping(schedule, "Update"); err != nil {
+		return err
+	}
+
 	if err := r.db.Save(schedule).Error; err != nil {
 		shared.LogError("error updating schedule", LogRepository, "Update", err, *schedule)
 		return fmt.Errorf(ErrorScheduleUpdating)
@@ -59,35 +54,31 @@ func (r DBRepository) Update(schedule *Schedule) error {
 	return nil
 }
 
-func (r DBRepository) Delete(schedule *Schedule) error {
-	if err := r.db.Delete(schedule).Error; err != nil {
-		shared.LogError("error deleting schedule", LogRepository, "Delete", err, *schedule)
-		return fmt.Errorf(ErrorScheduleDeleting)
+// checkOverlapping rejects the schedule when another schedule of the store on the same day, or the overnight
+// hours of the day before or after, overlaps it
+func (r DBRepository) checkOverlapping(schedule *Schedule, method string) error {
+	var daySchedules []Schedule
+	if err := r.db.Where("store_id = ? AND day IN ?", schedule.StoreID, AdjacentDays(schedule.Day)).Find(&daySchedules).Error; err != nil {
+		shared.LogError("error finding schedules", LogRepository, method, err, *schedule)
+		return fmt.Errorf(ErrorScheduleFinding)
 	}
-	return nil
-}
 
-func (r DBRepository) TodayStore(storeID string) (*Schedule, error) {
-	var schedule Schedule
-	day := strings.ToLower(time.Now().Weekday().String())
-	if err := r.db.Where("store_id = ? AND day = ? and enable = true", storeID, day).First(&schedule).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, nil
+	for _, daySchedule := range daySchedules {
+		if schedule.Overlaps(&daySchedule) {
+			shared.LogWarn("schedule overlaps", LogRepository, method, nil, *schedule, daySchedule)
+			return fmt.Errorf(ErrorScheduleOverlapping)
 		}
-		shared.LogError("error finding schedule", LogRepository, "Today", err, storeID)
-		return &schedule, fmt.Errorf(ErrorScheduleFindingTodayStore)
 	}
-	return &schedule, nil
+
+	return nil
 }
 
-func (r DBRepository) TodayBrand(brandID string) ([]Schedule, error) {
-	var schedules []Schedule
-	day := strings.ToLower(time.Now().Weekday().String())
-	if err := r.db.Where("brand_id = ? AND day = ?", brandID, day).Find(&schedules).Error; err != nil {
-		shared.LogError("error finding schedules", LogRepository, "Today", err, brandID)
-		return nil, fmt.Errorf(ErrorScheduleFindingTodayBrand)
+func (r DBRepository) Delete(schedule *Schedule) error {
+	if err := r.db.Delete(schedule).Error; err != nil {
+		shared.LogError("error deleting schedule", LogRepository, "Delete", err, *schedule)
+		return fmt.Errorf(ErrorScheduleDeleting)
 	}
-	return schedules, nil
+	return nil
 }
 
 func (r DBRepository) EnableStore(storeID string, enable bool) ([]Schedule, error) {
@@ -148,15 +139,33 @@ func (r DBRepository) FindHoliday() ([]Holiday, error) {
 	return holidays, nil
 }
 
-func (r DBRepository) GetTodayHoliday() (*Holiday, error) {
-	var holiday Holiday
-	day := time.Now().UTC().Add(-5 * time.Hour)
-	if err := r.db.Where("day = ?", day.Format("2006-01-02")).First(&holiday).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, nil
-		}
-		shared.LogError("error finding holiday", LogRepository, "GetTodayHoliday", err, day)
-		return &holiday, fmt.Errorf(ErrorHolidayFinding)
+func (r DBRepository) CreateException(exception *ScheduleException) (*ScheduleException, error) {
+	if err := r.db.Create(exception).Error; err != nil {
+		shared.LogError("error creating schedule exception", LogRepository, "CreateException", err, *exception)
+		return nil, fmt.Errorf(ErrorExceptionCreating)
 	}
-	return &holiday, nil
+	return exception, nil
 }
+
+func (r DBRepository) DeleteException(exceptionID string) error {
+	if err := r.db.Delete(&ScheduleException{}, exceptionID).Error; err != nil {
+		shared.LogError("error deleting schedule exception", LogRepository, "DeleteException", err, exceptionID)
+		return fmt.Errorf(ErrorExceptionDeleting)
+	}
+	return nil
+}
+
+// FindExceptions returns the exceptions of the store with dates between from and to, both included
+func (r DBRepository) FindExceptions(storeID string, from, to time.Time) ([]ScheduleException, error) {
+	var exceptions []ScheduleException
+	if err := r.db.
+		Where("store_id = ? AND date BETWEEN ? AND ?", storeID, from.Format(dateLayout), to.Format(dateLayout)).
+		Order("date ASC").
+		Find(&exceptions).Error; err != nil {
+		shared.LogError("error finding schedule exceptions", LogRepository, "FindExceptions", err, storeID, from, to)
+		return nil, fmt.Errorf(ErrorExceptionFinding)
+	}
+	return exceptions, nil
+}
+
+var _ Repository = DBRepository{}
@@ -1,26 +1,45 @@
 package scheduler
 
 import (
-	"github.com/BacoFoods/menu/pkg/store"
-	"gorm.io/gorm"
+	"sort"
 	"strings"
 	"time"
+
+	"github.com/BacoFoods/menu/pkg/store"
+	"gorm.io/gorm"
 )
 
 const (
-	ErrorScheduleFinding                  = "error finding schedules"
-	ErrorScheduleCreating                 = "error creating schedule"
-	ErrorScheduleUpdating                 = "error updating schedule"
-	ErrorScheduleDeleting                 = "error deleting schedule"
-	ErrorScheduleFindingTodayStore        = "error finding today schedule for store"
-	ErrorScheduleFindingTodayBrand        = "error finding today schedule for brand"
-	ErrorScheduleCreatingDayAlreadyExists = "error creating schedule, day already exists"
-	ErrorHolidayCreating                  = "error creating holiday"
-	ErrorHolidayUpdating                  = "error updating holiday"
-	ErrorHolidayDeleting                  = "error deleting holiday"
-	ErrorHolidayFinding                   = "error finding holiday"
+	ErrorScheduleFinding           = "error finding schedules"
+	ErrorScheduleCreating          = "error creating schedule"
+	ErrorScheduleUpdating          = "error updating schedule"
+	ErrorScheduleDeleting          = "error deleting schedule"
+	ErrorScheduleFindingTodayStore = "error finding today schedule for store"
+	ErrorScheduleFindingTodayBrand = "error finding today schedule for brand"
+	ErrorScheduleOverlapping       = "error schedule overlaps another schedule of the store"
+	ErrorScheduleFindingOpening    = "error finding store next opening"
+	ErrorHolidayCreating           = "error creating holiday"
+	ErrorHolidayUpdating           = "error updating holiday"
+	ErrorHolidayDeleting           = "error deleting holiday"
+	ErrorHolidayFinding            = "error finding holiday"
+	ErrorExceptionCreating         = "error creating schedule exception"
+	ErrorExceptionDeleting         = "error deleting schedule exception"
+	ErrorExceptionFinding          = "error finding schedule exceptions"
+	ErrorExceptionInvalidHours     = "error schedule exception needs opening and closing hours when not closed"
+	ErrorExceptionInvalidDate      = "error schedule exception date must be formatted as 2006-01-02"
+
+	DayHoliday = "holiday"
+
+	// NextOpeningDays is how many days ahead are checked looking for the next opening of a store
+	NextOpeningDays = 14
+
+	hourLayout = "15:04"
+	dateLayout = "2006-01-02"
 )
 
+// Schedule is an opening interval of a store on a week day, a store may have several schedules
+// on the same day as long as they don't overlap. A closing hour at or before the opening hour
+// closes on the next day, so 18:00 to 02:00 is an overnight range.
 type Schedule struct {
 	ID        uint            `json:"id"`
 	StoreID   *uint           `json:"store_id" binding:"required"`
@@ -35,39 +54,86 @@ type Schedule struct {
 	DeletedAt *gorm.DeletedAt `json:"deleted_at,omitempty" swaggerignore:"true"`
 }
 
-func (s *Schedule) IsOpen(holiday *Holiday) bool {
-	if holiday != nil && holiday.Enable == true && s.Day == "holiday" && s.Enable {
-		now := time.Now().UTC().Add(-5 * time.Hour)
-
-		opening, _ := time.Parse("15:04", s.Opening)
-		nowOpening := time.Date(now.Year(), now.Month(), now.Day(), opening.Hour(), opening.Minute(), opening.Second(), opening.Nanosecond(), time.UTC)
+// IntervalOn returns the opening interval of the schedule starting on the date of the given day,
+// the day of the schedule must be checked by the caller.
+func (s *Schedule) IntervalOn(day time.Time) (*Interval, bool) {
+	if !s.Enable {
+		return nil, false
+	}
 
-		closing, _ := time.Parse("15:04", s.Closing)
-		nowClosing := time.Date(now.Year(), now.Month(), now.Day(), closing.Hour(), closing.Minute(), closing.Second(), closing.Nanosecond(), time.UTC)
+	return interval(day, s.Opening, s.Closing)
+}
 
-		response := now.After(nowOpening) && now.Before(nowClosing)
-		return response
+// Overlaps checks if another schedule of the same day has overlapping hours, the overnight hours of a
+// schedule are also checked against the schedules of the next day.
+func (s *Schedule) Overlaps(other *Schedule) bool {
+	if s.ID == other.ID {
+		return false
 	}
 
-	// If the day is not the same as today, return false, so it doesn't show as open
-	if strings.ToLower(time.Now().Weekday().String()) != s.Day {
+	offset, ok := dayOffset(s.Day, other.Day)
+	if !ok {
 		return false
 	}
 
-	if !s.Enable {
+	reference := time.Date(2000, time.January, 3, 0, 0, 0, 0, time.UTC)
+	current, ok := interval(reference, s.Opening, s.Closing)
+	if !ok {
+		return false
+	}
+	another, ok := interval(reference.AddDate(0, 0, offset), other.Opening, other.Closing)
+	if !ok {
 		return false
 	}
 
-	now := time.Now().UTC().Add(-5 * time.Hour)
+	return current.OpensAt.Before(another.ClosesAt) && another.OpensAt.Before(current.ClosesAt)
+}
 
-	opening, _ := time.Parse("15:04", s.Opening)
-	nowOpening := time.Date(now.Year(), now.Month(), now.Day(), opening.Hour(), opening.Minute(), opening.Second(), opening.Nanosecond(), time.UTC)
+// AdjacentDays returns the day with the days before and after it, the schedules that may overlap the
+// schedules of the day. The holiday schedules only overlap among them.
+func AdjacentDays(day string) []string {
+	index, ok := weekDays[day]
+	if !ok {
+		return []string{day}
+	}
 
-	closing, _ := time.Parse("15:04", s.Closing)
-	nowClosing := time.Date(now.Year(), now.Month(), now.Day(), closing.Hour(), closing.Minute(), closing.Second(), closing.Nanosecond(), time.UTC)
+	return []string{day, WeekDayName((index + 6) % 7), WeekDayName((index + 1) % 7)}
+}
 
-	response := now.After(nowOpening) && now.Before(nowClosing)
-	return response
+// dayOffset returns the days from the first day to the other when they are the same or adjacent days
+func dayOffset(day, other string) (int, bool) {
+	if day == other {
+		return 0, true
+	}
+
+	index, ok := weekDays[day]
+	otherIndex, otherOk := weekDays[other]
+	if !ok || !otherOk {
+		return 0, false
+	}
+
+	switch (otherIndex - index + 7) % 7 {
+	case 1:
+		return 1, true
+	case 6:
+		return -1, true
+	}
+	return 0, false
+}
+
+var weekDays = map[string]int{
+	"sunday":    int(time.Sunday),
+	"monday":    int(time.Monday),
+	"tuesday":   int(time.Tuesday),
+	"wednesday": int(time.Wednesday),
+	"thursday":  int(time.Thursday),
+	"friday":    int(time.Friday),
+	"saturday":  int(time.Saturday),
+}
+
+// WeekDayName returns the schedule day name of a week day
+func WeekDayName(index int) string {
+	return strings.ToLower(time.Weekday(index).String())
 }
 
 func (s *Schedule) ToMap() map[string]any {
@@ -86,14 +152,14 @@ type Repository interface {
 	Create(schedule *Schedule) error
 	Update(schedule *Schedule) error
 	Delete(schedule *Schedule) error
-	TodayStore(storeID string) (*Schedule, error)
-	TodayBrand(brandID string) ([]Schedule, error)
 	EnableStore(storeID string, enable bool) ([]Schedule, error)
 	CreateHoliday(*Holiday) (*Holiday, error)
 	UpdateHoliday(*Holiday) (*Holiday, error)
 	DeleteHoliday(*Holiday) error
 	FindHoliday() ([]Holiday, error)
-	GetTodayHoliday() (*Holiday, error)
+	CreateException(*ScheduleException) (*ScheduleException, error)
+	DeleteException(exceptionID string) error
+	FindExceptions(storeID string, from, to time.Time) ([]ScheduleException, error)
 }
 
 type Holiday struct {
@@ -106,3 +172,165 @@ type Holiday struct {
 	UpdatedAt *time.Time      `json:"updated_at" swaggerignore:"true"`
 	DeletedAt *gorm.DeletedAt `json:"deleted_at,omitempty" swaggerignore:"true"`
 }
+
+// IsHolidayDate checks if the holiday is enabled for the date of the given day.
+func (h *Holiday) IsHolidayDate(day time.Time) bool {
+	if !h.Enable {
+		return false
+	}
+	return h.Day.Format(dateLayout) == day.Format(dateLayout)
+}
+
+// ScheduleException replaces the schedules of a store on a date, a closed exception keeps the
+// store closed all that day, otherwise each exception is an opening interval of the date.
+type ScheduleException struct {
+	ID        uint            `json:"id"`
+	StoreID   *uint           `json:"store_id"`
+	Date      time.Time       `json:"date" gorm:"type:date"`
+	Closed    bool            `json:"closed"`
+	Opening   string          `json:"open" example:"23:59"`
+	Closing   string          `json:"close" example:"23:59"`
+	Reason    string          `json:"reason"`
+	CreatedAt *time.Time      `json:"created_at" swaggerignore:"true"`
+	UpdatedAt *time.Time      `json:"updated_at" swaggerignore:"true"`
+	DeletedAt *gorm.DeletedAt `json:"deleted_at,omitempty" swaggerignore:"true"`
+}
+
+// IsDate checks if the exception is for the date of the given day.
+func (e *ScheduleException) IsDate(day time.Time) bool {
+	return e.Date.Format(dateLayout) == day.Format(dateLayout)
+}
+
+// Interval is a time range in which a store is open, the closing may fall on the next day.
+type Interval struct {
+	OpensAt  time.Time `json:"opens_at"`
+	ClosesAt time.Time `json:"closes_at"`
+}
+
+func (i Interval) Contains(at time.Time) bool {
+	return !at.Before(i.OpensAt) && at.Before(i.ClosesAt)
+}
+
+// interval builds the opening interval on the date and location of the given day.
+func interval(day time.Time, opening, closing string) (*Interval, bool) {
+	open, err := time.Parse(hourLayout, opening)
+	if err != nil {
+		return nil, false
+	}
+	close, err := time.Parse(hourLayout, closing)
+	if err != nil {
+		return nil, false
+	}
+
+	location := day.Location()
+	opensAt := time.Date(day.Year(), day.Month(), day.Day(), open.Hour(), open.Minute(), 0, 0, location)
+	closesAt := time.Date(day.Year(), day.Month(), day.Day(), close.Hour(), close.Minute(), 0, 0, location)
+	if !closesAt.After(opensAt) {
+		closesAt = time.Date(day.Year(), day.Month(), day.Day()+1, close.Hour(), close.Minute(), 0, 0, location)
+	}
+
+	return &Interval{OpensAt: opensAt, ClosesAt: closesAt}, true
+}
+
+// WeekDay returns the schedule day name for the date of the given time on its location.
+func WeekDay(at time.Time) string {
+	return strings.ToLower(at.Weekday().String())
+}
+
+// Calendar has the schedules, brand holidays and exceptions of a store on its time zone.
+type Calendar struct {
+	Location   *time.Location
+	Schedules  []Schedule
+	Holidays   []Holiday
+	Exceptions []ScheduleException
+}
+
+// IntervalsOn returns the opening intervals starting on the date of the given day, the exceptions of
+// the date take precedence over the holiday schedules and those over the week day schedules.
+func (c *Calendar) IntervalsOn(day time.Time) []Interval {
+	day = day.In(c.Location)
+	intervals := make([]Interval, 0)
+
+	exceptions := make([]ScheduleException, 0)
+	for _, exception := range c.Exceptions {
+		if exception.IsDate(day) {
+			exceptions = append(exceptions, exception)
+		}
+	}
+
+	if len(exceptions) > 0 {
+		for _, exception := range exceptions {
+			if exception.Closed {
+				return make([]Interval, 0)
+			}
+			if opening, ok := interval(day, exception.Opening, exception.Closing); ok {
+				intervals = append(intervals, *opening)
+			}
+		}
+		return sortIntervals(intervals)
+	}
+
+	weekDay := WeekDay(day)
+	for _, holiday := range c.Holidays {
+		if holiday.IsHolidayDate(day) {
+			weekDay = DayHoliday
+			break
+		}
+	}
+
+	for _, schedule := range c.Schedules {
+		if schedule.Day != weekDay {
+			continue
+		}
+		if opening, ok := schedule.IntervalOn(day); ok {
+			intervals = append(intervals, *opening)
+		}
+	}
+
+	return sortIntervals(intervals)
+}
+
+// IsOpenAt checks the intervals of the day and the overnight intervals of the day before.
+func (c *Calendar) IsOpenAt(at time.Time) bool {
+	local := at.In(c.Location)
+	for _, day := range []time.Time{local.AddDate(0, 0, -1), local} {
+		for _, opening := range c.IntervalsOn(day) {
+			if opening.Contains(at) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// NextOpening returns the interval in which the store is open at the given time, or the next one
+// to open within the given number of days.
+func (c *Calendar) NextOpening(from time.Time, days int) *Interval {
+	local := from.In(c.Location)
+	for offset := -1; offset <= days; offset++ {
+		for _, opening := range c.IntervalsOn(local.AddDate(0, 0, offset)) {
+			if opening.ClosesAt.After(from) {
+				return &opening
+			}
+		}
+	}
+
+	return nil
+}
+
+func sortIntervals(intervals []Interval) []Interval {
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i].OpensAt.Before(intervals[j].OpensAt)
+	})
+	return intervals
+}
+
+// StoreOpening is the current or next opening of a store shown by the ordering channels.
+type StoreOpening struct {
+	StoreID  uint       `json:"store_id"`
+	Timezone string     `json:"timezone"`
+	Open     bool       `json:"open"`
+	OpensAt  *time.Time `json:"opens_at"`
+	ClosesAt *time.Time `json:"closes_at"`
+}
@@ -0,0 +1,105 @@
+package scheduler_test
+
+import (
+	"time"
+
+	"github.com/BacoFoods/menu/pkg/scheduler"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func schedule(id uint, day, opening, closing string) scheduler.Schedule {
+	return scheduler.Schedule{ID: id, Day: day, Opening: opening, Closing: closing, Enable: true}
+}
+
+var _ = Describe("Schedule overlaps", func() {
+	It("Compares the hours of the same day", func() {
+		lunch := schedule(1, "monday", "12:00", "15:00")
+		Expect(lunch.Overlaps(&scheduler.Schedule{ID: 2, Day: "monday", Opening: "14:00", Closing: "18:00"})).To(BeTrue())
+		Expect(lunch.Overlaps(&scheduler.Schedule{ID: 2, Day: "monday", Opening: "15:00", Closing: "18:00"})).To(BeFalse())
+		Expect(lunch.Overlaps(&scheduler.Schedule{ID: 2, Day: "tuesday", Opening: "12:00", Closing: "15:00"})).To(BeFalse())
+	})
+
+	It("Doesn't compare the schedule with itself", func() {
+		lunch := schedule(1, "monday", "12:00", "15:00")
+		Expect(lunch.Overlaps(&lunch)).To(BeFalse())
+	})
+
+	It("Compares the overnight hours with the next day", func() {
+		night := schedule(1, "friday", "18:00", "02:00")
+		breakfast := schedule(2, "saturday", "01:00", "11:00")
+		Expect(night.Overlaps(&breakfast)).To(BeTrue())
+		Expect(breakfast.Overlaps(&night)).To(BeTrue())
+
+		late := schedule(3, "saturday", "02:00", "11:00")
+		Expect(night.Overlaps(&late)).To(BeFalse())
+	})
+
+	It("Wraps the overnight hours of sunday to monday", func() {
+		night := schedule(1, "sunday", "20:00", "03:00")
+		early := schedule(2, "monday", "00:30", "06:00")
+		Expect(night.Overlaps(&early)).To(BeTrue())
+	})
+
+	It("Only compares the holiday schedules among them", func() {
+		holiday := schedule(1, scheduler.DayHoliday, "10:00", "16:00")
+		Expect(holiday.Overlaps(&scheduler.Schedule{ID: 2, Day: scheduler.DayHoliday, Opening: "15:00", Closing: "20:00"})).To(BeTrue())
+		Expect(holiday.Overlaps(&scheduler.Schedule{ID: 2, Day: "monday", Opening: "10:00", Closing: "16:00"})).To(BeFalse())
+	})
+
+	It("Lists the adjacent days", func() {
+		Expect(scheduler.AdjacentDays("sunday")).To(ConsistOf("sunday", "saturday", "monday"))
+		Expect(scheduler.AdjacentDays(scheduler.DayHoliday)).To(ConsistOf(scheduler.DayHoliday))
+	})
+})
+
+var _ = Describe("Calendar", func() {
+	location, _ := time.LoadLocation("America/Bogota")
+	// 2023-10-06 is a friday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2023, time.October, day, hour, minute, 0, 0, location)
+	}
+
+	calendar := scheduler.Calendar{
+		Location: location,
+		Schedules: []scheduler.Schedule{
+			schedule(1, "friday", "18:00", "02:00"),
+			schedule(2, "saturday", "12:00", "15:00"),
+			schedule(3, "sunday", "12:00", "15:00"),
+		},
+		Holidays: []scheduler.Holiday{},
+		Exceptions: []scheduler.ScheduleException{
+			{Date: time.Date(2023, time.October, 8, 0, 0, 0, 0, time.UTC), Closed: true},
+		},
+	}
+
+	It("Is open on the overnight hours of the day before", func() {
+		Expect(calendar.IsOpenAt(at(6, 19, 0))).To(BeTrue())
+		Expect(calendar.IsOpenAt(at(7, 1, 30))).To(BeTrue())
+		Expect(calendar.IsOpenAt(at(7, 2, 0))).To(BeFalse())
+	})
+
+	It("Returns the current opening", func() {
+		opening := calendar.NextOpening(at(7, 1, 0), scheduler.NextOpeningDays)
+		Expect(opening).NotTo(BeNil())
+		Expect(opening.OpensAt).To(Equal(at(6, 18, 0)))
+		Expect(opening.ClosesAt).To(Equal(at(7, 2, 0)))
+	})
+
+	It("Returns the next opening when closed", func() {
+		opening := calendar.NextOpening(at(7, 3, 0), scheduler.NextOpeningDays)
+		Expect(opening).NotTo(BeNil())
+		Expect(opening.OpensAt).To(Equal(at(7, 12, 0)))
+	})
+
+	It("Skips the dates closed by an exception", func() {
+		opening := calendar.NextOpening(at(7, 16, 0), scheduler.NextOpeningDays)
+		Expect(opening).NotTo(BeNil())
+		Expect(opening.OpensAt).To(Equal(at(13, 18, 0)))
+	})
+
+	It("Has no opening without schedules", func() {
+		empty := scheduler.Calendar{Location: location}
+		Expect(empty.NextOpening(at(7, 16, 0), 3)).To(BeNil())
+	})
+})
@@ -113,3 +113,34 @@ func (r *RequestHoliday) ToHoliday() (*Holiday, error) {
 		CountryID: r.CountryID,
 	}, err
 }
+
+type RequestScheduleException struct {
+	StoreID *uint  `json:"store_id" binding:"required"`
+	Date    string `json:"date" binding:"required" example:"2021-12-24" format:"2006-01-02"`
+	Closed  bool   `json:"closed"`
+	Opening string `json:"open" example:"10:00"`
+	Closing string `json:"close" example:"16:00"`
+	Reason  string `json:"reason"`
+}
+
+func (r *RequestScheduleException) ToScheduleException() (*ScheduleException, error) {
+	date, err := time.Parse(dateLayout, r.Date)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorExceptionInvalidDate)
+	}
+
+	if !r.Closed {
+		if _, ok := interval(date, r.Opening, r.Closing); !ok {
+			return nil, fmt.Errorf(ErrorExceptionInvalidHours)
+		}
+	}
+
+	return &ScheduleException{
+		StoreID: r.StoreID,
+		Date:    date,
+		Closed:  r.Closed,
+		Opening: r.Opening,
+		Closing: r.Closing,
+		Reason:  r.Reason,
+	}, nil
+}
@@ -1,13 +1,16 @@
 package scheduler
 
 import (
-	"github.com/BacoFoods/menu/pkg/shared"
-	"github.com/gin-gonic/gin"
+	"fmt"
 	"net/http"
-	"strings"
 	"time"
+
+	"github.com/BacoFoods/menu/pkg/shared"
+	"github.com/gin-gonic/gin"
 )
 
+const LogHandler = "pkg/scheduler/handler"
+
 type Handler struct {
 	service Service
 }
@@ -54,11 +57,6 @@ func (h *Handler) Find(c *gin.Context) {
 		return
 	}
 
-	isTodayHoliday, err := h.service.GetTodayHoliday()
-	if err != nil {
-		shared.LogError("error getting today's holiday", LogRepository, "Find", err, nil)
-	}
-
 	responseBrand := ResponseBrand{}
 	stores := make(map[uint]*ResponseStore)
 	for _, schedule := range schedules {
@@ -90,14 +88,15 @@ func (h *Handler) Find(c *gin.Context) {
 				Enable:  schedule.Enable,
 			})
 		}
-
-		if strings.ToLower(time.Now().Weekday().String()) == schedule.Day {
-			isOpen := schedule.IsOpen(isTodayHoliday)
-			stores[schedule.Store.ID].Open = isOpen
-		}
 	}
 
+	now := time.Now()
 	for _, store := range stores {
+		isOpen, err := h.service.IsOpenAt(fmt.Sprint(store.ID), now)
+		if err != nil {
+			shared.LogError("error checking if store is open", LogHandler, "Find", err, store.ID)
+		}
+		store.Open = isOpen
 		responseBrand.Stores = append(responseBrand.Stores, *store)
 	}
 
@@ -206,7 +205,7 @@ func (h *Handler) Delete(c *gin.Context) {
 // @Produce json
 // @Security ApiKeyAuth
 // @Param id path string true "store id"
-// @Success 200 {object} object{status=string,data=Schedule}
+// @Success 200 {object} object{status=string,data=[]Schedule}
 // @Failure 400 {object} shared.Response
 // @Failure 422 {object} shared.Response
 // @Failure 401 {object} shared.Response
@@ -396,3 +395,123 @@ func (h *Handler) FindHoliday(c *gin.Context) {
 
 	c.JSON(http.StatusOK, shared.SuccessResponse(holidays))
 }
+
+// NextOpening to handle a request to get the current or next opening of a store
+// @Tags Schedule
+// @Summary To get the next opening of a store
+// @Description To get if a store is open and its current or next opening interval on the store time zone
+// @Accept json
+// @Produce json
+// @Param id path string true "store id"
+// @Success 200 {object} object{status=string,data=StoreOpening}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Router /schedules/store/{id}/next-opening [get]
+func (h *Handler) NextOpening(c *gin.Context) {
+	opening, err := h.service.NextOpening(c.Param("id"), time.Now())
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, shared.SuccessResponse(opening))
+}
+
+// CreateException to handle a request to create a dated exception for a store schedule
+// @Tags Schedule
+// @Summary To create a schedule exception
+// @Description To close a store or change its opening hours on a date
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param exception body RequestScheduleException true "schedule exception"
+// @Success 200 {object} object{status=string,data=ScheduleException}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /schedules/exception [post]
+func (h *Handler) CreateException(c *gin.Context) {
+	var request RequestScheduleException
+	if err := c.ShouldBindJSON(&request); err != nil {
+		shared.LogError("error binding request", LogHandler, "CreateException", err, request)
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	exception, err := request.ToScheduleException()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	exceptionDB, err := h.service.CreateException(exception)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, shared.SuccessResponse(exceptionDB))
+}
+
+// FindExceptions to handle a request to find the schedule exceptions of a store
+// @Tags Schedule
+// @Summary To find the schedule exceptions of a store
+// @Description To find the schedule exceptions of a store between two dates, by default the next 30 days
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "store id"
+// @Param from query string false "from date" format(2006-01-02)
+// @Param to query string false "to date" format(2006-01-02)
+// @Success 200 {object} object{status=string,data=[]ScheduleException}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /schedules/store/{id}/exceptions [get]
+func (h *Handler) FindExceptions(c *gin.Context) {
+	from := time.Now()
+	if c.Query("from") != "" {
+		date, err := time.Parse(dateLayout, c.Query("from"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorExceptionInvalidDate))
+			return
+		}
+		from = date
+	}
+
+	to := from.AddDate(0, 0, 30)
+	if c.Query("to") != "" {
+		date, err := time.Parse(dateLayout, c.Query("to"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorExceptionInvalidDate))
+			return
+		}
+		to = date
+	}
+
+	exceptions, err := h.service.FindExceptions(c.Param("id"), from, to)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, shared.SuccessResponse(exceptions))
+}
+
+// DeleteException to handle a request to delete a schedule exception
+// @Tags Schedule
+// @Summary To delete a schedule exception
+// @Description To delete a schedule exception
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "exception id"
+// @Success 200 {object} object{status=string,data=string}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /schedules/exception/{id} [delete]
+func (h *Handler) DeleteException(c *gin.Context) {
+	if err := h.service.DeleteException(c.Param("id")); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, shared.SuccessResponse(c.Param("id")))
+}
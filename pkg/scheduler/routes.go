@@ -12,7 +12,7 @@ func NewRoutes(handler *Handler) Routes {
 	return Routes{handler}
 }
 
-func (r Routes) RegisterRoutes(private *shared.CustomRoutes) {
+func (r Routes) RegisterRoutes(private, public *shared.CustomRoutes) {
 	private.GET("/schedules", r.handler.Find)
 	private.POST("/schedules", r.handler.Create)
 	private.PATCH("/schedules", r.handler.Update)
@@ -24,4 +24,9 @@ func (r Routes) RegisterRoutes(private *shared.CustomRoutes) {
 	private.PATCH("/schedules/holiday", r.handler.UpdateHoliday)
 	private.DELETE("/schedules/holiday", r.handler.DeleteHoliday)
 	private.GET("/schedules/holiday", r.handler.FindHoliday)
+	private.POST("/schedules/exception", r.handler.CreateException)
+	private.DELETE("/schedules/exception/:id", r.handler.DeleteException)
+	private.GET("/schedules/store/:id/exceptions", r.handler.FindExceptions)
+	private.GET("/schedules/store/:id/next-opening", r.handler.NextOpening)
+	public.GET("/schedules/store/:id/next-opening", r.handler.NextOpening)
 }
@@ -1,26 +1,41 @@
 package scheduler
 
+import (
+	"fmt"
+	"time"
+
+	"github.com/BacoFoods/menu/pkg/shared"
+	"github.com/BacoFoods/menu/pkg/store"
+)
+
+const LogService = "pkg/scheduler/service"
+
 type Service interface {
 	Find(filter map[string]any) ([]Schedule, error)
 	Create(schedule *Schedule) error
 	Update(schedule *Schedule) error
 	Delete(schedule *Schedule) error
-	Today(storeID string) (*Schedule, error)
+	Today(storeID string) ([]Schedule, error)
 	TodayStores(brandID string) ([]Schedule, error)
 	EnableStore(storeID string, enable bool) ([]Schedule, error)
 	CreateHoliday(holiday *Holiday) (*Holiday, error)
 	UpdateHoliday(holiday *Holiday) (*Holiday, error)
 	DeleteHoliday(holiday *Holiday) error
 	FindHoliday() ([]Holiday, error)
-	GetTodayHoliday() (*Holiday, error)
+	IsOpenAt(storeID string, at time.Time) (bool, error)
+	NextOpening(storeID string, from time.Time) (*StoreOpening, error)
+	CreateException(exception *ScheduleException) (*ScheduleException, error)
+	DeleteException(exceptionID string) error
+	FindExceptions(storeID string, from, to time.Time) ([]ScheduleException, error)
 }
 
 type service struct {
 	repository Repository
+	stores     store.Repository
 }
 
-func NewService(repository Repository) service {
-	return service{repository}
+func NewService(repository Repository, stores store.Repository) service {
+	return service{repository, stores}
 }
 
 func (s service) Find(filter map[string]any) ([]Schedule, error) {
@@ -39,12 +54,33 @@ func (s service) Delete(schedule *Schedule) error {
 	return s.repository.Delete(schedule)
 }
 
-func (s service) Today(storeID string) (*Schedule, error) {
-	return s.repository.TodayStore(storeID)
+// Today returns the enabled schedules of the store for the current week day on the store time zone.
+func (s service) Today(storeID string) ([]Schedule, error) {
+	storeDB, err := s.stores.Get(storeID)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorScheduleFindingTodayStore)
+	}
+
+	day := WeekDay(time.Now().In(storeDB.Location()))
+	return s.repository.Find(map[string]any{"store_id": storeID, "day": day, "enable": true})
 }
 
+// TodayStores returns the schedules of the brand stores for the current week day on each store time zone.
 func (s service) TodayStores(brandID string) ([]Schedule, error) {
-	return s.repository.TodayBrand(brandID)
+	schedules, err := s.repository.Find(map[string]any{"brand_id": brandID})
+	if err != nil {
+		return nil, fmt.Errorf(ErrorScheduleFindingTodayBrand)
+	}
+
+	now := time.Now()
+	today := make([]Schedule, 0)
+	for _, schedule := range schedules {
+		if schedule.Store != nil && schedule.Day == WeekDay(now.In(schedule.Store.Location())) {
+			today = append(today, schedule)
+		}
+	}
+
+	return today, nil
 }
 
 func (s service) EnableStore(storeID string, enable bool) ([]Schedule, error) {
@@ -67,6 +103,93 @@ func (s service) FindHoliday() ([]Holiday, error) {
 	return s.repository.FindHoliday()
 }
 
-func (s service) GetTodayHoliday() (*Holiday, error) {
-	return s.repository.GetTodayHoliday()
+// IsOpenAt checks the store schedules on the store time zone, including the overnight ranges of the
+// day before, the brand holidays and the dated exceptions of the store.
+func (s service) IsOpenAt(storeID string, at time.Time) (bool, error) {
+	calendar, err := s.calendar(storeID, at, at)
+	if err != nil {
+		return false, err
+	}
+
+	return calendar.IsOpenAt(at), nil
+}
+
+// NextOpening returns the current opening of the store, or the next one when it is closed.
+func (s service) NextOpening(storeID string, from time.Time) (*StoreOpening, error) {
+	storeDB, err := s.stores.Get(storeID)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorScheduleFindingOpening)
+	}
+
+	calendar, err := s.calendar(storeID, from, from.AddDate(0, 0, NextOpeningDays))
+	if err != nil {
+		return nil, err
+	}
+
+	opening := StoreOpening{
+		StoreID:  storeDB.ID,
+		Timezone: calendar.Location.String(),
+		Open:     calendar.IsOpenAt(from),
+	}
+
+	if next := calendar.NextOpening(from, NextOpeningDays); next != nil {
+		opening.OpensAt = &next.OpensAt
+		opening.ClosesAt = &next.ClosesAt
+	}
+
+	return &opening, nil
 }
+
+func (s service) CreateException(exception *ScheduleException) (*ScheduleException, error) {
+	return s.repository.CreateException(exception)
+}
+
+func (s service) DeleteException(exceptionID string) error {
+	return s.repository.DeleteException(exceptionID)
+}
+
+func (s service) FindExceptions(storeID string, from, to time.Time) ([]ScheduleException, error) {
+	return s.repository.FindExceptions(storeID, from, to)
+}
+
+// calendar loads the schedules, brand holidays and exceptions of the store between from and to,
+// the day before from is included for the overnight ranges.
+func (s service) calendar(storeID string, from, to time.Time) (*Calendar, error) {
+	storeDB, err := s.stores.Get(storeID)
+	if err != nil {
+		shared.LogError("error getting store", LogService, "calendar", err, storeID)
+		return nil, fmt.Errorf(ErrorScheduleFinding)
+	}
+
+	schedules, err := s.repository.Find(map[string]any{"store_id": storeID})
+	if err != nil {
+		return nil, err
+	}
+
+	holidays, err := s.repository.FindHoliday()
+	if err != nil {
+		return nil, err
+	}
+
+	brandHolidays := make([]Holiday, 0)
+	for _, holiday := range holidays {
+		if holiday.BrandID != nil && storeDB.BrandID != nil && *holiday.BrandID == *storeDB.BrandID {
+			brandHolidays = append(brandHolidays, holiday)
+		}
+	}
+
+	location := storeDB.Location()
+	exceptions, err := s.repository.FindExceptions(storeID, from.In(location).AddDate(0, 0, -1), to.In(location))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Calendar{
+		Location:   location,
+		Schedules:  schedules,
+		Holidays:   brandHolidays,
+		Exceptions: exceptions,
+	}, nil
+}
+
+var _ Service = service{}
@@ -1,6 +1,8 @@
 package shift
 
 import (
+	"errors"
+
 	"github.com/BacoFoods/menu/pkg/shared"
 	"gorm.io/gorm"
 )
@@ -35,9 +37,24 @@ func (r *DBRepository) Update(shift *Shift) (*Shift, error) {
 	return shift, nil
 }
 
+func (r *DBRepository) Get(shiftID string) (*Shift, error) {
+	var shift Shift
+	if err := r.db.First(&shift, shiftID).Error; err != nil {
+		shared.LogError("failed to get shift", LogDBRepository, "Get", err, shiftID)
+		return nil, err
+	}
+
+	return &shift, nil
+}
+
+// GetOpenShift returns the last open shift of the store whose cash audit was not submitted yet, nil when
+// the store has no such shift
 func (r *DBRepository) GetOpenShift(storeID *uint) (*Shift, error) {
 	var shift Shift
-	if err := r.db.Where("store_id = ? AND end_time IS NULL", storeID).First(&shift).Error; err != nil {
+	if err := r.db.Where("store_id = ? AND end_time IS NULL AND cash_audit_id IS NULL", storeID).Last(&shift).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
 		shared.LogError("failed to get open shift", LogDBRepository, "GetOpenShift", err)
 		return nil, err
 	}
@@ -45,6 +62,20 @@ func (r *DBRepository) GetOpenShift(storeID *uint) (*Shift, error) {
 	return &shift, nil
 }
 
+// GetOpenShiftByAccount returns the open shift of the cashier account, nil when the account has no open shift
+func (r *DBRepository) GetOpenShiftByAccount(accountID string) (*Shift, error) {
+	var shift Shift
+	if err := r.db.Where("account_id = ? AND end_time IS NULL", accountID).Last(&shift).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		shared.LogError("failed to get open shift", LogDBRepository, "GetOpenShiftByAccount", err, accountID)
+		return nil, err
+	}
+
+	return &shift, nil
+}
+
 func (r *DBRepository) GetLastShift(storeID string) (*Shift, error) {
 	var shift Shift
 	if err := r.db.Where("store_id = ?", storeID).Last(&shift).Error; err != nil {
@@ -54,3 +85,22 @@ func (r *DBRepository) GetLastShift(storeID string) (*Shift, error) {
 
 	return &shift, nil
 }
+
+func (r *DBRepository) CreateMovement(movement *DrawerMovement) (*DrawerMovement, error) {
+	if err := r.db.Create(movement).Error; err != nil {
+		shared.LogError("failed to create drawer movement", LogDBRepository, "CreateMovement", err, *movement)
+		return nil, err
+	}
+
+	return movement, nil
+}
+
+func (r *DBRepository) FindMovements(shiftID uint) ([]DrawerMovement, error) {
+	var movements []DrawerMovement
+	if err := r.db.Where("shift_id = ?", shiftID).Order("created_at ASC").Find(&movements).Error; err != nil {
+		shared.LogError("failed to find drawer movements", LogDBRepository, "FindMovements", err, shiftID)
+		return nil, err
+	}
+
+	return movements, nil
+}
@@ -7,14 +7,30 @@ import (
 
 const (
 	ErrorShiftGettingShiftOpened   = "error getting open shift"
-	ErrorShiftOpeningAlreadyOpened = "error opening shift, shift already open to this store"
+	ErrorShiftOpeningAlreadyOpened = "error opening shift, shift already open to this account"
+	ErrorShiftGetting              = "error getting shift"
+	ErrorShiftNotOpen              = "error there is no open shift for this account"
+	ErrorShiftClosingWithoutAudit  = "error closing shift, the cash audit of the shift must be submitted first"
+	ErrorDrawerMovementCreating    = "error creating drawer movement"
+	ErrorDrawerMovementFinding     = "error finding drawer movements"
+	ErrorDrawerMovementInvalidType = "error invalid drawer movement type"
+	ErrorDrawerMovementAmount      = "error drawer movement amount must be greater than zero"
+	ErrorDrawerMovementAudited     = "error the cash audit of the shift was already submitted"
+
+	DrawerMovementPayIn    = "pay_in"
+	DrawerMovementPayOut   = "pay_out"
+	DrawerMovementSafeDrop = "safe_drop"
 )
 
 type Repository interface {
 	Create(*Shift) (*Shift, error)
 	Update(*Shift) (*Shift, error)
+	Get(shiftID string) (*Shift, error)
 	GetOpenShift(storeID *uint) (*Shift, error)
+	GetOpenShiftByAccount(accountID string) (*Shift, error)
 	GetLastShift(storeID string) (*Shift, error)
+	CreateMovement(*DrawerMovement) (*DrawerMovement, error)
+	FindMovements(shiftID uint) ([]DrawerMovement, error)
 }
 
 type Shift struct {
@@ -26,7 +42,67 @@ type Shift struct {
 	EndTime      *time.Time      `json:"end_time"`
 	StartBalance float64         `json:"start_balance"`
 	EndBalance   float64         `json:"end_balance"`
+	CashAuditID  *uint           `json:"cash_audit_id"`
 	CreatedAt    *time.Time      `json:"created_at" swaggerignore:"true"`
 	UpdatedAt    *time.Time      `json:"updated_at" swaggerignore:"true"`
 	DeletedAt    *gorm.DeletedAt `json:"deleted_at,omitempty" swaggerignore:"true"`
 }
+
+// Audited tells whether the cash audit of the shift was submitted, an audited shift takes no more
+// orders nor drawer movements.
+func (s Shift) Audited() bool {
+	return s.CashAuditID != nil
+}
+
+// DrawerMovement is cash put in or taken out of the drawer during a shift, like the cash paid to
+// a supplier or the excess dropped into the safe.
+type DrawerMovement struct {
+	ID         uint            `json:"id" gorm:"primaryKey"`
+	ShiftID    *uint           `json:"shift_id" gorm:"index"`
+	StoreID    *uint           `json:"store_id"`
+	AccountID  *uint           `json:"account_id"`
+	Type       string          `json:"type" enums:"pay_in,pay_out,safe_drop"`
+	Amount     float64         `json:"amount" gorm:"precision:18;scale:4"`
+	Reason     string          `json:"reason"`
+	ReceiptURL string          `json:"receipt_url"`
+	CreatedAt  *time.Time      `json:"created_at" swaggerignore:"true"`
+	UpdatedAt  *time.Time      `json:"updated_at" swaggerignore:"true"`
+	DeletedAt  *gorm.DeletedAt `json:"deleted_at,omitempty" swaggerignore:"true"`
+}
+
+func IsValidDrawerMovementType(movementType string) bool {
+	switch movementType {
+	case DrawerMovementPayIn, DrawerMovementPayOut, DrawerMovementSafeDrop:
+		return true
+	}
+	return false
+}
+
+// CashEffect returns how the movement changes the cash in the drawer, pay-ins add cash and
+// pay-outs and safe drops take it out.
+func (m DrawerMovement) CashEffect() float64 {
+	if m.Type == DrawerMovementPayIn {
+		return m.Amount
+	}
+	return -m.Amount
+}
+
+// GetMovementsTotal returns the total amount of the movements of the given type
+func GetMovementsTotal(movements []DrawerMovement, movementType string) float64 {
+	total := 0.0
+	for _, movement := range movements {
+		if movement.Type == movementType {
+			total += movement.Amount
+		}
+	}
+	return total
+}
+
+// GetMovementsCash returns the net cash the movements put in the drawer
+func GetMovementsCash(movements []DrawerMovement) float64 {
+	total := 0.0
+	for _, movement := range movements {
+		total += movement.CashEffect()
+	}
+	return total
+}
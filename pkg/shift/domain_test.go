@@ -0,0 +1,29 @@
+package shift_test
+
+import (
+	"github.com/BacoFoods/menu/pkg/shift"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Drawer movements", func() {
+	movements := []shift.DrawerMovement{
+		{Type: shift.DrawerMovementPayIn, Amount: 50000},
+		{Type: shift.DrawerMovementPayOut, Amount: 12000},
+		{Type: shift.DrawerMovementSafeDrop, Amount: 30000},
+		{Type: shift.DrawerMovementPayIn, Amount: 2000},
+	}
+
+	It("Adds the pay-ins and takes out the pay-outs and safe drops", func() {
+		Expect(movements[0].CashEffect()).To(Equal(50000.0))
+		Expect(movements[1].CashEffect()).To(Equal(-12000.0))
+		Expect(movements[2].CashEffect()).To(Equal(-30000.0))
+		Expect(shift.GetMovementsCash(movements)).To(Equal(10000.0))
+	})
+
+	It("Totals the movements by type", func() {
+		Expect(shift.GetMovementsTotal(movements, shift.DrawerMovementPayIn)).To(Equal(52000.0))
+		Expect(shift.GetMovementsTotal(movements, shift.DrawerMovementSafeDrop)).To(Equal(30000.0))
+		Expect(shift.GetMovementsCash(nil)).To(BeZero())
+	})
+})
@@ -22,6 +22,13 @@ type RequestCloseShift struct {
 	EndBalance float64 `json:"end_balance" binding:"required"`
 }
 
+type RequestDrawerMovement struct {
+	Type       string  `json:"type" binding:"required" enums:"pay_in,pay_out,safe_drop"`
+	Amount     float64 `json:"amount" binding:"required"`
+	Reason     string  `json:"reason" binding:"required"`
+	ReceiptURL string  `json:"receipt_url"`
+}
+
 func NewHandler(service Service) *Handler {
 	return &Handler{service}
 }
@@ -95,3 +102,66 @@ func (h *Handler) Close(c *gin.Context) {
 
 	c.JSON(http.StatusOK, shared.SuccessResponse(shift))
 }
+
+// AddMovement to handle a drawer movement request
+// @Tags Shift
+// @Summary Add a drawer movement
+// @Description Add a pay-in, pay-out or safe drop to the open shift of the cashier
+// @Accept json
+// @Produce json
+// @Param movement body RequestDrawerMovement true "Request body"
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=DrawerMovement}
+// @Failure 400 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Router /shift/movement [post]
+func (h *Handler) AddMovement(c *gin.Context) {
+	accountID, ok := c.Get("account_id")
+	if !ok {
+		shared.LogError("account_id not found from jwt", LogHandler, "AddMovement", nil)
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse("account_id not found from jwt"))
+		return
+	}
+
+	var req RequestDrawerMovement
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	movement, err := h.service.AddMovement(accountID.(string), &DrawerMovement{
+		Type:       req.Type,
+		Amount:     req.Amount,
+		Reason:     req.Reason,
+		ReceiptURL: req.ReceiptURL,
+	})
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(movement))
+}
+
+// FindMovements to handle a request to find the drawer movements of a shift
+// @Tags Shift
+// @Summary Find drawer movements
+// @Description Find the drawer movements of a shift
+// @Accept json
+// @Produce json
+// @Param id path string true "shift id"
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=[]DrawerMovement}
+// @Failure 401 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Router /shift/{id}/movements [get]
+func (h *Handler) FindMovements(c *gin.Context) {
+	movements, err := h.service.FindMovements(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(movements))
+}
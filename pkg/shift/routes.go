@@ -13,4 +13,6 @@ func NewRoutes(handler *Handler) Routes {
 func (r Routes) RegisterRoutes(router *shared.CustomRoutes) {
 	router.POST("/shift/open", r.handler.Open)
 	router.POST("/shift/close", r.handler.Close)
+	router.POST("/shift/movement", r.handler.AddMovement)
+	router.GET("/shift/:id/movements", r.handler.FindMovements)
 }
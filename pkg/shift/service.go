@@ -14,6 +14,8 @@ const (
 type Service interface {
 	Open(accountID string, startBalance float64) (*Shift, error)
 	Close(accountUUID string, endBalance float64) (*Shift, error)
+	AddMovement(accountID string, movement *DrawerMovement) (*DrawerMovement, error)
+	FindMovements(shiftID string) ([]DrawerMovement, error)
 }
 
 type service struct {
@@ -32,14 +34,14 @@ func (s service) Open(accountID string, startBalance float64) (*Shift, error) {
 		return nil, fmt.Errorf(account.ErrorAccountGettingByID)
 	}
 
-	currentShift, err := s.repository.GetOpenShift(acc.StoreID)
+	currentShift, err := s.repository.GetOpenShiftByAccount(accountID)
 	if err != nil {
 		shared.LogError("failed to get open shift", LogService, "Open", err)
 		return nil, err
 	}
 
 	if currentShift != nil {
-		shared.LogWarn("shift already open to this store", LogService, "Open", nil)
+		shared.LogWarn("shift already open to this account", LogService, "Open", nil)
 		return nil, fmt.Errorf(ErrorShiftOpeningAlreadyOpened)
 	}
 
@@ -64,15 +66,98 @@ func (s service) Close(accountUUID string, endBalance float64) (*Shift, error) {
 		return nil, fmt.Errorf(account.ErrorAccountGettingByID)
 	}
 
-	openShift, err := s.repository.GetOpenShift(acc.StoreID)
+	openShift, err := s.repository.GetOpenShiftByAccount(fmt.Sprint(acc.Id))
 	if err != nil {
 		shared.LogError("failed to get open shift", LogService, "Close", err)
 		return nil, fmt.Errorf(ErrorShiftGettingShiftOpened)
 	}
 
+	if openShift == nil {
+		return nil, fmt.Errorf(ErrorShiftNotOpen)
+	}
+
+	// The shift is closed once the cashier has submitted its cash audit
+	if !openShift.Audited() {
+		shared.LogWarn("closing shift without cash audit", LogService, "Close", nil, openShift.ID)
+		return nil, fmt.Errorf(ErrorShiftClosingWithoutAudit)
+	}
+
 	now := time.Now()
 	openShift.EndTime = &now
 	openShift.EndBalance = endBalance
 
 	return s.repository.Update(openShift)
 }
+
+// AddMovement registers a drawer movement on the open shift of the account, movements are not
+// allowed once the cash audit of the shift was submitted.
+func (s service) AddMovement(accountID string, movement *DrawerMovement) (*DrawerMovement, error) {
+	if !IsValidDrawerMovementType(movement.Type) {
+		return nil, fmt.Errorf(ErrorDrawerMovementInvalidType)
+	}
+
+	if movement.Amount <= 0 {
+		return nil, fmt.Errorf(ErrorDrawerMovementAmount)
+	}
+
+	openShift, err := s.repository.GetOpenShiftByAccount(accountID)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorShiftGettingShiftOpened)
+	}
+
+	if openShift == nil {
+		return nil, fmt.Errorf(ErrorShiftNotOpen)
+	}
+
+	if openShift.Audited() {
+		return nil, fmt.Errorf(ErrorDrawerMovementAudited)
+	}
+
+	movement.ShiftID = &openShift.ID
+	movement.StoreID = openShift.StoreID
+	movement.AccountID = openShift.AccountID
+
+	movementDB, err := s.repository.CreateMovement(movement)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorDrawerMovementCreating)
+	}
+
+	return movementDB, nil
+}
+
+// OrderShift returns the shift a new order is accounted on, the open shift of the cashier account or
+// else the last open shift of the store. An audited shift is skipped, its orders were already counted
+// in the submitted cash audit. Returns nil when there is no shift to account the order on.
+func OrderShift(repository Repository, accountID string, storeID *uint) *Shift {
+	if accountID != "" {
+		accountShift, err := repository.GetOpenShiftByAccount(accountID)
+		if err != nil {
+			shared.LogWarn("error getting account shift", LogService, "OrderShift", err, accountID)
+		}
+		if accountShift != nil && !accountShift.Audited() {
+			return accountShift
+		}
+	}
+
+	storeShift, err := repository.GetOpenShift(storeID)
+	if err != nil {
+		shared.LogWarn("error getting store shift", LogService, "OrderShift", err, storeID)
+		return nil
+	}
+
+	return storeShift
+}
+
+func (s service) FindMovements(shiftID string) ([]DrawerMovement, error) {
+	shift, err := s.repository.Get(shiftID)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorShiftGetting)
+	}
+
+	movements, err := s.repository.FindMovements(shift.ID)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorDrawerMovementFinding)
+	}
+
+	return movements, nil
+}
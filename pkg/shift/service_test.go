@@ -0,0 +1,153 @@
+package shift_test
+
+import (
+	"fmt"
+
+	"github.com/BacoFoods/menu/pkg/account"
+	"github.com/BacoFoods/menu/pkg/shift"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// shiftRepository keeps the shifts in memory, it looks the open shifts up like the db repository.
+type shiftRepository struct {
+	shift.Repository
+	shifts    []*shift.Shift
+	movements []*shift.DrawerMovement
+}
+
+func (r *shiftRepository) GetOpenShift(storeID *uint) (*shift.Shift, error) {
+	for i := len(r.shifts) - 1; i >= 0; i-- {
+		s := r.shifts[i]
+		if s.StoreID != nil && *s.StoreID == *storeID && s.EndTime == nil && !s.Audited() {
+			return s, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *shiftRepository) GetOpenShiftByAccount(accountID string) (*shift.Shift, error) {
+	for i := len(r.shifts) - 1; i >= 0; i-- {
+		s := r.shifts[i]
+		if s.AccountID != nil && fmt.Sprint(*s.AccountID) == accountID && s.EndTime == nil {
+			return s, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *shiftRepository) Update(s *shift.Shift) (*shift.Shift, error) {
+	return s, nil
+}
+
+func (r *shiftRepository) CreateMovement(movement *shift.DrawerMovement) (*shift.DrawerMovement, error) {
+	r.movements = append(r.movements, movement)
+	return movement, nil
+}
+
+type accountRepository struct {
+	account.Repository
+	accounts map[string]*account.Account
+}
+
+func (r accountRepository) GetByUUID(uuid string) (*account.Account, error) {
+	if acc, ok := r.accounts[uuid]; ok {
+		return acc, nil
+	}
+	return nil, fmt.Errorf(account.ErrorAccountGettingByID)
+}
+
+var _ = Describe("Service", func() {
+	storeID := uint(1)
+	auditID := uint(7)
+	cashier := uint(10)
+	otherCashier := uint(11)
+
+	var repository *shiftRepository
+	var service shift.Service
+
+	BeforeEach(func() {
+		repository = &shiftRepository{}
+		service = shift.NewService(repository, accountRepository{accounts: map[string]*account.Account{
+			"cashier-uuid": {Id: cashier, UUID: "cashier-uuid", StoreID: &storeID},
+		}})
+	})
+
+	Describe("Close", func() {
+		It("Refuses to close the shift before its cash audit is submitted", func() {
+			openShift := &shift.Shift{ID: 1, StoreID: &storeID, AccountID: &cashier}
+			repository.shifts = []*shift.Shift{openShift}
+
+			_, err := service.Close("cashier-uuid", 100000)
+			Expect(err).To(MatchError(shift.ErrorShiftClosingWithoutAudit))
+			Expect(openShift.EndTime).To(BeNil())
+		})
+
+		It("Closes the audited shift with the end balance", func() {
+			openShift := &shift.Shift{ID: 1, StoreID: &storeID, AccountID: &cashier, CashAuditID: &auditID}
+			repository.shifts = []*shift.Shift{openShift}
+
+			closed, err := service.Close("cashier-uuid", 100000)
+			Expect(err).To(BeNil())
+			Expect(closed.EndTime).NotTo(BeNil())
+			Expect(closed.EndBalance).To(Equal(100000.0))
+		})
+
+		It("Fails when the account has no open shift", func() {
+			_, err := service.Close("cashier-uuid", 0)
+			Expect(err).To(MatchError(shift.ErrorShiftNotOpen))
+		})
+	})
+
+	Describe("AddMovement", func() {
+		It("Refuses the movements once the cash audit of the shift is submitted", func() {
+			repository.shifts = []*shift.Shift{{ID: 1, StoreID: &storeID, AccountID: &cashier, CashAuditID: &auditID}}
+
+			_, err := service.AddMovement(fmt.Sprint(cashier), &shift.DrawerMovement{Type: shift.DrawerMovementPayIn, Amount: 5000})
+			Expect(err).To(MatchError(shift.ErrorDrawerMovementAudited))
+			Expect(repository.movements).To(BeEmpty())
+		})
+
+		It("Registers the movement on the open shift of the account", func() {
+			repository.shifts = []*shift.Shift{{ID: 1, StoreID: &storeID, AccountID: &cashier}}
+
+			movement, err := service.AddMovement(fmt.Sprint(cashier), &shift.DrawerMovement{Type: shift.DrawerMovementPayIn, Amount: 5000})
+			Expect(err).To(BeNil())
+			Expect(*movement.ShiftID).To(Equal(uint(1)))
+			Expect(*movement.StoreID).To(Equal(storeID))
+		})
+	})
+
+	Describe("OrderShift", func() {
+		It("Accounts the order on the open shift of the cashier", func() {
+			repository.shifts = []*shift.Shift{
+				{ID: 1, StoreID: &storeID, AccountID: &cashier},
+				{ID: 2, StoreID: &storeID, AccountID: &otherCashier},
+			}
+
+			Expect(shift.OrderShift(repository, fmt.Sprint(cashier), &storeID).ID).To(Equal(uint(1)))
+		})
+
+		It("Falls back to the open shift of the store for the orders without cashier", func() {
+			repository.shifts = []*shift.Shift{{ID: 1, StoreID: &storeID, AccountID: &cashier}}
+
+			Expect(shift.OrderShift(repository, "", &storeID).ID).To(Equal(uint(1)))
+		})
+
+		It("Skips the shift of the cashier once its cash audit is submitted", func() {
+			repository.shifts = []*shift.Shift{
+				{ID: 1, StoreID: &storeID, AccountID: &otherCashier},
+				{ID: 2, StoreID: &storeID, AccountID: &cashier, CashAuditID: &auditID},
+			}
+
+			Expect(shift.OrderShift(repository, fmt.Sprint(cashier), &storeID).ID).To(Equal(uint(1)))
+			Expect(shift.OrderShift(repository, "", &storeID).ID).To(Equal(uint(1)))
+		})
+
+		It("Leaves the order without shift when every open shift is audited", func() {
+			repository.shifts = []*shift.Shift{{ID: 1, StoreID: &storeID, AccountID: &cashier, CashAuditID: &auditID}}
+
+			Expect(shift.OrderShift(repository, fmt.Sprint(cashier), &storeID)).To(BeNil())
+		})
+	})
+})
@@ -0,0 +1,236 @@
+package siesa
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	documentType = "FVR"
+
+	SheetHeader       = "Docto. ventas comercial"
+	SheetDiscounts    = "Descuentos"
+	SheetMovements    = "Movimientos"
+	SheetInstallments = "Cuotas CxC"
+	SheetInvalidItems = "Items Invalidos"
+)
+
+// DocumentHeader is the store and notes of a sales document
+type DocumentHeader struct {
+	OpsCenter string
+	Warehouse string
+	Notes     string
+	DueDate   string
+}
+
+// DocumentLine is a product sold on a sales document, the lines with an invalid reason are not posted to SIESA
+type DocumentLine struct {
+	Name          string
+	Reference     string
+	Quantity      int
+	GrossValue    float64
+	UnitDiscount  float64
+	TotalDiscount float64
+	OpsCenter     string
+	Warehouse     string
+	Invalid       string
+}
+
+// Source is an input adapter of the ERP export, it turns the sales of a source (Popapp orders, our invoices)
+// into the header and lines of a sales document
+type Source interface {
+	Document() (DocumentHeader, []DocumentLine, error)
+}
+
+// BuildDocument builds the sheets of the sales document posted to SIESA from the lines of the source. Every
+// movement gets a record number and its discount, if any, is recorded with the same number.
+func BuildDocument(number string, date time.Time, source Source) (map[string]interface{}, error) {
+	header, lines, err := source.Document()
+	if err != nil {
+		return nil, err
+	}
+
+	doc := make(map[string]interface{})
+	doc[SheetHeader] = []map[string]string{
+		{
+			"F350_ID_CO":                    header.OpsCenter,
+			"F350_ID_TIPO_DOCTO":            documentType,
+			"F350_CONSEC_DOCTO":             number,
+			"F350_FECHA":                    date.Format("20060102"),
+			"f461_id_co_fact":               header.OpsCenter,
+			"f461_notas":                    header.Notes,
+			"F461_ID_BODEGA_COMPON_PROCESO": header.Warehouse,
+		},
+	}
+
+	discounts := []map[string]string{}
+	movements := []map[string]string{}
+	invalidItems := []map[string]string{}
+
+	for _, line := range lines {
+		if line.Invalid != "" {
+			invalidItems = append(invalidItems, map[string]string{
+				"f470_id_co":        line.OpsCenter,
+				"f470_consec_docto": number,
+				"f470_nro_registro": strconv.Itoa(len(invalidItems) + 1),
+				"f470_id_bodega":    line.Warehouse,
+				"f470_id_co_movto":  line.OpsCenter,
+				"f470_cant_base":    strconv.Itoa(line.Quantity),
+				"f470_vlr_bruto":    formatValue(line.GrossValue),
+				"razon":             line.Invalid,
+			})
+			continue
+		}
+
+		record := strconv.Itoa(len(movements) + 1)
+		movements = append(movements, map[string]string{
+			"f470_id_co":           line.OpsCenter,
+			"f470_consec_docto":    number,
+			"f470_nro_registro":    record,
+			"f470_id_bodega":       line.Warehouse,
+			"f470_id_co_movto":     line.OpsCenter,
+			"f470_cant_base":       strconv.Itoa(line.Quantity),
+			"f470_vlr_bruto":       formatValue(line.GrossValue),
+			"f470_referencia_item": line.Reference,
+		})
+
+		if line.UnitDiscount == 0 && line.TotalDiscount == 0 {
+			continue
+		}
+
+		discounts = append(discounts, map[string]string{
+			"f471_id_co":         line.OpsCenter,
+			"f471_id_tipo_docto": documentType,
+			"f471_consec_docto":  number,
+			"f471_nro_registro":  record,
+			"f471_vlr_uni":       formatValue(line.UnitDiscount),
+			"f471_vlr_tot":       formatValue(line.TotalDiscount),
+		})
+	}
+
+	doc[SheetDiscounts] = discounts
+	doc[SheetMovements] = movements
+	doc[SheetInstallments] = []map[string]string{
+		{
+			"F350_ID_CO":        header.OpsCenter,
+			"F350_CONSEC_DOCTO": number,
+			"F353_FECHA_VCTO":   header.DueDate,
+		},
+	}
+	doc[SheetInvalidItems] = invalidItems
+
+	return doc, nil
+}
+
+// grossValue returns the units by the unit price, a price of zero is posted as one
+func grossValue(quantity int, unitPrice float64) float64 {
+	if unitPrice == 0 {
+		unitPrice = 1
+	}
+
+	return unitPrice * float64(quantity)
+}
+
+// formatValue formats a value without decimals
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'f', 0, 64)
+}
+
+// RenderExcel renders the sheets of a sales document as an Excel file
+func RenderExcel(doc map[string]interface{}) ([]byte, error) {
+	file, err := GenerateExcelFile(doc)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	buffer := &bytes.Buffer{}
+	if err := file.Write(buffer); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// ExcelColumnID genera el identificador de la columna en formato alfabético
+func ExcelColumnID(colIdx int) string {
+	var result string
+	for {
+		if colIdx > 0 {
+			colIdx--
+			result = string(rune('A'+colIdx%26)) + result
+			colIdx /= 26
+		} else {
+			break
+		}
+	}
+	return result
+}
+
+func GenerateExcelFile(doc map[string]interface{}) (*excelize.File, error) {
+	file := excelize.NewFile()
+
+	// Define sheet names and corresponding headers
+	sheetNames := []string{SheetHeader, SheetDiscounts, SheetInstallments, SheetMovements, SheetInvalidItems}
+	headersColumns := [][]string{
+		{"F350_ID_CO", "F350_ID_TIPO_DOCTO", "F350_CONSEC_DOCTO", "F350_FECHA", "f461_id_co_fact", "f461_notas", "F461_ID_BODEGA_COMPON_PROCESO"},
+		{"f471_id_co", "f471_id_tipo_docto", "f471_consec_docto", "f471_nro_registro", "f471_vlr_uni", "f471_vlr_tot"},
+		{"F350_ID_CO", "F350_CONSEC_DOCTO"},
+		{"f470_id_co", "f470_consec_docto", "f470_nro_registro", "f470_id_bodega", "f470_id_co_movto", "f470_cant_base", "f470_vlr_bruto", "f470_referencia_item"},
+		{"f470_id_co", "f470_consec_docto", "f470_nro_registro", "f470_id_bodega", "f470_id_co_movto", "f470_cant_base", "f470_vlr_bruto", "razon"},
+	}
+
+	// Define headers for each sheet
+	headers := [][]string{
+		{"Centro Operacion", "Tipo Documento", "Numero Docto", "Fecha Docto", "Centro operación factura", "Observaciones", "Bodega componentes Kit"},
+		{"Centro Operacion", "Tipo Documento", "Consecutivo Documento", "Numero Registro", "Valor Descuento Unitario", "Valor Descuento Total"},
+		{"Centro Operacion", "Número Documento"},
+		{"Centro Operacion", "Consecutivo Documento", "Numero Registro", "Bodega", "Centro Operacion Mvmnto", "Cantidad", "Valor Neto", "Referencia"},
+		{"Centro Operacion", "Consecutivo Documento", "Numero Registro", "Bodega", "Centro Operacion Mvmnto", "Cantidad", "Valor Neto", "Razon"},
+	}
+
+	// Create sheets and add headers
+	for i, sheetName := range sheetNames {
+		index, err := file.NewSheet(sheetName)
+		if err != nil {
+			return nil, err
+		}
+		file.SetActiveSheet(index)
+
+		// Add headers to the sheet
+		for col, header := range headers[i] {
+			cell := ExcelColumnID(col+1) + "1"
+			file.SetCellValue(sheetName, cell, header)
+		}
+
+		// Extract data from the document and add to the sheet
+		if data, ok := doc[sheetName]; ok {
+			if records, ok := data.([]map[string]string); ok {
+				for rowIdx, record := range records {
+					for colIdx, header := range headersColumns[i] {
+						value, ok := record[header]
+						if !ok {
+							return nil, fmt.Errorf("missing value for header %s in sheet %s", header, sheetName)
+						}
+						cell := ExcelColumnID(colIdx+1) + fmt.Sprint(rowIdx+2) // Start from row 2 for data
+						file.SetCellValue(sheetName, cell, value)
+					}
+				}
+			}
+		}
+	}
+	// Delete the default "Sheet1"
+	file.DeleteSheet("Sheet1")
+
+	// Check if the file is empty
+	if file.SheetCount == 0 {
+		return nil, errors.New("no data found, Excel file is empty")
+	}
+
+	return file, nil
+}
@@ -0,0 +1,176 @@
+package siesa_test
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/BacoFoods/menu/pkg/invoice"
+	"github.com/BacoFoods/menu/pkg/siesa"
+	"github.com/BacoFoods/menu/pkg/store"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// stubSource returns the document of its fields
+type stubSource struct {
+	header siesa.DocumentHeader
+	lines  []siesa.DocumentLine
+	err    error
+}
+
+func (s stubSource) Document() (siesa.DocumentHeader, []siesa.DocumentLine, error) {
+	return s.header, s.lines, s.err
+}
+
+var _ = Describe("Sales document", func() {
+	date := time.Date(2023, 10, 5, 0, 0, 0, 0, time.UTC)
+
+	Context("Build", func() {
+		source := stubSource{
+			header: siesa.DocumentHeader{OpsCenter: "300", Warehouse: "300", Notes: "Orden 1", DueDate: "20231005"},
+			lines: []siesa.DocumentLine{
+				{Name: "Baco", Reference: "1000", Quantity: 2, GrossValue: 60000, UnitDiscount: 3000, TotalDiscount: 6000, OpsCenter: "300", Warehouse: "300"},
+				{Name: "TIENE ENTRADA", Quantity: 1, GrossValue: 1, OpsCenter: "300", Warehouse: "300", Invalid: "producto invalido: TIENE ENTRADA"},
+				{Name: "Soda", Reference: "1001", Quantity: 1, GrossValue: 6000, OpsCenter: "300", Warehouse: "300"},
+				{Name: "Papas", Reference: "1002", Quantity: 1, GrossValue: 9000.4, UnitDiscount: 450.6, TotalDiscount: 450.6, OpsCenter: "300", Warehouse: "300"},
+			},
+		}
+
+		It("Fills the header and the installment with the document store", func() {
+			doc, err := siesa.BuildDocument("77", date, source)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(doc[siesa.SheetHeader]).To(Equal([]map[string]string{{
+				"F350_ID_CO":                    "300",
+				"F350_ID_TIPO_DOCTO":            "FVR",
+				"F350_CONSEC_DOCTO":             "77",
+				"F350_FECHA":                    "20231005",
+				"f461_id_co_fact":               "300",
+				"f461_notas":                    "Orden 1",
+				"F461_ID_BODEGA_COMPON_PROCESO": "300",
+			}}))
+			Expect(doc[siesa.SheetInstallments]).To(Equal([]map[string]string{{
+				"F350_ID_CO": "300", "F350_CONSEC_DOCTO": "77", "F353_FECHA_VCTO": "20231005",
+			}}))
+		})
+
+		It("Numbers the movements and records their discounts with the same number", func() {
+			doc, _ := siesa.BuildDocument("77", date, source)
+
+			movements := doc[siesa.SheetMovements].([]map[string]string)
+			Expect(movements).To(HaveLen(3))
+			Expect(movements[0]["f470_nro_registro"]).To(Equal("1"))
+			Expect(movements[0]["f470_referencia_item"]).To(Equal("1000"))
+			Expect(movements[0]["f470_cant_base"]).To(Equal("2"))
+			Expect(movements[2]["f470_nro_registro"]).To(Equal("3"))
+			Expect(movements[2]["f470_vlr_bruto"]).To(Equal("9000"))
+
+			discounts := doc[siesa.SheetDiscounts].([]map[string]string)
+			Expect(discounts).To(HaveLen(2))
+			Expect(discounts[0]["f471_nro_registro"]).To(Equal("1"))
+			Expect(discounts[0]["f471_vlr_uni"]).To(Equal("3000"))
+			Expect(discounts[0]["f471_vlr_tot"]).To(Equal("6000"))
+			Expect(discounts[1]["f471_nro_registro"]).To(Equal("3"))
+			Expect(discounts[1]["f471_vlr_uni"]).To(Equal("451"))
+		})
+
+		It("Leaves the invalid lines out of the movements", func() {
+			doc, _ := siesa.BuildDocument("77", date, source)
+
+			invalidItems := doc[siesa.SheetInvalidItems].([]map[string]string)
+			Expect(invalidItems).To(HaveLen(1))
+			Expect(invalidItems[0]["f470_nro_registro"]).To(Equal("1"))
+			Expect(invalidItems[0]["razon"]).To(Equal("producto invalido: TIENE ENTRADA"))
+		})
+
+		It("Fails with the source", func() {
+			_, err := siesa.BuildDocument("77", date, stubSource{err: fmt.Errorf(siesa.ErrorDocumentEmpty)})
+			Expect(err).To(MatchError(siesa.ErrorDocumentEmpty))
+		})
+	})
+
+	Context("Popapp source", func() {
+		shipping := 0
+		orders := []siesa.PopappOrder{{
+			DisplayID:     "A1",
+			Plataforma:    "Popapp",
+			Tipo:          "PICK_UP",
+			KeyLocal:      "bacuzonag",
+			NombreStore:   "Zona G",
+			FechaCreacion: "2023-10-05 12:30:00",
+			Total:         siesa.PopappTotal{TotalItems: 40000, Total: 36000, CostoEnvio: &shipping},
+			Items: []siesa.PopappItem{
+				{Cantidad: 1, Producto: siesa.PopappProduct{Nombre: "Baco", PrecioUnitario: 30000}},
+				{Cantidad: 2, Producto: siesa.PopappProduct{Nombre: "Soda", PrecioUnitario: 5000}},
+			},
+		}}
+		references := siesa.NewReferenceIndex([]siesa.ProductReference{
+			{Platform: siesa.PlatformPopapp, OrderType: "PICK_UP", Name: "Baco", ERPReference: "1000"},
+		})
+
+		It("Shares the order discount between the items and resolves their references", func() {
+			source := siesa.PopappSource{
+				Orders:     orders,
+				Locations:  siesa.Locations{"bacuzonag": &store.Store{OpsCenter: "300", Wharehouse: "300"}},
+				References: references,
+			}
+
+			doc, err := siesa.BuildDocument("78", date, source)
+
+			Expect(err).NotTo(HaveOccurred())
+			movements := doc[siesa.SheetMovements].([]map[string]string)
+			Expect(movements).To(HaveLen(2))
+			Expect(movements[0]["f470_id_co"]).To(Equal("300"))
+			Expect(movements[0]["f470_referencia_item"]).To(Equal("1000"))
+			Expect(movements[1]["f470_referencia_item"]).To(BeEmpty())
+			Expect(movements[1]["f470_vlr_bruto"]).To(Equal("10000"))
+
+			discounts := doc[siesa.SheetDiscounts].([]map[string]string)
+			Expect(discounts[0]["f471_vlr_tot"]).To(Equal("3000"))
+			Expect(discounts[1]["f471_vlr_tot"]).To(Equal("1000"))
+		})
+
+		It("Rejects the orders of a local not mapped to a store with ops center and warehouse", func() {
+			for _, locations := range []siesa.Locations{{}, {"bacuzonag": &store.Store{OpsCenter: "300"}}} {
+				source := siesa.PopappSource{Orders: orders, Locations: locations, References: references}
+
+				_, err := siesa.BuildDocument("78", date, source)
+				Expect(err).To(MatchError(siesa.ErrorDocumentLocation + " - bacuzonag -"))
+			}
+		})
+	})
+
+	Context("Invoice source", func() {
+		id := func(value uint) *uint { return &value }
+
+		It("Posts every invoice item with its discount", func() {
+			created := time.Date(2023, 10, 5, 20, 0, 0, 0, time.UTC)
+			source := siesa.InvoiceSource{
+				Stores: []store.Store{{ID: 1, OpsCenter: "300", Wharehouse: "300"}},
+				Invoices: []invoice.Invoice{{CreatedAt: &created, Items: []invoice.Item{
+					{ProductID: id(10), Name: "Baco", Price: 30000, DiscountedPrice: 27000},
+					{ProductID: id(11), Name: "Limonada", Price: 8000, DiscountedPrice: 8000},
+				}}},
+				References: siesa.NewReferenceIndex([]siesa.ProductReference{{Platform: siesa.PlatformPOS, ProductID: id(10), ERPReference: "1000"}}),
+			}
+
+			doc, err := siesa.BuildDocument("79", date, source)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(doc[siesa.SheetHeader].([]map[string]string)[0]["f461_notas"]).To(Equal("20231005  - del pdv [1]"))
+			Expect(doc[siesa.SheetMovements]).To(HaveLen(1))
+			Expect(doc[siesa.SheetDiscounts].([]map[string]string)[0]["f471_vlr_uni"]).To(Equal("3000"))
+			Expect(doc[siesa.SheetInvalidItems].([]map[string]string)[0]["razon"]).To(Equal("producto sin referencia: Limonada"))
+		})
+
+		It("Rejects the stores of different ops centers", func() {
+			source := siesa.InvoiceSource{
+				Stores:   []store.Store{{OpsCenter: "300", Wharehouse: "300"}, {OpsCenter: "301", Wharehouse: "300"}},
+				Invoices: []invoice.Invoice{{}},
+			}
+
+			_, err := siesa.BuildDocument("79", date, source)
+			Expect(err).To(MatchError(siesa.ErrorDocumentOpsCenter + " - 301 - 300 -"))
+		})
+	})
+})
@@ -1,20 +1,81 @@
 package siesa
 
 import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/BacoFoods/menu/pkg/invoice"
+	"github.com/BacoFoods/menu/pkg/order"
+	"github.com/BacoFoods/menu/pkg/shared"
+	"github.com/xuri/excelize/v2"
 	"gorm.io/gorm"
 )
 
 const (
-	ErrorBadRequest        = "error bad request"
-	ErrorInternalServer    = "internal server error"
-	ErrorGettingReferences = "error getting reference"
-	ErrorCreatingReference = "error creating reference"
-	ErrorDeletingReference = "error deleting reference"
-	ErrorUpdatingReference = "error updating reference"
+	ErrorBadRequest               = "error bad request"
+	ErrorInternalServer           = "internal server error"
+	ErrorGettingReferences        = "error getting reference"
+	ErrorCreatingReference        = "error creating reference"
+	ErrorDeletingReference        = "error deleting reference"
+	ErrorUpdatingReference        = "error updating reference"
+	ErrorReferenceInvalidPlatform = "error invalid reference platform"
+	ErrorReferenceWithoutProduct  = "error reference needs a product id, sku or name"
+	ErrorReferenceEmpty           = "error reference is empty"
+	ErrorReferenceDuplicated      = "error reference is duplicated"
+	ErrorReferenceFile            = "error reading references file"
+	ErrorReferenceColumns         = "error references file must have the platform and reference columns and a product_id, sku or name column"
+	ErrorReferenceInvalidFile     = "error invalid references file"
+	ErrorImportingReferences      = "error importing references"
+	ErrorUnmappedDates            = "error unmapped products dates must be formatted as 2006-01-02"
+	ErrorUnmappedRange            = "error unmapped products start date must be before the end date"
+	ErrorFindingUnmapped          = "error finding unmapped products"
+	ErrorFindingRuns              = "error finding runs"
+	ErrorGettingRun               = "error getting run"
+	ErrorRunAlreadySucceeded      = "error run already succeeded"
+	ErrorRunInProgress            = "error run is in progress"
+	ErrorRerunningRun             = "error re-running run"
+	ErrorDocumentEmpty            = "error document has no sales"
+	ErrorDocumentNoStores         = "error document has no stores"
+	ErrorDocumentOpsCenter        = "error stores don't share the same ops center"
+	ErrorDocumentWarehouse        = "error stores don't share the same warehouse"
+	ErrorDocumentLocation         = "error popapp local is not mapped to a store with ops center and warehouse"
+
+	RunSourcePopapp = "popapp" // the run posts the Popapp orders of the store locations
+	RunSourceNative = "native" // the run posts the invoices of the store on this POS
+
+	RunStatusPending = "pending"
+	RunStatusRunning = "running"
+	RunStatusSuccess = "success"
+	RunStatusFailed  = "failed"
+	RunStatusEmpty   = "empty" // the store had no paid orders on the day, nothing was posted
+
+	// RunStaleAfter is the time after which a running run is considered interrupted and can be re-run
+	RunStaleAfter = time.Hour
+
+	// PlatformPOS is used for the orders of this POS, mapped by channel and product
+	PlatformPOS             = "pos"
+	PlatformPopapp          = "popapp"
+	PlatformRappi           = "rappi"
+	PlatformDidi            = "didi"
+	PlatformBacuMarketplace = "bacu_marketplace"
+	PlatformOrderInTable    = "orderintable"
 )
 
+// popappPlatforms maps the platform names reported by Popapp orders to the reference platforms
+var popappPlatforms = map[string]string{
+	"Popapp":          PlatformPopapp,
+	"RAPPI":           PlatformRappi,
+	"DiDi":            PlatformDidi,
+	"BACOMARKETPLACE": PlatformBacuMarketplace,
+	"ORDERINTABLE":    PlatformOrderInTable,
+}
+
 type SiesaDocument struct {
 	ID          uint       `json:"id" gorm:"primaryKey"`
 	Stores      string     `json:"stores"`
@@ -27,31 +88,425 @@ type SiesaDocument struct {
 	CreatedAt   *time.Time `json:"created_at,omitempty" swaggerignore:"true"`
 }
 
-type Reference struct {
-	ID                       uint            `json:"id" gorm:"primaryKey"`
-	Category                 string          `json:"categoria"`
-	Popapp                   string          `json:"popapp"`
-	ReferenciaPdv            string          `json:"referencia_pdv"`
-	ReferenciaDeliveryInline string          `json:"referencia_delivery_inline"`
-	RappiPickUp              string          `json:"rappi_pick_up"`
-	RappiBacu                string          `json:"rappi_bacu"`
-	DidiStu                  string          `json:"didi_stu"`
-	DidiBacu                 string          `json:"didi_bacu"`
-	BacuMarketplace          string          `json:"bacu_marketplace"`
-	CreatedAt                *time.Time      `json:"created_at,omitempty" swaggerignore:"true"`
-	UpdatedAt                *time.Time      `json:"updated_at,omitempty" swaggerignore:"true"`
-	DeletedAt                *gorm.DeletedAt `json:"deleted_at,omitempty" swaggerignore:"true"`
+// SiesaRun is the scheduled integration of the orders of a store on a day, there is one run per store and
+// day so a day that succeeded is never posted again. The document number is kept between attempts.
+type SiesaRun struct {
+	ID            uint              `json:"id" gorm:"primaryKey"`
+	StoreID       uint              `json:"store_id" gorm:"uniqueIndex:idx_siesa_run_day"`
+	Date          time.Time         `json:"date" gorm:"type:date;uniqueIndex:idx_siesa_run_day"`
+	Source        string            `json:"source" enums:"popapp,native"`
+	Locations     string            `json:"locations"`
+	DocumentID    *uint             `json:"document_id"`
+	Status        string            `json:"status" gorm:"index" enums:"pending,running,success,failed,empty"`
+	Attempts      int               `json:"attempts"`
+	NextAttemptAt *time.Time        `json:"next_attempt_at"`
+	Orders        int               `json:"orders"`
+	Error         string            `json:"error"`
+	SucceededAt   *time.Time        `json:"succeeded_at"`
+	History       []SiesaRunAttempt `json:"history,omitempty" gorm:"foreignKey:RunID"`
+	CreatedAt     *time.Time        `json:"created_at,omitempty" swaggerignore:"true"`
+	UpdatedAt     *time.Time        `json:"updated_at,omitempty" swaggerignore:"true"`
+}
+
+// IsStale checks if the run is running for longer than RunStaleAfter, as when the replica running it stopped
+func (r SiesaRun) IsStale(now time.Time) bool {
+	return r.Status == RunStatusRunning && r.UpdatedAt != nil && now.Sub(*r.UpdatedAt) > RunStaleAfter
+}
+
+// SiesaRunAttempt records the payload posted to SIESA on an attempt of a run and the SIESA response
+type SiesaRunAttempt struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	RunID      uint       `json:"run_id" gorm:"index"`
+	Attempt    int        `json:"attempt"`
+	Request    string     `json:"request" gorm:"type:text"`
+	Response   string     `json:"response" gorm:"type:text"`
+	StatusCode int        `json:"status_code"`
+	Error      string     `json:"error"`
+	CreatedAt  *time.Time `json:"created_at,omitempty" swaggerignore:"true"`
+}
+
+// RetryBackoff returns the wait before the next attempt, doubled on every failed attempt
+func RetryBackoff(base time.Duration, attempts int) time.Duration {
+	backoff := base
+	for i := 1; i < attempts && backoff < 24*time.Hour; i++ {
+		backoff *= 2
+	}
+	return backoff
+}
+
+// ProductReference maps a product sold on a platform to its ERP reference. The product is identified by its
+// product id, SKU or, for the platforms integrated through Popapp, the name the platform reports. An empty
+// order type or channel applies to every order type or channel of the platform.
+type ProductReference struct {
+	ID           uint            `json:"id" gorm:"primaryKey"`
+	Platform     string          `json:"platform" binding:"required" gorm:"index" enums:"pos,popapp,rappi,didi,bacu_marketplace,orderintable"`
+	ChannelID    *uint           `json:"channel_id,omitempty"`
+	OrderType    string          `json:"order_type,omitempty" example:"PICK_UP"`
+	ProductID    *uint           `json:"product_id,omitempty" gorm:"index"`
+	SKU          string          `json:"sku,omitempty" gorm:"index"`
+	Name         string          `json:"name,omitempty"`
+	Category     string          `json:"category,omitempty"`
+	ERPReference string          `json:"erp_reference" binding:"required" example:"1002345"`
+	CreatedAt    *time.Time      `json:"created_at,omitempty" swaggerignore:"true"`
+	UpdatedAt    *time.Time      `json:"updated_at,omitempty" swaggerignore:"true"`
+	DeletedAt    *gorm.DeletedAt `json:"deleted_at,omitempty" swaggerignore:"true"`
+}
+
+func (ProductReference) TableName() string {
+	return "siesa_product_references"
+}
+
+// Validate checks the platform, the ERP reference and that the product is identified
+func (p ProductReference) Validate() error {
+	if !IsValidPlatform(p.Platform) {
+		return fmt.Errorf(ErrorReferenceInvalidPlatform)
+	}
+	if strings.TrimSpace(p.ERPReference) == "" {
+		return fmt.Errorf(ErrorReferenceEmpty)
+	}
+	if p.ProductID == nil && strings.TrimSpace(p.SKU) == "" && strings.TrimSpace(p.Name) == "" {
+		return fmt.Errorf(ErrorReferenceWithoutProduct)
+	}
+	return nil
+}
+
+// keys returns the index keys of the reference, one per product identifier
+func (p ProductReference) keys() []string {
+	keys := make([]string, 0)
+	if p.ProductID != nil {
+		keys = append(keys, referenceKey(p.Platform, p.ChannelID, p.OrderType, "id", fmt.Sprint(*p.ProductID)))
+	}
+	if p.SKU != "" {
+		keys = append(keys, referenceKey(p.Platform, p.ChannelID, p.OrderType, "sku", p.SKU))
+	}
+	if p.Name != "" {
+		keys = append(keys, referenceKey(p.Platform, p.ChannelID, p.OrderType, "name", p.Name))
+	}
+	return keys
+}
+
+func IsValidPlatform(platform string) bool {
+	switch platform {
+	case PlatformPOS, PlatformPopapp, PlatformRappi, PlatformDidi, PlatformBacuMarketplace, PlatformOrderInTable:
+		return true
+	}
+	return false
+}
+
+// PopappPlatform returns the reference platform of a Popapp order platform
+func PopappPlatform(plataforma string) (string, bool) {
+	platform, ok := popappPlatforms[plataforma]
+	return platform, ok
+}
+
+func referenceKey(platform string, channelID *uint, orderType, kind, value string) string {
+	channel := ""
+	if channelID != nil {
+		channel = fmt.Sprint(*channelID)
+	}
+	return fmt.Sprintf("%s|%s|%s|%s|%s", platform, channel, strings.ToUpper(orderType), kind, strings.ToLower(strings.TrimSpace(value)))
+}
+
+// ReferenceIndex resolves the ERP reference of a product without querying the database for every item
+type ReferenceIndex map[string]string
+
+func NewReferenceIndex(references []ProductReference) ReferenceIndex {
+	index := make(ReferenceIndex)
+	for _, reference := range references {
+		for _, key := range reference.keys() {
+			index[key] = reference.ERPReference
+		}
+	}
+	return index
+}
+
+// Resolve returns the ERP reference of the product on the platform. The most specific mapping wins: the
+// channel and order type ones before the ones for any channel or order type, and the product id before the
+// SKU and the name.
+func (i ReferenceIndex) Resolve(platform string, channelID *uint, orderType string, productID *uint, sku, name string) (string, bool) {
+	channels := []*uint{channelID}
+	if channelID != nil {
+		channels = append(channels, nil)
+	}
+	orderTypes := []string{orderType}
+	if orderType != "" {
+		orderTypes = append(orderTypes, "")
+	}
+
+	for _, channel := range channels {
+		for _, anyOrderType := range orderTypes {
+			if productID != nil {
+				if reference, ok := i[referenceKey(platform, channel, anyOrderType, "id", fmt.Sprint(*productID))]; ok {
+					return reference, true
+				}
+			}
+			if sku != "" {
+				if reference, ok := i[referenceKey(platform, channel, anyOrderType, "sku", sku)]; ok {
+					return reference, true
+				}
+			}
+			if name != "" {
+				if reference, ok := i[referenceKey(platform, channel, anyOrderType, "name", name)]; ok {
+					return reference, true
+				}
+			}
+		}
+	}
+
+	return "", false
+}
+
+// ReferenceRowError is the validation error of a row of a references file, rows are numbered from the header
+type ReferenceRowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// ReferenceImport is the result of a references file import, nothing is imported when a row is invalid
+type ReferenceImport struct {
+	Platforms []string            `json:"platforms"`
+	Imported  int                 `json:"imported"`
+	Errors    []ReferenceRowError `json:"errors"`
+}
+
+// referenceColumns are the accepted headers of the references file
+var referenceColumns = map[string]string{
+	"platform": "platform", "plataforma": "platform",
+	"channel_id": "channel_id", "canal": "channel_id",
+	"order_type": "order_type", "tipo": "order_type",
+	"product_id": "product_id", "producto": "product_id",
+	"sku":  "sku",
+	"name": "name", "nombre": "name",
+	"category": "category", "categoria": "category",
+	"erp_reference": "erp_reference", "reference": "erp_reference", "referencia": "erp_reference", "siesa_id": "erp_reference",
+}
+
+// ParseReferences reads the references of a CSV or XLSX file, the first sheet is read from the XLSX files.
+// Every row is validated and the errors are returned with the row number.
+func ParseReferences(file io.Reader, filename string) ([]ProductReference, []ReferenceRowError, error) {
+	var rows [][]string
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".xlsx":
+		workbook, err := excelize.OpenReader(file)
+		if err != nil {
+			shared.LogWarn("error opening references file", LogService, "ParseReferences", err, filename)
+			return nil, nil, fmt.Errorf(ErrorReferenceFile)
+		}
+		defer workbook.Close()
+
+		rows, err = workbook.GetRows(workbook.GetSheetName(0))
+		if err != nil {
+			shared.LogWarn("error reading references sheet", LogService, "ParseReferences", err, filename)
+			return nil, nil, fmt.Errorf(ErrorReferenceFile)
+		}
+	case ".csv":
+		reader := csv.NewReader(file)
+		reader.TrimLeadingSpace = true
+		reader.FieldsPerRecord = -1
+
+		var err error
+		if rows, err = reader.ReadAll(); err != nil {
+			shared.LogWarn("error reading references file", LogService, "ParseReferences", err, filename)
+			return nil, nil, fmt.Errorf(ErrorReferenceFile)
+		}
+	default:
+		return nil, nil, fmt.Errorf(ErrorReferenceFile)
+	}
+
+	if len(rows) == 0 {
+		return nil, nil, fmt.Errorf(ErrorReferenceColumns)
+	}
+
+	columns := make(map[string]int)
+	for i, header := range rows[0] {
+		if column, ok := referenceColumns[strings.ToLower(strings.TrimSpace(header))]; ok {
+			columns[column] = i
+		}
+	}
+
+	_, hasProductID := columns["product_id"]
+	_, hasSKU := columns["sku"]
+	_, hasName := columns["name"]
+	_, hasPlatform := columns["platform"]
+	_, hasReference := columns["erp_reference"]
+	if !hasPlatform || !hasReference || !(hasProductID || hasSKU || hasName) {
+		return nil, nil, fmt.Errorf(ErrorReferenceColumns)
+	}
+
+	references := make([]ProductReference, 0)
+	rowErrors := make([]ReferenceRowError, 0)
+	seen := make(map[string]int)
+	for i, row := range rows[1:] {
+		line := i + 2
+		value := func(column string) string {
+			index, ok := columns[column]
+			if !ok || index >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[index])
+		}
+
+		if strings.Join(row, "") == "" {
+			continue
+		}
+
+		reference := ProductReference{
+			Platform:     strings.ToLower(value("platform")),
+			OrderType:    strings.ToUpper(value("order_type")),
+			SKU:          value("sku"),
+			Name:         value("name"),
+			Category:     value("category"),
+			ERPReference: value("erp_reference"),
+		}
+
+		var err error
+		if reference.ChannelID, err = parseOptionalID(value("channel_id")); err != nil {
+			rowErrors = append(rowErrors, ReferenceRowError{Row: line, Error: "invalid channel_id"})
+			continue
+		}
+		if reference.ProductID, err = parseOptionalID(value("product_id")); err != nil {
+			rowErrors = append(rowErrors, ReferenceRowError{Row: line, Error: "invalid product_id"})
+			continue
+		}
+
+		if err := reference.Validate(); err != nil {
+			rowErrors = append(rowErrors, ReferenceRowError{Row: line, Error: err.Error()})
+			continue
+		}
+
+		duplicated := false
+		for _, key := range reference.keys() {
+			if previous, ok := seen[key]; ok {
+				rowErrors = append(rowErrors, ReferenceRowError{Row: line, Error: fmt.Sprintf("%s with row %d", ErrorReferenceDuplicated, previous)})
+				duplicated = true
+				break
+			}
+			seen[key] = line
+		}
+		if duplicated {
+			continue
+		}
+
+		references = append(references, reference)
+	}
+
+	return references, rowErrors, nil
+}
+
+func parseOptionalID(value string) (*uint, error) {
+	if value == "" {
+		return nil, nil
+	}
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	uid := uint(id)
+	return &uid, nil
+}
+
+// Platforms returns the platforms of the references
+func Platforms(references []ProductReference) []string {
+	platforms := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, reference := range references {
+		if seen[reference.Platform] {
+			continue
+		}
+		seen[reference.Platform] = true
+		platforms = append(platforms, reference.Platform)
+	}
+	sort.Strings(platforms)
+	return platforms
+}
+
+// UnmappedProduct is a product sold on the orders of this POS without an ERP reference
+type UnmappedProduct struct {
+	ProductID *uint  `json:"product_id"`
+	SKU       string `json:"sku"`
+	Name      string `json:"name"`
+	ChannelID *uint  `json:"channel_id"`
+	OrderType string `json:"order_type"`
+	Modifier  bool   `json:"modifier"`
+	Units     int    `json:"units"`
+	Orders    int    `json:"orders"`
+}
+
+// FindUnmapped returns the items and modifiers of the closed orders that the index can't resolve, grouped
+// by product, channel and order type, sorted by the units sold.
+func FindUnmapped(orders []order.Order, index ReferenceIndex) []UnmappedProduct {
+	unmapped := make([]UnmappedProduct, 0)
+	indexes := make(map[string]int)
+
+	add := func(closedOrder order.Order, productID *uint, sku, name string, modifier bool, counted map[string]bool) {
+		if _, ok := index.Resolve(PlatformPOS, closedOrder.ChannelID, closedOrder.OrderType, productID, sku, name); ok {
+			return
+		}
+
+		product := name
+		if productID != nil {
+			product = fmt.Sprint(*productID)
+		}
+		key := fmt.Sprintf("%s|%s|%t", referenceKey(PlatformPOS, closedOrder.ChannelID, closedOrder.OrderType, "product", product), sku, modifier)
+		position, ok := indexes[key]
+		if !ok {
+			position = len(unmapped)
+			indexes[key] = position
+			unmapped = append(unmapped, UnmappedProduct{
+				ProductID: productID,
+				SKU:       sku,
+				Name:      name,
+				ChannelID: closedOrder.ChannelID,
+				OrderType: closedOrder.OrderType,
+				Modifier:  modifier,
+			})
+		}
+
+		unmapped[position].Units++
+		if !counted[key] {
+			counted[key] = true
+			unmapped[position].Orders++
+		}
+	}
+
+	for _, closedOrder := range orders {
+		counted := make(map[string]bool)
+		for _, item := range closedOrder.Items {
+			add(closedOrder, item.ProductID, item.SKU, item.Name, false, counted)
+			for _, modifier := range item.Modifiers {
+				add(closedOrder, modifier.ProductID, modifier.SKU, modifier.Name, true, counted)
+			}
+			for _, component := range item.Components {
+				add(closedOrder, component.ProductID, component.SKU, component.Name, false, counted)
+			}
+		}
+	}
+
+	sort.SliceStable(unmapped, func(i, j int) bool {
+		return unmapped[i].Units > unmapped[j].Units
+	})
+
+	return unmapped
 }
 
 type Repository interface {
 	CreateDocument(*SiesaDocument) error
 	GetDocuments(limit int) ([]SiesaDocument, error)
+	GetDocument(documentID uint) (*SiesaDocument, error)
 	UpdateDocument(*SiesaDocument) error
-	Create(*Reference) error
-	TruncateRecords() error
-	Find(map[string]string) (*Reference, error)
-	FindReferences(query map[string]string) ([]Reference, error)
-	CreateReference(*Reference) (*Reference, error)
-	DeleteReference(string) (*Reference, error)
-	UpdateReference(*Reference) (*Reference, error)
+	FindReferences(query map[string]string) ([]ProductReference, error)
+	CreateReference(*ProductReference) (*ProductReference, error)
+	DeleteReference(string) (*ProductReference, error)
+	UpdateReference(*ProductReference) (*ProductReference, error)
+	ReplaceReferences(platforms []string, references []ProductReference) error
+	MigrateLegacyReferences() error
+	FindClosedOrders(storeID string, from, to time.Time) ([]order.Order, error)
+	FindPaidInvoices(storeID uint, from, to time.Time) ([]invoice.Invoice, error)
+	CreateRun(*SiesaRun) error
+	GetRun(runID string) (*SiesaRun, error)
+	FindRuns(filter map[string]string, limit int) ([]SiesaRun, error)
+	FindDueRuns(now time.Time, maxAttempts int) ([]SiesaRun, error)
+	ClaimRun(runID uint, statuses ...string) (bool, error)
+	UpdateRun(*SiesaRun) error
+	CreateRunAttempt(*SiesaRunAttempt) error
 }
@@ -0,0 +1,81 @@
+package siesa_test
+
+import (
+	"time"
+
+	"github.com/BacoFoods/menu/pkg/siesa"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Product references", func() {
+	id := func(value uint) *uint { return &value }
+	salon, rappi := id(1), id(2)
+
+	index := siesa.NewReferenceIndex([]siesa.ProductReference{
+		{Platform: siesa.PlatformPOS, ProductID: id(10), ERPReference: "1000"},
+		{Platform: siesa.PlatformPOS, SKU: "BUR-01", ERPReference: "1001"},
+		{Platform: siesa.PlatformPOS, Name: " Papas Fritas ", ERPReference: "1002"},
+		{Platform: siesa.PlatformPOS, ChannelID: rappi, ProductID: id(10), ERPReference: "2000"},
+		{Platform: siesa.PlatformPOS, ChannelID: rappi, OrderType: "pick_up", ProductID: id(10), ERPReference: "2001"},
+		{Platform: siesa.PlatformPOS, OrderType: "DELIVERY", SKU: "BUR-01", ERPReference: "3001"},
+		{Platform: siesa.PlatformPopapp, ProductID: id(10), ERPReference: "4000"},
+	})
+
+	resolve := func(platform string, channelID *uint, orderType string, productID *uint, sku, name string) string {
+		reference, ok := index.Resolve(platform, channelID, orderType, productID, sku, name)
+		Expect(ok).To(BeTrue())
+		return reference
+	}
+
+	It("Prefers the product id to the sku and the name", func() {
+		Expect(resolve(siesa.PlatformPOS, salon, "", id(10), "BUR-01", "Baco")).To(Equal("1000"))
+		Expect(resolve(siesa.PlatformPOS, salon, "", id(99), "BUR-01", "Papas fritas")).To(Equal("1001"))
+		Expect(resolve(siesa.PlatformPOS, salon, "", id(99), "", "PAPAS FRITAS")).To(Equal("1002"))
+	})
+
+	It("Prefers the channel and order type mappings to the ones for any channel or order type", func() {
+		Expect(resolve(siesa.PlatformPOS, rappi, "PICK_UP", id(10), "", "")).To(Equal("2001"))
+		Expect(resolve(siesa.PlatformPOS, rappi, "DELIVERY", id(10), "", "")).To(Equal("2000"))
+		Expect(resolve(siesa.PlatformPOS, salon, "delivery", id(99), "BUR-01", "")).To(Equal("3001"))
+		Expect(resolve(siesa.PlatformPOS, nil, "", id(10), "", "")).To(Equal("1000"))
+	})
+
+	It("Keeps the platforms apart", func() {
+		Expect(resolve(siesa.PlatformPopapp, nil, "", id(10), "", "")).To(Equal("4000"))
+
+		_, ok := index.Resolve(siesa.PlatformRappi, nil, "", id(10), "BUR-01", "Papas fritas")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("Misses the products without a mapping", func() {
+		reference, ok := index.Resolve(siesa.PlatformPOS, salon, "DINE_IN", nil, "", "Limonada")
+		Expect(ok).To(BeFalse())
+		Expect(reference).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Runs", func() {
+	It("Doubles the retry backoff on every failed attempt", func() {
+		Expect(siesa.RetryBackoff(15*time.Minute, 0)).To(Equal(15 * time.Minute))
+		Expect(siesa.RetryBackoff(15*time.Minute, 1)).To(Equal(15 * time.Minute))
+		Expect(siesa.RetryBackoff(15*time.Minute, 2)).To(Equal(30 * time.Minute))
+		Expect(siesa.RetryBackoff(15*time.Minute, 4)).To(Equal(2 * time.Hour))
+	})
+
+	It("Stops doubling the retry backoff after a day", func() {
+		Expect(siesa.RetryBackoff(15*time.Minute, 8)).To(Equal(32 * time.Hour))
+		Expect(siesa.RetryBackoff(15*time.Minute, 50)).To(Equal(32 * time.Hour))
+		Expect(siesa.RetryBackoff(0, 5)).To(BeZero())
+	})
+
+	It("Takes a running run without updates as stale", func() {
+		now := time.Date(2023, 10, 5, 12, 0, 0, 0, time.UTC)
+		updated := now.Add(-siesa.RunStaleAfter - time.Minute)
+		recent := now.Add(-time.Minute)
+
+		Expect(siesa.SiesaRun{Status: siesa.RunStatusRunning, UpdatedAt: &updated}.IsStale(now)).To(BeTrue())
+		Expect(siesa.SiesaRun{Status: siesa.RunStatusRunning, UpdatedAt: &recent}.IsStale(now)).To(BeFalse())
+		Expect(siesa.SiesaRun{Status: siesa.RunStatusFailed, UpdatedAt: &updated}.IsStale(now)).To(BeFalse())
+	})
+})
@@ -14,18 +14,6 @@ import (
 
 const LogHandler = "pkg/siesa/handler"
 
-var mapLocalesNombres = map[string][]string{
-	"ZonaG":      {"bacuzonagc14", "bacuzonag"},
-	"Flormorado": {"bacuflormoradopc2", "bacuflormorado", "flormorado10885"},
-	"CL109":      {"bacucalle109", "bacu109"},
-	"CL90":       {"feriadelmillon2", "bacucalle90delivery"},
-	"Connecta":   {"connectasalon110665", "bacuconnecta", "connectasalon210666"},
-	"CityU":      {"cityusalon1", "cityusalon2", "bacucityu"},
-	"Colina":     {"bacucolinapc110881"},
-	"Titan":      {"bacutitansalon10883"},
-	"Nogal":      {"bacunogalespc110884"},
-}
-
 type Handler struct {
 	service Service
 }
@@ -34,6 +22,11 @@ func NewHandler(service Service) *Handler {
 	return &Handler{service}
 }
 
+type RequestRunSchedule struct {
+	StoreID uint   `json:"store_id" binding:"required"`
+	Date    string `json:"date" binding:"required" example:"2006-01-02"`
+}
+
 type RequestExcelCreate struct {
 	StartDate   string   `json:"start_date"`
 	EndDate     string   `json:"end_date"`
@@ -42,7 +35,7 @@ type RequestExcelCreate struct {
 
 // GetLocales to handle the request to get the locales for SIESA
 // @Summary Get the locales for SIESA
-// @Description Get the locales for SIESA
+// @Description Get the Popapp locales for SIESA by store name, as mapped in the store external locations
 // @Tags SIESA
 // @Accept json
 // @Produce json
@@ -52,7 +45,14 @@ type RequestExcelCreate struct {
 // @Failure 422 {object} shared.Response
 // @Router /siesa/locales [get]
 func (h *Handler) GetLocales(c *gin.Context) {
-	c.JSON(http.StatusOK, shared.SuccessResponse(mapLocalesNombres))
+	locales, err := h.service.GetLocales()
+	if err != nil {
+		shared.LogError("error getting locales", LogHandler, "GetLocales", err)
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse("error getting locales"))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(locales))
 }
 
 // CreateJSON handles a request to generate an Excel file with orders.
@@ -160,6 +160,123 @@ func (h *Handler) GetRunHistory(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, shared.SuccessResponse(history))
 }
 
+// FindRuns handles a request to find the scheduled runs
+// @Summary Find the scheduled runs
+// @Description Find the scheduled integration runs by store, status and date, the latest days first
+// @Tags SIESA
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param store_id query string false "store id"
+// @Param status query string false "status" Enums(pending, running, success, failed, empty)
+// @Param date query string false "date" example(2006-01-02)
+// @Param limit query number false "Limit of results"
+// @Success 200 {object} object{status=string,data=[]SiesaRun}
+// @Failure 401 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Router /siesa/runs [get]
+func (h *Handler) FindRuns(ctx *gin.Context) {
+	filter := make(map[string]string)
+	for _, key := range []string{"store_id", "status", "date"} {
+		if value := ctx.Query(key); value != "" {
+			filter[key] = value
+		}
+	}
+
+	sLimit := ctx.Query("limit")
+	limit := 100
+	if v, err := strconv.Atoi(sLimit); sLimit != "" && err == nil {
+		limit = v
+	}
+
+	runs, err := h.service.FindRuns(filter, limit)
+	if err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, shared.SuccessResponse(runs))
+}
+
+// GetRun handles a request to get a scheduled run
+// @Summary Get a scheduled run
+// @Description Get a scheduled run with the payloads posted and the responses of every attempt
+// @Tags SIESA
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "run id"
+// @Success 200 {object} object{status=string,data=SiesaRun}
+// @Failure 401 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Router /siesa/runs/{id} [get]
+func (h *Handler) GetRun(ctx *gin.Context) {
+	run, err := h.service.GetRun(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, shared.SuccessResponse(run))
+}
+
+// ScheduleRun handles a request to schedule the run of a store and day
+// @Summary Schedule a run
+// @Description Schedule the integration of a store and day, as a day missed by the scheduler. The scheduler posts it on its next tick, a day that already has a run keeps it.
+// @Tags SIESA
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param run body RequestRunSchedule true "store and day"
+// @Success 200 {object} object{status=string,data=SiesaRun}
+// @Failure 400 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Router /siesa/runs [post]
+func (h *Handler) ScheduleRun(ctx *gin.Context) {
+	var requestBody RequestRunSchedule
+	if err := ctx.ShouldBindJSON(&requestBody); err != nil {
+		shared.LogWarn("warning binding request fail", LogHandler, "ScheduleRun", err)
+		ctx.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorBadRequest))
+		return
+	}
+
+	run, err := h.service.ScheduleRun(requestBody.StoreID, requestBody.Date)
+	if err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, shared.SuccessResponse(run))
+}
+
+// Rerun handles a request to re-run a failed run
+// @Summary Re-run a failed run
+// @Description Post a failed, empty or interrupted run again right away, the runs that succeeded can't be posted again
+// @Tags SIESA
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "run id"
+// @Success 200 {object} object{status=string,data=SiesaRun}
+// @Failure 401 {object} shared.Response
+// @Failure 409 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Router /siesa/runs/{id}/rerun [post]
+func (h *Handler) Rerun(ctx *gin.Context) {
+	run, err := h.service.Rerun(ctx.Param("id"))
+	if err != nil {
+		status := http.StatusUnprocessableEntity
+		if err.Error() == ErrorRunAlreadySucceeded || err.Error() == ErrorRunInProgress {
+			status = http.StatusConflict
+		}
+		ctx.JSON(status, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, shared.SuccessResponse(run))
+}
+
 // Create handles a request to generate an Excel file with orders.
 // @Summary Generate an Excel file with orders
 // @Description Handles a request to create an Excel file containing orders based on the specified parameters.
@@ -208,33 +325,26 @@ func (h *Handler) Create(ctx *gin.Context) {
 // FindReferences handles a request to find references based on filters
 // @Tags SIESA
 // @Summary Find references
-// @Description Find references based on filters
-// @Param referencia_pdv query string false "PDV Reference"
-// @Param referencia_delivery_inline query string false "Delivery Inline Reference"
+// @Description Find the ERP references of the products by platform, order type, channel or product
+// @Param platform query string false "platform" Enums(pos, popapp, rappi, didi, bacu_marketplace, orderintable)
+// @Param order_type query string false "order type"
+// @Param channel_id query string false "channel id"
+// @Param product_id query string false "product id"
+// @Param sku query string false "product sku"
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
-// @Success 200 {object} shared.Response
+// @Success 200 {object} object{status=string,data=[]ProductReference}
 // @Failure 400 {object} shared.Response
 // @Failure 422 {object} shared.Response
 // @Failure 401 {object} shared.Response
 // @Router /siesa/reference [get]
 func (h *Handler) FindReferences(ctx *gin.Context) {
 	filters := make(map[string]string)
-
-	referenciaPDV := ctx.Query("referencia_pdv")
-	if referenciaPDV != "" {
-		filters["referencia_pdv"] = referenciaPDV
-	}
-
-	referenciaDeliveryInline := ctx.Query("referencia_delivery_inline")
-	if referenciaDeliveryInline != "" {
-		filters["referencia_delivery_inline"] = referenciaDeliveryInline
-	}
-
-	// Check if both filters are empty, and if so, don't apply any filters
-	if referenciaPDV == "" && referenciaDeliveryInline == "" {
-		filters = nil
+	for _, filter := range []string{"platform", "order_type", "channel_id", "product_id", "sku"} {
+		if value := ctx.Query(filter); value != "" {
+			filters[filter] = value
+		}
 	}
 
 	references, err := h.service.FindReferences(filters)
@@ -250,17 +360,17 @@ func (h *Handler) FindReferences(ctx *gin.Context) {
 // CreateReference handles a request to create a reference
 // @Tags SIESA
 // @Summary Create a reference
-// @Description Create a reference
-// @Param reference body Reference true "Reference request"
+// @Description Map a product on a platform to its ERP reference, the product is identified by product id, SKU or name
+// @Param reference body ProductReference true "Reference request"
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
-// @Success 200 {object} shared.Response
+// @Success 200 {object} object{status=string,data=ProductReference}
 // @Failure 400 {object} shared.Response
 // @Failure 422 {object} shared.Response
 // @Router /siesa/reference [post]
 func (h *Handler) CreateReference(ctx *gin.Context) {
-	var requestBody Reference
+	var requestBody ProductReference
 	if err := ctx.BindJSON(&requestBody); err != nil {
 		shared.LogWarn("warning binding request failed", LogHandler, "CreateReference", err)
 		ctx.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorBadRequest))
@@ -269,7 +379,7 @@ func (h *Handler) CreateReference(ctx *gin.Context) {
 
 	reference, err := h.service.CreateReference(&requestBody)
 	if err != nil {
-		shared.LogError("error creating reference", LogHandler, "CreateReference", err, reference)
+		shared.LogError("error creating reference", LogHandler, "CreateReference", err, requestBody)
 		ctx.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(ErrorCreatingReference))
 		return
 	}
@@ -285,7 +395,7 @@ func (h *Handler) CreateReference(ctx *gin.Context) {
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
-// @Success 200 {object} object{status=string,data=Reference}
+// @Success 200 {object} object{status=string,data=ProductReference}
 // @Failure 400 {object} shared.Response
 // @Failure 422 {object} shared.Response
 // @Router /siesa/reference/{id} [delete]
@@ -293,7 +403,7 @@ func (h *Handler) DeleteReference(c *gin.Context) {
 	referenceID := c.Param("id")
 	reference, err := h.service.DeleteReference(referenceID)
 	if err != nil {
-		shared.LogError("error deleting category", LogHandler, "DeleteReference", err, reference)
+		shared.LogError("error deleting reference", LogHandler, "DeleteReference", err, referenceID)
 		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(ErrorDeletingReference))
 		return
 	}
@@ -305,28 +415,101 @@ func (h *Handler) DeleteReference(c *gin.Context) {
 // @Summary To update a reference
 // @Description To update a reference
 // @Param id path string true "reference id"
-// @Param reference body Reference true "reference request"
+// @Param reference body ProductReference true "reference request"
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
-// @Success 200 {object} object{status=string,data=Reference}
+// @Success 200 {object} object{status=string,data=ProductReference}
 // @Failure 400 {object} shared.Response
 // @Failure 422 {object} shared.Response
 // @Router /siesa/reference/{id} [patch]
 func (h *Handler) UpdateReference(c *gin.Context) {
-	var requestBody Reference
+	var requestBody ProductReference
 	if err := c.BindJSON(&requestBody); err != nil {
 		shared.LogWarn("warning binding request fail", LogHandler, "UpdateReference", err)
 		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorBadRequest))
 		return
 	}
-	category, err := h.service.UpdateReference(&requestBody)
+
+	referenceID, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
-		shared.LogError("error updating category", LogHandler, "Update", err, category)
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorBadRequest))
+		return
+	}
+	requestBody.ID = uint(referenceID)
+
+	reference, err := h.service.UpdateReference(&requestBody)
+	if err != nil {
+		shared.LogError("error updating reference", LogHandler, "UpdateReference", err, requestBody)
 		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(ErrorUpdatingReference))
 		return
 	}
-	c.JSON(http.StatusOK, shared.SuccessResponse(category))
+	c.JSON(http.StatusOK, shared.SuccessResponse(reference))
+}
+
+// ImportReferences handles a request to import the references of a CSV or XLSX file
+// @Tags SIESA
+// @Summary Import references
+// @Description Replaces the references of the platforms in the file. The header must have the platform and erp_reference columns and a product_id, sku or name column, order_type, channel_id and category are optional. Nothing is imported when a row is invalid, the errors are returned by row.
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "CSV or XLSX references file"
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=ReferenceImport}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} object{message=string,data=ReferenceImport}
+// @Router /siesa/reference/import [post]
+func (h *Handler) ImportReferences(c *gin.Context) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		shared.LogWarn("warning getting references file", LogHandler, "ImportReferences", err)
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorBadRequest))
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		shared.LogWarn("warning opening references file", LogHandler, "ImportReferences", err)
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorReferenceFile))
+		return
+	}
+	defer file.Close()
+
+	result, err := h.service.ImportReferences(file, fileHeader.Filename)
+	if err != nil {
+		if result != nil {
+			c.JSON(http.StatusUnprocessableEntity, shared.Response{Message: err.Error(), Data: result})
+			return
+		}
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(result))
+}
+
+// FindUnmappedProducts handles a request to find the products sold without an ERP reference
+// @Tags SIESA
+// @Summary Find unmapped products
+// @Description Find the products and modifiers sold on the closed orders of the period without an ERP reference for their channel and order type
+// @Accept json
+// @Produce json
+// @Param start_date query string true "start date" example(2006-01-02)
+// @Param end_date query string true "end date, included" example(2006-01-02)
+// @Param store_id query string false "store id"
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=[]UnmappedProduct}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Router /siesa/reference/unmapped [get]
+func (h *Handler) FindUnmappedProducts(c *gin.Context) {
+	unmapped, err := h.service.FindUnmappedProducts(c.Query("store_id"), c.Query("start_date"), c.Query("end_date"))
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(unmapped))
 }
 
 func (h *Handler) initSIESA(ctx *gin.Context, docType string) (*RequestExcelCreate, *SiesaDocument, time.Time, []PopappOrder, *shared.GinError) {
@@ -1,6 +1,11 @@
 package siesa
 
 import (
+	"strings"
+	"time"
+
+	"github.com/BacoFoods/menu/pkg/invoice"
+	"github.com/BacoFoods/menu/pkg/order"
 	"github.com/BacoFoods/menu/pkg/shared"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -26,12 +31,14 @@ func (r *DBRepository) GetDocuments(limit int) ([]SiesaDocument, error) {
 	return documents, nil
 }
 
-func (r *DBRepository) Create(reference *Reference) error {
-	if err := r.db.Save(reference).Error; err != nil {
-		shared.LogError("error creating reference", LogDBRepository, "Create", err, reference)
-		return err
+func (r *DBRepository) GetDocument(documentID uint) (*SiesaDocument, error) {
+	var document SiesaDocument
+	if err := r.db.First(&document, documentID).Error; err != nil {
+		shared.LogError("error getting document", LogDBRepository, "GetDocument", err, documentID)
+		return nil, err
 	}
-	return nil
+
+	return &document, nil
 }
 
 func (r *DBRepository) UpdateDocument(doc *SiesaDocument) error {
@@ -52,81 +59,294 @@ func (r *DBRepository) CreateDocument(doc *SiesaDocument) error {
 	return nil
 }
 
-// TruncateRecords truncates all records
-// TruncateRecords truncates all records in the YourModel table
-func (r *DBRepository) TruncateRecords() error {
-	// Comienza una transacción
-	tx := r.db.Begin()
-	if tx.Error != nil {
-		return tx.Error
+// FindReferences method for find product references in database
+func (r *DBRepository) FindReferences(filters map[string]string) ([]ProductReference, error) {
+	var references []ProductReference
+	if err := r.db.Order("platform, id").Find(&references, filters).Error; err != nil {
+		shared.LogError("error finding references", LogDBRepository, "FindReferences", err, filters)
+		return nil, err
 	}
+	return references, nil
+}
 
-	// Trunca la tabla 'references' dentro de la transacción con condiciones
-	if err := tx.Unscoped().Where("true").Delete(&Reference{}).Error; err != nil {
-		// Revierte la transacción en caso de error
-		tx.Rollback()
+// CreateReference method for create a new product reference in database
+func (r *DBRepository) CreateReference(reference *ProductReference) (*ProductReference, error) {
+	if err := r.db.Create(reference).Error; err != nil {
+		shared.LogError("error creating reference", LogDBRepository, "CreateReference", err, reference)
+		return nil, err
+	}
+	return reference, nil
+}
+
+// DeleteReference method for delete a product reference in database
+func (r *DBRepository) DeleteReference(referenceID string) (*ProductReference, error) {
+	var reference ProductReference
+	if err := r.db.First(&reference, referenceID).Error; err != nil {
+		shared.LogError("error getting reference", LogDBRepository, "DeleteReference", err, referenceID)
+		return nil, err
+	}
+	if err := r.db.Delete(&reference).Error; err != nil {
+		shared.LogError("error deleting reference", LogDBRepository, "DeleteReference", err, reference)
+		return nil, err
+	}
+	return &reference, nil
+}
+
+// UpdateReference method for update a product reference in database
+func (r *DBRepository) UpdateReference(reference *ProductReference) (*ProductReference, error) {
+	var referenceDB ProductReference
+	if err := r.db.First(&referenceDB, reference.ID).Error; err != nil {
+		shared.LogError("error getting reference", LogDBRepository, "UpdateReference", err, reference)
+		return nil, err
+	}
+	if err := r.db.Model(&referenceDB).
+		Select("platform", "channel_id", "order_type", "product_id", "sku", "name", "category", "erp_reference").
+		Updates(reference).Error; err != nil {
+		shared.LogError("error updating reference", LogDBRepository, "UpdateReference", err, reference)
+		return nil, err
+	}
+	return &referenceDB, nil
+}
+
+// ReplaceReferences method for replace the product references of the platforms in database
+func (r *DBRepository) ReplaceReferences(platforms []string, references []ProductReference) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("platform IN ?", platforms).Delete(&ProductReference{}).Error; err != nil {
+			shared.LogError("error deleting references", LogDBRepository, "ReplaceReferences", err, platforms)
+			return err
+		}
+
+		if len(references) == 0 {
+			return nil
+		}
+
+		if err := tx.CreateInBatches(references, 500).Error; err != nil {
+			shared.LogError("error creating references", LogDBRepository, "ReplaceReferences", err, platforms)
+			return err
+		}
+
+		return nil
+	})
+}
+
+// legacyReference is a row of the references table, with one column per platform
+type legacyReference struct {
+	Category                 string
+	Popapp                   string
+	ReferenciaPdv            string
+	ReferenciaDeliveryInline string
+	RappiPickUp              string
+	RappiBacu                string
+	DidiBacu                 string
+	BacuMarketplace          string
+}
+
+// legacyEquivalence is a row of the equivalences table of the connector
+type legacyEquivalence struct {
+	ChannelID string
+	ProductID string
+	SiesaID   string
+}
+
+// MigrateLegacyReferences copies the references and connector equivalences tables to the product
+// references, it only runs while there are no product references.
+func (r *DBRepository) MigrateLegacyReferences() error {
+	var count int64
+	if err := r.db.Model(&ProductReference{}).Count(&count).Error; err != nil {
+		shared.LogError("error counting references", LogDBRepository, "MigrateLegacyReferences", err)
 		return err
 	}
+	if count > 0 {
+		return nil
+	}
+
+	references := make([]ProductReference, 0)
+	add := func(platform, orderType, name, category, erpReference string) {
+		if strings.TrimSpace(name) == "" || strings.TrimSpace(erpReference) == "" {
+			return
+		}
+		references = append(references, ProductReference{
+			Platform:     platform,
+			OrderType:    orderType,
+			Name:         strings.TrimSpace(name),
+			Category:     category,
+			ERPReference: strings.TrimSpace(erpReference),
+		})
+	}
 
-	// Confirma la transacción si la eliminación fue exitosa
-	if err := tx.Commit().Error; err != nil {
+	if r.db.Migrator().HasTable("references") {
+		var rows []legacyReference
+		if err := r.db.Table("references").Where("deleted_at IS NULL").Find(&rows).Error; err != nil {
+			shared.LogError("error finding legacy references", LogDBRepository, "MigrateLegacyReferences", err)
+			return err
+		}
+
+		for _, row := range rows {
+			add(PlatformPopapp, "PICK_UP", row.Popapp, row.Category, row.ReferenciaDeliveryInline)
+			add(PlatformPopapp, "DINE_IN", row.Popapp, row.Category, row.ReferenciaPdv)
+			add(PlatformPopapp, "DELIVERY_BY_RESTAURANT", row.Popapp, row.Category, row.ReferenciaPdv)
+			add(PlatformRappi, "PICK_UP", row.RappiPickUp, row.Category, row.ReferenciaDeliveryInline)
+			add(PlatformRappi, "DELIVERY_BY_PLATAFORMA", row.RappiBacu, row.Category, row.ReferenciaDeliveryInline)
+			add(PlatformRappi, "DELIVERY_BY_RESTAURANT", row.RappiBacu, row.Category, row.ReferenciaDeliveryInline)
+			add(PlatformDidi, "", row.DidiBacu, row.Category, row.ReferenciaDeliveryInline)
+			add(PlatformBacuMarketplace, "", row.BacuMarketplace, row.Category, row.ReferenciaDeliveryInline)
+			add(PlatformOrderInTable, "", row.BacuMarketplace, row.Category, row.ReferenciaDeliveryInline)
+		}
+	}
+
+	if r.db.Migrator().HasTable("equivalences") {
+		var rows []legacyEquivalence
+		if err := r.db.Table("equivalences").Where("deleted_at IS NULL").Find(&rows).Error; err != nil {
+			shared.LogError("error finding legacy equivalences", LogDBRepository, "MigrateLegacyReferences", err)
+			return err
+		}
+
+		for _, row := range rows {
+			channelID, errChannel := parseOptionalID(row.ChannelID)
+			productID, errProduct := parseOptionalID(row.ProductID)
+			if errChannel != nil || errProduct != nil || productID == nil || row.SiesaID == "" {
+				shared.LogWarn("skipping legacy equivalence", LogDBRepository, "MigrateLegacyReferences", nil, row)
+				continue
+			}
+			references = append(references, ProductReference{
+				Platform:     PlatformPOS,
+				ChannelID:    channelID,
+				ProductID:    productID,
+				ERPReference: row.SiesaID,
+			})
+		}
+	}
+
+	// the legacy tables can map the same product twice, the first mapping is kept as the lookups did
+	unique := make([]ProductReference, 0)
+	seen := make(map[string]bool)
+	for _, reference := range references {
+		key := reference.keys()[0]
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, reference)
+	}
+
+	if len(unique) == 0 {
+		return nil
+	}
+
+	if err := r.db.CreateInBatches(unique, 500).Error; err != nil {
+		shared.LogError("error migrating legacy references", LogDBRepository, "MigrateLegacyReferences", err)
 		return err
 	}
 
 	return nil
 }
 
-func (r *DBRepository) Find(filters map[string]string) (*Reference, error) {
-	var reference Reference
-	if err := r.db.Unscoped().Preload(clause.Associations).Find(&reference, filters).Limit(1).Error; err != nil {
-		shared.LogError("error getting reference row", LogDBRepository, "Find", err, filters)
+// FindClosedOrders method for find the closed orders of a store with their items and modifiers in database
+func (r *DBRepository) FindClosedOrders(storeID string, from, to time.Time) ([]order.Order, error) {
+	var orders []order.Order
+	query := r.db.Preload("Items.Modifiers").Preload("Items.Components").
+		Where("current_status = ?", order.OrderStatusClosed).
+		Where("closed_at >= ? AND closed_at < ?", from, to)
+	if storeID != "" {
+		query = query.Where("store_id = ?", storeID)
+	}
+
+	if err := query.Find(&orders).Error; err != nil {
+		shared.LogError("error finding closed orders", LogDBRepository, "FindClosedOrders", err, storeID, from, to)
 		return nil, err
 	}
-	return &reference, nil
+
+	return orders, nil
 }
 
-func (r *DBRepository) FindReferences(filters map[string]string) ([]Reference, error) {
-	var reference []Reference
-	if err := r.db.Find(&reference, filters).Error; err != nil {
-		shared.LogError("error finding reference", LogDBRepository, "FindReferences", err, filters)
+// FindPaidInvoices method for find the latest invoice of every order of a store paid on the period in database
+func (r *DBRepository) FindPaidInvoices(storeID uint, from, to time.Time) ([]invoice.Invoice, error) {
+	var invoices []invoice.Invoice
+	if err := r.db.Preload("Items").
+		Select("DISTINCT ON (order_id) *").
+		Where("store_id = ? AND status = ?", storeID, "paid"). // TODO: use const
+		Where("created_at >= ? AND created_at < ?", from, to).
+		Order("order_id, created_at DESC").
+		Find(&invoices).Error; err != nil {
+		shared.LogError("error finding paid invoices", LogDBRepository, "FindPaidInvoices", err, storeID, from, to)
 		return nil, err
 	}
-	return reference, nil
+
+	return invoices, nil
 }
 
-// Create method for create a new category in database
-func (r *DBRepository) CreateReference(reference *Reference) (*Reference, error) {
-	if err := r.db.Preload(clause.Associations).Save(reference).Error; err != nil {
-		shared.LogError("error creating category", LogDBRepository, "CreateReference", err, reference)
-		return nil, err
+// CreateRun method for create the run of a store and day in database, an existing run of the day is kept
+func (r *DBRepository) CreateRun(run *SiesaRun) error {
+	if err := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(run).Error; err != nil {
+		shared.LogError("error creating run", LogDBRepository, "CreateRun", err, run)
+		return err
 	}
-	return reference, nil
+	return nil
 }
 
-// Delete method for delete a reference in database
-func (r *DBRepository) DeleteReference(referenceID string) (*Reference, error) {
-	var reference Reference
-	if err := r.db.First(&reference, referenceID).Error; err != nil {
-		shared.LogError("error getting category", LogDBRepository, "Delete", err, referenceID)
+// GetRun method for get a run with its attempts in database
+func (r *DBRepository) GetRun(runID string) (*SiesaRun, error) {
+	var run SiesaRun
+	if err := r.db.Preload("History", func(db *gorm.DB) *gorm.DB {
+		return db.Order("attempt")
+	}).First(&run, runID).Error; err != nil {
+		shared.LogError("error getting run", LogDBRepository, "GetRun", err, runID)
 		return nil, err
 	}
-	if err := r.db.Delete(&reference).Error; err != nil {
-		shared.LogError("error deleting category", LogDBRepository, "Delete", err, reference)
+	return &run, nil
+}
+
+// FindRuns method for find the runs by filter in database, the latest days first
+func (r *DBRepository) FindRuns(filter map[string]string, limit int) ([]SiesaRun, error) {
+	var runs []SiesaRun
+	if err := r.db.Where(filter).Order("date DESC, store_id").Limit(limit).Find(&runs).Error; err != nil {
+		shared.LogError("error finding runs", LogDBRepository, "FindRuns", err, filter)
 		return nil, err
 	}
-	return &reference, nil
+	return runs, nil
 }
 
-// Update method for update a reference in database
-func (r *DBRepository) UpdateReference(reference *Reference) (*Reference, error) {
-	var referenceDB Reference
-	if err := r.db.Preload(clause.Associations).First(&referenceDB, reference.ID).Error; err != nil {
-		shared.LogError("error getting category", LogDBRepository, "UpdateReference", err, reference)
+// FindDueRuns method for find the pending runs and the failed runs whose next attempt is due in database
+func (r *DBRepository) FindDueRuns(now time.Time, maxAttempts int) ([]SiesaRun, error) {
+	var runs []SiesaRun
+	if err := r.db.
+		Where("status = ?", RunStatusPending).
+		Or("status = ? AND attempts < ? AND (next_attempt_at IS NULL OR next_attempt_at <= ?)", RunStatusFailed, maxAttempts, now).
+		Order("date, store_id").
+		Find(&runs).Error; err != nil {
+		shared.LogError("error finding due runs", LogDBRepository, "FindDueRuns", err, now)
 		return nil, err
 	}
-	if err := r.db.Model(&referenceDB).Updates(reference).Error; err != nil {
-		shared.LogError("error updating category", LogDBRepository, "UpdateReference", err, reference)
-		return nil, err
+	return runs, nil
+}
+
+// ClaimRun method for set a run as running only if it still has one of the statuses in database, so two
+// replicas can't run it at the same time
+func (r *DBRepository) ClaimRun(runID uint, statuses ...string) (bool, error) {
+	tx := r.db.Model(&SiesaRun{}).
+		Where("id = ? AND status IN ?", runID, statuses).
+		Updates(map[string]any{"status": RunStatusRunning, "updated_at": time.Now()})
+	if tx.Error != nil {
+		shared.LogError("error claiming run", LogDBRepository, "ClaimRun", tx.Error, runID)
+		return false, tx.Error
 	}
-	return &referenceDB, nil
+	return tx.RowsAffected == 1, nil
+}
+
+// UpdateRun method for update a run in database
+func (r *DBRepository) UpdateRun(run *SiesaRun) error {
+	if err := r.db.Omit("History").Save(run).Error; err != nil {
+		shared.LogError("error updating run", LogDBRepository, "UpdateRun", err, run)
+		return err
+	}
+	return nil
+}
+
+// CreateRunAttempt method for record an attempt of a run in database
+func (r *DBRepository) CreateRunAttempt(attempt *SiesaRunAttempt) error {
+	if err := r.db.Create(attempt).Error; err != nil {
+		shared.LogError("error creating run attempt", LogDBRepository, "CreateRunAttempt", err, attempt.RunID)
+		return err
+	}
+	return nil
 }
@@ -21,9 +21,17 @@ func (r Routes) RegisterRoutes(private *shared.CustomRoutes) {
 	// Get the run history
 	private.GET("/siesa/history", r.handler.GetRunHistory)
 
+	// Scheduled runs
+	private.GET("/siesa/runs", r.handler.FindRuns)
+	private.POST("/siesa/runs", r.handler.ScheduleRun)
+	private.GET("/siesa/runs/:id", r.handler.GetRun)
+	private.POST("/siesa/runs/:id/rerun", r.handler.Rerun)
+
 	private.GET("/siesa/locales", r.handler.GetLocales)
 	private.GET("/siesa/reference", r.handler.FindReferences)
 	private.POST("/siesa/reference", r.handler.CreateReference)
+	private.POST("/siesa/reference/import", r.handler.ImportReferences)
+	private.GET("/siesa/reference/unmapped", r.handler.FindUnmappedProducts)
 	private.DELETE("/siesa/reference/:id", r.handler.DeleteReference)
 	private.PATCH("/siesa/reference/:id", r.handler.UpdateReference)
 
@@ -0,0 +1,388 @@
+package siesa
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BacoFoods/menu/internal"
+	"github.com/BacoFoods/menu/pkg/invoice"
+	"github.com/BacoFoods/menu/pkg/shared"
+	"github.com/BacoFoods/menu/pkg/store"
+)
+
+const (
+	LogScheduler = "pkg/siesa/scheduler"
+
+	schedulerLockKey = "menu:siesa:scheduler"
+	runDocumentType  = "scheduled"
+)
+
+// RunScheduler schedules the previous day of every store with Popapp locations or native invoices and posts the due runs on
+// every interval, until the context is done. The replicas take turns through a redis lock and every run is
+// claimed in database before it's posted, so a run is never posted twice at the same time.
+func (s Service) RunScheduler(ctx context.Context) {
+	if !s.config.ScheduleEnabled {
+		return
+	}
+
+	ticker := time.NewTicker(s.config.ScheduleInterval)
+	defer ticker.Stop()
+
+	for {
+		s.tick(time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s Service) tick(now time.Time) {
+	mu := internal.DistMutexWithTTL(s.redis, schedulerLockKey, s.config.ScheduleInterval)
+	locked, err := mu.TryLock()
+	if err != nil {
+		shared.LogError("error locking scheduler", LogScheduler, "tick", err)
+		return
+	}
+	if !locked {
+		return // another replica is running the scheduler
+	}
+	defer mu.Unlock()
+
+	s.scheduleRuns(now)
+
+	runs, err := s.repository.FindDueRuns(now, s.config.MaxAttempts)
+	if err != nil {
+		return
+	}
+
+	for _, run := range runs {
+		claimed, err := s.repository.ClaimRun(run.ID, RunStatusPending, RunStatusFailed)
+		if err != nil || !claimed {
+			continue
+		}
+		s.executeRun(run)
+	}
+}
+
+// scheduleRuns creates the run of the previous day of the stores whose local time is past the schedule hour.
+// The stores with Popapp locations post their Popapp orders and the native stores post their invoices.
+func (s Service) scheduleRuns(now time.Time) {
+	locations, err := s.storeLocations(map[string]string{"provider": store.ProviderPopapp})
+	if err != nil {
+		return
+	}
+
+	for _, storeLocations := range locations {
+		if storeLocations.store.ERPSource == store.ERPSourceNative {
+			continue
+		}
+		s.scheduleRun(now, storeLocations.store, RunSourcePopapp, strings.Join(storeLocations.keys, ","))
+	}
+
+	nativeStores, err := s.stores.Find(map[string]string{"erp_source": store.ERPSourceNative})
+	if err != nil {
+		return
+	}
+
+	for i := range nativeStores {
+		s.scheduleRun(now, &nativeStores[i], RunSourceNative, "")
+	}
+}
+
+func (s Service) scheduleRun(now time.Time, runStore *store.Store, source, locations string) {
+	local := now.In(runStore.Location())
+	if local.Hour() < s.config.ScheduleHour {
+		return
+	}
+
+	yesterday := local.AddDate(0, 0, -1)
+	_ = s.repository.CreateRun(&SiesaRun{
+		StoreID:   runStore.ID,
+		Date:      time.Date(yesterday.Year(), yesterday.Month(), yesterday.Day(), 0, 0, 0, 0, time.UTC),
+		Source:    source,
+		Locations: locations,
+		Status:    RunStatusPending,
+	})
+}
+
+type storeLocations struct {
+	store *store.Store
+	keys  []string
+}
+
+// storeLocations returns the Popapp local keys of the stores in the external locations registry
+func (s Service) storeLocations(filter map[string]string) ([]storeLocations, error) {
+	filter["provider"] = store.ProviderPopapp
+	externalLocations, err := s.stores.FindExternalLocations(filter)
+	if err != nil {
+		shared.LogError("error finding popapp locations", LogScheduler, "storeLocations", err, filter)
+		return nil, err
+	}
+
+	byStore := make(map[uint]*storeLocations)
+	for _, location := range externalLocations {
+		if location.Store == nil {
+			continue
+		}
+		if _, ok := byStore[location.Store.ID]; !ok {
+			byStore[location.Store.ID] = &storeLocations{store: location.Store}
+		}
+		byStore[location.Store.ID].keys = append(byStore[location.Store.ID].keys, location.ExternalID)
+	}
+
+	locations := make([]storeLocations, 0)
+	for _, storeLocation := range byStore {
+		sort.Strings(storeLocation.keys)
+		locations = append(locations, *storeLocation)
+	}
+
+	return locations, nil
+}
+
+// executeRun posts a claimed run, the attempt is recorded with the payload and the SIESA response. A failed
+// run is retried after the backoff until it reaches the max attempts.
+func (s Service) executeRun(run SiesaRun) {
+	now := time.Now()
+	run.Status = RunStatusRunning
+	run.Attempts++
+
+	// the locations are refreshed so a re-run uses the current registry
+	if run.Source != RunSourceNative {
+		if locations, err := s.storeLocations(map[string]string{"store_id": fmt.Sprint(run.StoreID)}); err == nil && len(locations) > 0 {
+			run.Locations = strings.Join(locations[0].keys, ",")
+		}
+	}
+
+	attempt := SiesaRunAttempt{RunID: run.ID, Attempt: run.Attempts}
+	source, sales, err := s.runSource(run)
+	if err != nil {
+		attempt.Error = err.Error()
+		s.finishRun(&run, &attempt, nil, now)
+		return
+	}
+
+	run.Orders = sales
+	if sales == 0 {
+		run.Status = RunStatusEmpty
+		run.Error = ""
+		run.NextAttemptAt = nil
+		_ = s.repository.UpdateRun(&run)
+		return
+	}
+
+	document, err := s.runDocument(&run)
+	if err != nil {
+		attempt.Error = err.Error()
+		s.finishRun(&run, &attempt, nil, now)
+		return
+	}
+
+	payload, err := BuildDocument(fmt.Sprint(document.ID), run.Date, source)
+	if err != nil {
+		attempt.Error = err.Error()
+		s.finishRun(&run, &attempt, document, now)
+		return
+	}
+
+	request, response, statusCode, err := s.postDocument(payload)
+	attempt.Request = request
+	attempt.Response = response
+	attempt.StatusCode = statusCode
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+
+	s.finishRun(&run, &attempt, document, now)
+}
+
+func (s Service) finishRun(run *SiesaRun, attempt *SiesaRunAttempt, document *SiesaDocument, now time.Time) {
+	_ = s.repository.CreateRunAttempt(attempt)
+
+	if attempt.Error != "" {
+		shared.LogWarn("siesa run failed", LogScheduler, "finishRun", nil, run.ID, run.Attempts, attempt.Error)
+		next := now.Add(RetryBackoff(s.config.RetryBackoff, run.Attempts))
+		run.Status = RunStatusFailed
+		run.Error = attempt.Error
+		run.NextAttemptAt = &next
+	} else {
+		run.Status = RunStatusSuccess
+		run.Error = ""
+		run.NextAttemptAt = nil
+		run.SucceededAt = &now
+	}
+
+	_ = s.repository.UpdateRun(run)
+
+	if document != nil {
+		status := "success"
+		if run.Status == RunStatusFailed {
+			status = "error"
+		}
+		_ = s.UpdateDocumentStatus(document, status, attempt.Response)
+	}
+}
+
+// runDocument returns the document of the run, it's created on the first attempt so the retries post the
+// same document number
+func (s Service) runDocument(run *SiesaRun) (*SiesaDocument, error) {
+	if run.DocumentID != nil {
+		return s.repository.GetDocument(*run.DocumentID)
+	}
+
+	stores := []string{fmt.Sprint(run.StoreID)}
+	if run.Source != RunSourceNative {
+		stores = strings.Split(run.Locations, ",")
+	}
+
+	date := run.Date.Format("2006-01-02")
+	document, err := s.GetDocument(stores, date, date, run.Orders, runDocumentType)
+	if err != nil {
+		return nil, err
+	}
+
+	run.DocumentID = &document.ID
+	return document, nil
+}
+
+// runSource returns the input adapter of the run source and its number of sales
+func (s Service) runSource(run SiesaRun) (Source, int, error) {
+	if run.Source == RunSourceNative {
+		invoices, runStore, err := s.fetchInvoices(run)
+		if err != nil {
+			return nil, 0, err
+		}
+		source := InvoiceSource{Invoices: invoices, Stores: []store.Store{*runStore}, References: s.referenceIndex()}
+		return source, len(invoices), nil
+	}
+
+	orders, err := s.fetchOrders(run)
+	if err != nil {
+		return nil, 0, err
+	}
+	return s.popappSource(orders), len(orders), nil
+}
+
+// fetchInvoices gets the paid invoices of the run store on the run day, in the store time zone
+func (s Service) fetchInvoices(run SiesaRun) ([]invoice.Invoice, *store.Store, error) {
+	runStore, err := s.stores.Get(fmt.Sprint(run.StoreID))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	from := time.Date(run.Date.Year(), run.Date.Month(), run.Date.Day(), 0, 0, 0, 0, runStore.Location())
+	invoices, err := s.repository.FindPaidInvoices(run.StoreID, from, from.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return invoices, runStore, nil
+}
+
+// fetchOrders gets the paid Popapp orders of the run locations on the run day
+func (s Service) fetchOrders(run SiesaRun) ([]PopappOrder, error) {
+	if run.Locations == "" {
+		return nil, fmt.Errorf("store %d has no popapp locations", run.StoreID)
+	}
+
+	date := run.Date.Format("2006-01-02")
+	response, err := GetOrders(date, date, strings.Split(run.Locations, ","))
+	if err != nil {
+		return nil, err
+	}
+
+	var orders []PopappOrder
+	if err := json.Unmarshal([]byte(response), &orders); err != nil {
+		return nil, fmt.Errorf("error unmarshalling popapp orders: %v", err)
+	}
+
+	return orders, nil
+}
+
+// FindRuns returns the runs filtered by store, status and date, the latest days first
+func (s Service) FindRuns(filter map[string]string, limit int) ([]SiesaRun, error) {
+	runs, err := s.repository.FindRuns(filter, limit)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorFindingRuns)
+	}
+	return runs, nil
+}
+
+// GetRun returns a run with the payloads of its attempts
+func (s Service) GetRun(runID string) (*SiesaRun, error) {
+	run, err := s.repository.GetRun(runID)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorGettingRun)
+	}
+	return run, nil
+}
+
+// ScheduleRun creates the run of a store and day, the scheduler posts it on its next tick. The existing run
+// of the day is returned when there is one.
+func (s Service) ScheduleRun(storeID uint, date string) (*SiesaRun, error) {
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorBadRequest)
+	}
+
+	runStore, err := s.stores.Get(fmt.Sprint(storeID))
+	if err != nil {
+		return nil, fmt.Errorf(ErrorBadRequest)
+	}
+
+	run := SiesaRun{StoreID: storeID, Date: day, Source: RunSourcePopapp, Status: RunStatusPending}
+	if runStore.ERPSource == store.ERPSourceNative {
+		run.Source = RunSourceNative
+	} else if locations, err := s.storeLocations(map[string]string{"store_id": fmt.Sprint(storeID)}); err == nil && len(locations) > 0 {
+		run.Locations = strings.Join(locations[0].keys, ",")
+	}
+
+	if err := s.repository.CreateRun(&run); err != nil {
+		return nil, fmt.Errorf(ErrorRerunningRun)
+	}
+
+	runs, err := s.repository.FindRuns(map[string]string{"store_id": fmt.Sprint(storeID), "date": date}, 1)
+	if err != nil || len(runs) == 0 {
+		return nil, fmt.Errorf(ErrorFindingRuns)
+	}
+
+	return &runs[0], nil
+}
+
+// Rerun posts a failed or interrupted run again right away, the runs that succeeded are never posted again
+func (s Service) Rerun(runID string) (*SiesaRun, error) {
+	run, err := s.repository.GetRun(runID)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorGettingRun)
+	}
+
+	statuses := []string{RunStatusPending, RunStatusFailed, RunStatusEmpty}
+	switch {
+	case run.Status == RunStatusSuccess:
+		return nil, fmt.Errorf(ErrorRunAlreadySucceeded)
+	case run.IsStale(time.Now()):
+		statuses = []string{RunStatusRunning}
+	case run.Status == RunStatusRunning:
+		return nil, fmt.Errorf(ErrorRunInProgress)
+	}
+
+	claimed, err := s.repository.ClaimRun(run.ID, statuses...)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorRerunningRun)
+	}
+	if !claimed {
+		return nil, fmt.Errorf(ErrorRunInProgress)
+	}
+
+	run.History = nil
+	go s.executeRun(*run)
+
+	run.Status = RunStatusRunning
+	return run, nil
+}
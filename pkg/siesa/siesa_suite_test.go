@@ -0,0 +1,13 @@
+package siesa_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestSiesa(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Siesa Suite")
+}
@@ -0,0 +1,202 @@
+package siesa
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/BacoFoods/menu/pkg/invoice"
+	"github.com/BacoFoods/menu/pkg/shared"
+	"github.com/BacoFoods/menu/pkg/store"
+)
+
+// popappModifierTax is removed from the price of the Popapp modifiers to get their discount
+const popappModifierTax = 1.08
+
+// PopappSource is the input adapter of the Popapp orders, the stores of the orders are resolved through
+// their local keys and a document with a local not mapped is rejected
+type PopappSource struct {
+	Orders     []PopappOrder
+	Locations  Locations
+	References ReferenceIndex
+}
+
+// Document returns the lines of the items and modifiers of the orders, the order discount is shared between
+// the items by their price
+func (p PopappSource) Document() (DocumentHeader, []DocumentLine, error) {
+	if len(p.Orders) == 0 {
+		return DocumentHeader{}, nil, fmt.Errorf(ErrorDocumentEmpty)
+	}
+
+	for _, order := range p.Orders {
+		if !p.Locations.Mapped(order.KeyLocal) {
+			return DocumentHeader{}, nil, fmt.Errorf("%s - %s -", ErrorDocumentLocation, order.KeyLocal)
+		}
+	}
+
+	first := p.Orders[0]
+	header := DocumentHeader{
+		OpsCenter: p.Locations.OpsCenter(first.KeyLocal),
+		Warehouse: p.Locations.Warehouse(first.KeyLocal),
+		Notes:     "Orden " + first.DisplayID + " - el " + formatDate(first.FechaCreacion) + " - del pdv " + first.NombreStore,
+		DueDate:   formatDate(first.FechaCreacion),
+	}
+
+	lines := make([]DocumentLine, 0)
+	for _, order := range p.Orders {
+		opsCenter := p.Locations.OpsCenter(order.KeyLocal)
+		warehouse := p.Locations.Warehouse(order.KeyLocal)
+		share := popappDiscountShare(order)
+
+		for _, item := range order.Items {
+			price := float64(item.Producto.PrecioUnitario)
+			line := DocumentLine{
+				Name:       item.Producto.Nombre,
+				Quantity:   item.Cantidad,
+				GrossValue: grossValue(item.Cantidad, price),
+				OpsCenter:  opsCenter,
+				Warehouse:  warehouse,
+			}
+
+			if !isValidProduct(item.Producto.Nombre) {
+				line.Invalid = "producto invalido: " + item.Producto.Nombre
+				lines = append(lines, line)
+				continue
+			}
+
+			// items with no reference are still included
+			line.Reference = popappReference(p.References, order, item.Producto.Nombre)
+			line.UnitDiscount = share * price
+			line.TotalDiscount = line.UnitDiscount * float64(item.Cantidad)
+			lines = append(lines, line)
+
+			for _, itemGroup := range item.ItemGroups {
+				for _, modifier := range itemGroup.Modifiers {
+					modifierPrice := float64(modifier.Producto.PrecioUnitario)
+					modifierLine := DocumentLine{
+						Name:       modifier.Producto.Nombre,
+						Quantity:   modifier.Cantidad,
+						GrossValue: grossValue(item.Cantidad*modifier.Cantidad, modifierPrice),
+						OpsCenter:  opsCenter,
+						Warehouse:  warehouse,
+					}
+
+					if !isValidProduct(modifier.Producto.Nombre) {
+						modifierLine.Quantity = item.Cantidad * modifier.Cantidad
+						modifierLine.Invalid = "modificador invalido: " + modifier.Producto.Nombre
+						lines = append(lines, modifierLine)
+						continue
+					}
+
+					// ignore modifiers with no reference
+					modifierLine.Reference = popappReference(p.References, order, modifier.Producto.Nombre)
+					if modifierLine.Reference == "" {
+						modifierLine.Invalid = "modificador sin referencia: " + modifier.Producto.Nombre
+						lines = append(lines, modifierLine)
+						continue
+					}
+
+					modifierLine.UnitDiscount = share * (modifierPrice / popappModifierTax)
+					modifierLine.TotalDiscount = share * (float64(item.Cantidad) * (modifierPrice / popappModifierTax))
+					lines = append(lines, modifierLine)
+				}
+			}
+		}
+	}
+
+	return header, lines, nil
+}
+
+// popappDiscountShare returns the share of the items price discounted on the order
+func popappDiscountShare(order PopappOrder) float64 {
+	totalItems := float64(order.Total.TotalItems)
+
+	shippingCost := 0
+	if order.Total.CostoEnvio != nil {
+		shippingCost = *order.Total.CostoEnvio
+	}
+
+	discount := (totalItems + float64(shippingCost)) - float64(order.Total.Total)
+	if discount < 0 {
+		discount = 0
+	}
+
+	if discount > float64(order.Total.Total) {
+		discount = float64(order.Total.Total) - 1
+	}
+
+	return discount / math.Max(totalItems, 1)
+}
+
+// InvoiceSource is the input adapter of the invoices of this POS, the stores of a document must share their
+// ops center and warehouse
+type InvoiceSource struct {
+	Invoices   []invoice.Invoice
+	Stores     []store.Store
+	References ReferenceIndex
+}
+
+// Document returns a line for every invoice item, the items without a reference are sent to the invalid items
+func (i InvoiceSource) Document() (DocumentHeader, []DocumentLine, error) {
+	if len(i.Stores) == 0 {
+		return DocumentHeader{}, nil, fmt.Errorf(ErrorDocumentNoStores)
+	}
+
+	if len(i.Invoices) == 0 {
+		return DocumentHeader{}, nil, fmt.Errorf(ErrorDocumentEmpty)
+	}
+
+	first := i.Stores[0]
+	storeIDs := make([]uint, 0)
+	for _, s := range i.Stores {
+		if s.OpsCenter != first.OpsCenter {
+			return DocumentHeader{}, nil, fmt.Errorf("%s - %s - %s -", ErrorDocumentOpsCenter, s.OpsCenter, first.OpsCenter)
+		}
+		if s.Wharehouse != first.Wharehouse {
+			return DocumentHeader{}, nil, fmt.Errorf("%s - %s - %s -", ErrorDocumentWarehouse, s.Wharehouse, first.Wharehouse)
+		}
+		storeIDs = append(storeIDs, s.ID)
+	}
+
+	date := time.Now()
+	if created := i.Invoices[0].CreatedAt; created != nil {
+		date = *created
+	} else if updated := i.Invoices[0].UpdatedAt; updated != nil {
+		date = *updated
+	}
+
+	header := DocumentHeader{
+		OpsCenter: first.OpsCenter,
+		Warehouse: first.Wharehouse,
+		Notes:     fmt.Sprintf("%s  - del pdv %v", date.Format("20060102"), storeIDs),
+		DueDate:   date.Format("20060102"),
+	}
+
+	lines := make([]DocumentLine, 0)
+	for _, inv := range i.Invoices {
+		for _, item := range inv.Items {
+			line := DocumentLine{
+				Name:       item.Name,
+				Quantity:   1,
+				GrossValue: grossValue(1, item.Price),
+				OpsCenter:  first.OpsCenter,
+				Warehouse:  first.Wharehouse,
+			}
+
+			reference, ok := i.References.Resolve(PlatformPOS, inv.ChannelID, "", item.ProductID, item.SKU, item.Name)
+			if !ok {
+				shared.LogWarn("product without reference", LogService, "InvoiceSource.Document", nil, inv.ChannelID, item.ProductID, item.Name)
+				line.Invalid = "producto sin referencia: " + item.Name
+				lines = append(lines, line)
+				continue
+			}
+
+			line.Reference = reference
+			line.UnitDiscount = item.Price - item.DiscountedPrice
+			line.TotalDiscount = line.UnitDiscount
+			lines = append(lines, line)
+		}
+	}
+
+	return header, lines, nil
+}
@@ -150,3 +150,160 @@ func (r *DBRepository) GetZoneByStore(storeID, zoneID string) (*tables.Zone, err
 
 	return &zone, nil
 }
+
+// CreateExternalLocation method for create an external location mapping in database
+func (r *DBRepository) CreateExternalLocation(location *ExternalLocation) (*ExternalLocation, error) {
+	if err := r.db.Create(location).Error; err != nil {
+		shared.LogError("error creating external location", LogDBRepository, "CreateExternalLocation", err, location)
+		return nil, err
+	}
+	return location, nil
+}
+
+// FindExternalLocations method for find external location mappings with their stores in database
+func (r *DBRepository) FindExternalLocations(filter map[string]string) ([]ExternalLocation, error) {
+	var locations []ExternalLocation
+	if err := r.db.Preload("Store").Order("provider, external_id").Find(&locations, filter).Error; err != nil {
+		shared.LogError("error finding external locations", LogDBRepository, "FindExternalLocations", err, filter)
+		return nil, err
+	}
+	return locations, nil
+}
+
+// UpdateExternalLocation method for update an external location mapping in database
+func (r *DBRepository) UpdateExternalLocation(location *ExternalLocation) (*ExternalLocation, error) {
+	if err := r.db.Model(&ExternalLocation{ID: location.ID}).
+		Select("store_id", "provider", "external_id", "name").
+		Updates(location).Error; err != nil {
+		shared.LogError("error updating external location", LogDBRepository, "UpdateExternalLocation", err, location)
+		return nil, err
+	}
+	return location, nil
+}
+
+// DeleteExternalLocation method for delete an external location mapping in database
+func (r *DBRepository) DeleteExternalLocation(externalLocationID string) (*ExternalLocation, error) {
+	var location ExternalLocation
+	if err := r.db.First(&location, externalLocationID).Error; err != nil {
+		shared.LogError("error getting external location", LogDBRepository, "DeleteExternalLocation", err, externalLocationID)
+		return nil, err
+	}
+
+	// Hard deleted, a soft deleted row would keep the provider and external id taken in the unique index
+	if err := r.db.Unscoped().Delete(&location).Error; err != nil {
+		shared.LogError("error deleting external location", LogDBRepository, "DeleteExternalLocation", err, externalLocationID)
+		return nil, err
+	}
+	return &location, nil
+}
+
+// GetByExternalLocation method for get the store mapped to the external location in database
+func (r *DBRepository) GetByExternalLocation(provider, externalID string) (*Store, error) {
+	var location ExternalLocation
+	if err := r.db.Preload("Store").
+		Where("provider = ? AND external_id = ?", provider, externalID).
+		First(&location).Error; err != nil {
+		shared.LogWarn("error getting external location", LogDBRepository, "GetByExternalLocation", err, provider, externalID)
+		return nil, fmt.Errorf(ErrorExternalLocationNotFound)
+	}
+
+	if location.Store == nil {
+		return nil, fmt.Errorf(ErrorExternalLocationNotFound)
+	}
+	return location.Store, nil
+}
+
+// legacyPopappLocation is the ops center, warehouse and arqueo display name of a Popapp local key before the
+// external locations registry, the keys without a name fall back to the store name
+type legacyPopappLocation struct {
+	OpsCenter string
+	Warehouse string
+	Name      string
+}
+
+// legacyPopappLocations are the Popapp local keys the SIESA integration had hardcoded
+var legacyPopappLocations = map[string]legacyPopappLocation{
+	"bacucityu":           {"402", "402", "CityU - PC3"},
+	"cityusalon1":         {"402", "402", "CityU - PC1"},
+	"cityusalon2":         {"402", "402", "CityU - PC2"},
+	"bacuconnecta":        {"401", "401", "Connecta - PC2"},
+	"connectasalon110665": {"401", "401", "Connecta - PC1"},
+	"connectasalon210666": {"401", "401", "Connecta - PC3"},
+	"bacuzonag":           {"300", "300", "ZonaG - PC2"},
+	"bacuzonagc14":        {"300", "300", "ZonaG - PC1"},
+	"bacuflormorado":      {"400", "400", "Flormorado - PC2"},
+	"bacuflormoradopc2":   {"400", "400", "Flormorado - PC1"},
+	"flormorado10885":     {"400", "400", "Flormorado - PC3"},
+	"feriadelmillon2":     {"301", "301", "CL90 - PC1"},
+	"bacucalle90delivery": {"301", "301", "CL90 - PC2"},
+	"bacucalle109":        {"302", "32A", "CL109 - PC1"},
+	"bacu109":             {"302", "32A", "CL109 - PC2"},
+	"bacudk140":           {"202", "202", ""},
+	"bacuferia":           {"301", "31E", ""},
+	"bacucolinapc110881":  {"405", "405", "Colina"},
+	"bacutitansalon10883": {"403", "403", "Titan"},
+	"bacunogalespc110884": {"303", "303", "Nogal"},
+}
+
+// MigrateLegacyLocations seeds the Popapp external locations with the local keys the SIESA integration had
+// hardcoded, every key is mapped to the store with its ops center and warehouse. It runs only while there
+// are no Popapp locations, the keys without a matching store are left to be mapped by hand.
+func (r *DBRepository) MigrateLegacyLocations() error {
+	var count int64
+	if err := r.db.Model(&ExternalLocation{}).Where("provider = ?", ProviderPopapp).Count(&count).Error; err != nil {
+		shared.LogError("error counting external locations", LogDBRepository, "MigrateLegacyLocations", err)
+		return err
+	}
+	if count > 0 {
+		return r.nameLegacyLocations()
+	}
+
+	locations := make([]ExternalLocation, 0)
+	for keyLocal, legacy := range legacyPopappLocations {
+		var stores []Store
+		if err := r.db.Where("ops_center = ? AND wharehouse = ?", legacy.OpsCenter, legacy.Warehouse).
+			Limit(2).
+			Find(&stores).Error; err != nil {
+			shared.LogError("error finding legacy location store", LogDBRepository, "MigrateLegacyLocations", err, keyLocal)
+			return err
+		}
+
+		if len(stores) != 1 {
+			shared.LogWarn("legacy popapp local without a single store", LogDBRepository, "MigrateLegacyLocations", nil, keyLocal, legacy, len(stores))
+			continue
+		}
+
+		storeID := stores[0].ID
+		locations = append(locations, ExternalLocation{
+			StoreID:    &storeID,
+			Provider:   ProviderPopapp,
+			ExternalID: keyLocal,
+			Name:       legacy.Name,
+		})
+	}
+
+	if len(locations) == 0 {
+		return nil
+	}
+
+	if err := r.db.Create(&locations).Error; err != nil {
+		shared.LogError("error creating legacy external locations", LogDBRepository, "MigrateLegacyLocations", err)
+		return err
+	}
+
+	return nil
+}
+
+// nameLegacyLocations sets the arqueo display name of the legacy Popapp locations seeded with the local key as name
+func (r *DBRepository) nameLegacyLocations() error {
+	for keyLocal, legacy := range legacyPopappLocations {
+		if err := r.db.Model(&ExternalLocation{}).
+			Where("provider = ? AND external_id = ? AND name = external_id", ProviderPopapp, keyLocal).
+			Update("name", legacy.Name).Error; err != nil {
+			shared.LogError("error naming legacy external location", LogDBRepository, "MigrateLegacyLocations", err, keyLocal)
+			return err
+		}
+	}
+
+	return nil
+}
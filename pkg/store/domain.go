@@ -23,6 +23,24 @@ const (
 	ErrorStoreGettingChannels       = "error getting channels by brand id"
 	ErrorStoreCreationBrandIDNil    = "error creating store, brand id is nil"
 	ErrorStoreIDEmpty               = "store id is empty"
+
+	ErrorExternalLocationCreation        = "error creating external location"
+	ErrorExternalLocationUpdate          = "error updating external location"
+	ErrorExternalLocationDelete          = "error deleting external location"
+	ErrorExternalLocationFind            = "error finding external locations"
+	ErrorExternalLocationNotFound        = "error external location not mapped to a store"
+	ErrorExternalLocationInvalidProvider = "error invalid external location provider"
+
+	ProviderPopapp = "popapp"
+	ProviderRappi  = "rappi"
+	ProviderDidi   = "didi"
+
+	// ERPSourcePopapp stores post their Popapp orders to the ERP, ERPSourceNative stores post the invoices of this POS
+	ERPSourcePopapp = "popapp"
+	ERPSourceNative = "native"
+
+	// DefaultTimezone is used when neither the store nor its country have a time zone
+	DefaultTimezone = "America/Bogota"
 )
 
 type Store struct {
@@ -43,11 +61,33 @@ type Store struct {
 	Latitude   float64           `json:"latitude"`
 	Longitude  float64           `json:"longitude"`
 	Address    string            `json:"address"`
+	Timezone   string            `json:"timezone" example:"America/Bogota"`
+	ERPSource  string            `json:"erp_source" enums:"popapp,native" gorm:"default:popapp"`
 	CreatedAt  *time.Time        `json:"created_at,omitempty" swaggerignore:"true"`
 	UpdatedAt  *time.Time        `json:"updated_at,omitempty" swaggerignore:"true"`
 	DeletedAt  *gorm.DeletedAt   `json:"deleted_at,omitempty" swaggerignore:"true"`
 }
 
+// Location returns the IANA time zone of the store, falling back to the time zone of its country
+// and then to DefaultTimezone when none is set or valid.
+func (s *Store) Location() *time.Location {
+	timezones := []string{s.Timezone}
+	if s.Country != nil {
+		timezones = append(timezones, s.Country.Timezone)
+	}
+
+	for _, timezone := range append(timezones, DefaultTimezone) {
+		if timezone == "" {
+			continue
+		}
+		if location, err := time.LoadLocation(timezone); err == nil {
+			return location
+		}
+	}
+
+	return time.UTC
+}
+
 type Repository interface {
 	Create(*Store) (*Store, error)
 	Find(map[string]string) ([]Store, error)
@@ -60,4 +100,32 @@ type Repository interface {
 
 	FindZonesByStore(storeID string) ([]tables.Zone, error)
 	GetZoneByStore(storeID, zoneID string) (*tables.Zone, error)
+
+	CreateExternalLocation(*ExternalLocation) (*ExternalLocation, error)
+	FindExternalLocations(filter map[string]string) ([]ExternalLocation, error)
+	UpdateExternalLocation(*ExternalLocation) (*ExternalLocation, error)
+	DeleteExternalLocation(externalLocationID string) (*ExternalLocation, error)
+	GetByExternalLocation(provider, externalID string) (*Store, error)
+}
+
+// ExternalLocation maps the location of an external provider to a store, as the Popapp local keys of each
+// point of sale or the Rappi and Didi store ids, so the integrations resolve the store from the registry.
+type ExternalLocation struct {
+	ID         uint            `json:"id"`
+	StoreID    *uint           `json:"store_id"`
+	Store      *Store          `json:"store,omitempty" gorm:"foreignKey:StoreID" swaggerignore:"true"`
+	Provider   string          `json:"provider" binding:"required" gorm:"uniqueIndex:idx_external_location" enums:"popapp,rappi,didi"`
+	ExternalID string          `json:"external_id" binding:"required" gorm:"uniqueIndex:idx_external_location" example:"bacuzonag"`
+	Name       string          `json:"name" example:"ZonaG - PC1"`
+	CreatedAt  *time.Time      `json:"created_at,omitempty" swaggerignore:"true"`
+	UpdatedAt  *time.Time      `json:"updated_at,omitempty" swaggerignore:"true"`
+	DeletedAt  *gorm.DeletedAt `json:"deleted_at,omitempty" swaggerignore:"true"`
+}
+
+func IsValidProvider(provider string) bool {
+	switch provider {
+	case ProviderPopapp, ProviderRappi, ProviderDidi:
+		return true
+	}
+	return false
 }
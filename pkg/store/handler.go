@@ -4,6 +4,7 @@ import (
 	"github.com/BacoFoods/menu/pkg/shared"
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"strconv"
 )
 
 const LogHandler = "pkg/store/handler"
@@ -291,3 +292,127 @@ func (h Handler) Enable(c *gin.Context) {
 
 	c.JSON(http.StatusOK, shared.SuccessResponse(store))
 }
+
+// CreateExternalLocation to handle a request to map an external location to a store
+// @Tags Store
+// @Summary To map an external location to a store
+// @Description To map a Popapp local key, Rappi store id or Didi store id to a store, the integrations resolve the store operation center and warehouse with it
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "store id"
+// @Param location body ExternalLocation true "external location"
+// @Success 200 {object} object{status=string,data=ExternalLocation}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /store/{id}/external-location [post]
+func (h Handler) CreateExternalLocation(c *gin.Context) {
+	var request ExternalLocation
+	if err := c.ShouldBindJSON(&request); err != nil {
+		shared.LogWarn("warning binding request", LogHandler, "CreateExternalLocation", err, request)
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorStoreBadRequest))
+		return
+	}
+
+	location, err := h.service.CreateExternalLocation(c.Param("id"), &request)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(location))
+}
+
+// FindExternalLocations to handle a request to find the external locations
+// @Tags Store
+// @Summary To find the external locations
+// @Description To find the external locations mapped to stores
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param provider query string false "provider" Enums(popapp, rappi, didi)
+// @Param store_id query string false "store id"
+// @Success 200 {object} object{status=string,data=[]ExternalLocation}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /store/external-location [get]
+func (h Handler) FindExternalLocations(c *gin.Context) {
+	filter := make(map[string]string)
+	if provider := c.Query("provider"); provider != "" {
+		filter["provider"] = provider
+	}
+	if storeID := c.Query("store_id"); storeID != "" {
+		filter["store_id"] = storeID
+	}
+
+	locations, err := h.service.FindExternalLocations(filter)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(locations))
+}
+
+// UpdateExternalLocation to handle a request to update an external location
+// @Tags Store
+// @Summary To update an external location
+// @Description To update an external location mapping
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "external location id"
+// @Param location body ExternalLocation true "external location"
+// @Success 200 {object} object{status=string,data=ExternalLocation}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /store/external-location/{id} [patch]
+func (h Handler) UpdateExternalLocation(c *gin.Context) {
+	var request ExternalLocation
+	if err := c.ShouldBindJSON(&request); err != nil {
+		shared.LogWarn("warning binding request", LogHandler, "UpdateExternalLocation", err, request)
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorStoreBadRequest))
+		return
+	}
+
+	locationID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorStoreBadRequest))
+		return
+	}
+	request.ID = uint(locationID)
+
+	location, err := h.service.UpdateExternalLocation(&request)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(location))
+}
+
+// DeleteExternalLocation to handle a request to delete an external location
+// @Tags Store
+// @Summary To delete an external location
+// @Description To delete an external location mapping
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "external location id"
+// @Success 200 {object} object{status=string,data=ExternalLocation}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /store/external-location/{id} [delete]
+func (h Handler) DeleteExternalLocation(c *gin.Context) {
+	location, err := h.service.DeleteExternalLocation(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(location))
+}
@@ -12,6 +12,9 @@ func NewRoutes(handler *Handler) Routes {
 
 func (r Routes) RegisterRoutes(private *shared.CustomRoutes) {
 	private.GET("/store", r.handler.Find)
+	private.GET("/store/external-location", r.handler.FindExternalLocations)
+	private.PATCH("/store/external-location/:id", r.handler.UpdateExternalLocation)
+	private.DELETE("/store/external-location/:id", r.handler.DeleteExternalLocation)
 	private.GET("/store/:id", r.handler.Get)
 	private.POST("/store", r.handler.Create)
 	private.PATCH("/store/:id", r.handler.Update)
@@ -19,6 +22,7 @@ func (r Routes) RegisterRoutes(private *shared.CustomRoutes) {
 
 	private.PATCH("/store/:id/enable", r.handler.Enable)
 	private.PATCH("/store/:id/channel/:channelID", r.handler.AddChannel)
+	private.POST("/store/:id/external-location", r.handler.CreateExternalLocation)
 
 	private.GET("/store/:id/zone", r.handler.FindZonesByStore)
 	private.GET("/store/:id/zone/:zoneID", r.handler.GetZoneByStore)
@@ -23,6 +23,11 @@ type Service interface {
 
 	FindZonesByStore(storeID string) ([]tables.Zone, error)
 	GetZoneByStore(storeID, zoneID string) (*tables.Zone, error)
+
+	CreateExternalLocation(storeID string, location *ExternalLocation) (*ExternalLocation, error)
+	FindExternalLocations(filter map[string]string) ([]ExternalLocation, error)
+	UpdateExternalLocation(location *ExternalLocation) (*ExternalLocation, error)
+	DeleteExternalLocation(externalLocationID string) (*ExternalLocation, error)
 }
 
 // service is the default implementation of the Service interface for store.
@@ -98,3 +103,55 @@ func (s service) FindZonesByStore(storeID string) ([]tables.Zone, error) {
 func (s service) GetZoneByStore(storeID, zoneID string) (*tables.Zone, error) {
 	return s.repository.GetZoneByStore(storeID, zoneID)
 }
+
+// CreateExternalLocation maps an external provider location to the store.
+func (s service) CreateExternalLocation(storeID string, location *ExternalLocation) (*ExternalLocation, error) {
+	if !IsValidProvider(location.Provider) {
+		return nil, fmt.Errorf(ErrorExternalLocationInvalidProvider)
+	}
+
+	store, err := s.repository.Get(storeID)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorStoreGet)
+	}
+
+	location.StoreID = &store.ID
+	if _, err := s.repository.CreateExternalLocation(location); err != nil {
+		return nil, fmt.Errorf(ErrorExternalLocationCreation)
+	}
+
+	return location, nil
+}
+
+// FindExternalLocations returns the external locations mapping filtering by query map.
+func (s service) FindExternalLocations(filter map[string]string) ([]ExternalLocation, error) {
+	locations, err := s.repository.FindExternalLocations(filter)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorExternalLocationFind)
+	}
+
+	return locations, nil
+}
+
+// UpdateExternalLocation updates the mapping of an external location.
+func (s service) UpdateExternalLocation(location *ExternalLocation) (*ExternalLocation, error) {
+	if !IsValidProvider(location.Provider) {
+		return nil, fmt.Errorf(ErrorExternalLocationInvalidProvider)
+	}
+
+	if _, err := s.repository.UpdateExternalLocation(location); err != nil {
+		return nil, fmt.Errorf(ErrorExternalLocationUpdate)
+	}
+
+	return location, nil
+}
+
+// DeleteExternalLocation removes the mapping of an external location.
+func (s service) DeleteExternalLocation(externalLocationID string) (*ExternalLocation, error) {
+	location, err := s.repository.DeleteExternalLocation(externalLocationID)
+	if err != nil {
+		return nil, fmt.Errorf(ErrorExternalLocationDelete)
+	}
+
+	return location, nil
+}
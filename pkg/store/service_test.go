@@ -11,6 +11,7 @@ import (
 	"github.com/BacoFoods/menu/pkg/currency"
 	"github.com/BacoFoods/menu/pkg/database"
 	"github.com/BacoFoods/menu/pkg/menu"
+	"github.com/BacoFoods/menu/pkg/payment"
 	"github.com/BacoFoods/menu/pkg/product"
 	"github.com/BacoFoods/menu/pkg/store"
 	"github.com/BacoFoods/menu/pkg/taxes"
@@ -61,7 +62,7 @@ var _ = Describe("Store Service", func() {
 
 		// Brand Implementation
 		brandRepository := brand.NewDBRepository(db)
-		brandService = brand.NewService(brandRepository, channelRepository)
+		brandService = brand.NewService(brandRepository, channelRepository, payment.NewDBRepository(db))
 	})
 
 	AfterSuite(func() {
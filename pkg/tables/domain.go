@@ -20,8 +20,38 @@ const (
 	ErrorTableScanningQR   = "error scanning qr"
 	ErrorTableGeneratingQR = "error generating qr"
 	ErrorTableIDEmpty      = "error table id empty"
+	ErrorTableInvalidState = "error invalid table state"
+	ErrorTableSettingState = "error setting table state"
+	ErrorTableFloorPlan    = "error getting floor plan"
 )
 
+const (
+	TableStateAvailable      = "available"
+	TableStateSeated         = "seated"
+	TableStateOrdered        = "ordered"
+	TableStateCheckRequested = "check_requested"
+	TableStatePaying         = "paying"
+	TableStateNeedsCleaning  = "needs_cleaning"
+)
+
+var TableStates = []string{
+	TableStateAvailable,
+	TableStateSeated,
+	TableStateOrdered,
+	TableStateCheckRequested,
+	TableStatePaying,
+	TableStateNeedsCleaning,
+}
+
+func IsValidTableState(state string) bool {
+	for _, s := range TableStates {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
 const (
 	ErrorZoneFinding        = "error finding zones"
 	ErrorZoneCreating       = "error creating zone"
@@ -75,18 +105,139 @@ type Table struct {
 	XLocation   float64 `json:"xlocation,omitempty"`
 	YLocation   float64 `json:"ylocation,omitempty"`
 	IsActive    bool    `json:"is_active"`
+	Capacity    int     `json:"capacity" gorm:"default:4"`
 	ZoneID      *uint   `json:"zone_id"`
 	Zone        *Zone   `json:"zone"`
 
 	// An order can only be assigned to one table and be active
 	OrderID *uint `json:"order_id" gorm:"uniqueIndex"`
 
+	// State is the lifecycle of the table, from available to needs cleaning
+	State          string     `json:"state" gorm:"default:'available'"`
+	StateChangedAt *time.Time `json:"state_changed_at"`
+	SeatedAt       *time.Time `json:"seated_at"`
+
+	// SessionID identifies the customers using the table, it changes every time the table is released
+	SessionID string        `json:"-"`
+	Session   *TableSession `json:"session,omitempty" gorm:"-"`
+
 	QR        *QR             `json:"qr,omitempty" gorm:"foreignKey:TableID"`
 	CreatedAt *time.Time      `json:"created_at,omitempty" swaggerignore:"true"`
 	UpdatedAt *time.Time      `json:"updated_at,omitempty" swaggerignore:"true"`
 	DeletedAt *gorm.DeletedAt `json:"deleted_at,omitempty" swaggerignore:"true"`
 }
 
+// ChangeState sets the state and its timestamps, the seating time is kept until the table is available again.
+func (t *Table) ChangeState(state string, now time.Time) error {
+	if !IsValidTableState(state) {
+		return fmt.Errorf(ErrorTableInvalidState)
+	}
+
+	t.State = state
+	t.StateChangedAt = &now
+
+	switch state {
+	case TableStateAvailable:
+		t.SeatedAt = nil
+	case TableStateSeated, TableStateOrdered, TableStateCheckRequested, TableStatePaying:
+		if t.SeatedAt == nil {
+			t.SeatedAt = &now
+		}
+	}
+
+	return nil
+}
+
+// StateColumns returns the columns to persist after ChangeState.
+func (t *Table) StateColumns() map[string]any {
+	return map[string]any{
+		"state":            t.State,
+		"state_changed_at": t.StateChangedAt,
+		"seated_at":        t.SeatedAt,
+	}
+}
+
+// TableStateChange is the history of the states of a table
+type TableStateChange struct {
+	ID        uint       `json:"id"`
+	TableID   uint       `json:"table_id" gorm:"index"`
+	OrderID   *uint      `json:"order_id"`
+	State     string     `json:"state"`
+	CreatedAt *time.Time `json:"created_at"`
+}
+
+// FloorPlanTable is a table with its occupancy data to draw the zone floor plan
+type FloorPlanTable struct {
+	ID                  uint       `json:"id"`
+	DisplayID           string     `json:"display_id"`
+	DisplayName         string     `json:"display_name"`
+	Number              int        `json:"number"`
+	XLocation           float64    `json:"xlocation"`
+	YLocation           float64    `json:"ylocation"`
+	IsActive            bool       `json:"is_active"`
+	Capacity            int        `json:"capacity"`
+	State               string     `json:"state"`
+	StateChangedAt      *time.Time `json:"state_changed_at"`
+	SeatedAt            *time.Time `json:"seated_at"`
+	ElapsedSeconds      int64      `json:"elapsed_seconds"`
+	StateElapsedSeconds int64      `json:"state_elapsed_seconds"`
+	OrderID             *uint      `json:"order_id"`
+	Seats               int        `json:"seats"`
+	OpenAmount          float64    `json:"open_amount"`
+}
+
+// FloorPlan is a zone with its tables ready to be drawn
+type FloorPlan struct {
+	ZoneID  uint             `json:"zone_id"`
+	Name    string           `json:"name"`
+	StoreID *uint            `json:"store_id"`
+	Tables  []FloorPlanTable `json:"tables"`
+}
+
+// OrderSummary is the open order data shown in the floor plan
+type OrderSummary struct {
+	OrderID    uint    `json:"order_id"`
+	Seats      int     `json:"seats"`
+	OpenAmount float64 `json:"open_amount"`
+}
+
+func NewFloorPlanTable(table Table, summary *OrderSummary, now time.Time) FloorPlanTable {
+	state := table.State
+	if state == "" {
+		state = TableStateAvailable
+	}
+
+	fpt := FloorPlanTable{
+		ID:             table.ID,
+		DisplayID:      table.DisplayID,
+		DisplayName:    table.DisplayName,
+		Number:         table.Number,
+		XLocation:      table.XLocation,
+		YLocation:      table.YLocation,
+		IsActive:       table.IsActive,
+		Capacity:       table.Capacity,
+		State:          state,
+		StateChangedAt: table.StateChangedAt,
+		SeatedAt:       table.SeatedAt,
+		OrderID:        table.OrderID,
+	}
+
+	if table.SeatedAt != nil {
+		fpt.ElapsedSeconds = int64(now.Sub(*table.SeatedAt).Seconds())
+	}
+
+	if table.StateChangedAt != nil {
+		fpt.StateElapsedSeconds = int64(now.Sub(*table.StateChangedAt).Seconds())
+	}
+
+	if summary != nil {
+		fpt.Seats = summary.Seats
+		fpt.OpenAmount = summary.OpenAmount
+	}
+
+	return fpt
+}
+
 type QR struct {
 	ID        uint   `json:"id,omitempty"`
 	TableID   *uint  `json:"table_id" binding:"required"`
@@ -110,4 +261,8 @@ type Repository interface {
 	RemoveOrder(tableID *uint) (*Table, error)
 	ScanQR(qrID string) (*Table, error)
 	CreateQR(qr QR) (*QR, error)
+	SetState(tableID uint, state string) (*Table, error)
+	RotateSession(tableID uint) (*Table, error)
+	GetBySession(sessionID string) (*Table, error)
+	FindOrderSummaries(orderIDs []uint) (map[uint]OrderSummary, error)
 }
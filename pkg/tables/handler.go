@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/BacoFoods/menu/pkg/realtime"
 	"github.com/BacoFoods/menu/pkg/shared"
 	"github.com/gin-gonic/gin"
 )
@@ -33,12 +34,17 @@ type RequestRemoveTable struct {
 	Tables []uint `json:"tables" binding:"required"`
 }
 
+type RequestTableState struct {
+	State string `json:"state" binding:"required"`
+}
+
 type Handler struct {
-	service Service
+	service    Service
+	subscriber realtime.Subscriber
 }
 
-func NewHandler(service Service) *Handler {
-	return &Handler{service}
+func NewHandler(service Service, subscriber realtime.Subscriber) *Handler {
+	return &Handler{service, subscriber}
 }
 
 // Get to handle get tables request
@@ -99,6 +105,51 @@ func (h Handler) Release(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, shared.SuccessResponse(table))
 }
 
+// SetState to handle a table state change request
+// @Tags Tables
+// @Summary Set table state
+// @Description Set table state: available, seated, ordered, check_requested, paying or needs_cleaning
+// @Param id path string true "table id"
+// @Param state body RequestTableState true "table state"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=Table}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /tables/{id}/state [patch]
+func (h Handler) SetState(ctx *gin.Context) {
+	pid := ctx.Param("id")
+	id, err := strconv.ParseUint(pid, 10, 64)
+	if err != nil {
+		shared.LogError("error parsing table id", LogHandler, "SetState", err, pid)
+		ctx.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorBadRequest))
+		return
+	}
+
+	var body RequestTableState
+	if err := ctx.ShouldBindJSON(&body); err != nil {
+		shared.LogError("error binding request body", LogHandler, "SetState", err, body)
+		ctx.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorBadRequest))
+		return
+	}
+
+	if !IsValidTableState(body.State) {
+		ctx.JSON(http.StatusBadRequest, shared.ErrorResponse(ErrorTableInvalidState))
+		return
+	}
+
+	table, err := h.service.SetState(uint(id), body.State)
+	if err != nil {
+		shared.LogError("error setting table state", LogHandler, "SetState", err, id, body.State)
+		ctx.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(ErrorTableSettingState))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, shared.SuccessResponse(table))
+}
+
 // Find to handle find tables request
 // @Tags Tables
 // @Summary Find tables
@@ -255,6 +306,39 @@ func (h Handler) ScanQR(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, shared.SuccessResponse(table))
 }
 
+// ScanQREvents to handle a subscription to the events of the table scanned
+// @Tags Tables
+// @Summary Subscribe to table events by QR
+// @Description Server sent events stream with the updates of the table and its current order, authenticated by the QR
+// @Param qrId path string true "qr id"
+// @Produce text/event-stream
+// @Success 200 {object} realtime.Event
+// @Failure 404 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Router /public/tables/scan/{qrId}/events [get]
+func (h Handler) ScanQREvents(ctx *gin.Context) {
+	qrID := ctx.Param("qrId")
+
+	table, err := h.service.ScanQR(qrID)
+	if err != nil {
+		shared.LogError("error scanning qr", LogHandler, "ScanQREvents", err, qrID)
+		ctx.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(ErrorTableScanningQR))
+		return
+	}
+
+	if table == nil {
+		ctx.JSON(http.StatusNotFound, shared.ErrorResponse(ErrorTableNotFound))
+		return
+	}
+
+	topics := []string{realtime.TableTopic(table.ID)}
+	if table.OrderID != nil {
+		topics = append(topics, realtime.OrderTopic(*table.OrderID))
+	}
+
+	realtime.Stream(ctx, h.subscriber, topics...)
+}
+
 // GenerateQR to handle qr generation request
 // @Tags Tables
 // @Summary Generate QR
@@ -317,6 +401,32 @@ func (h Handler) FindZones(c *gin.Context) {
 	c.JSON(http.StatusOK, shared.SuccessResponse(zones))
 }
 
+// FloorPlan to handle a request to get the floor plan of a zone
+// @Tags Zones
+// @Summary To get the floor plan of a zone
+// @Description To get the tables of a zone with its state, elapsed time, seats and open amount
+// @Param id path string true "zone id"
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} object{status=string,data=FloorPlan}
+// @Failure 400 {object} shared.Response
+// @Failure 422 {object} shared.Response
+// @Failure 401 {object} shared.Response
+// @Router /zone/{id}/floor-plan [get]
+func (h Handler) FloorPlan(c *gin.Context) {
+	zoneID := c.Param("id")
+
+	floorPlan, err := h.service.FloorPlan(zoneID)
+	if err != nil {
+		shared.LogError("error getting floor plan", LogHandler, "FloorPlan", err, zoneID)
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse(ErrorTableFloorPlan))
+		return
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(floorPlan))
+}
+
 // Get to handle a request to get a zone
 // @Tags Zones
 // @Summary To get a zone
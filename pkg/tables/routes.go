@@ -18,9 +18,11 @@ func (r Routes) RegisterRoutes(private, public *shared.CustomRoutes) {
 	private.PATCH("/tables/:id", r.handler.Update)
 	private.DELETE("/tables/:id", r.handler.Delete)
 	private.POST("/tables/:id/release", r.handler.Release)
+	private.PATCH("/tables/:id/state", r.handler.SetState)
 
 	private.GET("/zone", r.handler.FindZones)
 	private.GET("/zone/:id", r.handler.GetZone)
+	private.GET("/zone/:id/floor-plan", r.handler.FloorPlan)
 	private.POST("/zone", r.handler.CreateZone)
 	private.PATCH("/zone/:id", r.handler.UpdateZone)
 	private.DELETE("/zone/:id", r.handler.DeleteZone)
@@ -29,4 +31,5 @@ func (r Routes) RegisterRoutes(private, public *shared.CustomRoutes) {
 	private.PATCH("/zone/:id/enable", r.handler.Enable)
 
 	public.GET("/tables/scan/:qrId", r.handler.ScanQR)
+	public.GET("/tables/scan/:qrId/events", r.handler.ScanQREvents)
 }
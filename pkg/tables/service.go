@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/BacoFoods/menu/pkg/realtime"
 	"github.com/BacoFoods/menu/pkg/shared"
 	"github.com/google/uuid"
 )
@@ -20,6 +21,7 @@ type Service interface {
 	Update(id string, table *Table) (*Table, error)
 	Delete(id string) error
 	ScanQR(qrID string) (*Table, error)
+	ValidateSession(token string) (*Table, error)
 	GenerateQR(tableId string) (*Table, error)
 
 	FindZones(filters map[string]any) ([]Zone, error)
@@ -31,16 +33,21 @@ type Service interface {
 	RemoveTables(zoneID string, tables []uint) error
 	EnableZone(zoneID string) (*Zone, error)
 	ReleaseTable(tableID uint) (*Table, error)
+	SetState(tableID uint, state string) (*Table, error)
+	FloorPlan(zoneID string) (*FloorPlan, error)
 }
 
 type service struct {
-	zones      *zoneRepository
-	repository *tableRepository
-	oitHost    string
+	zones         *zoneRepository
+	repository    *tableRepository
+	oitHost       string
+	events        realtime.Publisher
+	sessionSecret []byte
+	sessionTTL    time.Duration
 }
 
-func NewService(repository *tableRepository, zones *zoneRepository, oitHost string) service {
-	return service{zones, repository, oitHost}
+func NewService(repository *tableRepository, zones *zoneRepository, oitHost string, events realtime.Publisher, sessionSecret string, sessionTTL time.Duration) service {
+	return service{zones, repository, oitHost, events, []byte(sessionSecret), sessionTTL}
 }
 
 func (s service) Get(id string) (*Table, error) {
@@ -56,7 +63,89 @@ func (s service) Create(table *Table) (*Table, error) {
 }
 
 func (s service) ReleaseTable(tableID uint) (*Table, error) {
-	return s.repository.RemoveOrder(&tableID)
+	table, err := s.repository.RemoveOrder(&tableID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.publish(realtime.EventTableReleased, table)
+
+	return table, nil
+}
+
+// SetState moves the table to the given lifecycle state.
+func (s service) SetState(tableID uint, state string) (*Table, error) {
+	if !IsValidTableState(state) {
+		return nil, fmt.Errorf(ErrorTableInvalidState)
+	}
+
+	table, err := s.repository.SetState(tableID, state)
+	if err != nil {
+		return nil, err
+	}
+
+	s.publish(realtime.EventTableStateChanged, table)
+
+	return table, nil
+}
+
+// FloorPlan returns the tables of a zone with its state, occupancy time, seats and open amount.
+func (s service) FloorPlan(zoneID string) (*FloorPlan, error) {
+	zone, err := s.zones.GetZone(zoneID)
+	if err != nil {
+		return nil, err
+	}
+
+	orderIDs := make([]uint, 0)
+	for _, table := range zone.Tables {
+		if table.OrderID != nil {
+			orderIDs = append(orderIDs, *table.OrderID)
+		}
+	}
+
+	summaries, err := s.repository.FindOrderSummaries(orderIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	floorPlan := FloorPlan{
+		ZoneID:  zone.ID,
+		Name:    zone.Name,
+		StoreID: zone.StoreID,
+		Tables:  make([]FloorPlanTable, 0, len(zone.Tables)),
+	}
+	for _, table := range zone.Tables {
+		var summary *OrderSummary
+		if table.OrderID != nil {
+			if sm, ok := summaries[*table.OrderID]; ok {
+				summary = &sm
+			}
+		}
+		floorPlan.Tables = append(floorPlan.Tables, NewFloorPlanTable(table, summary, now))
+	}
+
+	return &floorPlan, nil
+}
+
+// publish notifies the table subscribers, errors are only logged because the change is already saved.
+func (s service) publish(eventType string, table *Table) {
+	if s.events == nil || table == nil {
+		return
+	}
+
+	event := realtime.Event{
+		Type:    eventType,
+		TableID: &table.ID,
+		Data:    table,
+	}
+	if table.Zone != nil {
+		event.StoreID = table.Zone.StoreID
+	}
+
+	if err := s.events.Publish(event); err != nil {
+		shared.LogWarn("error publishing table event", LogService, "publish", err, eventType, table.ID)
+	}
 }
 
 func (s service) Update(id string, table *Table) (*Table, error) {
@@ -77,9 +166,50 @@ func (s service) ScanQR(qrID string) (*Table, error) {
 		return nil, nil
 	}
 
+	if table.SessionID == "" {
+		rotated, err := s.repository.RotateSession(table.ID)
+		if err != nil {
+			return nil, err
+		}
+		table.SessionID = rotated.SessionID
+	}
+
+	session, err := NewSessionToken(table, s.sessionSecret, s.sessionTTL, time.Now())
+	if err != nil {
+		shared.LogError("error signing table session", LogService, "ScanQR", err, table.ID)
+		return nil, fmt.Errorf(ErrorTableSessionCreating)
+	}
+	table.Session = session
+
 	return table, nil
 }
 
+// ValidateSession returns the table of the session token, the token must belong to the current
+// session of the table, so tokens issued before the table was released are rejected. A session moved
+// with its order to another table returns that table.
+func (s service) ValidateSession(token string) (*Table, error) {
+	claims, err := ParseSessionToken(token, s.sessionSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	table, err := s.repository.Get(fmt.Sprint(claims.TableID))
+	if err != nil {
+		return nil, fmt.Errorf(ErrorTableSessionInvalid)
+	}
+
+	if table.SessionID == claims.SessionID {
+		return table, nil
+	}
+
+	moved, err := s.repository.GetBySession(claims.SessionID)
+	if err != nil || moved == nil {
+		return nil, fmt.Errorf(ErrorTableSessionRotated)
+	}
+
+	return moved, nil
+}
+
 func (s service) GenerateQR(tableId string) (*Table, error) {
 	table, err := s.Get(tableId)
 	if err != nil {
@@ -0,0 +1,82 @@
+package tables
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	ErrorTableSessionInvalid  = "error invalid table session"
+	ErrorTableSessionExpired  = "error table session expired"
+	ErrorTableSessionRotated  = "error table session was closed"
+	ErrorTableSessionCreating = "error creating table session"
+	ErrorTableSessionSecret   = "error table session secret is not set"
+)
+
+// TableSession is the token given to the customer when the table QR is scanned,
+// it is needed to order and pay from the table and it is rotated when the table is released.
+type TableSession struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// SessionClaims are the claims signed in the table session token
+type SessionClaims struct {
+	TableID   uint   `json:"table_id"`
+	SessionID string `json:"session_id"`
+	jwt.StandardClaims
+}
+
+// NewSessionToken signs a session token for the current session of the table.
+func NewSessionToken(table *Table, secret []byte, ttl time.Duration, now time.Time) (*TableSession, error) {
+	if len(secret) == 0 {
+		return nil, fmt.Errorf(ErrorTableSessionSecret)
+	}
+
+	expiresAt := now.Add(ttl)
+	claims := SessionClaims{
+		TableID:   table.ID,
+		SessionID: table.SessionID,
+		StandardClaims: jwt.StandardClaims{
+			IssuedAt:  now.Unix(),
+			ExpiresAt: expiresAt.Unix(),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TableSession{Token: token, ExpiresAt: expiresAt}, nil
+}
+
+// ParseSessionToken checks the signature and expiration of a session token, no token is valid without a secret.
+func ParseSessionToken(tokenString string, secret []byte) (*SessionClaims, error) {
+	if len(secret) == 0 {
+		return nil, fmt.Errorf(ErrorTableSessionInvalid)
+	}
+
+	var claims SessionClaims
+	token, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (any, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return secret, nil
+	})
+
+	if err != nil {
+		if ve, ok := err.(*jwt.ValidationError); ok && ve.Errors&jwt.ValidationErrorExpired != 0 {
+			return nil, fmt.Errorf(ErrorTableSessionExpired)
+		}
+		return nil, fmt.Errorf(ErrorTableSessionInvalid)
+	}
+
+	if !token.Valid || claims.TableID == 0 || claims.SessionID == "" {
+		return nil, fmt.Errorf(ErrorTableSessionInvalid)
+	}
+
+	return &claims, nil
+}
@@ -0,0 +1,51 @@
+package tables_test
+
+import (
+	"time"
+
+	"github.com/BacoFoods/menu/pkg/tables"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Table session", func() {
+	table := &tables.Table{ID: 4, SessionID: "session"}
+	secret := []byte("secret")
+	now := time.Now()
+
+	It("Parses the tokens it signs", func() {
+		session, err := tables.NewSessionToken(table, secret, time.Hour, now)
+		Expect(err).NotTo(HaveOccurred())
+
+		claims, err := tables.ParseSessionToken(session.Token, secret)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(claims.TableID).To(Equal(uint(4)))
+		Expect(claims.SessionID).To(Equal("session"))
+	})
+
+	It("Rejects the tokens signed with another secret", func() {
+		session, err := tables.NewSessionToken(table, []byte("other"), time.Hour, now)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = tables.ParseSessionToken(session.Token, secret)
+		Expect(err).To(MatchError(tables.ErrorTableSessionInvalid))
+	})
+
+	It("Rejects the expired tokens", func() {
+		session, err := tables.NewSessionToken(table, secret, time.Hour, now.Add(-2*time.Hour))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = tables.ParseSessionToken(session.Token, secret)
+		Expect(err).To(MatchError(tables.ErrorTableSessionExpired))
+	})
+
+	It("Neither signs nor accepts tokens without a secret", func() {
+		_, err := tables.NewSessionToken(table, nil, time.Hour, now)
+		Expect(err).To(MatchError(tables.ErrorTableSessionSecret))
+
+		session, err := tables.NewSessionToken(table, secret, time.Hour, now)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = tables.ParseSessionToken(session.Token, nil)
+		Expect(err).To(MatchError(tables.ErrorTableSessionInvalid))
+	})
+})
@@ -3,8 +3,10 @@ package tables
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/BacoFoods/menu/pkg/shared"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
@@ -125,21 +127,42 @@ func (r tableRepository) SwapTable(orderID, newTableID uint, oldTableID *uint) (
 	err := r.db.Transaction(func(tx *gorm.DB) error {
 		// if oldTable is provided, release by setting old table order to nil
 		// oldTable is released first to avoid unique constraint error on order_id column
+		now := time.Now()
+		// the customers of the order keep their session on the new table, the old table starts a new one
+		sessionID := uuid.NewString()
 		if oldlTable.ID != 0 {
-			if err := tx.Model(&oldlTable).Update("order_id", gorm.Expr("NULL")).Error; err != nil {
+			if oldlTable.SessionID != "" {
+				sessionID = oldlTable.SessionID
+			}
+			if err := tx.Model(&oldlTable).Updates(map[string]any{"order_id": gorm.Expr("NULL"), "session_id": uuid.NewString()}).Error; err != nil {
 				shared.LogError(ErrorTableUpdating, LogRepository, "SwapTable", err, oldlTable.ID, orderID)
 				return err
 			}
+
+			// guests keep their seating time on the new table
+			newTable.SeatedAt = oldlTable.SeatedAt
+			_ = oldlTable.ChangeState(TableStateNeedsCleaning, now)
+			if err := r.saveState(tx, &oldlTable, &orderID); err != nil {
+				shared.LogError(ErrorTableSettingState, LogRepository, "SwapTable", err, oldlTable.ID, orderID)
+				return err
+			}
 		} else {
 			shared.LogWarn("no oldTable provided", LogRepository, "SwapTable", nil, oldlTable.ID, oldTableID, orderID)
 		}
 
 		// if newTable is provided, set new table order to old table order
 		if newTable.ID != 0 {
-			if err := tx.Model(&newTable).Update("order_id", orderID).Error; err != nil {
+			newTable.SessionID = sessionID
+			if err := tx.Model(&newTable).Updates(map[string]any{"order_id": orderID, "session_id": sessionID}).Error; err != nil {
 				shared.LogError(ErrorTableUpdating, LogRepository, "SwapTable", err, newTableID, orderID)
 				return err
 			}
+
+			_ = newTable.ChangeState(TableStateOrdered, now)
+			if err := r.saveState(tx, &newTable, &orderID); err != nil {
+				shared.LogError(ErrorTableSettingState, LogRepository, "SwapTable", err, newTableID, orderID)
+				return err
+			}
 		}
 
 		return nil
@@ -164,7 +187,15 @@ func (r tableRepository) SetOrder(tableID, orderID *uint) (*Table, error) {
 		return nil, fmt.Errorf(ErrorTableHasOrder)
 	}
 
-	if err := r.db.Model(&table).Update("order_id", orderID).Error; err != nil {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&table).Update("order_id", orderID).Error; err != nil {
+			return err
+		}
+
+		_ = table.ChangeState(TableStateOrdered, time.Now())
+		return r.saveState(tx, &table, orderID)
+	})
+	if err != nil {
 		shared.LogError(ErrorTableUpdating, LogRepository, "SetOrder", err, *tableID, *orderID)
 		return nil, err
 	}
@@ -180,17 +211,32 @@ func (r tableRepository) RemoveOrder(tableID *uint) (*Table, error) {
 	}
 
 	var table Table
-	if err := r.db.First(&table, tableID).Error; err != nil {
+	if err := r.db.Preload("Zone").First(&table, tableID).Error; err != nil {
 		shared.LogError(ErrorTableUpdating, LogRepository, "RemoveOrder", err, *tableID)
 		return nil, err
 	}
 
+	// the customers session is closed even if the table has no order
+	table.SessionID = uuid.NewString()
 	if table.OrderID == nil {
+		if err := r.db.Model(&table).Update("session_id", table.SessionID).Error; err != nil {
+			shared.LogError(ErrorTableUpdating, LogRepository, "RemoveOrder", err, *tableID)
+			return nil, err
+		}
 		return &table, nil
 	}
 
+	orderID := table.OrderID
 	table.OrderID = nil
-	if err := r.db.Model(&table).Update("order_id", gorm.Expr("NULL")).Error; err != nil {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&table).Updates(map[string]any{"order_id": gorm.Expr("NULL"), "session_id": table.SessionID}).Error; err != nil {
+			return err
+		}
+
+		_ = table.ChangeState(TableStateNeedsCleaning, time.Now())
+		return r.saveState(tx, &table, orderID)
+	})
+	if err != nil {
 		shared.LogError(ErrorTableUpdating, LogRepository, "RemoveOrder", err, *tableID)
 		return nil, err
 	}
@@ -222,3 +268,102 @@ func (r tableRepository) CreateQR(qr QR) (*QR, error) {
 
 	return &qr, nil
 }
+
+// SetState changes the state of a table and saves it in the state history
+func (r tableRepository) SetState(tableID uint, state string) (*Table, error) {
+	var table Table
+	if err := r.db.Preload("Zone").First(&table, tableID).Error; err != nil {
+		shared.LogError(ErrorTableGetting, LogRepository, "SetState", err, tableID, state)
+		return nil, err
+	}
+
+	if err := table.ChangeState(state, time.Now()); err != nil {
+		return nil, err
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		return r.saveState(tx, &table, table.OrderID)
+	})
+	if err != nil {
+		shared.LogError(ErrorTableSettingState, LogRepository, "SetState", err, tableID, state)
+		return nil, err
+	}
+
+	return &table, nil
+}
+
+// GetBySession returns the table the session is on now, nil when the session was closed
+func (r tableRepository) GetBySession(sessionID string) (*Table, error) {
+	var tables []Table
+	if err := r.db.Where("session_id = ?", sessionID).Limit(1).Find(&tables).Error; err != nil {
+		shared.LogError(ErrorTableGetting, LogRepository, "GetBySession", err)
+		return nil, err
+	}
+
+	if len(tables) == 0 {
+		return nil, nil
+	}
+
+	return &tables[0], nil
+}
+
+// RotateSession starts a new customers session on the table, the tokens of the previous session are no longer valid
+func (r tableRepository) RotateSession(tableID uint) (*Table, error) {
+	var table Table
+	if err := r.db.First(&table, tableID).Error; err != nil {
+		shared.LogError(ErrorTableGetting, LogRepository, "RotateSession", err, tableID)
+		return nil, err
+	}
+
+	table.SessionID = uuid.NewString()
+	if err := r.db.Model(&table).Update("session_id", table.SessionID).Error; err != nil {
+		shared.LogError(ErrorTableUpdating, LogRepository, "RotateSession", err, tableID)
+		return nil, err
+	}
+
+	return &table, nil
+}
+
+// saveState persists the state columns of the table and appends the change to the history
+func (r tableRepository) saveState(tx *gorm.DB, table *Table, orderID *uint) error {
+	if err := tx.Model(&Table{}).Where("id = ?", table.ID).Updates(table.StateColumns()).Error; err != nil {
+		return err
+	}
+
+	return tx.Create(&TableStateChange{
+		TableID:   table.ID,
+		OrderID:   orderID,
+		State:     table.State,
+		CreatedAt: table.StateChangedAt,
+	}).Error
+}
+
+// FindOrderSummaries returns the seats and the amount of the open orders by order id, net of the discounts of their
+// invoices
+func (r tableRepository) FindOrderSummaries(orderIDs []uint) (map[uint]OrderSummary, error) {
+	summaries := make(map[uint]OrderSummary)
+	if len(orderIDs) == 0 {
+		return summaries, nil
+	}
+
+	var rows []OrderSummary
+	err := r.db.Raw(`
+		SELECT o.id AS order_id,
+			o.seats AS seats,
+			COALESCE((SELECT SUM(oi.price) FROM order_items oi WHERE oi.order_id = o.id AND oi.deleted_at IS NULL), 0) +
+			COALESCE((SELECT SUM(om.price) FROM order_modifiers om WHERE om.order_id = o.id AND om.deleted_at IS NULL), 0) -
+			COALESCE((SELECT SUM(i.total_discounts) FROM invoices i WHERE i.order_id = o.id AND i.deleted_at IS NULL), 0) AS open_amount
+		FROM orders o
+		WHERE o.id IN ? AND o.deleted_at IS NULL`, orderIDs).
+		Scan(&rows).Error
+	if err != nil {
+		shared.LogError(ErrorTableFloorPlan, LogRepository, "FindOrderSummaries", err, orderIDs)
+		return nil, err
+	}
+
+	for _, row := range rows {
+		summaries[row.OrderID] = row
+	}
+
+	return summaries, nil
+}
@@ -0,0 +1,13 @@
+package tables_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestTables(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Tables Suite")
+}
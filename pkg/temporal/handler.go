@@ -10,14 +10,17 @@ import (
 
 	"github.com/BacoFoods/menu/internal"
 	"github.com/BacoFoods/menu/pkg/shared"
+	"github.com/BacoFoods/menu/pkg/store"
 	"github.com/gin-gonic/gin"
 	"google.golang.org/api/iterator"
 )
 
-type Handler struct{}
+type Handler struct {
+	stores store.Repository
+}
 
-func NewHandler() *Handler {
-	return &Handler{}
+func NewHandler(stores store.Repository) *Handler {
+	return &Handler{stores}
 }
 
 const (
@@ -25,27 +28,6 @@ const (
 	ErrorTemporalArqueoInvalidDate = "invalid date format"
 )
 
-var mapLocalesNombres = map[string]string{
-	"ZonaG - PC1":      "bacuzonagc14",
-	"ZonaG - PC2":      "bacuzonag",
-	"Flormorado - PC1": "bacuflormoradopc2",
-	"Flormorado - PC2": "bacuflormorado",
-	"Flormorado - PC3": "flormorado10885",
-	"CL109 - PC1":      "bacucalle109",
-	"CL109 - PC2":      "bacu109",
-	"CL90 - PC1":       "feriadelmillon2",
-	"CL90 - PC2":       "bacucalle90delivery",
-	"Connecta - PC1":   "connectasalon110665",
-	"Connecta - PC2":   "bacuconnecta",
-	"Connecta - PC3":   "connectasalon210666",
-	"CityU - PC1":      "cityusalon1",
-	"CityU - PC2":      "cityusalon2",
-	"CityU - PC3":      "bacucityu",
-	"Colina":           "bacucolinapc110881",
-	"Titan":            "bacutitansalon10883",
-	"Nogal":            "bacunogalespc110884",
-}
-
 type OrderPayment struct {
 	Total     float64 `firestore:"total"`
 	FormaPago string  `firestore:"formaPago"`
@@ -66,7 +48,7 @@ type Order struct {
 
 // GetLocales to handle the request to get the locales for arqueo
 // @Summary Get the locales for arqueo
-// @Description Get the locales for arqueo
+// @Description Get the Popapp locales for arqueo by name, as mapped in the store external locations
 // @Tags Temporal
 // @Accept json
 // @Produce json
@@ -76,7 +58,23 @@ type Order struct {
 // @Failure 422 {object} shared.Response
 // @Router /temporal/locales [get]
 func (h *Handler) GetLocales(c *gin.Context) {
-	c.JSON(http.StatusOK, shared.SuccessResponse(mapLocalesNombres))
+	externalLocations, err := h.stores.FindExternalLocations(map[string]string{"provider": store.ProviderPopapp})
+	if err != nil {
+		shared.LogError("error getting locales", LogHandler, "GetLocales", err)
+		c.JSON(http.StatusUnprocessableEntity, shared.ErrorResponse("error getting locales"))
+		return
+	}
+
+	locales := make(map[string]string)
+	for _, location := range externalLocations {
+		name := location.Name
+		if name == "" && location.Store != nil {
+			name = location.Store.Name
+		}
+		locales[name] = location.ExternalID
+	}
+
+	c.JSON(http.StatusOK, shared.SuccessResponse(locales))
 }
 
 // GetArqueo to handle the request to get the arqueo